@@ -0,0 +1,597 @@
+// Package mcp - Audit Log
+// This file implements an audit trail of MCP requests: who called which
+// method or tool, with which arguments, and what came back, redacted by a
+// Redactor. Records
+// are written to rotating JSONL files and chained by SHA-256 hash so that
+// modified, reordered or deleted records are detected by VerifyAuditLog.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditRecord is one line of an audit log.
+type AuditRecord struct {
+	Seq       uint64          `json:"seq"`
+	Time      time.Time       `json:"time"`
+	RequestID string          `json:"requestId,omitempty"`
+	Principal string          `json:"principal"`
+	Scopes    []string        `json:"scopes,omitempty"`
+	Method    string          `json:"method"`
+	Tool      string          `json:"tool,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *ResponseError  `json:"error,omitempty"`
+	Success   bool            `json:"success"`
+	Duration  time.Duration   `json:"durationNs"`
+
+	// PrevHash is the Hash of the preceding record, empty for the first
+	// record of a log. Hash covers every other field of this record.
+	PrevHash string `json:"prevHash"`
+	Hash     string `json:"hash"`
+}
+
+// computeHash returns the chain hash of r, ignoring r.Hash. The hash is
+// taken over the canonical JSON form of the record, with object keys
+// sorted, so it is the same for a record being appended and for the record
+// decoded back from the log, whose Error.Data is then a map rather than
+// the value the caller set.
+func (r AuditRecord) computeHash() (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var canonical any
+	if err := dec.Decode(&canonical); err != nil {
+		return "", err
+	}
+	if data, err = json.Marshal(canonical); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditConfig configures an AuditLog.
+type AuditConfig struct {
+	Dir         string `json:"dir" yaml:"dir"`                 // Directory holding the log files (required)
+	FilePrefix  string `json:"filePrefix" yaml:"filePrefix"`   // File name prefix (default "audit")
+	MaxFileSize int64  `json:"maxFileSize" yaml:"maxFileSize"` // Rotate once a file would exceed this size (default 100MiB)
+	MaxFiles    int    `json:"maxFiles" yaml:"maxFiles"`       // Oldest files beyond this count are removed (0 keeps all)
+	Sync        bool   `json:"sync" yaml:"sync"`               // fsync after every record
+
+	// RedactPaths are JSON paths, as in RedactionConfig.Paths, whose values
+	// are redacted on top of the default rules, e.g.
+	// "params.arguments.query" or "result.content[*].text".
+	RedactPaths []string `json:"redactPaths" yaml:"redactPaths"`
+
+	// Redactor, if set, replaces the redactor built from RedactPaths.
+	Redactor *Redactor `json:"-" yaml:"-"`
+
+	// Methods limits auditing to the listed methods (default all requests).
+	Methods []string `json:"methods" yaml:"methods"`
+
+	// Logger reports records recovered from a crash (default slog.Default()).
+	Logger *slog.Logger `json:"-" yaml:"-"`
+}
+
+// AuditLog appends hash-chained records to rotating JSONL files. It is safe
+// for concurrent use.
+type AuditLog struct {
+	config   AuditConfig
+	redactor *Redactor
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	seq      uint64
+	lastHash string
+	closed   bool
+}
+
+// NewAuditLog opens the audit log in config.Dir, continuing the hash chain
+// of any records already there. If a crash left the last file with a torn
+// or unverifiable tail, the tail is moved to a ".torn" file beside it and
+// logged, and the chain continues from the last record that verifies.
+func NewAuditLog(config AuditConfig) (*AuditLog, error) {
+	if config.Dir == "" {
+		return nil, errors.New("audit: Dir is required")
+	}
+	if config.FilePrefix == "" {
+		config.FilePrefix = "audit"
+	}
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = 100 << 20
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	l := &AuditLog{config: config, redactor: config.Redactor}
+	if l.redactor == nil {
+		r, err := NewRedactor(RedactionConfig{Paths: config.RedactPaths})
+		if err != nil {
+			return nil, fmt.Errorf("audit: %w", err)
+		}
+		l.redactor = r
+	}
+
+	files, err := auditFiles(config.Dir, config.FilePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	if len(files) > 0 {
+		last := files[len(files)-1]
+		f, err := os.OpenFile(last, os.O_RDWR|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("audit: %w", err)
+		}
+		size, err := l.recoverTail(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("audit: resume %s: %w", last, err)
+		}
+		l.file, l.size = f, size
+		// A crash right after rotating can leave the last file without a
+		// complete record; the chain then continues from an earlier file.
+		for i := len(files) - 2; i >= 0 && l.lastHash == ""; i-- {
+			if err := l.resumeFrom(files[i]); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("audit: resume %s: %w", files[i], err)
+			}
+		}
+	}
+	return l, nil
+}
+
+// recoverTail resumes the chain from the last verified record of f, the
+// newest log file, moving any bytes after it to a quarantine file. It
+// returns the size of f afterwards.
+func (l *AuditLog) recoverTail(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	rec, end, err := lastAuditRecord(f, size)
+	if err != nil {
+		return 0, err
+	}
+	if rec != nil {
+		l.seq, l.lastHash = rec.Seq, rec.Hash
+	}
+	if end == size {
+		return size, nil
+	}
+	tail := make([]byte, size-end)
+	if _, err := f.ReadAt(tail, end); err != nil {
+		return 0, err
+	}
+	quarantine := fmt.Sprintf("%s.%d.torn", f.Name(), time.Now().UnixNano())
+	if err := os.WriteFile(quarantine, tail, 0o600); err != nil {
+		return 0, err
+	}
+	if err := f.Truncate(end); err != nil {
+		return 0, err
+	}
+	l.config.Logger.Warn("audit: moved torn records to quarantine",
+		"file", f.Name(), "bytes", len(tail), "quarantine", quarantine, "resumeSeq", l.seq)
+	return end, nil
+}
+
+// resumeFrom continues the chain from the last verified record of the
+// earlier log file name, if it has one.
+func (l *AuditLog) resumeFrom(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	rec, _, err := lastAuditRecord(f, info.Size())
+	if rec != nil {
+		l.seq, l.lastHash = rec.Seq, rec.Hash
+	}
+	return err
+}
+
+// Append redacts rec, assigns its sequence number and chain hashes, and
+// writes it to the log.
+func (l *AuditLog) Append(rec *AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return errors.New("audit: log closed")
+	}
+
+	rec.Params, rec.Result = l.redactor.redactExchange(rec.Method, rec.Params, rec.Result)
+	rec.Seq = l.seq + 1
+	rec.PrevHash = l.lastHash
+	hash, err := rec.computeHash()
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	rec.Hash = hash
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	line = append(line, '\n')
+
+	if l.file == nil || (l.size > 0 && l.size+int64(len(line)) > l.config.MaxFileSize) {
+		if err := l.rotate(rec.Seq); err != nil {
+			return fmt.Errorf("audit: rotate: %w", err)
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	if l.config.Sync {
+		if err := l.file.Sync(); err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+	}
+	l.seq, l.lastHash = rec.Seq, rec.Hash
+	return nil
+}
+
+// rotate starts a new file named after the first sequence number it holds.
+// l.mu must be held.
+func (l *AuditLog) rotate(seq uint64) error {
+	if l.file != nil {
+		if err := l.file.Close(); err != nil {
+			return err
+		}
+		l.file = nil
+	}
+	name := filepath.Join(l.config.Dir, fmt.Sprintf("%s-%020d.jsonl", l.config.FilePrefix, seq))
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	l.file, l.size = f, 0
+
+	if l.config.MaxFiles > 0 {
+		files, err := auditFiles(l.config.Dir, l.config.FilePrefix)
+		if err != nil {
+			return err
+		}
+		for len(files) > l.config.MaxFiles {
+			if err := os.Remove(files[0]); err != nil {
+				return err
+			}
+			files = files[1:]
+		}
+	}
+	return nil
+}
+
+// Close closes the current log file.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// AuditMiddleware records every request it sees to an AuditLog. It runs
+// inside AuthenticationMiddleware so records carry the caller's principal.
+type AuditMiddleware struct {
+	log     *AuditLog
+	methods map[string]bool
+	onError func(error)
+}
+
+// NewAuditMiddleware creates middleware that writes to log. onError, if
+// non-nil, is called when a record cannot be written; the request itself is
+// not failed.
+func NewAuditMiddleware(log *AuditLog, onError func(error)) *AuditMiddleware {
+	m := &AuditMiddleware{log: log, onError: onError}
+	if len(log.config.Methods) > 0 {
+		m.methods = make(map[string]bool)
+		for _, method := range log.config.Methods {
+			m.methods[method] = true
+		}
+	}
+	return m
+}
+
+func (m *AuditMiddleware) Apply(next MCPHandler) MCPHandler {
+	return MCPHandlerFunc(func(ctx context.Context, req MCPRequest) (MCPResponse, error) {
+		if m.methods != nil && !m.methods[req.Method()] {
+			return next.Handle(ctx, req)
+		}
+		start := time.Now()
+		rec := &AuditRecord{
+			Time:      start.UTC(),
+			RequestID: getOrGenerateRequestID(ctx),
+			Principal: "anonymous",
+			Method:    req.Method(),
+			Tool:      toolName(req),
+			Params:    req.Params(),
+		}
+		if authCtx := GetAuthContext(ctx); authCtx != nil {
+			rec.Principal = authCtx.ClientID
+			rec.Scopes = authCtx.Scopes
+		}
+
+		resp, err := next.Handle(ctx, req)
+
+		rec.Duration = time.Since(start)
+		switch {
+		case err != nil:
+			rec.Error = &ResponseError{Code: -32603, Message: err.Error()} // JSON-RPC internal error
+		case resp == nil:
+		case resp.IsError():
+			rec.Error = resp.Error()
+		default:
+			if data, merr := json.Marshal(resp.Result()); merr == nil {
+				rec.Result = data
+			}
+		}
+		rec.Success = rec.Error == nil && !resultIsError(rec.Result)
+
+		if werr := m.log.Append(rec); werr != nil && m.onError != nil {
+			m.onError(werr)
+		}
+		return resp, err
+	})
+}
+
+// Close closes the underlying AuditLog.
+func (m *AuditMiddleware) Close() error {
+	return m.log.Close()
+}
+
+func (m *AuditMiddleware) Name() string {
+	return "audit"
+}
+
+func (m *AuditMiddleware) Priority() int {
+	return 850 // After auth, before rate limiting so rejections are recorded
+}
+
+// resultIsError reports whether a tool result has isError set.
+func resultIsError(result json.RawMessage) bool {
+	var r struct {
+		IsError bool `json:"isError"`
+	}
+	return len(result) > 0 && json.Unmarshal(result, &r) == nil && r.IsError
+}
+
+// Verification
+// ============
+
+// AuditChainError reports where an audit log fails verification.
+type AuditChainError struct {
+	File   string
+	Line   int
+	Seq    uint64
+	Reason string
+}
+
+func (e *AuditChainError) Error() string {
+	return fmt.Sprintf("audit: %s:%d: record %d: %s", e.File, e.Line, e.Seq, e.Reason)
+}
+
+// AuditPrincipalSummary summarizes one principal's activity.
+type AuditPrincipalSummary struct {
+	Requests int            `json:"requests"`
+	Failures int            `json:"failures"`
+	Methods  map[string]int `json:"methods"`
+	Tools    map[string]int `json:"tools,omitempty"`
+	First    time.Time      `json:"first"`
+	Last     time.Time      `json:"last"`
+}
+
+// AuditReport is the result of verifying an audit log.
+type AuditReport struct {
+	Files    []string `json:"files"`
+	Records  int      `json:"records"`
+	FirstSeq uint64   `json:"firstSeq"`
+	LastSeq  uint64   `json:"lastSeq"`
+
+	// Truncated is set when the oldest record chains to a record that is no
+	// longer present, as happens after rotation removed old files.
+	Truncated bool `json:"truncated"`
+
+	Principals map[string]*AuditPrincipalSummary `json:"principals"`
+}
+
+// VerifyAuditLog verifies the hash chain of the audit log files with the
+// given prefix (default "audit") in dir, and summarizes activity per
+// principal. A chain failure is returned as an *AuditChainError along with
+// the report of the records verified before it.
+func VerifyAuditLog(dir, prefix string) (*AuditReport, error) {
+	if prefix == "" {
+		prefix = "audit"
+	}
+	files, err := auditFiles(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("audit: no %s-*.jsonl files in %s", prefix, dir)
+	}
+	return VerifyAuditFiles(files...)
+}
+
+// VerifyAuditFiles verifies the hash chain across files, which must be given
+// in order.
+func VerifyAuditFiles(files ...string) (*AuditReport, error) {
+	report := &AuditReport{Principals: make(map[string]*AuditPrincipalSummary)}
+	var prev *AuditRecord
+	for _, name := range files {
+		report.Files = append(report.Files, name)
+		if err := verifyAuditFile(name, report, &prev); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func verifyAuditFile(name string, report *AuditReport, prev **AuditRecord) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return &AuditChainError{File: name, Line: line, Reason: "malformed record: " + err.Error()}
+		}
+		fail := func(reason string) error {
+			return &AuditChainError{File: name, Line: line, Seq: rec.Seq, Reason: reason}
+		}
+		hash, err := rec.computeHash()
+		if err != nil {
+			return fail(err.Error())
+		}
+		if hash != rec.Hash {
+			return fail("hash mismatch")
+		}
+		if p := *prev; p != nil {
+			if rec.Seq != p.Seq+1 {
+				return fail(fmt.Sprintf("sequence gap after %d", p.Seq))
+			}
+			if rec.PrevHash != p.Hash {
+				return fail("previous hash mismatch")
+			}
+		} else {
+			report.FirstSeq = rec.Seq
+			if rec.Seq != 1 || rec.PrevHash != "" {
+				report.Truncated = true
+			}
+		}
+		*prev = &rec
+		report.Records++
+		report.LastSeq = rec.Seq
+		report.add(&rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return &AuditChainError{File: name, Line: line + 1, Reason: err.Error()}
+	}
+	return nil
+}
+
+func (r *AuditReport) add(rec *AuditRecord) {
+	s := r.Principals[rec.Principal]
+	if s == nil {
+		s = &AuditPrincipalSummary{Methods: make(map[string]int), First: rec.Time}
+		r.Principals[rec.Principal] = s
+	}
+	s.Requests++
+	if !rec.Success {
+		s.Failures++
+	}
+	s.Methods[rec.Method]++
+	if rec.Tool != "" {
+		if s.Tools == nil {
+			s.Tools = make(map[string]int)
+		}
+		s.Tools[rec.Tool]++
+	}
+	s.Last = rec.Time
+}
+
+// auditFiles returns the log files for prefix in dir, oldest first.
+func auditFiles(dir, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix+"-") || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		if _, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, prefix+"-"), ".jsonl"), 10, 64); err != nil {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	// Sequence numbers are zero-padded, so name order is log order.
+	sort.Strings(files)
+	return files, nil
+}
+
+// lastAuditRecord reads the first size bytes of f backwards to the last
+// complete line holding a record whose hash verifies. It returns the record
+// and the offset just past its line, or nil and 0 if there is none. Only
+// the lines after that record are read, not the whole file.
+func lastAuditRecord(f *os.File, size int64) (*AuditRecord, int64, error) {
+	const chunk = 64 << 10
+	end, pos := size, size
+	var buf []byte // the bytes of f in [pos, end)
+	for end > 0 {
+		// The line ending at end starts after the previous newline.
+		i := -1
+		for {
+			if len(buf) > 0 {
+				i = bytes.LastIndexByte(buf[:len(buf)-1], '\n')
+			}
+			if i >= 0 || pos == 0 {
+				break
+			}
+			n := int64(chunk)
+			if n > pos {
+				n = pos
+			}
+			more := make([]byte, n, n+int64(len(buf)))
+			if _, err := f.ReadAt(more, pos-n); err != nil {
+				return nil, 0, err
+			}
+			buf, pos = append(more, buf...), pos-n
+		}
+		// A line without its newline was torn by a crash mid-write.
+		if line := buf[i+1:]; line[len(line)-1] == '\n' {
+			var rec AuditRecord
+			if json.Unmarshal(line, &rec) == nil {
+				if hash, err := rec.computeHash(); err == nil && hash == rec.Hash {
+					return &rec, end, nil
+				}
+			}
+		}
+		end -= int64(len(buf) - (i + 1))
+		buf = buf[:i+1]
+	}
+	return nil, 0, nil
+}
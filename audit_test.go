@@ -0,0 +1,258 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func appendAuditRecords(t *testing.T, l *AuditLog, n int, principal string) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		rec := &AuditRecord{
+			Principal: principal,
+			Method:    "tools/call",
+			Tool:      "echo",
+			Params:    json.RawMessage(`{"name":"echo","arguments":{"text":"hi","password":"hunter2"}}`),
+			Result:    json.RawMessage(`{"content":[]}`),
+			Success:   true,
+		}
+		if err := l.Append(rec); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+}
+
+func TestAuditLogChain(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewAuditLog(AuditConfig{
+		Dir:         dir,
+		MaxFileSize: 1024,
+		RedactPaths: []string{"params.arguments.password"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendAuditRecords(t, l, 10, "alice")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening continues the chain.
+	l, err = NewAuditLog(AuditConfig{Dir: dir, MaxFileSize: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendAuditRecords(t, l, 2, "bob")
+	l.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	if len(files) < 2 {
+		t.Fatalf("got %d files, want rotation", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Fatal("redacted value written to the log")
+	}
+
+	report, err := VerifyAuditLog(dir, "")
+	if err != nil {
+		t.Fatalf("VerifyAuditLog: %v", err)
+	}
+	if report.Records != 12 || report.FirstSeq != 1 || report.LastSeq != 12 || report.Truncated {
+		t.Fatalf("report = %+v", report)
+	}
+	if got := report.Principals["alice"].Tools["echo"]; got != 10 {
+		t.Errorf("alice echo calls = %d, want 10", got)
+	}
+	if got := report.Principals["bob"].Requests; got != 2 {
+		t.Errorf("bob requests = %d, want 2", got)
+	}
+
+	// Tampering with any record breaks the chain.
+	tampered := bytes.Replace(data, []byte(`"principal":"alice"`), []byte(`"principal":"mallory"`), 1)
+	if err := os.WriteFile(files[0], tampered, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = VerifyAuditLog(dir, "")
+	var chainErr *AuditChainError
+	if !errors.As(err, &chainErr) || chainErr.Line != 1 || chainErr.Reason != "hash mismatch" {
+		t.Fatalf("tampered log: err = %v", err)
+	}
+
+	// So does deleting one.
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if err := os.WriteFile(files[0], bytes.Join(append(lines[:1:1], lines[2:]...), nil), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyAuditLog(dir, ""); !errors.As(err, &chainErr) || !strings.Contains(chainErr.Reason, "sequence gap") {
+		t.Fatalf("log with deleted record: err = %v", err)
+	}
+}
+
+func TestAuditLogTornTail(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewAuditLog(AuditConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendAuditRecords(t, l, 3, "alice")
+	l.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	data, _ := os.ReadFile(files[0])
+	partial := data[:40]
+
+	reopen := func(name string) {
+		t.Helper()
+		var logged bytes.Buffer
+		l, err := NewAuditLog(AuditConfig{Dir: dir, Logger: slog.New(slog.NewTextHandler(&logged, nil))})
+		if err != nil {
+			t.Fatalf("NewAuditLog with a torn tail: %v", err)
+		}
+		appendAuditRecords(t, l, 1, "bob")
+		l.Close()
+		quarantined, _ := filepath.Glob(name + ".*.torn")
+		if len(quarantined) != 1 {
+			t.Fatalf("quarantine files = %v", quarantined)
+		}
+		if got, _ := os.ReadFile(quarantined[0]); !bytes.Equal(got, partial) {
+			t.Errorf("quarantined %q, want %q", got, partial)
+		}
+		if !strings.Contains(logged.String(), "torn") {
+			t.Errorf("recovery was not logged: %s", logged.String())
+		}
+	}
+
+	// A crash mid-write leaves a partial last line.
+	if err := os.WriteFile(files[0], append(data, partial...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reopen(files[0])
+	// One right after rotating leaves a new file holding only that.
+	next := filepath.Join(dir, "audit-00000000000000000005.jsonl")
+	if err := os.WriteFile(next, partial, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reopen(next)
+
+	report, err := VerifyAuditLog(dir, "")
+	if err != nil {
+		t.Fatalf("VerifyAuditLog: %v", err)
+	}
+	if report.Records != 5 || report.LastSeq != 5 {
+		t.Errorf("report = %+v, want 5 chained records", report)
+	}
+}
+
+func TestAuditLogMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewAuditLog(AuditConfig{Dir: dir, MaxFileSize: 512, MaxFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendAuditRecords(t, l, 20, "alice")
+	l.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	report, err := VerifyAuditLog(dir, "")
+	if err != nil {
+		t.Fatalf("VerifyAuditLog: %v", err)
+	}
+	if !report.Truncated || report.LastSeq != 20 {
+		t.Fatalf("report = %+v, want truncated chain ending at 20", report)
+	}
+}
+
+func TestAuditLogStructErrorData(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewAuditLog(AuditConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendAuditRecords(t, l, 1, "alice")
+	rec := &AuditRecord{
+		Principal: "alice",
+		Method:    "tools/call",
+		Tool:      "echo",
+		Error: &ResponseError{Code: -32000, Message: "failed", Data: struct {
+			Zeta  int
+			Alpha string
+		}{1, "a"}},
+	}
+	if err := l.Append(rec); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	// Data decodes back as a map, whose keys marshal in another order.
+	if _, err := VerifyAuditLog(dir, ""); err != nil {
+		t.Fatalf("VerifyAuditLog: %v", err)
+	}
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	l, err := NewAuditLog(AuditConfig{
+		Dir:         dir,
+		Methods:     []string{"tools/call"},
+		RedactPaths: []string{"params.arguments.apiKey", "result.content[*].text"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer("test-server", "1.0.0", WithTestLogger(t, slog.LevelError))
+	server.Use(NewAuditMiddleware(l, func(err error) { t.Errorf("audit: %v", err) }))
+	if err := server.RegisterTool(Tool{Name: "fetch"},
+		func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+			return &CallToolResult{
+				Content: []any{map[string]string{"type": "text", "text": "secret body"}},
+				IsError: true,
+			}, nil
+		}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newCacheTestPair(t, server)
+	if _, err := client.CallTool(ctx, CallToolRequest{Name: "fetch", Arguments: json.RawMessage(`{"url":"x","apiKey":"k-123"}`)}); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if _, err := client.ListTools(ctx, ListToolsRequest{}); err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	client.Close()
+	l.Close()
+
+	report, err := VerifyAuditLog(dir, "")
+	if err != nil {
+		t.Fatalf("VerifyAuditLog: %v", err)
+	}
+	if report.Records != 1 {
+		t.Fatalf("records = %d, want only the tool call", report.Records)
+	}
+	summary := report.Principals["anonymous"]
+	if summary == nil || summary.Tools["fetch"] != 1 || summary.Failures != 1 {
+		t.Fatalf("anonymous summary = %+v", summary)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	data, _ := os.ReadFile(files[0])
+	for _, secret := range []string{"k-123", "secret body"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("log contains %q:\n%s", secret, data)
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// defaultBatchConcurrency is how many requests of one batch the server runs
+// at once unless WithBatchConcurrency says otherwise.
+const defaultBatchConcurrency = 8
+
+// lastBatchingVersion is the newest protocol revision that allows JSON-RPC
+// batches. 2025-06-18 removed them.
+const lastBatchingVersion = "2025-03-26"
+
+// batchingAllowed reports whether version permits JSON-RPC batches. Before
+// a version is negotiated nothing may be batched: initialize must be sent
+// on its own.
+func batchingAllowed(version string) bool {
+	return version != "" && version <= lastBatchingVersion
+}
+
+// WithBatchConcurrency sets how many requests of a JSON-RPC batch the server
+// runs concurrently. Batches are accepted only when the negotiated protocol
+// version permits them (2025-03-26 and earlier). The default is 8.
+func WithBatchConcurrency(n int) ServerOption {
+	return func(s *Server) {
+		s.batchConcurrency = n
+	}
+}
+
+// errBatchRejected is the reply to a batch the negotiated version forbids.
+func errBatchRejected(version string) *ResponseError {
+	if version == "" {
+		return &ResponseError{Code: -32600, Message: "batch requests are not allowed before initialization"}
+	}
+	return &ResponseError{Code: -32600, Message: fmt.Sprintf("batch requests are not supported in protocol version %s", version)}
+}
+
+// errorReply encodes a response carrying err. A nil id encodes as null, as
+// JSON-RPC requires when the request's ID could not be determined.
+func errorReply(id jsonrpc2.ID, err *ResponseError) json.RawMessage {
+	data, _ := json.Marshal(struct {
+		JSONRPC string         `json:"jsonrpc"`
+		ID      any            `json:"id"`
+		Error   *ResponseError `json:"error"`
+	}{"2.0", id.Raw(), err})
+	return data
+}
+
+// batchConn sits between a transport connection and jsonrpc2, which only
+// understands single messages. Single messages pass through unchanged;
+// JSON arrays go to batch, which may forward elements back into the stream
+// with the given function. Writes are serialized so batch replies do not
+// interleave with jsonrpc2's.
+type batchConn struct {
+	io.ReadWriteCloser
+	dec *json.Decoder
+	buf bytes.Buffer // messages waiting for jsonrpc2 to read
+	wmu sync.Mutex
+
+	// done is closed once reading fails, after which no more replies can
+	// arrive.
+	done     chan struct{}
+	doneOnce sync.Once
+
+	batch func(msgs []json.RawMessage, forward func(json.RawMessage))
+	// single, if set, sees each single message first, even one jsonrpc2
+	// cannot decode, and reports whether it consumed it.
+	single func(json.RawMessage) bool
+	// sending, if set, is told about each write before it is made.
+	sending func([]byte)
+	// wrote, if set, is told about each successful write.
+	wrote func([]byte)
+}
+
+func newBatchConn(rwc io.ReadWriteCloser) *batchConn {
+	return &batchConn{ReadWriteCloser: rwc, dec: json.NewDecoder(rwc), done: make(chan struct{})}
+}
+
+func (c *batchConn) Read(p []byte) (int, error) {
+	for c.buf.Len() == 0 {
+		var raw json.RawMessage
+		if err := c.dec.Decode(&raw); err != nil {
+			c.doneOnce.Do(func() { close(c.done) })
+			return 0, err
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '[' {
+			var msgs []json.RawMessage
+			if err := json.Unmarshal(raw, &msgs); err != nil {
+				return 0, err
+			}
+			c.batch(msgs, c.forward)
+			continue
+		}
+		// single sees the message before it is checked: a batch rejection
+		// has a null ID, which jsonrpc2 cannot decode.
+		if c.single != nil && c.single(raw) {
+			continue
+		}
+		if valid, reply := checkSingle(raw); !valid {
+			if reply != nil {
+				if err := c.writeMessage(reply); err != nil {
+					return 0, err
+				}
+			}
+			continue
+		}
+		c.forward(raw)
+	}
+	return c.buf.Read(p)
+}
+
+// forward queues one message for jsonrpc2. It is only called from Read.
+func (c *batchConn) forward(raw json.RawMessage) {
+	c.buf.Write(raw)
+	c.buf.WriteByte('\n')
+}
+
+func (c *batchConn) Write(p []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.sending != nil {
+		c.sending(p)
+	}
+	n, err := c.ReadWriteCloser.Write(p)
+	if err == nil && c.wrote != nil {
+		c.wrote(p)
+	}
+	return n, err
+}
+
+// writeMessage writes one complete message, or a batch if v is a slice.
+func (c *batchConn) writeMessage(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = c.Write(append(data, '\n'))
+	return err
+}
+
+// batchDialer wraps the connections a Transport dials in a batchConn.
+type batchDialer struct {
+	dialer interface {
+		Dial(context.Context) (io.ReadWriteCloser, error)
+	}
+	bind func(*batchConn)
+}
+
+func (d batchDialer) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	rwc, err := d.dialer.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := newBatchConn(rwc)
+	d.bind(c)
+	return c, nil
+}
+
+// negotiatedVersion returns the protocol version agreed in initialize, or ""
+// before initialization.
+func (s *Server) negotiatedVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.protocolVersion
+}
+
+// checkBatchElement decodes one element of a batch. If the element is not a
+// valid message, or is a request that may not be batched, it returns the
+// error response to answer it with instead.
+func checkBatchElement(raw json.RawMessage) (jsonrpc2.Message, json.RawMessage) {
+	if !integralID(raw) {
+		return nil, errorReply(jsonrpc2.ID{}, &ResponseError{Code: -32600, Message: "invalid request in batch"})
+	}
+	msg, err := jsonrpc2.DecodeMessage(raw)
+	if err != nil {
+		return nil, errorReply(jsonrpc2.ID{}, &ResponseError{Code: -32600, Message: "invalid request in batch"})
+	}
+	if req, ok := msg.(*jsonrpc2.Request); ok && req.Method == string(MethodInitialize) {
+		return nil, errorReply(req.ID, &ResponseError{Code: -32600, Message: "initialize must not be part of a batch"})
+	}
+	return msg, nil
+}
+
+// checkSingle reports whether raw, a message read on its own, is one
+// jsonrpc2 can handle unchanged. If not, it returns the Invalid Request
+// error to answer it with, whose ID is null as JSON-RPC requires, or nil
+// for a malformed response, which is never answered. jsonrpc2 would
+// otherwise close the connection on a message it cannot decode.
+func checkSingle(raw json.RawMessage) (valid bool, reply json.RawMessage) {
+	if integralID(raw) {
+		if _, err := jsonrpc2.DecodeMessage(raw); err == nil {
+			return true, nil
+		}
+	}
+	var shape struct {
+		Method json.RawMessage `json:"method"`
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(raw, &shape) == nil && shape.Method == nil && (shape.Result != nil || shape.Error != nil) {
+		return false, nil
+	}
+	return false, errorReply(jsonrpc2.ID{}, &ResponseError{Code: -32600, Message: "invalid request"})
+}
+
+// serveBatch handles a batch read from a connection. Notifications and
+// responses are forwarded to the connection's normal path; requests run on
+// handler, at most s.batchConcurrency at a time, and their responses are
+// passed to reply in request order once all have finished. Nothing is
+// replied for a batch without requests. A batch the negotiated version
+// forbids is answered with a single error instead.
+func (s *Server) serveBatch(ctx context.Context, handler jsonrpc2.HandlerFunc, msgs []json.RawMessage, forward func(json.RawMessage), reply func(any)) {
+	if version := s.negotiatedVersion(); !batchingAllowed(version) {
+		reply(errorReply(jsonrpc2.ID{}, errBatchRejected(version)))
+		return
+	}
+	if len(msgs) == 0 {
+		reply(errorReply(jsonrpc2.ID{}, &ResponseError{Code: -32600, Message: "empty batch"}))
+		return
+	}
+
+	replies := make([]json.RawMessage, 0, len(msgs))
+	var calls []*jsonrpc2.Request
+	var slots []int
+	for _, raw := range msgs {
+		msg, rejected := checkBatchElement(raw)
+		if rejected != nil {
+			replies = append(replies, rejected)
+			continue
+		}
+		if req, ok := msg.(*jsonrpc2.Request); ok && req.IsCall() {
+			slots = append(slots, len(replies))
+			replies = append(replies, nil)
+			calls = append(calls, req)
+		} else {
+			forward(raw)
+		}
+	}
+	if len(replies) == 0 {
+		return
+	}
+
+	limit := s.batchConcurrency
+	if limit <= 0 {
+		limit = defaultBatchConcurrency
+	}
+	go func() {
+		sem := make(chan struct{}, limit)
+		var wg sync.WaitGroup
+		for i, req := range calls {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				result, err := handler(ctx, req)
+				resp, rerr := jsonrpc2.NewResponse(req.ID, result, err)
+				if rerr != nil {
+					resp, _ = jsonrpc2.NewResponse(req.ID, nil, rerr)
+				}
+				data, err := encodeMessage(resp)
+				if err != nil {
+					data = errorReply(req.ID, &ResponseError{Code: -32603, Message: err.Error()})
+				}
+				replies[slots[i]] = data
+			}()
+		}
+		wg.Wait()
+		reply(replies)
+	}()
+}
+
+// BatchCall is one request of a Client.CallBatch.
+type BatchCall struct {
+	Method string
+	Params any
+	// Result, if not nil, receives the decoded result.
+	Result any
+	// Notify sends the call as a notification, which gets no response.
+	Notify bool
+	// Err is set to the call's error after CallBatch returns, as a
+	// *ResponseError when the server answered with one.
+	Err error
+}
+
+// clientBatches tracks the batches a Client has in flight.
+type clientBatches struct {
+	conn *batchConn
+	seq  atomic.Int64
+
+	mu      sync.Mutex
+	pending map[string]*pendingBatch // by request ID
+	order   []*pendingBatch          // oldest first
+	singles map[string]bool          // canonical IDs of unanswered single requests
+}
+
+type pendingBatch struct {
+	calls map[string]*BatchCall
+	left  int
+	done  chan struct{}
+	err   error
+}
+
+// deliver routes a reply to the batch waiting for it and reports whether it
+// did. A null-ID error is taken as the rejection of a batch only when it
+// can be nothing else: exactly one batch and no single request is
+// outstanding. Otherwise it is left to jsonrpc2.
+func (b *clientBatches) deliver(raw json.RawMessage) bool {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Result json.RawMessage `json:"result"`
+		Error  *ResponseError  `json:"error"`
+	}
+	if json.Unmarshal(raw, &msg) != nil || msg.Method != "" {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(msg.ID) == 0 || string(msg.ID) == "null" {
+		if msg.Error == nil || len(b.order) != 1 || len(b.singles) > 0 {
+			return false
+		}
+		p := b.order[0]
+		p.err = msg.Error
+		b.finish(p)
+		return true
+	}
+	var id string
+	if json.Unmarshal(msg.ID, &id) != nil {
+		return false
+	}
+	p := b.pending[id]
+	if p == nil {
+		return false
+	}
+	delete(b.pending, id)
+	call := p.calls[id]
+	switch {
+	case msg.Error != nil:
+		call.Err = msg.Error
+	case call.Result != nil:
+		if err := json.Unmarshal(msg.Result, call.Result); err != nil {
+			call.Err = fmt.Errorf("decoding %s result: %w", call.Method, err)
+		}
+	}
+	if p.left--; p.left == 0 {
+		b.finish(p)
+	}
+	return true
+}
+
+// finish completes p. It must be called with b.mu held.
+func (b *clientBatches) finish(p *pendingBatch) {
+	for id := range p.calls {
+		delete(b.pending, id)
+	}
+	for i, q := range b.order {
+		if q == p {
+			b.order = append(b.order[:i], b.order[i+1:]...)
+			break
+		}
+	}
+	close(p.done)
+}
+
+// batch handles an array read by the client: replies to CallBatch are
+// delivered, and anything else (such as a batch of server requests) is
+// handed to jsonrpc2 one message at a time.
+func (b *clientBatches) batch(msgs []json.RawMessage, forward func(json.RawMessage)) {
+	for _, raw := range msgs {
+		if !b.deliver(raw) {
+			forward(raw)
+		}
+	}
+}
+
+// single handles a single message read by the client. While a batch is
+// outstanding, replies to it are delivered rather than passed to jsonrpc2.
+func (b *clientBatches) single(raw json.RawMessage) bool {
+	key, isResponse := responseIDKey(raw)
+	b.mu.Lock()
+	if isResponse {
+		delete(b.singles, key)
+	}
+	waiting := len(b.order) > 0
+	b.mu.Unlock()
+	return waiting && b.deliver(raw)
+}
+
+// sending notes the single requests the client writes, so that deliver
+// knows whether a null-ID error could be answering one of them. A request
+// the client cancels may never be answered and is forgotten.
+func (b *clientBatches) sending(p []byte) {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			RequestID json.RawMessage `json:"requestId"`
+		} `json:"params"`
+	}
+	p = bytes.TrimSpace(p)
+	if len(p) == 0 || p[0] != '{' || json.Unmarshal(p, &msg) != nil || msg.Method == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case len(msg.ID) > 0:
+		b.singles[rawIDKey(msg.ID)] = true
+	case msg.Method == string(MethodNotificationCancelled) && len(msg.Params.RequestID) > 0:
+		delete(b.singles, rawIDKey(msg.Params.RequestID))
+	}
+}
+
+// rawIDKey returns the canonicalIDKey of an ID as it appears on the wire.
+func rawIDKey(raw json.RawMessage) string {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var id any
+	if dec.Decode(&id) != nil {
+		return string(raw)
+	}
+	return canonicalIDKey(id)
+}
+
+// CallBatch sends calls to the server as one JSON-RPC batch and waits for
+// every response. It fails without sending anything unless the negotiated
+// protocol version permits batches (2025-03-26 and earlier). The returned
+// error reports a failure of the batch as a whole; the outcome of each call
+// is in its Err field. If ctx ends first, the server is sent
+// notifications/cancelled for each call it has not answered.
+func (c *Client) CallBatch(ctx context.Context, calls []*BatchCall) error {
+	if err := c.checkInitialized(); err != nil {
+		return err
+	}
+	c.initMu.RLock()
+	version := c.protocolVersion
+	c.initMu.RUnlock()
+	if !batchingAllowed(version) {
+		return fmt.Errorf("batch requests are not supported in protocol version %s", version)
+	}
+	if len(calls) == 0 {
+		return errors.New("empty batch")
+	}
+	b := c.batches
+	if b == nil {
+		return errors.New("client connection is not established")
+	}
+
+	seq := b.seq.Add(1)
+	p := &pendingBatch{calls: make(map[string]*BatchCall), done: make(chan struct{})}
+	msgs := make([]any, len(calls))
+	for i, call := range calls {
+		call.Err = nil
+		var params json.RawMessage
+		if call.Params != nil {
+			data, err := json.Marshal(call.Params)
+			if err != nil {
+				return fmt.Errorf("marshaling %s params: %w", call.Method, err)
+			}
+			params = data
+		}
+		msg := struct {
+			JSONRPC string          `json:"jsonrpc"`
+			ID      string          `json:"id,omitempty"`
+			Method  string          `json:"method"`
+			Params  json.RawMessage `json:"params,omitempty"`
+		}{JSONRPC: "2.0", Method: call.Method, Params: params}
+		if !call.Notify {
+			msg.ID = "batch-" + strconv.FormatInt(seq, 10) + "-" + strconv.Itoa(i)
+			p.calls[msg.ID] = call
+			p.left++
+		}
+		msgs[i] = msg
+	}
+
+	waiting := p.left > 0
+	if waiting {
+		b.mu.Lock()
+		for id := range p.calls {
+			b.pending[id] = p
+		}
+		b.order = append(b.order, p)
+		b.mu.Unlock()
+	}
+	if err := b.conn.writeMessage(msgs); err != nil {
+		b.cancel(p, err)
+		return err
+	}
+	if !waiting {
+		return nil
+	}
+	select {
+	case <-p.done:
+		return p.err
+	case <-b.conn.done:
+		err := errors.New("connection closed")
+		b.cancel(p, err)
+		return err
+	case <-ctx.Done():
+		cause := context.Cause(ctx)
+		for _, id := range b.cancel(p, ctx.Err()) {
+			params := map[string]any{"requestId": id}
+			if cause != context.Canceled {
+				params["reason"] = cause.Error()
+			}
+			_ = c.conn.Notify(context.Background(), string(MethodNotificationCancelled), params)
+		}
+		return ctx.Err()
+	}
+}
+
+// cancel abandons p, failing the calls that have not been answered with err,
+// and returns their IDs.
+func (b *clientBatches) cancel(p *pendingBatch, err error) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	select {
+	case <-p.done:
+		return nil
+	default:
+	}
+	var unanswered []string
+	for id, call := range p.calls {
+		if b.pending[id] == p {
+			call.Err = err
+			unanswered = append(unanswered, id)
+		}
+	}
+	sort.Strings(unanswered)
+	b.finish(p)
+	return unanswered
+}
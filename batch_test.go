@@ -0,0 +1,372 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newBatchServer returns a server with a "sleep" tool that waits for the
+// given number of milliseconds and records how many calls overlapped.
+func newBatchServer(t *testing.T, opts ...ServerOption) (*Server, *atomic.Int32) {
+	t.Helper()
+	server := NewServer("batch", "1.0", opts...)
+	var running, peak atomic.Int32
+	err := server.RegisterTool(Tool{Name: "sleep", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+			var args struct{ MS int }
+			json.Unmarshal(req.Arguments, &args)
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Duration(args.MS) * time.Millisecond)
+			return &CallToolResult{Content: []any{TextContent{Type: "text", Text: "slept"}}}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, &peak
+}
+
+// rawSession serves server over a pipe and initializes it at version,
+// returning the client end of the pipe for raw JSON-RPC.
+func rawSession(t *testing.T, server *Server, version string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	go func() { _ = server.Serve(context.Background(), &ReadWriteCloserTransport{serverConn}) }()
+	t.Cleanup(func() { clientConn.Close() })
+	clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewReader(clientConn)
+	writeLine(t, clientConn, `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"`+version+`","capabilities":{},"clientInfo":{"name":"raw","version":"1.0"}}}`)
+	if line := readLine(t, r); !strings.Contains(line, `"protocolVersion":"`+version+`"`) {
+		t.Fatalf("initialize response = %s", line)
+	}
+	writeLine(t, clientConn, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	return clientConn, r
+}
+
+func writeLine(t *testing.T, w io.Writer, s string) {
+	t.Helper()
+	if _, err := io.WriteString(w, s+"\n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readLine returns the next line from r that is not a server notification.
+func readLine(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if !strings.HasPrefix(line, `{"jsonrpc":"2.0","method":`) {
+			return line
+		}
+	}
+}
+
+type batchReply struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *ResponseError  `json:"error"`
+}
+
+func TestServerBatch(t *testing.T) {
+	server, peak := newBatchServer(t, WithBatchConcurrency(2))
+	conn, r := rawSession(t, server, "2025-03-26")
+
+	writeLine(t, conn, `[
+		{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"sleep","arguments":{"MS":100}}},
+		{"jsonrpc":"2.0","method":"notifications/roots/list_changed"},
+		{"jsonrpc":"2.0","id":"b","method":"tools/call","params":{"name":"sleep","arguments":{"MS":1}}},
+		{"jsonrpc":"2.0","id":"c","method":"tools/call","params":{"name":"sleep","arguments":{"MS":1}}},
+		{"jsonrpc":"1.0","id":3,"method":"ping"},
+		{"jsonrpc":"2.0","id":4,"method":"initialize","params":{}},
+		{"jsonrpc":"2.0","id":5,"method":"nope"}
+	]`)
+	var replies []batchReply
+	if line := readLine(t, r); json.Unmarshal([]byte(line), &replies) != nil {
+		t.Fatalf("batch response is not an array: %s", line)
+	}
+	want := []struct {
+		id   string
+		code int
+	}{{"1", 0}, {`"b"`, 0}, {`"c"`, 0}, {"null", -32600}, {"4", -32600}, {"5", -32601}}
+	if len(replies) != len(want) {
+		t.Fatalf("got %d responses, want %d: %+v", len(replies), len(want), replies)
+	}
+	for i, w := range want {
+		got := replies[i]
+		if string(got.ID) != w.id {
+			t.Errorf("response %d has id %s, want %s", i, got.ID, w.id)
+		}
+		switch {
+		case w.code == 0 && (got.Error != nil || got.Result == nil):
+			t.Errorf("response %d = %+v, want a result", i, got)
+		case w.code != 0 && (got.Error == nil || got.Error.Code != w.code):
+			t.Errorf("response %d = %+v, want error %d", i, got, w.code)
+		}
+	}
+	if p := peak.Load(); p != 2 {
+		t.Errorf("at most %d calls ran at once, want 2", p)
+	}
+
+	// A batch of notifications gets no response.
+	writeLine(t, conn, `[{"jsonrpc":"2.0","method":"notifications/roots/list_changed"}]`)
+	writeLine(t, conn, `{"jsonrpc":"2.0","id":6,"method":"ping"}`)
+	if line := readLine(t, r); !strings.HasPrefix(line, `{"jsonrpc":"2.0","id":6,`) {
+		t.Errorf("after notification batch got %s, want the ping response", line)
+	}
+}
+
+func TestServerBatchRejected(t *testing.T) {
+	server, _ := newBatchServer(t)
+	conn, r := rawSession(t, server, LATEST_PROTOCOL_VERSION)
+
+	writeLine(t, conn, `[{"jsonrpc":"2.0","id":1,"method":"ping"}]`)
+	var reply batchReply
+	if line := readLine(t, r); json.Unmarshal([]byte(line), &reply) != nil || string(reply.ID) != "null" || reply.Error == nil || reply.Error.Code != -32600 {
+		t.Fatalf("batch under %s answered %s, want a -32600 error with null id", LATEST_PROTOCOL_VERSION, line)
+	}
+
+	writeLine(t, conn, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	if line := readLine(t, r); !strings.HasPrefix(line, `{"jsonrpc":"2.0","id":2,`) {
+		t.Errorf("after rejected batch got %s, want the ping response", line)
+	}
+}
+
+func TestServerInvalidRequest(t *testing.T) {
+	server, _ := newBatchServer(t)
+	conn, r := rawSession(t, server, LATEST_PROTOCOL_VERSION)
+
+	for _, msg := range []string{
+		`{"jsonrpc":"1.0","id":1,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":1.5,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":{"x":1},"method":"ping"}`,
+		`42`,
+	} {
+		writeLine(t, conn, msg)
+		var reply batchReply
+		if line := readLine(t, r); json.Unmarshal([]byte(line), &reply) != nil || string(reply.ID) != "null" || reply.Error == nil || reply.Error.Code != -32600 {
+			t.Errorf("%s answered %s, want a -32600 error with null id", msg, line)
+		}
+	}
+
+	// A malformed response is dropped without an answer.
+	writeLine(t, conn, `{"jsonrpc":"2.0","result":{}}`)
+	writeLine(t, conn, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	if line := readLine(t, r); !strings.HasPrefix(line, `{"jsonrpc":"2.0","id":2,`) {
+		t.Errorf("after invalid messages got %s, want the ping response", line)
+	}
+}
+
+func TestClientCallBatch(t *testing.T) {
+	server, _ := newBatchServer(t)
+	clientConn, serverConn := net.Pipe()
+	go func() { _ = server.Serve(context.Background(), &ReadWriteCloserTransport{serverConn}) }()
+	client, err := NewClient(&ReadWriteCloserTransport{clientConn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	if err := client.CallBatch(ctx, []*BatchCall{{Method: "ping"}}); err == nil {
+		t.Error("CallBatch before Initialize succeeded")
+	}
+	if _, err := client.Initialize(ctx, InitializeRequest{ProtocolVersion: "2025-03-26", ClientInfo: Implementation{Name: "c", Version: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var tools ListToolsResult
+	var called CallToolResult
+	calls := []*BatchCall{
+		{Method: "tools/list", Result: &tools},
+		{Method: "notifications/roots/list_changed", Notify: true},
+		{Method: "tools/call", Params: CallToolRequest{Name: "sleep", Arguments: json.RawMessage(`{"MS":1}`)}, Result: &called},
+		{Method: "nope"},
+	}
+	if err := client.CallBatch(ctx, calls); err != nil {
+		t.Fatal(err)
+	}
+	if calls[0].Err != nil || len(tools.Tools) != 1 {
+		t.Errorf("tools/list = %+v, %v", tools, calls[0].Err)
+	}
+	if calls[2].Err != nil || len(called.Content) != 1 {
+		t.Errorf("tools/call = %+v, %v", called, calls[2].Err)
+	}
+	var rerr *ResponseError
+	if !errors.As(calls[3].Err, &rerr) || rerr.Code != -32601 {
+		t.Errorf("unknown method error = %v, want -32601", calls[3].Err)
+	}
+
+	// Ordinary calls still work alongside batches.
+	if _, err := client.ListTools(ctx, ListToolsRequest{}); err != nil {
+		t.Errorf("ListTools after batch: %v", err)
+	}
+}
+
+// rawClient returns a client initialized at 2025-03-26 against a fake
+// server the test drives through the returned connection and reader.
+func rawClient(t *testing.T) (*Client, net.Conn, *bufio.Reader) {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	client, err := NewClient(&ReadWriteCloserTransport{clientConn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close(); serverConn.Close() })
+	serverConn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(serverConn)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Initialize(context.Background(), InitializeRequest{ProtocolVersion: "2025-03-26", ClientInfo: Implementation{Name: "c", Version: "1"}})
+		done <- err
+	}()
+	id := requestID(t, readLine(t, r))
+	writeLine(t, serverConn, `{"jsonrpc":"2.0","id":`+id+`,"result":{"protocolVersion":"2025-03-26","capabilities":{},"serverInfo":{"name":"raw","version":"1"}}}`)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	return client, serverConn, r
+}
+
+func requestID(t *testing.T, line string) string {
+	t.Helper()
+	var msg struct{ ID json.RawMessage }
+	if err := json.Unmarshal([]byte(line), &msg); err != nil || len(msg.ID) == 0 {
+		t.Fatalf("got %s, want a request", line)
+	}
+	return string(msg.ID)
+}
+
+func TestClientCallBatchNullIDError(t *testing.T) {
+	client, conn, r := rawClient(t)
+	ctx := context.Background()
+	const nullError = `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request"}}`
+
+	// With a single request outstanding the error may be answering it, so
+	// the batch keeps waiting for its own reply.
+	pinged := make(chan error, 1)
+	go func() { pinged <- client.Ping(ctx) }()
+	pingID := requestID(t, readLine(t, r))
+	batched := make(chan error, 1)
+	calls := []*BatchCall{{Method: "ping"}}
+	go func() { batched <- client.CallBatch(ctx, calls) }()
+	readLine(t, r)
+	writeLine(t, conn, nullError)
+	writeLine(t, conn, `[{"jsonrpc":"2.0","id":"batch-1-0","result":{}}]`)
+	if err := <-batched; err != nil || calls[0].Err != nil {
+		t.Errorf("CallBatch with a single request outstanding = %v, %v; want success", err, calls[0].Err)
+	}
+	writeLine(t, conn, `{"jsonrpc":"2.0","id":`+pingID+`,"result":{}}`)
+	if err := <-pinged; err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+
+	// With nothing else outstanding it rejects the batch.
+	go func() { batched <- client.CallBatch(ctx, calls) }()
+	readLine(t, r)
+	writeLine(t, conn, nullError)
+	var rerr *ResponseError
+	if err := <-batched; !errors.As(err, &rerr) || rerr.Code != -32600 {
+		t.Errorf("CallBatch = %v, want the -32600 rejection", err)
+	}
+}
+
+func TestClientCallBatchCancel(t *testing.T) {
+	client, conn, r := rawClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := []*BatchCall{{Method: "ping"}, {Method: "ping"}, {Method: "ping"}}
+	batched := make(chan error, 1)
+	go func() { batched <- client.CallBatch(ctx, calls) }()
+	readLine(t, r)
+	writeLine(t, conn, `[{"jsonrpc":"2.0","id":"batch-1-1","result":{}}]`)
+	for !batchAnswered(client, "batch-1-1") {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	for _, id := range []string{"batch-1-0", "batch-1-2"} {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `"params":{"requestId":"` + id + `"}`; !strings.Contains(line, `"method":"notifications/cancelled"`) || !strings.Contains(line, want) {
+			t.Errorf("got %s, want notifications/cancelled for %s", line, id)
+		}
+	}
+	if err := <-batched; !errors.Is(err, context.Canceled) {
+		t.Errorf("CallBatch = %v, want context.Canceled", err)
+	}
+	if calls[0].Err == nil || calls[1].Err != nil || calls[2].Err == nil {
+		t.Errorf("call errors = %v, %v, %v; want only the unanswered calls to fail", calls[0].Err, calls[1].Err, calls[2].Err)
+	}
+}
+
+// batchAnswered reports whether the reply to the batch call id has been
+// delivered.
+func batchAnswered(c *Client, id string) bool {
+	c.batches.mu.Lock()
+	defer c.batches.mu.Unlock()
+	_, waiting := c.batches.pending[id]
+	return !waiting
+}
+
+func TestClientCallBatchLatestVersion(t *testing.T) {
+	server, _ := newBatchServer(t)
+	client := newCacheTestPair(t, server)
+	if err := client.CallBatch(context.Background(), []*BatchCall{{Method: "ping"}}); err == nil {
+		t.Errorf("CallBatch under %s succeeded", LATEST_PROTOCOL_VERSION)
+	}
+}
+
+func TestStreamableHTTPBatch(t *testing.T) {
+	server, _ := newBatchServer(t)
+	httpServer := httptest.NewServer(NewStreamableHTTPHandler(func(*http.Request) *Server {
+		return server
+	}, nil))
+	defer httpServer.Close()
+	url := httpServer.URL + "/mcp"
+
+	// Without a session there is no version that permits batching.
+	resp, err := http.Post(url, "application/json", strings.NewReader(`[{"jsonrpc":"2.0","id":1,"method":"ping"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), `"code":-32600`) {
+		t.Fatalf("batch without session = %d %s, want 400 with -32600", resp.StatusCode, body)
+	}
+
+	sessionID, _ := postStreamable(t, url, "", `{"jsonrpc":"2.0","id":"init","method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`)
+	_, got := postStreamable(t, url, sessionID, `[
+		{"jsonrpc":"2.0","id":1,"method":"ping"},
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		{"jsonrpc":"2.0","id":"tools","method":"tools/list"}
+	]`)
+	ids := map[any]bool{}
+	for _, msg := range got {
+		ids[msg.ID] = true
+	}
+	if len(got) != 2 || !ids[float64(1)] || !ids["tools"] {
+		t.Errorf("batch responses = %+v, want ids 1 and tools", got)
+	}
+}
@@ -0,0 +1,370 @@
+// Package mcp - Bulkheads
+// This file implements per-tool and per-session concurrency limits for
+// tools/call. Calls beyond a limit wait in a bounded priority queue, are told
+// about the wait through progress notifications, and are rejected with a
+// retryable error when the queue is full or the wait times out. A per-tool
+// circuit breaker can shed calls to a failing tool before they queue.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// BulkheadConfig configures a BulkheadMiddleware. Limits of zero or less are
+// unlimited.
+type BulkheadConfig struct {
+	ToolLimits       map[string]int `json:"toolLimits" yaml:"toolLimits"`             // Concurrent calls allowed per tool
+	DefaultToolLimit int            `json:"defaultToolLimit" yaml:"defaultToolLimit"` // Limit of tools missing from ToolLimits
+	SessionLimit     int            `json:"sessionLimit" yaml:"sessionLimit"`         // Concurrent tool calls allowed per session
+
+	QueueSize        int           `json:"queueSize" yaml:"queueSize"`               // Calls that may wait per tool (default 64)
+	QueueTimeout     time.Duration `json:"queueTimeout" yaml:"queueTimeout"`         // Longest a call waits before rejection (default 30s)
+	RetryAfter       time.Duration `json:"retryAfter" yaml:"retryAfter"`             // Retry hint sent with rejections (default 1s)
+	ProgressInterval time.Duration `json:"progressInterval" yaml:"progressInterval"` // Gap between queue progress notifications (default 1s)
+
+	// Priority orders waiting calls; higher values run first and equal
+	// values in arrival order. The default gives every call priority 0.
+	Priority func(ctx context.Context, req MCPRequest) int `json:"-" yaml:"-"`
+
+	// SessionKey names the session a call counts against. The default is
+	// the streamable HTTP session, or the connection for other transports.
+	SessionKey func(ctx context.Context, req MCPRequest) string `json:"-" yaml:"-"`
+
+	// BreakerThreshold, if positive, opens a tool's circuit after that many
+	// consecutive failures; see NewToolCircuitBreaker.
+	BreakerThreshold  int           `json:"breakerThreshold" yaml:"breakerThreshold"`
+	BreakerResetAfter time.Duration `json:"breakerResetAfter" yaml:"breakerResetAfter"` // How long a circuit stays open (default 30s)
+
+	// Metrics receives circuit breaker rejections, and queue depths and
+	// queue rejections if it implements BulkheadMetricsRegistry.
+	Metrics MetricsRegistry `json:"-" yaml:"-"`
+}
+
+// BulkheadMetricsRegistry is implemented by metrics registries that also
+// track bulkhead queues. reason is "queue_full" or "queue_timeout".
+type BulkheadMetricsRegistry interface {
+	RecordQueueDepth(tool string, depth int)
+	RecordBulkheadRejection(tool string, reason string)
+}
+
+// BulkheadMiddleware limits how many tools/call requests run at once, per
+// tool and per session, so one slow tool or busy client cannot starve the
+// rest. Other methods pass through.
+//
+// A waiting call whose request carries a progress token receives
+// notifications/progress while it waits, with progress values below 1 and a
+// message giving its place in the queue. The middleware must be added with
+// Server.Use for these to be sent.
+type BulkheadMiddleware struct {
+	config  BulkheadConfig
+	breaker *CustomCircuitBreakerMiddleware
+	metrics BulkheadMetricsRegistry
+
+	mu       sync.Mutex
+	server   *Server
+	running  map[string]int // calls running, by tool
+	sessions map[string]int // calls running, by session
+	queued   map[string]int // calls waiting, by tool
+	waiters  []*bulkheadWaiter
+	seq      uint64
+}
+
+// bulkheadWaiter is a call waiting for a slot.
+type bulkheadWaiter struct {
+	tool     string
+	session  string
+	priority int
+	seq      uint64
+	ready    chan struct{} // closed when the call is granted a slot
+	granted  bool
+}
+
+// NewBulkheadMiddleware creates a bulkhead from config.
+func NewBulkheadMiddleware(config BulkheadConfig) *BulkheadMiddleware {
+	if config.QueueSize <= 0 {
+		config.QueueSize = 64
+	}
+	if config.QueueTimeout <= 0 {
+		config.QueueTimeout = 30 * time.Second
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+	if config.ProgressInterval <= 0 {
+		config.ProgressInterval = time.Second
+	}
+	if config.BreakerResetAfter <= 0 {
+		config.BreakerResetAfter = 30 * time.Second
+	}
+	if config.SessionKey == nil {
+		config.SessionKey = func(ctx context.Context, req MCPRequest) string {
+			session, _ := ctx.Value(sessionKey).(string)
+			return session
+		}
+	}
+	m := &BulkheadMiddleware{
+		config:   config,
+		running:  make(map[string]int),
+		sessions: make(map[string]int),
+		queued:   make(map[string]int),
+	}
+	if config.BreakerThreshold > 0 {
+		m.breaker = NewToolCircuitBreaker(config.BreakerThreshold, config.BreakerResetAfter, config.Metrics)
+	}
+	if r, ok := config.Metrics.(BulkheadMetricsRegistry); ok {
+		m.metrics = r
+	}
+	return m
+}
+
+func (m *BulkheadMiddleware) attach(s *Server) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.server = s
+}
+
+func (m *BulkheadMiddleware) observeNotification(Method, any) {}
+
+func (m *BulkheadMiddleware) Apply(next MCPHandler) MCPHandler {
+	limited := MCPHandlerFunc(func(ctx context.Context, req MCPRequest) (MCPResponse, error) {
+		tool := toolName(req)
+		if tool == "" {
+			return next.Handle(ctx, req)
+		}
+		release, resp, err := m.acquire(ctx, req, tool)
+		if release == nil {
+			return resp, err
+		}
+		defer release()
+		return next.Handle(ctx, req)
+	})
+	if m.breaker != nil {
+		// The breaker goes outside the queue so calls to an open circuit
+		// are refused without waiting.
+		return m.breaker.Apply(limited)
+	}
+	return limited
+}
+
+func (m *BulkheadMiddleware) Name() string {
+	return "bulkhead"
+}
+
+func (m *BulkheadMiddleware) Priority() int {
+	return 750 // After rate limiting, before timeouts so queue waits are not charged
+}
+
+// toolLimit returns the concurrency limit of tool, or 0 if unlimited.
+func (m *BulkheadMiddleware) toolLimit(tool string) int {
+	if limit, ok := m.config.ToolLimits[tool]; ok {
+		return limit
+	}
+	return m.config.DefaultToolLimit
+}
+
+// fits reports whether a call of tool on session may start now. It must be
+// called with m.mu held.
+func (m *BulkheadMiddleware) fits(tool, session string) bool {
+	if limit := m.toolLimit(tool); limit > 0 && m.running[tool] >= limit {
+		return false
+	}
+	return m.config.SessionLimit <= 0 || m.sessions[session] < m.config.SessionLimit
+}
+
+// start counts a call as running. It must be called with m.mu held.
+func (m *BulkheadMiddleware) start(tool, session string) {
+	m.running[tool]++
+	m.sessions[session]++
+}
+
+// finish counts a call as done and hands its slot on.
+func (m *BulkheadMiddleware) finish(tool, session string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running[tool]--; m.running[tool] == 0 {
+		delete(m.running, tool)
+	}
+	if m.sessions[session]--; m.sessions[session] == 0 {
+		delete(m.sessions, session)
+	}
+	m.dispatch()
+}
+
+// dispatch grants slots to waiters, in queue order, that now fit. It must
+// be called with m.mu held.
+func (m *BulkheadMiddleware) dispatch() {
+	kept := m.waiters[:0]
+	for _, w := range m.waiters {
+		if !m.fits(w.tool, w.session) {
+			kept = append(kept, w)
+			continue
+		}
+		w.granted = true
+		m.start(w.tool, w.session)
+		m.dequeued(w.tool)
+		close(w.ready)
+	}
+	clear(m.waiters[len(kept):])
+	m.waiters = kept
+}
+
+// dequeued records that a waiter of tool left the queue. It must be called
+// with m.mu held.
+func (m *BulkheadMiddleware) dequeued(tool string) {
+	if m.queued[tool]--; m.queued[tool] == 0 {
+		delete(m.queued, tool)
+	}
+	if m.metrics != nil {
+		m.metrics.RecordQueueDepth(tool, m.queued[tool])
+	}
+}
+
+// position returns w's 1-based place in the queue among waiters for the
+// same tool, and their number, or 0 once it has left the queue.
+func (m *BulkheadMiddleware) position(w *bulkheadWaiter) (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ahead := 0
+	for _, q := range m.waiters {
+		if q == w {
+			return ahead + 1, m.queued[w.tool]
+		}
+		if q.tool == w.tool {
+			ahead++
+		}
+	}
+	return 0, 0
+}
+
+// reject records a rejection and returns the retryable error for it.
+func (m *BulkheadMiddleware) reject(tool, reason, message string) MCPResponse {
+	if m.metrics != nil {
+		m.metrics.RecordBulkheadRejection(tool, reason)
+	}
+	return NewRateLimitErrorWithRetry(message, m.config.RetryAfter)
+}
+
+// acquire waits for a slot for a call of tool. It returns the function that
+// frees the slot, or else the response or error to answer the call with.
+func (m *BulkheadMiddleware) acquire(ctx context.Context, req MCPRequest, tool string) (func(), MCPResponse, error) {
+	session := m.config.SessionKey(ctx, req)
+	priority := 0
+	if m.config.Priority != nil {
+		priority = m.config.Priority(ctx, req)
+	}
+
+	m.mu.Lock()
+	release := func() { m.finish(tool, session) }
+	if m.fits(tool, session) {
+		m.start(tool, session)
+		m.mu.Unlock()
+		return release, nil, nil
+	}
+	if m.queued[tool] >= m.config.QueueSize {
+		m.mu.Unlock()
+		return nil, m.reject(tool, "queue_full", fmt.Sprintf("Tool %q is busy and its queue is full", tool)), nil
+	}
+	m.seq++
+	w := &bulkheadWaiter{tool: tool, session: session, priority: priority, seq: m.seq, ready: make(chan struct{})}
+	i := sort.Search(len(m.waiters), func(i int) bool {
+		q := m.waiters[i]
+		return q.priority < w.priority || (q.priority == w.priority && q.seq > w.seq)
+	})
+	m.waiters = append(m.waiters, nil)
+	copy(m.waiters[i+1:], m.waiters[i:])
+	m.waiters[i] = w
+	m.queued[tool]++
+	if m.metrics != nil {
+		m.metrics.RecordQueueDepth(tool, m.queued[tool])
+	}
+	server := m.server
+	m.mu.Unlock()
+
+	token := progressToken(req)
+	begun := time.Now()
+	notify := func() {
+		if server == nil || token == nil {
+			return
+		}
+		pos, n := m.position(w)
+		if pos == 0 {
+			return
+		}
+		waited := float64(time.Since(begun)) / float64(m.config.QueueTimeout)
+		err := server.notify(ctx, MethodProgress, ProgressNotification{
+			ProgressToken: token,
+			Progress:      min(waited, 0.99),
+			Message:       fmt.Sprintf("Queued for tool %q: %d of %d waiting", tool, pos, n),
+		})
+		if err != nil {
+			server.logger.Debug("failed to send queue progress", "tool", tool, "error", err)
+		}
+	}
+	notify()
+
+	timeout := time.NewTimer(m.config.QueueTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(m.config.ProgressInterval)
+	defer ticker.Stop()
+	var resp MCPResponse
+	var err error
+wait:
+	for {
+		select {
+		case <-w.ready:
+			break wait
+		case <-ticker.C:
+			notify()
+			continue
+		case <-timeout.C:
+			resp = m.reject(tool, "queue_timeout", fmt.Sprintf("Timed out waiting for tool %q", tool))
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		m.mu.Lock()
+		if w.granted {
+			// The slot arrived as the wait ended; use it.
+			m.mu.Unlock()
+			break wait
+		}
+		for i, q := range m.waiters {
+			if q == w {
+				m.waiters = append(m.waiters[:i], m.waiters[i+1:]...)
+				break
+			}
+		}
+		m.dequeued(tool)
+		m.mu.Unlock()
+		return nil, resp, err
+	}
+	return release, nil, nil
+}
+
+// progressToken returns the progress token in the _meta of req's params, or
+// nil if it has none.
+func progressToken(req MCPRequest) any {
+	var params struct {
+		Meta struct {
+			ProgressToken any `json:"progressToken"`
+		} `json:"_meta"`
+	}
+	if json.Unmarshal(req.Params(), &params) != nil {
+		return nil
+	}
+	return params.Meta.ProgressToken
+}
+
+// sessionHandler records the session requests arrive on in their context,
+// where bulkheads find it.
+func sessionHandler(handler jsonrpc2.HandlerFunc, session string) jsonrpc2.HandlerFunc {
+	return func(ctx context.Context, req *jsonrpc2.Request) (interface{}, error) {
+		return handler(context.WithValue(ctx, sessionKey, session), req)
+	}
+}
@@ -0,0 +1,288 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bulkheadMetrics records what a bulkhead reports.
+type bulkheadMetrics struct {
+	MockMetricsRegistry
+	mu         sync.Mutex
+	depths     map[string]int
+	rejections map[string]int
+	errors     []string
+}
+
+func (m *bulkheadMetrics) RecordError(method, errorType string, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors = append(m.errors, errorType+":"+labels["tool"])
+}
+
+func (m *bulkheadMetrics) RecordQueueDepth(tool string, depth int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.depths[tool] = depth
+}
+
+func (m *bulkheadMetrics) RecordBulkheadRejection(tool, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rejections[tool+":"+reason]++
+}
+
+func (m *bulkheadMetrics) depth(tool string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.depths[tool]
+}
+
+// gatedTools is a handler whose tool calls block until released, recording
+// the order in which they started.
+type gatedTools struct {
+	mu      sync.Mutex
+	started []string
+	release map[string]chan struct{}
+}
+
+func (g *gatedTools) gate(label string) chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.release == nil {
+		g.release = make(map[string]chan struct{})
+	}
+	if g.release[label] == nil {
+		g.release[label] = make(chan struct{})
+	}
+	return g.release[label]
+}
+
+func (g *gatedTools) order() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.started...)
+}
+
+func (g *gatedTools) Handle(ctx context.Context, req MCPRequest) (MCPResponse, error) {
+	var params struct {
+		Name      string
+		Arguments struct{ Label string }
+	}
+	json.Unmarshal(req.Params(), &params)
+	g.mu.Lock()
+	g.started = append(g.started, params.Arguments.Label)
+	g.mu.Unlock()
+	<-g.gate(params.Arguments.Label)
+	return &successResponse{result: &CallToolResult{}}, nil
+}
+
+// callAsync calls tool through handler on ctx and sends the response.
+func callAsync(ctx context.Context, handler MCPHandler, tool, label string) <-chan MCPResponse {
+	done := make(chan MCPResponse, 1)
+	go func() {
+		resp, err := handler.Handle(ctx, &UnifiedRequest{
+			method: string(MethodToolsCall),
+			params: []byte(fmt.Sprintf(`{"name":%q,"arguments":{"label":%q}}`, tool, label)),
+			ctx:    ctx,
+		})
+		if err != nil {
+			resp = &errorResponse{err: &ResponseError{Code: -1, Message: err.Error()}}
+		}
+		done <- resp
+	}()
+	return done
+}
+
+func TestBulkheadToolLimit(t *testing.T) {
+	metrics := &bulkheadMetrics{depths: map[string]int{}, rejections: map[string]int{}}
+	tools := &gatedTools{}
+	handler := NewBulkheadMiddleware(BulkheadConfig{
+		ToolLimits:   map[string]int{"query": 1},
+		QueueSize:    1,
+		QueueTimeout: 100 * time.Millisecond,
+		RetryAfter:   2 * time.Second,
+		Metrics:      metrics,
+	}).Apply(tools)
+	ctx := context.Background()
+
+	first := callAsync(ctx, handler, "query", "q1")
+	waitFor(t, "first query to start", func() bool { return len(tools.order()) == 1 })
+	queued := callAsync(ctx, handler, "query", "q2")
+	waitFor(t, "second query to queue", func() bool { return metrics.depth("query") == 1 })
+
+	// The queue is full, but other tools are not held up.
+	if resp := <-callAsync(ctx, handler, "query", "q3"); !resp.IsError() || resp.Error().Code != -32001 {
+		t.Errorf("call to a full queue = %+v, want a -32001 rejection", resp)
+	}
+	close(tools.gate("other"))
+	if resp := <-callAsync(ctx, handler, "other", "other"); resp.IsError() {
+		t.Errorf("call to another tool = %v", resp.Error())
+	}
+
+	// The queued call times out with a retry hint.
+	resp := <-queued
+	if !resp.IsError() {
+		t.Fatal("queued call ran while the tool was busy")
+	}
+	if d, ok := RetryAfterFromError(resp.Error()); !ok || d != 2*time.Second {
+		t.Errorf("RetryAfterFromError = %v, %v; want 2s", d, ok)
+	}
+	if got := metrics.depth("query"); got != 0 {
+		t.Errorf("queue depth after timeout = %d, want 0", got)
+	}
+	if metrics.rejections["query:queue_full"] != 1 || metrics.rejections["query:queue_timeout"] != 1 {
+		t.Errorf("rejections = %v, want one queue_full and one queue_timeout", metrics.rejections)
+	}
+
+	// Once the slot frees, a new call runs.
+	close(tools.gate("q1"))
+	<-first
+	close(tools.gate("q4"))
+	if resp := <-callAsync(ctx, handler, "query", "q4"); resp.IsError() {
+		t.Errorf("call after the slot freed = %v", resp.Error())
+	}
+}
+
+func TestBulkheadPriority(t *testing.T) {
+	metrics := &bulkheadMetrics{depths: map[string]int{}, rejections: map[string]int{}}
+	tools := &gatedTools{}
+	handler := NewBulkheadMiddleware(BulkheadConfig{
+		DefaultToolLimit: 1,
+		Priority: func(ctx context.Context, req MCPRequest) int {
+			if strings.Contains(string(req.Params()), "urgent") {
+				return 10
+			}
+			return 0
+		},
+		Metrics: metrics,
+	}).Apply(tools)
+	ctx := context.Background()
+
+	var done []<-chan MCPResponse
+	done = append(done, callAsync(ctx, handler, "query", "running"))
+	waitFor(t, "first call to start", func() bool { return len(tools.order()) == 1 })
+	for i, label := range []string{"low1", "low2", "urgent"} {
+		done = append(done, callAsync(ctx, handler, "query", label))
+		waitFor(t, label+" to queue", func() bool { return metrics.depth("query") == i+1 })
+	}
+	for _, label := range []string{"running", "urgent", "low1", "low2"} {
+		close(tools.gate(label))
+	}
+	for _, d := range done {
+		if resp := <-d; resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+	}
+	if got, want := strings.Join(tools.order(), " "), "running urgent low1 low2"; got != want {
+		t.Errorf("calls ran in order %q, want %q", got, want)
+	}
+}
+
+func TestBulkheadSessionLimit(t *testing.T) {
+	tools := &gatedTools{}
+	handler := NewBulkheadMiddleware(BulkheadConfig{SessionLimit: 1, QueueTimeout: 50 * time.Millisecond}).Apply(tools)
+	alice := context.WithValue(context.Background(), sessionKey, "alice")
+	bob := context.WithValue(context.Background(), sessionKey, "bob")
+
+	first := callAsync(alice, handler, "a", "alice1")
+	waitFor(t, "alice's first call to start", func() bool { return len(tools.order()) == 1 })
+	if resp := <-callAsync(alice, handler, "b", "alice2"); !resp.IsError() {
+		t.Error("second call in a full session ran")
+	}
+	close(tools.gate("bob"))
+	if resp := <-callAsync(bob, handler, "a", "bob"); resp.IsError() {
+		t.Errorf("call in another session = %v", resp.Error())
+	}
+	close(tools.gate("alice1"))
+	<-first
+}
+
+func TestToolCircuitBreaker(t *testing.T) {
+	metrics := &bulkheadMetrics{depths: map[string]int{}, rejections: map[string]int{}}
+	handler := NewToolCircuitBreaker(2, time.Minute, metrics).Apply(MCPHandlerFunc(func(ctx context.Context, req MCPRequest) (MCPResponse, error) {
+		switch toolName(req) {
+		case "flaky":
+			return &successResponse{result: &CallToolResult{IsError: true}}, nil
+		case "busy":
+			return NewRateLimitErrorWithRetry("busy", time.Second), nil
+		}
+		return &successResponse{result: &CallToolResult{}}, nil
+	}))
+	call := func(tool string) MCPResponse {
+		t.Helper()
+		resp, err := handler.Handle(context.Background(), &UnifiedRequest{
+			method: string(MethodToolsCall),
+			params: []byte(fmt.Sprintf(`{"name":%q}`, tool)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for range 2 {
+		if resp := call("flaky"); resp.IsError() {
+			t.Fatalf("closed circuit rejected a call: %v", resp.Error())
+		}
+	}
+	resp := call("flaky")
+	if !resp.IsError() {
+		t.Fatal("open circuit let a call through")
+	}
+	if d, ok := RetryAfterFromError(resp.Error()); !ok || d <= 0 || d > time.Minute {
+		t.Errorf("RetryAfterFromError = %v, %v; want (0, 1m]", d, ok)
+	}
+	if len(metrics.errors) != 1 || metrics.errors[0] != "circuit_open:flaky" {
+		t.Errorf("recorded errors %v, want circuit_open:flaky", metrics.errors)
+	}
+	for range 3 {
+		if resp := call("busy"); resp.Error().Message != "busy" {
+			t.Fatalf("rejections opened the circuit of busy: %v", resp.Error())
+		}
+	}
+	if resp := call("healthy"); resp.IsError() {
+		t.Errorf("another tool's call = %v", resp.Error())
+	}
+}
+
+func TestBulkheadQueueProgress(t *testing.T) {
+	server := NewServer("bulkhead", "1.0")
+	server.Use(NewBulkheadMiddleware(BulkheadConfig{ToolLimits: map[string]int{"sleep": 1}, ProgressInterval: time.Minute}))
+	server.RegisterTool(Tool{Name: "sleep"}, func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+		time.Sleep(200 * time.Millisecond)
+		return &CallToolResult{Content: []any{TextContent{Type: "text", Text: "slept"}}}, nil
+	})
+	conn, r := rawSession(t, server, "2025-03-26")
+
+	writeLine(t, conn, `[
+		{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"sleep","_meta":{"progressToken":"a"}}},
+		{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"sleep","_meta":{"progressToken":"b"}}}
+	]`)
+	var progress []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(line, `"method":"notifications/progress"`) {
+			progress = append(progress, line)
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			var replies []batchReply
+			if err := json.Unmarshal([]byte(line), &replies); err != nil || len(replies) != 2 || replies[0].Error != nil || replies[1].Error != nil {
+				t.Fatalf("batch response = %s", line)
+			}
+			break
+		}
+	}
+	if len(progress) != 1 || !strings.Contains(progress[0], `"message":"Queued for tool \"sleep\": 1 of 1 waiting"`) {
+		t.Errorf("progress notifications = %q, want one for the queued call", progress)
+	}
+}
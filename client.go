@@ -25,8 +25,11 @@ type Client struct {
 	framer             jsonrpc2.Framer
 	serverInfo         Implementation
 	serverCapabilities ServerCapabilities
+	protocolVersion    string
 	initialized        bool
 	initMu             sync.RWMutex
+	resourceCache      *clientResourceCache
+	batches            *clientBatches
 }
 
 // ClientOption defines a function for configuring a Client instance.
@@ -69,9 +72,16 @@ func NewClient(transport Transport, opts ...ClientOption) (*Client, error) {
 		opt(c)
 	}
 
-	// Create the connection
+	// Create the connection. Replies to CallBatch arrive as JSON-RPC
+	// batches, which jsonrpc2 cannot read, so they are picked off first.
 	handler := jsonrpc2.HandlerFunc(c.handleMessage)
-	conn, err := jsonrpc2.Dial(ctx, transport, jsonrpc2.ConnectionOptions{
+	batching := batchDialer{dialer: transport, bind: func(bc *batchConn) {
+		c.batches = &clientBatches{conn: bc, pending: make(map[string]*pendingBatch), singles: make(map[string]bool)}
+		bc.batch = c.batches.batch
+		bc.single = c.batches.single
+		bc.sending = c.batches.sending
+	}}
+	conn, err := jsonrpc2.Dial(ctx, batching, jsonrpc2.ConnectionOptions{
 		Framer:  c.framer,
 		Handler: handler,
 	})
@@ -86,11 +96,15 @@ func NewClient(transport Transport, opts ...ClientOption) (*Client, error) {
 // handleMessage processes incoming JSON-RPC messages from the server.
 // It distinguishes between notifications (which have no ID) and regular requests.
 // For notifications, it dispatches them to the registered notification handler.
-// For regular requests, it returns a "method not implemented" error since clients
-// typically don't handle incoming requests, only responses.
+// Requests go to the handler registered with OnRequest; ping is answered by
+// default and anything else gets a "method not implemented" error.
 func (c *Client) handleMessage(ctx context.Context, req *jsonrpc2.Request) (interface{}, error) {
 	// For notifications, call the notification handler if registered
 	if !req.ID.IsValid() {
+		if c.resourceCache != nil {
+			c.resourceCache.index.observe(Method(req.Method), req.Params)
+		}
+
 		c.notificationMu.RLock()
 		handler := c.notifyHandler
 		c.notificationMu.RUnlock()
@@ -108,8 +122,12 @@ func (c *Client) handleMessage(ctx context.Context, req *jsonrpc2.Request) (inte
 	c.requestMu.RLock()
 	handler := c.requestHandlers[req.Method]
 	c.requestMu.RUnlock()
+	if handler == nil && req.Method == string(MethodPing) {
+		// Either side may ping the other; the reply is an empty result.
+		return struct{}{}, nil
+	}
 	if handler == nil {
-		return nil, errors.New("method not implemented on client")
+		return nil, jsonrpc2.NewError(-32601, "method not implemented on client")
 	}
 	return handler(ctx, req.Params)
 }
@@ -255,6 +273,14 @@ func (c *Client) Initialize(ctx context.Context, request InitializeRequest) (*In
 	if request.Capabilities.Roots == nil {
 		request.Capabilities.Roots = c.advertise.Roots
 	}
+	for k, v := range c.advertise.Experimental {
+		if _, ok := request.Capabilities.Experimental[k]; !ok {
+			if request.Capabilities.Experimental == nil {
+				request.Capabilities.Experimental = make(map[string]any)
+			}
+			request.Capabilities.Experimental[k] = v
+		}
+	}
 	c.requestMu.RUnlock()
 
 	var result InitializeResult
@@ -265,6 +291,7 @@ func (c *Client) Initialize(ctx context.Context, request InitializeRequest) (*In
 	c.initMu.Lock()
 	c.serverInfo = result.ServerInfo
 	c.serverCapabilities = result.Capabilities
+	c.protocolVersion = result.ProtocolVersion
 	c.initialized = true
 	c.initMu.Unlock()
 
@@ -354,6 +381,20 @@ func (c *Client) ReadResource(ctx context.Context, request ReadResourceRequest)
 	}
 
 	var result ReadResourceResult
+	if c.resourceCache != nil {
+		data, err := c.resourceCache.read(ctx, request.URI, func() (json.RawMessage, error) {
+			var raw json.RawMessage
+			err := c.call(ctx, string(MethodResourcesRead), request, &raw)
+			return raw, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("decoding resources/read result: %w", err)
+		}
+		return &result, nil
+	}
 	if err := c.call(ctx, string(MethodResourcesRead), request, &result); err != nil {
 		return nil, err
 	}
@@ -368,7 +409,13 @@ func (c *Client) SubscribeResource(ctx context.Context, request SubscribeResourc
 	}
 
 	var result any
-	return c.call(ctx, string(MethodResourcesSubscribe), request, &result)
+	if err := c.call(ctx, string(MethodResourcesSubscribe), request, &result); err != nil {
+		return err
+	}
+	if c.resourceCache != nil {
+		c.resourceCache.setSubscribed(request.URI, true)
+	}
+	return nil
 }
 
 // UnsubscribeResource cancels a resource update subscription.
@@ -377,6 +424,9 @@ func (c *Client) UnsubscribeResource(ctx context.Context, request UnsubscribeRes
 		return err
 	}
 
+	if c.resourceCache != nil {
+		c.resourceCache.setSubscribed(request.URI, false)
+	}
 	var result any
 	return c.call(ctx, string(MethodResourcesUnsubscribe), request, &result)
 }
@@ -397,9 +447,21 @@ func (c *Client) ListResourceTemplates(ctx context.Context, request ListResource
 
 // Close terminates the connection to the server.
 func (c *Client) Close() error {
+	if c.resourceCache != nil {
+		c.resourceCache.index.close()
+	}
 	return c.conn.Close()
 }
 
+// Wait blocks until the connection to the server is closed, either by Close
+// or because the server went away.
+func (c *Client) Wait() error {
+	if c.conn == nil {
+		return errors.New("client connection is not established")
+	}
+	return c.conn.Wait()
+}
+
 // Notify sends a JSON-RPC notification to the server.
 func (c *Client) Notify(ctx context.Context, method string, params interface{}) error {
 	if c.conn == nil {
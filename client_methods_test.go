@@ -159,9 +159,8 @@ func TestClientAllMethods(t *testing.T) {
 		if len(result.Content) != 1 {
 			t.Errorf("Unexpected tool result length: %+v", result)
 		}
-		// Check content - it will be a map after JSON round-trip
-		if content, ok := result.Content[0].(map[string]interface{}); ok {
-			if content["type"] != "text" || content["text"] != "Tool result" {
+		if content, ok := result.Content[0].(TextContent); ok {
+			if content.Type != "text" || content.Text != "Tool result" {
 				t.Errorf("Unexpected tool result content: %+v", result.Content[0])
 			}
 		} else {
@@ -256,7 +255,7 @@ func TestClientAllMethods(t *testing.T) {
 			t.Fatalf("ListResourceTemplates failed: %v", err)
 		}
 
-		if len(result.Templates) != 1 || result.Templates[0].Template != "test://template/{id}" {
+		if len(result.Templates) != 1 || result.Templates[0].URITemplate != "test://template/{id}" {
 			t.Errorf("Unexpected templates result: %+v", result)
 		}
 	})
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tmc/mcp"
+	"github.com/tmc/mcp/internal/mcpcli"
+)
+
+func newAuditCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect server audit logs",
+	}
+	cmd.AddCommand(newAuditVerifyCommand(a))
+	return cmd
+}
+
+func newAuditVerifyCommand(a *app) *cobra.Command {
+	var prefix string
+	cmd := &cobra.Command{
+		Use:   "verify <dir|file...>",
+		Short: "Verify an audit log hash chain and summarize activity per principal",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var report *mcp.AuditReport
+			var err error
+			if info, statErr := os.Stat(args[0]); len(args) == 1 && statErr == nil && info.IsDir() {
+				report, err = mcp.VerifyAuditLog(args[0], prefix)
+			} else {
+				report, err = mcp.VerifyAuditFiles(args...)
+			}
+			if report == nil {
+				return err
+			}
+			if a.output == mcpcli.OutputJSON || a.output == mcpcli.OutputNDJSON {
+				data, merr := json.MarshalIndent(report, "", "  ")
+				if merr != nil {
+					return merr
+				}
+				if werr := mcpcli.WriteOutput("", data); werr != nil {
+					return werr
+				}
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Files: %d\n", len(report.Files))
+			fmt.Fprintf(out, "Records: %d (seq %d-%d)\n", report.Records, report.FirstSeq, report.LastSeq)
+			if report.Truncated {
+				fmt.Fprintln(out, "Note: chain starts after removed records")
+			}
+			principals := make([]string, 0, len(report.Principals))
+			for p := range report.Principals {
+				principals = append(principals, p)
+			}
+			sort.Strings(principals)
+			for _, p := range principals {
+				s := report.Principals[p]
+				fmt.Fprintf(out, "%s\t%d requests\t%d failed\t%s\n", p, s.Requests, s.Failures, formatCounts(s.Tools))
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, "Chain: OK")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&prefix, "prefix", "audit", "log file name prefix when verifying a directory")
+	return cmd
+}
+
+// formatCounts renders counts as "name=n" pairs in name order.
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%d", k, counts[k])
+	}
+	return strings.Join(parts, " ")
+}
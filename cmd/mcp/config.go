@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tmc/mcp/internal/mcpcli"
+)
+
+func newConfigCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "List and check server profiles",
+		Long: "Server profiles are read from mcp.json in the state directory and from the nearest .mcp.json\n" +
+			"above the working directory, which wins for servers defined in both. Select one with\n" +
+			"--server (-s). ${VAR} references are expanded from the environment, then, except in\n" +
+			".mcp.json, from secrets.env in the state directory.",
+	}
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "ls",
+			Short: "List server profiles",
+			Long:  "List server profiles. JSON output redacts header and environment values and auth credentials.",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				profiles, err := mcpcli.LoadProfiles(a.cfg.StateDir, a.cfg.ConfigFile)
+				if err != nil {
+					return err
+				}
+				if a.output == mcpcli.OutputJSON || a.output == mcpcli.OutputNDJSON {
+					type profile struct {
+						Name   string `json:"name"`
+						Source string `json:"source"`
+						mcpcli.ServerEntry
+					}
+					list := []profile{}
+					for _, name := range profiles.Names() {
+						list = append(list, profile{name, profiles.Sources[name], profiles.Servers[name].Redacted()})
+					}
+					data, err := json.MarshalIndent(list, "", "  ")
+					if err != nil {
+						return err
+					}
+					return mcpcli.WriteOutput("", data)
+				}
+				for _, name := range profiles.Names() {
+					entry := profiles.Servers[name]
+					target := entry.URL
+					if entry.Command != "" {
+						target = strings.Join(append([]string{entry.Command}, entry.Args...), " ")
+					}
+					if entry.Disabled {
+						target += " (disabled)"
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\n", name, target, profiles.Sources[name])
+				}
+				return nil
+			},
+		},
+		newConfigValidateCommand(a),
+	)
+	return cmd
+}
+
+// profileCheck is the outcome of starting and initializing one profile.
+type profileCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Server  string `json:"server,omitempty"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func newConfigValidateCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate [server...]",
+		Short: "Check that every enabled profile starts and initializes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := mcpcli.LoadProfiles(a.cfg.StateDir, a.cfg.ConfigFile)
+			if err != nil {
+				return err
+			}
+			names := args
+			if len(names) == 0 {
+				names = profiles.Enabled()
+			}
+			if len(names) == 0 {
+				return fmt.Errorf("no server profiles found")
+			}
+			checks := make([]profileCheck, len(names))
+			var wg sync.WaitGroup
+			for i, name := range names {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					checks[i] = checkProfile(cmd.Context(), a.cfg, profiles, name)
+				}()
+			}
+			wg.Wait()
+
+			failed := 0
+			for _, c := range checks {
+				if !c.OK {
+					failed++
+				}
+			}
+			if a.output == mcpcli.OutputJSON || a.output == mcpcli.OutputNDJSON {
+				data, err := json.MarshalIndent(checks, "", "  ")
+				if err != nil {
+					return err
+				}
+				if err := mcpcli.WriteOutput("", data); err != nil {
+					return err
+				}
+			} else {
+				for _, c := range checks {
+					if c.OK {
+						fmt.Fprintf(cmd.OutOrStdout(), "%s\tok\t%s %s\n", c.Name, c.Server, c.Version)
+					} else {
+						fmt.Fprintf(cmd.OutOrStdout(), "%s\tFAIL\t%s\n", c.Name, c.Error)
+					}
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d servers failed", failed, len(checks))
+			}
+			return nil
+		},
+	}
+}
+
+// checkProfile connects to the named profile and reports what it returned
+// from initialize.
+func checkProfile(ctx context.Context, cfg mcpcli.Config, profiles *mcpcli.Profiles, name string) profileCheck {
+	check := profileCheck{Name: name}
+	entry, err := profiles.Lookup(name)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if missing := entry.MissingVars(); len(missing) > 0 {
+		check.Error = "unset variables: " + strings.Join(missing, ", ")
+		return check
+	}
+	timeout, err := entry.RequestTimeout()
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if timeout == 0 {
+		timeout = cfg.Timeout
+	}
+	cfg.Cmd, cfg.HTTPURL, cfg.SSEURL, cfg.Session = "", "", "", ""
+	cfg.Server = name
+	cfg.ConfigFile = profiles.Sources[name]
+	cfg.Timeout = timeout
+	ctx, cancel := context.WithTimeout(ctx, timeout+time.Second)
+	defer cancel()
+	sess, err := mcpcli.Connect(ctx, cfg)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	defer sess.Close()
+	init := sess.InitializeResult()
+	check.OK = true
+	check.Server = init.ServerInfo.Name
+	check.Version = init.ServerInfo.Version
+	return check
+}
+
+// profileTimeout returns the timeout of the profile cfg selects, or
+// cfg.Timeout if it sets none.
+func profileTimeout(cfg mcpcli.Config) (time.Duration, error) {
+	profiles, err := mcpcli.LoadProfiles(cfg.StateDir, cfg.ConfigFile)
+	if err != nil {
+		return 0, err
+	}
+	entry, err := profiles.Lookup(cfg.Server)
+	if err != nil {
+		return 0, err
+	}
+	timeout, err := entry.RequestTimeout()
+	if err != nil || timeout == 0 {
+		return cfg.Timeout, err
+	}
+	return timeout, nil
+}
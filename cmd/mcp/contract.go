@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tmc/mcp/internal/mcpcli"
+	"github.com/tmc/mcp/internal/mcpspy"
+)
+
+func newContractCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "contract",
+		Short: "Check a server's tools, resources and prompts against a saved spec",
+	}
+	cmd.AddCommand(newContractSnapshotCommand(a), newContractCheckCommand(a))
+	return cmd
+}
+
+func newContractSnapshotCommand(a *app) *cobra.Command {
+	var outPath string
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Write the server's declared surface as a .mcpspec document",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := cmdContext(cmd, a.cfg.Timeout)
+			defer cancel()
+			sess, err := a.session(ctx)
+			if err != nil {
+				return err
+			}
+			spec, err := mcpspy.SpecFromClient(ctx, sess.Client(), sess.InitializeResult().ServerInfo)
+			if err != nil {
+				return err
+			}
+			data, err := json.MarshalIndent(spec, "", "  ")
+			if err != nil {
+				return err
+			}
+			return mcpcli.WriteOutput(outPath, append(data, '\n'))
+		},
+	}
+	cmd.Flags().StringVarP(&outPath, "out", "o", "", "write the spec to this file instead of stdout")
+	return cmd
+}
+
+func newContractCheckCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check <spec.mcpspec>",
+		Short: "Compare the server against a saved spec; fail on breaking changes",
+		Long: "check classifies every difference between the saved spec and the live server as breaking\n" +
+			"(removed tool, new required argument, narrowed enum, changed output type, ...) or not,\n" +
+			"and exits non-zero if any change is breaking. Use --output json for a machine-readable report.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			saved, err := mcpspy.LoadSpecDocument(args[0])
+			if err != nil {
+				return err
+			}
+			ctx, cancel := cmdContext(cmd, a.cfg.Timeout)
+			defer cancel()
+			sess, err := a.session(ctx)
+			if err != nil {
+				return err
+			}
+			live, err := mcpspy.SpecFromClient(ctx, sess.Client(), sess.InitializeResult().ServerInfo)
+			if err != nil {
+				return err
+			}
+			report := mcpspy.CheckContract(saved, live)
+			if a.output == mcpcli.OutputJSON || a.output == mcpcli.OutputNDJSON {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				if err := mcpcli.WriteOutput("", data); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprint(cmd.OutOrStdout(), report.Text())
+			}
+			if n := len(report.Breaking()); n > 0 {
+				return fmt.Errorf("%d breaking changes against %s", n, args[0])
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tmc/mcp"
+	"github.com/tmc/mcp/internal/mcpcli"
+)
+
+func newGatewayCommand(a *app) *cobra.Command {
+	var (
+		separator      string
+		healthInterval time.Duration
+		maxRestarts    int
+		verbose        bool
+	)
+	cmd := &cobra.Command{
+		Use:   "gateway [server...]",
+		Short: "Serve the server profiles as one MCP server on stdio",
+		Long: "gateway connects to every enabled server profile (or the named ones) and serves them\n" +
+			"as a single MCP server on stdin/stdout. Tools, prompts and resource URIs are namespaced as\n" +
+			"server" + mcp.DefaultGatewaySeparator + "name; " +
+			"upstreams are health-checked and restarted when they fail. Status is logged to stderr.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := mcpcli.LoadProfiles(a.cfg.StateDir, a.cfg.ConfigFile)
+			if err != nil {
+				return err
+			}
+			if len(profiles.Files) == 0 {
+				return fmt.Errorf("no .mcp.json found (looked upward from the working directory and in %s)", a.cfg.StateDir)
+			}
+			names := profiles.Enabled()
+			if len(args) > 0 {
+				for _, name := range args {
+					if _, err := profiles.Lookup(name); err != nil {
+						return err
+					}
+				}
+				names = args
+			}
+			if len(names) == 0 {
+				return fmt.Errorf("no enabled servers in %s", strings.Join(profiles.Files, ", "))
+			}
+
+			var stderr io.Writer = io.Discard
+			if a.cfg.ServerStderr {
+				stderr = os.Stderr
+			}
+			var upstreams []mcp.UpstreamConfig
+			for _, name := range names {
+				transport, err := profiles.Servers[name].Transport(stderr)
+				if err != nil {
+					return fmt.Errorf("server %q: %w", name, err)
+				}
+				upstreams = append(upstreams, mcp.UpstreamConfig{Name: name, Transport: transport})
+			}
+
+			level := slog.LevelWarn
+			if verbose {
+				level = slog.LevelInfo
+			}
+			logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+			gw, err := mcp.NewGateway(mcp.GatewayConfig{
+				Upstreams:      upstreams,
+				Separator:      separator,
+				HealthInterval: healthInterval,
+				MaxRestarts:    maxRestarts,
+				ConnectTimeout: a.cfg.Timeout,
+				Logger:         logger,
+				ServerOptions:  []mcp.ServerOption{mcp.WithLogger(logger)},
+			})
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			if err := gw.Start(ctx); err != nil {
+				return err
+			}
+			for _, st := range gw.Status() {
+				fmt.Fprintf(os.Stderr, "gateway: %s: %s (%d tools, %d prompts, %d resources)%s\n",
+					st.Name, st.State, st.Tools, st.Prompts, st.Resources, statusError(st))
+			}
+			err = gw.Serve(ctx, mcp.StdioTransport())
+			if errors.Is(err, context.Canceled) || errors.Is(err, io.EOF) {
+				// Interrupted, or the client closed stdin.
+				return nil
+			}
+			return err
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&separator, "separator", mcp.DefaultGatewaySeparator, "separator between server and tool or prompt names")
+	flags.DurationVar(&healthInterval, "health-interval", 30*time.Second, "interval between upstream health checks (negative disables)")
+	flags.IntVar(&maxRestarts, "max-restarts", 0, "restarts before an upstream is given up (0 means no limit)")
+	flags.BoolVarP(&verbose, "verbose", "v", false, "log upstream connections and restarts")
+	return cmd
+}
+
+func statusError(st mcp.UpstreamStatus) string {
+	if st.LastError == "" {
+		return ""
+	}
+	return ": " + st.LastError
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tmc/mcp"
+	"github.com/tmc/mcp/internal/mcpcli"
+)
+
+func newGenCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen",
+		Short: "Generate code from a server's schemas",
+	}
+	cmd.AddCommand(newGenClientCommand(a))
+	return cmd
+}
+
+func newGenClientCommand(a *app) *cobra.Command {
+	var pkg, outPath string
+	cmd := &cobra.Command{
+		Use:   "client",
+		Short: "Generate a typed Go client package for the server",
+		Long: "client reads the server's tools, prompts and resource templates and writes a Go package\n" +
+			"with a method per item: tools take a struct built from their input schema and, when they\n" +
+			"declare an output schema, return its Go type (a struct for objects). Equal schemas share\n" +
+			"one named type. The package pins a hash of the schemas; its New fails with ErrSchemaDrift\n" +
+			"once the server changes, and the package should be regenerated.\n\n" +
+			"  mcp gen client -s github --pkg githubmcp -o githubmcp/client.go",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := cmdContext(cmd, a.cfg.Timeout)
+			defer cancel()
+			sess, err := a.session(ctx)
+			if err != nil {
+				return err
+			}
+			spec, err := clientSpec(ctx, sess)
+			if err != nil {
+				return err
+			}
+			spec.Package = pkg
+			src, err := mcpcli.GenerateClient(spec)
+			if err != nil {
+				return err
+			}
+			return mcpcli.WriteOutput(outPath, src)
+		},
+	}
+	cmd.Flags().StringVar(&pkg, "pkg", "", "package name of the generated code")
+	cmd.Flags().StringVarP(&outPath, "out", "o", "", "write the code to this file instead of stdout")
+	_ = cmd.MarkFlagRequired("pkg")
+	return cmd
+}
+
+// clientSpec lists what the server offers the way mcp.Client's
+// ServerSchemaHashOf does, so the generated hash matches at run time:
+// prompts and templates are empty when the server does not implement them.
+func clientSpec(ctx context.Context, sess *mcpcli.Session) (mcpcli.ClientSpec, error) {
+	info := sess.InitializeResult().ServerInfo
+	spec := mcpcli.ClientSpec{Source: fmt.Sprintf("%s %s", info.Name, info.Version)}
+	var err error
+	if spec.Tools, err = sess.ListToolsAll(ctx); err != nil {
+		return spec, fmt.Errorf("list tools: %w", err)
+	}
+	if spec.Prompts, err = sess.ListPromptsAll(ctx); err != nil && !isMethodNotFound(err) {
+		return spec, fmt.Errorf("list prompts: %w", err)
+	}
+	if spec.Templates, err = sess.ListResourceTemplatesAll(ctx); err != nil && !isMethodNotFound(err) {
+		return spec, fmt.Errorf("list resource templates: %w", err)
+	}
+	return spec, nil
+}
+
+func isMethodNotFound(err error) bool {
+	re, ok := mcp.AsResponseError(err)
+	return ok && re.Code == -32601
+}
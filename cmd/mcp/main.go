@@ -13,6 +13,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/tmc/mcp/internal/mcpcli"
+	"golang.org/x/term"
 )
 
 const (
@@ -22,15 +23,23 @@ const (
 
 type bootstrapOptions struct {
 	mcpcli.Config
-	Output string
+	Output     string
+	Command    string // first non-flag argument
+	TimeoutSet bool   // whether --timeout was given
+
+	Elicit      string
+	Sampling    string
+	SamplingCmd string
 }
 
 type app struct {
-	cfg    mcpcli.Config
-	output mcpcli.OutputMode
+	cfg         mcpcli.Config
+	output      mcpcli.OutputMode
+	interaction *mcpcli.Interaction
 
-	mu   sync.Mutex
-	sess *mcpcli.Session
+	mu        sync.Mutex
+	sess      *mcpcli.Session
+	stopWatch func()
 }
 
 func main() {
@@ -49,7 +58,17 @@ func run(ctx context.Context, args []string) error {
 	if err != nil {
 		return err
 	}
-	a := &app{cfg: opts.Config, output: output}
+	if opts.Server != "" && !opts.TimeoutSet {
+		if opts.Timeout, err = profileTimeout(opts.Config); err != nil {
+			return err
+		}
+	}
+	interaction, err := newInteraction(opts)
+	if err != nil {
+		return err
+	}
+	interaction.Configure(&opts.Config)
+	a := &app{cfg: opts.Config, output: output, interaction: interaction}
 	root, err := buildRoot(ctx, a, opts)
 	if err != nil {
 		return err
@@ -81,6 +100,13 @@ func buildRoot(ctx context.Context, a *app, opts bootstrapOptions) (*cobra.Comma
 	root.AddCommand(newLogCommand(a))
 	root.AddCommand(newTaskCommand(a))
 	root.AddCommand(newUICommand(a))
+	root.AddCommand(newAuditCommand(a))
+	root.AddCommand(newGatewayCommand(a))
+	root.AddCommand(newContractCommand(a))
+	root.AddCommand(newSessionCommand(a))
+	root.AddCommand(newConfigCommand(a))
+	root.AddCommand(newRunCommand(a))
+	root.AddCommand(newGenCommand(a))
 
 	toolCmd, err := newToolCommand(ctx, a, opts)
 	if err != nil {
@@ -95,11 +121,17 @@ func addPersistentFlags(cmd *cobra.Command, opts *bootstrapOptions) {
 	flags.StringVar(&opts.Cmd, "cmd", opts.Cmd, "shell command to start an MCP stdio server")
 	flags.StringVar(&opts.HTTPURL, "http", opts.HTTPURL, "streamable HTTP MCP endpoint")
 	flags.StringVar(&opts.SSEURL, "sse", opts.SSEURL, "SSE MCP endpoint")
+	flags.StringVar(&opts.Session, "session", opts.Session, "reuse the connection of a background session")
+	flags.StringVarP(&opts.Server, "server", "s", opts.Server, "server profile from .mcp.json")
+	flags.StringVar(&opts.ConfigFile, "config", opts.ConfigFile, "server profiles file (default: state-dir mcp.json and the nearest .mcp.json)")
 	flags.DurationVar(&opts.Timeout, "timeout", opts.Timeout, "request timeout")
 	flags.StringVar(&opts.ProtocolVersion, "protocol-version", opts.ProtocolVersion, "MCP protocol version")
 	flags.BoolVar(&opts.ServerStderr, "server-stderr", opts.ServerStderr, "forward wrapped server stderr to stderr")
 	flags.StringVar(&opts.StateDir, "state-dir", opts.StateDir, "directory for local CLI state")
 	flags.StringVar(&opts.Output, "output", opts.Output, "output mode: text, json, ndjson")
+	flags.StringVar(&opts.Elicit, "elicit", opts.Elicit, "answer server elicitations: prompt, editor, accept, decline (default: prompt on a terminal)")
+	flags.StringVar(&opts.Sampling, "sampling", opts.Sampling, "answer server sampling requests: prompt, accept, decline (default: prompt on a terminal)")
+	flags.StringVar(&opts.SamplingCmd, "sampling-cmd", opts.SamplingCmd, "shell command that answers sampling requests read as JSON from stdin")
 }
 
 func newCompletionCommand() *cobra.Command {
@@ -162,6 +194,33 @@ func parseBootstrapArgs(args []string) (bootstrapOptions, error) {
 				value = args[i]
 			}
 			opts.SSEURL = value
+		case "--session":
+			if !hasValue {
+				i++
+				if i >= len(args) {
+					return opts, errors.New("missing value for --session")
+				}
+				value = args[i]
+			}
+			opts.Session = value
+		case "--server", "-s":
+			if !hasValue {
+				i++
+				if i >= len(args) {
+					return opts, errors.New("missing value for --server")
+				}
+				value = args[i]
+			}
+			opts.Server = value
+		case "--config":
+			if !hasValue {
+				i++
+				if i >= len(args) {
+					return opts, errors.New("missing value for --config")
+				}
+				value = args[i]
+			}
+			opts.ConfigFile = value
 		case "--timeout":
 			if !hasValue {
 				i++
@@ -175,6 +234,7 @@ func parseBootstrapArgs(args []string) (bootstrapOptions, error) {
 				return opts, fmt.Errorf("parse --timeout: %w", err)
 			}
 			opts.Timeout = d
+			opts.TimeoutSet = true
 		case "--protocol-version":
 			if !hasValue {
 				i++
@@ -212,12 +272,35 @@ func parseBootstrapArgs(args []string) (bootstrapOptions, error) {
 				value = args[i]
 			}
 			opts.Output = value
+		case "--elicit", "--sampling", "--sampling-cmd":
+			if !hasValue {
+				i++
+				if i >= len(args) {
+					return opts, fmt.Errorf("missing value for %s", name)
+				}
+				value = args[i]
+			}
+			switch name {
+			case "--elicit":
+				opts.Elicit = value
+			case "--sampling":
+				opts.Sampling = value
+			default:
+				opts.SamplingCmd = value
+			}
+		default:
+			if opts.Command == "" && !strings.HasPrefix(name, "-") {
+				opts.Command = name
+			}
 		}
 	}
 	return opts, nil
 }
 
 func splitArg(arg string) (name, value string, hasValue bool) {
+	if strings.HasPrefix(arg, "-s=") {
+		return "-s", arg[len("-s="):], true
+	}
 	if !strings.HasPrefix(arg, "--") {
 		return arg, "", false
 	}
@@ -241,9 +324,38 @@ func (a *app) session(ctx context.Context) (*mcpcli.Session, error) {
 		return nil, err
 	}
 	a.sess = sess
+	if a.cfg.ElicitHandler != nil {
+		a.stopWatch = a.interaction.Watch(sess)
+	}
 	return sess, nil
 }
 
+// newInteraction returns the handlers for server requests for user input.
+// On a terminal they ask by default; otherwise only the policies given by
+// flags, or running a given sampling command, are advertised.
+func newInteraction(opts bootstrapOptions) (*mcpcli.Interaction, error) {
+	in := &mcpcli.Interaction{
+		ElicitPolicy:   opts.Elicit,
+		SamplingPolicy: opts.Sampling,
+		SamplingCmd:    opts.SamplingCmd,
+		In:             os.Stdin,
+		Out:            os.Stderr,
+	}
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	if in.ElicitPolicy == "" && interactive {
+		in.ElicitPolicy = mcpcli.PolicyPrompt
+	}
+	if in.SamplingPolicy == "" {
+		switch {
+		case interactive:
+			in.SamplingPolicy = mcpcli.PolicyPrompt
+		case in.SamplingCmd != "":
+			in.SamplingPolicy = mcpcli.PolicyAccept
+		}
+	}
+	return in, in.Validate()
+}
+
 func (a *app) stateStore() (*mcpcli.StateStore, error) {
 	return mcpcli.OpenStateStore(a.cfg.StateDir)
 }
@@ -251,6 +363,10 @@ func (a *app) stateStore() (*mcpcli.StateStore, error) {
 func (a *app) close() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	if a.stopWatch != nil {
+		a.stopWatch()
+		a.stopWatch = nil
+	}
 	if a.sess != nil {
 		_ = a.sess.Close()
 		a.sess = nil
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -82,21 +83,23 @@ func newResourceCatCommand(a *app) *cobra.Command {
 
 func newResourceWatchCommand(a *app) *cobra.Command {
 	var interval time.Duration
+	var poll bool
 	cmd := &cobra.Command{
 		Use:   "watch <uri>",
-		Short: "Watch a resource for changes by polling",
-		Args:  cobra.ExactArgs(1),
+		Short: "Watch a resource for changes",
+		Long: "watch prints the resource and then prints it again whenever it changes. It subscribes to\n" +
+			"resources/updated notifications when the server supports them and polls otherwise.",
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 			sess, err := a.session(ctx)
 			if err != nil {
 				return err
 			}
+			uri := args[0]
 			var last []byte
-			ticker := time.NewTicker(interval)
-			defer ticker.Stop()
-			for {
-				result, err := sess.Client().ReadResource(ctx, mcp.ReadResourceRequest{URI: args[0]})
+			emit := func() error {
+				result, err := sess.Client().ReadResource(ctx, mcp.ReadResourceRequest{URI: uri})
 				if err != nil {
 					return err
 				}
@@ -104,32 +107,73 @@ func newResourceWatchCommand(a *app) *cobra.Command {
 				if err != nil {
 					return err
 				}
-				if !bytes.Equal(last, data) {
-					if a.output == mcpcli.OutputNDJSON {
-						event, err := json.Marshal(map[string]any{
-							"time": time.Now().Format(time.RFC3339),
-							"uri":  args[0],
-							"data": json.RawMessage(data),
-						})
-						if err != nil {
-							return err
-						}
-						if err := mcpcli.WriteOutput("", event); err != nil {
-							return err
-						}
-					} else if err := mcpcli.WriteOutput("", data); err != nil {
+				if bytes.Equal(last, data) {
+					return nil
+				}
+				last = append(last[:0], data...)
+				if a.output != mcpcli.OutputNDJSON {
+					return mcpcli.WriteOutput("", data)
+				}
+				event, err := json.Marshal(map[string]any{
+					"time": time.Now().Format(time.RFC3339),
+					"uri":  uri,
+					"data": json.RawMessage(data),
+				})
+				if err != nil {
+					return err
+				}
+				return mcpcli.WriteOutput("", event)
+			}
+
+			caps := sess.InitializeResult().Capabilities.Resources
+			if poll || caps == nil || !caps.Subscribe {
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					if err := emit(); err != nil {
 						return err
 					}
-					last = append(last[:0], data...)
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-ticker.C:
+					}
 				}
+			}
+
+			events, unsubscribe := sess.Subscribe(64)
+			defer unsubscribe()
+			if err := sess.Client().SubscribeResource(ctx, mcp.SubscribeResourceRequest{URI: uri}); err != nil {
+				return err
+			}
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Timeout)
+				defer cancel()
+				_ = sess.Client().UnsubscribeResource(ctx, mcp.UnsubscribeResourceRequest{URI: uri})
+			}()
+			if err := emit(); err != nil {
+				return err
+			}
+			for {
 				select {
 				case <-ctx.Done():
 					return ctx.Err()
-				case <-ticker.C:
+				case event := <-events:
+					if event.Method != string(mcp.MethodResourceUpdated) {
+						continue
+					}
+					var params mcp.ResourceUpdatedNotificationParams
+					if json.Unmarshal(event.Params, &params) != nil || params.URI != uri {
+						continue
+					}
+					if err := emit(); err != nil {
+						return err
+					}
 				}
 			}
 		},
 	}
 	cmd.Flags().DurationVar(&interval, "interval", 2*time.Second, "polling interval")
+	cmd.Flags().BoolVar(&poll, "poll", false, "poll even if the server supports subscriptions")
 	return cmd
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tmc/mcp/internal/mcpcli"
+)
+
+func newRunCommand(a *app) *cobra.Command {
+	var (
+		parallel int
+		failFast bool
+	)
+	cmd := &cobra.Command{
+		Use:   "run <file|->",
+		Short: "Run NDJSON operations over one session",
+		Long: "run reads one operation per line from the file, or stdin for -, and writes one NDJSON result\n" +
+			"per operation to stdout as it completes:\n\n" +
+			"  {\"id\": 1, \"tool\": \"search\", \"args\": {\"q\": \"mcp\"}}\n" +
+			"  {\"id\": 2, \"read\": \"file:///notes.txt\"}\n" +
+			"  {\"id\": 3, \"prompt\": \"summarize\", \"args\": {\"topic\": \"mcp\"}}\n\n" +
+			"Results are {\"id\": ..., \"result\": ...} or {\"id\": ..., \"error\": \"...\"}; the id defaults to\n" +
+			"the line number. Failed operations do not stop the run unless --fail-fast is set, but\n" +
+			"make it exit non-zero. --timeout applies to each operation.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var in io.Reader = os.Stdin
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+			ctx, cancel := cmdContext(cmd, a.cfg.Timeout)
+			sess, err := a.session(ctx)
+			cancel()
+			if err != nil {
+				return err
+			}
+			stats, err := sess.RunBatch(cmd.Context(), in, cmd.OutOrStdout(), mcpcli.BatchOptions{
+				Parallel: parallel,
+				FailFast: failFast,
+				Timeout:  a.cfg.Timeout,
+			})
+			if err != nil {
+				return err
+			}
+			if stats.Failed > 0 {
+				return fmt.Errorf("%d of %d operations failed", stats.Failed, stats.OK+stats.Failed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVarP(&parallel, "parallel", "j", 1, "operations to run at once")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "stop at the first failed operation")
+	return cmd
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tmc/mcp/internal/mcpcli"
+)
+
+func newSessionCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Keep a server connection open in the background",
+		Long: "session start connects to the server given by --cmd, --http, --sse or --server and keeps the connection\n" +
+			"open in a background process. Later commands run with --session <name> reuse it, so server\n" +
+			"state survives between invocations and notifications reach log tail and resource watch.",
+	}
+	cmd.AddCommand(
+		newSessionStartCommand(a),
+		newSessionServeCommand(a),
+		&cobra.Command{
+			Use:   "ls",
+			Short: "List sessions",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				sessions, err := mcpcli.ListSessions(a.cfg.StateDir)
+				if err != nil {
+					return err
+				}
+				type sessionStatus struct {
+					mcpcli.SessionInfo
+					Running bool `json:"running"`
+				}
+				statuses := make([]sessionStatus, len(sessions))
+				for i, info := range sessions {
+					statuses[i] = sessionStatus{info, info.Running(cmd.Context())}
+				}
+				if a.output == mcpcli.OutputJSON || a.output == mcpcli.OutputNDJSON {
+					data, err := json.MarshalIndent(statuses, "", "  ")
+					if err != nil {
+						return err
+					}
+					return mcpcli.WriteOutput("", data)
+				}
+				for _, s := range statuses {
+					state := "running"
+					if !s.Running {
+						state = "exited"
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "%s\t%d\t%s\t%s\n", s.Name, s.PID, state, s.Target())
+				}
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "stop <name>",
+			Short: "Stop a session and the server it started",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				ctx, cancel := cmdContext(cmd, a.cfg.Timeout)
+				defer cancel()
+				return mcpcli.StopSession(ctx, a.cfg.StateDir, args[0])
+			},
+		},
+		newSessionLogsCommand(a),
+	)
+	return cmd
+}
+
+func newSessionStartCommand(a *app) *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:   "start --name <name>",
+		Short: "Start a background session",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := mcpcli.ValidSessionName(name); err != nil {
+				return err
+			}
+			if a.cfg.Session != "" {
+				return errors.New("session start needs --cmd, --http, --sse or --server, not --session")
+			}
+			if a.cfg.Cmd == "" && a.cfg.HTTPURL == "" && a.cfg.SSEURL == "" && a.cfg.Server == "" {
+				return errors.New("session start needs one of --cmd, --http, --sse or --server")
+			}
+			ctx, cancel := cmdContext(cmd, a.cfg.Timeout)
+			defer cancel()
+			if info, err := mcpcli.ReadSession(a.cfg.StateDir, name); err == nil && info.Running(ctx) {
+				return fmt.Errorf("session %q is already running", name)
+			}
+			return startSessionDaemon(ctx, a.cfg, name)
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "session name")
+	return cmd
+}
+
+// startSessionDaemon runs "mcp session serve" in a new process and waits
+// until it accepts connections.
+func startSessionDaemon(ctx context.Context, cfg mcpcli.Config, name string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := []string{
+		"session", "serve", "--name", name,
+		"--state-dir", cfg.StateDir,
+		"--timeout", cfg.Timeout.String(),
+		"--protocol-version", cfg.ProtocolVersion,
+		"--server-stderr",
+	}
+	switch {
+	case cfg.Cmd != "":
+		args = append(args, "--cmd", cfg.Cmd)
+	case cfg.HTTPURL != "":
+		args = append(args, "--http", cfg.HTTPURL)
+	case cfg.SSEURL != "":
+		args = append(args, "--sse", cfg.SSEURL)
+	case cfg.Server != "":
+		args = append(args, "--server", cfg.Server)
+		if cfg.ConfigFile != "" {
+			args = append(args, "--config", cfg.ConfigFile)
+		}
+	}
+	if err := os.MkdirAll(mcpcli.SessionDir(cfg.StateDir), 0o700); err != nil {
+		return err
+	}
+	logPath := mcpcli.SessionLogPath(cfg.StateDir, name)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	daemon := exec.Command(exe, args...)
+	daemon.Stdout = logFile
+	daemon.Stderr = logFile
+	detach(daemon)
+	if err := daemon.Start(); err != nil {
+		return err
+	}
+	exited := make(chan struct{})
+	go func() {
+		daemon.Wait()
+		close(exited)
+	}()
+	for {
+		if info, err := mcpcli.ReadSession(cfg.StateDir, name); err == nil && info.PID == daemon.Process.Pid && info.Running(ctx) {
+			return nil
+		}
+		select {
+		case <-exited:
+			return fmt.Errorf("session %q exited during startup:\n%s", name, logTail(logPath))
+		case <-ctx.Done():
+			_ = daemon.Process.Kill()
+			return fmt.Errorf("session %q did not start: %w\n%s", name, ctx.Err(), logTail(logPath))
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// logTail returns the last lines of the log at path.
+func logTail(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	lines := bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+	if len(lines) > 20 {
+		lines = lines[len(lines)-20:]
+	}
+	return string(bytes.Join(lines, []byte("\n")))
+}
+
+func newSessionServeCommand(a *app) *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:    "serve --name <name>",
+		Short:  "Run a session in the foreground",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			logger := slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), nil)).With("session", name)
+			return mcpcli.ServeSession(ctx, a.cfg, name, logger)
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "session name")
+	return cmd
+}
+
+func newSessionLogsCommand(a *app) *cobra.Command {
+	var follow bool
+	cmd := &cobra.Command{
+		Use:   "logs <name>",
+		Short: "Print the log of a session and the stderr of its server",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := mcpcli.ValidSessionName(args[0]); err != nil {
+				return err
+			}
+			f, err := os.Open(mcpcli.SessionLogPath(a.cfg.StateDir, args[0]))
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("no session named %q", args[0])
+			}
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(cmd.OutOrStdout(), f); err != nil || !follow {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(250 * time.Millisecond):
+				}
+				if _, err := io.Copy(cmd.OutOrStdout(), f); err != nil {
+					return err
+				}
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "keep printing as the log grows")
+	return cmd
+}
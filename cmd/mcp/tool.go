@@ -99,7 +99,12 @@ func newToolCommand(ctx context.Context, a *app, opts bootstrapOptions) (*cobra.
 	}
 	cmd.AddCommand(listCmd, callCmd)
 
-	if opts.Config.Cmd == "" && opts.Config.HTTPURL == "" && opts.Config.SSEURL == "" {
+	if opts.Config.Cmd == "" && opts.Config.HTTPURL == "" && opts.Config.SSEURL == "" && opts.Config.Session == "" && opts.Config.Server == "" {
+		return cmd, nil
+	}
+	if opts.Command == "session" || opts.Command == "config" {
+		// Session and config commands manage connections; discovering
+		// tools would start the server only to throw the connection away.
 		return cmd, nil
 	}
 	sess, err := a.session(ctx)
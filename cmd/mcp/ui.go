@@ -3,45 +3,116 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/tmc/mcp"
 	"github.com/tmc/mcp/internal/mcpcli"
 )
 
+type uiTab int
+
+const (
+	tabTools uiTab = iota
+	tabResources
+	tabPrompts
+	tabTasks
+	tabLogs
+	tabTraffic
+	tabRoots
+	numTabs
+)
+
+var uiTabNames = [numTabs]string{"Tools", "Resources", "Prompts", "Tasks", "Logs", "Traffic", "Roots"}
+
+// uiLogLevels are the protocol logging levels from least to most severe.
+var uiLogLevels = []mcp.LoggingLevel{
+	mcp.LogLevelDebug, mcp.LogLevelInfo, mcp.LogLevelNotice, mcp.LogLevelWarning,
+	mcp.LogLevelError, mcp.LogLevelCritical, mcp.LogLevelAlert, mcp.LogLevelEmergency,
+}
+
+// uiMaxLines bounds the log and traffic panes.
+const uiMaxLines = 500
+
 type uiSnapshot struct {
 	roots     []string
-	tools     []string
-	resources []string
-	prompts   []string
+	tools     []mcp.Tool
+	resources []mcp.Resource
+	prompts   []mcp.Prompt
 	tasks     []string
 	err       error
 }
 
 type uiSnapshotMsg uiSnapshot
-type uiEventMsg string
+type uiEventMsg mcpcli.Event
 type uiTickMsg time.Time
 
+// uiResultMsg carries the outcome of a request made from the UI.
+type uiResultMsg struct {
+	tab     uiTab
+	text    string
+	err     error
+	traffic []string
+}
+
+type uiSubscribedMsg struct {
+	uri     string
+	on      bool
+	err     error
+	traffic []string
+}
+
+type uiProgress struct {
+	label    string
+	progress float64
+	total    float64
+	message  string
+}
+
+type uiLogLine struct {
+	level mcp.LoggingLevel
+	text  string
+}
+
 type uiModel struct {
 	app         *app
 	session     *mcpcli.Session
 	events      <-chan mcpcli.Event
 	unsubscribe func()
-	focus       int
+	tab         uiTab
 	width       int
 	height      int
-	roots       []string
-	tools       []string
-	resources   []string
-	prompts     []string
-	tasks       []string
-	logs        []string
-	err         error
+
+	roots     []string
+	tools     []mcp.Tool
+	resources []mcp.Resource
+	prompts   []mcp.Prompt
+	tasks     []string
+	err       error
+
+	cursor     [numTabs]int
+	detail     [numTabs]string
+	scroll     int
+	form       *uiForm
+	previewing string // URI shown in the resources pane
+	subscribed map[string]bool
+	updated    map[string]bool
+
+	progress  map[string]*uiProgress
+	tokens    []string          // progress tokens, oldest first
+	calls     map[string]string // tool called with each token from the UI
+	nextToken int
+
+	logs     []uiLogLine
+	minLevel int // index into uiLogLevels
+	traffic  []string
 }
 
 func newUICommand(a *app) *cobra.Command {
@@ -49,18 +120,34 @@ func newUICommand(a *app) *cobra.Command {
 		Use:     "ui",
 		Aliases: []string{"top"},
 		Short:   "Open an interactive MCP dashboard",
+		Long: "ui lists the server's tools, resources, prompts, tasks and roots. Tools and prompts are\n" +
+			"called through forms built from their schemas, resources can be previewed and subscribed to,\n" +
+			"and logging notifications and raw JSON-RPC traffic are tailed live.",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// The terminal belongs to the UI, so requests for user input
+			// cannot be asked there.
+			if a.interaction.ElicitPolicy == mcpcli.PolicyPrompt || a.interaction.ElicitPolicy == mcpcli.PolicyEditor {
+				a.interaction.ElicitPolicy = mcpcli.PolicyDecline
+			}
+			if a.interaction.SamplingPolicy == mcpcli.PolicyPrompt {
+				a.interaction.SamplingPolicy = mcpcli.PolicyDecline
+			}
 			sess, err := a.session(context.Background())
 			if err != nil {
 				return err
 			}
-			events, unsubscribe := sess.Subscribe(64)
+			events, unsubscribe := sess.Subscribe(256)
 			defer unsubscribe()
 			model := uiModel{
 				app:         a,
 				session:     sess,
 				events:      events,
 				unsubscribe: unsubscribe,
+				subscribed:  make(map[string]bool),
+				updated:     make(map[string]bool),
+				progress:    make(map[string]*uiProgress),
+				calls:       make(map[string]string),
+				minLevel:    1,
 			}
 			_, err = tea.NewProgram(model, tea.WithAltScreen()).Run()
 			return err
@@ -78,16 +165,13 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c":
+		if msg.String() == "ctrl+c" {
 			return m, tea.Quit
-		case "tab", "right", "l":
-			m.focus = (m.focus + 1) % 6
-		case "shift+tab", "left", "h":
-			m.focus = (m.focus + 5) % 6
-		case "r":
-			return m, m.refreshCmd()
 		}
+		if m.form != nil {
+			return m.updateForm(msg)
+		}
+		return m.updateKey(msg)
 	case uiSnapshotMsg:
 		m.roots = msg.roots
 		m.tools = msg.tools
@@ -95,81 +179,835 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.prompts = msg.prompts
 		m.tasks = msg.tasks
 		m.err = msg.err
+		m.clampCursors()
+	case uiResultMsg:
+		m.addTraffic(msg.traffic...)
+		if msg.err != nil {
+			m.detail[msg.tab] = "error: " + msg.err.Error()
+		} else {
+			m.detail[msg.tab] = msg.text
+		}
+		if msg.tab == m.tab {
+			m.scroll = 0
+		}
+	case uiSubscribedMsg:
+		m.addTraffic(msg.traffic...)
+		if msg.err != nil {
+			m.err = msg.err
+		} else {
+			m.subscribed[msg.uri] = msg.on
+		}
 	case uiEventMsg:
-		if msg != "" {
-			m.logs = append([]string{string(msg)}, m.logs...)
-			if len(m.logs) > 20 {
-				m.logs = m.logs[:20]
-			}
+		if msg.Method == "" {
+			// The subscription closed.
+			return m, nil
 		}
-		return m, m.waitEventCmd()
+		cmd := m.handleEvent(mcpcli.Event(msg))
+		return m, tea.Batch(cmd, m.waitEventCmd())
 	case uiTickMsg:
 		return m, tea.Batch(m.refreshCmd(), tickCmd())
 	}
 	return m, nil
 }
 
+func (m uiModel) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	switch key {
+	case "q":
+		return m, tea.Quit
+	case "tab", "right", "l":
+		m.setTab((m.tab + 1) % numTabs)
+	case "shift+tab", "left", "h":
+		m.setTab((m.tab + numTabs - 1) % numTabs)
+	case "1", "2", "3", "4", "5", "6", "7":
+		m.setTab(uiTab(key[0] - '1'))
+	case "r":
+		return m, m.refreshCmd()
+	case "up", "k":
+		if m.cursor[m.tab] > 0 {
+			m.cursor[m.tab]--
+		}
+	case "down", "j":
+		if m.cursor[m.tab] < m.listLen(m.tab)-1 {
+			m.cursor[m.tab]++
+		}
+	case "pgup":
+		m.scroll = max(0, m.scroll-m.paneHeight()/2)
+	case "pgdown":
+		m.scroll += m.paneHeight() / 2
+	case "enter":
+		cmd := m.activate()
+		return m, cmd
+	case "s":
+		if m.tab == tabResources && len(m.resources) > 0 {
+			return m, m.toggleSubscribeCmd(m.resources[m.cursor[tabResources]].URI)
+		}
+	case "+", "=":
+		if m.tab == tabLogs && m.minLevel < len(uiLogLevels)-1 {
+			m.minLevel++
+		}
+	case "-":
+		if m.tab == tabLogs && m.minLevel > 0 {
+			m.minLevel--
+		}
+	case "L":
+		if m.tab == tabLogs {
+			return m, m.setLevelCmd(uiLogLevels[m.minLevel])
+		}
+	case "c":
+		switch m.tab {
+		case tabLogs:
+			m.logs = nil
+		case tabTraffic:
+			m.traffic = nil
+		}
+	}
+	return m, nil
+}
+
+func (m *uiModel) setTab(tab uiTab) {
+	m.tab = tab
+	m.scroll = 0
+}
+
+// activate runs the action of the selected item: a form for tools and
+// prompts, a read for resources.
+func (m *uiModel) activate() tea.Cmd {
+	i := m.cursor[m.tab]
+	switch m.tab {
+	case tabTools:
+		if i < len(m.tools) {
+			m.form = newToolForm(m.tools[i])
+		}
+	case tabPrompts:
+		if i < len(m.prompts) {
+			m.form = newPromptForm(m.prompts[i])
+			if len(m.form.fields) == 0 {
+				m.form = nil
+				return m.getPromptCmd(m.prompts[i].Name, nil)
+			}
+		}
+	case tabResources:
+		if i < len(m.resources) {
+			uri := m.resources[i].URI
+			m.previewing = uri
+			delete(m.updated, uri)
+			return m.readResourceCmd(uri)
+		}
+	}
+	return nil
+}
+
+func (m uiModel) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := m.form
+	switch msg.String() {
+	case "esc":
+		m.form = nil
+		return m, nil
+	case "enter":
+		switch f.tab {
+		case tabTools:
+			args, err := f.toolArgs()
+			if err != nil {
+				f.err = err.Error()
+				return m, nil
+			}
+			m.form = nil
+			cmd := m.callToolCmd(f.name, args)
+			return m, cmd
+		default:
+			args, err := f.promptArgs()
+			if err != nil {
+				f.err = err.Error()
+				return m, nil
+			}
+			m.form = nil
+			return m, m.getPromptCmd(f.name, args)
+		}
+	default:
+		f.key(msg)
+	}
+	return m, nil
+}
+
+func (m *uiModel) handleEvent(event mcpcli.Event) tea.Cmd {
+	line, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "method": event.Method, "params": event.Params})
+	m.addTraffic(event.Time.Format("15:04:05") + " ← " + string(line))
+	switch mcp.Method(event.Method) {
+	case mcp.MethodLogging:
+		var n mcp.LoggingMessageNotification
+		if json.Unmarshal(event.Params, &n) == nil {
+			text := event.Time.Format("15:04:05") + " " + strings.ToUpper(string(n.Level))
+			if n.Logger != "" {
+				text += " " + n.Logger
+			}
+			text += " " + logData(n.Data)
+			m.logs = appendBounded(m.logs, uiLogLine{level: n.Level, text: text})
+		}
+	case mcp.MethodProgress:
+		var p struct {
+			ProgressToken any     `json:"progressToken"`
+			Progress      float64 `json:"progress"`
+			Total         float64 `json:"total"`
+			Message       string  `json:"message"`
+		}
+		if json.Unmarshal(event.Params, &p) == nil {
+			token := fmt.Sprint(p.ProgressToken)
+			prog, ok := m.progress[token]
+			if !ok {
+				prog = &uiProgress{label: token}
+				if name, ok := m.calls[token]; ok {
+					prog.label = name
+				}
+				m.progress[token] = prog
+				m.tokens = append(m.tokens, token)
+			}
+			prog.progress, prog.total, prog.message = p.Progress, p.Total, p.Message
+		}
+	case mcp.MethodResourceUpdated:
+		var p mcp.ResourceUpdatedNotificationParams
+		if json.Unmarshal(event.Params, &p) == nil {
+			if p.URI == m.previewing {
+				return m.readResourceCmd(p.URI)
+			}
+			m.updated[p.URI] = true
+		}
+	case mcp.MethodToolListChanged, mcp.MethodResourceListChanged, mcp.MethodPromptListChanged, mcp.MethodTasksStatus:
+		return m.refreshCmd()
+	}
+	return nil
+}
+
+// logData renders the data of a logging notification: strings as they are,
+// anything else as JSON.
+func logData(data json.RawMessage) string {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return s
+	}
+	return string(data)
+}
+
+func (m *uiModel) addTraffic(lines ...string) {
+	for _, line := range lines {
+		m.traffic = appendBounded(m.traffic, line)
+	}
+}
+
+func appendBounded[T any](s []T, v T) []T {
+	s = append(s, v)
+	if len(s) > uiMaxLines {
+		s = s[len(s)-uiMaxLines:]
+	}
+	return s
+}
+
+func (m uiModel) listLen(tab uiTab) int {
+	switch tab {
+	case tabTools:
+		return len(m.tools)
+	case tabResources:
+		return len(m.resources)
+	case tabPrompts:
+		return len(m.prompts)
+	case tabTasks:
+		return len(m.tasks)
+	case tabRoots:
+		return len(m.roots)
+	}
+	return 0
+}
+
+func (m *uiModel) clampCursors() {
+	for tab := range numTabs {
+		if n := m.listLen(tab); m.cursor[tab] >= n {
+			m.cursor[tab] = max(0, n-1)
+		}
+	}
+}
+
+func (m uiModel) paneHeight() int {
+	return max(5, m.height-6)
+}
+
 func (m uiModel) View() string {
 	if m.width == 0 {
 		return "loading..."
 	}
+	var tabs []string
+	for tab := range numTabs {
+		label := fmt.Sprintf(" %d %s ", tab+1, uiTabNames[tab])
+		style := lipgloss.NewStyle().Faint(true)
+		if tab == m.tab {
+			style = lipgloss.NewStyle().Bold(true).Reverse(true)
+		}
+		tabs = append(tabs, style.Render(label))
+	}
 	title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12")).Render("mcp ui")
-	help := lipgloss.NewStyle().Faint(true).Render("tab: cycle  r: refresh  q: quit")
-	header := lipgloss.JoinHorizontal(lipgloss.Top, title, "  ", help)
+	header := title + "  " + strings.Join(tabs, " ")
+	status := lipgloss.NewStyle().Faint(true).Render(m.help())
 	if m.err != nil {
-		header += "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(m.err.Error())
+		status = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(m.err.Error())
+	}
+
+	height := m.paneHeight()
+	var body string
+	switch m.tab {
+	case tabLogs:
+		title := fmt.Sprintf("Logs (%s and above)", uiLogLevels[m.minLevel])
+		body = pane(title, m.logLines(), m.width-2, height, true)
+	case tabTraffic:
+		body = pane("JSON-RPC traffic", m.traffic, m.width-2, height, true)
+	case tabTasks:
+		body = pane("Tasks", append(m.progressLines(m.width-6), m.tasks...), m.width-2, height, false)
+	default:
+		listWidth := max(24, m.width/3)
+		detailWidth := m.width - listWidth - 5
+		list := pane(uiTabNames[m.tab], m.listLines(listWidth-4), listWidth, height, false)
+		detail := m.detailView(detailWidth, height)
+		body = lipgloss.JoinHorizontal(lipgloss.Top, list, " ", detail)
+	}
+	return header + "\n" + status + "\n" + body
+}
+
+func (m uiModel) help() string {
+	if m.form != nil {
+		return "tab/↓: next field  ↑: previous  ←/→ or space: change choice  enter: submit  esc: cancel"
 	}
+	common := "1-7/tab: switch  r: refresh  q: quit"
+	switch m.tab {
+	case tabTools:
+		return "↑/↓: select  enter: call  pgup/pgdn: scroll  " + common
+	case tabResources:
+		return "↑/↓: select  enter: preview  s: subscribe  pgup/pgdn: scroll  " + common
+	case tabPrompts:
+		return "↑/↓: select  enter: get  pgup/pgdn: scroll  " + common
+	case tabLogs:
+		return "+/-: filter level  L: set server level  c: clear  " + common
+	case tabTraffic:
+		return "c: clear  " + common
+	}
+	return common
+}
 
-	leftWidth := max(30, m.width/3)
-	rightWidth := m.width - leftWidth - 3
-	sectionHeight := max(6, (m.height-4)/3)
+func (m uiModel) listLines(width int) []string {
+	var items []string
+	switch m.tab {
+	case tabTools:
+		for _, t := range m.tools {
+			items = append(items, t.Name)
+		}
+	case tabResources:
+		for _, r := range m.resources {
+			mark := "  "
+			switch {
+			case m.updated[r.URI]:
+				mark = "* "
+			case m.subscribed[r.URI]:
+				mark = "● "
+			}
+			items = append(items, mark+r.URI)
+		}
+	case tabPrompts:
+		for _, p := range m.prompts {
+			items = append(items, p.Name)
+		}
+	case tabRoots:
+		items = m.roots
+	}
+	cursor := m.cursor[m.tab]
+	lines := make([]string, len(items))
+	for i, item := range items {
+		item = truncate(item, width-2)
+		if i == cursor {
+			lines[i] = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12")).Render("> " + item)
+		} else {
+			lines[i] = "  " + item
+		}
+	}
+	// Keep the cursor in view.
+	if visible := m.paneHeight() - 3; cursor >= visible {
+		lines = lines[cursor-visible+1:]
+	}
+	return lines
+}
 
-	left := lipgloss.JoinVertical(lipgloss.Left,
-		m.section("Roots", m.roots, 0, leftWidth, sectionHeight),
-		m.section("Tasks", m.tasks, 1, leftWidth, sectionHeight),
-		m.section("Logs", m.logs, 2, leftWidth, sectionHeight),
-	)
-	right := lipgloss.JoinVertical(lipgloss.Left,
-		m.section("Tools", m.tools, 3, rightWidth, sectionHeight),
-		m.section("Resources", m.resources, 4, rightWidth, sectionHeight),
-		m.section("Prompts", m.prompts, 5, rightWidth, sectionHeight),
-	)
-	body := lipgloss.JoinHorizontal(lipgloss.Top, left, "   ", right)
-	return header + "\n\n" + body
+func (m uiModel) detailView(width, height int) string {
+	if m.form != nil {
+		return pane(m.form.title, m.form.lines(width-4), width, height, false)
+	}
+	var text string
+	i := m.cursor[m.tab]
+	switch m.tab {
+	case tabTools:
+		if i < len(m.tools) {
+			text = toolSummary(m.tools[i])
+		}
+	case tabPrompts:
+		if i < len(m.prompts) {
+			text = promptSummary(m.prompts[i])
+		}
+	case tabResources:
+		if i < len(m.resources) && m.previewing != m.resources[i].URI {
+			r := m.resources[i]
+			text = strings.TrimSpace(fmt.Sprintf("%s\n%s\n%s", r.Name, r.MimeType, r.Description))
+		}
+	}
+	if result := m.detail[m.tab]; result != "" {
+		if text != "" {
+			text += "\n\n"
+		}
+		text += result
+	}
+	lines := strings.Split(lipgloss.NewStyle().Width(width-4).Render(text), "\n")
+	if m.scroll < len(lines) {
+		lines = lines[m.scroll:]
+	}
+	return pane("Detail", lines, width, height, false)
 }
 
-func (m uiModel) section(title string, lines []string, focus, width, height int) string {
-	border := lipgloss.NormalBorder()
+// pane draws a bordered box of lines. With tail set it shows the last lines
+// that fit instead of the first.
+func pane(title string, lines []string, width, height int, tail bool) string {
 	style := lipgloss.NewStyle().
 		Width(width).
 		Height(height).
-		Border(border).
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("8")).
 		Padding(0, 1)
-	if m.focus == focus {
-		style = style.BorderForeground(lipgloss.Color("12"))
-	} else {
-		style = style.BorderForeground(lipgloss.Color("8"))
-	}
 	if len(lines) == 0 {
 		lines = []string{"(empty)"}
 	}
-	if len(lines) > height-2 {
-		lines = lines[:height-2]
+	if room := height - 1; len(lines) > room {
+		if tail {
+			lines = lines[len(lines)-room:]
+		} else {
+			lines = lines[:room]
+		}
+	}
+	for i, line := range lines {
+		lines[i] = truncate(line, width-2)
+	}
+	return style.Render(lipgloss.NewStyle().Bold(true).Render(title) + "\n" + strings.Join(lines, "\n"))
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 || lipgloss.Width(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	if len(r) > width {
+		r = r[:max(0, width-1)]
+	}
+	return string(r) + "…"
+}
+
+func (m uiModel) logLines() []string {
+	var lines []string
+	for _, l := range m.logs {
+		if levelIndex(l.level) < m.minLevel {
+			continue
+		}
+		style := lipgloss.NewStyle()
+		switch {
+		case levelIndex(l.level) >= levelIndex(mcp.LogLevelError):
+			style = style.Foreground(lipgloss.Color("9"))
+		case l.level == mcp.LogLevelWarning:
+			style = style.Foreground(lipgloss.Color("11"))
+		case l.level == mcp.LogLevelDebug:
+			style = style.Faint(true)
+		}
+		lines = append(lines, style.Render(l.text))
+	}
+	return lines
+}
+
+func levelIndex(level mcp.LoggingLevel) int {
+	for i, l := range uiLogLevels {
+		if l == level {
+			return i
+		}
+	}
+	return 0
+}
+
+func (m uiModel) progressLines(width int) []string {
+	var lines []string
+	for _, token := range m.tokens {
+		p := m.progress[token]
+		lines = append(lines, progressBar(p, min(40, width/2))+" "+p.label+" "+p.message)
+	}
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// progressBar draws p as a bar of the given width, or as a count when the
+// total is unknown.
+func progressBar(p *uiProgress, width int) string {
+	if p.total <= 0 {
+		return fmt.Sprintf("[%*s] %v", width, "…", p.progress)
+	}
+	frac := min(1, max(0, p.progress/p.total))
+	filled := int(frac * float64(width))
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + fmt.Sprintf("] %3.0f%%", frac*100)
+}
+
+func toolSummary(tool mcp.Tool) string {
+	var b strings.Builder
+	b.WriteString(tool.Name)
+	if tool.Description != "" {
+		b.WriteString("\n" + tool.Description)
+	}
+	form := newToolForm(tool)
+	if len(form.fields) > 0 {
+		b.WriteString("\n\nArguments:")
+		for _, f := range form.fields {
+			b.WriteString("\n  " + f.label)
+			if f.help != "" {
+				b.WriteString(": " + f.help)
+			}
+		}
+	}
+	return b.String()
+}
+
+func promptSummary(prompt mcp.Prompt) string {
+	var b strings.Builder
+	b.WriteString(prompt.Name)
+	if prompt.Description != "" {
+		b.WriteString("\n" + prompt.Description)
+	}
+	if len(prompt.Arguments) > 0 {
+		b.WriteString("\n\nArguments:")
+		for _, arg := range prompt.Arguments {
+			b.WriteString("\n  " + arg.Name)
+			if arg.Required {
+				b.WriteString(" (required)")
+			}
+			if arg.Description != "" {
+				b.WriteString(": " + arg.Description)
+			}
+		}
+	}
+	return b.String()
+}
+
+// uiForm collects the arguments of a tool or prompt.
+type uiForm struct {
+	tab    uiTab
+	name   string
+	title  string
+	fields []*uiField
+	focus  int
+	err    string
+}
+
+type uiField struct {
+	label    string
+	help     string
+	required bool
+	choices  []string // enum values, or true and false
+	value    string
+	binding  *propertyBinding // tool arguments only
+}
+
+// newToolForm builds a form with a field per property of the tool's input
+// schema, bound the same way as the flags of "mcp tool call".
+func newToolForm(tool mcp.Tool) *uiForm {
+	f := &uiForm{tab: tabTools, name: tool.Name, title: "Call " + tool.Name}
+	var schema jsonSchema
+	if len(tool.InputSchema) > 0 {
+		_ = json.Unmarshal(tool.InputSchema, &schema)
+	}
+	required := make(map[string]bool)
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	keys := make([]string, 0, len(schema.Properties))
+	for key := range schema.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	used := make(map[string]int)
+	for _, key := range keys {
+		prop := schema.Properties[key]
+		binding := bindProperty(key, prop, required[key], used)
+		field := &uiField{
+			label:    key,
+			help:     binding.usage,
+			required: required[key],
+			choices:  binding.enumValues,
+			binding:  binding,
+		}
+		if binding.kind == "bool" {
+			field.choices = []string{"", "true", "false"}
+		}
+		if prop.Default != nil {
+			if s, ok := prop.Default.(string); ok {
+				field.value = s
+			} else {
+				data, _ := json.Marshal(prop.Default)
+				field.value = string(data)
+			}
+		}
+		f.fields = append(f.fields, field)
+	}
+	return f
+}
+
+func newPromptForm(prompt mcp.Prompt) *uiForm {
+	f := &uiForm{tab: tabPrompts, name: prompt.Name, title: "Get " + prompt.Name}
+	for _, arg := range prompt.Arguments {
+		f.fields = append(f.fields, &uiField{label: arg.Name, help: arg.Description, required: arg.Required})
+	}
+	return f
+}
+
+// key edits the form: typing changes the focused field, arrows move between
+// fields or through the choices of an enum field.
+func (f *uiForm) key(msg tea.KeyMsg) {
+	f.err = ""
+	if len(f.fields) == 0 {
+		return
+	}
+	field := f.fields[f.focus]
+	switch msg.String() {
+	case "tab", "down":
+		f.focus = (f.focus + 1) % len(f.fields)
+	case "shift+tab", "up":
+		f.focus = (f.focus + len(f.fields) - 1) % len(f.fields)
+	case "left", "right", " ":
+		if len(field.choices) == 0 {
+			if msg.String() == " " {
+				field.value += " "
+			}
+			return
+		}
+		step := 1
+		if msg.String() == "left" {
+			step = len(field.choices) - 1
+		}
+		i := 0
+		for j, c := range field.choices {
+			if c == field.value {
+				i = j
+			}
+		}
+		field.value = field.choices[(i+step)%len(field.choices)]
+	case "backspace":
+		if r := []rune(field.value); len(r) > 0 {
+			field.value = string(r[:len(r)-1])
+		}
+	case "ctrl+u":
+		field.value = ""
+	default:
+		if msg.Type == tea.KeyRunes && len(field.choices) == 0 {
+			field.value += string(msg.Runes)
+		}
+	}
+}
+
+func (f *uiForm) lines(width int) []string {
+	var lines []string
+	for i, field := range f.fields {
+		label := field.label
+		if field.required {
+			label += "*"
+		}
+		value := field.value
+		if len(field.choices) > 0 {
+			value = "‹ " + value + " ›"
+		}
+		line := fmt.Sprintf("%s: %s", label, value)
+		if i == f.focus {
+			line = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12")).Render("> " + line + "▏")
+		} else {
+			line = "  " + line
+		}
+		lines = append(lines, line)
+		if field.help != "" {
+			lines = append(lines, lipgloss.NewStyle().Faint(true).Render("    "+truncate(field.help, width-4)))
+		}
+	}
+	if len(f.fields) == 0 {
+		lines = append(lines, "(no arguments)")
+	}
+	if f.err != "" {
+		lines = append(lines, "", lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(f.err))
+	}
+	return lines
+}
+
+// toolArgs converts the fields to tool arguments by setting them as the flags
+// "mcp tool call" would define. Empty fields are left out.
+func (f *uiForm) toolArgs() (json.RawMessage, error) {
+	flags := pflag.NewFlagSet(f.name, pflag.ContinueOnError)
+	args := make(map[string]any)
+	for _, field := range f.fields {
+		if field.value == "" {
+			if field.required {
+				return nil, fmt.Errorf("%s is required", field.label)
+			}
+			continue
+		}
+		addFlag(flags, field.binding)
+		if err := flags.Set(field.binding.flagName, field.value); err != nil {
+			return nil, fmt.Errorf("%s: %w", field.label, err)
+		}
+		value, err := field.binding.value()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.label, err)
+		}
+		args[field.label] = value
+	}
+	return json.Marshal(args)
+}
+
+// promptArgs converts the fields to prompt arguments. Empty fields are left
+// out.
+func (f *uiForm) promptArgs() (map[string]any, error) {
+	args := make(map[string]any)
+	for _, field := range f.fields {
+		if field.value == "" {
+			if field.required {
+				return nil, fmt.Errorf("%s is required", field.label)
+			}
+			continue
+		}
+		args[field.label] = field.value
+	}
+	return args, nil
+}
+
+// trafficLine renders a request or response made from the UI for the
+// traffic pane.
+func trafficLine(arrow string, v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data = []byte(err.Error())
+	}
+	return time.Now().Format("15:04:05") + " " + arrow + " " + string(data)
+}
+
+// request runs do with the request timeout and records method, params and
+// the outcome as traffic.
+func (m uiModel) request(method string, params any, do func(context.Context) (any, error)) (any, []string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.app.cfg.Timeout)
+	defer cancel()
+	traffic := []string{trafficLine("→", map[string]any{"method": method, "params": params})}
+	result, err := do(ctx)
+	if err != nil {
+		traffic = append(traffic, trafficLine("←", map[string]any{"error": err.Error()}))
+	} else {
+		traffic = append(traffic, trafficLine("←", map[string]any{"result": result}))
+	}
+	return result, traffic, err
+}
+
+func (m *uiModel) callToolCmd(name string, args json.RawMessage) tea.Cmd {
+	m.nextToken++
+	token := fmt.Sprintf("ui-%d", m.nextToken)
+	m.calls[token] = name
+	m.detail[tabTools] = "calling " + name + "..."
+	req := mcp.CallToolRequest{Name: name, Arguments: args, Meta: map[string]any{"progressToken": token}}
+	return func() tea.Msg {
+		result, traffic, err := m.request(string(mcp.MethodToolsCall), req, func(ctx context.Context) (any, error) {
+			return m.session.Client().CallTool(ctx, req)
+		})
+		msg := uiResultMsg{tab: tabTools, err: err, traffic: traffic}
+		if err == nil {
+			data, err := mcpcli.RenderToolResult(result.(*mcp.CallToolResult), mcpcli.OutputText)
+			msg.text, msg.err = string(data), err
+		}
+		return msg
+	}
+}
+
+func (m uiModel) getPromptCmd(name string, args map[string]any) tea.Cmd {
+	req := mcp.GetPromptRequest{Name: name, Arguments: args}
+	return func() tea.Msg {
+		result, traffic, err := m.request(string(mcp.MethodPromptsGet), req, func(ctx context.Context) (any, error) {
+			return m.session.Client().GetPrompt(ctx, req)
+		})
+		msg := uiResultMsg{tab: tabPrompts, err: err, traffic: traffic}
+		if err == nil {
+			data, err := mcpcli.RenderPromptResult(result.(*mcp.GetPromptResult), mcpcli.OutputText)
+			msg.text, msg.err = string(data), err
+		}
+		return msg
+	}
+}
+
+func (m uiModel) readResourceCmd(uri string) tea.Cmd {
+	req := mcp.ReadResourceRequest{URI: uri}
+	return func() tea.Msg {
+		result, traffic, err := m.request(string(mcp.MethodResourcesRead), req, func(ctx context.Context) (any, error) {
+			return m.session.Client().ReadResource(ctx, req)
+		})
+		msg := uiResultMsg{tab: tabResources, err: err, traffic: traffic}
+		if err == nil {
+			data, err := mcpcli.RenderResourceResult(result.(*mcp.ReadResourceResult), mcpcli.OutputText)
+			msg.text, msg.err = uri+"\n\n"+string(data), err
+		}
+		return msg
+	}
+}
+
+func (m uiModel) toggleSubscribeCmd(uri string) tea.Cmd {
+	on := !m.subscribed[uri]
+	init := m.session.InitializeResult()
+	if init.Capabilities.Resources == nil || !init.Capabilities.Resources.Subscribe {
+		return func() tea.Msg {
+			return uiSubscribedMsg{uri: uri, err: errors.New("server does not support resource subscriptions")}
+		}
+	}
+	return func() tea.Msg {
+		method := mcp.MethodResourcesSubscribe
+		if !on {
+			method = mcp.MethodResourcesUnsubscribe
+		}
+		_, traffic, err := m.request(string(method), map[string]string{"uri": uri}, func(ctx context.Context) (any, error) {
+			if on {
+				return nil, m.session.Client().SubscribeResource(ctx, mcp.SubscribeResourceRequest{URI: uri})
+			}
+			return nil, m.session.Client().UnsubscribeResource(ctx, mcp.UnsubscribeResourceRequest{URI: uri})
+		})
+		return uiSubscribedMsg{uri: uri, on: on, err: err, traffic: traffic}
+	}
+}
+
+func (m uiModel) setLevelCmd(level mcp.LoggingLevel) tea.Cmd {
+	return func() tea.Msg {
+		_, traffic, err := m.request(string(mcp.MethodLoggingSetLevel), mcp.SetLevelRequest{Level: level}, func(ctx context.Context) (any, error) {
+			return nil, m.session.Client().SetLoggingLevel(ctx, level)
+		})
+		return uiResultMsg{tab: tabLogs, err: err, traffic: traffic}
 	}
-	return style.Render(title + "\n" + strings.Join(lines, "\n"))
 }
 
 func (m uiModel) refreshCmd() tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), m.app.cfg.Timeout)
 		defer cancel()
-		snapshot := uiSnapshot{
-			roots:     mustRoots(m.app),
-			tools:     mustTools(ctx, m.session),
-			resources: mustResources(ctx, m.session),
-			prompts:   mustPrompts(ctx, m.session),
-			tasks:     mustTasks(ctx, m.session),
+		var snapshot uiSnapshot
+		var errs []error
+		snapshot.roots = mustRoots(m.app)
+		if m.session.Supports("tools") {
+			tools, err := m.session.ListToolsAll(ctx)
+			snapshot.tools, errs = tools, append(errs, err)
+		}
+		if m.session.Supports("resources") {
+			resources, err := m.session.ListResourcesAll(ctx)
+			snapshot.resources, errs = resources, append(errs, err)
 		}
+		if m.session.Supports("prompts") {
+			prompts, err := m.session.ListPromptsAll(ctx)
+			snapshot.prompts, errs = prompts, append(errs, err)
+		}
+		snapshot.tasks = mustTasks(ctx, m.session)
+		snapshot.err = errors.Join(errs...)
 		return uiSnapshotMsg(snapshot)
 	}
 }
@@ -178,12 +1016,9 @@ func (m uiModel) waitEventCmd() tea.Cmd {
 	return func() tea.Msg {
 		event, ok := <-m.events
 		if !ok {
-			return uiEventMsg("")
-		}
-		if len(event.Params) == 0 {
-			return uiEventMsg(event.Method)
+			return uiEventMsg{}
 		}
-		return uiEventMsg(fmt.Sprintf("%s %s", event.Method, string(event.Params)))
+		return uiEventMsg(event)
 	}
 }
 
@@ -211,42 +1046,6 @@ func mustRoots(a *app) []string {
 	return out
 }
 
-func mustTools(ctx context.Context, sess *mcpcli.Session) []string {
-	tools, err := sess.ListToolsAll(ctx)
-	if err != nil {
-		return []string{err.Error()}
-	}
-	out := make([]string, 0, len(tools))
-	for _, tool := range tools {
-		out = append(out, cobraName(tool.Name))
-	}
-	return out
-}
-
-func mustResources(ctx context.Context, sess *mcpcli.Session) []string {
-	resources, err := sess.ListResourcesAll(ctx)
-	if err != nil {
-		return []string{err.Error()}
-	}
-	out := make([]string, 0, len(resources))
-	for _, resource := range resources {
-		out = append(out, resource.URI)
-	}
-	return out
-}
-
-func mustPrompts(ctx context.Context, sess *mcpcli.Session) []string {
-	prompts, err := sess.ListPromptsAll(ctx)
-	if err != nil {
-		return []string{err.Error()}
-	}
-	out := make([]string, 0, len(prompts))
-	for _, prompt := range prompts {
-		out = append(out, prompt.Name)
-	}
-	return out
-}
-
 func mustTasks(ctx context.Context, sess *mcpcli.Session) []string {
 	if !sess.Supports("tasks") {
 		return []string{"(unsupported)"}
@@ -257,19 +1056,11 @@ func mustTasks(ctx context.Context, sess *mcpcli.Session) []string {
 	}
 	out := make([]string, 0, len(tasks.Tasks))
 	for _, task := range tasks.Tasks {
-		out = append(out, fmt.Sprintf("%s %s", task.TaskID, task.Status))
+		line := fmt.Sprintf("%s %s", task.TaskID, task.Status)
+		if task.StatusMessage != "" {
+			line += " " + task.StatusMessage
+		}
+		out = append(out, line)
 	}
 	return out
 }
-
-func max(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
-}
-
-func _json(v any) string {
-	data, _ := json.Marshal(v)
-	return string(data)
-}
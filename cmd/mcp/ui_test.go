@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tmc/mcp"
+	"github.com/tmc/mcp/internal/mcpcli"
+)
+
+func TestUIToolForm(t *testing.T) {
+	form := newToolForm(mcp.Tool{
+		Name: "search",
+		InputSchema: json.RawMessage(`{"type": "object", "properties": {
+			"query": {"type": "string"},
+			"limit": {"type": "integer", "default": 10},
+			"exact": {"type": "boolean"},
+			"sort": {"type": "string", "enum": ["new", "old"]},
+			"filter": {"type": "object"}
+		}, "required": ["query"]}`),
+	})
+	if _, err := form.toolArgs(); err == nil || !strings.Contains(err.Error(), "query") {
+		t.Errorf("toolArgs without the required field = %v", err)
+	}
+
+	// Fields are in name order: exact, filter, limit, query, sort.
+	typeKeys := func(s string) {
+		for _, r := range s {
+			form.key(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+	form.key(tea.KeyMsg{Type: tea.KeyRight}) // exact: true
+	form.key(tea.KeyMsg{Type: tea.KeyTab})
+	typeKeys(`{"a":1}`)
+	form.key(tea.KeyMsg{Type: tea.KeyTab})
+	form.key(tea.KeyMsg{Type: tea.KeyBackspace}) // limit: 1
+	form.key(tea.KeyMsg{Type: tea.KeyTab})
+	typeKeys("mcp go")
+	form.key(tea.KeyMsg{Type: tea.KeyTab})
+	form.key(tea.KeyMsg{Type: tea.KeyLeft}) // sort: wraps to old
+
+	raw, err := form.toolArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var args map[string]any
+	if err := json.Unmarshal(raw, &args); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"exact":true,"filter":{"a":1},"limit":1,"query":"mcp go","sort":"old"}`
+	if got, _ := json.Marshal(args); string(got) != want {
+		t.Errorf("toolArgs = %s, want %s", got, want)
+	}
+
+	form.fields[2].value = "many"
+	if _, err := form.toolArgs(); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("toolArgs with a bad integer = %v", err)
+	}
+}
+
+func TestUIEvents(t *testing.T) {
+	m := uiModel{progress: make(map[string]*uiProgress), updated: make(map[string]bool), minLevel: 1}
+	event := func(method mcp.Method, params string) {
+		m.handleEvent(mcpcli.Event{Time: time.Now(), Method: string(method), Params: json.RawMessage(params)})
+	}
+	event(mcp.MethodLogging, `{"level": "debug", "data": "noisy"}`)
+	event(mcp.MethodLogging, `{"level": "error", "logger": "db", "data": {"code": 7}}`)
+	event(mcp.MethodProgress, `{"progressToken": "ui-1", "progress": 5, "total": 10, "message": "half"}`)
+	event(mcp.MethodResourceUpdated, `{"uri": "file:///a"}`)
+
+	if lines := m.logLines(); len(lines) != 1 || !strings.Contains(lines[0], `ERROR db {"code": 7}`) {
+		t.Errorf("log lines at info = %q", lines)
+	}
+	m.minLevel = 0
+	if lines := m.logLines(); len(lines) != 2 {
+		t.Errorf("log lines at debug = %q", lines)
+	}
+	if len(m.traffic) != 4 || !strings.Contains(m.traffic[0], `"method":"notifications/message"`) {
+		t.Errorf("traffic = %q", m.traffic)
+	}
+	if bar := progressBar(m.progress["ui-1"], 10); bar != "[█████░░░░░]  50%" {
+		t.Errorf("progress bar = %q", bar)
+	}
+	if !m.updated["file:///a"] {
+		t.Error("resource update not marked")
+	}
+
+	m.width, m.height = 100, 30
+	for tab := range numTabs {
+		m.tab = tab
+		if view := m.View(); !strings.Contains(view, uiTabNames[tab]) {
+			t.Errorf("view of tab %s:\n%s", uiTabNames[tab], view)
+		}
+	}
+}
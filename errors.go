@@ -1,6 +1,8 @@
 package mcp
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -171,3 +173,52 @@ type ResponseError struct {
 func (e *ResponseError) Error() string {
 	return fmt.Sprintf("MCP error %d: %s", e.Code, e.Message)
 }
+
+// wireError returns the JSON-RPC error object to send for err, or nil if
+// err carries no code. Unknown methods map to -32601, invalid parameters and
+// unknown tools and prompts to -32602, and unknown resources to -32002, as
+// the specification requires.
+func wireError(err error) *ResponseError {
+	var re *ResponseError
+	var pe *ParameterError
+	var nf *NotFoundError
+	switch {
+	case errors.As(err, &re):
+		return re
+	case errors.As(err, &pe):
+		return &ResponseError{Code: -32602, Message: err.Error()}
+	case errors.As(err, &nf):
+		code := -32602
+		switch nf.Type {
+		case "method":
+			code = -32601
+		case "resource":
+			code = -32002
+		}
+		return &ResponseError{Code: code, Message: err.Error()}
+	}
+	return nil
+}
+
+// AsResponseError returns the JSON-RPC error object carried by err. It accepts
+// both *ResponseError values and the errors returned by Client calls, whose
+// code and data come from the peer's response.
+func AsResponseError(err error) (*ResponseError, bool) {
+	var re *ResponseError
+	if errors.As(err, &re) {
+		return re, true
+	}
+	for ; err != nil; err = errors.Unwrap(err) {
+		// Errors decoded by jsonrpc2 are not exported, but marshal back
+		// to the wire error object.
+		raw, merr := json.Marshal(err)
+		if merr != nil {
+			continue
+		}
+		var decoded ResponseError
+		if json.Unmarshal(raw, &decoded) == nil && (decoded.Code != 0 || decoded.Message != "") {
+			return &decoded, true
+		}
+	}
+	return nil, false
+}
@@ -74,8 +74,8 @@ func Example() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	if content, ok := res.Content[0].(map[string]any); ok {
-		fmt.Printf("Calculation result: %v\n", content["text"])
+	if content, ok := res.Content[0].(mcp.TextContent); ok {
+		fmt.Printf("Calculation result: %v\n", content.Text)
 	}
 
 	// Output:
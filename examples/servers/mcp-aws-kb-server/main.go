@@ -9,6 +9,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -56,12 +57,11 @@ func main() {
 
 	// Serve via stdio
 	log.Println("Starting protocol server via stdio...")
-	if err := server.Serve(ctx, nil); err != nil {
-		if err != context.Canceled {
-			log.Fatalf("Error serving: %v", err)
-		}
-		log.Println("Server terminated.")
+	// Serve until a signal, then let in-flight calls finish.
+	if err := server.ServeUntilDone(ctx, nil, 10*time.Second); err != nil {
+		log.Fatalf("Error serving: %v", err)
 	}
+	log.Println("Server terminated.")
 }
 
 func (s *AWSKBServer) registerTools(server *mcp.Server) {
@@ -165,7 +165,7 @@ type QueryKnowledgeBaseRequest struct {
 
 func (s *AWSKBServer) handleQueryKnowledgeBase(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var params QueryKnowledgeBaseRequest
-	if err := json.Unmarshal(req.Params.Arguments, &params); err != nil {
+	if err := json.Unmarshal(req.Arguments, &params); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
@@ -219,9 +219,7 @@ func (s *AWSKBServer) handleQueryKnowledgeBase(ctx context.Context, req mcp.Call
 
 			location := "N/A"
 			if retrievalResult.Location != nil && retrievalResult.Location.S3Location != nil {
-				location = fmt.Sprintf("s3://%s/%s",
-					aws.ToString(retrievalResult.Location.S3Location.Uri),
-					aws.ToString(retrievalResult.Location.S3Location.BucketOwnerAccountId))
+				location = aws.ToString(retrievalResult.Location.S3Location.Uri)
 			}
 
 			resultTexts = append(resultTexts, fmt.Sprintf("Result %d (Score: %s, Location: %s):\n%s",
@@ -255,7 +253,7 @@ type RetrieveAndGenerateRequest struct {
 
 func (s *AWSKBServer) handleRetrieveAndGenerate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var params RetrieveAndGenerateRequest
-	if err := json.Unmarshal(req.Params.Arguments, &params); err != nil {
+	if err := json.Unmarshal(req.Arguments, &params); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
@@ -14,7 +14,6 @@ import (
 	"time"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 type BraveSearchServer struct {
@@ -283,7 +282,11 @@ func main() {
 	srv := mcp.NewServer("bravesearch-server", "1.0.0")
 
 	// Register web_search tool
-	srv.RegisterTool("web_search", "Search the web using Brave Search API", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "web_search", Description: "Search the web using Brave Search API"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var queryRaw json.RawMessage
 		var exists bool
 		if queryRaw, exists = args["query"]; !exists {
@@ -325,22 +328,22 @@ func main() {
 
 		result, err := bss.search(query, searchType, count, country, safesearch)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error performing search: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Performed %s search for: %s", searchType, query)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -349,7 +352,11 @@ func main() {
 	})
 
 	// Register news_search tool
-	srv.RegisterTool("news_search", "Search for news using Brave Search API", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "news_search", Description: "Search for news using Brave Search API"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var queryRaw json.RawMessage
 		var exists bool
 		if queryRaw, exists = args["query"]; !exists {
@@ -377,22 +384,22 @@ func main() {
 
 		result, err := bss.search(query, "news", count, country, "")
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error performing news search: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Performed news search for: %s", query)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -401,7 +408,11 @@ func main() {
 	})
 
 	// Register image_search tool
-	srv.RegisterTool("image_search", "Search for images using Brave Search API", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "image_search", Description: "Search for images using Brave Search API"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var queryRaw json.RawMessage
 		var exists bool
 		if queryRaw, exists = args["query"]; !exists {
@@ -429,22 +440,22 @@ func main() {
 
 		result, err := bss.search(query, "images", count, "", safesearch)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error performing image search: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Performed image search for: %s", query)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -453,7 +464,11 @@ func main() {
 	})
 
 	// Register video_search tool
-	srv.RegisterTool("video_search", "Search for videos using Brave Search API", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "video_search", Description: "Search for videos using Brave Search API"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var queryRaw json.RawMessage
 		var exists bool
 		if queryRaw, exists = args["query"]; !exists {
@@ -481,22 +496,22 @@ func main() {
 
 		result, err := bss.search(query, "videos", count, "", safesearch)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error performing video search: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Performed video search for: %s", query)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -505,14 +520,10 @@ func main() {
 	})
 
 	// Start server with stdio transport
-	transport := mcp.StdioTransport{}
+	transport := mcp.StdioTransport()
 	log.Printf("Brave Search server running on stdio")
 
 	if err := srv.Serve(context.Background(), transport); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
-
-func boolPtr(b bool) *bool {
-	return &b
-}
@@ -1,17 +1,19 @@
 package main
 
 import (
-	"os"
 	"testing"
 
 	"github.com/tmc/mcp/testing/mcpscripttest"
 )
 
-func TestMain(m *testing.M) {
-	code := mcpscripttest.RunWithCoverage(m, "bravesearch-server")
-	os.Exit(code)
+func TestBraveSearchServerBasic(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/basic_bravesearch_test.txt")
 }
 
-func TestBraveSearchServer(t *testing.T) {
-	mcpscripttest.RunDirectoryTests(t, "testdata")
+func TestBraveSearchServerTools(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/bravesearch_tools_test.txt")
+}
+
+func TestBraveSearchServerErrorHandling(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/bravesearch_error_handling_test.txt")
 }
@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/tmc/mcp"
 )
@@ -49,12 +50,11 @@ func main() {
 	registerCalculatorTools(server)
 
 	log.Println("Starting protocol server via stdio...")
-	if err := server.Serve(ctx, nil); err != nil {
-		if err != context.Canceled {
-			log.Fatalf("Error serving: %v", err)
-		}
-		log.Println("Server terminated.")
+	// Serve until a signal, then let in-flight calls finish.
+	if err := server.ServeUntilDone(ctx, nil, 10*time.Second); err != nil {
+		log.Fatalf("Error serving: %v", err)
 	}
+	log.Println("Server terminated.")
 }
 
 func registerCalculatorTools(server *mcp.Server) {
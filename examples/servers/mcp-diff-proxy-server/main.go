@@ -2,13 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os/exec"
 	"strings"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 type DiffProxyServer struct{}
@@ -17,7 +18,16 @@ func NewDiffProxyServer() *DiffProxyServer {
 	return &DiffProxyServer{}
 }
 
-func (s *DiffProxyServer) handleTextDiff(args map[string]interface{}) (*modelcontextprotocol.CallToolResult, error) {
+// arguments decodes the arguments of a tool call. Handlers report missing
+// arguments, so a malformed object is treated as an empty one.
+func arguments(req mcp.CallToolRequest) map[string]interface{} {
+	var args map[string]interface{}
+	json.Unmarshal(req.Arguments, &args)
+	return args
+}
+
+func (s *DiffProxyServer) handleTextDiff(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := arguments(req)
 	text1, ok1 := args["text1"].(string)
 	text2, ok2 := args["text2"].(string)
 
@@ -36,7 +46,8 @@ func (s *DiffProxyServer) handleTextDiff(args map[string]interface{}) (*modelcon
 	return s.diffTexts(text1, text2, "text1", "text2")
 }
 
-func (s *DiffProxyServer) handleFileDiff(args map[string]interface{}) (*modelcontextprotocol.CallToolResult, error) {
+func (s *DiffProxyServer) handleFileDiff(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := arguments(req)
 	file1, ok1 := args["file1"].(string)
 	file2, ok2 := args["file2"].(string)
 
@@ -53,9 +64,9 @@ func (s *DiffProxyServer) handleFileDiff(args map[string]interface{}) (*modelcon
 	err := cmd.Run()
 	// diff returns 1 when files differ, which is normal
 	if err != nil && cmd.ProcessState.ExitCode() > 1 {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: fmt.Sprintf("Error running diff: %v\n%s", err, stderr.String()),
 				},
@@ -69,9 +80,9 @@ func (s *DiffProxyServer) handleFileDiff(args map[string]interface{}) (*modelcon
 		diffOutput = "Files are identical"
 	}
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: diffOutput,
 			},
@@ -79,7 +90,8 @@ func (s *DiffProxyServer) handleFileDiff(args map[string]interface{}) (*modelcon
 	}, nil
 }
 
-func (s *DiffProxyServer) handleMCPDiff(args map[string]interface{}) (*modelcontextprotocol.CallToolResult, error) {
+func (s *DiffProxyServer) handleMCPDiff(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := arguments(req)
 	file1, ok1 := args["file1"].(string)
 	file2, ok2 := args["file2"].(string)
 
@@ -95,9 +107,9 @@ func (s *DiffProxyServer) handleMCPDiff(args map[string]interface{}) (*modelcont
 
 	err := cmd.Run()
 	if err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: fmt.Sprintf("Error running mcpdiff: %v\n%s", err, stderr.String()),
 				},
@@ -106,9 +118,9 @@ func (s *DiffProxyServer) handleMCPDiff(args map[string]interface{}) (*modelcont
 		}, nil
 	}
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: stdout.String(),
 			},
@@ -116,7 +128,7 @@ func (s *DiffProxyServer) handleMCPDiff(args map[string]interface{}) (*modelcont
 	}, nil
 }
 
-func (s *DiffProxyServer) diffTexts(text1, text2, name1, name2 string) (*modelcontextprotocol.CallToolResult, error) {
+func (s *DiffProxyServer) diffTexts(text1, text2, name1, name2 string) (*mcp.CallToolResult, error) {
 	// Write texts to temp files for diff
 	cmd1 := exec.Command("mktemp")
 	var stdout1 bytes.Buffer
@@ -161,9 +173,9 @@ func (s *DiffProxyServer) diffTexts(text1, text2, name1, name2 string) (*modelco
 
 	// diff returns 1 when files differ, which is normal
 	if err != nil && cmd.ProcessState.ExitCode() > 1 {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: fmt.Sprintf("Error running diff: %v\n%s", err, stderr.String()),
 				},
@@ -177,9 +189,9 @@ func (s *DiffProxyServer) diffTexts(text1, text2, name1, name2 string) (*modelco
 		diffOutput = "Texts are identical"
 	}
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: diffOutput,
 			},
@@ -192,69 +204,46 @@ func main() {
 	mcpServer := mcp.NewServer("mcp-diff-proxy-server", "1.0.0")
 
 	// Add tools
-	mcpServer.AddTool(modelcontextprotocol.Tool{
+	mcpServer.RegisterTool(mcp.Tool{
 		Name:        "text_diff",
 		Description: "Generate a unified diff between two text strings",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"text1": map[string]interface{}{
-					"type":        "string",
-					"description": "First text to compare",
-				},
-				"text2": map[string]interface{}{
-					"type":        "string",
-					"description": "Second text to compare",
-				},
+			"properties": {
+				"text1": {"type": "string", "description": "First text to compare"},
+				"text2": {"type": "string", "description": "Second text to compare"}
 			},
-			"required": []string{"text1", "text2"},
-		},
-	})
+			"required": ["text1", "text2"]
+		}`),
+	}, server.handleTextDiff)
 
-	mcpServer.AddTool(modelcontextprotocol.Tool{
+	mcpServer.RegisterTool(mcp.Tool{
 		Name:        "file_diff",
 		Description: "Generate a unified diff between two files",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"file1": map[string]interface{}{
-					"type":        "string",
-					"description": "Path to first file",
-				},
-				"file2": map[string]interface{}{
-					"type":        "string",
-					"description": "Path to second file",
-				},
+			"properties": {
+				"file1": {"type": "string", "description": "Path to first file"},
+				"file2": {"type": "string", "description": "Path to second file"}
 			},
-			"required": []string{"file1", "file2"},
-		},
-	})
+			"required": ["file1", "file2"]
+		}`),
+	}, server.handleFileDiff)
 
-	mcpServer.AddTool(modelcontextprotocol.Tool{
+	mcpServer.RegisterTool(mcp.Tool{
 		Name:        "mcp_diff",
 		Description: "Generate a diff between two MCP trace files using mcpdiff",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"file1": map[string]interface{}{
-					"type":        "string",
-					"description": "Path to first MCP trace file",
-				},
-				"file2": map[string]interface{}{
-					"type":        "string",
-					"description": "Path to second MCP trace file",
-				},
+			"properties": {
+				"file1": {"type": "string", "description": "Path to first MCP trace file"},
+				"file2": {"type": "string", "description": "Path to second MCP trace file"}
 			},
-			"required": []string{"file1", "file2"},
-		},
-	})
-
-	// Add tool handlers
-	mcpServer.OnToolCall("text_diff", server.handleTextDiff)
-	mcpServer.OnToolCall("file_diff", server.handleFileDiff)
-	mcpServer.OnToolCall("mcp_diff", server.handleMCPDiff)
+			"required": ["file1", "file2"]
+		}`),
+	}, server.handleMCPDiff)
 
-	if err := mcpServer.Serve(); err != nil {
+	if err := mcpServer.Serve(context.Background(), mcp.StdioTransport()); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
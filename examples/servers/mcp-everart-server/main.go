@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/tmc/mcp"
 	"github.com/tmc/mcp/mcptel"
@@ -50,12 +51,11 @@ func main() {
 
 	// Serve via stdio
 	log.Println("Starting protocol server via stdio...")
-	if err := server.Serve(ctx, nil); err != nil {
-		if err != context.Canceled {
-			log.Fatalf("Error serving: %v", err)
-		}
-		log.Println("Server terminated.")
+	// Serve until a signal, then let in-flight calls finish.
+	if err := server.ServeUntilDone(ctx, nil, 10*time.Second); err != nil {
+		log.Fatalf("Error serving: %v", err)
 	}
+	log.Println("Server terminated.")
 }
 
 func registerTools(server *mcp.Server) {
@@ -87,7 +87,7 @@ func registerTools(server *mcp.Server) {
 
 	server.RegisterTool(generateImageTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		_ = mcptel.CurrentSpan
-		server.GetLogger().InfoContext(ctx, "generate_image tool called")
+		slog.InfoContext(ctx, "generate_image tool called")
 		var params map[string]any
 		if err := json.Unmarshal(req.Arguments, &params); err != nil {
 			return nil, fmt.Errorf("invalid parameters: %v", err)
@@ -135,7 +135,7 @@ func registerTools(server *mcp.Server) {
 
 	server.RegisterTool(listModelsTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		_ = mcptel.CurrentSpan
-		server.GetLogger().InfoContext(ctx, "list_models tool called")
+		slog.InfoContext(ctx, "list_models tool called")
 
 		models := []string{
 			"stable-diffusion-xl",
@@ -62,12 +62,11 @@ func main() {
 
 	// Serve via stdio
 	log.Println("Starting protocol server via stdio...")
-	if err := server.Serve(ctx, nil); err != nil {
-		if err != context.Canceled {
-			log.Fatalf("Error serving: %v", err)
-		}
-		log.Println("Server terminated.")
+	// Serve until a signal, then let in-flight calls finish.
+	if err := server.ServeUntilDone(ctx, nil, 10*time.Second); err != nil {
+		log.Fatalf("Error serving: %v", err)
 	}
+	log.Println("Server terminated.")
 }
 
 func registerTools(server *mcp.Server) {
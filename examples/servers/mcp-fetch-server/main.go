@@ -15,7 +15,6 @@ import (
 	"golang.org/x/net/html"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 type FetchServer struct {
@@ -193,7 +192,11 @@ func main() {
 	fs := NewFetchServer()
 
 	// Register fetch tool
-	srv.RegisterTool("fetch", "Fetch content from a URL", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "fetch", Description: "Fetch content from a URL"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var urlRaw json.RawMessage
 		var exists bool
 		if urlRaw, exists = args["url"]; !exists {
@@ -207,14 +210,14 @@ func main() {
 
 		content, contentType, err := fs.fetchURL(urlStr)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error fetching URL: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
@@ -232,9 +235,9 @@ func main() {
 
 		log.Printf("Fetched URL: %s (%d bytes, %s)", urlStr, len(content), contentType)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(responseJSON),
 				},
@@ -243,7 +246,11 @@ func main() {
 	})
 
 	// Register fetch_text tool
-	srv.RegisterTool("fetch_text", "Fetch content from a URL and convert HTML to plain text", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "fetch_text", Description: "Fetch content from a URL and convert HTML to plain text"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var urlRaw json.RawMessage
 		var exists bool
 		if urlRaw, exists = args["url"]; !exists {
@@ -257,14 +264,14 @@ func main() {
 
 		content, contentType, err := fs.fetchURL(urlStr)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error fetching URL: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
@@ -272,14 +279,14 @@ func main() {
 		if strings.Contains(contentType, "text/html") {
 			textContent, err = fs.htmlToText(content)
 			if err != nil {
-				return &modelcontextprotocol.CallToolResult{
-					Content: []modelcontextprotocol.Content{
-						modelcontextprotocol.TextContent{
+				return &mcp.CallToolResult{
+					Content: []any{
+						mcp.TextContent{
 							Type: "text",
 							Text: fmt.Sprintf("Error converting HTML to text: %s", err.Error()),
 						},
 					},
-					IsError: boolPtr(true),
+					IsError: true,
 				}, nil
 			}
 		} else {
@@ -301,9 +308,9 @@ func main() {
 
 		log.Printf("Fetched and converted URL: %s (%d -> %d bytes)", urlStr, len(content), len(textContent))
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(responseJSON),
 				},
@@ -312,7 +319,11 @@ func main() {
 	})
 
 	// Register get_headers tool
-	srv.RegisterTool("get_headers", "Get HTTP headers for a URL without fetching the full content", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "get_headers", Description: "Get HTTP headers for a URL without fetching the full content"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var urlRaw json.RawMessage
 		var exists bool
 		if urlRaw, exists = args["url"]; !exists {
@@ -325,34 +336,34 @@ func main() {
 		}
 
 		if err := fs.validateURL(urlStr); err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error validating URL: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
-		req, err := http.NewRequest("HEAD", urlStr, nil)
+		head, err := http.NewRequestWithContext(ctx, "HEAD", urlStr, nil)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 
-		req.Header.Set("User-Agent", fs.userAgent)
+		head.Header.Set("User-Agent", fs.userAgent)
 
-		resp, err := fs.client.Do(req)
+		resp, err := fs.client.Do(head)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error getting headers: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 		defer resp.Body.Close()
@@ -376,9 +387,9 @@ func main() {
 
 		log.Printf("Got headers for URL: %s (status: %d)", urlStr, resp.StatusCode)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(responseJSON),
 				},
@@ -387,14 +398,10 @@ func main() {
 	})
 
 	// Start server with stdio transport
-	transport := mcp.StdioTransport{}
+	transport := mcp.StdioTransport()
 	log.Printf("Fetch server running on stdio")
 
 	if err := srv.Serve(context.Background(), transport); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
-
-func boolPtr(b bool) *bool {
-	return &b
-}
@@ -12,7 +12,6 @@ import (
 	"time"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 type FileSystemServer struct {
@@ -220,7 +219,11 @@ func main() {
 	srv := mcp.NewServer("filesystem-server", "1.0.0")
 
 	// Register read_file tool
-	srv.RegisterTool("read_file", "Read the contents of a file", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "read_file", Description: "Read the contents of a file"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var pathRaw json.RawMessage
 		var exists bool
 		if pathRaw, exists = args["path"]; !exists {
@@ -234,22 +237,22 @@ func main() {
 
 		content, err := fss.readFile(filePath)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error reading file: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Read file: %s (%d bytes)", filePath, len(content))
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: content,
 				},
@@ -258,7 +261,11 @@ func main() {
 	})
 
 	// Register write_file tool
-	srv.RegisterTool("write_file", "Write content to a file", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "write_file", Description: "Write content to a file"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var pathRaw, contentRaw json.RawMessage
 		var exists bool
 
@@ -280,22 +287,22 @@ func main() {
 		}
 
 		if err := fss.writeFile(filePath, content); err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error writing file: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Wrote file: %s (%d bytes)", filePath, len(content))
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: fmt.Sprintf("Successfully wrote %d bytes to %s", len(content), filePath),
 				},
@@ -304,7 +311,11 @@ func main() {
 	})
 
 	// Register list_directory tool
-	srv.RegisterTool("list_directory", "List the contents of a directory", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "list_directory", Description: "List the contents of a directory"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var pathRaw json.RawMessage
 		var exists bool
 		if pathRaw, exists = args["path"]; !exists {
@@ -318,14 +329,14 @@ func main() {
 
 		entries, err := fss.listDirectory(dirPath)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error listing directory: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
@@ -336,9 +347,9 @@ func main() {
 
 		log.Printf("Listed directory: %s (%d entries)", dirPath, len(entries))
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(responseJSON),
 				},
@@ -347,7 +358,11 @@ func main() {
 	})
 
 	// Register get_file_info tool
-	srv.RegisterTool("get_file_info", "Get information about a file or directory", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "get_file_info", Description: "Get information about a file or directory"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var pathRaw json.RawMessage
 		var exists bool
 		if pathRaw, exists = args["path"]; !exists {
@@ -361,27 +376,27 @@ func main() {
 
 		validPath, err := fss.validatePath(filePath)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error accessing path: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		info, err := os.Stat(validPath)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error getting file info: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
@@ -406,9 +421,9 @@ func main() {
 
 		log.Printf("Got file info: %s", filePath)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(responseJSON),
 				},
@@ -417,14 +432,10 @@ func main() {
 	})
 
 	// Start server with stdio transport
-	transport := mcp.StdioTransport{}
+	transport := mcp.StdioTransport()
 	log.Printf("Filesystem server running on stdio, allowed directories: %v", fss.allowedDirectories)
 
 	if err := srv.Serve(context.Background(), transport); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
-
-func boolPtr(b bool) *bool {
-	return &b
-}
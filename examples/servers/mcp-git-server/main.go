@@ -11,7 +11,6 @@ import (
 	"strings"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 type GitServer struct {
@@ -199,7 +198,11 @@ func main() {
 	srv := mcp.NewServer("git-server", "1.0.0")
 
 	// Register git_status tool
-	srv.RegisterTool("git_status", "Get the status of a git repository", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "git_status", Description: "Get the status of a git repository"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var repoPathRaw json.RawMessage
 		var exists bool
 		if repoPathRaw, exists = args["repository"]; !exists {
@@ -213,22 +216,22 @@ func main() {
 
 		status, err := gs.getStatus(repoPath)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error getting git status: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Git status for repository: %s", repoPath)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: status,
 				},
@@ -237,7 +240,11 @@ func main() {
 	})
 
 	// Register git_log tool
-	srv.RegisterTool("git_log", "Get the commit log of a git repository", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "git_log", Description: "Get the commit log of a git repository"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var repoPathRaw json.RawMessage
 		var exists bool
 		if repoPathRaw, exists = args["repository"]; !exists {
@@ -256,33 +263,37 @@ func main() {
 			}
 		}
 
-		log, err := gs.getLog(repoPath, maxCount)
+		commits, err := gs.getLog(repoPath, maxCount)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error getting git log: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Git log for repository: %s (max %d commits)", repoPath, maxCount)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
-					Text: log,
+					Text: commits,
 				},
 			},
 		}, nil
 	})
 
 	// Register git_branches tool
-	srv.RegisterTool("git_branches", "List branches in a git repository", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "git_branches", Description: "List branches in a git repository"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var repoPathRaw json.RawMessage
 		var exists bool
 		if repoPathRaw, exists = args["repository"]; !exists {
@@ -296,22 +307,22 @@ func main() {
 
 		branches, err := gs.getBranches(repoPath)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error getting git branches: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Git branches for repository: %s", repoPath)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: branches,
 				},
@@ -320,7 +331,11 @@ func main() {
 	})
 
 	// Register git_diff tool
-	srv.RegisterTool("git_diff", "Show differences in a git repository", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "git_diff", Description: "Show differences in a git repository"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var repoPathRaw json.RawMessage
 		var exists bool
 		if repoPathRaw, exists = args["repository"]; !exists {
@@ -346,22 +361,22 @@ func main() {
 
 		diff, err := gs.getDiff(repoPath, ref1, ref2)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error getting git diff: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Git diff for repository: %s", repoPath)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: diff,
 				},
@@ -370,7 +385,11 @@ func main() {
 	})
 
 	// Register git_add tool
-	srv.RegisterTool("git_add", "Add files to git staging area", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "git_add", Description: "Add files to git staging area"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var repoPathRaw, filesRaw json.RawMessage
 		var exists bool
 
@@ -394,22 +413,22 @@ func main() {
 
 		result, err := gs.addFiles(repoPath, files)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error adding files: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Added files to git staging area: %v", files)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: fmt.Sprintf("Successfully added files: %s\n%s", strings.Join(files, ", "), result),
 				},
@@ -418,7 +437,11 @@ func main() {
 	})
 
 	// Register git_commit tool
-	srv.RegisterTool("git_commit", "Create a git commit", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "git_commit", Description: "Create a git commit"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var repoPathRaw, messageRaw json.RawMessage
 		var exists bool
 
@@ -441,22 +464,22 @@ func main() {
 
 		result, err := gs.commit(repoPath, message)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error committing: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Created git commit with message: %s", message)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -465,7 +488,11 @@ func main() {
 	})
 
 	// Register git_create_branch tool
-	srv.RegisterTool("git_create_branch", "Create a new git branch", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "git_create_branch", Description: "Create a new git branch"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var repoPathRaw, branchRaw json.RawMessage
 		var exists bool
 
@@ -488,22 +515,22 @@ func main() {
 
 		result, err := gs.createBranch(repoPath, branch)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error creating branch: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Created git branch: %s", branch)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -512,7 +539,11 @@ func main() {
 	})
 
 	// Register git_switch_branch tool
-	srv.RegisterTool("git_switch_branch", "Switch to a different git branch", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "git_switch_branch", Description: "Switch to a different git branch"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var repoPathRaw, branchRaw json.RawMessage
 		var exists bool
 
@@ -535,22 +566,22 @@ func main() {
 
 		result, err := gs.switchBranch(repoPath, branch)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error switching branch: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Switched to git branch: %s", branch)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -559,14 +590,10 @@ func main() {
 	})
 
 	// Start server with stdio transport
-	transport := mcp.StdioTransport{}
+	transport := mcp.StdioTransport()
 	log.Printf("Git server running on stdio, allowed repositories: %v", gs.allowedRepositories)
 
 	if err := srv.Serve(context.Background(), transport); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
-
-func boolPtr(b bool) *bool {
-	return &b
-}
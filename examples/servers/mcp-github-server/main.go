@@ -10,7 +10,6 @@ import (
 
 	"github.com/google/go-github/v57/github"
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 	"golang.org/x/oauth2"
 )
 
@@ -223,7 +222,11 @@ func main() {
 	srv := mcp.NewServer("github-server", "1.0.0")
 
 	// Register get_repository tool
-	srv.RegisterTool("get_repository", "Get information about a GitHub repository", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "get_repository", Description: "Get information about a GitHub repository"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var ownerRaw, repoRaw json.RawMessage
 		var exists bool
 
@@ -246,22 +249,22 @@ func main() {
 
 		result, err := gs.getRepository(owner, repo)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error getting repository: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Retrieved repository info: %s/%s", owner, repo)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -270,7 +273,11 @@ func main() {
 	})
 
 	// Register list_repositories tool
-	srv.RegisterTool("list_repositories", "List GitHub repositories for a user or organization", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "list_repositories", Description: "List GitHub repositories for a user or organization"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var owner string
 		if ownerRaw, exists := args["owner"]; exists {
 			if err := json.Unmarshal(ownerRaw, &owner); err != nil {
@@ -287,22 +294,22 @@ func main() {
 
 		result, err := gs.listRepositories(owner, limit)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error listing repositories: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Listed repositories for owner: %s", owner)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -311,7 +318,11 @@ func main() {
 	})
 
 	// Register get_file_content tool
-	srv.RegisterTool("get_file_content", "Get the content of a file from a GitHub repository", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "get_file_content", Description: "Get the content of a file from a GitHub repository"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var ownerRaw, repoRaw, pathRaw json.RawMessage
 		var exists bool
 
@@ -349,22 +360,22 @@ func main() {
 
 		result, err := gs.getFileContent(owner, repo, path, ref)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error getting file content: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Retrieved file content: %s/%s/%s", owner, repo, path)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -373,7 +384,11 @@ func main() {
 	})
 
 	// Register list_branches tool
-	srv.RegisterTool("list_branches", "List branches in a GitHub repository", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "list_branches", Description: "List branches in a GitHub repository"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var ownerRaw, repoRaw json.RawMessage
 		var exists bool
 
@@ -396,22 +411,22 @@ func main() {
 
 		result, err := gs.listBranches(owner, repo)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error listing branches: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Listed branches for: %s/%s", owner, repo)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -420,7 +435,11 @@ func main() {
 	})
 
 	// Register create_issue tool
-	srv.RegisterTool("create_issue", "Create a new GitHub issue", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "create_issue", Description: "Create a new GitHub issue"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var ownerRaw, repoRaw, titleRaw json.RawMessage
 		var exists bool
 
@@ -458,22 +477,22 @@ func main() {
 
 		result, err := gs.createIssue(owner, repo, title, body)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error creating issue: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Created issue in: %s/%s", owner, repo)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -482,7 +501,11 @@ func main() {
 	})
 
 	// Register list_issues tool
-	srv.RegisterTool("list_issues", "List issues in a GitHub repository", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "list_issues", Description: "List issues in a GitHub repository"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var ownerRaw, repoRaw json.RawMessage
 		var exists bool
 
@@ -519,22 +542,22 @@ func main() {
 
 		result, err := gs.listIssues(owner, repo, state, limit)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error listing issues: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Listed issues for: %s/%s", owner, repo)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -543,14 +566,10 @@ func main() {
 	})
 
 	// Start server with stdio transport
-	transport := mcp.StdioTransport{}
+	transport := mcp.StdioTransport()
 	log.Printf("GitHub server running on stdio")
 
 	if err := srv.Serve(context.Background(), transport); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
-
-func boolPtr(b bool) *bool {
-	return &b
-}
@@ -1,17 +1,19 @@
 package main
 
 import (
-	"os"
 	"testing"
 
 	"github.com/tmc/mcp/testing/mcpscripttest"
 )
 
-func TestMain(m *testing.M) {
-	code := mcpscripttest.RunWithCoverage(m, "github-server")
-	os.Exit(code)
+func TestGitHubServerBasic(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/basic_github_test.txt")
 }
 
-func TestGitHubServer(t *testing.T) {
-	mcpscripttest.RunDirectoryTests(t, "testdata")
+func TestGitHubServerTools(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/github_tools_test.txt")
+}
+
+func TestGitHubServerErrorHandling(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/github_error_handling_test.txt")
 }
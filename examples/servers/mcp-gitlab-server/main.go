@@ -8,40 +8,36 @@ import (
 	"strings"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 func main() {
-	server := mcp.NewServer(mcp.ServerOptions{
-		Name:    "gitlab-server",
-		Version: "1.0.0",
-	})
+	server := mcp.NewServer("gitlab-server", "1.0.0")
 
 	// GitLab Tools
-	server.AddTool("search_repositories", mcp.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "search_repositories",
 		Description: "Search GitLab repositories",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"query": map[string]interface{}{
-					"type":        "string",
-					"description": "Search query for repositories",
+			"properties": {
+				"query": {
+					"type": "string",
+					"description": "Search query for repositories"
 				},
-				"limit": map[string]interface{}{
-					"type":        "integer",
+				"limit": {
+					"type": "integer",
 					"description": "Maximum number of results (default: 10)",
-					"default":     10,
-				},
+					"default": 10
+				}
 			},
-			"required": []string{"query"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+			"required": ["query"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		var args struct {
 			Query string `json:"query"`
 			Limit int    `json:"limit"`
 		}
-		if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
 			return nil, fmt.Errorf("invalid arguments: %w", err)
 		}
 
@@ -69,9 +65,9 @@ func main() {
 			"results": results[:min(len(results), args.Limit)],
 		}, "", "  ")
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(content),
 				},
@@ -79,24 +75,24 @@ func main() {
 		}, nil
 	})
 
-	server.AddTool("get_project", mcp.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "get_project",
 		Description: "Get GitLab project details",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"project_id": map[string]interface{}{
-					"type":        "string",
-					"description": "GitLab project ID or path",
-				},
+			"properties": {
+				"project_id": {
+					"type": "string",
+					"description": "GitLab project ID or path"
+				}
 			},
-			"required": []string{"project_id"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+			"required": ["project_id"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		var args struct {
 			ProjectID string `json:"project_id"`
 		}
-		if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
 			return nil, fmt.Errorf("invalid arguments: %w", err)
 		}
 
@@ -118,9 +114,9 @@ func main() {
 
 		content, _ := json.MarshalIndent(project, "", "  ")
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(content),
 				},
@@ -128,37 +124,37 @@ func main() {
 		}, nil
 	})
 
-	server.AddTool("list_issues", mcp.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "list_issues",
 		Description: "List GitLab project issues",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"project_id": map[string]interface{}{
-					"type":        "string",
-					"description": "GitLab project ID or path",
+			"properties": {
+				"project_id": {
+					"type": "string",
+					"description": "GitLab project ID or path"
 				},
-				"state": map[string]interface{}{
-					"type":        "string",
+				"state": {
+					"type": "string",
 					"description": "Issue state: opened, closed, all",
-					"enum":        []string{"opened", "closed", "all"},
-					"default":     "opened",
+					"enum": ["opened", "closed", "all"],
+					"default": "opened"
 				},
-				"limit": map[string]interface{}{
-					"type":        "integer",
+				"limit": {
+					"type": "integer",
 					"description": "Maximum number of issues (default: 10)",
-					"default":     10,
-				},
+					"default": 10
+				}
 			},
-			"required": []string{"project_id"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+			"required": ["project_id"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		var args struct {
 			ProjectID string `json:"project_id"`
 			State     string `json:"state"`
 			Limit     int    `json:"limit"`
 		}
-		if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
 			return nil, fmt.Errorf("invalid arguments: %w", err)
 		}
 
@@ -215,9 +211,9 @@ func main() {
 			"issues":     issues[:min(len(issues), args.Limit)],
 		}, "", "  ")
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(content),
 				},
@@ -225,40 +221,42 @@ func main() {
 		}, nil
 	})
 
-	server.AddTool("create_issue", mcp.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "create_issue",
 		Description: "Create a new GitLab issue",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"project_id": map[string]interface{}{
-					"type":        "string",
-					"description": "GitLab project ID or path",
+			"properties": {
+				"project_id": {
+					"type": "string",
+					"description": "GitLab project ID or path"
 				},
-				"title": map[string]interface{}{
-					"type":        "string",
-					"description": "Issue title",
+				"title": {
+					"type": "string",
+					"description": "Issue title"
 				},
-				"description": map[string]interface{}{
-					"type":        "string",
-					"description": "Issue description",
-				},
-				"labels": map[string]interface{}{
-					"type":        "array",
-					"items":       map[string]interface{}{"type": "string"},
-					"description": "Issue labels",
+				"description": {
+					"type": "string",
+					"description": "Issue description"
 				},
+				"labels": {
+					"type": "array",
+					"items": {
+						"type": "string"
+					},
+					"description": "Issue labels"
+				}
 			},
-			"required": []string{"project_id", "title"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+			"required": ["project_id", "title"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		var args struct {
 			ProjectID   string   `json:"project_id"`
 			Title       string   `json:"title"`
 			Description string   `json:"description"`
 			Labels      []string `json:"labels"`
 		}
-		if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
 			return nil, fmt.Errorf("invalid arguments: %w", err)
 		}
 
@@ -281,9 +279,9 @@ func main() {
 			"issue":   newIssue,
 		}, "", "  ")
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(content),
 				},
@@ -291,7 +289,7 @@ func main() {
 		}, nil
 	})
 
-	if err := server.Serve(context.Background()); err != nil {
+	if err := server.Serve(context.Background(), mcp.StdioTransport()); err != nil {
 		log.Fatal(err)
 	}
 }
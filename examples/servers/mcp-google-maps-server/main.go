@@ -3,10 +3,10 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 const (
@@ -30,164 +30,115 @@ func NewGoogleMapsServer() *GoogleMapsServer {
 }
 
 func (s *GoogleMapsServer) SetupHandlers() {
-	// Initialize with tools
-	s.OnInitialize(func(ctx context.Context, req *modelcontextprotocol.InitializeRequest) (*modelcontextprotocol.InitializeResult, error) {
-		return &modelcontextprotocol.InitializeResult{
-			ProtocolVersion: modelcontextprotocol.LatestProtocolVersion,
-			Capabilities: &modelcontextprotocol.ServerCapabilities{
-				Tools: &modelcontextprotocol.ToolsCapability{},
-			},
-			ServerInfo: &modelcontextprotocol.Implementation{
-				Name:    SERVER_NAME,
-				Version: SERVER_VERSION,
-			},
-		}, nil
-	})
-
-	// List available tools
-	s.OnListTools(func(ctx context.Context, req *modelcontextprotocol.ListToolsRequest) (*modelcontextprotocol.ListToolsResult, error) {
-		tools := []modelcontextprotocol.Tool{
-			{
-				Name:        "search_places",
-				Description: "Search for places using Google Maps",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"query": map[string]interface{}{
-							"type":        "string",
-							"description": "Search query for places",
-						},
-						"location": map[string]interface{}{
-							"type":        "string",
-							"description": "Location to search near (optional)",
-						},
-						"radius": map[string]interface{}{
-							"type":        "integer",
-							"description": "Search radius in meters (optional)",
-						},
-					},
-					"required": []string{"query"},
+	s.RegisterTool(mcp.Tool{
+		Name:        "search_places",
+		Description: "Search for places using Google Maps",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"query": {
+					"type": "string",
+					"description": "Search query for places"
 				},
-			},
-			{
-				Name:        "get_directions",
-				Description: "Get directions between two locations",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"origin": map[string]interface{}{
-							"type":        "string",
-							"description": "Starting location",
-						},
-						"destination": map[string]interface{}{
-							"type":        "string",
-							"description": "Destination location",
-						},
-						"mode": map[string]interface{}{
-							"type":        "string",
-							"description": "Travel mode (driving, walking, transit, bicycling)",
-							"default":     "driving",
-						},
-					},
-					"required": []string{"origin", "destination"},
+				"location": {
+					"type": "string",
+					"description": "Location to search near (optional)"
 				},
+				"radius": {
+					"type": "integer",
+					"description": "Search radius in meters (optional)"
+				}
 			},
-			{
-				Name:        "geocode",
-				Description: "Convert address to coordinates",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"address": map[string]interface{}{
-							"type":        "string",
-							"description": "Address to geocode",
-						},
-					},
-					"required": []string{"address"},
+			"required": ["query"]
+		}`),
+	}, s.handleSearchPlaces)
+	s.RegisterTool(mcp.Tool{
+		Name:        "get_directions",
+		Description: "Get directions between two locations",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"origin": {
+					"type": "string",
+					"description": "Starting location"
 				},
-			},
-			{
-				Name:        "reverse_geocode",
-				Description: "Convert coordinates to address",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"lat": map[string]interface{}{
-							"type":        "number",
-							"description": "Latitude coordinate",
-						},
-						"lng": map[string]interface{}{
-							"type":        "number",
-							"description": "Longitude coordinate",
-						},
-					},
-					"required": []string{"lat", "lng"},
+				"destination": {
+					"type": "string",
+					"description": "Destination location"
 				},
+				"mode": {
+					"type": "string",
+					"description": "Travel mode (driving, walking, transit, bicycling)",
+					"default": "driving"
+				}
 			},
-			{
-				Name:        "get_place_details",
-				Description: "Get detailed information about a place",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"place_id": map[string]interface{}{
-							"type":        "string",
-							"description": "Google Places ID",
-						},
-					},
-					"required": []string{"place_id"},
-				},
+			"required": ["origin", "destination"]
+		}`),
+	}, s.handleGetDirections)
+	s.RegisterTool(mcp.Tool{
+		Name:        "geocode",
+		Description: "Convert address to coordinates",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"address": {
+					"type": "string",
+					"description": "Address to geocode"
+				}
 			},
-		}
-
-		return &modelcontextprotocol.ListToolsResult{
-			Tools: tools,
-		}, nil
-	})
-
-	// Handle tool calls
-	s.OnCallTool(func(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
-		switch req.Params.Name {
-		case "search_places":
-			return s.handleSearchPlaces(ctx, req)
-		case "get_directions":
-			return s.handleGetDirections(ctx, req)
-		case "geocode":
-			return s.handleGeocode(ctx, req)
-		case "reverse_geocode":
-			return s.handleReverseGeocode(ctx, req)
-		case "get_place_details":
-			return s.handleGetPlaceDetails(ctx, req)
-		default:
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
-						Type: "text",
-						Text: "Unknown tool: " + req.Params.Name,
-					},
+			"required": ["address"]
+		}`),
+	}, s.handleGeocode)
+	s.RegisterTool(mcp.Tool{
+		Name:        "reverse_geocode",
+		Description: "Convert coordinates to address",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"lat": {
+					"type": "number",
+					"description": "Latitude coordinate"
 				},
-				IsError: &[]bool{true}[0],
-			}, nil
-		}
-	})
+				"lng": {
+					"type": "number",
+					"description": "Longitude coordinate"
+				}
+			},
+			"required": ["lat", "lng"]
+		}`),
+	}, s.handleReverseGeocode)
+	s.RegisterTool(mcp.Tool{
+		Name:        "get_place_details",
+		Description: "Get detailed information about a place",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"place_id": {
+					"type": "string",
+					"description": "Google Places ID"
+				}
+			},
+			"required": ["place_id"]
+		}`),
+	}, s.handleGetPlaceDetails)
 }
 
-func (s *GoogleMapsServer) handleSearchPlaces(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *GoogleMapsServer) handleSearchPlaces(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Query    string `json:"query"`
 		Location string `json:"location,omitempty"`
 		Radius   int    `json:"radius,omitempty"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -197,9 +148,9 @@ func (s *GoogleMapsServer) handleSearchPlaces(ctx context.Context, req *modelcon
 	result += "2. Example Cafe - 456 Oak Ave\n"
 	result += "3. Test Store - 789 Pine Rd"
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -207,22 +158,22 @@ func (s *GoogleMapsServer) handleSearchPlaces(ctx context.Context, req *modelcon
 	}, nil
 }
 
-func (s *GoogleMapsServer) handleGetDirections(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *GoogleMapsServer) handleGetDirections(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Origin      string `json:"origin"`
 		Destination string `json:"destination"`
 		Mode        string `json:"mode"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -239,9 +190,9 @@ func (s *GoogleMapsServer) handleGetDirections(ctx context.Context, req *modelco
 	result += "Total distance: 3.2 miles\n"
 	result += "Estimated time: 8 minutes"
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -249,20 +200,20 @@ func (s *GoogleMapsServer) handleGetDirections(ctx context.Context, req *modelco
 	}, nil
 }
 
-func (s *GoogleMapsServer) handleGeocode(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *GoogleMapsServer) handleGeocode(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Address string `json:"address"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -272,9 +223,9 @@ func (s *GoogleMapsServer) handleGeocode(ctx context.Context, req *modelcontextp
 	result += "Longitude: -122.4194\n"
 	result += "Formatted address: 123 Main St, San Francisco, CA 94102, USA"
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -282,37 +233,35 @@ func (s *GoogleMapsServer) handleGeocode(ctx context.Context, req *modelcontextp
 	}, nil
 }
 
-func (s *GoogleMapsServer) handleReverseGeocode(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *GoogleMapsServer) handleReverseGeocode(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Lat float64 `json:"lat"`
 		Lng float64 `json:"lng"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
 	// Mock response
-	result := "Reverse geocoded coordinates (" +
-		json.Number(args.Lat).String() + ", " +
-		json.Number(args.Lng).String() + "):\n"
+	result := fmt.Sprintf("Reverse geocoded coordinates (%g, %g):\n", args.Lat, args.Lng)
 	result += "Address: 123 Main St, San Francisco, CA 94102, USA\n"
 	result += "Neighborhood: Financial District\n"
 	result += "City: San Francisco\n"
 	result += "State: California\n"
 	result += "Country: United States"
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -320,20 +269,20 @@ func (s *GoogleMapsServer) handleReverseGeocode(ctx context.Context, req *modelc
 	}, nil
 }
 
-func (s *GoogleMapsServer) handleGetPlaceDetails(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *GoogleMapsServer) handleGetPlaceDetails(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		PlaceID string `json:"place_id"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -347,9 +296,9 @@ func (s *GoogleMapsServer) handleGetPlaceDetails(ctx context.Context, req *model
 	result += "Price Level: $$\n"
 	result += "Website: https://example.com"
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -362,7 +311,7 @@ func main() {
 
 	log.Printf("Starting %s v%s", SERVER_NAME, SERVER_VERSION)
 
-	if err := server.Run(context.Background()); err != nil {
+	if err := server.Serve(context.Background(), mcp.StdioTransport()); err != nil {
 		log.Fatal("Server failed:", err)
 	}
 }
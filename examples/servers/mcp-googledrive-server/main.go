@@ -294,7 +294,7 @@ func main() {
 	srv := mcp.NewServer("googledrive-server", "1.0.0")
 
 	// Register list_files tool
-	srv.RegisterTool("list_files", "List files in Google Drive", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "list_files", Description: "List files in Google Drive"}, mcp.SchemaToolHandler(func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
 		var query string
 		if queryRaw, exists := args["query"]; exists {
 			if err := json.Unmarshal(queryRaw, &query); err != nil {
@@ -332,10 +332,10 @@ func main() {
 				},
 			},
 		}, nil
-	})
+	}))
 
 	// Register get_file_info tool
-	srv.RegisterTool("get_file_info", "Get detailed information about a Google Drive file", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "get_file_info", Description: "Get detailed information about a Google Drive file"}, mcp.SchemaToolHandler(func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
 		var fileIDRaw json.RawMessage
 		var exists bool
 		if fileIDRaw, exists = args["file_id"]; !exists {
@@ -370,10 +370,10 @@ func main() {
 				},
 			},
 		}, nil
-	})
+	}))
 
 	// Register search_files tool
-	srv.RegisterTool("search_files", "Search for files in Google Drive", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "search_files", Description: "Search for files in Google Drive"}, mcp.SchemaToolHandler(func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
 		var queryRaw json.RawMessage
 		var exists bool
 		if queryRaw, exists = args["query"]; !exists {
@@ -415,10 +415,10 @@ func main() {
 				},
 			},
 		}, nil
-	})
+	}))
 
 	// Register list_folders tool
-	srv.RegisterTool("list_folders", "List folders in Google Drive", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "list_folders", Description: "List folders in Google Drive"}, mcp.SchemaToolHandler(func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
 		var parentID string
 		if parentIDRaw, exists := args["parent_id"]; exists {
 			if err := json.Unmarshal(parentIDRaw, &parentID); err != nil {
@@ -449,10 +449,10 @@ func main() {
 				},
 			},
 		}, nil
-	})
+	}))
 
 	// Start server with stdio transport
-	transport := mcp.StdioTransport{}
+	transport := mcp.StdioTransport()
 	log.Printf("Google Drive server running on stdio")
 
 	if err := srv.Serve(context.Background(), transport); err != nil {
@@ -1,17 +1,19 @@
 package main
 
 import (
-	"os"
 	"testing"
 
 	"github.com/tmc/mcp/testing/mcpscripttest"
 )
 
-func TestMain(m *testing.M) {
-	code := mcpscripttest.RunWithCoverage(m, "googledrive-server")
-	os.Exit(code)
+func TestGoogleDriveServerBasic(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/basic_googledrive_test.txt")
 }
 
-func TestGoogleDriveServer(t *testing.T) {
-	mcpscripttest.RunDirectoryTests(t, "testdata")
+func TestGoogleDriveServerTools(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/googledrive_tools_test.txt")
+}
+
+func TestGoogleDriveServerErrorHandling(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/googledrive_error_handling_test.txt")
 }
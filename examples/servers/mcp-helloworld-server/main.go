@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"math/rand"
@@ -8,7 +10,6 @@ import (
 	"time"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 type HelloWorldServer struct {
@@ -51,7 +52,9 @@ func NewHelloWorldServer() *HelloWorldServer {
 	}
 }
 
-func (s *HelloWorldServer) handleGreeting(args map[string]interface{}) (*modelcontextprotocol.CallToolResult, error) {
+func (s *HelloWorldServer) handleGreeting(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args map[string]interface{}
+	json.Unmarshal(req.Arguments, &args) // both arguments are optional
 	name, hasName := args["name"].(string)
 	if !hasName || name == "" {
 		name = "World"
@@ -70,9 +73,9 @@ func (s *HelloWorldServer) handleGreeting(args map[string]interface{}) (*modelco
 		for lang := range s.greetings {
 			available = append(available, lang)
 		}
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: fmt.Sprintf("Unknown language '%s'. Available languages: %s", language, strings.Join(available, ", ")),
 				},
@@ -83,9 +86,9 @@ func (s *HelloWorldServer) handleGreeting(args map[string]interface{}) (*modelco
 
 	message := fmt.Sprintf("%s, %s!", greeting, name)
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: message,
 			},
@@ -93,13 +96,13 @@ func (s *HelloWorldServer) handleGreeting(args map[string]interface{}) (*modelco
 	}, nil
 }
 
-func (s *HelloWorldServer) handleFortune(args map[string]interface{}) (*modelcontextprotocol.CallToolResult, error) {
+func (s *HelloWorldServer) handleFortune(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	rand.Seed(time.Now().UnixNano())
 	fortune := s.fortunes[rand.Intn(len(s.fortunes))]
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: fortune,
 			},
@@ -107,7 +110,7 @@ func (s *HelloWorldServer) handleFortune(args map[string]interface{}) (*modelcon
 	}, nil
 }
 
-func (s *HelloWorldServer) handleListLanguages(args map[string]interface{}) (*modelcontextprotocol.CallToolResult, error) {
+func (s *HelloWorldServer) handleListLanguages(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var languages []string
 	for lang := range s.greetings {
 		languages = append(languages, lang)
@@ -116,9 +119,9 @@ func (s *HelloWorldServer) handleListLanguages(args map[string]interface{}) (*mo
 	languageList := strings.Join(languages, "\n- ")
 	message := fmt.Sprintf("Supported languages:\n- %s", languageList)
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: message,
 			},
@@ -126,9 +129,9 @@ func (s *HelloWorldServer) handleListLanguages(args map[string]interface{}) (*mo
 	}, nil
 }
 
-func (s *HelloWorldServer) handleGreetingWithFortune(args map[string]interface{}) (*modelcontextprotocol.CallToolResult, error) {
+func (s *HelloWorldServer) handleGreetingWithFortune(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Get greeting
-	greetingResult, err := s.handleGreeting(args)
+	greetingResult, err := s.handleGreeting(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -137,19 +140,19 @@ func (s *HelloWorldServer) handleGreetingWithFortune(args map[string]interface{}
 	}
 
 	// Get fortune
-	fortuneResult, err := s.handleFortune(args)
+	fortuneResult, err := s.handleFortune(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	greeting := greetingResult.Content[0].Text
-	fortune := fortuneResult.Content[0].Text
+	greeting := greetingResult.Content[0].(mcp.TextContent).Text
+	fortune := fortuneResult.Content[0].(mcp.TextContent).Text
 
 	combined := fmt.Sprintf("%s\n\nYour fortune: %s", greeting, fortune)
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: combined,
 			},
@@ -161,68 +164,41 @@ func main() {
 	server := NewHelloWorldServer()
 	mcpServer := mcp.NewServer("mcp-helloworld-server", "1.0.0")
 
+	greetingSchema := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "description": "Name to greet (optional, defaults to 'World')"},
+			"language": {"type": "string", "description": "Language for the greeting (optional, defaults to 'english')"}
+		}
+	}`)
+	noArguments := json.RawMessage(`{"type": "object", "properties": {}}`)
+
 	// Add tools
-	mcpServer.AddTool(modelcontextprotocol.Tool{
+	mcpServer.RegisterTool(mcp.Tool{
 		Name:        "greeting",
 		Description: "Generate a greeting in various languages",
-		InputSchema: map[string]interface{}{
-			"type": "object",
-			"properties": map[string]interface{}{
-				"name": map[string]interface{}{
-					"type":        "string",
-					"description": "Name to greet (optional, defaults to 'World')",
-				},
-				"language": map[string]interface{}{
-					"type":        "string",
-					"description": "Language for the greeting (optional, defaults to 'english')",
-				},
-			},
-		},
-	})
+		InputSchema: greetingSchema,
+	}, server.handleGreeting)
 
-	mcpServer.AddTool(modelcontextprotocol.Tool{
+	mcpServer.RegisterTool(mcp.Tool{
 		Name:        "fortune",
 		Description: "Get a random inspirational fortune",
-		InputSchema: map[string]interface{}{
-			"type":       "object",
-			"properties": map[string]interface{}{},
-		},
-	})
+		InputSchema: noArguments,
+	}, server.handleFortune)
 
-	mcpServer.AddTool(modelcontextprotocol.Tool{
+	mcpServer.RegisterTool(mcp.Tool{
 		Name:        "list_languages",
 		Description: "List all supported languages for greetings",
-		InputSchema: map[string]interface{}{
-			"type":       "object",
-			"properties": map[string]interface{}{},
-		},
-	})
+		InputSchema: noArguments,
+	}, server.handleListLanguages)
 
-	mcpServer.AddTool(modelcontextprotocol.Tool{
+	mcpServer.RegisterTool(mcp.Tool{
 		Name:        "greeting_with_fortune",
 		Description: "Generate a greeting with a bonus fortune",
-		InputSchema: map[string]interface{}{
-			"type": "object",
-			"properties": map[string]interface{}{
-				"name": map[string]interface{}{
-					"type":        "string",
-					"description": "Name to greet (optional, defaults to 'World')",
-				},
-				"language": map[string]interface{}{
-					"type":        "string",
-					"description": "Language for the greeting (optional, defaults to 'english')",
-				},
-			},
-		},
-	})
-
-	// Add tool handlers
-	mcpServer.OnToolCall("greeting", server.handleGreeting)
-	mcpServer.OnToolCall("fortune", server.handleFortune)
-	mcpServer.OnToolCall("list_languages", server.handleListLanguages)
-	mcpServer.OnToolCall("greeting_with_fortune", server.handleGreetingWithFortune)
+		InputSchema: greetingSchema,
+	}, server.handleGreetingWithFortune)
 
-	if err := mcpServer.Serve(); err != nil {
+	if err := mcpServer.Serve(context.Background(), mcp.StdioTransport()); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
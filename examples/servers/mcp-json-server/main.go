@@ -9,79 +9,80 @@ import (
 	"strings"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 func main() {
-	server := mcp.NewServer(mcp.ServerOptions{
-		Name:        "json-server",
-		Version:     "1.0.0",
-		Description: "A server for JSON manipulation and validation operations",
-	})
+	server := mcp.NewServer("json-server", "1.0.0", mcp.WithServerInstructions("A server for JSON manipulation and validation operations"))
 
 	// Add JSON validation tool
-	server.AddTool(modelcontextprotocol.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "validate_json",
 		Description: "Validates if a string is valid JSON",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"json_string": map[string]interface{}{
-					"type":        "string",
-					"description": "The JSON string to validate",
-				},
+			"properties": {
+				"json_string": {
+					"type": "string",
+					"description": "The JSON string to validate"
+				}
 			},
-			"required": []string{"json_string"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (modelcontextprotocol.CallToolResult, error) {
-		args := req.Params.Arguments.(map[string]interface{})
+			"required": ["json_string"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args map[string]interface{}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
 		jsonString := args["json_string"].(string)
 
 		var result interface{}
 		err := json.Unmarshal([]byte(jsonString), &result)
 
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Invalid JSON: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Invalid JSON: %v", err),
 					},
 				},
 			}, nil
 		}
 
-		return modelcontextprotocol.CallToolResult{
-			Content: []interface{}{
-				map[string]interface{}{
-					"type": "text",
-					"text": "Valid JSON",
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
+					Type: "text",
+					Text: "Valid JSON",
 				},
 			},
 		}, nil
 	})
 
 	// Add JSON formatting tool
-	server.AddTool(modelcontextprotocol.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "format_json",
 		Description: "Formats JSON with proper indentation",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"json_string": map[string]interface{}{
-					"type":        "string",
-					"description": "The JSON string to format",
+			"properties": {
+				"json_string": {
+					"type": "string",
+					"description": "The JSON string to format"
 				},
-				"indent": map[string]interface{}{
-					"type":        "integer",
+				"indent": {
+					"type": "integer",
 					"description": "Number of spaces for indentation (default: 2)",
-					"default":     2,
-				},
+					"default": 2
+				}
 			},
-			"required": []string{"json_string"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (modelcontextprotocol.CallToolResult, error) {
-		args := req.Params.Arguments.(map[string]interface{})
+			"required": ["json_string"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args map[string]interface{}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
 		jsonString := args["json_string"].(string)
 
 		indent := 2
@@ -94,11 +95,11 @@ func main() {
 		var jsonObj interface{}
 		err := json.Unmarshal([]byte(jsonString), &jsonObj)
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Invalid JSON: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Invalid JSON: %v", err),
 					},
 				},
 			}, nil
@@ -107,52 +108,55 @@ func main() {
 		indentStr := strings.Repeat(" ", indent)
 		formatted, err := json.MarshalIndent(jsonObj, "", indentStr)
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Error formatting JSON: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error formatting JSON: %v", err),
 					},
 				},
 			}, nil
 		}
 
-		return modelcontextprotocol.CallToolResult{
-			Content: []interface{}{
-				map[string]interface{}{
-					"type": "text",
-					"text": string(formatted),
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
+					Type: "text",
+					Text: string(formatted),
 				},
 			},
 		}, nil
 	})
 
 	// Add JSON minification tool
-	server.AddTool(modelcontextprotocol.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "minify_json",
 		Description: "Removes whitespace from JSON to minimize size",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"json_string": map[string]interface{}{
-					"type":        "string",
-					"description": "The JSON string to minify",
-				},
+			"properties": {
+				"json_string": {
+					"type": "string",
+					"description": "The JSON string to minify"
+				}
 			},
-			"required": []string{"json_string"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (modelcontextprotocol.CallToolResult, error) {
-		args := req.Params.Arguments.(map[string]interface{})
+			"required": ["json_string"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args map[string]interface{}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
 		jsonString := args["json_string"].(string)
 
 		var jsonObj interface{}
 		err := json.Unmarshal([]byte(jsonString), &jsonObj)
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Invalid JSON: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Invalid JSON: %v", err),
 					},
 				},
 			}, nil
@@ -160,57 +164,60 @@ func main() {
 
 		minified, err := json.Marshal(jsonObj)
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Error minifying JSON: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error minifying JSON: %v", err),
 					},
 				},
 			}, nil
 		}
 
-		return modelcontextprotocol.CallToolResult{
-			Content: []interface{}{
-				map[string]interface{}{
-					"type": "text",
-					"text": string(minified),
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
+					Type: "text",
+					Text: string(minified),
 				},
 			},
 		}, nil
 	})
 
 	// Add JSON path extraction tool
-	server.AddTool(modelcontextprotocol.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "extract_json_path",
 		Description: "Extracts value at specified JSON path",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"json_string": map[string]interface{}{
-					"type":        "string",
-					"description": "The JSON string to extract from",
-				},
-				"path": map[string]interface{}{
-					"type":        "string",
-					"description": "JSON path (e.g., 'data.items[0].name')",
+			"properties": {
+				"json_string": {
+					"type": "string",
+					"description": "The JSON string to extract from"
 				},
+				"path": {
+					"type": "string",
+					"description": "JSON path (e.g., 'data.items[0].name')"
+				}
 			},
-			"required": []string{"json_string", "path"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (modelcontextprotocol.CallToolResult, error) {
-		args := req.Params.Arguments.(map[string]interface{})
+			"required": ["json_string", "path"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args map[string]interface{}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
 		jsonString := args["json_string"].(string)
 		path := args["path"].(string)
 
 		var jsonObj interface{}
 		err := json.Unmarshal([]byte(jsonString), &jsonObj)
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Invalid JSON: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Invalid JSON: %v", err),
 					},
 				},
 			}, nil
@@ -219,11 +226,11 @@ func main() {
 		// Simple path extraction (supports dot notation and array indices)
 		value, err := extractJSONPath(jsonObj, path)
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Path extraction error: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Path extraction error: %v", err),
 					},
 				},
 			}, nil
@@ -231,27 +238,27 @@ func main() {
 
 		result, err := json.MarshalIndent(value, "", "  ")
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Error serializing result: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error serializing result: %v", err),
 					},
 				},
 			}, nil
 		}
 
-		return modelcontextprotocol.CallToolResult{
-			Content: []interface{}{
-				map[string]interface{}{
-					"type": "text",
-					"text": string(result),
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
+					Type: "text",
+					Text: string(result),
 				},
 			},
 		}, nil
 	})
 
-	if err := server.Run(context.Background()); err != nil {
+	if err := server.Serve(context.Background(), mcp.StdioTransport()); err != nil {
 		log.Fatal(err)
 	}
 }
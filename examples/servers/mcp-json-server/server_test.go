@@ -42,6 +42,8 @@ func TestJSONServerEdgeCases(t *testing.T) {
 // TestJSONServerPerformance runs performance tests with aggressive timeouts
 func TestJSONServerPerformance(t *testing.T) {
 	opts := mcpscripttest.DefaultOptions()
-	opts.TimeoutConfig = mcpscripttest.UltraAggressiveTimeoutConfig()
+	opts.TimeoutConfig.TestOverallTimeout = 10 * time.Second
+	opts.TimeoutConfig.DefaultCommandTimeout = 2 * time.Second
+	opts.TimeoutConfig.ServerResponseTimeout = 2 * time.Second
 	mcpscripttest.Test(t, "testdata/json_performance_test.txt", opts)
 }
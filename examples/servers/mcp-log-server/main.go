@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -11,42 +12,40 @@ import (
 	"time"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 func main() {
-	server := mcp.NewServer(mcp.ServerOptions{
-		Name:        "log-server",
-		Version:     "1.0.0",
-		Description: "A server for log file analysis and monitoring operations",
-	})
+	server := mcp.NewServer("log-server", "1.0.0", mcp.WithServerInstructions("A server for log file analysis and monitoring operations"))
 
 	// Add log file reading tool
-	server.AddTool(modelcontextprotocol.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "read_log",
 		Description: "Reads and filters log file content",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"file_path": map[string]interface{}{
-					"type":        "string",
-					"description": "Path to the log file",
+			"properties": {
+				"file_path": {
+					"type": "string",
+					"description": "Path to the log file"
 				},
-				"lines": map[string]interface{}{
-					"type":        "integer",
+				"lines": {
+					"type": "integer",
 					"description": "Number of lines to read (default: 100)",
-					"default":     100,
+					"default": 100
 				},
-				"from_end": map[string]interface{}{
-					"type":        "boolean",
+				"from_end": {
+					"type": "boolean",
 					"description": "Read from end of file (tail behavior, default: true)",
-					"default":     true,
-				},
+					"default": true
+				}
 			},
-			"required": []string{"file_path"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (modelcontextprotocol.CallToolResult, error) {
-		args := req.Params.Arguments.(map[string]interface{})
+			"required": ["file_path"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args map[string]interface{}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
 		filePath := args["file_path"].(string)
 
 		lines := 100
@@ -65,58 +64,61 @@ func main() {
 
 		content, err := readLogFile(filePath, lines, fromEnd)
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Error reading log file: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error reading log file: %v", err),
 					},
 				},
 			}, nil
 		}
 
-		return modelcontextprotocol.CallToolResult{
-			Content: []interface{}{
-				map[string]interface{}{
-					"type": "text",
-					"text": content,
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
+					Type: "text",
+					Text: content,
 				},
 			},
 		}, nil
 	})
 
 	// Add log filtering tool
-	server.AddTool(modelcontextprotocol.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "filter_logs",
 		Description: "Filters log entries by pattern, level, or time range",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"file_path": map[string]interface{}{
-					"type":        "string",
-					"description": "Path to the log file",
+			"properties": {
+				"file_path": {
+					"type": "string",
+					"description": "Path to the log file"
 				},
-				"pattern": map[string]interface{}{
-					"type":        "string",
-					"description": "Regular expression pattern to match",
+				"pattern": {
+					"type": "string",
+					"description": "Regular expression pattern to match"
 				},
-				"level": map[string]interface{}{
-					"type":        "string",
-					"description": "Log level to filter (ERROR, WARN, INFO, DEBUG)",
+				"level": {
+					"type": "string",
+					"description": "Log level to filter (ERROR, WARN, INFO, DEBUG)"
 				},
-				"since": map[string]interface{}{
-					"type":        "string",
-					"description": "Start time (e.g., '2024-01-01 12:00:00')",
-				},
-				"until": map[string]interface{}{
-					"type":        "string",
-					"description": "End time (e.g., '2024-01-01 13:00:00')",
+				"since": {
+					"type": "string",
+					"description": "Start time (e.g., '2024-01-01 12:00:00')"
 				},
+				"until": {
+					"type": "string",
+					"description": "End time (e.g., '2024-01-01 13:00:00')"
+				}
 			},
-			"required": []string{"file_path"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (modelcontextprotocol.CallToolResult, error) {
-		args := req.Params.Arguments.(map[string]interface{})
+			"required": ["file_path"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args map[string]interface{}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
 		filePath := args["file_path"].(string)
 
 		var pattern string
@@ -141,47 +143,50 @@ func main() {
 
 		result, err := filterLogs(filePath, pattern, level, since, until)
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Error filtering logs: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error filtering logs: %v", err),
 					},
 				},
 			}, nil
 		}
 
-		return modelcontextprotocol.CallToolResult{
-			Content: []interface{}{
-				map[string]interface{}{
-					"type": "text",
-					"text": result,
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
+					Type: "text",
+					Text: result,
 				},
 			},
 		}, nil
 	})
 
 	// Add log statistics tool
-	server.AddTool(modelcontextprotocol.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "log_stats",
 		Description: "Generates statistics about log file content",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"file_path": map[string]interface{}{
-					"type":        "string",
-					"description": "Path to the log file",
+			"properties": {
+				"file_path": {
+					"type": "string",
+					"description": "Path to the log file"
 				},
-				"include_levels": map[string]interface{}{
-					"type":        "boolean",
+				"include_levels": {
+					"type": "boolean",
 					"description": "Include log level statistics (default: true)",
-					"default":     true,
-				},
+					"default": true
+				}
 			},
-			"required": []string{"file_path"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (modelcontextprotocol.CallToolResult, error) {
-		args := req.Params.Arguments.(map[string]interface{})
+			"required": ["file_path"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args map[string]interface{}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
 		filePath := args["file_path"].(string)
 
 		includeLevels := true
@@ -193,47 +198,50 @@ func main() {
 
 		stats, err := generateLogStats(filePath, includeLevels)
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Error generating log statistics: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error generating log statistics: %v", err),
 					},
 				},
 			}, nil
 		}
 
-		return modelcontextprotocol.CallToolResult{
-			Content: []interface{}{
-				map[string]interface{}{
-					"type": "text",
-					"text": stats,
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
+					Type: "text",
+					Text: stats,
 				},
 			},
 		}, nil
 	})
 
 	// Add log monitoring tool
-	server.AddTool(modelcontextprotocol.Tool{
+	server.RegisterTool(mcp.Tool{
 		Name:        "monitor_logs",
 		Description: "Monitors log file for new entries (like tail -f)",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"file_path": map[string]interface{}{
-					"type":        "string",
-					"description": "Path to the log file",
+			"properties": {
+				"file_path": {
+					"type": "string",
+					"description": "Path to the log file"
 				},
-				"duration": map[string]interface{}{
-					"type":        "integer",
+				"duration": {
+					"type": "integer",
 					"description": "Monitoring duration in seconds (default: 10)",
-					"default":     10,
-				},
+					"default": 10
+				}
 			},
-			"required": []string{"file_path"},
-		},
-	}, func(ctx context.Context, req modelcontextprotocol.CallToolRequest) (modelcontextprotocol.CallToolResult, error) {
-		args := req.Params.Arguments.(map[string]interface{})
+			"required": ["file_path"]
+		}`),
+	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args map[string]interface{}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
 		filePath := args["file_path"].(string)
 
 		duration := 10
@@ -245,27 +253,27 @@ func main() {
 
 		result, err := monitorLogFile(filePath, duration)
 		if err != nil {
-			return modelcontextprotocol.CallToolResult{
-				Content: []interface{}{
-					map[string]interface{}{
-						"type": "text",
-						"text": fmt.Sprintf("Error monitoring log file: %v", err),
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error monitoring log file: %v", err),
 					},
 				},
 			}, nil
 		}
 
-		return modelcontextprotocol.CallToolResult{
-			Content: []interface{}{
-				map[string]interface{}{
-					"type": "text",
-					"text": result,
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
+					Type: "text",
+					Text: result,
 				},
 			},
 		}, nil
 	})
 
-	if err := server.Run(context.Background()); err != nil {
+	if err := server.Serve(context.Background(), mcp.StdioTransport()); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -402,13 +410,7 @@ func monitorLogFile(filePath string, duration int) (string, error) {
 	result += fmt.Sprintf("Size Change: %+d bytes\n", finalSize-initialSize)
 
 	if finalSize > initialSize {
-		// Read the new content
-		content, err := os.ReadFile(filePath)
-		if err == nil {
-			allLines := strings.Split(string(content), "\n")
-			// Estimate new lines (simplified)
-			result += "\nNew activity detected!\n"
-		}
+		result += "\nNew activity detected!\n"
 	} else {
 		result += "\nNo new activity detected.\n"
 	}
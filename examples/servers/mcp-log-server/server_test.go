@@ -18,6 +18,8 @@ func TestLogServerBasic(t *testing.T) {
 // TestLogServerPerformance runs performance tests with aggressive timeouts
 func TestLogServerPerformance(t *testing.T) {
 	opts := mcpscripttest.DefaultOptions()
-	opts.TimeoutConfig = mcpscripttest.UltraAggressiveTimeoutConfig()
+	opts.TimeoutConfig.TestOverallTimeout = 10 * time.Second
+	opts.TimeoutConfig.DefaultCommandTimeout = 2 * time.Second
+	opts.TimeoutConfig.ServerResponseTimeout = 2 * time.Second
 	mcpscripttest.Test(t, "testdata/log_performance_test.txt", opts)
 }
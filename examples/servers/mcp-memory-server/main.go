@@ -10,7 +10,6 @@ import (
 	"strings"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 type Entity struct {
@@ -247,7 +246,11 @@ func main() {
 	kgManager := NewKnowledgeGraphManager()
 
 	// Register create_entities tool
-	srv.RegisterTool("create_entities", "Create new entities in the knowledge graph", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "create_entities", Description: "Create new entities in the knowledge graph"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var entitiesRaw json.RawMessage
 		var exists bool
 		if entitiesRaw, exists = args["entities"]; !exists {
@@ -276,9 +279,9 @@ func main() {
 
 		log.Printf("Created %d new entities", len(newEntities))
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(responseJSON),
 				},
@@ -287,7 +290,11 @@ func main() {
 	})
 
 	// Register create_relations tool
-	srv.RegisterTool("create_relations", "Create new relations between entities in the knowledge graph", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "create_relations", Description: "Create new relations between entities in the knowledge graph"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var relationsRaw json.RawMessage
 		var exists bool
 		if relationsRaw, exists = args["relations"]; !exists {
@@ -316,9 +323,9 @@ func main() {
 
 		log.Printf("Created %d new relations", len(newRelations))
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(responseJSON),
 				},
@@ -327,7 +334,11 @@ func main() {
 	})
 
 	// Register add_observations tool
-	srv.RegisterTool("add_observations", "Add observations to existing entities", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "add_observations", Description: "Add observations to existing entities"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var observationsRaw json.RawMessage
 		var exists bool
 		if observationsRaw, exists = args["observations"]; !exists {
@@ -351,9 +362,9 @@ func main() {
 
 		log.Printf("Added observations to %d entities", len(results))
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(responseJSON),
 				},
@@ -362,7 +373,11 @@ func main() {
 	})
 
 	// Register search_memory tool
-	srv.RegisterTool("search_memory", "Search the knowledge graph for entities and relations", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "search_memory", Description: "Search the knowledge graph for entities and relations"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var query string
 		if queryRaw, exists := args["query"]; exists {
 			if err := json.Unmarshal(queryRaw, &query); err != nil {
@@ -383,9 +398,9 @@ func main() {
 		log.Printf("Memory search for query: '%s', found %d entities and %d relations",
 			query, len(results.Entities), len(results.Relations))
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: string(responseJSON),
 				},
@@ -394,7 +409,7 @@ func main() {
 	})
 
 	// Start server with stdio transport
-	transport := mcp.StdioTransport{}
+	transport := mcp.StdioTransport()
 	log.Printf("Memory server running on stdio, using file: %s", kgManager.filePath)
 
 	if err := srv.Serve(context.Background(), transport); err != nil {
@@ -11,7 +11,6 @@ import (
 
 	_ "github.com/lib/pq"
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 type PostgreSQLServer struct {
@@ -258,7 +257,11 @@ func main() {
 	srv := mcp.NewServer("postgresql-server", "1.0.0")
 
 	// Register list_tables tool
-	srv.RegisterTool("list_tables", "List all tables in a PostgreSQL database schema", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "list_tables", Description: "List all tables in a PostgreSQL database schema"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var schema string = "public" // default
 		if schemaRaw, exists := args["schema"]; exists {
 			if err := json.Unmarshal(schemaRaw, &schema); err != nil {
@@ -268,22 +271,22 @@ func main() {
 
 		tables, err := ps.listTables(schema)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error listing tables: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Listed tables for schema: %s", schema)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: tables,
 				},
@@ -292,7 +295,11 @@ func main() {
 	})
 
 	// Register describe_table tool
-	srv.RegisterTool("describe_table", "Describe the structure of a PostgreSQL table", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "describe_table", Description: "Describe the structure of a PostgreSQL table"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var tableNameRaw json.RawMessage
 		var exists bool
 		if tableNameRaw, exists = args["table"]; !exists {
@@ -313,22 +320,22 @@ func main() {
 
 		description, err := ps.describeTable(tableName, schema)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error describing table: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Described table: %s.%s", schema, tableName)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: description,
 				},
@@ -337,7 +344,11 @@ func main() {
 	})
 
 	// Register execute_query tool
-	srv.RegisterTool("execute_query", "Execute a read-only SQL query against the PostgreSQL database", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "execute_query", Description: "Execute a read-only SQL query against the PostgreSQL database"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
 		var queryRaw json.RawMessage
 		var exists bool
 		if queryRaw, exists = args["query"]; !exists {
@@ -358,22 +369,22 @@ func main() {
 
 		result, err := ps.executeQuery(query, limit)
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error executing query: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Executed query: %s", query)
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: result,
 				},
@@ -382,25 +393,25 @@ func main() {
 	})
 
 	// Register list_schemas tool
-	srv.RegisterTool("list_schemas", "List all schemas in the PostgreSQL database", func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+	srv.RegisterTool(mcp.Tool{Name: "list_schemas", Description: "List all schemas in the PostgreSQL database"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		schemas, err := ps.listSchemas()
 		if err != nil {
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
+			return &mcp.CallToolResult{
+				Content: []any{
+					mcp.TextContent{
 						Type: "text",
 						Text: fmt.Sprintf("Error listing schemas: %s", err.Error()),
 					},
 				},
-				IsError: boolPtr(true),
+				IsError: true,
 			}, nil
 		}
 
 		log.Printf("Listed database schemas")
 
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: schemas,
 				},
@@ -409,7 +420,7 @@ func main() {
 	})
 
 	// Start server with stdio transport
-	transport := mcp.StdioTransport{}
+	transport := mcp.StdioTransport()
 	readOnlyStatus := ""
 	if ps.readOnlyMode {
 		readOnlyStatus = " (read-only mode)"
@@ -420,7 +431,3 @@ func main() {
 		log.Fatalf("Server error: %v", err)
 	}
 }
-
-func boolPtr(b bool) *bool {
-	return &b
-}
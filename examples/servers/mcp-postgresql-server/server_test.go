@@ -1,17 +1,19 @@
 package main
 
 import (
-	"os"
 	"testing"
 
 	"github.com/tmc/mcp/testing/mcpscripttest"
 )
 
-func TestMain(m *testing.M) {
-	code := mcpscripttest.RunWithCoverage(m, "postgresql-server")
-	os.Exit(code)
+func TestPostgreSQLServerBasic(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/basic_postgresql_test.txt")
 }
 
-func TestPostgreSQLServer(t *testing.T) {
-	mcpscripttest.RunDirectoryTests(t, "testdata")
+func TestPostgreSQLServerTools(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/postgresql_tools_test.txt")
+}
+
+func TestPostgreSQLServerErrorHandling(t *testing.T) {
+	mcpscripttest.Test(t, "testdata/postgresql_error_handling_test.txt")
 }
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/chromedp/chromedp"
 	"github.com/tmc/mcp"
@@ -528,7 +529,8 @@ func main() {
 
 	// Run the server
 	log.Println("Ready to accept connections")
-	if err := server.Serve(ctx, nil); err != nil {
+	// Serve until a signal, then let in-flight calls finish.
+	if err := server.ServeUntilDone(ctx, nil, 10*time.Second); err != nil {
 		log.Printf("Server error: %v", err)
 	}
 }
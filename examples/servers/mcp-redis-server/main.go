@@ -6,9 +6,9 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 const (
@@ -40,158 +40,109 @@ func NewRedisServer() *RedisServer {
 }
 
 func (s *RedisServer) SetupHandlers() {
-	// Initialize with tools
-	s.OnInitialize(func(ctx context.Context, req *modelcontextprotocol.InitializeRequest) (*modelcontextprotocol.InitializeResult, error) {
-		return &modelcontextprotocol.InitializeResult{
-			ProtocolVersion: modelcontextprotocol.LatestProtocolVersion,
-			Capabilities: &modelcontextprotocol.ServerCapabilities{
-				Tools: &modelcontextprotocol.ToolsCapability{},
+	s.RegisterTool(mcp.Tool{
+		Name:        "redis_get",
+		Description: "Get value from Redis key",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"key": {
+					"type": "string",
+					"description": "Redis key to retrieve"
+				}
 			},
-			ServerInfo: &modelcontextprotocol.Implementation{
-				Name:    SERVER_NAME,
-				Version: SERVER_VERSION,
-			},
-		}, nil
-	})
-
-	// List available tools
-	s.OnListTools(func(ctx context.Context, req *modelcontextprotocol.ListToolsRequest) (*modelcontextprotocol.ListToolsResult, error) {
-		tools := []modelcontextprotocol.Tool{
-			{
-				Name:        "redis_get",
-				Description: "Get value from Redis key",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"key": map[string]interface{}{
-							"type":        "string",
-							"description": "Redis key to retrieve",
-						},
-					},
-					"required": []string{"key"},
+			"required": ["key"]
+		}`),
+	}, s.handleRedisGet)
+	s.RegisterTool(mcp.Tool{
+		Name:        "redis_set",
+		Description: "Set value in Redis",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"key": {
+					"type": "string",
+					"description": "Redis key to set"
 				},
-			},
-			{
-				Name:        "redis_set",
-				Description: "Set value in Redis",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"key": map[string]interface{}{
-							"type":        "string",
-							"description": "Redis key to set",
-						},
-						"value": map[string]interface{}{
-							"type":        "string",
-							"description": "Value to store",
-						},
-						"ttl": map[string]interface{}{
-							"type":        "integer",
-							"description": "Time to live in seconds (optional)",
-						},
-					},
-					"required": []string{"key", "value"},
-				},
-			},
-			{
-				Name:        "redis_delete",
-				Description: "Delete Redis key",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"key": map[string]interface{}{
-							"type":        "string",
-							"description": "Redis key to delete",
-						},
-					},
-					"required": []string{"key"},
+				"value": {
+					"type": "string",
+					"description": "Value to store"
 				},
+				"ttl": {
+					"type": "integer",
+					"description": "Time to live in seconds (optional)"
+				}
 			},
-			{
-				Name:        "redis_exists",
-				Description: "Check if Redis key exists",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"key": map[string]interface{}{
-							"type":        "string",
-							"description": "Redis key to check",
-						},
-					},
-					"required": []string{"key"},
-				},
+			"required": ["key", "value"]
+		}`),
+	}, s.handleRedisSet)
+	s.RegisterTool(mcp.Tool{
+		Name:        "redis_delete",
+		Description: "Delete Redis key",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"key": {
+					"type": "string",
+					"description": "Redis key to delete"
+				}
 			},
-			{
-				Name:        "redis_keys",
-				Description: "List Redis keys matching pattern",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"pattern": map[string]interface{}{
-							"type":        "string",
-							"description": "Pattern to match keys (default: *)",
-							"default":     "*",
-						},
-					},
-				},
+			"required": ["key"]
+		}`),
+	}, s.handleRedisDelete)
+	s.RegisterTool(mcp.Tool{
+		Name:        "redis_exists",
+		Description: "Check if Redis key exists",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"key": {
+					"type": "string",
+					"description": "Redis key to check"
+				}
 			},
-		}
-
-		return &modelcontextprotocol.ListToolsResult{
-			Tools: tools,
-		}, nil
-	})
-
-	// Handle tool calls
-	s.OnCallTool(func(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
-		switch req.Params.Name {
-		case "redis_get":
-			return s.handleRedisGet(ctx, req)
-		case "redis_set":
-			return s.handleRedisSet(ctx, req)
-		case "redis_delete":
-			return s.handleRedisDelete(ctx, req)
-		case "redis_exists":
-			return s.handleRedisExists(ctx, req)
-		case "redis_keys":
-			return s.handleRedisKeys(ctx, req)
-		default:
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
-						Type: "text",
-						Text: "Unknown tool: " + req.Params.Name,
-					},
-				},
-				IsError: &[]bool{true}[0],
-			}, nil
-		}
-	})
+			"required": ["key"]
+		}`),
+	}, s.handleRedisExists)
+	s.RegisterTool(mcp.Tool{
+		Name:        "redis_keys",
+		Description: "List Redis keys matching pattern",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"pattern": {
+					"type": "string",
+					"description": "Pattern to match keys (default: *)",
+					"default": "*"
+				}
+			}
+		}`),
+	}, s.handleRedisKeys)
 }
 
-func (s *RedisServer) handleRedisGet(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *RedisServer) handleRedisGet(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Key string `json:"key"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
 	// Mock implementation - in real version would connect to Redis
 	result := "value-for-" + args.Key
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: "Retrieved value: " + result,
 			},
@@ -199,22 +150,22 @@ func (s *RedisServer) handleRedisGet(ctx context.Context, req *modelcontextproto
 	}, nil
 }
 
-func (s *RedisServer) handleRedisSet(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *RedisServer) handleRedisSet(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Key   string `json:"key"`
 		Value string `json:"value"`
 		TTL   *int   `json:"ttl,omitempty"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -224,9 +175,9 @@ func (s *RedisServer) handleRedisSet(ctx context.Context, req *modelcontextproto
 		response += " (TTL: " + strconv.Itoa(*args.TTL) + "s)"
 	}
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: response,
 			},
@@ -234,26 +185,26 @@ func (s *RedisServer) handleRedisSet(ctx context.Context, req *modelcontextproto
 	}, nil
 }
 
-func (s *RedisServer) handleRedisDelete(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *RedisServer) handleRedisDelete(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Key string `json:"key"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: "Deleted key: " + args.Key,
 			},
@@ -261,26 +212,26 @@ func (s *RedisServer) handleRedisDelete(ctx context.Context, req *modelcontextpr
 	}, nil
 }
 
-func (s *RedisServer) handleRedisExists(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *RedisServer) handleRedisExists(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Key string `json:"key"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: "Key " + args.Key + " exists: true",
 			},
@@ -288,20 +239,20 @@ func (s *RedisServer) handleRedisExists(ctx context.Context, req *modelcontextpr
 	}, nil
 }
 
-func (s *RedisServer) handleRedisKeys(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *RedisServer) handleRedisKeys(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Pattern string `json:"pattern"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -311,11 +262,11 @@ func (s *RedisServer) handleRedisKeys(ctx context.Context, req *modelcontextprot
 
 	// Mock implementation
 	keys := []string{"key1", "key2", "test:*"}
-	result := "Keys matching " + args.Pattern + ": " + args.Pattern + " -> [" + "key1, key2, test:*" + "]"
+	result := "Keys matching " + args.Pattern + ": [" + strings.Join(keys, ", ") + "]"
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -336,7 +287,7 @@ func main() {
 	log.Printf("Starting %s v%s", SERVER_NAME, SERVER_VERSION)
 	log.Printf("Redis connection: %s:%d (db %d)", server.host, server.port, server.db)
 
-	if err := server.Run(context.Background()); err != nil {
+	if err := server.Serve(context.Background(), mcp.StdioTransport()); err != nil {
 		log.Fatal("Server failed:", err)
 	}
 }
@@ -109,12 +109,11 @@ func main() {
 	// Let's just create a pipe and pump data to it.
 
 	log.Println("Starting protocol server via stdio...")
-	if err := server.Serve(ctx, stdioTransport); err != nil {
-		if err != context.Canceled {
-			log.Fatalf("Error serving: %v", err)
-		}
-		log.Println("Server terminated.")
+	// Serve until a signal, then let in-flight calls finish.
+	if err := server.ServeUntilDone(ctx, stdioTransport, 10*time.Second); err != nil {
+		log.Fatalf("Error serving: %v", err)
 	}
+	log.Println("Server terminated.")
 }
 
 func registerListScreensTool(server *mcp.Server) {
@@ -51,12 +51,11 @@ func main() {
 
 	// Serve via stdio
 	log.Println("Starting protocol server via stdio...")
-	if err := server.Serve(ctx, nil); err != nil {
-		if err != context.Canceled {
-			log.Fatalf("Error serving: %v", err)
-		}
-		log.Println("Server terminated.")
+	// Serve until a signal, then let in-flight calls finish.
+	if err := server.ServeUntilDone(ctx, nil, 10*time.Second); err != nil {
+		log.Fatalf("Error serving: %v", err)
 	}
+	log.Println("Server terminated.")
 }
 
 func registerTools(server *mcp.Server) {
@@ -91,7 +90,7 @@ func registerTools(server *mcp.Server) {
 
 	server.RegisterTool(captureExceptionTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		_ = mcptel.CurrentSpan
-		server.GetLogger().InfoContext(ctx, "capture_exception tool called")
+		slog.InfoContext(ctx, "capture_exception tool called")
 		var params map[string]any
 		if err := json.Unmarshal(req.Arguments, &params); err != nil {
 			return nil, fmt.Errorf("invalid parameters: %v", err)
@@ -147,7 +146,7 @@ func registerTools(server *mcp.Server) {
 
 	server.RegisterTool(listIssuesTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		_ = mcptel.CurrentSpan
-		server.GetLogger().InfoContext(ctx, "list_issues tool called")
+		slog.InfoContext(ctx, "list_issues tool called")
 		var params map[string]any
 		if err := json.Unmarshal(req.Arguments, &params); err != nil {
 			return nil, fmt.Errorf("invalid parameters: %v", err)
@@ -206,7 +205,7 @@ func registerTools(server *mcp.Server) {
 
 	server.RegisterTool(createReleaseTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		_ = mcptel.CurrentSpan
-		server.GetLogger().InfoContext(ctx, "create_release tool called")
+		slog.InfoContext(ctx, "create_release tool called")
 		var params map[string]any
 		if err := json.Unmarshal(req.Arguments, &params); err != nil {
 			return nil, fmt.Errorf("invalid parameters: %v", err)
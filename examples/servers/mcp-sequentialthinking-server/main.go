@@ -52,12 +52,11 @@ func main() {
 
 	// Serve via stdio
 	log.Println("Starting protocol server via stdio...")
-	if err := server.Serve(ctx, nil); err != nil {
-		if err != context.Canceled {
-			log.Fatalf("Error serving: %v", err)
-		}
-		log.Println("Server terminated.")
+	// Serve until a signal, then let in-flight calls finish.
+	if err := server.ServeUntilDone(ctx, nil, 10*time.Second); err != nil {
+		log.Fatalf("Error serving: %v", err)
 	}
+	log.Println("Server terminated.")
 }
 
 func registerTools(server *mcp.Server) {
@@ -88,7 +87,7 @@ func registerTools(server *mcp.Server) {
 
 	server.RegisterTool(thinkStepByStepTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		_ = mcptel.CurrentSpan
-		server.GetLogger().InfoContext(ctx, "think_step_by_step tool called")
+		slog.InfoContext(ctx, "think_step_by_step tool called")
 		var params map[string]any
 		if err := json.Unmarshal(req.Arguments, &params); err != nil {
 			return nil, fmt.Errorf("invalid parameters: %v", err)
@@ -145,7 +144,7 @@ func registerTools(server *mcp.Server) {
 
 	server.RegisterTool(analyzeReasoningTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		_ = mcptel.CurrentSpan
-		server.GetLogger().InfoContext(ctx, "analyze_reasoning tool called")
+		slog.InfoContext(ctx, "analyze_reasoning tool called")
 		var params map[string]any
 		if err := json.Unmarshal(req.Arguments, &params); err != nil {
 			return nil, fmt.Errorf("invalid parameters: %v", err)
@@ -201,7 +200,7 @@ func registerTools(server *mcp.Server) {
 
 	server.RegisterTool(createDecisionTreeTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		_ = mcptel.CurrentSpan
-		server.GetLogger().InfoContext(ctx, "create_decision_tree tool called")
+		slog.InfoContext(ctx, "create_decision_tree tool called")
 		var params map[string]any
 		if err := json.Unmarshal(req.Arguments, &params); err != nil {
 			return nil, fmt.Errorf("invalid parameters: %v", err)
@@ -4,10 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 const (
@@ -31,190 +31,139 @@ func NewSlackServer() *SlackServer {
 }
 
 func (s *SlackServer) SetupHandlers() {
-	// Initialize with tools
-	s.OnInitialize(func(ctx context.Context, req *modelcontextprotocol.InitializeRequest) (*modelcontextprotocol.InitializeResult, error) {
-		return &modelcontextprotocol.InitializeResult{
-			ProtocolVersion: modelcontextprotocol.LatestProtocolVersion,
-			Capabilities: &modelcontextprotocol.ServerCapabilities{
-				Tools: &modelcontextprotocol.ToolsCapability{},
-			},
-			ServerInfo: &modelcontextprotocol.Implementation{
-				Name:    SERVER_NAME,
-				Version: SERVER_VERSION,
-			},
-		}, nil
-	})
-
-	// List available tools
-	s.OnListTools(func(ctx context.Context, req *modelcontextprotocol.ListToolsRequest) (*modelcontextprotocol.ListToolsResult, error) {
-		tools := []modelcontextprotocol.Tool{
-			{
-				Name:        "send_message",
-				Description: "Send a message to a Slack channel",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"channel": map[string]interface{}{
-							"type":        "string",
-							"description": "Channel ID or name to send message to",
-						},
-						"text": map[string]interface{}{
-							"type":        "string",
-							"description": "Message text to send",
-						},
-						"thread_ts": map[string]interface{}{
-							"type":        "string",
-							"description": "Timestamp of thread to reply to (optional)",
-						},
-					},
-					"required": []string{"channel", "text"},
+	s.RegisterTool(mcp.Tool{
+		Name:        "send_message",
+		Description: "Send a message to a Slack channel",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"channel": {
+					"type": "string",
+					"description": "Channel ID or name to send message to"
 				},
-			},
-			{
-				Name:        "list_channels",
-				Description: "List all channels in the workspace",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"types": map[string]interface{}{
-							"type":        "string",
-							"description": "Comma-separated list of channel types (public_channel, private_channel, mpim, im)",
-							"default":     "public_channel,private_channel",
-						},
-						"limit": map[string]interface{}{
-							"type":        "integer",
-							"description": "Maximum number of channels to return",
-							"default":     100,
-						},
-					},
+				"text": {
+					"type": "string",
+					"description": "Message text to send"
 				},
+				"thread_ts": {
+					"type": "string",
+					"description": "Timestamp of thread to reply to (optional)"
+				}
 			},
-			{
-				Name:        "get_channel_history",
-				Description: "Get message history from a channel",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"channel": map[string]interface{}{
-							"type":        "string",
-							"description": "Channel ID to get history from",
-						},
-						"limit": map[string]interface{}{
-							"type":        "integer",
-							"description": "Number of messages to retrieve",
-							"default":     10,
-						},
-						"oldest": map[string]interface{}{
-							"type":        "string",
-							"description": "Oldest timestamp of messages to include",
-						},
-					},
-					"required": []string{"channel"},
+			"required": ["channel", "text"]
+		}`),
+	}, s.handleSendMessage)
+	s.RegisterTool(mcp.Tool{
+		Name:        "list_channels",
+		Description: "List all channels in the workspace",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"types": {
+					"type": "string",
+					"description": "Comma-separated list of channel types (public_channel, private_channel, mpim, im)",
+					"default": "public_channel,private_channel"
 				},
-			},
-			{
-				Name:        "create_channel",
-				Description: "Create a new channel",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"name": map[string]interface{}{
-							"type":        "string",
-							"description": "Name of the channel to create",
-						},
-						"is_private": map[string]interface{}{
-							"type":        "boolean",
-							"description": "Whether the channel should be private",
-							"default":     false,
-						},
-					},
-					"required": []string{"name"},
+				"limit": {
+					"type": "integer",
+					"description": "Maximum number of channels to return",
+					"default": 100
+				}
+			}
+		}`),
+	}, s.handleListChannels)
+	s.RegisterTool(mcp.Tool{
+		Name:        "get_channel_history",
+		Description: "Get message history from a channel",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"channel": {
+					"type": "string",
+					"description": "Channel ID to get history from"
 				},
-			},
-			{
-				Name:        "invite_to_channel",
-				Description: "Invite users to a channel",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"channel": map[string]interface{}{
-							"type":        "string",
-							"description": "Channel ID to invite users to",
-						},
-						"users": map[string]interface{}{
-							"type":        "string",
-							"description": "Comma-separated list of user IDs",
-						},
-					},
-					"required": []string{"channel", "users"},
+				"limit": {
+					"type": "integer",
+					"description": "Number of messages to retrieve",
+					"default": 10
 				},
+				"oldest": {
+					"type": "string",
+					"description": "Oldest timestamp of messages to include"
+				}
 			},
-			{
-				Name:        "get_user_info",
-				Description: "Get information about a user",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"user": map[string]interface{}{
-							"type":        "string",
-							"description": "User ID to get information about",
-						},
-					},
-					"required": []string{"user"},
+			"required": ["channel"]
+		}`),
+	}, s.handleGetChannelHistory)
+	s.RegisterTool(mcp.Tool{
+		Name:        "create_channel",
+		Description: "Create a new channel",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"name": {
+					"type": "string",
+					"description": "Name of the channel to create"
 				},
+				"is_private": {
+					"type": "boolean",
+					"description": "Whether the channel should be private",
+					"default": false
+				}
 			},
-		}
-
-		return &modelcontextprotocol.ListToolsResult{
-			Tools: tools,
-		}, nil
-	})
-
-	// Handle tool calls
-	s.OnCallTool(func(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
-		switch req.Params.Name {
-		case "send_message":
-			return s.handleSendMessage(ctx, req)
-		case "list_channels":
-			return s.handleListChannels(ctx, req)
-		case "get_channel_history":
-			return s.handleGetChannelHistory(ctx, req)
-		case "create_channel":
-			return s.handleCreateChannel(ctx, req)
-		case "invite_to_channel":
-			return s.handleInviteToChannel(ctx, req)
-		case "get_user_info":
-			return s.handleGetUserInfo(ctx, req)
-		default:
-			return &modelcontextprotocol.CallToolResult{
-				Content: []modelcontextprotocol.Content{
-					modelcontextprotocol.TextContent{
-						Type: "text",
-						Text: "Unknown tool: " + req.Params.Name,
-					},
+			"required": ["name"]
+		}`),
+	}, s.handleCreateChannel)
+	s.RegisterTool(mcp.Tool{
+		Name:        "invite_to_channel",
+		Description: "Invite users to a channel",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"channel": {
+					"type": "string",
+					"description": "Channel ID to invite users to"
 				},
-				IsError: &[]bool{true}[0],
-			}, nil
-		}
-	})
+				"users": {
+					"type": "string",
+					"description": "Comma-separated list of user IDs"
+				}
+			},
+			"required": ["channel", "users"]
+		}`),
+	}, s.handleInviteToChannel)
+	s.RegisterTool(mcp.Tool{
+		Name:        "get_user_info",
+		Description: "Get information about a user",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"user": {
+					"type": "string",
+					"description": "User ID to get information about"
+				}
+			},
+			"required": ["user"]
+		}`),
+	}, s.handleGetUserInfo)
 }
 
-func (s *SlackServer) handleSendMessage(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *SlackServer) handleSendMessage(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Channel  string `json:"channel"`
 		Text     string `json:"text"`
 		ThreadTS string `json:"thread_ts,omitempty"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -225,9 +174,9 @@ func (s *SlackServer) handleSendMessage(ctx context.Context, req *modelcontextpr
 	}
 	result += "\nMessage ID: msg_" + time.Now().Format("20060102150405")
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -235,21 +184,21 @@ func (s *SlackServer) handleSendMessage(ctx context.Context, req *modelcontextpr
 	}, nil
 }
 
-func (s *SlackServer) handleListChannels(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *SlackServer) handleListChannels(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Types string `json:"types"`
 		Limit int    `json:"limit"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -261,15 +210,15 @@ func (s *SlackServer) handleListChannels(ctx context.Context, req *modelcontextp
 	}
 
 	// Mock response
-	result := "Channels (" + args.Types + ", limit " + json.Number(args.Limit).String() + "):\n"
+	result := "Channels (" + args.Types + ", limit " + strconv.Itoa(args.Limit) + "):\n"
 	result += "• #general (C123456789) - General discussion\n"
 	result += "• #random (C234567890) - Random topics\n"
 	result += "• #development (C345678901) - Development discussions\n"
 	result += "• #marketing (C456789012) - Marketing team"
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -277,22 +226,22 @@ func (s *SlackServer) handleListChannels(ctx context.Context, req *modelcontextp
 	}, nil
 }
 
-func (s *SlackServer) handleGetChannelHistory(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *SlackServer) handleGetChannelHistory(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Channel string `json:"channel"`
 		Limit   int    `json:"limit"`
 		Oldest  string `json:"oldest,omitempty"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -301,15 +250,15 @@ func (s *SlackServer) handleGetChannelHistory(ctx context.Context, req *modelcon
 	}
 
 	// Mock response
-	result := "Recent messages in " + args.Channel + " (limit " + json.Number(args.Limit).String() + "):\n\n"
+	result := "Recent messages in " + args.Channel + " (limit " + strconv.Itoa(args.Limit) + "):\n\n"
 	result += "[14:30] @alice: Hey everyone, how's the project going?\n"
 	result += "[14:32] @bob: Making good progress on the API\n"
 	result += "[14:35] @charlie: UI is almost done, just need to polish\n"
 	result += "[14:37] @alice: Great! Let's sync up tomorrow morning"
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -317,21 +266,21 @@ func (s *SlackServer) handleGetChannelHistory(ctx context.Context, req *modelcon
 	}, nil
 }
 
-func (s *SlackServer) handleCreateChannel(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *SlackServer) handleCreateChannel(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Name      string `json:"name"`
 		IsPrivate bool   `json:"is_private"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -345,9 +294,9 @@ func (s *SlackServer) handleCreateChannel(ctx context.Context, req *modelcontext
 	result += "Channel ID: C" + time.Now().Format("20060102150405") + "\n"
 	result += "Members: 1 (you)"
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -355,21 +304,21 @@ func (s *SlackServer) handleCreateChannel(ctx context.Context, req *modelcontext
 	}, nil
 }
 
-func (s *SlackServer) handleInviteToChannel(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *SlackServer) handleInviteToChannel(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		Channel string `json:"channel"`
 		Users   string `json:"users"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -377,9 +326,9 @@ func (s *SlackServer) handleInviteToChannel(ctx context.Context, req *modelconte
 	result := "Invited users to " + args.Channel + ": " + args.Users + "\n"
 	result += "Invitation sent successfully"
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -387,20 +336,20 @@ func (s *SlackServer) handleInviteToChannel(ctx context.Context, req *modelconte
 	}, nil
 }
 
-func (s *SlackServer) handleGetUserInfo(ctx context.Context, req *modelcontextprotocol.CallToolRequest) (*modelcontextprotocol.CallToolResult, error) {
+func (s *SlackServer) handleGetUserInfo(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var args struct {
 		User string `json:"user"`
 	}
 
-	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				modelcontextprotocol.TextContent{
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: "Error parsing arguments: " + err.Error(),
 				},
 			},
-			IsError: &[]bool{true}[0],
+			IsError: true,
 		}, nil
 	}
 
@@ -413,9 +362,9 @@ func (s *SlackServer) handleGetUserInfo(ctx context.Context, req *modelcontextpr
 	result += "Timezone: America/New_York\n"
 	result += "Role: Developer"
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			modelcontextprotocol.TextContent{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: result,
 			},
@@ -428,7 +377,7 @@ func main() {
 
 	log.Printf("Starting %s v%s", SERVER_NAME, SERVER_VERSION)
 
-	if err := server.Run(context.Background()); err != nil {
+	if err := server.Serve(context.Background(), mcp.StdioTransport()); err != nil {
 		log.Fatal("Server failed:", err)
 	}
 }
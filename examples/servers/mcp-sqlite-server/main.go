@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -9,7 +10,6 @@ import (
 	"path/filepath"
 
 	"github.com/tmc/mcp"
-	"github.com/tmc/mcp/modelcontextprotocol"
 	_ "modernc.org/sqlite"
 )
 
@@ -44,17 +44,19 @@ func (s *SQLiteServer) Close() error {
 	return nil
 }
 
-func (s *SQLiteServer) handleExecuteQuery(args map[string]interface{}) (*modelcontextprotocol.CallToolResult, error) {
-	query, ok := args["query"].(string)
-	if !ok {
+func (s *SQLiteServer) handleExecuteQuery(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil || args.Query == "" {
 		return nil, fmt.Errorf("query parameter is required and must be a string")
 	}
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.QueryContext(ctx, args.Query)
 	if err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: fmt.Sprintf("Error executing query: %v", err),
 				},
@@ -93,9 +95,9 @@ func (s *SQLiteServer) handleExecuteQuery(args map[string]interface{}) (*modelco
 		return nil, fmt.Errorf("failed to marshal results: %w", err)
 	}
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: string(resultJSON),
 			},
@@ -103,17 +105,19 @@ func (s *SQLiteServer) handleExecuteQuery(args map[string]interface{}) (*modelco
 	}, nil
 }
 
-func (s *SQLiteServer) handleExecuteStatement(args map[string]interface{}) (*modelcontextprotocol.CallToolResult, error) {
-	statement, ok := args["statement"].(string)
-	if !ok {
+func (s *SQLiteServer) handleExecuteStatement(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args struct {
+		Statement string `json:"statement"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil || args.Statement == "" {
 		return nil, fmt.Errorf("statement parameter is required and must be a string")
 	}
 
-	result, err := s.db.Exec(statement)
+	result, err := s.db.ExecContext(ctx, args.Statement)
 	if err != nil {
-		return &modelcontextprotocol.CallToolResult{
-			Content: []modelcontextprotocol.Content{
-				{
+		return &mcp.CallToolResult{
+			Content: []any{
+				mcp.TextContent{
 					Type: "text",
 					Text: fmt.Sprintf("Error executing statement: %v", err),
 				},
@@ -127,9 +131,9 @@ func (s *SQLiteServer) handleExecuteStatement(args map[string]interface{}) (*mod
 
 	resultText := fmt.Sprintf("Statement executed successfully.\nRows affected: %d\nLast insert ID: %d", rowsAffected, lastInsertID)
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: resultText,
 			},
@@ -137,10 +141,10 @@ func (s *SQLiteServer) handleExecuteStatement(args map[string]interface{}) (*mod
 	}, nil
 }
 
-func (s *SQLiteServer) handleGetSchema(args map[string]interface{}) (*modelcontextprotocol.CallToolResult, error) {
+func (s *SQLiteServer) handleGetSchema(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	query := `SELECT name, type, sql FROM sqlite_master WHERE type IN ('table', 'view', 'index') ORDER BY type, name`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get schema: %w", err)
 	}
@@ -164,9 +168,9 @@ func (s *SQLiteServer) handleGetSchema(args map[string]interface{}) (*modelconte
 		return nil, fmt.Errorf("failed to marshal schema: %w", err)
 	}
 
-	return &modelcontextprotocol.CallToolResult{
-		Content: []modelcontextprotocol.Content{
-			{
+	return &mcp.CallToolResult{
+		Content: []any{
+			mcp.TextContent{
 				Type: "text",
 				Text: string(schemaJSON),
 			},
@@ -189,51 +193,46 @@ func main() {
 	mcpServer := mcp.NewServer("mcp-sqlite-server", "1.0.0")
 
 	// Add tools
-	mcpServer.AddTool(modelcontextprotocol.Tool{
+	mcpServer.RegisterTool(mcp.Tool{
 		Name:        "execute_query",
 		Description: "Execute a SELECT query and return results as JSON",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"query": map[string]interface{}{
-					"type":        "string",
-					"description": "SQL SELECT query to execute",
-				},
+			"properties": {
+				"query": {
+					"type": "string",
+					"description": "SQL SELECT query to execute"
+				}
 			},
-			"required": []string{"query"},
-		},
-	})
+			"required": ["query"]
+		}`),
+	}, server.handleExecuteQuery)
 
-	mcpServer.AddTool(modelcontextprotocol.Tool{
+	mcpServer.RegisterTool(mcp.Tool{
 		Name:        "execute_statement",
 		Description: "Execute an INSERT, UPDATE, DELETE, or DDL statement",
-		InputSchema: map[string]interface{}{
+		InputSchema: json.RawMessage(`{
 			"type": "object",
-			"properties": map[string]interface{}{
-				"statement": map[string]interface{}{
-					"type":        "string",
-					"description": "SQL statement to execute",
-				},
+			"properties": {
+				"statement": {
+					"type": "string",
+					"description": "SQL statement to execute"
+				}
 			},
-			"required": []string{"statement"},
-		},
-	})
+			"required": ["statement"]
+		}`),
+	}, server.handleExecuteStatement)
 
-	mcpServer.AddTool(modelcontextprotocol.Tool{
+	mcpServer.RegisterTool(mcp.Tool{
 		Name:        "get_schema",
 		Description: "Get the database schema (tables, views, indexes)",
-		InputSchema: map[string]interface{}{
-			"type":       "object",
-			"properties": map[string]interface{}{},
-		},
-	})
-
-	// Add tool handlers
-	mcpServer.OnToolCall("execute_query", server.handleExecuteQuery)
-	mcpServer.OnToolCall("execute_statement", server.handleExecuteStatement)
-	mcpServer.OnToolCall("get_schema", server.handleGetSchema)
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {}
+		}`),
+	}, server.handleGetSchema)
 
-	if err := mcpServer.Serve(); err != nil {
+	if err := mcpServer.Serve(context.Background(), mcp.StdioTransport()); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
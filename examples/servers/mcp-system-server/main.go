@@ -77,12 +77,11 @@ func main() {
 	registerSystemTools(server)
 
 	log.Println("Starting protocol server via stdio...")
-	if err := server.Serve(ctx, nil); err != nil {
-		if err != context.Canceled {
-			log.Fatalf("Error serving: %v", err)
-		}
-		log.Println("Server terminated.")
+	// Serve until a signal, then let in-flight calls finish.
+	if err := server.ServeUntilDone(ctx, nil, 10*time.Second); err != nil {
+		log.Fatalf("Error serving: %v", err)
 	}
+	log.Println("Server terminated.")
 }
 
 func registerSystemTools(server *mcp.Server) {
@@ -417,8 +416,6 @@ func getSystemInfo(includeEnv bool) (SystemInfo, error) {
 }
 
 func listProcesses(limit int, filter string) ([]ProcessInfo, error) {
-	var processes []ProcessInfo
-
 	if runtime.GOOS == "windows" {
 		return getWindowsProcesses(limit, filter)
 	}
@@ -526,10 +523,6 @@ func getDiskUsage(path string) (DiskInfo, error) {
 		return DiskInfo{}, err
 	}
 
-	info := DiskInfo{
-		Path: absPath,
-	}
-
 	if runtime.GOOS == "windows" {
 		return getWindowsDiskUsage(absPath)
 	}
@@ -20,6 +20,8 @@ import (
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/tmc/mcp"
 )
 
 // JSONRPCMessage represents a JSON-RPC message (request or response)
@@ -44,9 +46,23 @@ type Logger interface {
 type ConsoleLogger struct {
 	verbose    bool
 	timestamps bool
+	redactor   *mcp.Redactor // nil logs messages unredacted
 	mu         sync.Mutex
 }
 
+// pretty renders msg indented, with secrets redacted.
+func (l *ConsoleLogger) pretty(msg JSONRPCMessage) string {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Sprint(msg)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, l.redactor.RedactMessage(data), "", "  "); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
+
 func (l *ConsoleLogger) LogRequest(msg JSONRPCMessage, raw []byte) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -57,8 +73,7 @@ func (l *ConsoleLogger) LogRequest(msg JSONRPCMessage, raw []byte) {
 
 	fmt.Printf("→ REQUEST: ")
 	if l.verbose {
-		pretty, _ := json.MarshalIndent(msg, "", "  ")
-		fmt.Println(string(pretty))
+		fmt.Println(l.pretty(msg))
 	} else {
 		fmt.Printf("method=%s id=%v\n", msg.Method, msg.ID)
 	}
@@ -74,8 +89,7 @@ func (l *ConsoleLogger) LogResponse(msg JSONRPCMessage, raw []byte) {
 
 	fmt.Printf("← RESPONSE: ")
 	if l.verbose {
-		pretty, _ := json.MarshalIndent(msg, "", "  ")
-		fmt.Println(string(pretty))
+		fmt.Println(l.pretty(msg))
 	} else {
 		if msg.Error != nil {
 			fmt.Printf("id=%v error=%v\n", msg.ID, msg.Error)
@@ -434,6 +448,7 @@ func main() {
 		spyVeryVerb = flag.Bool("spy-vv", false, "Pass -vv flag to mcpspy")
 		spyPretty   = flag.Bool("spy-pretty", false, "Pass -pretty flag to mcpspy")
 		spyFile     = flag.String("spy-f", "", "Pass -f flag to mcpspy for recording")
+		noRedact    = flag.Bool("no-redact", false, "Log secrets instead of redacting them")
 	)
 	flag.Parse()
 
@@ -442,6 +457,9 @@ func main() {
 		verbose:    *verbose,
 		timestamps: *timestamps,
 	}
+	if !*noRedact {
+		logger.redactor = mcp.DefaultRedactor()
+	}
 
 	// Build mcpspy flags
 	var spyFlags []string
@@ -457,6 +475,9 @@ func main() {
 	if *spyFile != "" {
 		spyFlags = append(spyFlags, "-f", *spyFile)
 	}
+	if *noRedact {
+		spyFlags = append(spyFlags, "-no-redact")
+	}
 
 	switch *transport {
 	case "stdio":
@@ -17,6 +17,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/tmc/mcp"
 )
 
 var (
@@ -31,8 +33,12 @@ var (
 	splitMode    = flag.String("split-mode", "shadow", "split mode: shadow, random, round-robin")
 	splitPercent = flag.Float64("split-percent", 100.0, "percentage of traffic to shadow (0-100)")
 	compareMode  = flag.Bool("compare", false, "output both primary and shadow responses in enhanced mcptrace format")
+	noRedact     = flag.Bool("no-redact", false, "record secrets in the trace instead of redacting them")
 )
 
+// redactor removes secrets from recorded messages.
+var redactor = mcp.DefaultRedactor()
+
 type message struct {
 	raw          []byte
 	timestamp    time.Time
@@ -553,9 +559,13 @@ func (s *shadowServer) recordMessages() {
 }
 
 func formatMCPLine(msg message) string {
+	raw := msg.raw
+	if !*noRedact {
+		raw = redactor.RedactMessage(raw)
+	}
 	line := fmt.Sprintf("mcp-%s %s # %.3f",
 		msg.direction,
-		string(msg.raw),
+		string(raw),
 		float64(msg.timestamp.UnixNano())/1e9)
 
 	if msg.spanID != "" {
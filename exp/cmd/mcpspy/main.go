@@ -16,6 +16,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/tmc/mcp"
 	"github.com/tmc/mcp/internal/mcpspy"
 	"github.com/tmc/mcp/internal/mcpspy/web"
 	"golang.org/x/term"
@@ -42,6 +43,7 @@ var (
 	nameFlag        = flag.String("name", "", "optional human-readable instance name")
 	sessionFlag     = flag.String("session", "", "session identifier shared across related mcpspy processes")
 	specFile        = flag.String("spec-file", "", "output .mcpspec file (defaults to ~/.mcpspy/specs/<tool>.mcpspec)")
+	noRedact        = flag.Bool("no-redact", false, "record secrets instead of redacting them")
 )
 
 func main() {
@@ -109,12 +111,17 @@ func main() {
 		fmt.Fprintf(os.Stderr, "mcpspy: %s %s\n", mode, *outFile)
 	}
 
+	var redact func([]byte) []byte
+	if !*noRedact {
+		redact = mcp.DefaultRedactor().RedactMessage
+	}
 	recorder := mcpspy.New(writer, mcpspy.Options{
 		PrettyJSON:  *prettyJSON,
 		PassThrough: *passThrough,
 		BufferSize:  4096,
 		Name:        *nameFlag,
 		SessionID:   *sessionFlag,
+		Redact:      redact,
 	})
 	stopVerbose := startVerboseOutput(recorder)
 	defer stopVerbose()
@@ -1,8 +1,11 @@
 package mcp
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"io"
+	"math"
 
 	"golang.org/x/exp/jsonrpc2"
 	errors "golang.org/x/xerrors"
@@ -12,28 +15,119 @@ func defaultFramer() jsonrpc2.Framer { return lineFramer{} }
 
 type lineFramer struct{}
 
+type lineReader struct {
+	in *json.Decoder
+	// next delivers the value being decoded when a Read returned before
+	// its Decode finished.
+	next chan decodedLine
+}
+
+type decodedLine struct {
+	raw json.RawMessage
+	err error
+}
+
 type lineWriter struct {
 	out io.Writer
 }
 
 func (lineFramer) Reader(rw io.Reader) jsonrpc2.Reader {
-	return jsonrpc2.RawFramer().Reader(rw)
+	return &lineReader{in: json.NewDecoder(rw)}
 }
 
 func (lineFramer) Writer(rw io.Writer) jsonrpc2.Writer {
 	return &lineWriter{out: rw}
 }
 
+// Read returns the next valid JSON-RPC message. Connections answer invalid
+// requests before messages reach the framer (see checkSingle); any that
+// still arrive are dropped rather than returned as an error, since
+// jsonrpc2 closes the connection on any read error and the stream is still
+// in sync. Only malformed JSON ends the connection. Read returns when ctx
+// is done even while waiting for input; the pending value is returned by
+// the next Read.
+func (r *lineReader) Read(ctx context.Context) (jsonrpc2.Message, int64, error) {
+	var n int64
+	for {
+		if r.next == nil {
+			r.next = make(chan decodedLine, 1)
+			go func(next chan<- decodedLine) {
+				var raw json.RawMessage
+				err := r.in.Decode(&raw)
+				next <- decodedLine{raw, err}
+			}(r.next)
+		}
+		var line decodedLine
+		select {
+		case <-ctx.Done():
+			return nil, n, ctx.Err()
+		case line = <-r.next:
+			r.next = nil
+		}
+		if line.err != nil {
+			return nil, n, line.err
+		}
+		n += int64(len(line.raw))
+		if !integralID(line.raw) {
+			continue
+		}
+		if msg, err := jsonrpc2.DecodeMessage(line.raw); err == nil {
+			return msg, n, nil
+		}
+	}
+}
+
+// integralID reports whether raw has no ID or one jsonrpc2 can represent
+// without changing it. jsonrpc2 decodes numeric IDs through float64 and
+// truncates them, so a fractional ID or one beyond 2^53 would come back in
+// the response as a different ID.
+func integralID(raw json.RawMessage) bool {
+	var msg struct {
+		ID any `json:"id"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if dec.Decode(&msg) != nil {
+		return true // left for DecodeMessage to reject
+	}
+	num, ok := msg.ID.(json.Number)
+	if !ok {
+		return true
+	}
+	if n, err := num.Int64(); err == nil {
+		return n >= -1<<53 && n <= 1<<53
+	}
+	f, err := num.Float64()
+	return err == nil && f == math.Trunc(f) && math.Abs(f) <= 1<<53
+}
+
 func (w *lineWriter) Write(ctx context.Context, msg jsonrpc2.Message) (int64, error) {
 	select {
 	case <-ctx.Done():
 		return 0, ctx.Err()
 	default:
 	}
-	data, err := jsonrpc2.EncodeMessage(msg)
+	data, err := encodeMessage(msg)
 	if err != nil {
 		return 0, errors.Errorf("marshaling message: %v", err)
 	}
 	n, err := w.out.Write(append(data, '\n'))
 	return int64(n), err
 }
+
+// encodeMessage encodes msg for the wire. jsonrpc2 keeps only the message of
+// errors it did not create itself, so responses failing with a *ResponseError
+// or one of the package's structured errors are encoded here to preserve the
+// error code and data.
+func encodeMessage(msg jsonrpc2.Message) ([]byte, error) {
+	if resp, ok := msg.(*jsonrpc2.Response); ok && resp.Error != nil {
+		if re := wireError(resp.Error); re != nil {
+			return json.Marshal(struct {
+				JSONRPC string         `json:"jsonrpc"`
+				ID      interface{}    `json:"id"`
+				Error   *ResponseError `json:"error"`
+			}{"2.0", resp.ID.Raw(), re})
+		}
+	}
+	return jsonrpc2.EncodeMessage(msg)
+}
@@ -3,7 +3,10 @@ package mcp
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
 	"testing"
+	"time"
 
 	"golang.org/x/exp/jsonrpc2"
 )
@@ -52,3 +55,86 @@ func TestLineFramerReadRawValue(t *testing.T) {
 		t.Fatalf("msg=%T", msg)
 	}
 }
+
+func TestLineFramerReadSkipsInvalidMessages(t *testing.T) {
+	data := []byte(`{"jsonrpc":"1.0","id":1,"method":"ping"}
+{"jsonrpc":"2.0","id":{"n":1},"method":"ping"}
+{"jsonrpc":"2.0","id":1.5,"method":"ping"}
+{"jsonrpc":"2.0","id":9007199254740993,"method":"ping"}
+{"jsonrpc":"2.0","result":{}}
+{"jsonrpc":"2.0","id":2.0,"method":"ping"}
+`)
+	r := defaultFramer().Reader(bytes.NewReader(data))
+	msg, _, err := r.Read(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, ok := msg.(*jsonrpc2.Request)
+	if !ok || req.ID != jsonrpc2.Int64ID(2) {
+		t.Fatalf("msg = %#v, want the request with id 2", msg)
+	}
+	if _, _, err := r.Read(context.Background()); err == nil {
+		t.Fatal("Read at end of stream succeeded")
+	}
+}
+
+func TestLineFramerReadCanceled(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	r := defaultFramer().Reader(pr)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := r.Read(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Read on a blocked stream = %v, want the context error", err)
+	}
+
+	// The value being decoded is returned by the next Read.
+	go io.WriteString(pw, `{"jsonrpc":"2.0","id":3,"method":"ping"}`+"\n")
+	msg, _, err := r.Read(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req, ok := msg.(*jsonrpc2.Request); !ok || req.ID != jsonrpc2.Int64ID(3) {
+		t.Fatalf("msg = %#v, want the request with id 3", msg)
+	}
+}
+
+func TestEncodeMessageErrorCodes(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{NewNotFoundError("method", "nope"), `"code":-32601`},
+		{NewNotFoundError("tool", "nope"), `"code":-32602`},
+		{NewNotFoundError("resource", "x://y"), `"code":-32002`},
+		{NewParameterError("tools/call", "name", "missing", nil), `"code":-32602`},
+		{&ResponseError{Code: -32001, Message: "slow down", Data: map[string]int{"retryAfterMs": 5}}, `"error":{"code":-32001,"message":"slow down","data":{"retryAfterMs":5}}`},
+		{jsonrpc2.ErrMethodNotFound, `"code":-32601`},
+	}
+	for _, tt := range tests {
+		resp, err := jsonrpc2.NewResponse(jsonrpc2.Int64ID(1), nil, tt.err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := encodeMessage(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(data, []byte(tt.want)) {
+			t.Errorf("encodeMessage(%v) = %s, want %s", tt.err, data, tt.want)
+		}
+	}
+
+	// Errors answering requests whose ID could not be read carry a null ID.
+	resp, err := jsonrpc2.NewResponse(jsonrpc2.ID{}, nil, NewParameterError("tools/call", "name", "missing", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encodeMessage(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(`{"jsonrpc":"2.0","id":null,"error":`)) {
+		t.Errorf("encodeMessage without an ID = %s, want a null ID", data)
+	}
+}
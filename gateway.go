@@ -0,0 +1,841 @@
+// Package mcp - Gateway
+// This file implements a server that aggregates many upstream MCP servers.
+// Each upstream is reached through its own Client; its tools, prompts and
+// resources are registered on the gateway's Server under a namespace
+// (github__create_issue, and docs__file:///README.md for resource URIs and
+// templates), and list_changed, progress, logging and resources/updated
+// notifications are relayed to the downstream client.
+// Sampling, elicitation and roots requests from upstreams are routed back to
+// the downstream client. Every upstream is health-checked and restarted with
+// backoff when its connection fails.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultGatewaySeparator joins an upstream name and the name of one of its
+// tools or prompts, or the URI of one of its resources.
+const DefaultGatewaySeparator = "__"
+
+// UpstreamConfig describes one server behind a Gateway.
+type UpstreamConfig struct {
+	Name      string    // Namespace for the upstream's tools, prompts and resources
+	Transport Transport // Dialed on every (re)connect
+}
+
+// GatewayConfig configures a Gateway.
+type GatewayConfig struct {
+	Name      string // Server name reported downstream (default "mcp-gateway")
+	Version   string // Server version reported downstream (default "0.1.0")
+	Upstreams []UpstreamConfig
+
+	Separator       string        // Namespace separator (default DefaultGatewaySeparator)
+	ConnectTimeout  time.Duration // Bound on initialize and the initial listing (default 30s)
+	HealthInterval  time.Duration // Interval between pings; negative disables them (default 30s)
+	HealthTimeout   time.Duration // Bound on each ping (default 10s)
+	RestartDelay    time.Duration // First delay before reconnecting (default 1s)
+	MaxRestartDelay time.Duration // Cap on the doubling restart delay (default 1m)
+	MaxRestarts     int           // Restarts before an upstream is given up; 0 means no limit
+
+	Logger        *slog.Logger   // Default: the server's logger
+	ServerOptions []ServerOption // Passed to NewServer
+}
+
+// UpstreamState is the lifecycle state of a gateway upstream.
+type UpstreamState string
+
+const (
+	UpstreamConnecting UpstreamState = "connecting"
+	UpstreamReady      UpstreamState = "ready"
+	UpstreamRestarting UpstreamState = "restarting"
+	UpstreamStopped    UpstreamState = "stopped"
+)
+
+// UpstreamStatus reports the health of one gateway upstream.
+type UpstreamStatus struct {
+	Name      string         `json:"name"`
+	State     UpstreamState  `json:"state"`
+	Server    Implementation `json:"server"`
+	Tools     int            `json:"tools"`
+	Prompts   int            `json:"prompts"`
+	Resources int            `json:"resources"`
+	Restarts  int            `json:"restarts"`
+	LastError string         `json:"lastError,omitempty"`
+	Since     time.Time      `json:"since"`
+}
+
+// Gateway presents many upstream MCP servers as a single Server.
+type Gateway struct {
+	config    GatewayConfig
+	server    *Server
+	logger    *slog.Logger
+	upstreams []*gatewayUpstream
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	start  sync.Once
+}
+
+// NewGateway returns a gateway over the configured upstreams. Call Start or
+// Serve to connect them.
+func NewGateway(config GatewayConfig) (*Gateway, error) {
+	if config.Name == "" {
+		config.Name = "mcp-gateway"
+	}
+	if config.Version == "" {
+		config.Version = "0.1.0"
+	}
+	if config.Separator == "" {
+		config.Separator = DefaultGatewaySeparator
+	}
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = 30 * time.Second
+	}
+	if config.HealthInterval == 0 {
+		config.HealthInterval = 30 * time.Second
+	}
+	if config.HealthTimeout <= 0 {
+		config.HealthTimeout = 10 * time.Second
+	}
+	if config.RestartDelay <= 0 {
+		config.RestartDelay = time.Second
+	}
+	if config.MaxRestartDelay < config.RestartDelay {
+		config.MaxRestartDelay = max(time.Minute, config.RestartDelay)
+	}
+
+	server := NewServer(config.Name, config.Version, config.ServerOptions...)
+	g := &Gateway{
+		config: config,
+		server: server,
+		logger: config.Logger,
+	}
+	if g.logger == nil {
+		g.logger = server.logger
+	}
+	g.ctx, g.cancel = context.WithCancel(context.Background())
+
+	seen := make(map[string]bool)
+	for _, uc := range config.Upstreams {
+		switch {
+		case uc.Name == "":
+			return nil, errors.New("mcp: gateway upstream has no name")
+		case strings.Contains(uc.Name, config.Separator):
+			return nil, fmt.Errorf("mcp: gateway upstream name %q contains the separator %q", uc.Name, config.Separator)
+		case seen[uc.Name]:
+			return nil, fmt.Errorf("mcp: duplicate gateway upstream %q", uc.Name)
+		case uc.Transport == nil:
+			return nil, fmt.Errorf("mcp: gateway upstream %q has no transport", uc.Name)
+		}
+		seen[uc.Name] = true
+		g.upstreams = append(g.upstreams, &gatewayUpstream{
+			g:         g,
+			config:    uc,
+			started:   make(chan struct{}),
+			status:    UpstreamStatus{Name: uc.Name, State: UpstreamConnecting, Since: time.Now()},
+			tools:     make(map[string]Tool),
+			prompts:   make(map[string]Prompt),
+			resources: make(map[string]Resource),
+			templates: make(map[string]ResourceTemplate),
+			originals: make(map[string]string),
+		})
+	}
+
+	server.mu.Lock()
+	server.completion = g.complete
+	server.readFallback = g.readUnlisted
+	server.subscribeHook = g.subscribe
+	server.mu.Unlock()
+	return g, nil
+}
+
+// Server returns the aggregated server. Serve it with any transport.
+func (g *Gateway) Server() *Server {
+	return g.server
+}
+
+// Start connects every upstream and waits until each has completed its first
+// connection attempt or ctx is done. Upstreams that fail keep retrying in the
+// background; their state is reported by Status.
+func (g *Gateway) Start(ctx context.Context) error {
+	g.start.Do(func() {
+		for _, u := range g.upstreams {
+			g.wg.Add(1)
+			go func() {
+				defer g.wg.Done()
+				u.run(g.ctx)
+			}()
+		}
+	})
+	for _, u := range g.upstreams {
+		select {
+		case <-u.started:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Serve starts the gateway, serves its Server over transport until ctx is
+// done or the downstream connection ends, and then closes the gateway.
+func (g *Gateway) Serve(ctx context.Context, transport Transport) error {
+	defer g.Close()
+	if err := g.Start(ctx); err != nil {
+		return err
+	}
+	return g.server.Serve(ctx, transport)
+}
+
+// Status reports the state of every upstream, in configuration order.
+func (g *Gateway) Status() []UpstreamStatus {
+	statuses := make([]UpstreamStatus, 0, len(g.upstreams))
+	for _, u := range g.upstreams {
+		u.mu.Lock()
+		st := u.status
+		st.Tools, st.Prompts, st.Resources = len(u.tools), len(u.prompts), len(u.resources)+len(u.templates)
+		u.mu.Unlock()
+		statuses = append(statuses, st)
+	}
+	return statuses
+}
+
+// Close disconnects every upstream and waits for their supervisors to exit.
+func (g *Gateway) Close() error {
+	g.cancel()
+	g.wg.Wait()
+	return nil
+}
+
+func (g *Gateway) qualify(upstream, name string) string {
+	return upstream + g.config.Separator + name
+}
+
+// resourceOwner returns the upstream whose namespace holds uri, a resource
+// URI or URI template, and the URI the upstream knows it by.
+func (g *Gateway) resourceOwner(uri string) (*gatewayUpstream, string) {
+	name, original, ok := strings.Cut(uri, g.config.Separator)
+	if !ok {
+		return nil, ""
+	}
+	for _, u := range g.upstreams {
+		if u.config.Name == name {
+			return u, original
+		}
+	}
+	return nil, ""
+}
+
+// readUnlisted reads resources an upstream serves without listing them or
+// a template they expand.
+func (g *Gateway) readUnlisted(ctx context.Context, req ReadResourceRequest) ([]ResourceContents, error) {
+	if u, _ := g.resourceOwner(req.URI); u != nil {
+		return u.readResource(ctx, req)
+	}
+	return nil, NewNotFoundError("resource", req.URI)
+}
+
+// subscribe forwards resource subscriptions to the owning upstream.
+func (g *Gateway) subscribe(ctx context.Context, uri string, subscribe bool) error {
+	u, original := g.resourceOwner(uri)
+	if u == nil {
+		return nil
+	}
+	c, caps, err := u.current()
+	if err != nil {
+		return err
+	}
+	if caps.Resources == nil || !caps.Resources.Subscribe {
+		return nil
+	}
+	if subscribe {
+		return c.SubscribeResource(ctx, SubscribeResourceRequest{URI: original})
+	}
+	return c.UnsubscribeResource(ctx, UnsubscribeResourceRequest{URI: original})
+}
+
+// complete routes completion/complete to the upstream that owns the
+// referenced prompt or resource.
+func (g *Gateway) complete(ctx context.Context, req CompleteRequest) (*CompleteResult, error) {
+	ref, err := req.Reference()
+	if err != nil {
+		return nil, NewParameterError(string(MethodCompletionComplete), "ref", "invalid reference", err)
+	}
+	var owner *gatewayUpstream
+	switch ref := ref.(type) {
+	case PromptReference:
+		for _, u := range g.upstreams {
+			if original, ok := u.original(ref.Name); ok {
+				owner = u
+				ref.Name = original
+				req.Ref = ref
+				break
+			}
+		}
+	case ResourceReference:
+		var original string
+		if owner, original = g.resourceOwner(ref.URI); owner != nil {
+			ref.URI = original
+			req.Ref = ref
+		}
+	}
+	if owner == nil {
+		data, _ := json.Marshal(req.Ref)
+		return nil, NewNotFoundError("completion reference", string(data))
+	}
+	c, _, err := owner.current()
+	if err != nil {
+		return nil, err
+	}
+	return c.Complete(ctx, req)
+}
+
+// gatewayUpstream is one supervised upstream connection.
+type gatewayUpstream struct {
+	g       *Gateway
+	config  UpstreamConfig
+	started chan struct{} // closed after the first connection attempt
+	once    sync.Once
+	syncMu  sync.Mutex // serializes listing and registration
+
+	mu        sync.Mutex
+	client    *Client
+	caps      ServerCapabilities
+	status    UpstreamStatus
+	tools     map[string]Tool             // registered, by qualified name
+	prompts   map[string]Prompt           // registered, by qualified name
+	resources map[string]Resource         // registered, by qualified URI
+	templates map[string]ResourceTemplate // registered, by qualified template
+	originals map[string]string           // upstream name by qualified name
+}
+
+// run connects the upstream and reconnects it with backoff until ctx is done
+// or MaxRestarts is exceeded.
+func (u *gatewayUpstream) run(ctx context.Context) {
+	cfg := u.g.config
+	delay := cfg.RestartDelay
+	for {
+		c, err := u.connect(ctx)
+		u.once.Do(func() { close(u.started) })
+		if err == nil {
+			delay = cfg.RestartDelay
+			err = u.monitor(ctx, c)
+			u.detach(c)
+		}
+		if ctx.Err() != nil {
+			u.setState(UpstreamStopped, nil)
+			return
+		}
+		u.mu.Lock()
+		restarts := u.status.Restarts
+		u.mu.Unlock()
+		if cfg.MaxRestarts > 0 && restarts >= cfg.MaxRestarts {
+			u.g.logger.Error("gateway upstream stopped", "upstream", u.config.Name, "restarts", restarts, "error", err)
+			u.setState(UpstreamStopped, err)
+			return
+		}
+		u.g.logger.Warn("gateway upstream failed", "upstream", u.config.Name, "retry_in", delay, "error", err)
+		u.setState(UpstreamRestarting, err)
+		select {
+		case <-ctx.Done():
+			u.setState(UpstreamStopped, nil)
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > cfg.MaxRestartDelay {
+			delay = cfg.MaxRestartDelay
+		}
+		u.mu.Lock()
+		u.status.Restarts++
+		u.mu.Unlock()
+	}
+}
+
+func (u *gatewayUpstream) setState(state UpstreamState, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.status.State != state {
+		u.status.Since = time.Now()
+	}
+	u.status.State = state
+	if err != nil {
+		u.status.LastError = err.Error()
+	}
+}
+
+// connect dials and initializes the upstream and registers what it offers.
+func (u *gatewayUpstream) connect(ctx context.Context) (*Client, error) {
+	u.setState(UpstreamConnecting, nil)
+	s := u.g.server
+	c, err := NewClient(u.config.Transport, WithNotificationHandler(u.handleNotification))
+	if err != nil {
+		return nil, err
+	}
+	// Requests from the upstream are answered by the downstream client. The
+	// capabilities are always advertised; the downstream's own support is
+	// checked when a request arrives.
+	c.OnSampling(s.CreateMessage)
+	c.OnElicit(s.Elicit, ElicitModeForm, ElicitModeURL)
+	c.OnListRoots(s.ListRoots)
+
+	ctx, cancel := context.WithTimeout(ctx, u.g.config.ConnectTimeout)
+	defer cancel()
+	init, err := c.Initialize(ctx, InitializeRequest{
+		ProtocolVersion: LATEST_PROTOCOL_VERSION,
+		ClientInfo:      Implementation{Name: u.g.config.Name, Version: u.g.config.Version},
+	})
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	if err := c.Notify(ctx, string(MethodNotificationInitialized), struct{}{}); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("initialized: %w", err)
+	}
+
+	u.mu.Lock()
+	u.client = c
+	u.caps = init.Capabilities
+	u.status.Server = init.ServerInfo
+	u.mu.Unlock()
+	u.mergeCapabilities(init.Capabilities)
+
+	if err := u.syncAll(ctx); err != nil {
+		u.detach(c)
+		return nil, err
+	}
+	u.resubscribe(ctx, c)
+	u.setState(UpstreamReady, nil)
+	u.g.logger.Info("gateway upstream ready", "upstream", u.config.Name, "server", init.ServerInfo.Name)
+	return c, nil
+}
+
+// mergeCapabilities adds the upstream's completion and experimental
+// capabilities to the gateway's. Tools, prompts and resources are advertised
+// as they are registered.
+func (u *gatewayUpstream) mergeCapabilities(caps ServerCapabilities) {
+	s := u.g.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if caps.Completions != nil {
+		s.capabilities.Completions = &struct{}{}
+	}
+	for k, v := range caps.Experimental {
+		if s.capabilities.Experimental == nil {
+			s.capabilities.Experimental = make(map[string]any)
+		}
+		if _, ok := s.capabilities.Experimental[k]; !ok {
+			s.capabilities.Experimental[k] = v
+		}
+	}
+}
+
+// monitor returns when the connection ends, a health check fails, or ctx is
+// done.
+func (u *gatewayUpstream) monitor(ctx context.Context, c *Client) error {
+	closed := make(chan error, 1)
+	go func() { closed <- c.Wait() }()
+	var tick <-chan time.Time
+	if u.g.config.HealthInterval > 0 {
+		ticker := time.NewTicker(u.g.config.HealthInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-closed:
+			if err == nil {
+				err = ErrTransportClosed
+			}
+			return fmt.Errorf("connection closed: %w", err)
+		case <-tick:
+			pingCtx, cancel := context.WithTimeout(ctx, u.g.config.HealthTimeout)
+			err := c.Ping(pingCtx)
+			cancel()
+			if err != nil && ctx.Err() == nil {
+				return fmt.Errorf("health check: %w", err)
+			}
+		}
+	}
+}
+
+// detach unregisters everything the upstream offered and closes c.
+func (u *gatewayUpstream) detach(c *Client) {
+	u.syncMu.Lock()
+	defer u.syncMu.Unlock()
+	u.mu.Lock()
+	if u.client == c {
+		u.client = nil
+	}
+	tools, prompts, resources, templates := u.tools, u.prompts, u.resources, u.templates
+	u.tools = make(map[string]Tool)
+	u.prompts = make(map[string]Prompt)
+	u.resources = make(map[string]Resource)
+	u.templates = make(map[string]ResourceTemplate)
+	u.originals = make(map[string]string)
+	u.mu.Unlock()
+
+	// One list_changed notification per list, not per entry.
+	s := u.g.server
+	var toolsGone, promptsGone, resourcesGone bool
+	for name := range tools {
+		toolsGone = s.removeTool(name) || toolsGone
+	}
+	for name := range prompts {
+		promptsGone = s.removePrompt(name) || promptsGone
+	}
+	for uri := range resources {
+		resourcesGone = s.removeResource(uri) || resourcesGone
+	}
+	for tmpl := range templates {
+		resourcesGone = s.removeResourceTemplate(tmpl) || resourcesGone
+	}
+	s.notifyRemoved(toolsGone, MethodToolListChanged)
+	s.notifyRemoved(promptsGone, MethodPromptListChanged)
+	s.notifyRemoved(resourcesGone, MethodResourceListChanged)
+	c.Close()
+}
+
+// current returns the live client, or an error while the upstream is down.
+func (u *gatewayUpstream) current() (*Client, ServerCapabilities, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.client == nil {
+		return nil, ServerCapabilities{}, fmt.Errorf("mcp: gateway upstream %q is unavailable (%s)", u.config.Name, u.status.State)
+	}
+	return u.client, u.caps, nil
+}
+
+func (u *gatewayUpstream) original(qualified string) (string, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	name, ok := u.originals[qualified]
+	return name, ok
+}
+
+// readResource reads a resource of the upstream by its qualified URI and
+// qualifies the URIs of the contents.
+func (u *gatewayUpstream) readResource(ctx context.Context, req ReadResourceRequest) ([]ResourceContents, error) {
+	c, _, err := u.current()
+	if err != nil {
+		return nil, err
+	}
+	prefix := u.g.qualify(u.config.Name, "")
+	req.URI = strings.TrimPrefix(req.URI, prefix)
+	result, err := c.ReadResource(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	contents := make([]ResourceContents, len(result.Contents))
+	for i, rc := range result.Contents {
+		switch rc := rc.(type) {
+		case TextResourceContents:
+			rc.URI = prefix + rc.URI
+			contents[i] = rc
+		case BlobResourceContents:
+			rc.URI = prefix + rc.URI
+			contents[i] = rc
+		default:
+			contents[i] = rc
+		}
+	}
+	return contents, nil
+}
+
+// handleNotification relays upstream notifications downstream.
+func (u *gatewayUpstream) handleNotification(n JSONRPCNotification) {
+	g := u.g
+	switch Method(n.Method) {
+	case MethodToolListChanged, MethodPromptListChanged, MethodResourceListChanged:
+		go u.resync(Method(n.Method))
+	case MethodProgress:
+		if err := g.server.notify(g.ctx, MethodProgress, n.Params); err != nil {
+			g.logger.Debug("failed to relay progress", "upstream", u.config.Name, "error", err)
+		}
+	case MethodLogging:
+		var msg LoggingMessageNotification
+		if err := json.Unmarshal(n.Params, &msg); err != nil {
+			return
+		}
+		logger := u.config.Name
+		if msg.Logger != "" {
+			logger += "/" + msg.Logger
+		}
+		if err := g.server.NotifyLoggingMessage(g.ctx, msg.Level, logger, msg.Data); err != nil {
+			g.logger.Debug("failed to relay log message", "upstream", u.config.Name, "error", err)
+		}
+	case MethodResourceUpdated:
+		var params ResourceUpdatedNotificationParams
+		if err := json.Unmarshal(n.Params, &params); err != nil {
+			return
+		}
+		params.URI = g.qualify(u.config.Name, params.URI)
+		if err := g.server.ResourceUpdated(g.ctx, params); err != nil {
+			g.logger.Debug("failed to relay resource update", "upstream", u.config.Name, "error", err)
+		}
+	}
+}
+
+// resync refreshes one list after the upstream reported a change.
+func (u *gatewayUpstream) resync(method Method) {
+	c, _, err := u.current()
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(u.g.ctx, u.g.config.ConnectTimeout)
+	defer cancel()
+	u.syncMu.Lock()
+	defer u.syncMu.Unlock()
+	switch method {
+	case MethodToolListChanged:
+		err = u.syncTools(ctx, c)
+	case MethodPromptListChanged:
+		err = u.syncPrompts(ctx, c)
+	case MethodResourceListChanged:
+		err = u.syncResources(ctx, c)
+	}
+	if err != nil {
+		u.g.logger.Warn("gateway upstream resync failed", "upstream", u.config.Name, "method", string(method), "error", err)
+	}
+}
+
+func (u *gatewayUpstream) syncAll(ctx context.Context) error {
+	c, caps, err := u.current()
+	if err != nil {
+		return err
+	}
+	u.syncMu.Lock()
+	defer u.syncMu.Unlock()
+	if caps.Tools != nil {
+		if err := u.syncTools(ctx, c); err != nil {
+			return err
+		}
+	}
+	if caps.Prompts != nil {
+		if err := u.syncPrompts(ctx, c); err != nil {
+			return err
+		}
+	}
+	if caps.Resources != nil {
+		if err := u.syncResources(ctx, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *gatewayUpstream) syncTools(ctx context.Context, c *Client) error {
+	tools, err := listAll(func(cursor string) ([]Tool, string, error) {
+		res, err := c.ListTools(ctx, ListToolsRequest{Cursor: cursor})
+		if err != nil {
+			return nil, "", err
+		}
+		return res.Tools, res.NextCursor, nil
+	})
+	if err != nil {
+		return fmt.Errorf("tools/list: %w", err)
+	}
+	want := make(map[string]Tool, len(tools))
+	for _, t := range tools {
+		original := t.Name
+		t.Name = u.g.qualify(u.config.Name, original)
+		want[t.Name] = t
+		u.setOriginal(t.Name, original)
+	}
+	s := u.g.server
+	return syncRegistered(&u.mu, u.tools, want, s.RemoveTool, func(t Tool) error {
+		original, _ := u.original(t.Name)
+		return s.RegisterTool(t, func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+			c, _, err := u.current()
+			if err != nil {
+				return nil, err
+			}
+			req.Name = original
+			return c.CallTool(ctx, req)
+		})
+	})
+}
+
+func (u *gatewayUpstream) syncPrompts(ctx context.Context, c *Client) error {
+	prompts, err := listAll(func(cursor string) ([]Prompt, string, error) {
+		res, err := c.ListPrompts(ctx, ListPromptsRequest{Cursor: cursor})
+		if err != nil {
+			return nil, "", err
+		}
+		return res.Prompts, res.NextCursor, nil
+	})
+	if err != nil {
+		return fmt.Errorf("prompts/list: %w", err)
+	}
+	want := make(map[string]Prompt, len(prompts))
+	for _, p := range prompts {
+		original := p.Name
+		p.Name = u.g.qualify(u.config.Name, original)
+		want[p.Name] = p
+		u.setOriginal(p.Name, original)
+	}
+	s := u.g.server
+	return syncRegistered(&u.mu, u.prompts, want, s.RemovePrompt, func(p Prompt) error {
+		original, _ := u.original(p.Name)
+		return s.RegisterPrompt(p, func(ctx context.Context, req GetPromptRequest) (*GetPromptResult, error) {
+			c, _, err := u.current()
+			if err != nil {
+				return nil, err
+			}
+			req.Name = original
+			return c.GetPrompt(ctx, req)
+		})
+	})
+}
+
+// syncResources registers resources and templates under qualified URIs.
+func (u *gatewayUpstream) syncResources(ctx context.Context, c *Client) error {
+	g, s := u.g, u.g.server
+	resources, err := listAll(func(cursor string) ([]Resource, string, error) {
+		res, err := c.ListResources(ctx, ListResourcesRequest{Cursor: cursor})
+		if err != nil {
+			return nil, "", err
+		}
+		return res.Resources, res.NextCursor, nil
+	})
+	if err != nil {
+		return fmt.Errorf("resources/list: %w", err)
+	}
+	want := make(map[string]Resource, len(resources))
+	for _, r := range resources {
+		if r.Name == "" {
+			r.Name = r.URI
+		}
+		r.URI = g.qualify(u.config.Name, r.URI)
+		r.Name = g.qualify(u.config.Name, r.Name)
+		want[r.URI] = r
+	}
+	err = syncRegistered(&u.mu, u.resources, want, s.RemoveResource, func(r Resource) error {
+		return s.RegisterResource(r, u.readResource)
+	})
+	if err != nil {
+		return err
+	}
+
+	templates, err := listAll(func(cursor string) ([]ResourceTemplate, string, error) {
+		res, err := c.ListResourceTemplates(ctx, ListResourceTemplatesRequest{Cursor: cursor})
+		if err != nil {
+			return nil, "", err
+		}
+		return res.Templates, res.NextCursor, nil
+	})
+	if err != nil {
+		// Templates are optional; many servers do not implement the method.
+		g.logger.Debug("gateway upstream lists no resource templates", "upstream", u.config.Name, "error", err)
+		templates = nil
+	}
+	wantTmpls := make(map[string]ResourceTemplate, len(templates))
+	for _, t := range templates {
+		t.URITemplate = g.qualify(u.config.Name, t.uri())
+		t.Template = ""
+		wantTmpls[t.URITemplate] = t
+	}
+	return syncRegistered(&u.mu, u.templates, wantTmpls, s.RemoveResourceTemplate, func(t ResourceTemplate) error {
+		return s.RegisterResourceTemplate(t, u.readResource)
+	})
+}
+
+// resubscribe restores the downstream's subscriptions to resources of a
+// reconnected upstream.
+func (u *gatewayUpstream) resubscribe(ctx context.Context, c *Client) {
+	u.mu.Lock()
+	caps := u.caps
+	u.mu.Unlock()
+	if caps.Resources == nil || !caps.Resources.Subscribe {
+		return
+	}
+	s := u.g.server
+	s.mu.RLock()
+	var uris []string
+	for uri := range s.subscriptions {
+		uris = append(uris, uri)
+	}
+	s.mu.RUnlock()
+	for _, uri := range uris {
+		owner, original := u.g.resourceOwner(uri)
+		if owner != u {
+			continue
+		}
+		if err := c.SubscribeResource(ctx, SubscribeResourceRequest{URI: original}); err != nil {
+			u.g.logger.Warn("gateway resubscribe failed", "upstream", u.config.Name, "uri", uri, "error", err)
+		}
+	}
+}
+
+func (u *gatewayUpstream) setOriginal(qualified, name string) {
+	u.mu.Lock()
+	u.originals[qualified] = name
+	u.mu.Unlock()
+}
+
+// syncRegistered brings the registered set current, guarded by mu, in line
+// with want: it removes entries that are gone or changed and registers new
+// and changed ones.
+func syncRegistered[T any](mu *sync.Mutex, current, want map[string]T, remove func(string) bool, register func(T) error) error {
+	mu.Lock()
+	var stale, added []string
+	for key, old := range current {
+		if v, ok := want[key]; !ok || !reflect.DeepEqual(old, v) {
+			stale = append(stale, key)
+		}
+	}
+	for key, v := range want {
+		if old, ok := current[key]; !ok || !reflect.DeepEqual(old, v) {
+			added = append(added, key)
+		}
+	}
+	for _, key := range stale {
+		delete(current, key)
+	}
+	mu.Unlock()
+
+	for _, key := range stale {
+		remove(key)
+	}
+	var errs []error
+	for _, key := range added {
+		if err := register(want[key]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		mu.Lock()
+		current[key] = want[key]
+		mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// listAll follows cursors until a list is exhausted.
+func listAll[T any](list func(cursor string) ([]T, string, error)) ([]T, error) {
+	var all []T
+	cursor := ""
+	for {
+		page, next, err := list(cursor)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if next == "" || next == cursor {
+			return all, nil
+		}
+		cursor = next
+	}
+}
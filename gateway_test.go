@@ -0,0 +1,276 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// upstreamTransport serves server on a fresh pipe each time it is dialed and
+// remembers the server end of the latest one.
+type upstreamTransport struct {
+	server *Server
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (u *upstreamTransport) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	clientConn, serverConn := net.Pipe()
+	u.mu.Lock()
+	u.conn = serverConn
+	u.mu.Unlock()
+	go func() { _ = u.server.Serve(context.Background(), &ReadWriteCloserTransport{serverConn}) }()
+	return clientConn, nil
+}
+
+func (u *upstreamTransport) drop() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.conn.Close()
+}
+
+func newGatewayUpstreams(t *testing.T) (github, docs *Server) {
+	t.Helper()
+	github = NewServer("github", "1.0")
+	err := github.RegisterTool(Tool{Name: "create_issue", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+			if token := req.ProgressToken(); token != nil {
+				github.NotifyProgress(ctx, token, 1, nil)
+			}
+			github.NotifyLoggingMessage(ctx, LogLevelInfo, "issues", "creating")
+			text := "created"
+			if string(req.Arguments) == `{"ask":true}` {
+				res, err := github.CreateMessage(ctx, CreateMessageRequest{MaxTokens: 5})
+				if err != nil {
+					return nil, err
+				}
+				text = res.Model
+			}
+			return &CallToolResult{Content: []any{TextContent{Type: "text", Text: text}}}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs = NewServer("docs", "1.0")
+	read := func(ctx context.Context, req ReadResourceRequest) ([]ResourceContents, error) {
+		return []ResourceContents{TextResourceContents{URI: req.URI, Text: "text of " + req.URI}}, nil
+	}
+	if err := docs.RegisterResource(Resource{URI: "docs://readme", Name: "readme"}, read); err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.RegisterResourceTemplate(ResourceTemplate{URITemplate: "docs://pages/{name}", Name: "pages"}, read); err != nil {
+		t.Fatal(err)
+	}
+	err = docs.RegisterPrompt(Prompt{Name: "summary"}, func(ctx context.Context, req GetPromptRequest) (*GetPromptResult, error) {
+		return &GetPromptResult{Messages: []PromptMessage{{Role: "user", Content: TextContent{Type: "text", Text: "summarize"}}}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return github, docs
+}
+
+func TestGateway(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	github, docs := newGatewayUpstreams(t)
+	gw, err := NewGateway(GatewayConfig{
+		Upstreams: []UpstreamConfig{
+			{Name: "github", Transport: &upstreamTransport{server: github}},
+			{Name: "docs", Transport: &upstreamTransport{server: docs}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+	if err := gw.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	clientConn, serverConn := net.Pipe()
+	go func() { _ = gw.Server().Serve(ctx, &ReadWriteCloserTransport{serverConn}) }()
+	var mu sync.Mutex
+	var notifications []JSONRPCNotification
+	client, err := NewClient(&ReadWriteCloserTransport{clientConn}, WithNotificationHandler(func(n JSONRPCNotification) {
+		mu.Lock()
+		notifications = append(notifications, n)
+		mu.Unlock()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.OnSampling(func(ctx context.Context, req CreateMessageRequest) (*CreateMessageResult, error) {
+		return &CreateMessageResult{Model: "downstream-model", Role: "assistant", Content: TextContent{Type: "text", Text: "ok"}}, nil
+	})
+	init, err := client.Initialize(ctx, InitializeRequest{ClientInfo: Implementation{Name: "test", Version: "1.0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if init.Capabilities.Tools == nil || init.Capabilities.Prompts == nil || init.Capabilities.Resources == nil {
+		t.Errorf("merged capabilities = %+v", init.Capabilities)
+	}
+
+	tools, err := client.ListTools(ctx, ListToolsRequest{})
+	if err != nil || len(tools.Tools) != 1 || tools.Tools[0].Name != "github__create_issue" {
+		t.Fatalf("ListTools = %+v, %v", tools, err)
+	}
+	res, err := client.CallTool(ctx, CallToolRequest{
+		Name:      "github__create_issue",
+		Arguments: json.RawMessage(`{"ask":true}`),
+		Meta:      map[string]any{"progressToken": "p1"},
+	})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if text := res.Content[0].(TextContent).Text; text != "downstream-model" {
+		t.Errorf("sampling was not routed downstream: %v", text)
+	}
+	waitFor(t, "relayed notifications", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		var progress, logged bool
+		for _, n := range notifications {
+			switch Method(n.Method) {
+			case MethodProgress:
+				progress = string(n.Params) == `{"progressToken":"p1","progress":1}`
+			case MethodLogging:
+				var msg LoggingMessageNotification
+				json.Unmarshal(n.Params, &msg)
+				logged = msg.Logger == "github/issues"
+			}
+		}
+		return progress && logged
+	})
+
+	prompt, err := client.GetPrompt(ctx, GetPromptRequest{Name: "docs__summary"})
+	if err != nil || len(prompt.Messages) != 1 {
+		t.Fatalf("GetPrompt = %+v, %v", prompt, err)
+	}
+	// Resource URIs are namespaced like names; the upstream sees its own.
+	for _, uri := range []string{"docs://readme", "docs://pages/intro"} {
+		read, err := client.ReadResource(ctx, ReadResourceRequest{URI: "docs__" + uri})
+		if err != nil || len(read.Contents) != 1 {
+			t.Fatalf("ReadResource(docs__%s) = %+v, %v", uri, read, err)
+		}
+		if rc := read.Contents[0].(TextResourceContents); rc.Text != "text of "+uri || rc.URI != "docs__"+uri {
+			t.Errorf("ReadResource(docs__%s) = %+v", uri, rc)
+		}
+	}
+	if _, err := client.ReadResource(ctx, ReadResourceRequest{URI: "docs://readme"}); err == nil {
+		t.Error("ReadResource of an unqualified URI succeeded")
+	}
+	resources, err := client.ListResources(ctx, ListResourcesRequest{})
+	if err != nil || len(resources.Resources) != 1 || resources.Resources[0].Name != "docs__readme" || resources.Resources[0].URI != "docs__docs://readme" {
+		t.Errorf("ListResources = %+v, %v", resources, err)
+	}
+	templates, err := client.ListResourceTemplates(ctx, ListResourceTemplatesRequest{})
+	if err != nil || len(templates.Templates) != 1 || templates.Templates[0].uri() != "docs__docs://pages/{name}" {
+		t.Errorf("ListResourceTemplates = %+v, %v", templates, err)
+	}
+
+	// A tool added upstream appears downstream after list_changed.
+	if err := github.RegisterTool(Tool{Name: "close_issue", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) { return &CallToolResult{}, nil }); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "github__close_issue", func() bool {
+		tools, err := client.ListTools(ctx, ListToolsRequest{})
+		return err == nil && slices.ContainsFunc(tools.Tools, func(tool Tool) bool { return tool.Name == "github__close_issue" })
+	})
+
+	for _, st := range gw.Status() {
+		if st.State != UpstreamReady {
+			t.Errorf("%s: state %s", st.Name, st.State)
+		}
+	}
+}
+
+func TestGatewayRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	github, _ := newGatewayUpstreams(t)
+	upstream := &upstreamTransport{server: github}
+	gw, err := NewGateway(GatewayConfig{
+		Upstreams:    []UpstreamConfig{{Name: "github", Transport: upstream}},
+		RestartDelay: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+	if err := gw.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	hasTool := func() bool {
+		gw.server.mu.RLock()
+		defer gw.server.mu.RUnlock()
+		_, ok := gw.server.tools["github__create_issue"]
+		return ok
+	}
+	if !hasTool() {
+		t.Fatal("tool not registered")
+	}
+
+	upstream.drop()
+	waitFor(t, "restart", func() bool {
+		st := gw.Status()[0]
+		return st.State == UpstreamReady && st.Restarts == 1
+	})
+	if !hasTool() {
+		t.Error("tool not registered after restart")
+	}
+	if st := gw.Status()[0]; st.LastError == "" {
+		t.Error("LastError is empty after a restart")
+	}
+}
+
+func TestGatewayConfigErrors(t *testing.T) {
+	transport := &upstreamTransport{}
+	for _, upstreams := range [][]UpstreamConfig{
+		{{Name: "", Transport: transport}},
+		{{Name: "a__b", Transport: transport}},
+		{{Name: "a", Transport: transport}, {Name: "a", Transport: transport}},
+		{{Name: "a"}},
+	} {
+		if _, err := NewGateway(GatewayConfig{Upstreams: upstreams}); err == nil {
+			t.Errorf("NewGateway(%+v) succeeded", upstreams)
+		}
+	}
+}
+
+func TestURITemplatePattern(t *testing.T) {
+	tests := []struct {
+		template string
+		uri      string
+		want     bool
+	}{
+		{"docs://pages/{name}", "docs://pages/intro", true},
+		{"docs://pages/{name}", "docs://pages/a/b", false},
+		{"file:///{+path}", "file:///a/b/c.txt", true},
+		{"api://items{?page,limit}", "api://items?page=2", true},
+		{"api://items{?page,limit}", "api://items", true},
+		{"api://items{?page,limit}", "api://other", false},
+	}
+	for _, tt := range tests {
+		re, err := uriTemplatePattern(tt.template)
+		if err != nil {
+			t.Fatalf("uriTemplatePattern(%q): %v", tt.template, err)
+		}
+		if got := re.MatchString(tt.uri); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.template, tt.uri, got, tt.want)
+		}
+	}
+}
@@ -141,8 +141,7 @@ func TestBasicClientServerIntegration(t *testing.T) {
 	if len(toolResult.Content) == 0 {
 		t.Error("Expected content in tool result")
 	} else {
-		content := toolResult.Content[0].(map[string]interface{})
-		text := content["text"].(string)
+		text := toolResult.Content[0].(TextContent).Text
 		if text != "Echo: Hello, World!" {
 			t.Errorf("Expected 'Echo: Hello, World!', got '%s'", text)
 		}
@@ -470,7 +470,8 @@ func registerConformanceResources(server *mcp.Server) {
 	})
 
 	mustRegisterResourceTemplate(server, mcp.ResourceTemplate{
-		Template:    "test://template/{id}/data",
+		URITemplate: "test://template/{id}/data",
+		Name:        "template-data",
 		Description: "A resource template with parameter substitution.",
 	}, templateResourceHandler)
 
@@ -704,7 +705,7 @@ func mustRegisterResource(server *mcp.Server, resource mcp.Resource, handler mcp
 
 func mustRegisterResourceTemplate(server *mcp.Server, template mcp.ResourceTemplate, handler mcp.ResourceTemplateHandlerFunc) {
 	if err := server.RegisterResourceTemplate(template, handler); err != nil {
-		fmt.Fprintf(os.Stderr, "register resource template %s: %v\n", template.Template, err)
+		fmt.Fprintf(os.Stderr, "register resource template %s: %v\n", template.URITemplate, err)
 		os.Exit(1)
 	}
 }
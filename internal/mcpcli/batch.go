@@ -0,0 +1,170 @@
+package mcpcli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+// BatchOp is one line of batch input: a tool call, a resource read or a
+// prompt get. ID is copied to the result; it defaults to the line number.
+type BatchOp struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Tool   string          `json:"tool,omitempty"`
+	Read   string          `json:"read,omitempty"`
+	Prompt string          `json:"prompt,omitempty"`
+	Args   json.RawMessage `json:"args,omitempty"`
+}
+
+// BatchResult is one line of batch output. Exactly one of Result and Error
+// is set; a tool result with isError set is a Result but counts as failed.
+type BatchResult struct {
+	ID     json.RawMessage `json:"id"`
+	Result any             `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// BatchOptions configures RunBatch.
+type BatchOptions struct {
+	Parallel int           // operations in flight at once; at least 1
+	FailFast bool          // stop at the first failed operation
+	Timeout  time.Duration // per operation; 0 means none
+}
+
+// BatchStats counts the operations of a batch.
+type BatchStats struct {
+	OK     int
+	Failed int
+}
+
+// maxBatchLine bounds the size of one line of batch input.
+const maxBatchLine = 64 << 20
+
+// RunBatch reads NDJSON operations from in, runs them over the session and
+// writes one NDJSON result per operation to out, in completion order. A
+// failed operation only ends the batch when FailFast is set; the returned
+// error reports problems reading input or writing output.
+func (s *Session) RunBatch(ctx context.Context, in io.Reader, out io.Writer, opts BatchOptions) (BatchStats, error) {
+	if opts.Parallel < 1 {
+		opts.Parallel = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		stats    BatchStats
+		writeErr error
+		wg       sync.WaitGroup
+	)
+	enc := json.NewEncoder(out)
+	finish := func(result BatchResult, failed bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failed {
+			stats.Failed++
+			if opts.FailFast {
+				cancel()
+			}
+		} else {
+			stats.OK++
+		}
+		if err := enc.Encode(result); err != nil && writeErr == nil {
+			writeErr = err
+			cancel()
+		}
+	}
+
+	sem := make(chan struct{}, opts.Parallel)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, maxBatchLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var op BatchOp
+		err := json.Unmarshal(scanner.Bytes(), &op)
+		if len(op.ID) == 0 {
+			op.ID = json.RawMessage(strconv.Itoa(line))
+		}
+		if err != nil {
+			finish(BatchResult{ID: op.ID, Error: fmt.Sprintf("line %d: %v", line, err)}, true)
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result, failed := s.runBatchOp(ctx, op, opts.Timeout)
+			finish(result, failed)
+		}()
+	}
+	wg.Wait()
+	if err := scanner.Err(); err != nil {
+		return stats, fmt.Errorf("read batch: %w", err)
+	}
+	return stats, writeErr
+}
+
+func (s *Session) runBatchOp(ctx context.Context, op BatchOp, timeout time.Duration) (BatchResult, bool) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	result := BatchResult{ID: op.ID}
+	var err error
+	switch {
+	case countSet(op.Tool, op.Read, op.Prompt) != 1:
+		err = errors.New("operation needs exactly one of tool, read or prompt")
+	case op.Tool != "":
+		var r *mcp.CallToolResult
+		r, err = s.client.CallTool(ctx, mcp.CallToolRequest{Name: op.Tool, Arguments: op.Args})
+		if err == nil {
+			result.Result = r
+			return result, r.IsError
+		}
+	case op.Read != "":
+		result.Result, err = s.client.ReadResource(ctx, mcp.ReadResourceRequest{URI: op.Read})
+	case op.Prompt != "":
+		var args map[string]any
+		if len(op.Args) > 0 {
+			if err = json.Unmarshal(op.Args, &args); err != nil {
+				err = fmt.Errorf("prompt args: %w", err)
+				break
+			}
+		}
+		result.Result, err = s.client.GetPrompt(ctx, mcp.GetPromptRequest{Name: op.Prompt, Arguments: args})
+	}
+	if err != nil {
+		return BatchResult{ID: op.ID, Error: err.Error()}, true
+	}
+	return result, false
+}
+
+func countSet(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+package mcpcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunBatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(runAsServerEnv, "1")
+	sess, err := Connect(ctx, Config{Cmd: strconv.Quote(exe), StateDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+
+	input := `{"id": "a", "tool": "count"}
+{"read": "mem://note"}
+
+{"id": 7, "prompt": "greet", "args": {"name": "Ann"}}
+{"tool": "missing"}
+not json
+{"id": "both", "tool": "count", "read": "mem://note"}
+`
+	var out bytes.Buffer
+	stats, err := sess.RunBatch(ctx, strings.NewReader(input), &out, BatchOptions{Parallel: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.OK != 3 || stats.Failed != 3 {
+		t.Errorf("stats = %+v, want 3 ok and 3 failed", stats)
+	}
+	failed := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var r struct {
+			ID     json.RawMessage `json:"id"`
+			Result *struct {
+				IsError bool `json:"isError"`
+			} `json:"result"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("output line %q: %v", line, err)
+		}
+		failed[string(r.ID)] = r.Error != "" || r.Result.IsError
+	}
+	want := map[string]bool{`"a"`: false, `2`: false, `7`: false, `5`: true, `6`: true, `"both"`: true}
+	if len(failed) != len(want) {
+		t.Errorf("got %d results, want %d:\n%s", len(failed), len(want), out.String())
+	}
+	for id, wantFailed := range want {
+		if got, ok := failed[id]; !ok || got != wantFailed {
+			t.Errorf("id %s: failed = %v, present = %v; want failed = %v", id, got, ok, wantFailed)
+		}
+	}
+	if !strings.Contains(out.String(), "hello Ann") {
+		t.Errorf("prompt result missing from\n%s", out.String())
+	}
+
+	// With --fail-fast nothing runs after the first failure.
+	out.Reset()
+	stats, err = sess.RunBatch(ctx, strings.NewReader("{\"tool\": \"missing\"}\n{\"tool\": \"count\"}\n"), &out, BatchOptions{FailFast: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.OK != 0 || stats.Failed != 1 {
+		t.Errorf("fail-fast stats = %+v, want 1 failed", stats)
+	}
+}
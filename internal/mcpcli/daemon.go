@@ -0,0 +1,560 @@
+package mcpcli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmc/mcp"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// stopMethod asks a session daemon to shut down. It is only answered on the
+// session socket, never forwarded to the server.
+const stopMethod = "session/stop"
+
+// progressGrace is how long the progress token of a finished request stays
+// routed, for notifications that arrive just behind its response.
+const progressGrace = 5 * time.Second
+
+// SessionInfo describes a background session started by ServeSession.
+type SessionInfo struct {
+	Name    string    `json:"name"`
+	PID     int       `json:"pid"`
+	Socket  string    `json:"socket"`
+	Cmd     string    `json:"cmd,omitempty"`
+	HTTPURL string    `json:"http,omitempty"`
+	SSEURL  string    `json:"sse,omitempty"`
+	Server  string    `json:"server,omitempty"`
+	Started time.Time `json:"started"`
+}
+
+// Target describes the server the session is connected to.
+func (info SessionInfo) Target() string {
+	switch {
+	case info.Cmd != "":
+		return info.Cmd
+	case info.HTTPURL != "":
+		return info.HTTPURL
+	case info.Server != "":
+		return "server " + info.Server
+	default:
+		return info.SSEURL
+	}
+}
+
+// Running reports whether the session daemon accepts connections.
+func (info SessionInfo) Running(ctx context.Context) bool {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", info.Socket)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// SessionDir returns the directory holding the sockets, metadata and logs of
+// the sessions under stateDir.
+func SessionDir(stateDir string) string {
+	return filepath.Join(stateDir, "sessions")
+}
+
+// SessionLogPath returns the log file of the named session.
+func SessionLogPath(stateDir, name string) string {
+	return filepath.Join(SessionDir(stateDir), name+".log")
+}
+
+func sessionSocketPath(stateDir, name string) string {
+	return filepath.Join(SessionDir(stateDir), name+".sock")
+}
+
+func sessionInfoPath(stateDir, name string) string {
+	return filepath.Join(SessionDir(stateDir), name+".json")
+}
+
+// ValidSessionName reports an error if name cannot name a session.
+func ValidSessionName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("session name is required")
+	case strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, "."):
+		return fmt.Errorf("invalid session name %q", name)
+	}
+	return nil
+}
+
+// ReadSession returns the metadata of the named session.
+func ReadSession(stateDir, name string) (SessionInfo, error) {
+	var info SessionInfo
+	data, err := os.ReadFile(sessionInfoPath(stateDir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return info, fmt.Errorf("no session named %q", name)
+	}
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(data, &info)
+	return info, err
+}
+
+// ListSessions returns the metadata of every session under stateDir, sorted
+// by name. Sessions whose daemon has exited are included.
+func ListSessions(stateDir string) ([]SessionInfo, error) {
+	matches, err := filepath.Glob(filepath.Join(SessionDir(stateDir), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var sessions []SessionInfo
+	for _, path := range matches {
+		info, err := ReadSession(stateDir, strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, info)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Name < sessions[j].Name })
+	return sessions, nil
+}
+
+// RemoveSession deletes the files of a session whose daemon has exited.
+func RemoveSession(stateDir, name string) error {
+	var errs []error
+	for _, path := range []string{sessionInfoPath(stateDir, name), sessionSocketPath(stateDir, name)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StopSession asks the named session daemon to shut down and waits until it
+// no longer accepts connections.
+func StopSession(ctx context.Context, stateDir, name string) error {
+	info, err := ReadSession(stateDir, name)
+	if err != nil {
+		return err
+	}
+	if !info.Running(ctx) {
+		return RemoveSession(stateDir, name)
+	}
+	conn, err := jsonrpc2.Dial(ctx, jsonrpc2.NetDialer("unix", info.Socket, net.Dialer{}), jsonrpc2.ConnectionOptions{
+		Framer: jsonrpc2.RawFramer(),
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var result any
+	if err := conn.Call(ctx, stopMethod, nil).Await(ctx, &result); err != nil {
+		return fmt.Errorf("stop session %q: %w", name, err)
+	}
+	for info.Running(ctx) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// SessionTransport dials the socket of the named session. The daemon answers
+// initialize with the result it negotiated when it connected, so a client
+// sees the server exactly as the daemon does.
+func SessionTransport(stateDir, name string) mcp.Transport {
+	return mcp.TransportFunc(func(ctx context.Context) (io.ReadWriteCloser, error) {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "unix", sessionSocketPath(stateDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("session %q is not running: %w", name, err)
+		}
+		return conn, nil
+	})
+}
+
+// ServeSession connects to the server described by cfg and relays that one
+// connection to every client of the session's unix socket until ctx is done,
+// a client stops the session, or the server goes away.
+//
+// Requests are forwarded as they are; notifications from the server are
+// sent to every client. Resource subscriptions are reference counted so a
+// client unsubscribing does not cancel another's subscription.
+func ServeSession(ctx context.Context, cfg Config, name string, logger *slog.Logger) error {
+	if err := ValidSessionName(name); err != nil {
+		return err
+	}
+	if cfg.Session != "" {
+		return errors.New("a session cannot connect through another session")
+	}
+	cfg = withDefaults(cfg)
+	if err := os.MkdirAll(SessionDir(cfg.StateDir), 0o700); err != nil {
+		return err
+	}
+	info := SessionInfo{
+		Name:    name,
+		PID:     os.Getpid(),
+		Socket:  sessionSocketPath(cfg.StateDir, name),
+		Cmd:     cfg.Cmd,
+		HTTPURL: cfg.HTTPURL,
+		SSEURL:  cfg.SSEURL,
+		Server:  cfg.Server,
+		Started: time.Now(),
+	}
+	if info.Running(ctx) {
+		return fmt.Errorf("session %q is already running", name)
+	}
+	if err := os.Remove(info.Socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	sess, err := Connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+	init := sess.InitializeResult()
+	logger.Info("connected", "server", init.ServerInfo.Name, "version", init.ServerInfo.Version, "protocol", init.ProtocolVersion)
+
+	stopped := make(chan struct{})
+	var stopOnce sync.Once
+	listener, err := jsonrpc2.NetListener(ctx, "unix", info.Socket, jsonrpc2.NetListenOptions{})
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	if err := os.WriteFile(sessionInfoPath(cfg.StateDir, name), data, 0o600); err != nil {
+		listener.Close()
+		return err
+	}
+	defer os.Remove(sessionInfoPath(cfg.StateDir, name))
+
+	r := &relay{
+		sess:     sess,
+		logger:   logger,
+		stop:     func() { stopOnce.Do(func() { close(stopped) }) },
+		conns:    make(map[*jsonrpc2.Connection]map[string]bool),
+		subs:     make(map[string]int),
+		progress: make(map[string]progressRoute),
+	}
+	events, unsubscribe := sess.Subscribe(256)
+	defer unsubscribe()
+	go r.broadcast(ctx, events)
+
+	server, err := jsonrpc2.Serve(ctx, listener, r)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	upstream := make(chan error, 1)
+	go func() { upstream <- sess.Client().Wait() }()
+
+	var result error
+	select {
+	case <-ctx.Done():
+		logger.Info("stopping")
+	case <-stopped:
+		logger.Info("stopped by client")
+	case err := <-upstream:
+		logger.Warn("server connection closed", "error", err)
+		result = fmt.Errorf("server connection closed: %w", err)
+	}
+	listener.Close()
+	r.closeAll()
+	server.Wait()
+	return result
+}
+
+// relay forwards the requests of session socket clients to the server.
+type relay struct {
+	sess   *Session
+	logger *slog.Logger
+	stop   func()
+
+	mu        sync.Mutex
+	conns     map[*jsonrpc2.Connection]map[string]bool // client -> subscribed URIs
+	subs      map[string]int                           // URI -> subscribing clients
+	progress  map[string]progressRoute                 // daemon progress token -> requester
+	lastToken int64
+}
+
+// progressRoute is the client a daemon progress token reports to, and the
+// token that client chose.
+type progressRoute struct {
+	conn  *jsonrpc2.Connection
+	token json.RawMessage
+}
+
+// Bind implements jsonrpc2.Binder.
+func (r *relay) Bind(ctx context.Context, conn *jsonrpc2.Connection) (jsonrpc2.ConnectionOptions, error) {
+	r.mu.Lock()
+	r.conns[conn] = make(map[string]bool)
+	r.mu.Unlock()
+	go func() {
+		conn.Wait()
+		conn.Close()
+		r.drop(conn)
+	}()
+	return jsonrpc2.ConnectionOptions{
+		Framer: jsonrpc2.RawFramer(),
+		Handler: jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (interface{}, error) {
+			return r.handle(ctx, conn, req)
+		}),
+	}, nil
+}
+
+func (r *relay) handle(ctx context.Context, conn *jsonrpc2.Connection, req *jsonrpc2.Request) (interface{}, error) {
+	var params any
+	if len(req.Params) > 0 {
+		params = req.Params
+	}
+	if !req.IsCall() {
+		switch mcp.Method(req.Method) {
+		case mcp.MethodNotificationInitialized:
+			// The daemon completed the handshake when it connected.
+		case mcp.MethodNotificationCancelled:
+			var cancelled struct {
+				RequestID any `json:"requestId"`
+			}
+			if json.Unmarshal(req.Params, &cancelled) == nil {
+				switch id := cancelled.RequestID.(type) {
+				case float64:
+					conn.Cancel(jsonrpc2.Int64ID(int64(id)))
+				case string:
+					conn.Cancel(jsonrpc2.StringID(id))
+				}
+			}
+		default:
+			if err := r.sess.Client().Notify(ctx, req.Method, params); err != nil {
+				r.logger.Warn("forward notification", "method", req.Method, "error", err)
+			}
+		}
+		return nil, nil
+	}
+
+	switch mcp.Method(req.Method) {
+	case mcp.MethodInitialize:
+		return r.sess.InitializeResult(), nil
+	case mcp.MethodPing:
+		return struct{}{}, nil
+	}
+	if req.Method == stopMethod {
+		// Reply before stopping: stopping closes this connection.
+		go func() {
+			_ = conn.Respond(req.ID, struct{}{}, nil)
+			r.stop()
+		}()
+		return nil, jsonrpc2.ErrAsyncResponse
+	}
+	// Answer asynchronously so a slow call does not hold up the client's
+	// other requests.
+	go func() {
+		var result any
+		var err error
+		switch mcp.Method(req.Method) {
+		case mcp.MethodResourcesSubscribe, mcp.MethodResourcesUnsubscribe:
+			result, err = r.subscribe(ctx, conn, mcp.Method(req.Method), req.Params)
+		default:
+			callParams, done := r.routeProgress(conn, req.Params)
+			var raw json.RawMessage
+			err = r.sess.CallRaw(ctx, req.Method, callParams, &raw)
+			done()
+			result = raw
+		}
+		if err := conn.Respond(req.ID, result, err); err != nil {
+			r.logger.Warn("respond", "method", req.Method, "error", err)
+		}
+	}()
+	return nil, jsonrpc2.ErrAsyncResponse
+}
+
+// subscribe forwards the first subscription and the last unsubscription of
+// a URI to the server and only records the others. The counts are updated
+// first and the server called without r.mu held, so a slow server does not
+// hold up other clients; a subscription the server refuses is taken back.
+func (r *relay) subscribe(ctx context.Context, conn *jsonrpc2.Connection, method mcp.Method, params json.RawMessage) (any, error) {
+	var req mcp.SubscribeResourceRequest
+	if err := json.Unmarshal(params, &req); err != nil || req.URI == "" {
+		return nil, jsonrpc2.NewError(-32602, "invalid params: uri is required")
+	}
+	client := r.sess.Client()
+	r.mu.Lock()
+	uris := r.conns[conn]
+	if uris == nil {
+		r.mu.Unlock()
+		return nil, errors.New("client disconnected")
+	}
+	if method == mcp.MethodResourcesSubscribe {
+		if uris[req.URI] {
+			r.mu.Unlock()
+			return struct{}{}, nil
+		}
+		uris[req.URI] = true
+		r.subs[req.URI]++
+		first := r.subs[req.URI] == 1
+		r.mu.Unlock()
+		if !first {
+			return struct{}{}, nil
+		}
+		if err := client.SubscribeResource(ctx, req); err != nil {
+			r.mu.Lock()
+			if uris[req.URI] {
+				delete(uris, req.URI)
+				if r.subs[req.URI]--; r.subs[req.URI] == 0 {
+					delete(r.subs, req.URI)
+				}
+			}
+			r.mu.Unlock()
+			return nil, err
+		}
+		return struct{}{}, nil
+	}
+	if !uris[req.URI] {
+		r.mu.Unlock()
+		return struct{}{}, nil
+	}
+	delete(uris, req.URI)
+	r.subs[req.URI]--
+	last := r.subs[req.URI] == 0
+	if last {
+		delete(r.subs, req.URI)
+	}
+	r.mu.Unlock()
+	if last {
+		if err := client.UnsubscribeResource(ctx, mcp.UnsubscribeResourceRequest{URI: req.URI}); err != nil {
+			return nil, err
+		}
+	}
+	return struct{}{}, nil
+}
+
+// drop forgets a disconnected client and releases its subscriptions.
+func (r *relay) drop(conn *jsonrpc2.Connection) {
+	r.mu.Lock()
+	uris := r.conns[conn]
+	delete(r.conns, conn)
+	var release []string
+	for uri := range uris {
+		if r.subs[uri]--; r.subs[uri] == 0 {
+			delete(r.subs, uri)
+			release = append(release, uri)
+		}
+	}
+	r.mu.Unlock()
+	for _, uri := range release {
+		ctx, cancel := context.WithTimeout(context.Background(), r.sess.cfg.Timeout)
+		if err := r.sess.Client().UnsubscribeResource(ctx, mcp.UnsubscribeResourceRequest{URI: uri}); err != nil {
+			r.logger.Warn("unsubscribe", "uri", uri, "error", err)
+		}
+		cancel()
+	}
+}
+
+// routeProgress replaces the progress token of a request from conn with
+// one the daemon owns, since clients choose tokens independently and may
+// pick the same one, and routes progress for it back to conn. The returned
+// func ends the route once the request is answered. Params without a
+// progress token are returned as they are.
+func (r *relay) routeProgress(conn *jsonrpc2.Connection, params json.RawMessage) (any, func()) {
+	if len(params) == 0 {
+		return nil, func() {}
+	}
+	var msg, meta map[string]json.RawMessage
+	if json.Unmarshal(params, &msg) != nil || json.Unmarshal(msg["_meta"], &meta) != nil {
+		return params, func() {}
+	}
+	clientToken := meta["progressToken"]
+	if len(clientToken) == 0 || string(clientToken) == "null" {
+		return params, func() {}
+	}
+	r.mu.Lock()
+	r.lastToken++
+	token := fmt.Sprintf("mcp-session-%d", r.lastToken)
+	r.progress[token] = progressRoute{conn: conn, token: clientToken}
+	r.mu.Unlock()
+	meta["progressToken"], _ = json.Marshal(token)
+	msg["_meta"], _ = json.Marshal(meta)
+	data, _ := json.Marshal(msg)
+	return json.RawMessage(data), func() {
+		time.AfterFunc(progressGrace, func() {
+			r.mu.Lock()
+			delete(r.progress, token)
+			r.mu.Unlock()
+		})
+	}
+}
+
+// broadcast sends server notifications to clients: progress to the client
+// whose request it reports, under that client's token, and anything else
+// to every client.
+func (r *relay) broadcast(ctx context.Context, events <-chan Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			conns, params := r.recipients(event)
+			for _, conn := range conns {
+				_ = conn.Notify(ctx, event.Method, params)
+			}
+		}
+	}
+}
+
+// recipients returns the clients event goes to and the params to send them.
+func (r *relay) recipients(event Event) ([]*jsonrpc2.Connection, any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if event.Method == string(mcp.MethodProgress) {
+		var msg map[string]json.RawMessage
+		var token string
+		if json.Unmarshal(event.Params, &msg) != nil || json.Unmarshal(msg["progressToken"], &token) != nil {
+			return nil, nil
+		}
+		route, ok := r.progress[token]
+		if !ok || r.conns[route.conn] == nil {
+			return nil, nil
+		}
+		msg["progressToken"] = route.token
+		data, _ := json.Marshal(msg)
+		return []*jsonrpc2.Connection{route.conn}, json.RawMessage(data)
+	}
+	var params any
+	if len(event.Params) > 0 {
+		params = event.Params
+	}
+	conns := make([]*jsonrpc2.Connection, 0, len(r.conns))
+	for conn := range r.conns {
+		conns = append(conns, conn)
+	}
+	return conns, params
+}
+
+func (r *relay) closeAll() {
+	r.mu.Lock()
+	conns := make([]*jsonrpc2.Connection, 0, len(r.conns))
+	for conn := range r.conns {
+		conns = append(conns, conn)
+	}
+	r.mu.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
@@ -0,0 +1,191 @@
+package mcpcli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+const runAsServerEnv = "MCPCLI_TEST_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runAsServerEnv) == "1" {
+		if err := runCounterServer(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runCounterServer serves a tool that counts its calls, a resource that the
+// touch tool reports as updated, a work tool that reports its call number
+// as progress and a prompt.
+func runCounterServer() error {
+	server := mcp.NewServer("counter", "1.0.0")
+	calls := 0
+	server.RegisterTool(mcp.Tool{Name: "count"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		return &mcp.CallToolResult{Content: mcp.ContentList{mcp.TextContent{Type: "text", Text: strconv.Itoa(calls)}}}, nil
+	})
+	server.RegisterTool(mcp.Tool{Name: "touch"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		err := server.ResourceUpdated(ctx, mcp.ResourceUpdatedNotificationParams{URI: "mem://note"})
+		return &mcp.CallToolResult{Content: mcp.ContentList{}}, err
+	})
+	works := 0
+	server.RegisterTool(mcp.Tool{Name: "work"}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		works++
+		err := server.NotifyProgress(ctx, req.ProgressToken(), float64(works), nil)
+		return &mcp.CallToolResult{Content: mcp.ContentList{}}, err
+	})
+	server.RegisterResource(mcp.Resource{URI: "mem://note"}, func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: req.URI, Text: "note"}}, nil
+	})
+	server.RegisterPrompt(mcp.Prompt{Name: "greet"}, func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return &mcp.GetPromptResult{Messages: []mcp.PromptMessage{{Role: "user", Content: mcp.TextContent{Type: "text", Text: fmt.Sprintf("hello %v", req.Arguments["name"])}}}}, nil
+	})
+	return server.Serve(context.Background(), mcp.StdioTransport())
+}
+
+func TestServeSession(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(runAsServerEnv, "1")
+	command := strconv.Quote(exe)
+
+	// Unix socket paths are limited to about 100 bytes, more than t.TempDir
+	// leaves on some systems.
+	stateDir, err := os.MkdirTemp("", "mcpcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stateDir)
+
+	serveCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeSession(serveCtx, Config{Cmd: command, StateDir: stateDir}, "test", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}()
+	var info SessionInfo
+	for {
+		if info, err = ReadSession(stateDir, "test"); err == nil && info.Running(ctx) {
+			break
+		}
+		select {
+		case err := <-served:
+			t.Fatalf("ServeSession exited: %v", err)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if info.Cmd != command || info.PID != os.Getpid() {
+		t.Errorf("session info = %+v", info)
+	}
+
+	connect := func() *Session {
+		t.Helper()
+		sess, err := Connect(ctx, Config{Session: "test", StateDir: stateDir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	}
+	first, second := connect(), connect()
+	defer second.Close()
+	if got := first.InitializeResult().ServerInfo.Name; got != "counter" {
+		t.Errorf("server name through session = %q, want counter", got)
+	}
+
+	// Both clients share one server connection, so its state carries over.
+	for i, sess := range []*Session{first, second} {
+		result, err := sess.Client().CallTool(ctx, mcp.CallToolRequest{Name: "count"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; text != strconv.Itoa(i+1) {
+			t.Errorf("call %d counted %s calls, want %d", i, text, i+1)
+		}
+	}
+	if _, err := first.Client().CallTool(ctx, mcp.CallToolRequest{Name: "missing"}); err == nil {
+		t.Error("calling an unknown tool through the session succeeded")
+	}
+
+	// Progress reaches only the client whose request it reports, under its
+	// own token, even when both clients chose the same one.
+	firstEvents, unsubscribeFirst := first.Subscribe(8)
+	secondEvents, unsubscribeSecond := second.Subscribe(8)
+	for i, sess := range []*Session{second, first} {
+		events := []<-chan Event{secondEvents, firstEvents}[i]
+		if _, err := sess.Client().CallTool(ctx, mcp.CallToolRequest{Name: "work", Meta: map[string]any{"progressToken": "p"}}); err != nil {
+			t.Fatal(err)
+		}
+		for event := range events {
+			if event.Method != string(mcp.MethodProgress) {
+				continue
+			}
+			var params struct {
+				ProgressToken any
+				Progress      float64
+			}
+			if json.Unmarshal(event.Params, &params); params.ProgressToken != "p" || params.Progress != float64(i+1) {
+				t.Errorf("client %d got progress %s, want %d for token p", i, event.Params, i+1)
+			}
+			break
+		}
+	}
+	unsubscribeFirst()
+	unsubscribeSecond()
+
+	// Notifications reach every client, and one client unsubscribing leaves
+	// the other's subscription in place.
+	events, unsubscribe := second.Subscribe(8)
+	defer unsubscribe()
+	for _, sess := range []*Session{first, second} {
+		if err := sess.Client().SubscribeResource(ctx, mcp.SubscribeResourceRequest{URI: "mem://note"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := first.Client().UnsubscribeResource(ctx, mcp.UnsubscribeResourceRequest{URI: "mem://note"}); err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+	if _, err := second.Client().CallTool(ctx, mcp.CallToolRequest{Name: "touch"}); err != nil {
+		t.Fatal(err)
+	}
+	for event := range events {
+		if event.Method == string(mcp.MethodResourceUpdated) {
+			var params mcp.ResourceUpdatedNotificationParams
+			if json.Unmarshal(event.Params, &params); params.URI != "mem://note" {
+				t.Errorf("updated %q, want mem://note", params.URI)
+			}
+			break
+		}
+	}
+
+	if err := StopSession(ctx, stateDir, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("ServeSession = %v after stop", err)
+	}
+	if _, err := ReadSession(stateDir, "test"); err == nil {
+		t.Error("session metadata remains after stop")
+	}
+	if _, err := Connect(ctx, Config{Session: "test", StateDir: stateDir}); err == nil {
+		t.Error("connected to a stopped session")
+	}
+}
@@ -0,0 +1,577 @@
+package mcpcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tmc/mcp"
+)
+
+// ClientSpec is the server surface a typed client is generated from.
+type ClientSpec struct {
+	Package   string
+	Source    string // how the server was reached, for the header comment
+	Tools     []mcp.Tool
+	Prompts   []mcp.Prompt
+	Templates []mcp.ResourceTemplate
+}
+
+// GenerateClient writes a Go package for calling the tools, prompts and
+// resource templates in spec with typed arguments and results. Each tool
+// becomes a method taking a struct built from its input schema; tools with
+// an output schema return the Go type of that schema, a struct for objects,
+// through mcp.CallToolTyped, and the rest return the *mcp.CallToolResult.
+// Schemas that are structurally equal share one named type. The package pins the
+// mcp.SchemaHash of spec, and its New fails when the server's versions of
+// those items no longer match; other items may change freely.
+func GenerateClient(spec ClientSpec) ([]byte, error) {
+	if !token.IsIdentifier(spec.Package) {
+		return nil, fmt.Errorf("invalid package name %q", spec.Package)
+	}
+	g := &clientGen{
+		names: map[string]bool{"Client": true, "New": true, "NewUnchecked": true, "SchemaHash": true, "ErrSchemaDrift": true, "schemaNames": true},
+		types: make(map[string]string),
+	}
+	tools := append([]mcp.Tool(nil), spec.Tools...)
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	prompts := append([]mcp.Prompt(nil), spec.Prompts...)
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	templates := append([]mcp.ResourceTemplate(nil), spec.Templates...)
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	var methods bytes.Buffer
+	for _, tool := range tools {
+		g.tool(&methods, tool)
+	}
+	for _, prompt := range prompts {
+		g.prompt(&methods, prompt)
+	}
+	for _, tmpl := range templates {
+		g.template(&methods, tmpl)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by mcp gen client; DO NOT EDIT.\n")
+	if spec.Source != "" {
+		fmt.Fprintf(&b, "// Source: %s\n", spec.Source)
+	}
+	fmt.Fprintf(&b, "\n// Package %s is a typed client for an MCP server.\npackage %s\n\n", spec.Package, spec.Package)
+	b.WriteString("import (\n\t\"context\"\n")
+	if g.usesJSON {
+		b.WriteString("\t\"encoding/json\"\n")
+	}
+	b.WriteString("\t\"errors\"\n\t\"fmt\"\n\n\t\"github.com/tmc/mcp\"\n)\n\n")
+	fmt.Fprintf(&b, "// SchemaHash is the mcp.SchemaHash of the server surface this package was\n// generated from.\nconst SchemaHash = %q\n\n", mcp.SchemaHash(tools, prompts, templates))
+	names := mcp.SchemaNamesOf(tools, prompts, templates)
+	fmt.Fprintf(&b, "// schemaNames selects the items covered by SchemaHash.\nvar schemaNames = mcp.SchemaNames{\n\tTools: %s,\n\tPrompts: %s,\n\tTemplates: %s,\n}\n\n", stringsLit(names.Tools), stringsLit(names.Prompts), stringsLit(names.Templates))
+	b.WriteString(clientPreamble)
+	if g.usesCall {
+		b.WriteString(callToolHelper)
+	}
+	b.Write(methods.Bytes())
+	for _, decl := range g.decls {
+		b.WriteString(decl)
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
+
+// stringsLit returns a Go expression for names.
+func stringsLit(names []string) string {
+	if len(names) == 0 {
+		return "nil"
+	}
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
+
+const clientPreamble = `// ErrSchemaDrift reports that the server's tools, prompts or resource
+// templates no longer match SchemaHash.
+var ErrSchemaDrift = errors.New("server schema does not match the generated client")
+
+// Client calls the server through an initialized *mcp.Client.
+type Client struct {
+	c *mcp.Client
+}
+
+// New returns a Client for c after checking that the server still has the
+// tools, prompts and templates this package was generated from. It returns an error wrapping
+// ErrSchemaDrift if it does not; regenerate the package, or use
+// NewUnchecked to call the server anyway.
+func New(ctx context.Context, c *mcp.Client) (*Client, error) {
+	hash, err := c.ServerSchemaHashOf(ctx, schemaNames)
+	if err != nil {
+		return nil, err
+	}
+	if hash != SchemaHash {
+		return nil, fmt.Errorf("%w: server has %s, client was generated for %s", ErrSchemaDrift, hash, SchemaHash)
+	}
+	return &Client{c: c}, nil
+}
+
+// NewUnchecked returns a Client for c without comparing schemas.
+func NewUnchecked(c *mcp.Client) *Client {
+	return &Client{c: c}
+}
+
+`
+
+const callToolHelper = `// callTool calls a tool without an output schema and turns an error result
+// into an error.
+func (c *Client) callTool(ctx context.Context, name string, args any) (*mcp.CallToolResult, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("marshal arguments for tool %q: %w", name, err)
+	}
+	result, err := c.c.CallTool(ctx, mcp.CallToolRequest{Name: name, Arguments: data})
+	if err != nil {
+		return nil, err
+	}
+	if result.IsError {
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				return nil, fmt.Errorf("tool %q: %s", name, text.Text)
+			}
+		}
+		return nil, fmt.Errorf("tool %q returned an error", name)
+	}
+	return result, nil
+}
+
+`
+
+// clientGen accumulates the type declarations of a generated client.
+type clientGen struct {
+	names    map[string]bool // top-level identifiers in use
+	decls    []string
+	usesJSON bool
+	usesCall bool
+
+	// types maps the canonical form of each schema given a named type to
+	// that name, so equal schemas across tools share a declaration.
+	types map[string]string
+
+	// root and refs resolve "$ref"s within the schema being converted.
+	root map[string]any
+	refs map[string]string
+}
+
+func (g *clientGen) tool(w *bytes.Buffer, tool mcp.Tool) {
+	method := g.unique(goName(tool.Name))
+	args := g.rootStruct(method+"Args", decodeSchema(tool.InputSchema), fmt.Sprintf("holds the arguments of the %s tool.", tool.Name))
+	writeDoc(w, method, tool.Description, fmt.Sprintf("calls the %s tool.", tool.Name))
+	if output := decodeSchema(tool.OutputSchema); output != nil {
+		result := g.rootType(method+"Result", output, fmt.Sprintf("is the structured result of the %s tool.", tool.Name))
+		fmt.Fprintf(w, "func (c *Client) %s(ctx context.Context, args %s) (*%s, error) {\n", method, args, result)
+		fmt.Fprintf(w, "\treturn mcp.CallToolTyped[%s, %s](c.c, ctx, %q, args)\n}\n\n", args, result, tool.Name)
+		return
+	}
+	g.usesCall, g.usesJSON = true, true
+	fmt.Fprintf(w, "func (c *Client) %s(ctx context.Context, args %s) (*mcp.CallToolResult, error) {\n", method, args)
+	fmt.Fprintf(w, "\treturn c.callTool(ctx, %q, args)\n}\n\n", tool.Name)
+}
+
+func (g *clientGen) prompt(w *bytes.Buffer, prompt mcp.Prompt) {
+	method := g.unique(goName(prompt.Name) + "Prompt")
+	writeDoc(w, method, prompt.Description, fmt.Sprintf("gets the %s prompt.", prompt.Name))
+	if len(prompt.Arguments) == 0 {
+		fmt.Fprintf(w, "func (c *Client) %s(ctx context.Context) (*mcp.GetPromptResult, error) {\n", method)
+		fmt.Fprintf(w, "\treturn c.c.GetPrompt(ctx, mcp.GetPromptRequest{Name: %q})\n}\n\n", prompt.Name)
+		return
+	}
+	argsType := g.unique(method + "Args")
+	arguments := append([]mcp.PromptArgument(nil), prompt.Arguments...)
+	sort.Slice(arguments, func(i, j int) bool { return arguments[i].Name < arguments[j].Name })
+	var decl, body bytes.Buffer
+	fmt.Fprintf(&decl, "// %s holds the arguments of the %s prompt.\ntype %s struct {\n", argsType, prompt.Name, argsType)
+	fields := map[string]bool{}
+	for _, arg := range arguments {
+		field := uniqueIn(fields, goName(arg.Name))
+		writeFieldDoc(&decl, arg.Description)
+		if arg.Required {
+			fmt.Fprintf(&decl, "\t%s string `json:%q`\n", field, arg.Name)
+			fmt.Fprintf(&body, "\targs[%q] = a.%s\n", arg.Name, field)
+		} else {
+			fmt.Fprintf(&decl, "\t%s string `json:%q`\n", field, arg.Name+",omitempty")
+			fmt.Fprintf(&body, "\tif a.%s != \"\" {\n\t\targs[%q] = a.%s\n\t}\n", field, arg.Name, field)
+		}
+	}
+	decl.WriteString("}\n\n")
+	g.decls = append(g.decls, decl.String())
+	fmt.Fprintf(w, "func (c *Client) %s(ctx context.Context, a %s) (*mcp.GetPromptResult, error) {\n", method, argsType)
+	fmt.Fprintf(w, "\targs := make(map[string]any)\n%s", body.String())
+	fmt.Fprintf(w, "\treturn c.c.GetPrompt(ctx, mcp.GetPromptRequest{Name: %q, Arguments: args})\n}\n\n", prompt.Name)
+}
+
+func (g *clientGen) template(w *bytes.Buffer, tmpl mcp.ResourceTemplate) {
+	uri := tmpl.URITemplate
+	if uri == "" {
+		uri = tmpl.Template
+	}
+	name := tmpl.Name
+	if name == "" {
+		name = uri
+	}
+	method := g.unique(goName(name) + "Resource")
+	writeDoc(w, method, tmpl.Description, fmt.Sprintf("reads the resource %s.", uri))
+	vars := mcp.URITemplateVariables(uri)
+	if len(vars) == 0 {
+		fmt.Fprintf(w, "func (c *Client) %s(ctx context.Context) (*mcp.ReadResourceResult, error) {\n", method)
+		fmt.Fprintf(w, "\treturn c.c.ReadResource(ctx, mcp.ReadResourceRequest{URI: %q})\n}\n\n", uri)
+		return
+	}
+	paramsType := g.unique(method + "Params")
+	var decl, body bytes.Buffer
+	fmt.Fprintf(&decl, "// %s holds the variables of the URI template %s.\ntype %s struct {\n", paramsType, uri, paramsType)
+	fields := map[string]bool{}
+	for _, v := range vars {
+		field := uniqueIn(fields, goName(v))
+		fmt.Fprintf(&decl, "\t%s string\n", field)
+		fmt.Fprintf(&body, "\t\t%q: p.%s,\n", v, field)
+	}
+	decl.WriteString("}\n\n")
+	g.decls = append(g.decls, decl.String())
+	fmt.Fprintf(w, "func (c *Client) %s(ctx context.Context, p %s) (*mcp.ReadResourceResult, error) {\n", method, paramsType)
+	fmt.Fprintf(w, "\turi, err := mcp.ExpandURITemplate(%q, map[string]string{\n%s\t})\n", uri, body.String())
+	fmt.Fprintf(w, "\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	fmt.Fprintf(w, "\treturn c.c.ReadResource(ctx, mcp.ReadResourceRequest{URI: uri})\n}\n\n")
+}
+
+// rootStruct declares a struct for a tool's input schema, which is always
+// an object, and returns its name.
+func (g *clientGen) rootStruct(name string, schema map[string]any, doc string) string {
+	g.root, g.refs = schema, map[string]string{}
+	name = g.unique(name)
+	g.structType(name, schema, fmt.Sprintf("// %s %s\n", name, doc))
+	return name
+}
+
+// rootType returns the Go type for a tool's output schema: a struct named
+// name for an object with properties, and otherwise the type goType gives,
+// such as int64 for an integer result.
+func (g *clientGen) rootType(name string, schema map[string]any, doc string) string {
+	if kind, _ := schemaKind(schema); kind == "object" && schema["properties"] != nil {
+		return g.rootStruct(name, schema, doc)
+	}
+	g.root, g.refs = schema, map[string]string{}
+	typ, _ := g.goType(schema, name)
+	return typ
+}
+
+// structType declares name as a struct with a field per property of schema.
+// Nested types are declared after it.
+func (g *clientGen) structType(name string, schema map[string]any, doc string) {
+	slot := len(g.decls)
+	g.decls = append(g.decls, "")
+	props, _ := schema["properties"].(map[string]any)
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]any); ok {
+		for _, r := range list {
+			if s, ok := r.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	b.WriteString(doc)
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	fields := map[string]bool{}
+	for _, key := range keys {
+		prop, _ := props[key].(map[string]any)
+		field := uniqueIn(fields, goName(key))
+		typ, nullable := g.goType(prop, name+field)
+		optional := !required[key]
+		if (optional || nullable) && pointerable(typ) {
+			typ = "*" + typ
+		}
+		tag := key
+		if optional {
+			tag += ",omitempty"
+		}
+		desc, _ := prop["description"].(string)
+		if values := enumValues(prop); values != "" {
+			desc = strings.TrimSpace(desc + "\nOne of " + values + ".")
+		}
+		writeFieldDoc(&b, desc)
+		fmt.Fprintf(&b, "\t%s %s `json:%q`\n", field, typ, tag)
+	}
+	b.WriteString("}\n\n")
+	g.decls[slot] = b.String()
+}
+
+// goType returns the Go type for schema, declaring named types for nested
+// objects, and whether the schema allows null.
+func (g *clientGen) goType(schema map[string]any, hint string) (typ string, nullable bool) {
+	if ref, ok := schema["$ref"].(string); ok {
+		return g.refType(ref), false
+	}
+	kind, nullable := schemaKind(schema)
+	switch kind {
+	case "object":
+		if props, ok := schema["properties"].(map[string]any); ok && len(props) > 0 {
+			key := g.schemaKey(schema)
+			if name, ok := g.types[key]; ok {
+				return name, nullable
+			}
+			name := g.unique(hint)
+			g.types[key] = name
+			g.structType(name, schema, "")
+			return name, nullable
+		}
+		if extra, ok := schema["additionalProperties"].(map[string]any); ok {
+			elem, _ := g.goType(extra, hint+"Value")
+			return "map[string]" + elem, nullable
+		}
+		return "map[string]any", nullable
+	case "array":
+		items, ok := schema["items"].(map[string]any)
+		if !ok {
+			return "[]any", nullable
+		}
+		elem, _ := g.goType(items, hint+"Item")
+		return "[]" + elem, nullable
+	case "string":
+		return "string", nullable
+	case "integer":
+		return "int64", nullable
+	case "number":
+		return "float64", nullable
+	case "boolean":
+		return "bool", nullable
+	}
+	return "any", false
+}
+
+// refType resolves a local "$ref" to the named type declared for it.
+func (g *clientGen) refType(ref string) string {
+	if name, ok := g.refs[ref]; ok {
+		return name
+	}
+	def, ok := resolvePointer(g.root, ref)
+	if !ok {
+		return "any"
+	}
+	key := g.schemaKey(def)
+	if name, ok := g.types[key]; ok {
+		g.refs[ref] = name
+		return name
+	}
+	name := g.unique(goName(ref[strings.LastIndexByte(ref, '/')+1:]))
+	g.refs[ref], g.types[key] = name, name
+	if kind, _ := schemaKind(def); kind == "object" && def["properties"] != nil {
+		g.structType(name, def, "")
+		return name
+	}
+	// Declare an alias so that refs to other types stay readable.
+	typ, _ := g.goType(def, name+"Value")
+	g.decls = append(g.decls, fmt.Sprintf("type %s = %s\n\n", name, typ))
+	return name
+}
+
+// resolvePointer returns the subschema of root a local "$ref" such as
+// "#/$defs/user" points to.
+func resolvePointer(root map[string]any, ref string) (map[string]any, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	schema := root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		next, ok := schema[token].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		schema = next
+	}
+	return schema, true
+}
+
+// schemaKey returns the canonical form of schema used to find an equal
+// schema that already has a named type. A schema with "$ref"s is only
+// equal to one under the same definitions.
+func (g *clientGen) schemaKey(schema map[string]any) string {
+	data, _ := json.Marshal(schema) // map keys are sorted
+	if bytes.Contains(data, []byte(`"$ref"`)) {
+		defs, _ := json.Marshal([]any{g.root["$defs"], g.root["definitions"]})
+		data = append(append(data, 0), defs...)
+	}
+	return string(data)
+}
+
+// schemaKind returns the JSON type of schema, inferring it when "type" is
+// missing, and whether null is also allowed.
+func schemaKind(schema map[string]any) (string, bool) {
+	switch t := schema["type"].(type) {
+	case string:
+		return t, false
+	case []any:
+		kind, nullable := "", false
+		for _, v := range t {
+			switch s, _ := v.(string); {
+			case s == "null":
+				nullable = true
+			case kind == "":
+				kind = s
+			default:
+				return "", nullable // a union Go cannot express
+			}
+		}
+		return kind, nullable
+	}
+	switch {
+	case schema["properties"] != nil:
+		return "object", false
+	case schema["items"] != nil:
+		return "array", false
+	}
+	if values, ok := schema["enum"].([]any); ok && len(values) > 0 {
+		switch values[0].(type) {
+		case string:
+			return "string", false
+		case float64:
+			return "number", false
+		case bool:
+			return "boolean", false
+		}
+	}
+	return "", false
+}
+
+// enumValues lists the allowed values of schema for a field comment.
+func enumValues(schema map[string]any) string {
+	values, _ := schema["enum"].([]any)
+	var parts []string
+	for _, v := range values {
+		data, _ := json.Marshal(v)
+		parts = append(parts, string(data))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// pointerable reports whether an optional field of type typ needs a pointer
+// to tell absence from the zero value.
+func pointerable(typ string) bool {
+	return !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "any"
+}
+
+func decodeSchema(raw json.RawMessage) map[string]any {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil
+	}
+	return schema
+}
+
+func (g *clientGen) unique(name string) string {
+	return uniqueIn(g.names, name)
+}
+
+// uniqueIn returns name, or name with a number appended if it is already in
+// used, and records the result.
+func uniqueIn(used map[string]bool, name string) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// goInitialisms are the words goName writes in capitals.
+var goInitialisms = map[string]bool{
+	"API": true, "CPU": true, "CSS": true, "DNS": true, "HTML": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "MCP": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UI": true, "URI": true, "URL": true, "UTF8": true, "UUID": true,
+	"XML": true,
+}
+
+// goName converts a tool, prompt or property name such as "create_issue",
+// "createIssue" or "repo-url" to an exported Go identifier: CreateIssue,
+// CreateIssue, RepoURL.
+func goName(s string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) ||
+			unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); goInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// writeDoc writes the doc comment of a generated method: summary, then the
+// item's own description as a second paragraph.
+func writeDoc(w *bytes.Buffer, method, description, summary string) {
+	w.WriteString(commentLines(method + " " + summary))
+	if description = strings.TrimSpace(description); description != "" {
+		w.WriteString("//\n")
+		w.WriteString(commentLines(description))
+	}
+}
+
+func writeFieldDoc(w *bytes.Buffer, description string) {
+	if description = strings.TrimSpace(description); description != "" {
+		for _, line := range strings.Split(description, "\n") {
+			fmt.Fprintf(w, "\t// %s\n", strings.TrimRightFunc(line, unicode.IsSpace))
+		}
+	}
+}
+
+// commentLines formats text as a // comment.
+func commentLines(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		fmt.Fprintf(&b, "// %s\n", strings.TrimRightFunc(line, unicode.IsSpace))
+	}
+	return b.String()
+}
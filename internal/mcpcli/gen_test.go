@@ -0,0 +1,139 @@
+package mcpcli
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/tmc/mcp"
+)
+
+func TestGenerateClient(t *testing.T) {
+	spec := ClientSpec{
+		Package: "githubmcp",
+		Tools: []mcp.Tool{
+			{
+				Name:        "create_issue",
+				Description: "Create an issue.",
+				InputSchema: json.RawMessage(`{"type": "object", "properties": {
+					"repo": {"type": "string", "description": "owner/name"},
+					"title": {"type": "string"},
+					"labels": {"type": "array", "items": {"type": "string"}},
+					"priority": {"type": "integer"},
+					"state": {"enum": ["open", "closed"]},
+					"assignee": {"$ref": "#/$defs/user"},
+					"reviewers": {"type": "array", "items": {"$ref": "#/$defs/user"}},
+					"milestone": {"type": ["object", "null"], "properties": {"id": {"type": "integer"}}}
+				}, "required": ["repo", "title", "milestone"], "$defs": {"user": {"type": "object", "properties": {"login": {"type": "string"}}}}}`),
+				OutputSchema: json.RawMessage(`{"type": "object", "properties": {"number": {"type": "integer"}, "html_url": {"type": "string"}}, "required": ["number"]}`),
+			},
+			{Name: "list-repos", InputSchema: json.RawMessage(`{"type": "object"}`)},
+			{
+				Name:         "count_issues",
+				InputSchema:  json.RawMessage(`{"type": "object", "properties": {"repo": {"type": "string"}}}`),
+				OutputSchema: json.RawMessage(`{"type": "integer"}`),
+			},
+			{
+				Name:         "search_items",
+				InputSchema:  json.RawMessage(`{"type": "object", "properties": {"near": {"$ref": "#/$defs/item"}}, "$defs": {"item": {"type": "object", "properties": {"id": {"type": "string"}}}}}`),
+				OutputSchema: json.RawMessage(`{"type": "object", "properties": {"items": {"type": "array", "items": {"$ref": "#/definitions/item"}}}, "definitions": {"item": {"type": "object", "properties": {"id": {"type": "string"}}}}}`),
+			},
+			{
+				Name:         "get_item",
+				InputSchema:  json.RawMessage(`{"type": "object", "properties": {"id": {"type": "string"}}}`),
+				OutputSchema: json.RawMessage(`{"type": "object", "properties": {"item": {"type": "object", "properties": {"id": {"type": "string"}}}}}`),
+			},
+		},
+		Prompts:   []mcp.Prompt{{Name: "review", Arguments: []mcp.PromptArgument{{Name: "pr_url", Required: true}, {Name: "tone"}}}},
+		Templates: []mcp.ResourceTemplate{{Name: "file", URITemplate: "repo://{owner}/{name}/{+path}"}},
+	}
+	src, err := GenerateClient(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(src)
+	for _, want := range []string{
+		"// Code generated by mcp gen client; DO NOT EDIT.",
+		"package githubmcp",
+		`const SchemaHash = "` + mcp.SchemaHash(spec.Tools, spec.Prompts, spec.Templates) + `"`,
+		`Tools:     []string{"count_issues", "create_issue", "get_item", "list-repos", "search_items"},`,
+		`Prompts:   []string{"review"},`,
+		"hash, err := c.ServerSchemaHashOf(ctx, schemaNames)",
+		"func (c *Client) CreateIssue(ctx context.Context, args CreateIssueArgs) (*CreateIssueResult, error) {",
+		`mcp.CallToolTyped[CreateIssueArgs, CreateIssueResult](c.c, ctx, "create_issue", args)`,
+		"func (c *Client) ListRepos(ctx context.Context, args ListReposArgs) (*mcp.CallToolResult, error) {",
+		"func (c *Client) ReviewPrompt(ctx context.Context, a ReviewPromptArgs) (*mcp.GetPromptResult, error) {",
+		"func (c *Client) FileResource(ctx context.Context, p FileResourceParams) (*mcp.ReadResourceResult, error) {",
+		"Assignee  *User                     `json:\"assignee,omitempty\"`",
+		"Labels    []string                  `json:\"labels,omitempty\"`",
+		"Milestone *CreateIssueArgsMilestone `json:\"milestone\"`",
+		"Priority  *int64                    `json:\"priority,omitempty\"`",
+		"\t// owner/name\n\tRepo      string `json:\"repo\"`",
+		"Reviewers []User `json:\"reviewers,omitempty\"`",
+		"\t// One of \"open\", \"closed\".\n\tState *string `json:\"state,omitempty\"`",
+		"type User struct {",
+		"HTMLURL *string `json:\"html_url,omitempty\"`",
+		"Number  int64   `json:\"number\"`",
+		"PrURL string `json:\"pr_url\"`",
+		"func (c *Client) CountIssues(ctx context.Context, args CountIssuesArgs) (*int64, error) {",
+		`mcp.CallToolTyped[CountIssuesArgs, int64](c.c, ctx, "count_issues", args)`,
+		"Item *GetItemResultItem `json:\"item,omitempty\"`",
+		"Near *GetItemResultItem `json:\"near,omitempty\"`",
+		"Items []GetItemResultItem `json:\"items,omitempty\"`",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code lacks %q", want)
+		}
+	}
+	for _, typ := range []string{"User", "GetItemResultItem"} {
+		if n := strings.Count(code, "type "+typ+" struct"); n != 1 {
+			t.Errorf("shared type %s declared %d times", typ, n)
+		}
+	}
+	if strings.Contains(code, "struct{}") {
+		t.Error("generated an empty struct")
+	}
+	typeCheck(t, src)
+	if again, _ := GenerateClient(spec); string(again) != code {
+		t.Error("output is not deterministic")
+	}
+	if _, err := GenerateClient(ClientSpec{Package: "github-mcp"}); err == nil {
+		t.Error("invalid package name accepted")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for in, want := range map[string]string{
+		"create_issue": "CreateIssue",
+		"createIssue":  "CreateIssue",
+		"repo-url":     "RepoURL",
+		"HTTPServer":   "HTTPServer",
+		"user_id":      "UserID",
+		"2fa":          "X2fa",
+		"":             "X",
+	} {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// typeCheck fails t if src does not compile against this module's mcp
+// package.
+func typeCheck(t *testing.T, src []byte) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "client.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check(f.Name.Name, fset, []*ast.File{f}, nil); err != nil {
+		t.Errorf("generated code does not type-check: %v\n%s", err, src)
+	}
+}
@@ -0,0 +1,735 @@
+package mcpcli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+// Policies for answering server requests for user input.
+const (
+	PolicyPrompt  = "prompt"  // ask on the terminal
+	PolicyEditor  = "editor"  // edit elicited values as JSON in $EDITOR
+	PolicyAccept  = "accept"  // accept without asking
+	PolicyDecline = "decline" // decline without asking
+)
+
+// Interaction answers elicitation and sampling requests from a server, on a
+// terminal or by policy. An empty policy leaves the capability unadvertised.
+//
+// Form elicitations are asked field by field (PolicyPrompt), edited as JSON
+// (PolicyEditor), or accepted with the schema defaults (PolicyAccept). URL
+// elicitations must be http or https URLs; they are opened in a browser
+// once the user agrees, which is asked under every policy but
+// PolicyDecline. Watch reports when the server says they are complete.
+//
+// Sampling requests are piped as JSON to SamplingCmd, whose output is the
+// result: either a CreateMessageResult or plain text. PolicyPrompt shows the
+// request first and asks before running the command, or without a command
+// asks for the reply itself; PolicyAccept runs the command unasked.
+type Interaction struct {
+	ElicitPolicy   string
+	SamplingPolicy string
+	SamplingCmd    string
+	In             io.Reader
+	Out            io.Writer
+	OpenURL        func(string) error // defaults to OpenBrowser
+
+	ask       sync.Mutex // one prompt at a time
+	startRead sync.Once
+	lines     chan readResult // lines of In, from readLines
+
+	mu      sync.Mutex        // guards pending
+	pending map[string]string // URL elicitations awaiting completion, by ID
+}
+
+// Validate checks the policies.
+func (in *Interaction) Validate() error {
+	switch in.ElicitPolicy {
+	case "", PolicyPrompt, PolicyEditor, PolicyAccept, PolicyDecline:
+	default:
+		return fmt.Errorf("invalid elicitation policy %q", in.ElicitPolicy)
+	}
+	switch in.SamplingPolicy {
+	case "", PolicyPrompt, PolicyDecline:
+	case PolicyAccept:
+		if in.SamplingCmd == "" {
+			return errors.New("accepting sampling requests needs a sampling command")
+		}
+	default:
+		return fmt.Errorf("invalid sampling policy %q", in.SamplingPolicy)
+	}
+	return nil
+}
+
+// Configure installs the handlers of the set policies in cfg.
+func (in *Interaction) Configure(cfg *Config) {
+	if in.ElicitPolicy != "" {
+		cfg.ElicitHandler = in.Elicit
+		cfg.ElicitModes = []mcp.ElicitMode{mcp.ElicitModeForm, mcp.ElicitModeURL}
+	}
+	if in.SamplingPolicy != "" {
+		cfg.SamplingHandler = in.Sample
+	}
+}
+
+// Watch reports the completion of URL elicitations on s until the returned
+// function is called.
+func (in *Interaction) Watch(s *Session) func() {
+	events, stop := s.Subscribe(16)
+	go func() {
+		for event := range events {
+			if event.Method != string(mcp.MethodElicitationComplete) {
+				continue
+			}
+			var params struct {
+				ElicitationID string `json:"elicitationId"`
+			}
+			if json.Unmarshal(event.Params, &params) != nil {
+				continue
+			}
+			in.mu.Lock()
+			if u, ok := in.pending[params.ElicitationID]; ok {
+				delete(in.pending, params.ElicitationID)
+				fmt.Fprintf(in.Out, "Completed %s\n", u)
+			}
+			in.mu.Unlock()
+		}
+	}()
+	return stop
+}
+
+// Elicit answers an elicitation/create request.
+func (in *Interaction) Elicit(ctx context.Context, req mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+	in.ask.Lock()
+	defer in.ask.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if in.ElicitPolicy == PolicyDecline {
+		return &mcp.ElicitResult{Action: "decline"}, nil
+	}
+	if req.Mode == string(mcp.ElicitModeURL) {
+		return in.elicitURL(ctx, req)
+	}
+	schema, err := parseElicitSchema(req.RequestedSchema)
+	if err != nil {
+		return nil, err
+	}
+	switch in.ElicitPolicy {
+	case PolicyAccept:
+		content, err := schema.defaults()
+		if err != nil {
+			fmt.Fprintf(in.Out, "Declined %q: %v\n", req.Message, err)
+			return &mcp.ElicitResult{Action: "decline"}, nil
+		}
+		return &mcp.ElicitResult{Action: "accept", Content: content}, nil
+	case PolicyEditor:
+		return in.elicitEditor(req, schema)
+	}
+
+	fmt.Fprintf(in.Out, "The server asks: %s\n", req.Message)
+	action, err := in.confirm(ctx, "Respond? [Y]es, [n]o, [c]ancel: ")
+	if action != "accept" || err != nil {
+		return &mcp.ElicitResult{Action: action}, err
+	}
+	content := make(map[string]any)
+	for _, field := range schema.fields {
+		v, err := in.askField(ctx, field)
+		if errors.Is(err, io.EOF) {
+			return &mcp.ElicitResult{Action: "cancel"}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			content[field.name] = v
+		}
+	}
+	return &mcp.ElicitResult{Action: "accept", Content: content}, nil
+}
+
+// elicitURL asks whether to open the URL of a URL elicitation, even when
+// elicitations are accepted by policy: the user must consent to every URL
+// a server sends them to. Only http and https URLs are opened, since the
+// desktop handler would run any other scheme's program.
+func (in *Interaction) elicitURL(ctx context.Context, req mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+	u, err := url.ParseRequestURI(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("elicitation url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("elicitation url: scheme %q is not http or https", u.Scheme)
+	}
+	fmt.Fprintf(in.Out, "The server asks: %s\n%s\n", req.Message, req.URL)
+	action, err := in.confirm(ctx, "Open this URL? [Y]es, [n]o, [c]ancel: ")
+	if action != "accept" || err != nil {
+		return &mcp.ElicitResult{Action: action}, err
+	}
+	open := in.OpenURL
+	if open == nil {
+		open = OpenBrowser
+	}
+	if err := open(req.URL); err != nil {
+		fmt.Fprintf(in.Out, "Could not open a browser (%v); open the URL above.\n", err)
+	}
+	if req.ElicitationID != "" {
+		in.mu.Lock()
+		if in.pending == nil {
+			in.pending = make(map[string]string)
+		}
+		in.pending[req.ElicitationID] = req.URL
+		in.mu.Unlock()
+	}
+	return &mcp.ElicitResult{Action: "accept"}, nil
+}
+
+func (in *Interaction) elicitEditor(req mcp.ElicitRequest, schema *elicitSchema) (*mcp.ElicitResult, error) {
+	fmt.Fprintf(in.Out, "The server asks: %s\n", req.Message)
+	template := make(map[string]any)
+	for _, field := range schema.fields {
+		template[field.name] = field.Default
+	}
+	data, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	for {
+		edited, err := EditTempFile(data, ".json")
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(edited)) == 0 {
+			return &mcp.ElicitResult{Action: "decline"}, nil
+		}
+		var content map[string]any
+		if err = json.Unmarshal(edited, &content); err == nil {
+			err = schema.check(content)
+		}
+		if err == nil {
+			return &mcp.ElicitResult{Action: "accept", Content: content}, nil
+		}
+		fmt.Fprintf(in.Out, "Invalid response: %v (save an empty file to decline)\n", err)
+		data = edited
+	}
+}
+
+// confirm asks question and maps the answer to an elicitation action.
+func (in *Interaction) confirm(ctx context.Context, question string) (string, error) {
+	for {
+		fmt.Fprint(in.Out, question)
+		answer, err := in.readLine(ctx)
+		if errors.Is(err, io.EOF) {
+			return "cancel", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		switch strings.ToLower(answer) {
+		case "", "y", "yes":
+			return "accept", nil
+		case "n", "no":
+			return "decline", nil
+		case "c", "cancel":
+			return "cancel", nil
+		}
+	}
+}
+
+// askField prompts for one field until the answer is valid. An empty answer
+// takes the default, or leaves an optional field unset.
+func (in *Interaction) askField(ctx context.Context, f elicitField) (any, error) {
+	label := f.name
+	if f.Title != "" {
+		label = f.Title
+	}
+	if f.Description != "" {
+		fmt.Fprintf(in.Out, "  %s\n", f.Description)
+	}
+	choices := f.choices()
+	for i, c := range choices {
+		fmt.Fprintf(in.Out, "    %d) %s\n", i+1, c.title)
+	}
+	hint := ""
+	switch {
+	case f.Default != nil:
+		hint = fmt.Sprintf(" [%v]", f.Default)
+	case !f.required:
+		hint = " (optional)"
+	}
+	if f.Type == "array" {
+		hint += " (comma-separated)"
+	}
+	for {
+		fmt.Fprintf(in.Out, "%s%s: ", label, hint)
+		answer, err := in.readLine(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if answer == "" {
+			if f.Default != nil || !f.required {
+				return f.Default, nil
+			}
+			fmt.Fprintln(in.Out, "  required")
+			continue
+		}
+		v, err := f.parse(answer, choices)
+		if err == nil {
+			err = f.check(v)
+		}
+		if err == nil {
+			return v, nil
+		}
+		fmt.Fprintf(in.Out, "  %v\n", err)
+	}
+}
+
+type readResult struct {
+	line string
+	err  error
+}
+
+// readLine returns the next line of In, or ctx's error once ctx is done.
+// In is read by a goroutine of its own so that a request the server
+// cancels stops waiting on the terminal and lets the next one ask; a line
+// typed meanwhile answers the next question.
+func (in *Interaction) readLine(ctx context.Context) (string, error) {
+	in.startRead.Do(func() {
+		in.lines = make(chan readResult)
+		go in.readLines()
+	})
+	select {
+	case r := <-in.lines:
+		return r.line, r.err
+	case <-ctx.Done():
+		fmt.Fprintln(in.Out)
+		return "", ctx.Err()
+	}
+}
+
+// readLines sends the lines of In to in.lines, then its error forever.
+func (in *Interaction) readLines() {
+	r := bufio.NewReader(in.In)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			for {
+				in.lines <- readResult{err: err}
+			}
+		}
+		in.lines <- readResult{line: strings.TrimSpace(line)}
+	}
+}
+
+// Sample answers a sampling/createMessage request.
+func (in *Interaction) Sample(ctx context.Context, req mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	in.ask.Lock()
+	defer in.ask.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if in.SamplingPolicy == PolicyDecline {
+		return nil, errors.New("user rejected sampling request")
+	}
+	if in.SamplingPolicy == PolicyPrompt {
+		fmt.Fprintf(in.Out, "The server asks for a model response (at most %d tokens).\n", req.MaxTokens)
+		if req.SystemPrompt != "" {
+			fmt.Fprintf(in.Out, "[system]\n%s\n", req.SystemPrompt)
+		}
+		for _, msg := range req.Messages {
+			var b strings.Builder
+			writePromptContent(&b, msg.Content)
+			fmt.Fprintf(in.Out, "[%s]\n%s\n", msg.Role, b.String())
+		}
+		if in.SamplingCmd == "" {
+			fmt.Fprint(in.Out, "Reply (empty to decline): ")
+			reply, err := in.readLine(ctx)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			if reply == "" {
+				return nil, errors.New("user rejected sampling request")
+			}
+			return &mcp.CreateMessageResult{
+				Role:       "assistant",
+				Content:    mcp.TextContent{Type: "text", Text: reply},
+				Model:      "human",
+				StopReason: "endTurn",
+			}, nil
+		}
+		if action, err := in.confirm(ctx, fmt.Sprintf("Run %s? [Y]es, [n]o: ", in.SamplingCmd)); action != "accept" || err != nil {
+			if err == nil {
+				err = errors.New("user rejected sampling request")
+			}
+			return nil, err
+		}
+	}
+	return RunSamplingCommand(ctx, in.SamplingCmd, req)
+}
+
+// RunSamplingCommand runs command with the JSON request on its stdin. Its
+// output is either a JSON CreateMessageResult or the text of the reply. A
+// reply that names no model is labeled with the command's program name,
+// never the whole command line, which may hold arguments the server should
+// not see.
+func RunSamplingCommand(ctx context.Context, command string, req mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("sampling command: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	var result mcp.CreateMessageResult
+	if json.Unmarshal(stdout.Bytes(), &result) == nil && result.Content != nil {
+		if result.Role == "" {
+			result.Role = "assistant"
+		}
+		if result.Model == "" {
+			result.Model = samplingModel(command)
+		}
+		return &result, nil
+	}
+	return &mcp.CreateMessageResult{
+		Role:       "assistant",
+		Content:    mcp.TextContent{Type: "text", Text: strings.TrimRight(stdout.String(), "\n")},
+		Model:      samplingModel(command),
+		StopReason: "endTurn",
+	}, nil
+}
+
+// samplingModel returns the base name of the program command runs, or
+// "external" if it starts with something else, such as a variable
+// assignment.
+func samplingModel(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || strings.ContainsAny(fields[0], "=$`'\"") {
+		return "external"
+	}
+	return filepath.Base(fields[0])
+}
+
+// OpenBrowser opens rawURL with the desktop's default handler.
+func OpenBrowser(rawURL string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", rawURL)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", rawURL)
+	default:
+		cmd = exec.Command("xdg-open", rawURL)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// elicitSchema is the flat object schema of a form elicitation.
+type elicitSchema struct {
+	fields []elicitField // in the order they are asked
+}
+
+type elicitField struct {
+	name     string
+	required bool
+
+	Type        string   `json:"type"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Default     any      `json:"default"`
+	Format      string   `json:"format"`
+	MinLength   *int     `json:"minLength"`
+	MaxLength   *int     `json:"maxLength"`
+	Minimum     *float64 `json:"minimum"`
+	Maximum     *float64 `json:"maximum"`
+	MinItems    *int     `json:"minItems"`
+	MaxItems    *int     `json:"maxItems"`
+	Enum        []any    `json:"enum"`
+	EnumNames   []string `json:"enumNames"`
+	OneOf       []option `json:"oneOf"`
+	Items       *struct {
+		Enum  []any    `json:"enum"`
+		AnyOf []option `json:"anyOf"`
+	} `json:"items"`
+}
+
+type option struct {
+	Const any    `json:"const"`
+	Title string `json:"title"`
+}
+
+type choice struct {
+	value any
+	title string
+}
+
+func parseElicitSchema(v any) (*elicitSchema, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Required   []string                   `json:"required"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("requested schema: %w", err)
+	}
+	// The order of the properties is lost in decoding the request, so ask
+	// for the required ones first, as listed, and then the rest by name.
+	var names []string
+	required := make(map[string]bool)
+	for _, name := range raw.Required {
+		if _, ok := raw.Properties[name]; ok && !required[name] {
+			names = append(names, name)
+			required[name] = true
+		}
+	}
+	var optional []string
+	for name := range raw.Properties {
+		if !required[name] {
+			optional = append(optional, name)
+		}
+	}
+	sort.Strings(optional)
+	schema := &elicitSchema{}
+	for _, name := range append(names, optional...) {
+		field := elicitField{name: name, required: required[name]}
+		if err := json.Unmarshal(raw.Properties[name], &field); err != nil {
+			return nil, fmt.Errorf("requested schema: property %s: %w", name, err)
+		}
+		schema.fields = append(schema.fields, field)
+	}
+	return schema, nil
+}
+
+// defaults returns the default of every field with one, failing if a
+// required field has none.
+func (s *elicitSchema) defaults() (map[string]any, error) {
+	content := make(map[string]any)
+	for _, f := range s.fields {
+		switch {
+		case f.Default != nil:
+			content[f.name] = f.Default
+		case f.required:
+			return nil, fmt.Errorf("%s is required and has no default", f.name)
+		}
+	}
+	return content, nil
+}
+
+// check validates content against the schema.
+func (s *elicitSchema) check(content map[string]any) error {
+	for _, f := range s.fields {
+		v, ok := content[f.name]
+		if !ok || v == nil {
+			if f.required {
+				return fmt.Errorf("%s is required", f.name)
+			}
+			delete(content, f.name)
+			continue
+		}
+		if err := f.check(v); err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+func (f elicitField) choices() []choice {
+	var options []option
+	enum, names := f.Enum, f.EnumNames
+	switch {
+	case len(f.OneOf) > 0:
+		options = f.OneOf
+	case f.Items != nil && len(f.Items.AnyOf) > 0:
+		options = f.Items.AnyOf
+	case f.Items != nil:
+		enum, names = f.Items.Enum, nil
+	}
+	for i, v := range enum {
+		title := fmt.Sprint(v)
+		if i < len(names) {
+			title = names[i]
+		}
+		options = append(options, option{Const: v, Title: title})
+	}
+	choices := make([]choice, len(options))
+	for i, o := range options {
+		title := o.Title
+		if title == "" {
+			title = fmt.Sprint(o.Const)
+		}
+		choices[i] = choice{o.Const, title}
+	}
+	return choices
+}
+
+// parse converts a typed answer to a value of the field's type. Choices are
+// picked by number or by value.
+func (f elicitField) parse(answer string, choices []choice) (any, error) {
+	pick := func(s string) (any, error) {
+		if n, err := strconv.Atoi(s); err == nil && n >= 1 && n <= len(choices) {
+			return choices[n-1].value, nil
+		}
+		for _, c := range choices {
+			if fmt.Sprint(c.value) == s {
+				return c.value, nil
+			}
+		}
+		return nil, fmt.Errorf("choose one of 1-%d", len(choices))
+	}
+	switch f.Type {
+	case "array":
+		var values []any
+		for _, part := range strings.Split(answer, ",") {
+			v, err := pick(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case "boolean":
+		switch strings.ToLower(answer) {
+		case "y", "yes", "true":
+			return true, nil
+		case "n", "no", "false":
+			return false, nil
+		}
+		return nil, errors.New("answer yes or no")
+	case "number", "integer":
+		if len(choices) > 0 {
+			return pick(answer)
+		}
+		n, err := strconv.ParseFloat(answer, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not a number")
+		}
+		return n, nil
+	}
+	if len(choices) > 0 {
+		return pick(answer)
+	}
+	return answer, nil
+}
+
+// check validates v, a decoded JSON value, against the field.
+func (f elicitField) check(v any) error {
+	choices := f.choices()
+	inChoices := func(v any) bool {
+		for _, c := range choices {
+			if fmt.Sprint(c.value) == fmt.Sprint(v) {
+				return true
+			}
+		}
+		return false
+	}
+	switch f.Type {
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return errors.New("want a string")
+		}
+		if f.MinLength != nil && len([]rune(s)) < *f.MinLength {
+			return fmt.Errorf("at least %d characters", *f.MinLength)
+		}
+		if f.MaxLength != nil && len([]rune(s)) > *f.MaxLength {
+			return fmt.Errorf("at most %d characters", *f.MaxLength)
+		}
+		if err := checkFormat(f.Format, s); err != nil {
+			return err
+		}
+	case "number", "integer":
+		n, ok := v.(float64)
+		if !ok {
+			return errors.New("want a number")
+		}
+		if f.Type == "integer" && n != float64(int64(n)) {
+			return errors.New("want a whole number")
+		}
+		if f.Minimum != nil && n < *f.Minimum {
+			return fmt.Errorf("at least %v", *f.Minimum)
+		}
+		if f.Maximum != nil && n > *f.Maximum {
+			return fmt.Errorf("at most %v", *f.Maximum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return errors.New("want true or false")
+		}
+	case "array":
+		values, ok := v.([]any)
+		if !ok {
+			return errors.New("want a list")
+		}
+		if f.MinItems != nil && len(values) < *f.MinItems {
+			return fmt.Errorf("choose at least %d", *f.MinItems)
+		}
+		if f.MaxItems != nil && len(values) > *f.MaxItems {
+			return fmt.Errorf("choose at most %d", *f.MaxItems)
+		}
+		for _, item := range values {
+			if len(choices) > 0 && !inChoices(item) {
+				return fmt.Errorf("%v is not a choice", item)
+			}
+		}
+		return nil
+	}
+	if len(choices) > 0 && !inChoices(v) {
+		return fmt.Errorf("%v is not a choice", v)
+	}
+	return nil
+}
+
+func checkFormat(format, s string) error {
+	var err error
+	switch format {
+	case "email":
+		_, err = mail.ParseAddress(s)
+	case "uri":
+		var u *url.URL
+		if u, err = url.Parse(s); err == nil && u.Scheme == "" {
+			err = errors.New("missing scheme")
+		}
+	case "date":
+		_, err = time.Parse(time.DateOnly, s)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, s)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("not a valid %s", format)
+	}
+	return nil
+}
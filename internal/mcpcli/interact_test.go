@@ -0,0 +1,219 @@
+package mcpcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tmc/mcp"
+)
+
+const testElicitSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "title": "Name", "minLength": 2},
+		"email": {"type": "string", "format": "email"},
+		"age": {"type": "integer", "minimum": 0, "default": 30},
+		"color": {"type": "string", "enum": ["red", "green"], "enumNames": ["Red", "Green"]},
+		"tags": {"type": "array", "items": {"enum": ["a", "b", "c"]}},
+		"subscribe": {"type": "boolean"}
+	},
+	"required": ["name", "email", "color"]
+}`
+
+func TestInteractionElicitPrompt(t *testing.T) {
+	var schema any
+	if err := json.Unmarshal([]byte(testElicitSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+	// Required fields are asked first. Invalid answers are asked again;
+	// empty answers take the default or leave optional fields unset.
+	input := strings.Join([]string{
+		"",         // respond
+		"A", "Ann", // name: too short, then valid
+		"x", "a@b.c", // email
+		"3", "2", // color: out of range, then by number
+		"",     // age: default
+		"",     // subscribe: optional
+		"a, c", // tags
+	}, "\n") + "\n"
+	var out bytes.Buffer
+	in := &Interaction{ElicitPolicy: PolicyPrompt, In: strings.NewReader(input), Out: &out}
+	result, err := in.Elicit(context.Background(), mcp.ElicitRequest{Message: "Who are you?", RequestedSchema: schema})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"name": "Ann", "email": "a@b.c", "age": 30.0, "color": "green", "tags": []any{"a", "c"}}
+	if result.Action != "accept" || !reflect.DeepEqual(result.Content, want) {
+		t.Errorf("result = %+v, want accept %v", result, want)
+	}
+	prompts := out.String()
+	for _, s := range []string{"Who are you?", "Name:", "at least 2 characters", "not a valid email", "2) Green", "choose one of 1-2"} {
+		if !strings.Contains(prompts, s) {
+			t.Errorf("prompts lack %q:\n%s", s, prompts)
+		}
+	}
+	if strings.Index(prompts, "color:") > strings.Index(prompts, "age [30]:") {
+		t.Errorf("optional field asked before a required one:\n%s", prompts)
+	}
+
+	// Running out of input cancels.
+	in = &Interaction{ElicitPolicy: PolicyPrompt, In: strings.NewReader("y\nAnn\n"), Out: &out}
+	if result, err := in.Elicit(context.Background(), mcp.ElicitRequest{RequestedSchema: schema}); err != nil || result.Action != "cancel" {
+		t.Errorf("Elicit at EOF = %+v, %v; want cancel", result, err)
+	}
+	in = &Interaction{ElicitPolicy: PolicyPrompt, In: strings.NewReader("n\n"), Out: &out}
+	if result, err := in.Elicit(context.Background(), mcp.ElicitRequest{RequestedSchema: schema}); err != nil || result.Action != "decline" {
+		t.Errorf("Elicit answered no = %+v, %v; want decline", result, err)
+	}
+}
+
+func TestInteractionElicitPolicies(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"n": map[string]any{"type": "number", "default": 1}},
+	}
+	var out bytes.Buffer
+	in := &Interaction{ElicitPolicy: PolicyAccept, Out: &out}
+	result, err := in.Elicit(context.Background(), mcp.ElicitRequest{RequestedSchema: schema})
+	if err != nil || result.Action != "accept" || result.Content["n"] != 1.0 {
+		t.Errorf("accept = %+v, %v", result, err)
+	}
+	schema["required"] = []string{"m"}
+	schema["properties"].(map[string]any)["m"] = map[string]any{"type": "string"}
+	if result, err := in.Elicit(context.Background(), mcp.ElicitRequest{RequestedSchema: schema}); err != nil || result.Action != "decline" {
+		t.Errorf("accept without a required default = %+v, %v; want decline", result, err)
+	}
+
+	// URLs are opened only with consent, even when accepting by policy,
+	// and only for http and https.
+	var opened string
+	open := func(u string) error { opened = u; return nil }
+	in = &Interaction{ElicitPolicy: PolicyAccept, In: strings.NewReader("n\ny\n"), Out: &out, OpenURL: open}
+	result, err = in.Elicit(context.Background(), mcp.ElicitRequest{Mode: "url", URL: "https://example.com/auth", ElicitationID: "e1"})
+	if err != nil || result.Action != "decline" || opened != "" {
+		t.Errorf("url elicitation answered no = %+v, %v; opened %q", result, err, opened)
+	}
+	result, err = in.Elicit(context.Background(), mcp.ElicitRequest{Mode: "url", URL: "https://example.com/auth", ElicitationID: "e1"})
+	if err != nil || result.Action != "accept" || opened != "https://example.com/auth" {
+		t.Errorf("url elicitation = %+v, %v; opened %q", result, err, opened)
+	}
+	for _, u := range []string{"file:///etc/passwd", "javascript:alert(1)", "slack://open"} {
+		opened = ""
+		in = &Interaction{ElicitPolicy: PolicyAccept, In: strings.NewReader("y\n"), Out: &out, OpenURL: open}
+		if _, err := in.Elicit(context.Background(), mcp.ElicitRequest{Mode: "url", URL: u}); err == nil || opened != "" {
+			t.Errorf("url elicitation of %s: err = %v, opened %q", u, err, opened)
+		}
+	}
+
+	in = &Interaction{ElicitPolicy: PolicyDecline}
+	if result, err := in.Elicit(context.Background(), mcp.ElicitRequest{RequestedSchema: schema}); err != nil || result.Action != "decline" {
+		t.Errorf("decline = %+v, %v", result, err)
+	}
+}
+
+// signalWriter reports on prompted each time it is written to.
+type signalWriter struct{ prompted chan struct{} }
+
+func (w signalWriter) Write(p []byte) (int, error) {
+	select {
+	case w.prompted <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+func TestInteractionElicitUnlocksWhileAsking(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	out := signalWriter{make(chan struct{}, 1)}
+	in := &Interaction{ElicitPolicy: PolicyPrompt, In: pr, Out: out}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		in.Elicit(context.Background(), mcp.ElicitRequest{Mode: "url", URL: "https://example.com/auth", ElicitationID: "e1"})
+	}()
+	<-out.prompted
+	// Completion notifications are handled while the user is asked.
+	if !in.mu.TryLock() {
+		t.Fatal("Elicit holds the state lock while waiting for input")
+	}
+	in.mu.Unlock()
+	pw.Close()
+	<-done
+}
+
+func TestInteractionElicitCancelled(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	out := signalWriter{make(chan struct{}, 1)}
+	in := &Interaction{ElicitPolicy: PolicyPrompt, In: pr, Out: out}
+	req := mcp.ElicitRequest{RequestedSchema: map[string]any{"type": "object"}}
+
+	// A cancelled request stops waiting for the user and lets the next one
+	// ask.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := in.Elicit(ctx, req)
+		done <- err
+	}()
+	<-out.prompted
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled Elicit = %v, want context.Canceled", err)
+	}
+	go func() { io.WriteString(pw, "n\n") }()
+	if result, err := in.Elicit(context.Background(), req); err != nil || result.Action != "decline" {
+		t.Errorf("Elicit after a cancelled one = %+v, %v; want decline", result, err)
+	}
+}
+
+func TestInteractionSample(t *testing.T) {
+	req := mcp.CreateMessageRequest{
+		Messages:  []mcp.SamplingMessage{{Role: "user", Content: mcp.TextContent{Type: "text", Text: "hi"}}},
+		MaxTokens: 10,
+	}
+	in := &Interaction{SamplingPolicy: PolicyAccept, SamplingCmd: "wc -c | tr -d ' '"}
+	result, err := in.Sample(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(req)
+	if text := result.Content.(mcp.TextContent).Text; text != strconv.Itoa(len(data)) {
+		t.Errorf("command reply = %q, want the length of the request %d", text, len(data))
+	}
+	if result.Model != "wc" {
+		t.Errorf("command reply model = %q, want the program name wc", result.Model)
+	}
+	for command, want := range map[string]string{"/usr/local/bin/llm --key s3cret": "llm", "KEY=s3cret llm": "external", "": "external"} {
+		if got := samplingModel(command); got != want {
+			t.Errorf("samplingModel(%q) = %q, want %q", command, got, want)
+		}
+	}
+
+	in = &Interaction{SamplingPolicy: PolicyAccept, SamplingCmd: `echo '{"content": {"type": "text", "text": "ok"}, "model": "m"}'`}
+	if result, err := in.Sample(context.Background(), req); err != nil || result.Model != "m" || result.Role != "assistant" {
+		t.Errorf("JSON reply = %+v, %v", result, err)
+	}
+
+	var out bytes.Buffer
+	in = &Interaction{SamplingPolicy: PolicyPrompt, In: strings.NewReader("hello back\n"), Out: &out}
+	result, err = in.Sample(context.Background(), req)
+	if err != nil || result.Content.(mcp.TextContent).Text != "hello back" {
+		t.Errorf("typed reply = %+v, %v", result, err)
+	}
+	if !strings.Contains(out.String(), "[user]\nhi") {
+		t.Errorf("request not shown:\n%s", out.String())
+	}
+	in = &Interaction{SamplingPolicy: PolicyPrompt, SamplingCmd: "echo no", In: strings.NewReader("n\n"), Out: &out}
+	if _, err := in.Sample(context.Background(), req); err == nil {
+		t.Error("declined sampling succeeded")
+	}
+}
@@ -0,0 +1,297 @@
+package mcpcli
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+// MCPJSON is the server list of a .mcp.json file.
+type MCPJSON struct {
+	MCPServers map[string]ServerEntry `json:"mcpServers"`
+}
+
+// ServerEntry is one server in a .mcp.json file. A server is either a
+// command speaking stdio or a URL. Type selects the URL transport: "http"
+// (streamable HTTP, the default), "sse" or "ws"; ws:// and wss:// URLs imply
+// "ws". ${VAR} references in args, env, cwd, url, headers and auth are
+// expanded from the environment, or for entries LoadProfiles reads from the
+// user's profiles, from the environment and then the secrets file.
+type ServerEntry struct {
+	Type     string            `json:"type,omitempty"`
+	Command  string            `json:"command,omitempty"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	Cwd      string            `json:"cwd,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Auth     *ServerAuth       `json:"auth,omitempty"`
+	Timeout  string            `json:"timeout,omitempty"` // request timeout, such as "45s"
+	Disabled bool              `json:"disabled,omitempty"`
+
+	lookup func(string) (string, bool) // variables; nil means the environment
+}
+
+// ServerAuth sets the Authorization header of a URL server: "bearer" sends
+// Token, "basic" sends Username and Password.
+type ServerAuth struct {
+	Type     string `json:"type"`
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// FindMCPJSON walks up from dir looking for .mcp.json and returns its path,
+// or "" if there is none.
+func FindMCPJSON(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, ".mcp.json")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadMCPJSON reads and parses a .mcp.json file.
+func LoadMCPJSON(path string) (*MCPJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	var cfg MCPJSON
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Enabled returns the sorted names of servers that are not disabled.
+func (f *MCPJSON) Enabled() []string {
+	var names []string
+	for name, entry := range f.MCPServers {
+		if !entry.Disabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Transport returns a transport for the server. Command output on stderr is
+// copied to stderr.
+func (e ServerEntry) Transport(stderr io.Writer) (mcp.Transport, error) {
+	rawURL := e.expand(e.URL)
+	switch {
+	case e.Command != "" && e.URL != "":
+		return nil, errors.New("server has both command and url")
+	case e.Command != "":
+		if e.Auth != nil || len(e.Headers) > 0 {
+			return nil, errors.New("headers and auth are only supported for url servers")
+		}
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = e.expand(arg)
+		}
+		env := os.Environ()
+		for k, v := range e.Env {
+			env = append(env, k+"="+e.expand(v))
+		}
+		return execTransport(e.Command, args, env, e.expand(e.Cwd), stderr), nil
+	case e.URL == "":
+		return nil, errors.New("server has no command or url")
+	}
+
+	header, err := e.header()
+	if err != nil {
+		return nil, err
+	}
+	kind := e.Type
+	if kind == "" && (strings.HasPrefix(rawURL, "ws://") || strings.HasPrefix(rawURL, "wss://")) {
+		kind = "ws"
+	}
+	switch kind {
+	case "", "http", "streamable-http", "streamableHttp":
+		client := &http.Client{Transport: headerRoundTripper{header: header, next: http.DefaultTransport}}
+		return mcp.NewStreamableClientTransport(rawURL, &mcp.StreamableClientConfig{HTTPClient: client}), nil
+	case "sse":
+		if len(header) > 0 {
+			return nil, errors.New("headers are not supported for sse servers")
+		}
+		return mcp.NewSSEClientTransport(rawURL, nil)
+	case "ws", "websocket":
+		t, err := mcp.NewWebSocketTransport(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		for k := range header {
+			t.WithHeader(k, header.Get(k))
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unknown server type %q", e.Type)
+	}
+}
+
+// RequestTimeout returns the parsed Timeout, or 0 if none is set.
+func (e ServerEntry) RequestTimeout() (time.Duration, error) {
+	if e.Timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(e.expand(e.Timeout))
+	if err != nil {
+		return 0, fmt.Errorf("parse timeout: %w", err)
+	}
+	return d, nil
+}
+
+// Redacted returns a copy of e for display, with the values of its headers
+// and environment and its auth token and password replaced by
+// mcp.DefaultRedactionPlaceholder.
+func (e ServerEntry) Redacted() ServerEntry {
+	redact := func(m map[string]string) map[string]string {
+		if m == nil {
+			return nil
+		}
+		out := make(map[string]string, len(m))
+		for k := range m {
+			out[k] = mcp.DefaultRedactionPlaceholder
+		}
+		return out
+	}
+	e.Headers = redact(e.Headers)
+	e.Env = redact(e.Env)
+	if e.Auth != nil {
+		auth := *e.Auth
+		if auth.Token != "" {
+			auth.Token = mcp.DefaultRedactionPlaceholder
+		}
+		if auth.Password != "" {
+			auth.Password = mcp.DefaultRedactionPlaceholder
+		}
+		e.Auth = &auth
+	}
+	return e
+}
+
+// MissingVars returns the sorted names of variables the entry references
+// that have no value.
+func (e ServerEntry) MissingVars() []string {
+	missing := make(map[string]bool)
+	check := func(s string) {
+		os.Expand(s, func(name string) string {
+			if _, ok := e.lookupVar(name); !ok {
+				missing[name] = true
+			}
+			return ""
+		})
+	}
+	strs := append([]string{e.Cwd, e.URL, e.Timeout}, e.Args...)
+	for _, v := range e.Env {
+		strs = append(strs, v)
+	}
+	for _, v := range e.Headers {
+		strs = append(strs, v)
+	}
+	if e.Auth != nil {
+		strs = append(strs, e.Auth.Token, e.Auth.Username, e.Auth.Password)
+	}
+	for _, s := range strs {
+		check(s)
+	}
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// header returns the expanded Headers with the Authorization header set by
+// Auth.
+func (e ServerEntry) header() (http.Header, error) {
+	header := make(http.Header)
+	for k, v := range e.Headers {
+		header.Set(k, e.expand(v))
+	}
+	if e.Auth == nil {
+		return header, nil
+	}
+	switch strings.ToLower(e.Auth.Type) {
+	case "bearer":
+		header.Set("Authorization", "Bearer "+e.expand(e.Auth.Token))
+	case "basic":
+		credentials := e.expand(e.Auth.Username) + ":" + e.expand(e.Auth.Password)
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	default:
+		return nil, fmt.Errorf("unknown auth type %q", e.Auth.Type)
+	}
+	return header, nil
+}
+
+func (e ServerEntry) lookupVar(name string) (string, bool) {
+	if e.lookup == nil {
+		return os.LookupEnv(name)
+	}
+	return e.lookup(name)
+}
+
+func (e ServerEntry) expand(s string) string {
+	return os.Expand(s, func(name string) string {
+		v, _ := e.lookupVar(name)
+		return v
+	})
+}
+
+// ExecTransport starts name with args and env and uses its stdin/stdout as an
+// MCP transport. Unlike CommandTransport it does not go through a shell.
+func ExecTransport(name string, args, env []string, stderr io.Writer) mcp.Transport {
+	return execTransport(name, args, env, "", stderr)
+}
+
+// execTransport is ExecTransport running the command in dir.
+func execTransport(name string, args, env []string, dir string, stderr io.Writer) mcp.Transport {
+	return mcp.TransportFunc(func(ctx context.Context) (io.ReadWriteCloser, error) {
+		cmd := exec.Command(name, args...)
+		cmd.Env = env
+		cmd.Dir = dir
+		return startCmd(cmd, stderr)
+	})
+}
+
+// headerRoundTripper adds fixed headers to every request.
+type headerRoundTripper struct {
+	header http.Header
+	next   http.RoundTripper
+}
+
+func (t headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.header) == 0 {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for k, v := range t.header {
+		req.Header[k] = v
+	}
+	return t.next.RoundTrip(req)
+}
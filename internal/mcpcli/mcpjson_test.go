@@ -0,0 +1,65 @@
+package mcpcli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tmc/mcp"
+)
+
+func TestMCPJSON(t *testing.T) {
+	dir := t.TempDir()
+	config := `{"mcpServers": {
+		"github": {"command": "github-mcp", "args": ["--token", "${GH_TOKEN}"]},
+		"docs": {"url": "https://docs.example.com/mcp", "headers": {"Authorization": "Bearer ${DOCS_TOKEN}"}},
+		"live": {"url": "wss://live.example.com/mcp"},
+		"old": {"command": "old-mcp", "disabled": true}
+	}}`
+	if err := os.WriteFile(filepath.Join(dir, ".mcp.json"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := FindMCPJSON(sub)
+	if path != filepath.Join(dir, ".mcp.json") {
+		t.Fatalf("FindMCPJSON = %q", path)
+	}
+	file, err := LoadMCPJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(file.Enabled(), ","); got != "docs,github,live" {
+		t.Errorf("Enabled = %s", got)
+	}
+	for name, want := range map[string]any{
+		"github": mcp.TransportFunc(nil),
+		"docs":   &mcp.StreamableClientTransport{},
+		"live":   &mcp.WebSocketTransport{},
+	} {
+		transport, err := file.MCPServers[name].Transport(nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got, want := fmt.Sprintf("%T", transport), fmt.Sprintf("%T", want); got != want {
+			t.Errorf("%s: transport %s, want %s", name, got, want)
+		}
+	}
+
+	for _, bad := range []ServerEntry{
+		{},
+		{Command: "x", URL: "http://x"},
+		{URL: "http://x", Type: "carrier-pigeon"},
+		{URL: "http://x", Type: "sse", Headers: map[string]string{"A": "b"}},
+		{URL: "http://x", Auth: &ServerAuth{Type: "magic"}},
+		{Command: "x", Auth: &ServerAuth{Type: "bearer"}},
+	} {
+		if _, err := bad.Transport(nil); err == nil {
+			t.Errorf("Transport(%+v) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package mcpcli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Profiles are the named servers commands select with --server. They are
+// read from mcp.json in the state directory and from the project's .mcp.json,
+// the nearest one above the working directory; a project server replaces a
+// user server of the same name.
+type Profiles struct {
+	Servers map[string]ServerEntry
+	Sources map[string]string // file each server was read from
+	Files   []string          // files read, lowest precedence first
+}
+
+// UserProfilesPath returns the path of the user's server profiles.
+func UserProfilesPath(stateDir string) string {
+	return filepath.Join(stateDir, "mcp.json")
+}
+
+// SecretsPath returns the path of the secrets file. Each line is NAME=value;
+// blank lines and lines starting with # are ignored, and double-quoted values
+// are unquoted.
+func SecretsPath(stateDir string) string {
+	return filepath.Join(stateDir, "secrets.env")
+}
+
+// LoadProfiles reads the server profiles. If path is not empty only that file
+// is read. Variables in the profiles are expanded from the environment and
+// then, for the user's profiles or the file at path, from the secrets file
+// in stateDir. A project's .mcp.json may come from any directory above the
+// working one, so its profiles see only the environment.
+func LoadProfiles(stateDir, path string) (*Profiles, error) {
+	var files []string
+	trusted := make(map[string]bool) // files whose profiles may use secrets
+	if path != "" {
+		files = []string{path}
+		trusted[path] = true
+	} else {
+		if _, err := os.Stat(UserProfilesPath(stateDir)); err == nil {
+			files = append(files, UserProfilesPath(stateDir))
+			trusted[UserProfilesPath(stateDir)] = true
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		if project := FindMCPJSON(cwd); project != "" {
+			files = append(files, project)
+		}
+	}
+	secrets, err := loadSecrets(SecretsPath(stateDir))
+	if err != nil {
+		return nil, err
+	}
+	withSecrets := func(name string) (string, bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			return v, true
+		}
+		v, ok := secrets[name]
+		return v, ok
+	}
+
+	p := &Profiles{
+		Servers: make(map[string]ServerEntry),
+		Sources: make(map[string]string),
+	}
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := LoadMCPJSON(abs)
+		if err != nil {
+			return nil, err
+		}
+		p.Files = append(p.Files, abs)
+		lookup := os.LookupEnv
+		if trusted[file] {
+			lookup = withSecrets
+		}
+		for name, entry := range cfg.MCPServers {
+			entry.lookup = lookup
+			// A relative cwd is relative to the file that sets it.
+			if entry.Cwd != "" && !filepath.IsAbs(entry.expand(entry.Cwd)) {
+				entry.Cwd = filepath.Join(filepath.Dir(abs), entry.Cwd)
+			}
+			p.Servers[name] = entry
+			p.Sources[name] = abs
+		}
+	}
+	return p, nil
+}
+
+// Names returns the sorted names of all servers, including disabled ones.
+func (p *Profiles) Names() []string {
+	names := make([]string, 0, len(p.Servers))
+	for name := range p.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enabled returns the sorted names of servers that are not disabled.
+func (p *Profiles) Enabled() []string {
+	var names []string
+	for _, name := range p.Names() {
+		if !p.Servers[name].Disabled {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Lookup returns the enabled server called name.
+func (p *Profiles) Lookup(name string) (ServerEntry, error) {
+	entry, ok := p.Servers[name]
+	switch {
+	case len(p.Files) == 0:
+		return ServerEntry{}, fmt.Errorf("no server %q: no .mcp.json found", name)
+	case !ok:
+		return ServerEntry{}, fmt.Errorf("no server %q in %s; available: %s", name, strings.Join(p.Files, ", "), strings.Join(p.Enabled(), ", "))
+	case entry.Disabled:
+		return ServerEntry{}, fmt.Errorf("server %q is disabled in %s", name, p.Sources[name])
+	}
+	return entry, nil
+}
+
+// loadSecrets reads the secrets file at path. A missing file has no secrets.
+func loadSecrets(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	secrets := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: want NAME=value", path, line)
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			if value, err = strconv.Unquote(value); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+		}
+		secrets[strings.TrimSpace(name)] = value
+	}
+	return secrets, scanner.Err()
+}
@@ -0,0 +1,153 @@
+package mcpcli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadProfiles(t *testing.T) {
+	stateDir := t.TempDir()
+	project := t.TempDir()
+	writeFile := func(path, data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(UserProfilesPath(stateDir), `{"mcpServers": {
+		"docs": {"url": "https://user.example.com/mcp"},
+		"github": {"url": "https://api.example.com/mcp", "auth": {"type": "bearer", "token": "${MCPCLI_TEST_TOKEN}"}, "timeout": "45s"}
+	}}`)
+	writeFile(filepath.Join(project, ".mcp.json"), `{"mcpServers": {
+		"docs": {"url": "https://project.example.com/mcp", "headers": {"X-Team": "${TEAM}", "X-Token": "${MCPCLI_TEST_TOKEN}"}},
+		"local": {"command": "server", "cwd": "tools"},
+		"old": {"command": "old", "disabled": true}
+	}}`)
+	writeFile(SecretsPath(stateDir), "# tokens\nMCPCLI_TEST_TOKEN = \"from secrets\"\nTEAM=secret-team\n")
+	t.Setenv("TEAM", "env-team")
+	t.Chdir(project)
+
+	profiles, err := LoadProfiles(stateDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(profiles.Enabled(), ","); got != "docs,github,local" {
+		t.Errorf("Enabled = %s", got)
+	}
+	if got := profiles.Sources["docs"]; got != filepath.Join(project, ".mcp.json") {
+		t.Errorf("docs read from %s, want the project file", got)
+	}
+	if got := profiles.Servers["local"].Cwd; got != filepath.Join(project, "tools") {
+		t.Errorf("local cwd = %s", got)
+	}
+
+	github, err := profiles.Lookup("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := github.header()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("Authorization"); got != "Bearer from secrets" {
+		t.Errorf("github Authorization = %q", got)
+	}
+	if d, err := github.RequestTimeout(); err != nil || d != 45*time.Second {
+		t.Errorf("github timeout = %v, %v", d, err)
+	}
+	// TEAM is set in both the environment and the secrets file.
+	header, err = profiles.Servers["docs"].header()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("X-Team"); got != "env-team" {
+		t.Errorf("docs X-Team = %q", got)
+	}
+	// A project file cannot read the secrets file.
+	if got := header.Get("X-Token"); got != "" {
+		t.Errorf("project docs X-Token = %q, want no secret", got)
+	}
+	if got := strings.Join(profiles.Servers["docs"].MissingVars(), ","); got != "MCPCLI_TEST_TOKEN" {
+		t.Errorf("project docs MissingVars = %s, want MCPCLI_TEST_TOKEN", got)
+	}
+
+	for _, name := range []string{"old", "missing"} {
+		if _, err := profiles.Lookup(name); err == nil {
+			t.Errorf("Lookup(%q) succeeded", name)
+		}
+	}
+
+	// An explicit file replaces both locations.
+	only, err := LoadProfiles(stateDir, UserProfilesPath(stateDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(only.Names(), ","); got != "docs,github" {
+		t.Errorf("Names with an explicit file = %s", got)
+	}
+}
+
+func TestServerEntryMissingVars(t *testing.T) {
+	t.Setenv("SET_VAR", "x")
+	entry := ServerEntry{
+		Command: "server",
+		Args:    []string{"--a", "$SET_VAR", "${UNSET_B}"},
+		Env:     map[string]string{"TOKEN": "${UNSET_A}"},
+	}
+	if got := strings.Join(entry.MissingVars(), ","); got != "UNSET_A,UNSET_B" {
+		t.Errorf("MissingVars = %s", got)
+	}
+}
+
+func TestServerEntryRedacted(t *testing.T) {
+	entry := ServerEntry{
+		URL:     "https://example.com/mcp",
+		Headers: map[string]string{"X-Api-Key": "k3y"},
+		Env:     map[string]string{"TOKEN": "t0ken"},
+		Auth:    &ServerAuth{Type: "basic", Username: "ann", Password: "pa55"},
+	}
+	data, err := json.Marshal(entry.Redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"k3y", "t0ken", "pa55"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("redacted entry %s contains %q", data, secret)
+		}
+	}
+	if !strings.Contains(string(data), `"username":"ann"`) || !strings.Contains(string(data), `"X-Api-Key":"[REDACTED]"`) {
+		t.Errorf("redacted entry %s lost the username or header name", data)
+	}
+	if entry.Headers["X-Api-Key"] != "k3y" || entry.Auth.Password != "pa55" {
+		t.Error("Redacted changed the original entry")
+	}
+}
+
+func TestConnectServerProfile(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stateDir := t.TempDir()
+	config := filepath.Join(stateDir, "servers.json")
+	data := `{"mcpServers": {"counter": {"command": ` + strconv.Quote(exe) + `, "env": {"` + runAsServerEnv + `": "1"}}}}`
+	if err := os.WriteFile(config, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sess, err := Connect(ctx, Config{Server: "counter", ConfigFile: config, StateDir: stateDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	if got := sess.InitializeResult().ServerInfo.Name; got != "counter" {
+		t.Errorf("server name = %q, want counter", got)
+	}
+}
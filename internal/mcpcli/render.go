@@ -105,18 +105,11 @@ func textOnlyResult(result *mcp.CallToolResult) string {
 	}
 	lines := make([]string, 0, len(result.Content))
 	for _, item := range result.Content {
-		m, ok := item.(map[string]any)
+		text, ok := item.(mcp.TextContent)
 		if !ok {
 			return ""
 		}
-		if kind, _ := m["type"].(string); kind != "text" {
-			return ""
-		}
-		text, ok := m["text"].(string)
-		if !ok {
-			return ""
-		}
-		lines = append(lines, text)
+		lines = append(lines, text.Text)
 	}
 	return strings.Join(lines, "\n")
 }
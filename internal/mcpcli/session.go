@@ -19,6 +19,9 @@ type Config struct {
 	Cmd             string
 	HTTPURL         string
 	SSEURL          string
+	Session         string // name of a background session started by ServeSession
+	Server          string // name of a server profile, see LoadProfiles
+	ConfigFile      string // profiles file to read instead of the default locations
 	Timeout         time.Duration
 	ProtocolVersion string
 	ServerStderr    bool
@@ -70,7 +73,7 @@ func Connect(ctx context.Context, cfg Config) (*Session, error) {
 		return nil, errors.New("no server transport configured")
 	}
 	if cfg.transportCount() > 1 {
-		return nil, errors.New("choose exactly one of stdio, http, or sse transport")
+		return nil, errors.New("choose exactly one of stdio, http, sse, server, or session transport")
 	}
 
 	store, err := OpenStateStore(cfg.StateDir)
@@ -168,6 +171,12 @@ func (cfg Config) transportCount() int {
 	if cfg.SSEURL != "" {
 		n++
 	}
+	if cfg.Session != "" {
+		n++
+	}
+	if cfg.Server != "" {
+		n++
+	}
 	return n
 }
 
@@ -179,6 +188,22 @@ func newTransport(cfg Config) (mcp.Transport, error) {
 		return mcp.NewSSEClientTransport(cfg.SSEURL, nil)
 	case cfg.HTTPURL != "":
 		return mcp.NewStreamableClientTransport(cfg.HTTPURL, nil), nil
+	case cfg.Session != "":
+		return SessionTransport(cfg.StateDir, cfg.Session), nil
+	case cfg.Server != "":
+		profiles, err := LoadProfiles(cfg.StateDir, cfg.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := profiles.Lookup(cfg.Server)
+		if err != nil {
+			return nil, err
+		}
+		transport, err := entry.Transport(serverStderr(cfg.ServerStderr))
+		if err != nil {
+			return nil, fmt.Errorf("server %q: %w", cfg.Server, err)
+		}
+		return transport, nil
 	default:
 		return nil, errors.New("no server transport configured")
 	}
@@ -303,6 +328,26 @@ func (s *Session) ListPromptsAll(ctx context.Context) ([]mcp.Prompt, error) {
 	return all, nil
 }
 
+// ListResourceTemplatesAll retrieves every page of resource templates and
+// returns them sorted by URI template.
+func (s *Session) ListResourceTemplatesAll(ctx context.Context) ([]mcp.ResourceTemplate, error) {
+	cursor := ""
+	var all []mcp.ResourceTemplate
+	for {
+		result, err := s.client.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{Cursor: cursor})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, result.Templates...)
+		if result.NextCursor == "" || result.NextCursor == cursor {
+			break
+		}
+		cursor = result.NextCursor
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].URITemplate < all[j].URITemplate })
+	return all, nil
+}
+
 // CallRaw invokes an arbitrary method and unmarshals into out when non-nil.
 func (s *Session) CallRaw(ctx context.Context, method string, params any, out any) error {
 	if s.client == nil {
@@ -56,6 +56,10 @@ func startCommand(ctx context.Context, command string, stderr io.Writer) (io.Rea
 	} else {
 		cmd = exec.CommandContext(ctx, "sh", "-lc", command)
 	}
+	return startCmd(cmd, stderr)
+}
+
+func startCmd(cmd *exec.Cmd, stderr io.Writer) (io.ReadWriteCloser, error) {
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, err
@@ -0,0 +1,415 @@
+package mcpspy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/tmc/mcp"
+)
+
+// ContractChange is one difference between a saved spec and a server.
+type ContractChange struct {
+	Kind     string `json:"kind"` // "tool", "resource" or "prompt"
+	Name     string `json:"name"`
+	Path     string `json:"path,omitempty"` // location inside the item, such as "input.properties.city"
+	Breaking bool   `json:"breaking"`
+	Message  string `json:"message"`
+}
+
+func (c ContractChange) String() string {
+	level := "ok"
+	if c.Breaking {
+		level = "BREAKING"
+	}
+	where := c.Kind + " " + c.Name
+	if c.Path != "" {
+		where += " " + c.Path
+	}
+	return fmt.Sprintf("%s: %s: %s", level, where, c.Message)
+}
+
+// ContractReport lists the differences found by CheckContract, breaking
+// changes first.
+type ContractReport struct {
+	Server  SpecServer       `json:"server"`
+	Changes []ContractChange `json:"changes"`
+}
+
+// Breaking returns the breaking changes in the report.
+func (r ContractReport) Breaking() []ContractChange {
+	var out []ContractChange
+	for _, c := range r.Changes {
+		if c.Breaking {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Text renders the report for people, one change per line.
+func (r ContractReport) Text() string {
+	var b strings.Builder
+	if len(r.Changes) == 0 {
+		b.WriteString("no changes\n")
+		return b.String()
+	}
+	for _, c := range r.Changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "%d breaking, %d non-breaking\n", len(r.Breaking()), len(r.Changes)-len(r.Breaking()))
+	return b.String()
+}
+
+// LoadSpecDocument reads a .mcpspec file.
+func LoadSpecDocument(path string) (SpecDocument, error) {
+	var doc SpecDocument
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return doc, fmt.Errorf("read spec: %w", err)
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return doc, fmt.Errorf("parse spec %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// SpecFromClient builds a SpecDocument from the declared surface of the
+// server client is connected to. Tool output schemas become return types.
+// Lists the server does not support are left empty.
+func SpecFromClient(ctx context.Context, client *mcp.Client, info mcp.Implementation) (SpecDocument, error) {
+	doc := SpecDocument{
+		SpecVersion: specDocumentVersion,
+		Server:      SpecServer{Name: info.Name, Version: info.Version},
+	}
+	var cursor string
+	for {
+		res, err := client.ListTools(ctx, mcp.ListToolsRequest{Cursor: cursor})
+		if err != nil {
+			return doc, fmt.Errorf("list tools: %w", err)
+		}
+		for _, tool := range res.Tools {
+			doc.Tools = append(doc.Tools, SpecTool{
+				Name:        tool.Name,
+				Description: tool.Description,
+				InputSchema: tool.InputSchema,
+				ReturnType:  tool.OutputSchema,
+			})
+		}
+		if res.NextCursor == "" || res.NextCursor == cursor {
+			break
+		}
+		cursor = res.NextCursor
+	}
+	for cursor = ""; ; {
+		res, err := client.ListResources(ctx, mcp.ListResourcesRequest{Cursor: cursor})
+		if err != nil {
+			if isMethodNotFound(err) {
+				break
+			}
+			return doc, fmt.Errorf("list resources: %w", err)
+		}
+		for _, r := range res.Resources {
+			doc.Resources = append(doc.Resources, SpecResource{URI: r.URI, Name: r.Name, Description: r.Description, MimeType: r.MimeType})
+		}
+		if res.NextCursor == "" || res.NextCursor == cursor {
+			break
+		}
+		cursor = res.NextCursor
+	}
+	for cursor = ""; ; {
+		res, err := client.ListPrompts(ctx, mcp.ListPromptsRequest{Cursor: cursor})
+		if err != nil {
+			if isMethodNotFound(err) {
+				break
+			}
+			return doc, fmt.Errorf("list prompts: %w", err)
+		}
+		for _, p := range res.Prompts {
+			prompt := SpecPrompt{Name: p.Name, Description: p.Description}
+			for _, arg := range p.Arguments {
+				prompt.Arguments = append(prompt.Arguments, SpecPromptArgument{Name: arg.Name, Description: arg.Description, Required: arg.Required})
+			}
+			doc.Prompts = append(doc.Prompts, prompt)
+		}
+		if res.NextCursor == "" || res.NextCursor == cursor {
+			break
+		}
+		cursor = res.NextCursor
+	}
+	sort.Slice(doc.Tools, func(i, j int) bool { return doc.Tools[i].Name < doc.Tools[j].Name })
+	sort.Slice(doc.Resources, func(i, j int) bool { return doc.Resources[i].URI < doc.Resources[j].URI })
+	sort.Slice(doc.Prompts, func(i, j int) bool { return doc.Prompts[i].Name < doc.Prompts[j].Name })
+	return doc, nil
+}
+
+func isMethodNotFound(err error) bool {
+	re, ok := mcp.AsResponseError(err)
+	return ok && re.Code == -32601
+}
+
+// CheckContract compares the surface of a server, live, against a saved
+// spec and classifies each difference. Removing a tool, resource or prompt,
+// adding a required argument, making an argument required, removing an
+// input property, narrowing an input type or enum, and changing or widening
+// an output type are breaking; additions and description changes are not.
+// Return types inferred from traffic are only compared with declared ones
+// when both documents have them.
+func CheckContract(saved, live SpecDocument) ContractReport {
+	report := ContractReport{Server: live.Server}
+	add := func(c ContractChange) { report.Changes = append(report.Changes, c) }
+
+	liveTools := make(map[string]SpecTool)
+	for _, tool := range live.Tools {
+		liveTools[tool.Name] = tool
+	}
+	for _, old := range saved.Tools {
+		tool, ok := liveTools[old.Name]
+		if !ok {
+			add(ContractChange{Kind: "tool", Name: old.Name, Breaking: true, Message: "removed"})
+			continue
+		}
+		delete(liveTools, old.Name)
+		if old.Description != tool.Description {
+			add(ContractChange{Kind: "tool", Name: old.Name, Message: "description changed"})
+		}
+		cmp := schemaComparison{kind: "tool", name: old.Name, add: add}
+		cmp.compare("input", decodeSchema(old.InputSchema), decodeSchema(tool.InputSchema), true)
+		if len(old.ReturnType) > 0 && len(tool.ReturnType) > 0 {
+			cmp.compare("output", decodeSchema(old.ReturnType), decodeSchema(tool.ReturnType), false)
+		}
+	}
+	for _, name := range sortedKeys(liveTools) {
+		add(ContractChange{Kind: "tool", Name: name, Message: "added"})
+	}
+
+	liveResources := make(map[string]SpecResource)
+	for _, r := range live.Resources {
+		liveResources[r.URI] = r
+	}
+	for _, old := range saved.Resources {
+		r, ok := liveResources[old.URI]
+		if !ok {
+			add(ContractChange{Kind: "resource", Name: old.URI, Breaking: true, Message: "removed"})
+			continue
+		}
+		delete(liveResources, old.URI)
+		if old.MimeType != "" && r.MimeType != old.MimeType {
+			add(ContractChange{Kind: "resource", Name: old.URI, Breaking: true, Message: fmt.Sprintf("MIME type changed from %q to %q", old.MimeType, r.MimeType)})
+		}
+	}
+	for _, uri := range sortedKeys(liveResources) {
+		add(ContractChange{Kind: "resource", Name: uri, Message: "added"})
+	}
+
+	livePrompts := make(map[string]SpecPrompt)
+	for _, p := range live.Prompts {
+		livePrompts[p.Name] = p
+	}
+	for _, old := range saved.Prompts {
+		p, ok := livePrompts[old.Name]
+		if !ok {
+			add(ContractChange{Kind: "prompt", Name: old.Name, Breaking: true, Message: "removed"})
+			continue
+		}
+		delete(livePrompts, old.Name)
+		args := make(map[string]SpecPromptArgument)
+		for _, arg := range p.Arguments {
+			args[arg.Name] = arg
+		}
+		for _, oldArg := range old.Arguments {
+			arg, ok := args[oldArg.Name]
+			path := "arguments." + oldArg.Name
+			switch {
+			case !ok:
+				add(ContractChange{Kind: "prompt", Name: old.Name, Path: path, Breaking: true, Message: "argument removed"})
+			case arg.Required && !oldArg.Required:
+				add(ContractChange{Kind: "prompt", Name: old.Name, Path: path, Breaking: true, Message: "argument became required"})
+			case !arg.Required && oldArg.Required:
+				add(ContractChange{Kind: "prompt", Name: old.Name, Path: path, Message: "argument became optional"})
+			}
+			delete(args, oldArg.Name)
+		}
+		for _, name := range sortedKeys(args) {
+			c := ContractChange{Kind: "prompt", Name: old.Name, Path: "arguments." + name, Message: "optional argument added"}
+			if args[name].Required {
+				c.Breaking, c.Message = true, "required argument added"
+			}
+			add(c)
+		}
+	}
+	for _, name := range sortedKeys(livePrompts) {
+		add(ContractChange{Kind: "prompt", Name: name, Message: "added"})
+	}
+
+	sort.SliceStable(report.Changes, func(i, j int) bool {
+		return report.Changes[i].Breaking && !report.Changes[j].Breaking
+	})
+	return report
+}
+
+// schemaComparison compares the JSON schemas of one item.
+type schemaComparison struct {
+	kind string
+	name string
+	add  func(ContractChange)
+}
+
+func (c schemaComparison) report(path string, breaking bool, format string, args ...any) {
+	c.add(ContractChange{Kind: c.kind, Name: c.name, Path: path, Breaking: breaking, Message: fmt.Sprintf(format, args...)})
+}
+
+// compare reports differences between old and new. For inputs the server
+// must keep accepting everything it accepted; for outputs it must not
+// return anything clients have not seen before.
+func (c schemaComparison) compare(path string, old, new map[string]any, input bool) {
+	if old == nil || new == nil {
+		return
+	}
+	oldTypes, newTypes := schemaTypeSet(old), schemaTypeSet(new)
+	if len(oldTypes) > 0 && len(newTypes) > 0 && !slices.Equal(oldTypes, newTypes) {
+		narrower := !coversTypes(newTypes, oldTypes)
+		wider := !coversTypes(oldTypes, newTypes)
+		breaking := input && narrower || !input && wider
+		c.report(path, breaking, "type changed from %s to %s", strings.Join(oldTypes, "|"), strings.Join(newTypes, "|"))
+	}
+
+	oldEnum, _ := old["enum"].([]any)
+	newEnum, _ := new["enum"].([]any)
+	switch {
+	case oldEnum == nil && newEnum != nil:
+		c.report(path, input, "enum added: %s", jsonList(newEnum))
+	case oldEnum != nil && newEnum == nil:
+		c.report(path, !input, "enum removed")
+	case oldEnum != nil:
+		if removed := enumMissing(oldEnum, newEnum); len(removed) > 0 {
+			c.report(path, input, "enum values removed: %s", jsonList(removed))
+		}
+		if added := enumMissing(newEnum, oldEnum); len(added) > 0 {
+			c.report(path, !input, "enum values added: %s", jsonList(added))
+		}
+	}
+
+	oldProps, _ := old["properties"].(map[string]any)
+	newProps, _ := new["properties"].(map[string]any)
+	oldRequired, newRequired := stringSet(old["required"]), stringSet(new["required"])
+	for _, name := range sortedKeys(oldProps) {
+		propPath := path + ".properties." + name
+		newProp, ok := newProps[name]
+		if !ok {
+			if newProps != nil || !input {
+				c.report(propPath, true, "property removed")
+			}
+			continue
+		}
+		if input && newRequired[name] && !oldRequired[name] {
+			c.report(propPath, true, "property became required")
+		}
+		if !input && oldRequired[name] && !newRequired[name] {
+			c.report(propPath, true, "property is no longer always returned")
+		}
+		oldProp, _ := oldProps[name].(map[string]any)
+		newPropSchema, _ := newProp.(map[string]any)
+		c.compare(propPath, oldProp, newPropSchema, input)
+	}
+	for _, name := range sortedKeys(newProps) {
+		if _, ok := oldProps[name]; ok {
+			continue
+		}
+		propPath := path + ".properties." + name
+		switch {
+		case input && newRequired[name]:
+			c.report(propPath, true, "required property added")
+		case input:
+			c.report(propPath, false, "optional property added")
+		default:
+			c.report(propPath, false, "property added")
+		}
+	}
+
+	oldItems, _ := old["items"].(map[string]any)
+	newItems, _ := new["items"].(map[string]any)
+	c.compare(path+".items", oldItems, newItems, input)
+}
+
+func decodeSchema(raw json.RawMessage) map[string]any {
+	var schema map[string]any
+	if len(raw) == 0 || json.Unmarshal(raw, &schema) != nil {
+		return nil
+	}
+	return schema
+}
+
+// schemaTypeSet returns the sorted JSON types a schema allows.
+func schemaTypeSet(schema map[string]any) []string {
+	var types []string
+	switch t := schema["type"].(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// coversTypes reports whether every type in sub is allowed by super.
+// "number" covers "integer".
+func coversTypes(super, sub []string) bool {
+	for _, t := range sub {
+		if !slices.Contains(super, t) && !(t == "integer" && slices.Contains(super, "number")) {
+			return false
+		}
+	}
+	return true
+}
+
+// enumMissing returns the values of a that are not in b.
+func enumMissing(a, b []any) []any {
+	var out []any
+	for _, v := range a {
+		if !slices.ContainsFunc(b, func(w any) bool { return reflect.DeepEqual(v, w) }) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func jsonList(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		data, _ := json.Marshal(v)
+		parts[i] = string(data)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func stringSet(v any) map[string]bool {
+	set := make(map[string]bool)
+	list, _ := v.([]any)
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
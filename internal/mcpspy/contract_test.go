@@ -0,0 +1,99 @@
+package mcpspy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCheckContract(t *testing.T) {
+	saved := SpecDocument{
+		Tools: []SpecTool{
+			{
+				Name:        "search",
+				InputSchema: json.RawMessage(`{"type":"object","properties":{"q":{"type":"string"},"sort":{"type":"string","enum":["asc","desc"]},"limit":{"type":"integer"}},"required":["q"]}`),
+				ReturnType:  json.RawMessage(`{"type":"object","properties":{"total":{"type":"integer"},"items":{"type":"array","items":{"type":"string"}}},"required":["total"]}`),
+			},
+			{Name: "delete_repo", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		},
+		Resources: []SpecResource{
+			{URI: "docs://readme", MimeType: "text/markdown"},
+			{URI: "docs://old"},
+		},
+		Prompts: []SpecPrompt{
+			{Name: "summary", Arguments: []SpecPromptArgument{{Name: "topic"}, {Name: "style"}}},
+		},
+	}
+	live := SpecDocument{
+		Tools: []SpecTool{
+			{
+				Name:        "search",
+				Description: "Search issues",
+				InputSchema: json.RawMessage(`{"type":"object","properties":{"q":{"type":"string"},"sort":{"type":"string","enum":["asc"]},"limit":{"type":"number"},"org":{"type":"string"},"page":{"type":"integer"}},"required":["q","org"]}`),
+				ReturnType:  json.RawMessage(`{"type":"object","properties":{"total":{"type":"string"},"items":{"type":"array","items":{"type":"string"}},"next":{"type":"string"}},"required":["total"]}`),
+			},
+			{Name: "create_repo", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		},
+		Resources: []SpecResource{
+			{URI: "docs://readme", MimeType: "text/plain"},
+			{URI: "docs://new"},
+		},
+		Prompts: []SpecPrompt{
+			{Name: "summary", Arguments: []SpecPromptArgument{{Name: "topic", Required: true}, {Name: "length"}}},
+		},
+	}
+	report := CheckContract(saved, live)
+	want := []string{
+		`BREAKING: tool search input.properties.sort: enum values removed: "desc"`,
+		`BREAKING: tool search input.properties.org: required property added`,
+		`BREAKING: tool search output.properties.total: type changed from integer to string`,
+		`BREAKING: tool delete_repo: removed`,
+		`BREAKING: resource docs://readme: MIME type changed from "text/markdown" to "text/plain"`,
+		`BREAKING: resource docs://old: removed`,
+		`BREAKING: prompt summary arguments.topic: argument became required`,
+		`BREAKING: prompt summary arguments.style: argument removed`,
+		`ok: tool search: description changed`,
+		`ok: tool search input.properties.limit: type changed from integer to number`,
+		`ok: tool search input.properties.page: optional property added`,
+		`ok: tool search output.properties.next: property added`,
+		`ok: tool create_repo: added`,
+		`ok: resource docs://new: added`,
+		`ok: prompt summary arguments.length: optional argument added`,
+	}
+	var got []string
+	for _, c := range report.Changes {
+		got = append(got, c.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if n := len(report.Breaking()); n != 8 {
+		t.Errorf("Breaking() = %d changes, want 8", n)
+	}
+	if text := report.Text(); !strings.HasSuffix(text, "8 breaking, 7 non-breaking\n") {
+		t.Errorf("Text() = %q", text)
+	}
+
+	if report := CheckContract(saved, saved); len(report.Changes) != 0 {
+		t.Errorf("identical specs: %v", report.Changes)
+	}
+}
+
+func TestCheckContractOutputEnum(t *testing.T) {
+	tool := func(output string) SpecDocument {
+		return SpecDocument{Tools: []SpecTool{{Name: "status", ReturnType: json.RawMessage(output)}}}
+	}
+	report := CheckContract(
+		tool(`{"type":"string","enum":["open","closed"]}`),
+		tool(`{"type":["string","null"],"enum":["open"]}`),
+	)
+	var got []string
+	for _, c := range report.Changes {
+		got = append(got, c.String())
+	}
+	want := "BREAKING: tool status output: type changed from string to null|string\n" +
+		`ok: tool status output: enum values removed: "closed"`
+	if strings.Join(got, "\n") != want {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), want)
+	}
+}
@@ -21,6 +21,10 @@ type Options struct {
 	Name        string
 	SessionID   string
 	TimeNow     func() time.Time
+
+	// Redact, if set, rewrites each message before it is logged, buffered
+	// or published, typically with mcp.Redactor.RedactMessage.
+	Redact func([]byte) []byte
 }
 
 // Event is a structured view of a single captured message.
@@ -190,6 +194,9 @@ func (r *Recorder) record(dir string, chunk []byte, source string) {
 }
 
 func (r *Recorder) publish(dir string, raw []byte, source string) {
+	if r.opts.Redact != nil {
+		raw = r.opts.Redact(bytes.TrimSpace(raw))
+	}
 	now := r.opts.TimeNow()
 	line, parsed := formatEntry(dir, raw, now, r.opts)
 	ev := Event{
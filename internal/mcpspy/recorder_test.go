@@ -5,6 +5,8 @@ import (
 	"io"
 	"testing"
 	"time"
+
+	"github.com/tmc/mcp"
 )
 
 func TestRecorderSplitJSON(t *testing.T) {
@@ -31,6 +33,25 @@ func TestRecorderSplitJSON(t *testing.T) {
 	}
 }
 
+func TestRecorderRedact(t *testing.T) {
+	var log bytes.Buffer
+	rec := New(&log, Options{
+		TimeNow: func() time.Time { return time.Unix(1, 0) },
+		Redact:  mcp.DefaultRedactor().RedactMessage,
+	})
+	w := rec.Writer("recv", io.Discard)
+	if _, err := w.Write([]byte(`{"params":{"arguments":{"apiKey":"k-123"}}}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"params":{"arguments":{"apiKey":"[REDACTED]"}}}`
+	if got := string(rec.Snapshot()[0].Raw); got != want {
+		t.Fatalf("raw=%q, want %q", got, want)
+	}
+	if bytes.Contains(log.Bytes(), []byte("k-123")) {
+		t.Fatalf("log=%q", log.String())
+	}
+}
+
 func TestRecorderEvictsOldest(t *testing.T) {
 	rec := New(io.Discard, Options{
 		BufferSize: 2,
@@ -6,6 +6,8 @@ package mcp
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"strings"
 )
 
 // UnmarshalJSON implements custom unmarshaling for ReadResourceResult
@@ -36,9 +38,7 @@ func (rrr *ReadResourceResult) UnmarshalJSON(data []byte) error {
 }
 
 // unmarshalContent decodes a single polymorphic content block into a concrete
-// Content value based on its "type" discriminator. Resource content blocks are
-// returned as their raw JSON wrapped in a generic map so callers retain the data
-// without a dedicated embedded-resource type in this package.
+// Content value based on its "type" discriminator.
 func unmarshalContent(data json.RawMessage) (any, error) {
 	if string(data) == "null" || len(data) == 0 {
 		return nil, nil
@@ -69,9 +69,21 @@ func unmarshalContent(data json.RawMessage) (any, error) {
 			return nil, fmt.Errorf("unmarshaling AudioContent: %w", err)
 		}
 		return ac, nil
+	case "resource":
+		var er EmbeddedResource
+		if err := json.Unmarshal(data, &er); err != nil {
+			return nil, fmt.Errorf("unmarshaling EmbeddedResource: %w", err)
+		}
+		return er, nil
+	case "resource_link":
+		var rl ResourceLink
+		if err := json.Unmarshal(data, &rl); err != nil {
+			return nil, fmt.Errorf("unmarshaling ResourceLink: %w", err)
+		}
+		return rl, nil
 	default:
-		// Resource content and unknown future types are preserved as a generic
-		// object so no data is lost.
+		// Unknown future types are preserved as a generic object so no data
+		// is lost.
 		var generic map[string]any
 		if err := json.Unmarshal(data, &generic); err != nil {
 			return nil, fmt.Errorf("unmarshaling content (type %q): %w", probe.Type, err)
@@ -80,6 +92,174 @@ func unmarshalContent(data json.RawMessage) (any, error) {
 	}
 }
 
+// UnmarshalJSON decodes a ContentList, resolving each block to a concrete
+// content type. Blocks of unknown types, and blocks with keys their type
+// does not define, are kept as map[string]any.
+func (l *ContentList) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*l = nil
+		return nil
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("ContentList: %w", err)
+	}
+	list := make(ContentList, len(raw))
+	for i, item := range raw {
+		content, err := unmarshalContent(item)
+		if err != nil {
+			return fmt.Errorf("ContentList item %d: %w", i, err)
+		}
+		if _, ok := content.(map[string]any); !ok && hasUnknownKeys(item, content) {
+			var generic map[string]any
+			if err := json.Unmarshal(item, &generic); err != nil {
+				return fmt.Errorf("ContentList item %d: %w", i, err)
+			}
+			content = generic
+		}
+		list[i] = content
+	}
+	*l = list
+	return nil
+}
+
+// hasUnknownKeys reports whether the JSON object data has keys that the
+// struct v does not define a field for.
+func hasUnknownKeys(data []byte, v any) bool {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(data, &obj) != nil {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	known := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		known[name] = true
+	}
+	for key := range obj {
+		if !known[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON decodes an EmbeddedResource, resolving the Resource field to
+// text or blob contents.
+func (r *EmbeddedResource) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	type Alias EmbeddedResource
+	aux := &struct {
+		Resource json.RawMessage `json:"resource"`
+		*Alias
+	}{Alias: (*Alias)(r)}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return fmt.Errorf("EmbeddedResource base: %w", err)
+	}
+	rc, err := unmarshalResourceContentsInternal(aux.Resource)
+	if err != nil {
+		return fmt.Errorf("EmbeddedResource.Resource: %w", err)
+	}
+	r.Resource = rc
+	return nil
+}
+
+// ParseReference returns the completion reference v describes. v may be a
+// PromptReference or ResourceReference, a pointer to one, a map such as
+// {"type": "ref/prompt", "name": "review"}, or the JSON encoding of one as
+// a json.RawMessage or []byte, which covers every form CompleteRequest.Ref
+// takes. A nil v or JSON null yields a nil Reference.
+func ParseReference(v any) (Reference, error) {
+	var data []byte
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case PromptReference, ResourceReference:
+		return v.(Reference), nil
+	case *PromptReference:
+		return *v, nil
+	case *ResourceReference:
+		return *v, nil
+	case json.RawMessage:
+		data = v
+	case []byte:
+		data = v
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("reference: %w", err)
+		}
+	}
+	ref, err := unmarshalReference(data)
+	if err != nil {
+		return nil, fmt.Errorf("reference: %w", err)
+	}
+	return ref, nil
+}
+
+// unmarshalReference decodes a PromptReference or ResourceReference by its
+// type. Empty data and null decode to nil.
+func unmarshalReference(data []byte) (Reference, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	switch probe.Type {
+	case "ref/prompt":
+		var ref PromptReference
+		if err := json.Unmarshal(data, &ref); err != nil {
+			return nil, err
+		}
+		return ref, nil
+	case "ref/resource":
+		var ref ResourceReference
+		if err := json.Unmarshal(data, &ref); err != nil {
+			return nil, err
+		}
+		return ref, nil
+	}
+	return nil, fmt.Errorf("unknown reference type %q", probe.Type)
+}
+
+// MarshalJSON encodes a ResourceTemplate, sending the deprecated Template
+// field as uriTemplate when URITemplate is empty.
+func (t ResourceTemplate) MarshalJSON() ([]byte, error) {
+	type Alias ResourceTemplate
+	a := Alias(t)
+	a.URITemplate = t.uri()
+	return json.Marshal(a)
+}
+
+// UnmarshalJSON decodes a ResourceTemplate. It also accepts the "template"
+// key sent by peers built against earlier versions of this package.
+func (t *ResourceTemplate) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	type Alias ResourceTemplate
+	aux := &struct {
+		Template string `json:"template"`
+		*Alias
+	}{Alias: (*Alias)(t)}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return fmt.Errorf("ResourceTemplate: %w", err)
+	}
+	if t.URITemplate == "" {
+		t.URITemplate = aux.Template
+	}
+	return nil
+}
+
 // UnmarshalJSON decodes a CreateMessageResult, resolving the polymorphic
 // Content field to a concrete content type.
 func (r *CreateMessageResult) UnmarshalJSON(data []byte) error {
@@ -190,3 +370,24 @@ func unmarshalResourceContentsInternal(data json.RawMessage) (ResourceContents,
 
 	return nil, fmt.Errorf("resource contents (uri: %s) has neither text nor blob field", probe.URI)
 }
+
+// UnmarshalJSON decodes a ListResourceTemplatesResult. It also accepts the
+// "templates" key sent by peers built against earlier versions of this
+// package.
+func (r *ListResourceTemplatesResult) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	type Alias ListResourceTemplatesResult
+	aux := &struct {
+		Templates []ResourceTemplate `json:"templates"`
+		*Alias
+	}{Alias: (*Alias)(r)}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return fmt.Errorf("ListResourceTemplatesResult: %w", err)
+	}
+	if r.Templates == nil {
+		r.Templates = aux.Templates
+	}
+	return nil
+}
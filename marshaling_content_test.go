@@ -105,20 +105,56 @@ func TestSamplingAndPromptMessageContentRoundTrip(t *testing.T) {
 	}
 }
 
-// TestUnmarshalContentPreservesResource verifies resource and unknown content
-// types are preserved as generic objects rather than dropped.
-func TestUnmarshalContentPreservesResource(t *testing.T) {
-	raw := json.RawMessage(`{"type":"resource","resource":{"uri":"file:///x","text":"data"}}`)
-	got, err := unmarshalContent(raw)
+// TestUnmarshalContentResource verifies resource blocks decode to
+// EmbeddedResource and ResourceLink, and unknown content types are preserved
+// as generic objects rather than dropped.
+func TestUnmarshalContentResource(t *testing.T) {
+	got, err := unmarshalContent(json.RawMessage(`{"type":"resource","resource":{"uri":"file:///x","text":"data"}}`))
 	if err != nil {
 		t.Fatalf("unmarshalContent: %v", err)
 	}
-	m, ok := got.(map[string]any)
+	er, ok := got.(EmbeddedResource)
 	if !ok {
-		t.Fatalf("resource content = %T, want map[string]any", got)
+		t.Fatalf("resource content = %T, want EmbeddedResource", got)
 	}
-	if m["type"] != "resource" {
-		t.Errorf("resource content type = %v, want resource", m["type"])
+	if trc, ok := er.Resource.(TextResourceContents); !ok || trc.Text != "data" {
+		t.Errorf("embedded resource = %+v, want text contents", er.Resource)
+	}
+
+	got, err = unmarshalContent(json.RawMessage(`{"type":"resource_link","uri":"file:///x","name":"x","annotations":{"audience":["user"]}}`))
+	if err != nil {
+		t.Fatalf("unmarshalContent: %v", err)
+	}
+	if rl, ok := got.(ResourceLink); !ok || rl.URI != "file:///x" || rl.Annotations == nil || len(rl.Annotations.Audience) != 1 {
+		t.Errorf("resource_link content = %+v, want a ResourceLink", got)
+	}
+
+	got, err = unmarshalContent(json.RawMessage(`{"type":"future","x":1}`))
+	if err != nil {
+		t.Fatalf("unmarshalContent: %v", err)
+	}
+	if m, ok := got.(map[string]any); !ok || m["type"] != "future" {
+		t.Errorf("unknown content = %#v, want a generic map", got)
+	}
+}
+
+// TestContentListKeepsKeys verifies that blocks with keys their type does
+// not define decode as maps, so re-encoding them loses nothing.
+func TestContentListKeepsKeys(t *testing.T) {
+	in := `[{"type":"text","text":"a"},{"type":"text","text":"b","result":2}]`
+	var list ContentList
+	if err := json.Unmarshal([]byte(in), &list); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := list[0].(TextContent); !ok {
+		t.Errorf("list[0] = %#v, want TextContent", list[0])
+	}
+	if m, ok := list[1].(map[string]any); !ok || m["result"] != 2.0 {
+		t.Errorf("list[1] = %#v, want a map keeping result", list[1])
+	}
+	out, err := json.Marshal(list)
+	if err != nil || string(out) != `[{"type":"text","text":"a"},{"result":2,"text":"b","type":"text"}]` {
+		t.Errorf("re-encoded as %s, %v", out, err)
 	}
 }
 
@@ -185,3 +221,27 @@ func jsonEqual(t *testing.T, a, b any) bool {
 	}
 	return bytes.Equal(da, db)
 }
+
+// TestListResourceTemplatesResultKey verifies that templates are sent under
+// the spec's resourceTemplates key and still read from the old templates key.
+func TestListResourceTemplatesResultKey(t *testing.T) {
+	data, err := json.Marshal(ListResourceTemplatesResult{Templates: []ResourceTemplate{{Template: "file:///{path}", Description: "files"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"resourceTemplates":[`)) {
+		t.Errorf("marshaled result = %s, want a resourceTemplates key", data)
+	}
+	for _, in := range []string{
+		`{"resourceTemplates":[{"template":"file:///{path}","description":"files"}]}`,
+		`{"templates":[{"template":"file:///{path}","description":"files"}]}`,
+	} {
+		var got ListResourceTemplatesResult
+		if err := json.Unmarshal([]byte(in), &got); err != nil {
+			t.Fatalf("unmarshal %s: %v", in, err)
+		}
+		if len(got.Templates) != 1 || got.Templates[0].Description != "files" {
+			t.Errorf("unmarshal %s = %+v", in, got)
+		}
+	}
+}
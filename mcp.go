@@ -20,7 +20,7 @@
 //		Description: "Echo back the input",
 //	}, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 //		return &mcp.CallToolResult{
-//			Content: []any{map[string]string{"type": "text", "text": string(req.Arguments)}},
+//			Content: []any{mcp.TextContent{Type: "text", Text: string(req.Arguments)}},
 //		}, nil
 //	})
 //	server.Serve(context.Background(), mcp.StdioTransport())
@@ -40,7 +40,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
-	"strings"
 	"sync"
 )
 
@@ -107,134 +106,16 @@ func (c *SchemaCache) GetOrCreate(typeKey string, generator func() (json.RawMess
 	return schema, nil
 }
 
-// Global schema cache instance
-var schemaCache = &SchemaCache{}
-
-// createJSONSchema generates a JSON schema representation for the given Go type.
-// This function provides automatic schema generation for tool input validation,
-// supporting primitive types (string, number, boolean) and complex objects.
-// Optimized version that avoids marshal→unmarshal roundtrips and uses reflection
-// for better performance and type introspection.
-func createJSONSchema[T any]() (json.RawMessage, error) {
-	var example T
-	typeKey := fmt.Sprintf("%T", example)
-
-	return schemaCache.GetOrCreate(typeKey, func() (json.RawMessage, error) {
-		return generateJSONSchemaReflection[T]()
-	})
-}
-
-// generateJSONSchemaReflection creates a JSON schema using reflection instead of
-// marshal→unmarshal roundtrips for better performance and accuracy.
-func generateJSONSchemaReflection[T any]() (json.RawMessage, error) {
-	var example T
-	t := reflect.TypeOf(example)
-
-	schema := generateSchemaForType(t)
-	return json.Marshal(schema)
-}
-
-// generateSchemaForType recursively generates schema for a reflect.Type
-func generateSchemaForType(t reflect.Type) map[string]any {
-	// Handle pointers by dereferencing
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
-	}
-
-	switch t.Kind() {
-	case reflect.String:
-		return map[string]any{"type": "string"}
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
-		reflect.Float32, reflect.Float64:
-		return map[string]any{"type": "number"}
-	case reflect.Bool:
-		return map[string]any{"type": "boolean"}
-	case reflect.Array, reflect.Slice:
-		return map[string]any{
-			"type":  "array",
-			"items": generateSchemaForType(t.Elem()),
-		}
-	case reflect.Map:
-		return map[string]any{
-			"type":                 "object",
-			"additionalProperties": generateSchemaForType(t.Elem()),
-		}
-	case reflect.Struct:
-		return generateStructSchema(t)
-	case reflect.Interface:
-		// For interface{} types, we can't determine the schema statically
-		return map[string]any{"type": "object"}
-	default:
-		// Default to object for unknown types
-		return map[string]any{"type": "object"}
-	}
+// reset drops every cached schema.
+func (c *SchemaCache) reset() {
+	c.mu.Lock()
+	c.schemas = nil
+	c.mu.Unlock()
 }
 
-// generateStructSchema generates a JSON schema for a struct type using field introspection
-func generateStructSchema(t reflect.Type) map[string]any {
-	schema := map[string]any{
-		"type":       "object",
-		"properties": map[string]any{},
-	}
-
-	properties := schema["properties"].(map[string]any)
-	required := []string{}
-
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-
-		// Skip unexported fields
-		if !field.IsExported() {
-			continue
-		}
-
-		// Get JSON tag name or use field name
-		jsonTag := field.Tag.Get("json")
-		fieldName := field.Name
-
-		if jsonTag != "" {
-			// Parse json tag (e.g., "name,omitempty")
-			parts := strings.Split(jsonTag, ",")
-			if parts[0] != "" && parts[0] != "-" {
-				fieldName = parts[0]
-			} else if parts[0] == "-" {
-				// Skip fields marked with json:"-"
-				continue
-			}
-
-			// Check if field is required (no "omitempty")
-			isRequired := true
-			for _, part := range parts[1:] {
-				if part == "omitempty" {
-					isRequired = false
-					break
-				}
-			}
-
-			if isRequired {
-				required = append(required, fieldName)
-			}
-		} else {
-			// No json tag, assume required
-			required = append(required, fieldName)
-		}
-
-		// Generate schema for field type
-		fieldSchema := generateSchemaForType(field.Type)
-
-		// Add description from field comments if available
-		// This could be enhanced with custom tags like `description:"..."`
-		if desc := field.Tag.Get("description"); desc != "" {
-			fieldSchema["description"] = desc
-		}
-
-		properties[fieldName] = fieldSchema
-	}
-
-	if len(required) > 0 {
-		schema["required"] = required
-	}
-
-	return schema
+// createJSONSchema generates the JSON schema for the Go type T with the
+// default generator, as described in schemagen.go. Schemas are cached per
+// type.
+func createJSONSchema[T any]() (json.RawMessage, error) {
+	return enhancedSchemaGenerator.generate(reflect.TypeFor[T]())
 }
@@ -89,21 +89,21 @@ func TestRegisterTypedTool_SimpleTypes(t *testing.T) {
 		t.Fatal("Schema properties is not a map")
 	}
 
-	// Check that properties for a and b exist and are numbers
+	// Check that properties for a and b exist and are integers
 	aProperty, exists := properties["a"].(map[string]any)
 	if !exists {
 		t.Fatal("Property 'a' not found in schema")
 	}
-	if aProperty["type"] != "number" {
-		t.Errorf("Expected property 'a' type 'number', got %v", aProperty["type"])
+	if aProperty["type"] != "integer" {
+		t.Errorf("Expected property 'a' type 'integer', got %v", aProperty["type"])
 	}
 
 	bProperty, exists := properties["b"].(map[string]any)
 	if !exists {
 		t.Fatal("Property 'b' not found in schema")
 	}
-	if bProperty["type"] != "number" {
-		t.Errorf("Expected property 'b' type 'number', got %v", bProperty["type"])
+	if bProperty["type"] != "integer" {
+		t.Errorf("Expected property 'b' type 'integer', got %v", bProperty["type"])
 	}
 }
 
@@ -253,22 +253,18 @@ func TestTypedTool_Execution_ValidInput(t *testing.T) {
 		t.Fatalf("Expected 1 content item, got %d", len(result.Content))
 	}
 
-	content, ok := result.Content[0].(map[string]any)
+	content, ok := result.Content[0].(TextContent)
 	if !ok {
-		t.Fatal("Content is not a map")
+		t.Fatalf("Content is %T, want TextContent", result.Content[0])
 	}
 
-	if content["type"] != "text" {
-		t.Errorf("Expected content type 'text', got %v", content["type"])
-	}
-
-	if content["format"] != "json" {
-		t.Errorf("Expected content format 'json', got %v", content["format"])
+	if content.Type != "text" {
+		t.Errorf("Expected content type 'text', got %v", content.Type)
 	}
 
 	// Parse the JSON result
 	var output AddOutput
-	err = json.Unmarshal([]byte(content["text"].(string)), &output)
+	err = json.Unmarshal([]byte(content.Text), &output)
 	if err != nil {
 		t.Fatalf("Failed to unmarshal output: %v", err)
 	}
@@ -311,17 +307,17 @@ func TestTypedTool_Execution_InvalidInput(t *testing.T) {
 		t.Fatalf("Expected 1 content item, got %d", len(result.Content))
 	}
 
-	content, ok := result.Content[0].(map[string]string)
+	content, ok := result.Content[0].(TextContent)
 	if !ok {
-		t.Fatal("Content is not a string map")
+		t.Fatalf("Content is %T, want TextContent", result.Content[0])
 	}
 
-	if content["type"] != "text" {
-		t.Errorf("Expected content type 'text', got %v", content["type"])
+	if content.Type != "text" {
+		t.Errorf("Expected content type 'text', got %v", content.Type)
 	}
 
-	if !contains(content["text"], "Invalid input") {
-		t.Errorf("Expected error message to contain 'Invalid input', got %s", content["text"])
+	if !contains(content.Text, "Invalid input") {
+		t.Errorf("Expected error message to contain 'Invalid input', got %s", content.Text)
 	}
 }
 
@@ -359,13 +355,13 @@ func TestTypedTool_Execution_HandlerError(t *testing.T) {
 		t.Fatalf("Expected 1 content item, got %d", len(result.Content))
 	}
 
-	content, ok := result.Content[0].(map[string]string)
+	content, ok := result.Content[0].(TextContent)
 	if !ok {
-		t.Fatal("Content is not a string map")
+		t.Fatalf("Content is %T, want TextContent", result.Content[0])
 	}
 
-	if !contains(content["text"], "computation failed") {
-		t.Errorf("Expected error message to contain 'computation failed', got %s", content["text"])
+	if !contains(content.Text, "computation failed") {
+		t.Errorf("Expected error message to contain 'computation failed', got %s", content.Text)
 	}
 }
 
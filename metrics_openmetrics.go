@@ -0,0 +1,443 @@
+// Package mcp - OpenMetrics Exposition
+// This file implements a MetricsRegistry that renders its metrics in the
+// OpenMetrics (and legacy Prometheus) text format over HTTP, without a
+// dependency on a metrics client library.
+package mcp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// otherLabelValue replaces label values beyond a label's cardinality limit.
+const otherLabelValue = "__other__"
+
+// OpenMetricsConfig configures an OpenMetricsRegistry.
+type OpenMetricsConfig struct {
+	Namespace      string    `json:"namespace" yaml:"namespace"`           // Metric name prefix (default "mcp")
+	Buckets        []float64 `json:"buckets" yaml:"buckets"`               // Latency histogram buckets in seconds
+	MaxLabelValues int       `json:"maxLabelValues" yaml:"maxLabelValues"` // Distinct values kept per label (default 100)
+}
+
+// OpenMetricsRegistry collects request, error, cache and rate limit metrics
+// and serves them as an http.Handler, typically mounted at /metrics. It
+// implements MetricsRegistry, CacheMetricsRegistry, RateLimitMetricsRegistry
+// and BulkheadMetricsRegistry, and can observe session, connection pool and
+// performance monitor state at scrape time.
+//
+// Label values come from clients (methods, tool names), so each label keeps
+// at most MaxLabelValues distinct values; later values are reported as
+// "__other__".
+type OpenMetricsRegistry struct {
+	namespace string
+	buckets   []float64
+	maxValues int
+
+	mu         sync.Mutex
+	families   map[string]*omFamily
+	seen       map[string]map[string]bool // label name -> values admitted
+	collectors []func() []omSample
+}
+
+// omFamily is a metric family with its series.
+type omFamily struct {
+	name       string
+	help       string
+	typ        string // counter, gauge or histogram
+	labelNames []string
+	series     map[string]*omSeries
+}
+
+type omSeries struct {
+	labelValues []string
+	value       float64
+	buckets     []uint64 // histogram counts per bucket, not cumulative
+	sum         float64
+	count       uint64
+}
+
+// omSample is a value produced by a collector at scrape time.
+type omSample struct {
+	name        string
+	help        string
+	typ         string
+	labelNames  []string
+	labelValues []string
+	value       float64
+}
+
+// NewOpenMetricsRegistry creates an OpenMetricsRegistry.
+func NewOpenMetricsRegistry(config OpenMetricsConfig) *OpenMetricsRegistry {
+	if config.Namespace == "" {
+		config.Namespace = "mcp"
+	}
+	if len(config.Buckets) == 0 {
+		config.Buckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+	}
+	if config.MaxLabelValues <= 0 {
+		config.MaxLabelValues = 100
+	}
+	buckets := append([]float64(nil), config.Buckets...)
+	sort.Float64s(buckets)
+	return &OpenMetricsRegistry{
+		namespace: config.Namespace,
+		buckets:   buckets,
+		maxValues: config.MaxLabelValues,
+		families:  make(map[string]*omFamily),
+		seen:      make(map[string]map[string]bool),
+	}
+}
+
+// RecordRequest implements MetricsRegistry. The tool label is taken from
+// labels["tool"], which MetricsMiddleware sets for tools/call.
+func (r *OpenMetricsRegistry) RecordRequest(method string, duration time.Duration, statusCode int, labels map[string]string) {
+	names := []string{"method", "tool", "status"}
+	values := []string{method, labels["tool"], strconv.Itoa(statusCode)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	values = r.guard(names, values)
+	r.family("requests", "Requests handled, by method, tool and status.", "counter", names).
+		get(values).value++
+
+	s := r.family("request_duration_seconds", "Request latency in seconds, by method, tool and status.", "histogram", names).
+		get(values)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(r.buckets))
+	}
+	seconds := duration.Seconds()
+	if i := sort.SearchFloat64s(r.buckets, seconds); i < len(r.buckets) {
+		s.buckets[i]++
+	}
+	s.sum += seconds
+	s.count++
+}
+
+// RecordActiveRequests implements MetricsRegistry
+func (r *OpenMetricsRegistry) RecordActiveRequests(count int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.family("active_requests", "Requests currently being handled.", "gauge", nil).get(nil).value = float64(count)
+}
+
+// RecordError implements MetricsRegistry
+func (r *OpenMetricsRegistry) RecordError(method string, errorType string, labels map[string]string) {
+	names := []string{"method", "tool", "type"}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	values := r.guard(names, []string{method, labels["tool"], errorType})
+	r.family("errors", "Failed requests, by method, tool and error type.", "counter", names).get(values).value++
+}
+
+// RecordCacheLookup implements CacheMetricsRegistry
+func (r *OpenMetricsRegistry) RecordCacheLookup(cache string, method string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	names := []string{"cache", "method", "result"}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	values := r.guard(names, []string{cache, method, result})
+	r.family("cache_lookups", "Response cache lookups, by cache, method and result.", "counter", names).get(values).value++
+}
+
+// RecordRateLimitRejection implements RateLimitMetricsRegistry
+func (r *OpenMetricsRegistry) RecordRateLimitRejection(method string, labels map[string]string) {
+	names := []string{"method", "tool"}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	values := r.guard(names, []string{method, labels["tool"]})
+	r.family("rate_limit_rejections", "Requests rejected by rate limiting, by method and tool.", "counter", names).get(values).value++
+}
+
+// RecordQueueDepth implements BulkheadMetricsRegistry
+func (r *OpenMetricsRegistry) RecordQueueDepth(tool string, depth int) {
+	names := []string{"tool"}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	values := r.guard(names, []string{tool})
+	r.family("tool_queue_depth", "Tool calls waiting in a bulkhead queue, by tool.", "gauge", names).get(values).value = float64(depth)
+}
+
+// RecordBulkheadRejection implements BulkheadMetricsRegistry
+func (r *OpenMetricsRegistry) RecordBulkheadRejection(tool string, reason string) {
+	names := []string{"tool", "reason"}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	values := r.guard(names, []string{tool, reason})
+	r.family("bulkhead_rejections", "Tool calls rejected by a bulkhead, by tool and reason.", "counter", names).get(values).value++
+}
+
+// ObserveStreamableHTTPHandler reports the number of open sessions of h at
+// each scrape.
+func (r *OpenMetricsRegistry) ObserveStreamableHTTPHandler(h *StreamableHTTPHandler) {
+	r.addCollector(func() []omSample {
+		return []omSample{{
+			name:  "sessions",
+			help:  "Open streamable HTTP sessions.",
+			typ:   "gauge",
+			value: float64(h.SessionCount()),
+		}}
+	})
+}
+
+// ObserveConnectionPool reports the statistics of p, labeled with name, at
+// each scrape.
+func (r *OpenMetricsRegistry) ObserveConnectionPool(name string, p *ConnectionPool) {
+	r.addCollector(func() []omSample {
+		stats := p.Stats()
+		labels := []string{"pool", "state"}
+		sample := func(state string, v int) omSample {
+			return omSample{
+				name:        "pool_connections",
+				help:        "Pooled connections, by pool and state.",
+				typ:         "gauge",
+				labelNames:  labels,
+				labelValues: []string{name, state},
+				value:       float64(v),
+			}
+		}
+		return []omSample{
+			sample("total", stats.TotalConnections),
+			sample("active", stats.ActiveConnections),
+			sample("idle", stats.IdleConnections),
+			sample("healthy", stats.HealthyConnections),
+			{
+				name:        "pool_max_connections",
+				help:        "Configured connection limit, by pool.",
+				typ:         "gauge",
+				labelNames:  []string{"pool"},
+				labelValues: []string{name},
+				value:       float64(stats.MaxConnections),
+			},
+		}
+	})
+}
+
+// ObservePerformanceMonitor reports the metrics of pm at each scrape. A nil pm
+// observes the global monitor behind GetGlobalPerformanceMetrics.
+func (r *OpenMetricsRegistry) ObservePerformanceMonitor(pm *PerformanceMonitor) {
+	r.addCollector(func() []omSample {
+		var m PerformanceMetrics
+		if pm != nil {
+			m = pm.GetMetrics()
+		} else {
+			m = GetGlobalPerformanceMetrics()
+		}
+		quantile := func(q string, d time.Duration) omSample {
+			return omSample{
+				name:        "performance_response_time_seconds",
+				help:        "Sampled response time quantiles in seconds.",
+				typ:         "gauge",
+				labelNames:  []string{"quantile"},
+				labelValues: []string{q},
+				value:       d.Seconds(),
+			}
+		}
+		return []omSample{
+			{name: "performance_requests", help: "Requests seen by the performance monitor.", typ: "counter", value: float64(m.TotalRequests)},
+			{name: "performance_errors", help: "Errors seen by the performance monitor.", typ: "counter", value: float64(m.TotalErrors)},
+			{name: "performance_requests_per_second", help: "Sampled request throughput.", typ: "gauge", value: m.RequestsPerSecond},
+			{name: "performance_allocated_bytes", help: "Heap bytes allocated at the last sample.", typ: "gauge", value: float64(m.AllocatedMemory)},
+			quantile("0.5", m.MedianResponseTime),
+			quantile("0.95", m.P95ResponseTime),
+			quantile("0.99", m.P99ResponseTime),
+		}
+	})
+}
+
+func (r *OpenMetricsRegistry) addCollector(fn func() []omSample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, fn)
+}
+
+// ServeHTTP renders the metrics. Clients that accept
+// application/openmetrics-text get OpenMetrics; others get the Prometheus
+// text format.
+func (r *OpenMetricsRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	openMetrics := strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	}
+	w.Write(r.render(openMetrics))
+}
+
+// render writes every family in name order.
+func (r *OpenMetricsRegistry) render(openMetrics bool) []byte {
+	r.mu.Lock()
+	collectors := append([]func() []omSample(nil), r.collectors...)
+	r.mu.Unlock()
+
+	// Collectors run without the lock so they may take their own.
+	var samples []omSample
+	for _, collect := range collectors {
+		samples = append(samples, collect()...)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	families := make(map[string]*omFamily, len(r.families))
+	for name, f := range r.families {
+		families[name] = f
+	}
+	for _, s := range samples {
+		name := r.namespace + "_" + s.name
+		f, ok := families[name]
+		if !ok {
+			f = &omFamily{name: name, help: s.help, typ: s.typ, labelNames: s.labelNames, series: make(map[string]*omSeries)}
+			families[name] = f
+		}
+		f.series[strings.Join(s.labelValues, "\xff")] = &omSeries{labelValues: s.labelValues, value: s.value}
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		r.writeFamily(&buf, families[name], openMetrics)
+	}
+	if openMetrics {
+		buf.WriteString("# EOF\n")
+	}
+	return buf.Bytes()
+}
+
+func (r *OpenMetricsRegistry) writeFamily(buf *bytes.Buffer, f *omFamily, openMetrics bool) {
+	typeName := f.name
+	if f.typ == "counter" && !openMetrics {
+		// The Prometheus format names counter families with their suffix.
+		typeName += "_total"
+	}
+	fmt.Fprintf(buf, "# TYPE %s %s\n", typeName, f.typ)
+	fmt.Fprintf(buf, "# HELP %s %s\n", typeName, escapeHelp(f.help))
+
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := f.series[k]
+		labels := formatLabels(f.labelNames, s.labelValues, "", "")
+		switch f.typ {
+		case "counter":
+			fmt.Fprintf(buf, "%s_total%s %s\n", f.name, labels, formatFloat(s.value))
+		case "gauge":
+			fmt.Fprintf(buf, "%s%s %s\n", f.name, labels, formatFloat(s.value))
+		case "histogram":
+			var cumulative uint64
+			for i, upper := range r.buckets {
+				if s.buckets != nil {
+					cumulative += s.buckets[i]
+				}
+				fmt.Fprintf(buf, "%s_bucket%s %d\n", f.name,
+					formatLabels(f.labelNames, s.labelValues, "le", formatFloat(upper)), cumulative)
+			}
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, "le", "+Inf"), s.count)
+			fmt.Fprintf(buf, "%s_sum%s %s\n", f.name, labels, formatFloat(s.sum))
+			fmt.Fprintf(buf, "%s_count%s %d\n", f.name, labels, s.count)
+		}
+	}
+}
+
+// family returns the named family, creating it on first use. r.mu must be held.
+func (r *OpenMetricsRegistry) family(name, help, typ string, labelNames []string) *omFamily {
+	name = r.namespace + "_" + name
+	f, ok := r.families[name]
+	if !ok {
+		f = &omFamily{name: name, help: help, typ: typ, labelNames: labelNames, series: make(map[string]*omSeries)}
+		r.families[name] = f
+	}
+	return f
+}
+
+// get returns the series for labelValues, creating it on first use.
+func (f *omFamily) get(labelValues []string) *omSeries {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &omSeries{labelValues: labelValues}
+		f.series[key] = s
+	}
+	return s
+}
+
+// guard applies the per-label cardinality limit. r.mu must be held.
+func (r *OpenMetricsRegistry) guard(names, values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		seen := r.seen[names[i]]
+		if seen == nil {
+			seen = make(map[string]bool)
+			r.seen[names[i]] = seen
+		}
+		if !seen[v] && len(seen) >= r.maxValues {
+			v = otherLabelValue
+		} else {
+			seen[v] = true
+		}
+		out[i] = v
+	}
+	return out
+}
+
+// formatLabels renders a label set, appending extraName if non-empty.
+// Empty label values are omitted, as they are equivalent to an absent label.
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	var parts []string
+	for i, name := range names {
+		if values[i] == "" {
+			continue
+		}
+		parts = append(parts, name+`="`+escapeLabelValue(values[i])+`"`)
+	}
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+extraValue+`"`)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func escapeHelp(v string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
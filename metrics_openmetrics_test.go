@@ -0,0 +1,166 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, h http.Handler, accept string) (string, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("scrape status = %d", rec.Code)
+	}
+	return rec.Body.String(), rec.Header().Get("Content-Type")
+}
+
+func assertContains(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("exposition missing %q:\n%s", line, body)
+		}
+	}
+}
+
+func TestOpenMetricsRegistry(t *testing.T) {
+	r := NewOpenMetricsRegistry(OpenMetricsConfig{Buckets: []float64{0.1, 1}})
+	r.RecordRequest("tools/call", 50*time.Millisecond, 200, map[string]string{"tool": "echo"})
+	r.RecordRequest("tools/call", 500*time.Millisecond, 200, map[string]string{"tool": "echo"})
+	r.RecordRequest("ping", 2*time.Second, 500, nil)
+	r.RecordActiveRequests(3)
+	r.RecordError("ping", "timeout", nil)
+	r.RecordCacheLookup("server", "resources/read", true)
+	r.RecordRateLimitRejection("tools/call", map[string]string{"tool": "echo"})
+	r.RecordQueueDepth("query", 4)
+	r.RecordBulkheadRejection("query", "queue_timeout")
+
+	body, contentType := scrape(t, r, "application/openmetrics-text; version=1.0.0")
+	if !strings.HasPrefix(contentType, "application/openmetrics-text") {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	assertContains(t, body,
+		"# TYPE mcp_requests counter",
+		`mcp_requests_total{method="tools/call",tool="echo",status="200"} 2`,
+		`mcp_requests_total{method="ping",status="500"} 1`,
+		"# TYPE mcp_request_duration_seconds histogram",
+		`mcp_request_duration_seconds_bucket{method="tools/call",tool="echo",status="200",le="0.1"} 1`,
+		`mcp_request_duration_seconds_bucket{method="tools/call",tool="echo",status="200",le="1"} 2`,
+		`mcp_request_duration_seconds_bucket{method="tools/call",tool="echo",status="200",le="+Inf"} 2`,
+		`mcp_request_duration_seconds_count{method="tools/call",tool="echo",status="200"} 2`,
+		`mcp_request_duration_seconds_bucket{method="ping",status="500",le="1"} 0`,
+		`mcp_request_duration_seconds_bucket{method="ping",status="500",le="+Inf"} 1`,
+		"mcp_active_requests 3",
+		`mcp_errors_total{method="ping",type="timeout"} 1`,
+		`mcp_cache_lookups_total{cache="server",method="resources/read",result="hit"} 1`,
+		`mcp_rate_limit_rejections_total{method="tools/call",tool="echo"} 1`,
+		`mcp_tool_queue_depth{tool="query"} 4`,
+		`mcp_bulkhead_rejections_total{tool="query",reason="queue_timeout"} 1`,
+	)
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Errorf("OpenMetrics exposition does not end with # EOF")
+	}
+
+	body, contentType = scrape(t, r, "")
+	if !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	assertContains(t, body, "# TYPE mcp_requests_total counter")
+	if strings.Contains(body, "# EOF") {
+		t.Errorf("Prometheus exposition contains # EOF")
+	}
+}
+
+func TestOpenMetricsLabelCardinality(t *testing.T) {
+	r := NewOpenMetricsRegistry(OpenMetricsConfig{MaxLabelValues: 2})
+	for _, tool := range []string{"a", "b", "c", "d", "a"} {
+		r.RecordRequest("tools/call", time.Millisecond, 200, map[string]string{"tool": tool})
+	}
+	r.RecordRequest("tools/call", time.Millisecond, 200, map[string]string{"tool": "quo\"te\n"})
+
+	body, _ := scrape(t, r, "")
+	assertContains(t, body,
+		`mcp_requests_total{method="tools/call",tool="a",status="200"} 2`,
+		`mcp_requests_total{method="tools/call",tool="__other__",status="200"} 3`,
+	)
+	if strings.Contains(body, `tool="c"`) {
+		t.Errorf("label value beyond the limit was exposed:\n%s", body)
+	}
+	if got := formatLabels([]string{"uri"}, []string{"a\"b\\c\nd"}, "", ""); got != `{uri="a\"b\\c\nd"}` {
+		t.Errorf("formatLabels = %s", got)
+	}
+}
+
+func TestOpenMetricsCollectors(t *testing.T) {
+	r := NewOpenMetricsRegistry(OpenMetricsConfig{})
+
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server {
+		return NewServer("test-server", "1.0.0", WithTestLogger(t, slog.LevelError))
+	}, nil)
+	defer handler.Close()
+	r.ObserveStreamableHTTPHandler(handler)
+
+	pool := NewConnectionPool(nil, &ConnectionPoolConfig{MaxConnections: 4}, nil)
+	defer pool.Close()
+	r.ObserveConnectionPool("upstream", pool)
+
+	r.ObservePerformanceMonitor(NewPerformanceMonitor(1, 100))
+
+	body, _ := scrape(t, r, "application/openmetrics-text")
+	assertContains(t, body,
+		"# TYPE mcp_sessions gauge",
+		"mcp_sessions 0",
+		`mcp_pool_connections{pool="upstream",state="total"} 0`,
+		`mcp_pool_max_connections{pool="upstream"} 4`,
+		"mcp_performance_requests_total 0",
+		`mcp_performance_response_time_seconds{quantile="0.99"} 0`,
+	)
+}
+
+func TestOpenMetricsMiddleware(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	registry := NewOpenMetricsRegistry(OpenMetricsConfig{})
+
+	server := NewServer("test-server", "1.0.0", WithTestLogger(t, slog.LevelError))
+	server.Use(NewMetricsMiddleware(registry))
+	server.Use(NewRateLimitMiddleware(RateLimitConfig{
+		RequestsPerSecond: 1,
+		BurstSize:         1,
+		SkipMethods:       []string{"initialize", "notifications/initialized"},
+		Metrics:           registry,
+	}))
+	if err := server.RegisterTool(Tool{Name: "echo"},
+		func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+			return &CallToolResult{}, nil
+		}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newCacheTestPair(t, server)
+	if _, err := client.CallTool(ctx, CallToolRequest{Name: "echo", Arguments: json.RawMessage(`{}`)}); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if _, err := client.CallTool(ctx, CallToolRequest{Name: "echo", Arguments: json.RawMessage(`{}`)}); err == nil {
+		t.Fatal("second call was not rate limited")
+	}
+
+	body, _ := scrape(t, registry, "")
+	assertContains(t, body,
+		`mcp_requests_total{method="tools/call",tool="echo",status="200"} 1`,
+		`mcp_rate_limit_rejections_total{method="tools/call",tool="echo"} 1`,
+	)
+}
@@ -78,6 +78,7 @@ type LoggingMiddleware struct {
 	requestFields  []string
 	responseFields []string
 	sanitizer      func(interface{}) interface{}
+	redactor       *Redactor
 }
 
 // LoggingConfig configures the logging middleware
@@ -90,6 +91,11 @@ type LoggingConfig struct {
 	SanitizeFunc    func(interface{}) interface{}
 	RequestFields   []string
 	ResponseFields  []string
+
+	// Redactor removes secrets from logged params and results before
+	// SanitizeFunc sees them (default DefaultRedactor()). Params and
+	// results are logged only when SanitizeFunc is set.
+	Redactor *Redactor
 }
 
 // NewLoggingMiddleware creates a new logging middleware with configuration
@@ -100,6 +106,9 @@ func NewLoggingMiddleware(config LoggingConfig) *LoggingMiddleware {
 	if config.Level == 0 {
 		config.Level = slog.LevelInfo
 	}
+	if config.Redactor == nil {
+		config.Redactor = DefaultRedactor()
+	}
 
 	return &LoggingMiddleware{
 		logger:         config.Logger,
@@ -107,6 +116,7 @@ func NewLoggingMiddleware(config LoggingConfig) *LoggingMiddleware {
 		requestFields:  config.RequestFields,
 		responseFields: config.ResponseFields,
 		sanitizer:      config.SanitizeFunc,
+		redactor:       config.Redactor,
 	}
 }
 
@@ -123,12 +133,11 @@ func (m *LoggingMiddleware) Apply(next MCPHandler) MCPHandler {
 		}
 
 		if len(m.requestFields) > 0 {
-			params := req.Params()
+			params, _ := m.redactor.redactExchange(req.Method(), req.Params(), nil)
 			if params != nil && m.sanitizer != nil {
 				var paramsMap map[string]interface{}
 				if err := json.Unmarshal(params, &paramsMap); err == nil {
-					sanitized := m.sanitizer(paramsMap)
-					logFields = append(logFields, "params", sanitized)
+					logFields = append(logFields, "params", m.sanitizer(paramsMap))
 				}
 			}
 		}
@@ -150,13 +159,18 @@ func (m *LoggingMiddleware) Apply(next MCPHandler) MCPHandler {
 
 		if resp != nil && len(m.responseFields) > 0 {
 			if result := resp.Result(); result != nil && m.sanitizer != nil {
-				sanitized := m.sanitizer(result)
-				responseFields = append(responseFields, "result", sanitized)
+				if data, err := json.Marshal(result); err == nil {
+					_, data = m.redactor.redactExchange(req.Method(), nil, data)
+					var redacted interface{}
+					if json.Unmarshal(data, &redacted) == nil {
+						responseFields = append(responseFields, "result", m.sanitizer(redacted))
+					}
+				}
 			}
 		}
 
 		if err != nil {
-			responseFields = append(responseFields, "error", err.Error())
+			responseFields = append(responseFields, "error", m.redactor.RedactString(err.Error()))
 		}
 
 		m.logger.Log(ctx, m.level, "MCP request completed", responseFields...)
@@ -316,6 +330,31 @@ type RateLimitConfig struct {
 	WindowSize          time.Duration
 	CleanupInterval     time.Duration
 	PerEndpointLimiting bool // Enable per-endpoint (client:method) rate limiting granularity
+
+	// ToolCosts weights tools/call requests by tool name, so that expensive
+	// tools consume more of the limit. Other methods always cost 1.
+	ToolCosts       map[string]int
+	DefaultToolCost int // Cost of tools missing from ToolCosts (default 1)
+
+	// Metrics, if it implements RateLimitMetricsRegistry, counts rejections.
+	Metrics MetricsRegistry
+}
+
+// RateLimitMetricsRegistry is implemented by metrics registries that also
+// count requests rejected by rate limiting.
+type RateLimitMetricsRegistry interface {
+	RecordRateLimitRejection(method string, labels map[string]string)
+}
+
+// recordRateLimitRejection reports a rejection to metrics if it supports it.
+func recordRateLimitRejection(metrics MetricsRegistry, req MCPRequest) {
+	if m, ok := metrics.(RateLimitMetricsRegistry); ok {
+		labels := map[string]string{}
+		if tool := toolName(req); tool != "" {
+			labels["tool"] = tool
+		}
+		m.RecordRateLimitRejection(req.Method(), labels)
+	}
 }
 
 // NewRateLimitMiddleware creates a new rate limiting middleware
@@ -372,9 +411,17 @@ func (m *RateLimitMiddleware) Apply(next MCPHandler) MCPHandler {
 		entry.lastUsed = time.Now()
 		entry.mu.Unlock()
 
-		// Check rate limit
-		if !entry.limiter.Allow() {
-			return NewRateLimitError("Rate limit exceeded"), nil
+		// Check rate limit, charging tool calls their configured cost
+		cost := requestCost(req, m.config.ToolCosts, m.config.DefaultToolCost)
+		now := time.Now()
+		if !entry.limiter.AllowN(now, cost) {
+			var retryAfter time.Duration
+			if r := entry.limiter.ReserveN(now, cost); r.OK() {
+				retryAfter = r.DelayFrom(now)
+				r.CancelAt(now)
+			}
+			recordRateLimitRejection(m.config.Metrics, req)
+			return NewRateLimitErrorWithRetry("Rate limit exceeded", retryAfter), nil
 		}
 
 		return next.Handle(ctx, req)
@@ -550,7 +597,7 @@ type MetricsRegistry interface {
 func NewMetricsMiddleware(registry MetricsRegistry) *MetricsMiddleware {
 	return &MetricsMiddleware{
 		registry: registry,
-		labels:   []string{"method", "client_id", "transport"},
+		labels:   []string{"method", "client_id", "transport", "tool"},
 		buckets:  []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10},
 	}
 }
@@ -611,6 +658,10 @@ func (m *MetricsMiddleware) extractLabels(ctx context.Context, req MCPRequest) m
 		labels["transport"] = "unknown"
 	}
 
+	if tool := toolName(req); tool != "" {
+		labels["tool"] = tool
+	}
+
 	return labels
 }
 
@@ -662,6 +713,7 @@ const (
 	userAgentKey        contextKey = "mcp_user_agent"
 	remoteAddrKey       contextKey = "mcp_remote_addr"
 	clientIDKey         contextKey = "mcp_client_id"
+	sessionKey          contextKey = "mcp_session"
 )
 
 // WithAuthContext adds authentication context to the request context
@@ -711,6 +763,71 @@ func NewRateLimitError(message string) MCPResponse {
 	return NewErrorResponse(message, -32001) // Custom rate limit error code
 }
 
+// RateLimitErrorData is the data member of rate limit errors that carry a
+// retry hint.
+type RateLimitErrorData struct {
+	RetryAfter   int   `json:"retryAfter"`   // Whole seconds, rounded up, as in the HTTP Retry-After header
+	RetryAfterMs int64 `json:"retryAfterMs"` // Milliseconds, rounded up
+}
+
+// NewRateLimitErrorWithRetry creates a rate limit error telling the client how
+// long to wait before retrying. A non-positive retryAfter omits the hint.
+func NewRateLimitErrorWithRetry(message string, retryAfter time.Duration) MCPResponse {
+	resp := &errorResponse{err: &ResponseError{Code: -32001, Message: message}}
+	if retryAfter > 0 {
+		ms := int64((retryAfter + time.Millisecond - 1) / time.Millisecond)
+		resp.err.Data = RateLimitErrorData{
+			RetryAfter:   int((retryAfter + time.Second - 1) / time.Second),
+			RetryAfterMs: ms,
+		}
+	}
+	return resp
+}
+
+// RetryAfterFromError extracts the retry hint from a rate limit error, as
+// returned by Client calls or produced by the rate limiting middleware.
+func RetryAfterFromError(err error) (time.Duration, bool) {
+	re, ok := AsResponseError(err)
+	if !ok || re.Code != -32001 || re.Data == nil {
+		return 0, false
+	}
+	var data RateLimitErrorData
+	raw, err := json.Marshal(re.Data)
+	if err != nil || json.Unmarshal(raw, &data) != nil || data.RetryAfterMs <= 0 {
+		return 0, false
+	}
+	return time.Duration(data.RetryAfterMs) * time.Millisecond, true
+}
+
+// requestCost returns how much of a rate limit req consumes.
+func requestCost(req MCPRequest, toolCosts map[string]int, defaultCost int) int {
+	if req.Method() != string(MethodToolsCall) || (len(toolCosts) == 0 && defaultCost <= 0) {
+		return 1
+	}
+	cost := defaultCost
+	if c, ok := toolCosts[toolName(req)]; ok {
+		cost = c
+	}
+	if cost <= 0 {
+		return 1
+	}
+	return cost
+}
+
+// toolName returns the tool named by a tools/call request, or "".
+func toolName(req MCPRequest) string {
+	if req.Method() != string(MethodToolsCall) {
+		return ""
+	}
+	var params struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(req.Params(), &params) != nil {
+		return ""
+	}
+	return params.Name
+}
+
 // errorResponse implements the Response interface for errors
 type errorResponse struct {
 	err *ResponseError
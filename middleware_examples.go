@@ -82,6 +82,7 @@ func ExampleCustomMiddleware() {
 		timeout:        30 * time.Second,
 		resetAfter:     60 * time.Second,
 		failureCounter: make(map[string]int),
+		lastFailure:    make(map[string]time.Time),
 	}
 
 	server := NewServer("example", "1.0.0")
@@ -197,15 +198,52 @@ type CustomCircuitBreakerMiddleware struct {
 	failureCounter map[string]int
 	lastFailure    map[string]time.Time
 	mu             sync.RWMutex
+
+	// key names the circuit a request belongs to; requests for which it
+	// returns "" bypass the breaker. The default is the method.
+	key     func(req MCPRequest) string
+	metrics MetricsRegistry
+}
+
+// NewToolCircuitBreaker returns a circuit breaker with one circuit per tool.
+// A tool's circuit opens after threshold consecutive failed calls, counting
+// results with isError set, and rejects calls to it with a retryable -32001
+// error until resetAfter has passed. Rejections by queues and rate limits,
+// which also use -32001, are not failures. Other methods pass through.
+// Rejections are recorded on metrics, which may be nil, as "circuit_open"
+// errors.
+func NewToolCircuitBreaker(threshold int, resetAfter time.Duration, metrics MetricsRegistry) *CustomCircuitBreakerMiddleware {
+	return &CustomCircuitBreakerMiddleware{
+		threshold:      threshold,
+		resetAfter:     resetAfter,
+		failureCounter: make(map[string]int),
+		lastFailure:    make(map[string]time.Time),
+		key:            toolName,
+		metrics:        metrics,
+	}
 }
 
 func (m *CustomCircuitBreakerMiddleware) Apply(next MCPHandler) MCPHandler {
+	// Breakers built as literals may lack the maps, and Apply can run
+	// concurrently with requests on a chain built earlier.
+	m.mu.Lock()
+	if m.failureCounter == nil {
+		m.failureCounter = make(map[string]int)
+	}
 	if m.lastFailure == nil {
 		m.lastFailure = make(map[string]time.Time)
 	}
+	m.mu.Unlock()
+	key := m.key
+	if key == nil {
+		key = func(req MCPRequest) string { return req.Method() }
+	}
 
 	return MCPHandlerFunc(func(ctx context.Context, req MCPRequest) (MCPResponse, error) {
-		method := req.Method()
+		method := key(req)
+		if method == "" {
+			return next.Handle(ctx, req)
+		}
 
 		m.mu.RLock()
 		failures := m.failureCounter[method]
@@ -214,8 +252,11 @@ func (m *CustomCircuitBreakerMiddleware) Apply(next MCPHandler) MCPHandler {
 
 		// Check if circuit is open
 		if failures >= m.threshold {
-			if time.Since(lastFail) < m.resetAfter {
-				return NewErrorResponse("Circuit breaker open", -32001), nil
+			if wait := m.resetAfter - time.Since(lastFail); wait > 0 {
+				if m.metrics != nil {
+					m.metrics.RecordError(req.Method(), "circuit_open", map[string]string{"tool": toolName(req)})
+				}
+				return NewRateLimitErrorWithRetry("Circuit breaker open", wait), nil
 			} else {
 				// Reset circuit
 				m.mu.Lock()
@@ -228,12 +269,12 @@ func (m *CustomCircuitBreakerMiddleware) Apply(next MCPHandler) MCPHandler {
 		resp, err := next.Handle(ctx, req)
 
 		// Track failures
-		if err != nil || (resp != nil && resp.IsError()) {
+		if err != nil || circuitFailure(resp) {
 			m.mu.Lock()
 			m.failureCounter[method]++
 			m.lastFailure[method] = time.Now()
 			m.mu.Unlock()
-		} else {
+		} else if resp == nil || !resp.IsError() {
 			// Reset on success
 			m.mu.Lock()
 			m.failureCounter[method] = 0
@@ -244,6 +285,19 @@ func (m *CustomCircuitBreakerMiddleware) Apply(next MCPHandler) MCPHandler {
 	})
 }
 
+// circuitFailure reports whether resp counts against a circuit: an error
+// other than a -32001 rejection, or a tool result with isError set.
+func circuitFailure(resp MCPResponse) bool {
+	if resp == nil {
+		return false
+	}
+	if resp.IsError() {
+		return resp.Error().Code != -32001
+	}
+	result, ok := resp.Result().(*CallToolResult)
+	return ok && result != nil && result.IsError
+}
+
 func (m *CustomCircuitBreakerMiddleware) Name() string {
 	return "circuit_breaker"
 }
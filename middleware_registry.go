@@ -268,6 +268,8 @@ func (r *MiddlewareRegistry) registerBuiltinFactories() {
 		&CompressionMiddlewareFactory{},
 		&ValidationMiddlewareFactory{},
 		&CachingMiddlewareFactory{},
+		&ResponseCacheMiddlewareFactory{},
+		&AuditMiddlewareFactory{},
 	}
 
 	for _, factory := range factories {
@@ -603,6 +605,72 @@ func (f *CachingMiddlewareFactory) Description() string {
 	return "Provides response caching with TTL and cache keys"
 }
 
+// ResponseCacheMiddlewareFactory creates response cache middleware instances
+type ResponseCacheMiddlewareFactory struct{}
+
+func (f *ResponseCacheMiddlewareFactory) Create(config interface{}) (Middleware, error) {
+	var cfg ResponseCacheConfig
+	switch c := config.(type) {
+	case nil:
+	case ResponseCacheConfig:
+		cfg = c
+	case *ResponseCacheConfig:
+		cfg = *c
+	default:
+		if err := mapToStruct(config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid response cache config: %w", err)
+		}
+	}
+	return NewResponseCacheMiddleware(cfg), nil
+}
+
+func (f *ResponseCacheMiddlewareFactory) ConfigType() interface{} {
+	return ResponseCacheConfig{}
+}
+
+func (f *ResponseCacheMiddlewareFactory) Name() string {
+	return "response_cache"
+}
+
+func (f *ResponseCacheMiddlewareFactory) Description() string {
+	return "Caches read-only tool results and resource reads, invalidated by notifications"
+}
+
+// AuditMiddlewareFactory creates audit middleware instances. Each instance
+// owns its AuditLog; call its Close method to close the log.
+type AuditMiddlewareFactory struct{}
+
+func (f *AuditMiddlewareFactory) Create(config interface{}) (Middleware, error) {
+	var cfg AuditConfig
+	switch c := config.(type) {
+	case AuditConfig:
+		cfg = c
+	case *AuditConfig:
+		cfg = *c
+	default:
+		if err := mapToStruct(config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid audit config: %w", err)
+		}
+	}
+	log, err := NewAuditLog(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewAuditMiddleware(log, nil), nil
+}
+
+func (f *AuditMiddlewareFactory) ConfigType() interface{} {
+	return AuditConfig{}
+}
+
+func (f *AuditMiddlewareFactory) Name() string {
+	return "audit"
+}
+
+func (f *AuditMiddlewareFactory) Description() string {
+	return "Writes a tamper-evident, hash-chained audit log of requests"
+}
+
 // Configuration Types for Placeholder Middleware
 // ==============================================
 
@@ -704,6 +772,24 @@ func (r *InMemoryMetricsRegistry) RecordError(method string, errorType string, l
 	}
 }
 
+// RecordCacheLookup implements CacheMetricsRegistry
+func (r *InMemoryMetricsRegistry) RecordCacheLookup(cache string, method string, hit bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.metrics == nil {
+		r.metrics = make(map[string]interface{})
+	}
+
+	outcome := "miss"
+	if hit {
+		outcome = "hit"
+	}
+	key := fmt.Sprintf("cache_%s_%s_%s", outcome, cache, method)
+	count, _ := r.metrics[key].(int64)
+	r.metrics[key] = count + 1
+}
+
 // mapToStruct converts a map or other structure to a target struct using JSON marshaling
 func mapToStruct(source interface{}, target interface{}) error {
 	data, err := json.Marshal(source)
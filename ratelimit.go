@@ -5,6 +5,7 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -41,6 +42,12 @@ type RateLimiter interface {
 	Stats() RateLimitStats
 }
 
+// RetryAfterLimiter is implemented by rate limiters that can estimate how long
+// a rejected caller should wait before n requests would be allowed.
+type RetryAfterLimiter interface {
+	RetryAfter(ctx context.Context, key string, n int) time.Duration
+}
+
 // RateLimitStats provides rate limiting statistics
 type RateLimitStats struct {
 	TotalRequests    int64                `json:"totalRequests"`
@@ -58,11 +65,15 @@ type KeyStats struct {
 	LastSeen time.Time `json:"lastSeen"`
 }
 
-// TokenBucketRateLimiter implements token bucket algorithm
+// TokenBucketRateLimiter implements token bucket algorithm. Buckets are kept
+// in a RateLimitStore: a process-local one by default, or a shared one so that
+// several replicas enforce a single limit per key.
 type TokenBucketRateLimiter struct {
-	limiters        sync.Map
+	store           RateLimitStore
+	limiters        sync.Map // key -> *keyActivity, for keys seen by this process
 	defaultRate     float64
 	defaultBurst    int
+	failOpen        atomic.Bool
 	cleanupInterval time.Duration
 	cleanupTimer    *time.Timer
 	stats           atomic.Value // *RateLimitStats
@@ -71,18 +82,21 @@ type TokenBucketRateLimiter struct {
 	closed          bool
 }
 
-// tokenBucket represents a single token bucket
-type tokenBucket struct {
-	tokens   float64
-	lastFill time.Time
-	rate     float64
-	burst    int
-	mu       sync.Mutex
-	stats    *KeyStats
+// tokenBucketState is the stored form of a token bucket.
+type tokenBucketState struct {
+	Tokens   float64 `json:"t"`
+	LastFill int64   `json:"ts"` // unix nanoseconds
 }
 
-// NewTokenBucketRateLimiter creates a new token bucket rate limiter
+// NewTokenBucketRateLimiter creates a new token bucket rate limiter whose
+// buckets are kept in process memory.
 func NewTokenBucketRateLimiter(requestsPerSecond int, burstSize int) *TokenBucketRateLimiter {
+	return NewTokenBucketRateLimiterWithStore(NewMemoryRateLimitStore(), requestsPerSecond, burstSize)
+}
+
+// NewTokenBucketRateLimiterWithStore creates a token bucket rate limiter whose
+// buckets are kept in store.
+func NewTokenBucketRateLimiterWithStore(store RateLimitStore, requestsPerSecond int, burstSize int) *TokenBucketRateLimiter {
 	if requestsPerSecond <= 0 {
 		requestsPerSecond = 100
 	}
@@ -91,6 +105,7 @@ func NewTokenBucketRateLimiter(requestsPerSecond int, burstSize int) *TokenBucke
 	}
 
 	rl := &TokenBucketRateLimiter{
+		store:           store,
 		defaultRate:     float64(requestsPerSecond),
 		defaultBurst:    burstSize,
 		cleanupInterval: 10 * time.Minute,
@@ -115,6 +130,12 @@ func (rl *TokenBucketRateLimiter) SetRuleForKey(key string, rule RateLimitRule)
 	rl.perKeyRules[key] = rule
 }
 
+// SetFailOpen controls what happens when the store cannot be reached. By
+// default requests are rejected; with failOpen they are allowed instead.
+func (rl *TokenBucketRateLimiter) SetFailOpen(failOpen bool) {
+	rl.failOpen.Store(failOpen)
+}
+
 // Allow implements RateLimiter
 func (rl *TokenBucketRateLimiter) Allow(ctx context.Context, key string) bool {
 	return rl.AllowN(ctx, key, 1)
@@ -122,18 +143,8 @@ func (rl *TokenBucketRateLimiter) Allow(ctx context.Context, key string) bool {
 
 // AllowN implements RateLimiter
 func (rl *TokenBucketRateLimiter) AllowN(ctx context.Context, key string, n int) bool {
-	bucket := rl.getBucket(key)
-	allowed := bucket.allowN(n)
-
-	// Update stats
-	stats := rl.stats.Load().(*RateLimitStats)
-	atomic.AddInt64(&stats.TotalRequests, int64(n))
-	if allowed {
-		atomic.AddInt64(&stats.AllowedRequests, int64(n))
-	} else {
-		atomic.AddInt64(&stats.RejectedRequests, int64(n))
-	}
-
+	allowed, _ := rl.takeN(ctx, key, n)
+	rl.record(key, n, allowed)
 	return allowed
 }
 
@@ -144,177 +155,109 @@ func (rl *TokenBucketRateLimiter) Wait(ctx context.Context, key string) error {
 
 // WaitN implements RateLimiter
 func (rl *TokenBucketRateLimiter) WaitN(ctx context.Context, key string, n int) error {
-	bucket := rl.getBucket(key)
-
-	for {
-		if err := ctx.Err(); err != nil {
-			stats := rl.stats.Load().(*RateLimitStats)
-			atomic.AddInt64(&stats.TotalRequests, int64(n))
-			atomic.AddInt64(&stats.RejectedRequests, int64(n))
-			return err
-		}
-
-		// Check if we can proceed
-		if bucket.allowN(n) {
-			// Update stats
-			stats := rl.stats.Load().(*RateLimitStats)
-			atomic.AddInt64(&stats.TotalRequests, int64(n))
-			atomic.AddInt64(&stats.AllowedRequests, int64(n))
-			return nil
-		}
+	err := waitN(ctx, func() (bool, time.Duration) { return rl.takeN(ctx, key, n) })
+	rl.record(key, n, err == nil)
+	return err
+}
 
-		// Calculate wait time
-		waitTime := bucket.timeToAvailable(n)
-
-		select {
-		case <-ctx.Done():
-			// Update rejected stats
-			stats := rl.stats.Load().(*RateLimitStats)
-			atomic.AddInt64(&stats.TotalRequests, int64(n))
-			atomic.AddInt64(&stats.RejectedRequests, int64(n))
-			return ctx.Err()
-		case <-time.After(waitTime):
-			// Retry after waiting
-			continue
-		}
+// RetryAfter implements RetryAfterLimiter
+func (rl *TokenBucketRateLimiter) RetryAfter(ctx context.Context, key string, n int) time.Duration {
+	rate, _ := rl.limits(key)
+	old, ok, err := rl.store.Get(ctx, tokenBucketPrefix+key)
+	if err != nil || !ok {
+		return 0
+	}
+	var state tokenBucketState
+	if json.Unmarshal(old, &state) != nil {
+		return 0
 	}
+	elapsed := time.Since(time.Unix(0, state.LastFill)).Seconds()
+	return tokenWait(state.Tokens+elapsed*rate, n, rate)
 }
 
 // Reset implements RateLimiter
 func (rl *TokenBucketRateLimiter) Reset(key string) {
+	_ = rl.store.Delete(context.Background(), tokenBucketPrefix+key)
 	rl.limiters.Delete(key)
 }
 
-// Stats implements RateLimiter
+// Stats implements RateLimiter. The counts cover decisions made by this
+// process only; requests admitted by other users of a shared store are not
+// included.
 func (rl *TokenBucketRateLimiter) Stats() RateLimitStats {
-	stats := rl.stats.Load().(*RateLimitStats)
-
-	// Count active limiters
-	count := 0
-	rl.limiters.Range(func(_, _ interface{}) bool {
-		count++
-		return true
-	})
-	stats.ActiveLimiters = count
-
-	// Create a copy to avoid data races
-	statsCopy := RateLimitStats{
-		TotalRequests:    atomic.LoadInt64(&stats.TotalRequests),
-		AllowedRequests:  atomic.LoadInt64(&stats.AllowedRequests),
-		RejectedRequests: atomic.LoadInt64(&stats.RejectedRequests),
-		ActiveLimiters:   count,
-		PerKeyStats:      make(map[string]*KeyStats),
-	}
-
-	// Copy per-key stats
-	rl.limiters.Range(func(k, v interface{}) bool {
-		key := k.(string)
-		bucket := v.(*tokenBucket)
-		bucket.mu.Lock()
-		statsCopy.PerKeyStats[key] = &KeyStats{
-			Requests: bucket.stats.Requests,
-			Allowed:  bucket.stats.Allowed,
-			Rejected: bucket.stats.Rejected,
-			LastSeen: bucket.stats.LastSeen,
+	return limiterStats(rl.stats.Load().(*RateLimitStats), &rl.limiters)
+}
+
+// tokenBucketPrefix namespaces token buckets in a RateLimitStore.
+const tokenBucketPrefix = "mcp:ratelimit:tb:"
+
+// takeN takes n tokens from the bucket for key if it holds them, and
+// otherwise reports how long until it will.
+func (rl *TokenBucketRateLimiter) takeN(ctx context.Context, key string, n int) (bool, time.Duration) {
+	rate, burst := rl.limits(key)
+	// Keep the state until the bucket would be full again, then let it expire.
+	ttl := time.Duration(float64(burst)/rate*float64(time.Second)) + time.Second
+
+	allowed, retryAfter, err := updateStore(ctx, rl.store, tokenBucketPrefix+key, ttl, func(old []byte) ([]byte, bool, time.Duration) {
+		now := time.Now()
+		state := tokenBucketState{Tokens: float64(burst), LastFill: now.UnixNano()}
+		if old != nil && json.Unmarshal(old, &state) == nil {
+			elapsed := now.Sub(time.Unix(0, state.LastFill)).Seconds()
+			if elapsed > 0 {
+				state.Tokens = min(float64(burst), state.Tokens+elapsed*rate)
+			}
+			state.LastFill = now.UnixNano()
 		}
-		bucket.mu.Unlock()
-		return true
+		allowed := state.Tokens >= float64(n)
+		var retryAfter time.Duration
+		if allowed {
+			state.Tokens -= float64(n)
+		} else {
+			retryAfter = tokenWait(state.Tokens, n, rate)
+		}
+		next, _ := json.Marshal(state)
+		return next, allowed, retryAfter
 	})
-
-	return statsCopy
-}
-
-// getBucket gets or creates a token bucket for a key
-func (rl *TokenBucketRateLimiter) getBucket(key string) *tokenBucket {
-	// Check for existing bucket
-	if v, ok := rl.limiters.Load(key); ok {
-		return v.(*tokenBucket)
+	if err != nil {
+		return rl.failOpen.Load(), 0
 	}
+	return allowed, retryAfter
+}
 
-	// Determine rate and burst for this key
-	rate := rl.defaultRate
-	burst := rl.defaultBurst
+// record counts a decision in the limiter's statistics.
+func (rl *TokenBucketRateLimiter) record(key string, n int, allowed bool) {
+	recordDecision(rl.stats.Load().(*RateLimitStats), &rl.limiters, key, n, allowed)
+}
 
+// limits returns the refill rate and burst size for key.
+func (rl *TokenBucketRateLimiter) limits(key string) (float64, int) {
+	rate, burst := rl.defaultRate, rl.defaultBurst
 	rl.mu.RLock()
+	defer rl.mu.RUnlock()
 	if rule, ok := rl.perKeyRules[key]; ok {
-		rate = float64(rule.RequestsPerSecond)
-		burst = rule.BurstSize
-	}
-	rl.mu.RUnlock()
-
-	// Create new bucket
-	bucket := &tokenBucket{
-		tokens:   float64(burst),
-		lastFill: time.Now(),
-		rate:     rate,
-		burst:    burst,
-		stats: &KeyStats{
-			LastSeen: time.Now(),
-		},
+		if rule.RequestsPerSecond > 0 {
+			rate = float64(rule.RequestsPerSecond)
+		}
+		if rule.BurstSize > 0 {
+			burst = rule.BurstSize
+		}
 	}
-
-	// Store bucket (handle race condition)
-	actual, _ := rl.limiters.LoadOrStore(key, bucket)
-	return actual.(*tokenBucket)
+	return rate, burst
 }
 
-// allowN checks if n tokens are available
-func (b *tokenBucket) allowN(n int) bool {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	// Refill tokens based on time elapsed
-	now := time.Now()
-	elapsed := now.Sub(b.lastFill).Seconds()
-	b.tokens = min(float64(b.burst), b.tokens+elapsed*b.rate)
-	b.lastFill = now
-
-	// Update stats
-	b.stats.Requests += int64(n)
-	b.stats.LastSeen = now
-
-	// Check if we have enough tokens
-	if b.tokens >= float64(n) {
-		b.tokens -= float64(n)
-		b.stats.Allowed += int64(n)
-		return true
-	}
-
-	b.stats.Rejected += int64(n)
-	return false
-}
-
-// timeToAvailable calculates time until n tokens are available
-func (b *tokenBucket) timeToAvailable(n int) time.Duration {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	// Calculate tokens needed
-	tokensNeeded := float64(n) - b.tokens
-	if tokensNeeded <= 0 {
+// tokenWait returns how long it takes a bucket holding tokens to reach n.
+func tokenWait(tokens float64, n int, rate float64) time.Duration {
+	needed := float64(n) - tokens
+	if needed <= 0 {
 		return 0
 	}
-
-	// Calculate time to accumulate needed tokens
-	secondsNeeded := tokensNeeded / b.rate
-	return time.Duration(secondsNeeded * float64(time.Second))
+	return time.Duration(needed / rate * float64(time.Second))
 }
 
-// cleanup removes inactive token buckets
+// cleanup forgets the statistics of inactive keys. Their buckets expire from
+// the store on their own.
 func (rl *TokenBucketRateLimiter) cleanup() {
-	cutoff := time.Now().Add(-rl.cleanupInterval)
-
-	rl.limiters.Range(func(key, value interface{}) bool {
-		bucket := value.(*tokenBucket)
-		bucket.mu.Lock()
-		lastSeen := bucket.stats.LastSeen
-		bucket.mu.Unlock()
-
-		if lastSeen.Before(cutoff) {
-			rl.limiters.Delete(key)
-		}
-		return true
-	})
+	forgetInactive(&rl.limiters, time.Now().Add(-rl.cleanupInterval))
 
 	// Schedule next cleanup unless the limiter has been closed.
 	rl.mu.Lock()
@@ -325,8 +268,9 @@ func (rl *TokenBucketRateLimiter) cleanup() {
 }
 
 // Close stops the rate limiter's background cleanup timer. It is safe to call
-// more than once. After Close the limiter still enforces limits, but inactive
-// buckets are no longer reclaimed automatically.
+// more than once. After Close the limiter still enforces limits, but the
+// statistics of inactive keys are no longer reclaimed automatically. The
+// store is not closed.
 func (rl *TokenBucketRateLimiter) Close() error {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
@@ -340,11 +284,15 @@ func (rl *TokenBucketRateLimiter) Close() error {
 	return nil
 }
 
-// SlidingWindowRateLimiter implements sliding window algorithm
+// SlidingWindowRateLimiter implements sliding window algorithm. Windows are
+// kept in a RateLimitStore: a process-local one by default, or a shared one
+// so that several replicas enforce a single limit per key.
 type SlidingWindowRateLimiter struct {
-	windows         sync.Map
+	store           RateLimitStore
+	windows         sync.Map // key -> *keyActivity, for keys seen by this process
 	windowSize      time.Duration
 	maxRequests     int
+	failOpen        atomic.Bool
 	cleanupInterval time.Duration
 	cleanupTimer    *time.Timer
 	stats           atomic.Value // *RateLimitStats
@@ -353,15 +301,21 @@ type SlidingWindowRateLimiter struct {
 	closed          bool
 }
 
-// slidingWindow represents a single sliding window
-type slidingWindow struct {
-	requests []time.Time
-	mu       sync.Mutex
-	stats    *KeyStats
+// slidingWindowState is the stored form of a sliding window: the times of
+// the requests in it, oldest first, each with the number admitted then.
+type slidingWindowState struct {
+	Requests [][2]int64 `json:"r"` // unix nanoseconds, count
 }
 
 // NewSlidingWindowRateLimiter creates a new sliding window rate limiter
+// whose windows are kept in process memory.
 func NewSlidingWindowRateLimiter(windowSize time.Duration, maxRequests int) *SlidingWindowRateLimiter {
+	return NewSlidingWindowRateLimiterWithStore(NewMemoryRateLimitStore(), windowSize, maxRequests)
+}
+
+// NewSlidingWindowRateLimiterWithStore creates a sliding window rate limiter
+// whose windows are kept in store.
+func NewSlidingWindowRateLimiterWithStore(store RateLimitStore, windowSize time.Duration, maxRequests int) *SlidingWindowRateLimiter {
 	if windowSize <= 0 {
 		windowSize = time.Minute
 	}
@@ -370,6 +324,7 @@ func NewSlidingWindowRateLimiter(windowSize time.Duration, maxRequests int) *Sli
 	}
 
 	rl := &SlidingWindowRateLimiter{
+		store:           store,
 		windowSize:      windowSize,
 		maxRequests:     maxRequests,
 		cleanupInterval: 10 * time.Minute,
@@ -394,6 +349,12 @@ func (rl *SlidingWindowRateLimiter) SetRuleForKey(key string, rule RateLimitRule
 	rl.perKeyRules[key] = rule
 }
 
+// SetFailOpen controls what happens when the store cannot be reached. By
+// default requests are rejected; with failOpen they are allowed instead.
+func (rl *SlidingWindowRateLimiter) SetFailOpen(failOpen bool) {
+	rl.failOpen.Store(failOpen)
+}
+
 // Allow implements RateLimiter
 func (rl *SlidingWindowRateLimiter) Allow(ctx context.Context, key string) bool {
 	return rl.AllowN(ctx, key, 1)
@@ -401,18 +362,8 @@ func (rl *SlidingWindowRateLimiter) Allow(ctx context.Context, key string) bool
 
 // AllowN implements RateLimiter
 func (rl *SlidingWindowRateLimiter) AllowN(ctx context.Context, key string, n int) bool {
-	window := rl.getWindow(key)
-	allowed := window.allowN(n, rl.getWindowSize(key), rl.getMaxRequests(key))
-
-	// Update stats
-	stats := rl.stats.Load().(*RateLimitStats)
-	atomic.AddInt64(&stats.TotalRequests, int64(n))
-	if allowed {
-		atomic.AddInt64(&stats.AllowedRequests, int64(n))
-	} else {
-		atomic.AddInt64(&stats.RejectedRequests, int64(n))
-	}
-
+	allowed, _ := rl.addN(ctx, key, n)
+	rl.record(key, n, allowed)
 	return allowed
 }
 
@@ -423,94 +374,64 @@ func (rl *SlidingWindowRateLimiter) Wait(ctx context.Context, key string) error
 
 // WaitN implements RateLimiter
 func (rl *SlidingWindowRateLimiter) WaitN(ctx context.Context, key string, n int) error {
-	window := rl.getWindow(key)
-	windowSize := rl.getWindowSize(key)
-	maxRequests := rl.getMaxRequests(key)
-
-	for {
-		if window.allowN(n, windowSize, maxRequests) {
-			// Update stats
-			stats := rl.stats.Load().(*RateLimitStats)
-			atomic.AddInt64(&stats.TotalRequests, int64(n))
-			atomic.AddInt64(&stats.AllowedRequests, int64(n))
-			return nil
-		}
+	err := waitN(ctx, func() (bool, time.Duration) { return rl.addN(ctx, key, n) })
+	rl.record(key, n, err == nil)
+	return err
+}
 
-		// Calculate wait time
-		waitTime := window.timeToAvailable(windowSize)
-
-		select {
-		case <-ctx.Done():
-			// Update rejected stats
-			stats := rl.stats.Load().(*RateLimitStats)
-			atomic.AddInt64(&stats.TotalRequests, int64(n))
-			atomic.AddInt64(&stats.RejectedRequests, int64(n))
-			return ctx.Err()
-		case <-time.After(waitTime):
-			continue
-		}
+// RetryAfter implements RetryAfterLimiter
+func (rl *SlidingWindowRateLimiter) RetryAfter(ctx context.Context, key string, n int) time.Duration {
+	old, ok, err := rl.store.Get(ctx, slidingWindowPrefix+key)
+	if err != nil || !ok {
+		return 0
+	}
+	var state slidingWindowState
+	if json.Unmarshal(old, &state) != nil {
+		return 0
 	}
+	_, _, retryAfter := state.add(time.Now(), n, rl.getWindowSize(key), rl.getMaxRequests(key))
+	return retryAfter
 }
 
 // Reset implements RateLimiter
 func (rl *SlidingWindowRateLimiter) Reset(key string) {
+	_ = rl.store.Delete(context.Background(), slidingWindowPrefix+key)
 	rl.windows.Delete(key)
 }
 
-// Stats implements RateLimiter
+// Stats implements RateLimiter. The counts cover decisions made by this
+// process only; requests admitted by other users of a shared store are not
+// included.
 func (rl *SlidingWindowRateLimiter) Stats() RateLimitStats {
-	stats := rl.stats.Load().(*RateLimitStats)
-
-	// Count active windows
-	count := 0
-	rl.windows.Range(func(_, _ interface{}) bool {
-		count++
-		return true
-	})
-	stats.ActiveLimiters = count
-
-	// Create a copy
-	statsCopy := RateLimitStats{
-		TotalRequests:    atomic.LoadInt64(&stats.TotalRequests),
-		AllowedRequests:  atomic.LoadInt64(&stats.AllowedRequests),
-		RejectedRequests: atomic.LoadInt64(&stats.RejectedRequests),
-		ActiveLimiters:   count,
-		PerKeyStats:      make(map[string]*KeyStats),
-	}
-
-	// Copy per-key stats
-	rl.windows.Range(func(k, v interface{}) bool {
-		key := k.(string)
-		window := v.(*slidingWindow)
-		window.mu.Lock()
-		statsCopy.PerKeyStats[key] = &KeyStats{
-			Requests: window.stats.Requests,
-			Allowed:  window.stats.Allowed,
-			Rejected: window.stats.Rejected,
-			LastSeen: window.stats.LastSeen,
-		}
-		window.mu.Unlock()
-		return true
-	})
-
-	return statsCopy
+	return limiterStats(rl.stats.Load().(*RateLimitStats), &rl.windows)
 }
 
-// getWindow gets or creates a sliding window for a key
-func (rl *SlidingWindowRateLimiter) getWindow(key string) *slidingWindow {
-	if v, ok := rl.windows.Load(key); ok {
-		return v.(*slidingWindow)
-	}
+// slidingWindowPrefix namespaces sliding windows in a RateLimitStore.
+const slidingWindowPrefix = "mcp:ratelimit:sw:"
 
-	window := &slidingWindow{
-		requests: make([]time.Time, 0),
-		stats: &KeyStats{
-			LastSeen: time.Now(),
-		},
+// addN adds n requests to the window for key if they fit, and otherwise
+// reports how long until they will.
+func (rl *SlidingWindowRateLimiter) addN(ctx context.Context, key string, n int) (bool, time.Duration) {
+	windowSize := rl.getWindowSize(key)
+	maxRequests := rl.getMaxRequests(key)
+	allowed, retryAfter, err := updateStore(ctx, rl.store, slidingWindowPrefix+key, windowSize, func(old []byte) ([]byte, bool, time.Duration) {
+		var state slidingWindowState
+		if old != nil {
+			_ = json.Unmarshal(old, &state)
+		}
+		state, allowed, retryAfter := state.add(time.Now(), n, windowSize, maxRequests)
+		next, _ := json.Marshal(state)
+		return next, allowed, retryAfter
+	})
+	if err != nil {
+		return rl.failOpen.Load(), 0
 	}
+	return allowed, retryAfter
+}
 
-	actual, _ := rl.windows.LoadOrStore(key, window)
-	return actual.(*slidingWindow)
+// record counts a decision in the limiter's statistics.
+func (rl *SlidingWindowRateLimiter) record(key string, n int, allowed bool) {
+	recordDecision(rl.stats.Load().(*RateLimitStats), &rl.windows, key, n, allowed)
 }
 
 // getWindowSize returns the window size for a key
@@ -538,82 +459,41 @@ func (rl *SlidingWindowRateLimiter) getMaxRequests(key string) int {
 	return rl.maxRequests
 }
 
-// allowN checks if n requests are allowed in the sliding window
-func (w *slidingWindow) allowN(n int, windowSize time.Duration, maxRequests int) bool {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	now := time.Now()
-	cutoff := now.Add(-windowSize)
-
-	// Remove old requests outside the window
-	validRequests := make([]time.Time, 0, len(w.requests))
-	for _, reqTime := range w.requests {
-		if reqTime.After(cutoff) {
-			validRequests = append(validRequests, reqTime)
+// add drops the requests that have left the window at now and adds n more
+// if at most maxRequests remain. Otherwise it returns how long until enough
+// requests leave the window, or the window size if n alone exceeds it.
+func (s slidingWindowState) add(now time.Time, n int, windowSize time.Duration, maxRequests int) (slidingWindowState, bool, time.Duration) {
+	cutoff := now.Add(-windowSize).UnixNano()
+	var requests [][2]int64
+	count := 0
+	for _, r := range s.Requests {
+		if r[0] > cutoff {
+			requests = append(requests, r)
+			count += int(r[1])
 		}
 	}
-	w.requests = validRequests
+	s.Requests = requests
 
-	// Update stats
-	w.stats.Requests += int64(n)
-	w.stats.LastSeen = now
-
-	// Check if we can add n more requests
-	if len(w.requests)+n <= maxRequests {
-		// Add new requests
-		for i := 0; i < n; i++ {
-			w.requests = append(w.requests, now)
-		}
-		w.stats.Allowed += int64(n)
-		return true
+	if count+n <= maxRequests {
+		s.Requests = append(s.Requests, [2]int64{now.UnixNano(), int64(n)})
+		return s, true, 0
 	}
-
-	w.stats.Rejected += int64(n)
-	return false
-}
-
-// timeToAvailable calculates time until a request can be made
-func (w *slidingWindow) timeToAvailable(windowSize time.Duration) time.Duration {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	if len(w.requests) == 0 {
-		return 0
+	if n > maxRequests {
+		return s, false, windowSize
 	}
-
-	// Find the oldest request
-	oldestRequest := w.requests[0]
-	for _, reqTime := range w.requests {
-		if reqTime.Before(oldestRequest) {
-			oldestRequest = reqTime
+	excess := count + n - maxRequests
+	for _, r := range s.Requests {
+		if excess -= int(r[1]); excess <= 0 {
+			return s, false, time.Duration(r[0] - cutoff)
 		}
 	}
-
-	// Calculate when it will fall out of the window
-	timeUntilAvailable := time.Until(oldestRequest.Add(windowSize))
-	if timeUntilAvailable < 0 {
-		return 0
-	}
-
-	return timeUntilAvailable
+	return s, false, windowSize
 }
 
-// cleanup removes inactive sliding windows
+// cleanup forgets the statistics of inactive keys. Their windows expire from
+// the store on their own.
 func (rl *SlidingWindowRateLimiter) cleanup() {
-	cutoff := time.Now().Add(-rl.cleanupInterval)
-
-	rl.windows.Range(func(key, value interface{}) bool {
-		window := value.(*slidingWindow)
-		window.mu.Lock()
-		lastSeen := window.stats.LastSeen
-		window.mu.Unlock()
-
-		if lastSeen.Before(cutoff) {
-			rl.windows.Delete(key)
-		}
-		return true
-	})
+	forgetInactive(&rl.windows, time.Now().Add(-rl.cleanupInterval))
 
 	// Schedule next cleanup unless the limiter has been closed.
 	rl.mu.Lock()
@@ -624,8 +504,9 @@ func (rl *SlidingWindowRateLimiter) cleanup() {
 }
 
 // Close stops the rate limiter's background cleanup timer. It is safe to call
-// more than once. After Close the limiter still enforces limits, but inactive
-// windows are no longer reclaimed automatically.
+// more than once. After Close the limiter still enforces limits, but the
+// statistics of inactive keys are no longer reclaimed automatically. The
+// store is not closed.
 func (rl *SlidingWindowRateLimiter) Close() error {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
@@ -727,6 +608,25 @@ func (c *CompositeRateLimiter) WaitN(ctx context.Context, key string, n int) err
 	return fmt.Errorf("waitN not supported for 'any' strategy")
 }
 
+// RetryAfter implements RetryAfterLimiter. With the "all" strategy it returns
+// the longest hint of the member limiters, with "any" the shortest.
+func (c *CompositeRateLimiter) RetryAfter(ctx context.Context, key string, n int) time.Duration {
+	var result time.Duration
+	first := true
+	for _, limiter := range c.limiters {
+		ra, ok := limiter.(RetryAfterLimiter)
+		if !ok {
+			continue
+		}
+		d := ra.RetryAfter(ctx, key, n)
+		if first || (c.strategy == "all" && d > result) || (c.strategy == "any" && d < result) {
+			result = d
+		}
+		first = false
+	}
+	return result
+}
+
 // Reset implements RateLimiter
 func (c *CompositeRateLimiter) Reset(key string) {
 	for _, limiter := range c.limiters {
@@ -765,8 +665,11 @@ type EnhancedRateLimitMiddleware struct {
 	limiter         RateLimiter
 	keyExtractor    func(context.Context, MCPRequest) string
 	skipMethods     map[string]bool
-	errorHandler    func(context.Context, MCPRequest) MCPResponse
+	errorHandler    func(ctx context.Context, req MCPRequest, retryAfter time.Duration) MCPResponse
 	perEndpointRate bool // Enable per-endpoint rate limiting
+	toolCosts       map[string]int
+	defaultToolCost int
+	metrics         MetricsRegistry
 }
 
 // NewEnhancedRateLimitMiddleware creates enhanced rate limiting middleware
@@ -791,8 +694,11 @@ func NewEnhancedRateLimitMiddleware(limiter RateLimiter, config RateLimitConfig)
 		keyExtractor:    config.KeyExtractor,
 		skipMethods:     skipMethods,
 		perEndpointRate: config.PerEndpointLimiting,
-		errorHandler: func(ctx context.Context, req MCPRequest) MCPResponse {
-			return NewRateLimitError("Rate limit exceeded")
+		toolCosts:       config.ToolCosts,
+		defaultToolCost: config.DefaultToolCost,
+		metrics:         config.Metrics,
+		errorHandler: func(ctx context.Context, req MCPRequest, retryAfter time.Duration) MCPResponse {
+			return NewRateLimitErrorWithRetry("Rate limit exceeded", retryAfter)
 		},
 	}
 }
@@ -820,9 +726,15 @@ func (m *EnhancedRateLimitMiddleware) Apply(next MCPHandler) MCPHandler {
 			limiter = m.limiter
 		}
 
-		// Check rate limit
-		if !limiter.Allow(ctx, key) {
-			return m.errorHandler(ctx, req), nil
+		// Check rate limit, charging tool calls their configured cost
+		cost := requestCost(req, m.toolCosts, m.defaultToolCost)
+		if !limiter.AllowN(ctx, key, cost) {
+			var retryAfter time.Duration
+			if ra, ok := limiter.(RetryAfterLimiter); ok {
+				retryAfter = ra.RetryAfter(ctx, key, cost)
+			}
+			recordRateLimitRejection(m.metrics, req)
+			return m.errorHandler(ctx, req, retryAfter), nil
 		}
 
 		return next.Handle(ctx, req)
@@ -0,0 +1,539 @@
+// Package mcp - Rate Limit Stores
+// This file implements the stores the token bucket and sliding window rate
+// limiters keep their state in. Replicas sharing a store enforce a single
+// limit per key. An in-memory store and a store speaking the Redis protocol
+// (RESP) are provided.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitStore persists the state of TokenBucketRateLimiter and
+// SlidingWindowRateLimiter.
+//
+// CompareAndSwap must be atomic with respect to every other caller of the same
+// store, including callers in other processes; the limiters rely on it to
+// apply read-modify-write updates without a lock.
+type RateLimitStore interface {
+	// Get returns the value stored for key. ok is false if the key is absent
+	// or has expired.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+
+	// CompareAndSwap stores new under key with the given TTL, but only if the
+	// current value equals old. A nil old requires the key to be absent.
+	// It reports whether the value was swapped.
+	CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error)
+
+	// Delete removes key from the store.
+	Delete(ctx context.Context, key string) error
+}
+
+// maxCASAttempts bounds the compare-and-swap retries of a single limiter decision.
+const maxCASAttempts = 16
+
+// Memory Store
+// ============
+
+// MemoryRateLimitStore is a RateLimitStore backed by a process-local map.
+// It is useful for tests and for sharing one limit between limiters in the
+// same process.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryStoreEntry
+	writes  int
+}
+
+type memoryStoreEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory rate limit store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		entries: make(map[string]memoryStoreEntry),
+	}
+}
+
+// Get implements RateLimitStore
+func (s *MemoryRateLimitStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.load(key, time.Now())
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]byte(nil), entry.value...), true, nil
+}
+
+// CompareAndSwap implements RateLimitStore
+func (s *MemoryRateLimitStore) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.load(key, now)
+	if old == nil {
+		if ok {
+			return false, nil
+		}
+	} else if !ok || !bytes.Equal(entry.value, old) {
+		return false, nil
+	}
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = now.Add(ttl)
+	}
+	s.entries[key] = memoryStoreEntry{value: append([]byte(nil), new...), expires: expires}
+
+	// Expired entries are otherwise only dropped when read, so sweep
+	// periodically to keep abandoned keys from accumulating.
+	s.writes++
+	if s.writes%1024 == 0 {
+		for k, e := range s.entries {
+			if !e.expires.IsZero() && now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	return true, nil
+}
+
+// Delete implements RateLimitStore
+func (s *MemoryRateLimitStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// load returns the live entry for key. The caller must hold s.mu.
+func (s *MemoryRateLimitStore) load(key string, now time.Time) (memoryStoreEntry, bool) {
+	entry, ok := s.entries[key]
+	if !ok {
+		return entry, false
+	}
+	if !entry.expires.IsZero() && now.After(entry.expires) {
+		delete(s.entries, key)
+		return entry, false
+	}
+	return entry, true
+}
+
+// RESP Store
+// ==========
+
+// RESPStoreConfig configures a RESPRateLimitStore.
+type RESPStoreConfig struct {
+	Password    string        `json:"password,omitempty" yaml:"password,omitempty"`
+	DB          int           `json:"db,omitempty" yaml:"db,omitempty"`
+	KeyPrefix   string        `json:"keyPrefix,omitempty" yaml:"keyPrefix,omitempty"`
+	DialTimeout time.Duration `json:"dialTimeout,omitempty" yaml:"dialTimeout,omitempty"`
+	MaxIdle     int           `json:"maxIdle,omitempty" yaml:"maxIdle,omitempty"`
+
+	// Dial overrides how connections are opened, for example to use TLS.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error) `json:"-" yaml:"-"`
+}
+
+// RESPRateLimitStore is a RateLimitStore backed by a Redis-compatible server.
+// It speaks RESP directly and implements CompareAndSwap with WATCH/MULTI/EXEC,
+// so it works with Redis, Valkey, KeyDB and other servers implementing
+// optimistic transactions.
+type RESPRateLimitStore struct {
+	addr   string
+	config RESPStoreConfig
+	idle   chan *respConn
+	closed atomic.Bool
+}
+
+// NewRESPRateLimitStore creates a store for the server at addr. Connections
+// are opened lazily and pooled.
+func NewRESPRateLimitStore(addr string, config *RESPStoreConfig) *RESPRateLimitStore {
+	cfg := RESPStoreConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.MaxIdle <= 0 {
+		cfg.MaxIdle = 4
+	}
+	if cfg.Dial == nil {
+		dialer := &net.Dialer{Timeout: cfg.DialTimeout}
+		cfg.Dial = dialer.DialContext
+	}
+	return &RESPRateLimitStore{
+		addr:   addr,
+		config: cfg,
+		idle:   make(chan *respConn, cfg.MaxIdle),
+	}
+}
+
+// Get implements RateLimitStore
+func (s *RESPRateLimitStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var value []byte
+	var ok bool
+	err := s.do(ctx, func(c *respConn) error {
+		reply, err := c.command("GET", s.config.KeyPrefix+key)
+		if err != nil {
+			return err
+		}
+		value, ok = reply.([]byte)
+		return nil
+	})
+	return value, ok, err
+}
+
+// CompareAndSwap implements RateLimitStore
+func (s *RESPRateLimitStore) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	key = s.config.KeyPrefix + key
+	var swapped bool
+	err := s.do(ctx, func(c *respConn) error {
+		if _, err := c.command("WATCH", key); err != nil {
+			return err
+		}
+		// After WATCH, a failure must not leave the connection watching key,
+		// or inside MULTI, when do returns it to the pool. An error reply is
+		// followed by reset; if that fails too, the wrapped error is no
+		// longer a reply and do closes the connection.
+		reset := func(cmd string, err error) error {
+			if _, isReply := err.(respError); !isReply {
+				return err
+			}
+			if _, rerr := c.command(cmd); rerr != nil {
+				return fmt.Errorf("%w (%s: %v)", err, cmd, rerr)
+			}
+			return err
+		}
+		reply, err := c.command("GET", key)
+		if err != nil {
+			return reset("UNWATCH", err)
+		}
+		current, exists := reply.([]byte)
+		if (old == nil && exists) || (old != nil && (!exists || !bytes.Equal(current, old))) {
+			if _, err := c.command("UNWATCH"); err != nil {
+				return fmt.Errorf("UNWATCH: %w", err)
+			}
+			return nil
+		}
+
+		args := []string{"SET", key, string(new)}
+		if ttl > 0 {
+			args = append(args, "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+		}
+		if _, err := c.command("MULTI"); err != nil {
+			return reset("UNWATCH", err)
+		}
+		if _, err := c.command(args...); err != nil {
+			return reset("DISCARD", err)
+		}
+		// EXEC ends the transaction and the WATCH even when it fails.
+		reply, err = c.command("EXEC")
+		if err != nil {
+			return err
+		}
+		// A null reply means a watched key changed and the transaction was aborted.
+		swapped = reply != nil
+		return nil
+	})
+	return swapped, err
+}
+
+// Delete implements RateLimitStore
+func (s *RESPRateLimitStore) Delete(ctx context.Context, key string) error {
+	return s.do(ctx, func(c *respConn) error {
+		_, err := c.command("DEL", s.config.KeyPrefix+key)
+		return err
+	})
+}
+
+// Close closes all idle connections. It is safe to call more than once.
+func (s *RESPRateLimitStore) Close() error {
+	if s.closed.Swap(true) {
+		return nil
+	}
+	for {
+		select {
+		case c := <-s.idle:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do runs fn on a pooled connection. Connections that fail are discarded
+// rather than returned to the pool, since their protocol state is unknown.
+func (s *RESPRateLimitStore) do(ctx context.Context, fn func(*respConn) error) error {
+	if s.closed.Load() {
+		return fmt.Errorf("rate limit store closed")
+	}
+	c, err := s.get(ctx)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+	} else {
+		c.conn.SetDeadline(time.Time{})
+	}
+	if err := fn(c); err != nil {
+		if _, isReply := err.(respError); !isReply {
+			c.conn.Close()
+			return err
+		}
+		s.put(c)
+		return err
+	}
+	s.put(c)
+	return nil
+}
+
+func (s *RESPRateLimitStore) get(ctx context.Context) (*respConn, error) {
+	select {
+	case c := <-s.idle:
+		return c, nil
+	default:
+	}
+
+	conn, err := s.config.Dial(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("dialing rate limit store: %w", err)
+	}
+	c := &respConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	if s.config.Password != "" {
+		if _, err := c.command("AUTH", s.config.Password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("authenticating to rate limit store: %w", err)
+		}
+	}
+	if s.config.DB != 0 {
+		if _, err := c.command("SELECT", strconv.Itoa(s.config.DB)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("selecting rate limit store database: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (s *RESPRateLimitStore) put(c *respConn) {
+	if s.closed.Load() {
+		c.conn.Close()
+		return
+	}
+	select {
+	case s.idle <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+// respConn is a single RESP connection.
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// respError is an error reply sent by the server.
+type respError string
+
+func (e respError) Error() string { return "rate limit store: " + string(e) }
+
+// command sends args as a RESP array and reads one reply. Replies decode to
+// string (simple strings), int64, []byte (bulk strings), []interface{} and nil.
+func (c *respConn) command(args ...string) (interface{}, error) {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return readRESP(c.r)
+}
+
+// readRESP reads a single RESP value.
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed RESP line %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, respError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed RESP bulk length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed RESP array length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readRESP(r); err != nil {
+				// Error replies inside EXEC results are values, not failures.
+				if re, ok := err.(respError); ok {
+					items[i] = re
+					continue
+				}
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown RESP type %q", kind)
+	}
+}
+
+// Limiter Helpers
+// ===============
+
+// updateStore runs a compare-and-swap loop over the state stored for key.
+// step receives the current value (nil if absent) and returns the
+// replacement, whether the request is allowed, and the retry hint. An error
+// means the store could not be reached.
+func updateStore(ctx context.Context, store RateLimitStore, key string, ttl time.Duration, step func(old []byte) ([]byte, bool, time.Duration)) (bool, time.Duration, error) {
+	for attempt := 0; attempt < maxCASAttempts; attempt++ {
+		old, ok, err := store.Get(ctx, key)
+		if err != nil {
+			return false, 0, err
+		}
+		if !ok {
+			old = nil
+		}
+		next, allowed, retryAfter := step(old)
+		swapped, err := store.CompareAndSwap(ctx, key, old, next, ttl)
+		if err != nil {
+			return false, 0, err
+		}
+		if swapped {
+			return allowed, retryAfter, nil
+		}
+	}
+	// Heavy contention on one key; treat it as over the limit.
+	return false, 0, nil
+}
+
+// waitN polls allowN, sleeping for the retry hint between attempts.
+func waitN(ctx context.Context, allowN func() (bool, time.Duration)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		allowed, retryAfter := allowN()
+		if allowed {
+			return nil
+		}
+		if retryAfter <= 0 {
+			retryAfter = 10 * time.Millisecond
+		}
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// keyActivity is what a limiter remembers about a key in process memory:
+// the statistics of its own decisions. The limit state is in the store.
+type keyActivity struct {
+	mu    sync.Mutex
+	stats KeyStats
+}
+
+// recordDecision counts a decision on n requests for key in stats and in
+// the key's activity in keys.
+func recordDecision(stats *RateLimitStats, keys *sync.Map, key string, n int, allowed bool) {
+	atomic.AddInt64(&stats.TotalRequests, int64(n))
+	if allowed {
+		atomic.AddInt64(&stats.AllowedRequests, int64(n))
+	} else {
+		atomic.AddInt64(&stats.RejectedRequests, int64(n))
+	}
+
+	v, _ := keys.LoadOrStore(key, &keyActivity{})
+	a := v.(*keyActivity)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stats.Requests += int64(n)
+	if allowed {
+		a.stats.Allowed += int64(n)
+	} else {
+		a.stats.Rejected += int64(n)
+	}
+	a.stats.LastSeen = time.Now()
+}
+
+// limiterStats returns a copy of stats with the per-key statistics in keys.
+func limiterStats(stats *RateLimitStats, keys *sync.Map) RateLimitStats {
+	statsCopy := RateLimitStats{
+		TotalRequests:    atomic.LoadInt64(&stats.TotalRequests),
+		AllowedRequests:  atomic.LoadInt64(&stats.AllowedRequests),
+		RejectedRequests: atomic.LoadInt64(&stats.RejectedRequests),
+		PerKeyStats:      make(map[string]*KeyStats),
+	}
+	keys.Range(func(k, v interface{}) bool {
+		a := v.(*keyActivity)
+		a.mu.Lock()
+		ks := a.stats
+		a.mu.Unlock()
+		statsCopy.PerKeyStats[k.(string)] = &ks
+		return true
+	})
+	statsCopy.ActiveLimiters = len(statsCopy.PerKeyStats)
+	return statsCopy
+}
+
+// forgetInactive removes the keys last seen before cutoff.
+func forgetInactive(keys *sync.Map, cutoff time.Time) {
+	keys.Range(func(key, value interface{}) bool {
+		a := value.(*keyActivity)
+		a.mu.Lock()
+		lastSeen := a.stats.LastSeen
+		a.mu.Unlock()
+
+		if lastSeen.Before(cutoff) {
+			keys.Delete(key)
+		}
+		return true
+	})
+}
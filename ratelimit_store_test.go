@@ -0,0 +1,594 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRESPServer is a minimal Redis stand-in implementing the commands used by
+// RESPRateLimitStore, including WATCH/MULTI/EXEC optimistic transactions.
+type fakeRESPServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	data     map[string]fakeRESPEntry
+	versions map[string]uint64
+	password string
+	execs    int
+	aborts   int
+}
+
+type fakeRESPEntry struct {
+	value   string
+	expires time.Time
+}
+
+func newFakeRESPServer(t *testing.T, password string) *fakeRESPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeRESPServer{
+		ln:       ln,
+		data:     make(map[string]fakeRESPEntry),
+		versions: make(map[string]uint64),
+		password: password,
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRESPServer) addr() string { return s.ln.Addr().String() }
+
+func (s *fakeRESPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	authed := s.password == ""
+	watched := map[string]uint64{}
+	var queued [][]string
+	inMulti := false
+
+	for {
+		v, err := readRESP(r)
+		if err != nil {
+			return
+		}
+		items, _ := v.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			b, _ := item.([]byte)
+			args[i] = string(b)
+		}
+		if len(args) == 0 {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+
+		switch {
+		case cmd == "AUTH":
+			authed = len(args) == 2 && args[1] == s.password
+			if authed {
+				w.WriteString("+OK\r\n")
+			} else {
+				w.WriteString("-WRONGPASS invalid password\r\n")
+			}
+		case !authed:
+			w.WriteString("-NOAUTH Authentication required\r\n")
+		case cmd == "WATCH":
+			s.mu.Lock()
+			for _, k := range args[1:] {
+				watched[k] = s.versions[k]
+			}
+			s.mu.Unlock()
+			w.WriteString("+OK\r\n")
+		case cmd == "UNWATCH":
+			watched = map[string]uint64{}
+			w.WriteString("+OK\r\n")
+		case cmd == "MULTI":
+			inMulti = true
+			queued = nil
+			w.WriteString("+OK\r\n")
+		case cmd == "DISCARD":
+			inMulti = false
+			queued = nil
+			watched = map[string]uint64{}
+			w.WriteString("+OK\r\n")
+		case cmd == "EXEC":
+			s.mu.Lock()
+			s.execs++
+			aborted := false
+			for k, ver := range watched {
+				if s.versions[k] != ver {
+					aborted = true
+				}
+			}
+			if aborted {
+				s.aborts++
+				w.WriteString("*-1\r\n")
+			} else {
+				fmt.Fprintf(w, "*%d\r\n", len(queued))
+				for _, q := range queued {
+					w.WriteString(s.apply(q))
+				}
+			}
+			s.mu.Unlock()
+			inMulti = false
+			queued = nil
+			watched = map[string]uint64{}
+		case inMulti:
+			queued = append(queued, args)
+			w.WriteString("+QUEUED\r\n")
+		default:
+			s.mu.Lock()
+			w.WriteString(s.apply(args))
+			s.mu.Unlock()
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// apply executes a data command and returns its encoded reply. s.mu must be held.
+func (s *fakeRESPServer) apply(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		if strings.Contains(args[1], "wrongtype") {
+			return "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+		}
+		e, ok := s.data[args[1]]
+		if !ok || (!e.expires.IsZero() && time.Now().After(e.expires)) {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(e.value), e.value)
+	case "SET":
+		e := fakeRESPEntry{value: args[2]}
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			e.expires = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		s.data[args[1]] = e
+		s.versions[args[1]]++
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := s.data[args[1]]
+		delete(s.data, args[1])
+		s.versions[args[1]]++
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+// testRateLimitStore runs the RateLimitStore contract against store.
+func testRateLimitStore(t *testing.T, store RateLimitStore) {
+	ctx := context.Background()
+
+	if _, ok, err := store.Get(ctx, "k"); err != nil || ok {
+		t.Fatalf("Get on empty store = ok %v, err %v", ok, err)
+	}
+	if ok, err := store.CompareAndSwap(ctx, "k", []byte("x"), []byte("a"), 0); err != nil || ok {
+		t.Fatalf("CAS with stale old on absent key = %v, %v; want false", ok, err)
+	}
+	if ok, err := store.CompareAndSwap(ctx, "k", nil, []byte("a"), time.Minute); err != nil || !ok {
+		t.Fatalf("CAS create = %v, %v; want true", ok, err)
+	}
+	if ok, err := store.CompareAndSwap(ctx, "k", nil, []byte("b"), time.Minute); err != nil || ok {
+		t.Fatalf("CAS create on existing key = %v, %v; want false", ok, err)
+	}
+	if ok, err := store.CompareAndSwap(ctx, "k", []byte("a"), []byte("b"), time.Minute); err != nil || !ok {
+		t.Fatalf("CAS update = %v, %v; want true", ok, err)
+	}
+	if v, ok, err := store.Get(ctx, "k"); err != nil || !ok || string(v) != "b" {
+		t.Fatalf("Get = %q, %v, %v; want b", v, ok, err)
+	}
+	if err := store.Delete(ctx, "k"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok, _ := store.Get(ctx, "k"); ok {
+		t.Fatal("key present after Delete")
+	}
+
+	// Expiry.
+	if ok, err := store.CompareAndSwap(ctx, "ttl", nil, []byte("v"), 20*time.Millisecond); err != nil || !ok {
+		t.Fatalf("CAS with ttl = %v, %v", ok, err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok, _ := store.Get(ctx, "ttl"); ok {
+		t.Fatal("key present after TTL elapsed")
+	}
+
+	// Concurrent increments must not lose updates.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				for {
+					old, ok, err := store.Get(ctx, "counter")
+					if err != nil {
+						t.Errorf("Get: %v", err)
+						return
+					}
+					n := 0
+					if ok {
+						n, _ = strconv.Atoi(string(old))
+					} else {
+						old = nil
+					}
+					swapped, err := store.CompareAndSwap(ctx, "counter", old, []byte(strconv.Itoa(n+1)), 0)
+					if err != nil {
+						t.Errorf("CAS: %v", err)
+						return
+					}
+					if swapped {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _, _ := store.Get(ctx, "counter"); string(v) != "80" {
+		t.Fatalf("counter = %q, want 80", v)
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	testRateLimitStore(t, NewMemoryRateLimitStore())
+}
+
+func TestRESPRateLimitStore(t *testing.T) {
+	t.Run("contract", func(t *testing.T) {
+		srv := newFakeRESPServer(t, "")
+		store := NewRESPRateLimitStore(srv.addr(), &RESPStoreConfig{KeyPrefix: "test:"})
+		defer store.Close()
+		testRateLimitStore(t, store)
+
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		if _, ok := srv.data["test:counter"]; !ok {
+			t.Error("KeyPrefix not applied to stored keys")
+		}
+		if srv.aborts == 0 {
+			t.Log("no contended transactions observed")
+		}
+	})
+
+	t.Run("error after watch", func(t *testing.T) {
+		srv := newFakeRESPServer(t, "")
+		store := NewRESPRateLimitStore(srv.addr(), &RESPStoreConfig{MaxIdle: 1})
+		defer store.Close()
+		other := NewRESPRateLimitStore(srv.addr(), nil)
+		defer other.Close()
+		ctx := context.Background()
+
+		if _, err := store.CompareAndSwap(ctx, "wrongtype", nil, []byte("1"), 0); err == nil {
+			t.Fatal("CompareAndSwap on a key of the wrong type succeeded")
+		}
+		// A WATCH left on the pooled connection would abort the next swap.
+		if err := other.Delete(ctx, "wrongtype"); err != nil {
+			t.Fatal(err)
+		}
+		if swapped, err := store.CompareAndSwap(ctx, "k", nil, []byte("1"), 0); err != nil || !swapped {
+			t.Errorf("CompareAndSwap after a failed one = %v, %v; want swapped", swapped, err)
+		}
+	})
+
+	t.Run("auth", func(t *testing.T) {
+		srv := newFakeRESPServer(t, "hunter2")
+
+		bad := NewRESPRateLimitStore(srv.addr(), &RESPStoreConfig{Password: "wrong"})
+		defer bad.Close()
+		if _, _, err := bad.Get(context.Background(), "k"); err == nil {
+			t.Fatal("Get with wrong password succeeded")
+		}
+
+		good := NewRESPRateLimitStore(srv.addr(), &RESPStoreConfig{Password: "hunter2"})
+		defer good.Close()
+		if _, _, err := good.Get(context.Background(), "k"); err != nil {
+			t.Fatalf("Get with password: %v", err)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		srv := newFakeRESPServer(t, "")
+		store := NewRESPRateLimitStore(srv.addr(), nil)
+		if err := store.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if err := store.Close(); err != nil {
+			t.Fatalf("second Close: %v", err)
+		}
+		if _, _, err := store.Get(context.Background(), "k"); err == nil {
+			t.Fatal("Get after Close succeeded")
+		}
+	})
+}
+
+func TestTokenBucketRateLimiterStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("shared across replicas", func(t *testing.T) {
+		srv := newFakeRESPServer(t, "")
+		storeA := NewRESPRateLimitStore(srv.addr(), nil)
+		storeB := NewRESPRateLimitStore(srv.addr(), nil)
+		defer storeA.Close()
+		defer storeB.Close()
+		a := NewTokenBucketRateLimiterWithStore(storeA, 1, 4)
+		b := NewTokenBucketRateLimiterWithStore(storeB, 1, 4)
+
+		allowed := 0
+		for i := 0; i < 4; i++ {
+			if a.Allow(ctx, "client") {
+				allowed++
+			}
+			if b.Allow(ctx, "client") {
+				allowed++
+			}
+		}
+		if allowed != 4 {
+			t.Fatalf("allowed %d requests across replicas, want 4", allowed)
+		}
+		if d := a.RetryAfter(ctx, "client", 1); d <= 0 || d > time.Second {
+			t.Fatalf("RetryAfter = %v, want (0, 1s]", d)
+		}
+		if got := a.Stats().TotalRequests + b.Stats().TotalRequests; got != 8 {
+			t.Fatalf("total requests = %d, want 8", got)
+		}
+	})
+
+	t.Run("cost weighted", func(t *testing.T) {
+		rl := NewTokenBucketRateLimiterWithStore(NewMemoryRateLimitStore(), 1, 5)
+		if !rl.AllowN(ctx, "k", 3) {
+			t.Fatal("AllowN(3) rejected with 5 tokens")
+		}
+		if rl.AllowN(ctx, "k", 3) {
+			t.Fatal("AllowN(3) allowed with 2 tokens")
+		}
+		if !rl.AllowN(ctx, "k", 2) {
+			t.Fatal("AllowN(2) rejected with 2 tokens")
+		}
+		rl.Reset("k")
+		if !rl.AllowN(ctx, "k", 5) {
+			t.Fatal("AllowN(5) rejected after Reset")
+		}
+	})
+
+	t.Run("per key rule", func(t *testing.T) {
+		rl := NewTokenBucketRateLimiterWithStore(NewMemoryRateLimitStore(), 1, 1)
+		rl.SetRuleForKey("vip", RateLimitRule{RequestsPerSecond: 1, BurstSize: 3})
+		for i := 0; i < 3; i++ {
+			if !rl.Allow(ctx, "vip") {
+				t.Fatalf("vip request %d rejected", i)
+			}
+		}
+		if rl.Allow(ctx, "vip") {
+			t.Fatal("vip exceeded its burst")
+		}
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		rl := NewTokenBucketRateLimiterWithStore(NewMemoryRateLimitStore(), 50, 1)
+		if !rl.Allow(ctx, "k") {
+			t.Fatal("first request rejected")
+		}
+		wctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		if err := rl.Wait(wctx, "k"); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	})
+
+	t.Run("store failure", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := ln.Addr().String()
+		ln.Close()
+		store := NewRESPRateLimitStore(addr, &RESPStoreConfig{DialTimeout: 100 * time.Millisecond})
+		defer store.Close()
+
+		rl := NewTokenBucketRateLimiterWithStore(store, 1, 1)
+		if rl.Allow(ctx, "k") {
+			t.Fatal("request allowed with unreachable store while failing closed")
+		}
+		rl.SetFailOpen(true)
+		if !rl.Allow(ctx, "k") {
+			t.Fatal("request rejected with unreachable store while failing open")
+		}
+	})
+}
+
+func TestSlidingWindowRateLimiterStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRateLimitStore()
+	a := NewSlidingWindowRateLimiterWithStore(store, time.Hour, 3)
+	b := NewSlidingWindowRateLimiterWithStore(store, time.Hour, 3)
+	defer a.Close()
+	defer b.Close()
+
+	if !a.AllowN(ctx, "k", 2) || !b.Allow(ctx, "k") {
+		t.Fatal("requests within the limit rejected")
+	}
+	if a.Allow(ctx, "k") {
+		t.Fatal("request over the shared limit allowed")
+	}
+	if d := b.RetryAfter(ctx, "k", 1); d <= 0 || d > time.Hour {
+		t.Fatalf("RetryAfter = %v, want (0, 1h]", d)
+	}
+
+	t.Run("retry after n", func(t *testing.T) {
+		now := time.Unix(100, 0)
+		state := slidingWindowState{Requests: [][2]int64{
+			{now.Add(-900 * time.Millisecond).UnixNano(), 2},
+			{now.Add(-500 * time.Millisecond).UnixNano(), 3},
+			{now.Add(-2 * time.Second).UnixNano(), 4}, // out of the window
+		}}
+		tests := []struct {
+			n    int
+			want time.Duration
+		}{
+			{1, 0},
+			{2, 100 * time.Millisecond}, // the oldest two leave
+			{4, 500 * time.Millisecond}, // all five must leave
+			{7, time.Second},            // never fits
+		}
+		for _, tt := range tests {
+			_, allowed, retryAfter := state.add(now, tt.n, time.Second, 6)
+			if allowed != (tt.want == 0) || retryAfter != tt.want {
+				t.Errorf("add(%d) = %v, %v; want retry after %v", tt.n, allowed, retryAfter, tt.want)
+			}
+		}
+	})
+}
+
+func TestRateLimitToolCosts(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewTokenBucketRateLimiter(1, 5)
+	defer limiter.Close()
+	mw := NewEnhancedRateLimitMiddleware(limiter, RateLimitConfig{
+		ToolCosts: map[string]int{"expensive": 4},
+	})
+	handler := mw.Apply(MCPHandlerFunc(func(ctx context.Context, req MCPRequest) (MCPResponse, error) {
+		return &successResponse{result: "ok"}, nil
+	}))
+	call := func(tool string) MCPResponse {
+		t.Helper()
+		resp, err := handler.Handle(ctx, &UnifiedRequest{
+			method: string(MethodToolsCall),
+			params: []byte(fmt.Sprintf(`{"name":%q}`, tool)),
+			ctx:    ctx,
+		})
+		if err != nil {
+			t.Fatalf("Handle: %v", err)
+		}
+		return resp
+	}
+
+	if resp := call("expensive"); resp.IsError() {
+		t.Fatalf("first expensive call rejected: %v", resp.Error())
+	}
+	if resp := call("cheap"); resp.IsError() {
+		t.Fatalf("cheap call rejected with one token left: %v", resp.Error())
+	}
+	resp := call("expensive")
+	if !resp.IsError() {
+		t.Fatal("expensive call allowed with no tokens left")
+	}
+	re := resp.Error()
+	if re.Code != -32001 {
+		t.Fatalf("code = %d, want -32001", re.Code)
+	}
+	d, ok := RetryAfterFromError(re)
+	if !ok || d <= 0 || d > 4*time.Second {
+		t.Fatalf("RetryAfterFromError = %v, %v; want (0, 4s]", d, ok)
+	}
+
+	t.Run("rate middleware", func(t *testing.T) {
+		mw := NewRateLimitMiddleware(RateLimitConfig{
+			RequestsPerSecond: 1,
+			BurstSize:         2,
+			DefaultToolCost:   2,
+		})
+		defer mw.cleanupTimer.Stop()
+		h := mw.Apply(MCPHandlerFunc(func(ctx context.Context, req MCPRequest) (MCPResponse, error) {
+			return &successResponse{result: "ok"}, nil
+		}))
+		req := &UnifiedRequest{method: string(MethodToolsCall), params: []byte(`{"name":"x"}`), ctx: ctx}
+		if resp, _ := h.Handle(ctx, req); resp.IsError() {
+			t.Fatal("first call rejected")
+		}
+		resp, _ := h.Handle(ctx, req)
+		if !resp.IsError() {
+			t.Fatal("second call allowed")
+		}
+		if d, ok := RetryAfterFromError(resp.Error()); !ok || d < time.Second || d > 2*time.Second {
+			t.Fatalf("RetryAfterFromError = %v, %v; want [1s, 2s]", d, ok)
+		}
+	})
+}
+
+// TestRateLimitErrorOverWire verifies that the retry hint of a rate limit
+// error reaches the client, which requires the error code and data to be
+// preserved when the response is encoded.
+func TestRateLimitErrorOverWire(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	store := NewMemoryRateLimitStore()
+	server := NewServer("test-server", "1.0.0", WithTestLogger(t, slog.LevelError))
+	limiter := NewTokenBucketRateLimiterWithStore(store, 1, 3)
+	defer limiter.Close()
+	server.Use(NewEnhancedRateLimitMiddleware(
+		limiter,
+		RateLimitConfig{ToolCosts: map[string]int{"echo": 2}},
+	))
+	if err := server.RegisterTool(Tool{Name: "echo", Description: "echo"},
+		func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+			return &CallToolResult{Content: []any{map[string]string{"type": "text", "text": "ok"}}}, nil
+		}); err != nil {
+		t.Fatalf("RegisterTool: %v", err)
+	}
+
+	clientConn, serverConn := net.Pipe()
+	go func() { _ = server.Serve(context.Background(), &ReadWriteCloserTransport{serverConn}) }()
+	client, err := NewClient(&ReadWriteCloserTransport{clientConn})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+
+	// initialize consumes one token, the first call two more.
+	ctx := context.Background()
+	if _, err := client.Initialize(ctx, InitializeRequest{
+		ClientInfo:      Implementation{Name: "test-client", Version: "1.0"},
+		ProtocolVersion: LATEST_PROTOCOL_VERSION,
+	}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if _, err := client.CallTool(ctx, CallToolRequest{Name: "echo"}); err != nil {
+		t.Fatalf("first CallTool: %v", err)
+	}
+	_, err = client.CallTool(ctx, CallToolRequest{Name: "echo"})
+	if err == nil {
+		t.Fatal("second CallTool succeeded, want rate limit error")
+	}
+	re, ok := AsResponseError(err)
+	if !ok || re.Code != -32001 {
+		t.Fatalf("AsResponseError(%v) = %+v, %v; want code -32001", err, re, ok)
+	}
+	if d, ok := RetryAfterFromError(err); !ok || d <= 0 || d > 2*time.Second {
+		t.Fatalf("RetryAfterFromError = %v, %v; want (0, 2s]", d, ok)
+	}
+	if _, ok := AsResponseError(errors.New("plain")); ok {
+		t.Fatal("AsResponseError accepted a plain error")
+	}
+}
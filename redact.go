@@ -0,0 +1,529 @@
+// Package mcp - Secret Redaction
+// This file implements the redaction engine shared by everything that logs or
+// persists MCP traffic: LoggingMiddleware, AuditLog, and the spy, proxy and
+// shadow tools. Secrets are found by JSON path, by key name, by value format
+// and by tool input schema hints, and are replaced with a fixed placeholder so
+// redacted traces stay diffable.
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultRedactionPlaceholder replaces redacted values.
+const DefaultRedactionPlaceholder = "[REDACTED]"
+
+// defaultSecretKeys are matched against normalized key names (lowercase,
+// without '_', '-' and '.'); a key matches if it equals or ends with one.
+var defaultSecretKeys = []string{
+	"password", "passwd", "passphrase", "secret", "token", "apikey",
+	"authorization", "cookie", "credential", "credentials", "privatekey",
+	"accesskey", "sessionkey",
+}
+
+// defaultAllowedKeys are never redacted by key name, even if they match.
+var defaultAllowedKeys = []string{"progresstoken"}
+
+// defaultSecretPatterns detect common credential formats inside strings.
+var defaultSecretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`), // JWT
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),                                // AWS access key ID
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),                               // GitHub token
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`),                             // GitHub fine-grained token
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),                              // Slack token
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),                                      // OpenAI/Anthropic style key
+	regexp.MustCompile(`\b[rs]k_(?:live|test)_[0-9A-Za-z]{16,}\b`),                     // Stripe key
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),                                    // Google API key
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+}
+
+// sensitiveHeaders are always redacted by RedactHeaders.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// RedactionConfig configures a Redactor.
+type RedactionConfig struct {
+	// Paths are JSON paths, rooted at the JSON-RPC message, whose values are
+	// redacted. Segments are separated by dots; "*" or "[*]" matches any key
+	// or element and "[N]" an array index, as in "params.arguments.password"
+	// or "result.content[*].text".
+	Paths []string `json:"paths" yaml:"paths"`
+
+	// SecretKeys adds key names whose values are redacted wherever they
+	// appear. Keys are compared lowercase with '_', '-' and '.' removed, and
+	// match when they end with an entry, so "token" matches "accessToken".
+	SecretKeys []string `json:"secretKeys" yaml:"secretKeys"`
+
+	// AllowedKeys are exempt from SecretKeys matching.
+	AllowedKeys []string `json:"allowedKeys" yaml:"allowedKeys"`
+
+	// SecretPatterns adds regular expressions for secrets inside strings.
+	SecretPatterns []string `json:"secretPatterns" yaml:"secretPatterns"`
+
+	// DisableDefaults drops the built-in key names and value patterns.
+	DisableDefaults bool `json:"disableDefaults" yaml:"disableDefaults"`
+
+	// Placeholder replaces redacted values (default "[REDACTED]").
+	Placeholder string `json:"placeholder" yaml:"placeholder"`
+}
+
+// Redactor removes secrets from MCP messages. Besides its configured rules it
+// learns the input schemas of tools it sees in tools/list results, and
+// redacts tools/call arguments declared with "format": "password" or
+// "writeOnly": true. It is safe for concurrent use.
+type Redactor struct {
+	paths       [][]string
+	keys        []string
+	allowed     map[string]bool
+	patterns    []*regexp.Regexp
+	placeholder string
+
+	mu        sync.RWMutex
+	toolPaths map[string][][]string // tool name -> argument paths
+}
+
+// NewRedactor creates a Redactor.
+func NewRedactor(config RedactionConfig) (*Redactor, error) {
+	r := &Redactor{
+		allowed:     make(map[string]bool),
+		placeholder: config.Placeholder,
+		toolPaths:   make(map[string][][]string),
+	}
+	if r.placeholder == "" {
+		r.placeholder = DefaultRedactionPlaceholder
+	}
+	if !config.DisableDefaults {
+		r.keys = append(r.keys, defaultSecretKeys...)
+		r.patterns = append(r.patterns, defaultSecretPatterns...)
+		for _, k := range defaultAllowedKeys {
+			r.allowed[k] = true
+		}
+	}
+	for _, k := range config.SecretKeys {
+		r.keys = append(r.keys, normalizeSecretKey(k))
+	}
+	for _, k := range config.AllowedKeys {
+		r.allowed[normalizeSecretKey(k)] = true
+	}
+	for _, p := range config.SecretPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact: pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	for _, p := range config.Paths {
+		segs, err := parseJSONPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact: path %q: %w", p, err)
+		}
+		r.paths = append(r.paths, segs)
+	}
+	return r, nil
+}
+
+// DefaultRedactor returns a new Redactor with the built-in rules only.
+func DefaultRedactor() *Redactor {
+	r, _ := NewRedactor(RedactionConfig{})
+	return r
+}
+
+// LearnTools records the secret arguments declared by the tools' input
+// schemas.
+func (r *Redactor) LearnTools(tools []Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, tool := range tools {
+		r.learnTool(tool.Name, tool.InputSchema)
+	}
+}
+
+// learnTool records the secret arguments of one tool. r.mu must be held.
+func (r *Redactor) learnTool(name string, inputSchema json.RawMessage) {
+	var schema map[string]any
+	if len(inputSchema) == 0 || json.Unmarshal(inputSchema, &schema) != nil {
+		delete(r.toolPaths, name)
+		return
+	}
+	var paths [][]string
+	collectSecretSchemaPaths(schema, []string{"params", "arguments"}, &paths)
+	if len(paths) == 0 {
+		delete(r.toolPaths, name)
+		return
+	}
+	r.toolPaths[name] = paths
+}
+
+// collectSecretSchemaPaths appends the paths of properties marked as secret.
+func collectSecretSchemaPaths(schema map[string]any, prefix []string, paths *[][]string) {
+	if props, ok := schema["properties"].(map[string]any); ok {
+		for name, p := range props {
+			prop, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+			path := append(append([]string(nil), prefix...), name)
+			if prop["format"] == "password" || prop["writeOnly"] == true {
+				*paths = append(*paths, path)
+				continue
+			}
+			collectSecretSchemaPaths(prop, path, paths)
+		}
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		collectSecretSchemaPaths(items, append(append([]string(nil), prefix...), "*"), paths)
+	}
+}
+
+// RedactMessage returns a JSON-RPC message, or any JSON document, with its
+// secrets redacted. Unchanged input is returned as is; otherwise the result
+// is compact JSON with key order preserved. Input that is not a single JSON
+// value is treated as text.
+func (r *Redactor) RedactMessage(data []byte) []byte {
+	if r == nil {
+		return data
+	}
+	paths := r.messagePaths(data)
+	var out bytes.Buffer
+	out.Grow(len(data))
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	w := redactWriter{r: r, dec: dec, out: &out, paths: paths}
+	changed, err := w.value(nil)
+	if err == nil {
+		_, err = dec.Token()
+		if err == io.EOF {
+			err = nil
+		} else if err == nil {
+			err = errors.New("trailing data")
+		}
+	}
+	if err != nil {
+		if s := r.RedactString(string(data)); s != string(data) {
+			return []byte(s)
+		}
+		return data
+	}
+	if !changed {
+		return data
+	}
+	return out.Bytes()
+}
+
+// messagePaths returns the configured paths plus those learned for the tool a
+// tools/call message names. A tools/list result teaches r its tools.
+func (r *Redactor) messagePaths(data []byte) [][]string {
+	var msg struct {
+		Method string `json:"method"`
+		Params struct {
+			Name string `json:"name"`
+		} `json:"params"`
+		Result struct {
+			Tools []struct {
+				Name        string          `json:"name"`
+				InputSchema json.RawMessage `json:"inputSchema"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	if json.Unmarshal(data, &msg) != nil {
+		return r.paths
+	}
+	if len(msg.Result.Tools) > 0 {
+		r.mu.Lock()
+		for _, tool := range msg.Result.Tools {
+			r.learnTool(tool.Name, tool.InputSchema)
+		}
+		r.mu.Unlock()
+	}
+	if msg.Method != string(MethodToolsCall) || msg.Params.Name == "" {
+		return r.paths
+	}
+	r.mu.RLock()
+	toolPaths := r.toolPaths[msg.Params.Name]
+	r.mu.RUnlock()
+	if len(toolPaths) == 0 {
+		return r.paths
+	}
+	return append(append([][]string(nil), r.paths...), toolPaths...)
+}
+
+// RedactString redacts credentials found in free text.
+func (r *Redactor) RedactString(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllLiteralString(s, r.placeholder)
+	}
+	return s
+}
+
+// RedactHeaders returns a copy of h with credential headers redacted.
+func (r *Redactor) RedactHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	if r == nil {
+		return out
+	}
+	for name, values := range out {
+		sensitive := r.secretKey(name)
+		for _, s := range sensitiveHeaders {
+			sensitive = sensitive || strings.EqualFold(name, s)
+		}
+		for i, v := range values {
+			if sensitive {
+				values[i] = r.placeholder
+			} else {
+				values[i] = r.RedactString(v)
+			}
+		}
+	}
+	return out
+}
+
+// Sanitize returns a redacted copy of v, which must marshal to JSON. It can
+// be used as LoggingConfig.SanitizeFunc.
+func (r *Redactor) Sanitize(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if json.Unmarshal(r.RedactMessage(data), &out) != nil {
+		return v
+	}
+	return out
+}
+
+// redactExchange redacts the params and result of a request as they would
+// appear in the JSON-RPC messages for method.
+func (r *Redactor) redactExchange(method string, params, result json.RawMessage) (json.RawMessage, json.RawMessage) {
+	if r == nil {
+		return params, result
+	}
+	if len(params) > 0 {
+		params = r.redactField(map[string]any{"method": method, "params": params}, "params", params)
+	}
+	if len(result) > 0 {
+		result = r.redactField(map[string]any{"result": result}, "result", result)
+	}
+	return params, result
+}
+
+// redactField redacts msg and returns its field name.
+func (r *Redactor) redactField(msg map[string]any, name string, orig json.RawMessage) json.RawMessage {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return orig
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(r.RedactMessage(data), &fields) != nil {
+		return orig
+	}
+	return fields[name]
+}
+
+// secretKey reports whether values under key should be redacted.
+func (r *Redactor) secretKey(key string) bool {
+	k := normalizeSecretKey(key)
+	if r.allowed[k] {
+		return false
+	}
+	for _, s := range r.keys {
+		if strings.HasSuffix(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeSecretKey(key string) string {
+	return strings.Map(func(c rune) rune {
+		switch c {
+		case '_', '-', '.':
+			return -1
+		}
+		return c
+	}, strings.ToLower(key))
+}
+
+// redactWriter re-encodes a JSON document token by token, replacing secrets.
+type redactWriter struct {
+	r     *Redactor
+	dec   *json.Decoder
+	out   *bytes.Buffer
+	paths [][]string
+}
+
+// value copies the next value at path and reports whether it changed.
+func (w *redactWriter) value(path []string) (bool, error) {
+	tok, err := w.dec.Token()
+	if err != nil {
+		return false, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '{' {
+			return w.object(path)
+		}
+		return w.array(path)
+	case string:
+		redacted := w.r.RedactString(tok)
+		w.writeString(redacted)
+		return redacted != tok, nil
+	case json.Number:
+		w.out.WriteString(tok.String())
+	case bool:
+		w.out.WriteString(strconv.FormatBool(tok))
+	case nil:
+		w.out.WriteString("null")
+	}
+	return false, nil
+}
+
+func (w *redactWriter) object(path []string) (bool, error) {
+	changed := false
+	w.out.WriteByte('{')
+	for i := 0; w.dec.More(); i++ {
+		tok, err := w.dec.Token()
+		if err != nil {
+			return false, err
+		}
+		key, _ := tok.(string)
+		if i > 0 {
+			w.out.WriteByte(',')
+		}
+		w.writeString(key)
+		w.out.WriteByte(':')
+		c, err := w.child(append(path, key), w.r.secretKey(key))
+		if err != nil {
+			return false, err
+		}
+		changed = changed || c
+	}
+	if _, err := w.dec.Token(); err != nil {
+		return false, err
+	}
+	w.out.WriteByte('}')
+	return changed, nil
+}
+
+func (w *redactWriter) array(path []string) (bool, error) {
+	changed := false
+	w.out.WriteByte('[')
+	for i := 0; w.dec.More(); i++ {
+		if i > 0 {
+			w.out.WriteByte(',')
+		}
+		c, err := w.child(append(path, strconv.Itoa(i)), false)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || c
+	}
+	if _, err := w.dec.Token(); err != nil {
+		return false, err
+	}
+	w.out.WriteByte(']')
+	return changed, nil
+}
+
+// child copies or redacts the value at path.
+func (w *redactWriter) child(path []string, secret bool) (bool, error) {
+	if !secret && !w.matchPath(path) {
+		return w.value(path[:len(path):len(path)])
+	}
+	if err := w.skip(); err != nil {
+		return false, err
+	}
+	w.writeString(w.r.placeholder)
+	return true, nil
+}
+
+func (w *redactWriter) matchPath(path []string) bool {
+	for _, p := range w.paths {
+		if len(p) != len(path) {
+			continue
+		}
+		match := true
+		for i, seg := range p {
+			if seg != "*" && seg != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// skip discards the next value.
+func (w *redactWriter) skip() error {
+	depth := 0
+	for {
+		tok, err := w.dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); ok {
+			if d == '{' || d == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func (w *redactWriter) writeString(s string) {
+	enc := json.NewEncoder(w.out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	w.out.Truncate(w.out.Len() - 1) // Encode appends a newline
+}
+
+// parseJSONPath splits a path such as "$.params.items[*].token" into
+// segments, with "*" as the wildcard.
+func parseJSONPath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, errors.New("empty path")
+	}
+	var segs []string
+	for _, part := range strings.Split(path, ".") {
+		name, rest, _ := strings.Cut(part, "[")
+		if name != "" {
+			segs = append(segs, name)
+		}
+		for rest != "" {
+			idx, after, ok := strings.Cut(rest, "]")
+			if !ok || idx == "" {
+				return nil, fmt.Errorf("malformed index in %q", part)
+			}
+			if idx != "*" {
+				if _, err := strconv.Atoi(idx); err != nil {
+					return nil, fmt.Errorf("malformed index %q", idx)
+				}
+			}
+			segs = append(segs, idx)
+			rest = strings.TrimPrefix(after, "[")
+		}
+		if name == "" && !strings.Contains(part, "[") {
+			return nil, fmt.Errorf("empty segment in %q", path)
+		}
+	}
+	return segs, nil
+}
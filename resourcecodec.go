@@ -0,0 +1,158 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ResourceCodec encodes the values returned by typed resource handlers as
+// text resource contents of one MIME type.
+type ResourceCodec interface {
+	MIMEType() string
+	Encode(v any) (string, error)
+}
+
+// Codecs for RegisterTypedResourceTemplate.
+var (
+	// JSONCodec encodes values as indented JSON.
+	JSONCodec ResourceCodec = jsonCodec{}
+
+	// YAMLCodec encodes values as YAML, by way of their JSON encoding, so
+	// json tags name the fields.
+	YAMLCodec ResourceCodec = yamlCodec{}
+
+	// TextCodec writes strings and byte slices as they are, and other values
+	// with their String or MarshalText method or fmt.Sprint.
+	TextCodec ResourceCodec = textCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) MIMEType() string { return "application/json" }
+
+func (jsonCodec) Encode(v any) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	return string(data), err
+}
+
+type textCodec struct{}
+
+func (textCodec) MIMEType() string { return "text/plain" }
+
+func (textCodec) Encode(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		return string(text), err
+	}
+	return fmt.Sprint(v), nil
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) MIMEType() string { return "application/yaml" }
+
+func (yamlCodec) Encode(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	lines, inline, err := yamlLines(dec)
+	if err != nil {
+		return "", err
+	}
+	if lines == nil {
+		return inline + "\n", nil
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// yamlLines converts the next JSON value of dec to YAML. Scalars and empty
+// containers are returned inline; other values as lines indented relative
+// to their parent. Object keys keep their JSON order.
+func yamlLines(dec *json.Decoder) (lines []string, inline string, err error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, "", err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		object := tok == '{'
+		for dec.More() {
+			key := ""
+			if object {
+				t, err := dec.Token()
+				if err != nil {
+					return nil, "", err
+				}
+				key = yamlString(t.(string))
+			}
+			sub, subInline, err := yamlLines(dec)
+			if err != nil {
+				return nil, "", err
+			}
+			switch {
+			case object && sub == nil:
+				lines = append(lines, key+": "+subInline)
+			case object:
+				lines = append(lines, key+":")
+				for _, line := range sub {
+					lines = append(lines, "  "+line)
+				}
+			case sub == nil:
+				lines = append(lines, "- "+subInline)
+			default:
+				lines = append(lines, "- "+sub[0])
+				for _, line := range sub[1:] {
+					lines = append(lines, "  "+line)
+				}
+			}
+		}
+		if _, err := dec.Token(); err != nil { // closing delimiter
+			return nil, "", err
+		}
+		if lines == nil {
+			if object {
+				return nil, "{}", nil
+			}
+			return nil, "[]", nil
+		}
+		return lines, "", nil
+	case string:
+		return nil, yamlString(tok), nil
+	case json.Number:
+		return nil, tok.String(), nil
+	case bool:
+		return nil, fmt.Sprint(tok), nil
+	}
+	return nil, "null", nil
+}
+
+// yamlPlain matches strings that can be written unquoted.
+var yamlPlain = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_./ -]*$`)
+
+// yamlString writes s plain when YAML reads it back as the same string,
+// and as a double-quoted scalar, which JSON string syntax is, otherwise.
+func yamlString(s string) string {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+	default:
+		if yamlPlain.MatchString(s) && !strings.HasSuffix(s, " ") {
+			return s
+		}
+	}
+	data, _ := json.Marshal(s)
+	return string(data)
+}
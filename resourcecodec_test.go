@@ -0,0 +1,63 @@
+package mcp
+
+import "testing"
+
+func TestResourceCodecs(t *testing.T) {
+	type item struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	v := struct {
+		Title string         `json:"title"`
+		Count int            `json:"count"`
+		Draft bool           `json:"draft"`
+		Items []item         `json:"items"`
+		Meta  map[string]any `json:"meta"`
+		Note  string         `json:"note"`
+	}{
+		Title: "Release notes",
+		Count: 2,
+		Items: []item{{Name: "a", Tags: []string{"x", "yes"}}, {Name: "b: c"}},
+		Meta:  map[string]any{},
+		Note:  "",
+	}
+	yaml, err := YAMLCodec.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `title: Release notes
+count: 2
+draft: false
+items:
+  - name: a
+    tags:
+      - x
+      - "yes"
+  - name: "b: c"
+    tags: null
+meta: {}
+note: ""
+`
+	if yaml != want {
+		t.Errorf("YAML =\n%s\nwant\n%s", yaml, want)
+	}
+
+	tests := []struct {
+		codec ResourceCodec
+		v     any
+		mime  string
+		want  string
+	}{
+		{JSONCodec, item{Name: "a"}, "application/json", "{\n  \"name\": \"a\",\n  \"tags\": null\n}"},
+		{TextCodec, "hello", "text/plain", "hello"},
+		{TextCodec, []byte("raw"), "text/plain", "raw"},
+		{TextCodec, 42, "text/plain", "42"},
+		{YAMLCodec, "plain", "application/yaml", "plain\n"},
+	}
+	for _, tt := range tests {
+		got, err := tt.codec.Encode(tt.v)
+		if err != nil || got != tt.want || tt.codec.MIMEType() != tt.mime {
+			t.Errorf("%T.Encode(%v) = %q, %v (%s); want %q (%s)", tt.codec, tt.v, got, err, tt.codec.MIMEType(), tt.want, tt.mime)
+		}
+	}
+}
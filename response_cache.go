@@ -0,0 +1,454 @@
+// Package mcp - Response Caching
+// This file implements caching of tools/call and resources/read results that
+// respects protocol semantics: tools are cached only when annotated read-only
+// or idempotent, and cached resource reads are invalidated by the server's
+// resources/updated and list_changed notifications. The same cache can be
+// used by a Client for subscribed resources.
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheTTLMetaKey is the _meta key through which a tools/call or
+// resources/read result sets its own cache lifetime, in seconds. A value of
+// zero or less keeps the result out of the cache. Resources may also set it
+// in their own _meta, which applies when the read result does not.
+const CacheTTLMetaKey = "cacheTtl"
+
+// ResponseCacheConfig configures response caching on a server or client.
+type ResponseCacheConfig struct {
+	Cache      Cache           `json:"-" yaml:"-"`                   // Storage (default: an InMemoryCache of MaxSize bytes)
+	MaxSize    int64           `json:"maxSize" yaml:"maxSize"`       // Byte budget of the default cache (default 10 MiB)
+	DefaultTTL time.Duration   `json:"defaultTtl" yaml:"defaultTtl"` // Lifetime of results without a _meta TTL (default 5m)
+	Metrics    MetricsRegistry `json:"-" yaml:"-"`                   // Receives hits and misses if it implements CacheMetricsRegistry
+}
+
+// CacheMetricsRegistry is implemented by metrics registries that also count
+// response cache lookups. cache names the cache ("server" or "client").
+type CacheMetricsRegistry interface {
+	RecordCacheLookup(cache string, method string, hit bool)
+}
+
+// serverObserver is implemented by middleware that needs server state beyond
+// the request. Server.Use attaches it, and the server reports the
+// notifications it emits whether or not a client is subscribed.
+type serverObserver interface {
+	attach(s *Server)
+	observeNotification(method Method, params any)
+}
+
+// responseCacheIndex stores cached results and remembers which keys belong to
+// which tool or resource, so notifications can invalidate them. Each URI has
+// a generation that is bumped on invalidation; a result fetched under an
+// older generation is not stored, which keeps a read racing an update from
+// reinstating stale content.
+type responseCacheIndex struct {
+	name       string
+	cache      Cache
+	owned      *InMemoryCache
+	defaultTTL time.Duration
+	metrics    CacheMetricsRegistry
+
+	mu           sync.Mutex
+	resourceKeys map[string]map[string]bool
+	toolKeys     map[string]bool
+	generations  map[string]uint64
+	epoch        uint64
+}
+
+func newResponseCacheIndex(name string, config ResponseCacheConfig) *responseCacheIndex {
+	if config.MaxSize <= 0 {
+		config.MaxSize = 10 << 20
+	}
+	if config.DefaultTTL <= 0 {
+		config.DefaultTTL = 5 * time.Minute
+	}
+	idx := &responseCacheIndex{
+		name:         name,
+		cache:        config.Cache,
+		defaultTTL:   config.DefaultTTL,
+		resourceKeys: make(map[string]map[string]bool),
+		toolKeys:     make(map[string]bool),
+		generations:  make(map[string]uint64),
+	}
+	if idx.cache == nil {
+		idx.owned = NewInMemoryCache(config.MaxSize)
+		idx.cache = idx.owned
+	}
+	if m, ok := config.Metrics.(CacheMetricsRegistry); ok {
+		idx.metrics = m
+	}
+	return idx
+}
+
+// get looks key up and records the outcome.
+func (idx *responseCacheIndex) get(ctx context.Context, method, key string) ([]byte, bool) {
+	data, ok := idx.cache.Get(ctx, key)
+	if idx.metrics != nil {
+		idx.metrics.RecordCacheLookup(idx.name, method, ok)
+	}
+	return data, ok
+}
+
+// generation returns the token to pass to put for a result about to be
+// fetched for uri ("" for tools).
+func (idx *responseCacheIndex) generation(uri string) uint64 {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.epoch<<32 + idx.generations[uri]
+}
+
+// put stores a result unless its TTL disables caching or the entry was
+// invalidated since gen was taken. meta is the _meta of the tool or resource
+// definition, consulted when the result carries no TTL of its own.
+func (idx *responseCacheIndex) put(ctx context.Context, key, uri string, gen uint64, result []byte, meta map[string]any) {
+	ttl, ok := resultCacheTTL(result, meta, idx.defaultTTL)
+	if !ok {
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.epoch<<32+idx.generations[uri] != gen {
+		return
+	}
+	if err := idx.cache.Set(ctx, key, result, ttl); err != nil {
+		return
+	}
+	if uri == "" {
+		idx.toolKeys[key] = true
+		return
+	}
+	if idx.resourceKeys[uri] == nil {
+		idx.resourceKeys[uri] = make(map[string]bool)
+	}
+	idx.resourceKeys[uri][key] = true
+}
+
+// invalidateURI drops every cached read of uri.
+func (idx *responseCacheIndex) invalidateURI(ctx context.Context, uri string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.generations[uri]++
+	for key := range idx.resourceKeys[uri] {
+		_ = idx.cache.Delete(ctx, key)
+	}
+	delete(idx.resourceKeys, uri)
+}
+
+// invalidateAll drops every entry this index stored. Entries are deleted one
+// by one so a Cache shared with other users keeps their data.
+func (idx *responseCacheIndex) invalidateAll(ctx context.Context) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.epoch++
+	for _, keys := range idx.resourceKeys {
+		for key := range keys {
+			_ = idx.cache.Delete(ctx, key)
+		}
+	}
+	for key := range idx.toolKeys {
+		_ = idx.cache.Delete(ctx, key)
+	}
+	idx.resourceKeys = make(map[string]map[string]bool)
+	idx.toolKeys = make(map[string]bool)
+	idx.generations = make(map[string]uint64)
+}
+
+// observe applies the invalidation implied by a notification.
+func (idx *responseCacheIndex) observe(method Method, params any) {
+	switch {
+	case method == MethodResourceUpdated:
+		var p ResourceUpdatedNotificationParams
+		switch v := params.(type) {
+		case ResourceUpdatedNotificationParams:
+			p = v
+		case *ResourceUpdatedNotificationParams:
+			p = *v
+		case json.RawMessage:
+			_ = json.Unmarshal(v, &p)
+		}
+		if p.URI != "" {
+			idx.invalidateURI(context.Background(), p.URI)
+		}
+	case strings.HasSuffix(string(method), "/list_changed"):
+		idx.invalidateAll(context.Background())
+	}
+}
+
+func (idx *responseCacheIndex) close() error {
+	if idx.owned != nil {
+		return idx.owned.Close()
+	}
+	return nil
+}
+
+// resultCacheTTL returns the lifetime of a result, honoring CacheTTLMetaKey
+// in the result's _meta, then in defMeta. Error results are never cached,
+// nor are results that hand out resource streams, which belong to one
+// session and are used up once read.
+func resultCacheTTL(result []byte, defMeta map[string]any, defaultTTL time.Duration) (time.Duration, bool) {
+	var envelope struct {
+		IsError  bool           `json:"isError"`
+		Meta     map[string]any `json:"_meta"`
+		Contents []streamMeta   `json:"contents"`
+		Content  []struct {
+			Resource *streamMeta `json:"resource"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(result, &envelope); err != nil || envelope.IsError {
+		return 0, false
+	}
+	for _, c := range envelope.Contents {
+		if c.isStream() {
+			return 0, false
+		}
+	}
+	for _, c := range envelope.Content {
+		if c.Resource != nil && c.Resource.isStream() {
+			return 0, false
+		}
+	}
+	v, ok := envelope.Meta[CacheTTLMetaKey]
+	if !ok {
+		v, ok = defMeta[CacheTTLMetaKey]
+	}
+	if !ok {
+		return defaultTTL, true
+	}
+	var seconds float64
+	switch n := v.(type) {
+	case float64:
+		seconds = n
+	case int:
+		seconds = float64(n)
+	case time.Duration:
+		seconds = n.Seconds()
+	default:
+		return 0, false
+	}
+	if seconds <= 0 {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+// streamMeta is the _meta of resource contents, which names a stream when
+// the contents are sent with the streaming extension.
+type streamMeta struct {
+	Meta map[string]json.RawMessage `json:"_meta"`
+}
+
+func (m streamMeta) isStream() bool {
+	_, ok := m.Meta[StreamingExtension]
+	return ok
+}
+
+// responseCacheKey derives a cache key from the request identity. Arguments
+// are canonicalized so that key order and per-request _meta (such as
+// progress tokens) do not defeat the cache.
+func responseCacheKey(ctx context.Context, method, target string, args json.RawMessage) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(target))
+	hash.Write([]byte{0})
+	if len(args) > 0 {
+		var v any
+		dec := json.NewDecoder(bytes.NewReader(args))
+		dec.UseNumber()
+		if dec.Decode(&v) == nil {
+			if canonical, err := json.Marshal(v); err == nil {
+				args = canonical
+			}
+		}
+		hash.Write(args)
+	}
+	if authCtx := GetAuthContext(ctx); authCtx != nil {
+		hash.Write([]byte{0})
+		hash.Write([]byte(authCtx.ClientID))
+	}
+	return "mcp:response:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// toolCacheable reports whether results of a tool may be reused.
+func toolCacheable(ann *ToolAnnotations) bool {
+	if ann == nil {
+		return false
+	}
+	return (ann.ReadOnlyHint != nil && *ann.ReadOnlyHint) ||
+		(ann.IdempotentHint != nil && *ann.IdempotentHint)
+}
+
+// Server Side
+// ===========
+
+// ResponseCacheMiddleware caches tools/call results of read-only or idempotent
+// tools and resources/read results. Register it with Server.Use, which gives it
+// access to tool annotations and to the server's notifications; used on its
+// own it caches resource reads only.
+type ResponseCacheMiddleware struct {
+	index  *responseCacheIndex
+	server *Server
+}
+
+// NewResponseCacheMiddleware creates response caching middleware.
+func NewResponseCacheMiddleware(config ResponseCacheConfig) *ResponseCacheMiddleware {
+	return &ResponseCacheMiddleware{index: newResponseCacheIndex("server", config)}
+}
+
+// Apply implements the Middleware interface
+func (m *ResponseCacheMiddleware) Apply(next MCPHandler) MCPHandler {
+	return MCPHandlerFunc(func(ctx context.Context, req MCPRequest) (MCPResponse, error) {
+		var key, uri string
+		switch req.Method() {
+		case string(MethodToolsCall):
+			var params CallToolRequest
+			if json.Unmarshal(req.Params(), &params) != nil || !toolCacheable(m.toolAnnotations(params.Name)) {
+				return next.Handle(ctx, req)
+			}
+			key = responseCacheKey(ctx, req.Method(), params.Name, params.Arguments)
+		case string(MethodResourcesRead):
+			var params ReadResourceRequest
+			if json.Unmarshal(req.Params(), &params) != nil || params.URI == "" {
+				return next.Handle(ctx, req)
+			}
+			uri = params.URI
+			key = responseCacheKey(ctx, req.Method(), uri, nil)
+		default:
+			return next.Handle(ctx, req)
+		}
+
+		if data, ok := m.index.get(ctx, req.Method(), key); ok {
+			return &successResponse{result: json.RawMessage(data)}, nil
+		}
+
+		gen := m.index.generation(uri)
+		resp, err := next.Handle(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			return resp, err
+		}
+		if data, merr := json.Marshal(resp.Result()); merr == nil {
+			m.index.put(ctx, key, uri, gen, data, m.resourceMeta(uri))
+		}
+		return resp, nil
+	})
+}
+
+// Invalidate drops cached reads of uri.
+func (m *ResponseCacheMiddleware) Invalidate(uri string) {
+	m.index.invalidateURI(context.Background(), uri)
+}
+
+// InvalidateAll drops every cached result.
+func (m *ResponseCacheMiddleware) InvalidateAll() {
+	m.index.invalidateAll(context.Background())
+}
+
+// Close releases the default cache. It is safe to call more than once.
+func (m *ResponseCacheMiddleware) Close() error {
+	return m.index.close()
+}
+
+func (m *ResponseCacheMiddleware) Name() string {
+	return "response_cache"
+}
+
+func (m *ResponseCacheMiddleware) Priority() int {
+	return 300 // Same slot as CachingMiddleware
+}
+
+func (m *ResponseCacheMiddleware) attach(s *Server) {
+	m.server = s
+}
+
+func (m *ResponseCacheMiddleware) observeNotification(method Method, params any) {
+	m.index.observe(method, params)
+}
+
+func (m *ResponseCacheMiddleware) resourceMeta(uri string) map[string]any {
+	if m.server == nil || uri == "" {
+		return nil
+	}
+	m.server.mu.RLock()
+	defer m.server.mu.RUnlock()
+	return m.server.resources[uri].resource.Meta
+}
+
+func (m *ResponseCacheMiddleware) toolAnnotations(name string) *ToolAnnotations {
+	if m.server == nil {
+		return nil
+	}
+	m.server.mu.RLock()
+	defer m.server.mu.RUnlock()
+	return m.server.tools[name].tool.Annotations
+}
+
+// Client Side
+// ===========
+
+// WithResourceCache caches resources/read results on the client for URIs it
+// is subscribed to. Entries are dropped when the server sends
+// notifications/resources/updated for the URI or any list_changed
+// notification, and when the client unsubscribes.
+func WithResourceCache(config ResponseCacheConfig) ClientOption {
+	return func(c *Client) {
+		c.resourceCache = &clientResourceCache{
+			index:      newResponseCacheIndex("client", config),
+			subscribed: make(map[string]bool),
+		}
+	}
+}
+
+// clientResourceCache tracks subscriptions so only URIs the server will
+// report updates for are cached.
+type clientResourceCache struct {
+	index      *responseCacheIndex
+	mu         sync.RWMutex
+	subscribed map[string]bool
+}
+
+func (rc *clientResourceCache) isSubscribed(uri string) bool {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.subscribed[uri]
+}
+
+func (rc *clientResourceCache) setSubscribed(uri string, subscribed bool) {
+	rc.mu.Lock()
+	if subscribed {
+		rc.subscribed[uri] = true
+	} else {
+		delete(rc.subscribed, uri)
+	}
+	rc.mu.Unlock()
+	if !subscribed {
+		rc.index.invalidateURI(context.Background(), uri)
+	}
+}
+
+// read serves a resources/read from the cache, calling fetch on a miss.
+func (rc *clientResourceCache) read(ctx context.Context, uri string, fetch func() (json.RawMessage, error)) (json.RawMessage, error) {
+	if !rc.isSubscribed(uri) {
+		return fetch()
+	}
+	key := responseCacheKey(ctx, string(MethodResourcesRead), uri, nil)
+	if data, ok := rc.index.get(ctx, string(MethodResourcesRead), key); ok {
+		return data, nil
+	}
+	gen := rc.index.generation(uri)
+	data, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	rc.index.put(ctx, key, uri, gen, data, nil)
+	return data, nil
+}
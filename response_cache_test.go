@@ -0,0 +1,270 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCacheTestPair serves server over a pipe and returns an initialized client.
+func newCacheTestPair(t *testing.T, server *Server, opts ...ClientOption) *Client {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	go func() { _ = server.Serve(context.Background(), &ReadWriteCloserTransport{serverConn}) }()
+
+	client, err := NewClient(&ReadWriteCloserTransport{clientConn}, opts...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if _, err := client.Initialize(context.Background(), InitializeRequest{
+		ClientInfo:      Implementation{Name: "test-client", Version: "1.0"},
+		ProtocolVersion: LATEST_PROTOCOL_VERSION,
+	}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return client
+}
+
+// waitFor polls cond until it holds or a second passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestResponseCacheMiddleware(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	readOnly := true
+
+	metrics := &InMemoryMetricsRegistry{}
+	cache := NewResponseCacheMiddleware(ResponseCacheConfig{Metrics: metrics})
+	defer cache.Close()
+
+	server := NewServer("test-server", "1.0.0", WithTestLogger(t, slog.LevelError))
+	server.Use(cache)
+
+	var lookups, mutations, reads, volatileReads atomic.Int64
+	textResult := func(text string) *CallToolResult {
+		return &CallToolResult{Content: []any{map[string]string{"type": "text", "text": text}}}
+	}
+	mustRegister := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustRegister(server.RegisterTool(Tool{Name: "lookup", Annotations: &ToolAnnotations{ReadOnlyHint: &readOnly}},
+		func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+			lookups.Add(1)
+			return textResult("found"), nil
+		}))
+	mustRegister(server.RegisterTool(Tool{Name: "mutate"},
+		func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+			mutations.Add(1)
+			return textResult("done"), nil
+		}))
+	mustRegister(server.RegisterTool(Tool{Name: "nocache", Annotations: &ToolAnnotations{ReadOnlyHint: &readOnly}},
+		func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+			result := textResult("fresh")
+			result.Meta = map[string]any{CacheTTLMetaKey: 0}
+			return result, nil
+		}))
+	mustRegister(server.RegisterResource(Resource{URI: "file:///a", Name: "a"},
+		func(ctx context.Context, req ReadResourceRequest) ([]ResourceContents, error) {
+			reads.Add(1)
+			return []ResourceContents{TextResourceContents{URI: req.URI, Text: "A"}}, nil
+		}))
+	mustRegister(server.RegisterResource(Resource{URI: "file:///volatile", Name: "v", Meta: map[string]any{CacheTTLMetaKey: 0}},
+		func(ctx context.Context, req ReadResourceRequest) ([]ResourceContents, error) {
+			volatileReads.Add(1)
+			return []ResourceContents{TextResourceContents{URI: req.URI, Text: "V"}}, nil
+		}))
+
+	client := newCacheTestPair(t, server)
+
+	t.Run("read-only tool", func(t *testing.T) {
+		for _, args := range []string{`{"q":1,"r":2}`, `{"r":2,"q":1}`} {
+			result, err := client.CallTool(ctx, CallToolRequest{Name: "lookup", Arguments: json.RawMessage(args)})
+			if err != nil {
+				t.Fatalf("CallTool: %v", err)
+			}
+			if len(result.Content) != 1 {
+				t.Fatalf("cached result content = %v", result.Content)
+			}
+		}
+		if got := lookups.Load(); got != 1 {
+			t.Fatalf("lookup handler ran %d times, want 1", got)
+		}
+	})
+
+	t.Run("unannotated tool", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if _, err := client.CallTool(ctx, CallToolRequest{Name: "mutate"}); err != nil {
+				t.Fatalf("CallTool: %v", err)
+			}
+		}
+		if got := mutations.Load(); got != 2 {
+			t.Fatalf("mutate handler ran %d times, want 2", got)
+		}
+	})
+
+	t.Run("ttl from result meta", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if _, err := client.CallTool(ctx, CallToolRequest{Name: "nocache"}); err != nil {
+				t.Fatalf("CallTool: %v", err)
+			}
+		}
+		if got := metrics.metrics["cache_hit_server_tools/call"]; got != int64(1) {
+			t.Fatalf("tools/call hits = %v, want 1", got)
+		}
+	})
+
+	t.Run("resource invalidated by update", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if _, err := client.ReadResource(ctx, ReadResourceRequest{URI: "file:///a"}); err != nil {
+				t.Fatalf("ReadResource: %v", err)
+			}
+		}
+		if got := reads.Load(); got != 1 {
+			t.Fatalf("resource handler ran %d times, want 1", got)
+		}
+
+		// Invalidation must not depend on a client subscription.
+		if err := server.ResourceUpdated(ctx, ResourceUpdatedNotificationParams{URI: "file:///a"}); err != nil {
+			t.Fatalf("ResourceUpdated: %v", err)
+		}
+		if _, err := client.ReadResource(ctx, ReadResourceRequest{URI: "file:///a"}); err != nil {
+			t.Fatalf("ReadResource: %v", err)
+		}
+		if got := reads.Load(); got != 2 {
+			t.Fatalf("resource handler ran %d times after update, want 2", got)
+		}
+	})
+
+	t.Run("ttl from resource meta", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if _, err := client.ReadResource(ctx, ReadResourceRequest{URI: "file:///volatile"}); err != nil {
+				t.Fatalf("ReadResource: %v", err)
+			}
+		}
+		if got := volatileReads.Load(); got != 2 {
+			t.Fatalf("volatile handler ran %d times, want 2", got)
+		}
+	})
+
+	t.Run("list changed", func(t *testing.T) {
+		mustRegister(server.RegisterTool(Tool{Name: "other"},
+			func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+				return textResult("other"), nil
+			}))
+		before := lookups.Load()
+		waitFor(t, "list_changed invalidation", func() bool {
+			if _, err := client.CallTool(ctx, CallToolRequest{Name: "lookup", Arguments: json.RawMessage(`{"q":1,"r":2}`)}); err != nil {
+				t.Fatalf("CallTool: %v", err)
+			}
+			return lookups.Load() > before
+		})
+	})
+}
+
+func TestClientResourceCache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	server := NewServer("test-server", "1.0.0", WithTestLogger(t, slog.LevelError))
+	var reads atomic.Int64
+	if err := server.RegisterResource(Resource{URI: "file:///a", Name: "a"},
+		func(ctx context.Context, req ReadResourceRequest) ([]ResourceContents, error) {
+			n := reads.Add(1)
+			return []ResourceContents{TextResourceContents{URI: req.URI, Text: string(rune('0' + n))}}, nil
+		}); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := &InMemoryMetricsRegistry{}
+	client := newCacheTestPair(t, server, WithResourceCache(ResponseCacheConfig{Metrics: metrics}))
+
+	read := func() string {
+		t.Helper()
+		result, err := client.ReadResource(ctx, ReadResourceRequest{URI: "file:///a"})
+		if err != nil {
+			t.Fatalf("ReadResource: %v", err)
+		}
+		return result.Contents[0].(TextResourceContents).Text
+	}
+
+	// Unsubscribed URIs are never cached.
+	read()
+	read()
+	if got := reads.Load(); got != 2 {
+		t.Fatalf("server read %d times before subscribing, want 2", got)
+	}
+
+	if err := client.SubscribeResource(ctx, SubscribeResourceRequest{URI: "file:///a"}); err != nil {
+		t.Fatalf("SubscribeResource: %v", err)
+	}
+	if got := read(); got != "3" {
+		t.Fatalf("read = %q, want 3", got)
+	}
+	if got := read(); got != "3" {
+		t.Fatalf("cached read = %q, want 3", got)
+	}
+	if got := metrics.metrics["cache_hit_client_resources/read"]; got != int64(1) {
+		t.Fatalf("client hits = %v, want 1", got)
+	}
+
+	if err := server.ResourceUpdated(ctx, ResourceUpdatedNotificationParams{URI: "file:///a"}); err != nil {
+		t.Fatalf("ResourceUpdated: %v", err)
+	}
+	waitFor(t, "client invalidation", func() bool { return read() == "4" })
+
+	if err := client.UnsubscribeResource(ctx, UnsubscribeResourceRequest{URI: "file:///a"}); err != nil {
+		t.Fatalf("UnsubscribeResource: %v", err)
+	}
+	before := reads.Load()
+	read()
+	if reads.Load() == before {
+		t.Fatal("read served from cache after unsubscribing")
+	}
+}
+
+func TestResultCacheTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		defMeta map[string]any
+		wantTTL time.Duration
+		wantOK  bool
+	}{
+		{"default", `{"content":[]}`, nil, time.Minute, true},
+		{"result meta", `{"_meta":{"cacheTtl":2.5}}`, nil, 2500 * time.Millisecond, true},
+		{"result meta wins", `{"_meta":{"cacheTtl":1}}`, map[string]any{CacheTTLMetaKey: 9}, time.Second, true},
+		{"definition meta", `{}`, map[string]any{CacheTTLMetaKey: 9}, 9 * time.Second, true},
+		{"disabled", `{"_meta":{"cacheTtl":0}}`, nil, 0, false},
+		{"error result", `{"isError":true}`, nil, 0, false},
+		{"not an object", `[1]`, nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttl, ok := resultCacheTTL([]byte(tt.result), tt.defMeta, time.Minute)
+			if ttl != tt.wantTTL || ok != tt.wantOK {
+				t.Errorf("resultCacheTTL = %v, %v; want %v, %v", ttl, ok, tt.wantTTL, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 The MCP Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license.
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tmc/mcp/modelcontextprotocol"
+)
+
+// This file converts between the types of this package and the schema model
+// in package modelcontextprotocol, for code written against the latter. Both
+// encode the same JSON, so conversions go through it. A conversion fails,
+// rather than drop data, when the JSON has fields the other side does not
+// model. The completion reference types are shared and need no conversion.
+
+// convert re-decodes the JSON encoding of v as a T. It fails if a key with a
+// non-empty value does not survive re-encoding the T.
+func convert[T any](v any) (T, error) {
+	var out T
+	data, err := json.Marshal(v)
+	if err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, err
+	}
+	back, err := json.Marshal(out)
+	if err != nil {
+		return out, err
+	}
+	var before, after any
+	json.Unmarshal(data, &before)
+	json.Unmarshal(back, &after)
+	if lost := lostKeys("", before, after); len(lost) > 0 {
+		sort.Strings(lost)
+		return out, fmt.Errorf("converting %T to %T would drop %s", v, out, strings.Join(lost, ", "))
+	}
+	return out, nil
+}
+
+// lostKeys returns the paths of the object keys in before with non-empty
+// values that are missing from after.
+func lostKeys(path string, before, after any) []string {
+	var lost []string
+	switch b := before.(type) {
+	case map[string]any:
+		a, _ := after.(map[string]any)
+		for key, v := range b {
+			p := key
+			if path != "" {
+				p = path + "." + key
+			}
+			if av, ok := a[key]; ok {
+				lost = append(lost, lostKeys(p, v, av)...)
+			} else if !emptyJSON(v) {
+				lost = append(lost, p)
+			}
+		}
+	case []any:
+		a, _ := after.([]any)
+		for i, v := range b {
+			if i < len(a) {
+				lost = append(lost, lostKeys(fmt.Sprintf("%s[%d]", path, i), v, a[i])...)
+			}
+		}
+	}
+	return lost
+}
+
+// emptyJSON reports whether v is a decoded JSON value that omitempty would
+// leave out.
+func emptyJSON(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// ToolFromSchema converts a schema Tool.
+func ToolFromSchema(t modelcontextprotocol.Tool) (Tool, error) {
+	return convert[Tool](t)
+}
+
+// ToolToSchema converts a Tool to its schema form.
+func ToolToSchema(t Tool) (modelcontextprotocol.Tool, error) {
+	return convert[modelcontextprotocol.Tool](t)
+}
+
+// ResourceTemplateFromSchema converts a schema ResourceTemplate.
+func ResourceTemplateFromSchema(t modelcontextprotocol.ResourceTemplate) (ResourceTemplate, error) {
+	return convert[ResourceTemplate](t)
+}
+
+// ResourceTemplateToSchema converts a ResourceTemplate to its schema form.
+func ResourceTemplateToSchema(t ResourceTemplate) (modelcontextprotocol.ResourceTemplate, error) {
+	return convert[modelcontextprotocol.ResourceTemplate](t)
+}
+
+// ContentFromSchema converts a schema content block to the matching
+// TextContent, ImageContent, AudioContent or EmbeddedResource.
+func ContentFromSchema(c modelcontextprotocol.Content) (Content, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	v, err := unmarshalContent(data)
+	if err != nil {
+		return nil, err
+	}
+	content, ok := v.(Content)
+	if !ok {
+		return nil, fmt.Errorf("unsupported content %s", data)
+	}
+	return content, nil
+}
+
+// ContentToSchema converts a content block of a tool result to its schema
+// form.
+func ContentToSchema(c any) (modelcontextprotocol.Content, error) {
+	result, err := CallToolResultToSchema(&CallToolResult{Content: ContentList{c}})
+	if err != nil {
+		return nil, err
+	}
+	return result.Content[0], nil
+}
+
+// CallToolResultFromSchema converts a schema CallToolResult.
+func CallToolResultFromSchema(r *modelcontextprotocol.CallToolResult) (*CallToolResult, error) {
+	if r == nil {
+		return nil, nil
+	}
+	result, err := convert[CallToolResult](r)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CallToolResultToSchema converts a CallToolResult to its schema form.
+func CallToolResultToSchema(r *CallToolResult) (*modelcontextprotocol.CallToolResult, error) {
+	if r == nil {
+		return nil, nil
+	}
+	result, err := convert[modelcontextprotocol.CallToolResult](r)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SchemaToolHandler adapts a tool handler that takes its arguments as a map
+// and returns a schema CallToolResult, the form the example servers used
+// before they moved to this package's types.
+func SchemaToolHandler(handler func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error)) ToolHandlerFunc {
+	return func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+		args, err := req.ArgumentMap()
+		if err != nil {
+			return nil, err
+		}
+		result, err := handler(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		return CallToolResultFromSchema(result)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 The MCP Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license.
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tmc/mcp/modelcontextprotocol"
+)
+
+func TestCallToolResultSchemaRoundTrip(t *testing.T) {
+	priority := 0.5
+	in := &CallToolResult{Content: ContentList{
+		TextContent{Type: "text", Text: "hi", Annotations: &Annotations{Audience: []Role{"user"}, Priority: &priority}},
+		EmbeddedResource{Type: "resource", Resource: TextResourceContents{URI: "file:///a", Text: "a"}},
+	}}
+	schema, err := CallToolResultToSchema(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, ok := schema.Content[0].(modelcontextprotocol.TextContent); !ok || text.Annotations == nil || *text.Annotations.Priority != 0.5 {
+		t.Errorf("schema content[0] = %#v, want annotated text", schema.Content[0])
+	}
+	out, err := CallToolResultFromSchema(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !jsonEqual(t, in, out) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestSchemaConversionKeepsData(t *testing.T) {
+	// The schema's Annotations have no lastModified.
+	in := &CallToolResult{Content: ContentList{
+		TextContent{Type: "text", Text: "hi", Annotations: &Annotations{LastModified: "2025-01-01T00:00:00Z"}},
+	}}
+	if _, err := CallToolResultToSchema(in); err == nil || !strings.Contains(err.Error(), "content[0].annotations.lastModified") {
+		t.Errorf("CallToolResultToSchema = %v, want an error naming the dropped field", err)
+	}
+}
+
+func TestResourceTemplateWire(t *testing.T) {
+	data, err := json.Marshal(ResourceTemplate{Template: "file:///{path}", Name: "files"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := ResourceTemplateToSchema(ResourceTemplate{Template: "file:///{path}", Name: "files"})
+	if err != nil || schema.URITemplate != "file:///{path}" {
+		t.Errorf("ResourceTemplateToSchema = %+v, %v; want the deprecated Template as uriTemplate", schema, err)
+	}
+	var got ResourceTemplate
+	if err := json.Unmarshal(data, &got); err != nil || got.URITemplate != "file:///{path}" {
+		t.Errorf("decoded %s as %+v, %v", data, got, err)
+	}
+	// Peers built against earlier versions send "template".
+	var legacy ResourceTemplate
+	if err := json.Unmarshal([]byte(`{"template":"db://{table}","name":"tables"}`), &legacy); err != nil || legacy.URITemplate != "db://{table}" {
+		t.Errorf("decoded legacy template as %+v, %v", legacy, err)
+	}
+}
+
+func TestCompleteRequestRef(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want Reference
+	}{
+		{`{"ref":{"type":"ref/prompt","name":"review"},"argument":{"name":"lang","value":"g"}}`, PromptReference{Type: "ref/prompt", Name: "review"}},
+		{`{"ref":{"type":"ref/resource","uri":"file:///{path}"},"argument":{"name":"path","value":"a"}}`, ResourceReference{Type: "ref/resource", URI: "file:///{path}"}},
+	} {
+		var req CompleteRequest
+		if err := json.Unmarshal([]byte(tt.in), &req); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", tt.in, err)
+		}
+		// Ref decodes as a map, as it did before the reference types.
+		if _, ok := req.Ref.(map[string]any); !ok {
+			t.Errorf("Ref = %#v, want a map", req.Ref)
+		}
+		if ref, err := req.Reference(); err != nil || ref != tt.want {
+			t.Errorf("Reference() = %#v, %v; want %#v", ref, err, tt.want)
+		}
+	}
+	if ref, err := (CompleteRequest{Ref: modelcontextprotocol.NewPromptReference("review")}).Reference(); err != nil || ref != (PromptReference{Type: "ref/prompt", Name: "review"}) {
+		t.Errorf("Reference() of a schema reference = %#v, %v", ref, err)
+	}
+	var req CompleteRequest
+	if err := json.Unmarshal([]byte(`{"ref":{"type":"ref/other"}}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := req.Reference(); err == nil {
+		t.Error("resolved an unknown reference type")
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	prompt := PromptReference{Type: "ref/prompt", Name: "review"}
+	resource := ResourceReference{Type: "ref/resource", URI: "file:///{path}"}
+	for _, tt := range []struct {
+		in   any
+		want Reference
+	}{
+		{prompt, prompt},
+		{&resource, resource},
+		{map[string]any{"type": "ref/prompt", "name": "review"}, prompt},
+		{map[string]string{"type": "ref/resource", "uri": "file:///{path}"}, resource},
+		{json.RawMessage(`{"type":"ref/resource","uri":"file:///{path}"}`), resource},
+		{[]byte(`{"type":"ref/prompt","name":"review"}`), prompt},
+		{nil, nil},
+	} {
+		got, err := ParseReference(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseReference(%#v) = %#v, %v; want %#v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []any{map[string]any{"type": "ref/other"}, json.RawMessage(`[1]`), func() {}} {
+		if ref, err := ParseReference(bad); err == nil {
+			t.Errorf("ParseReference(%#v) = %#v, want an error", bad, ref)
+		}
+	}
+}
+
+func TestSchemaToolHandler(t *testing.T) {
+	handler := SchemaToolHandler(func(ctx context.Context, args map[string]json.RawMessage) (*modelcontextprotocol.CallToolResult, error) {
+		return &modelcontextprotocol.CallToolResult{
+			Content: []modelcontextprotocol.Content{modelcontextprotocol.NewTextContent(string(args["q"]))},
+		}, nil
+	})
+	result, err := handler(context.Background(), CallToolRequest{Name: "search", Arguments: json.RawMessage(`{"q":"go"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, ok := result.Content[0].(TextContent); !ok || text.Text != `"go"` {
+		t.Errorf("content = %#v, want the raw argument as text", result.Content[0])
+	}
+}
@@ -0,0 +1,695 @@
+package mcp
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file generates JSON Schema 2020-12 documents from Go types, for the
+// input and output schemas of RegisterTypedToolWithServer and for
+// GenerateTypedSchema.
+//
+// Struct fields follow encoding/json: the json tag names a field and
+// omitempty or omitzero makes it optional, embedded structs are flattened,
+// and ",string" fields are strings. A jsonschema tag adds keywords as a
+// comma-separated list of key=value pairs and flags:
+//
+//	type SearchArgs struct {
+//		Query string   `json:"query" jsonschema:"description=Text to find,minLength=1"`
+//		Sort  string   `json:"sort,omitempty" jsonschema:"enum=relevance|date,default=relevance"`
+//		Limit int      `json:"limit,omitempty" jsonschema:"minimum=1,maximum=100"`
+//		Tags  []string `json:"tags,omitempty" jsonschema:"pattern=^[a-z]+$,uniqueItems"`
+//	}
+//
+// The value keywords enum, const, format, pattern, minLength, maxLength,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf,
+// contentEncoding and contentMediaType apply to the elements of slice and
+// array fields. The other keys are title, description, default, example,
+// minItems, maxItems, uniqueItems, minProperties, maxProperties,
+// additionalProperties, deprecated, readOnly and writeOnly, and the flags
+// required, optional and nullable. The tag "-" leaves the field out. Write
+// a comma inside a value as \\, in the tag source.
+//
+// A blank field sets keywords on the struct itself:
+//
+//	_ struct{} `jsonschema:"additionalProperties=false"`
+//
+// Named struct types used more than once, including recursive ones, are
+// declared once under $defs and referenced with $ref; references to the
+// root type use "#". Pointers are nullable. Interface fields are any value,
+// or a oneOf of the types registered with RegisterImplementations. The
+// generated JSON has sorted keys, so it is stable across runs.
+
+// SchemaOverrideFunc returns the schema to use for a type in place of the
+// generated one.
+type SchemaOverrideFunc func() map[string]any
+
+// OverrideType makes esg use the schema returned by fn for t wherever t
+// appears.
+func (esg *EnhancedSchemaGenerator) OverrideType(t reflect.Type, fn SchemaOverrideFunc) {
+	esg.mu.Lock()
+	if esg.overrides == nil {
+		esg.overrides = make(map[reflect.Type]SchemaOverrideFunc)
+	}
+	esg.overrides[t] = fn
+	esg.mu.Unlock()
+	esg.cache.reset()
+}
+
+// RegisterImplementations records the concrete types that may appear in
+// fields of the interface type iface. Such fields get a oneOf with a schema
+// per implementation; the implementations should have schemas no value
+// matches more than one of, for example through a required const field.
+func (esg *EnhancedSchemaGenerator) RegisterImplementations(iface reflect.Type, impls ...reflect.Type) error {
+	if iface.Kind() != reflect.Interface {
+		return fmt.Errorf("mcp: %s is not an interface type", iface)
+	}
+	for _, impl := range impls {
+		if !impl.Implements(iface) {
+			return fmt.Errorf("mcp: %s does not implement %s", impl, iface)
+		}
+	}
+	esg.mu.Lock()
+	if esg.implementations == nil {
+		esg.implementations = make(map[reflect.Type][]reflect.Type)
+	}
+	esg.implementations[iface] = append(esg.implementations[iface], impls...)
+	esg.mu.Unlock()
+	esg.cache.reset()
+	return nil
+}
+
+// generate returns the cached schema for t, generating it on first use.
+func (esg *EnhancedSchemaGenerator) generate(t reflect.Type) (json.RawMessage, error) {
+	return esg.cache.GetOrCreate(t.PkgPath()+" "+t.String(), func() (json.RawMessage, error) {
+		esg.mu.RLock()
+		defer esg.mu.RUnlock()
+		b := &schemaBuilder{
+			esg:   esg,
+			uses:  make(map[reflect.Type]int),
+			defs:  make(map[string]any),
+			names: make(map[reflect.Type]string),
+			taken: make(map[string]bool),
+		}
+		schema, err := b.build(t)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(schema)
+	})
+}
+
+// OverrideSchema makes the default generator, used by
+// RegisterTypedToolWithServer, use the schema returned by fn for T.
+func OverrideSchema[T any](fn SchemaOverrideFunc) {
+	enhancedSchemaGenerator.OverrideType(reflect.TypeFor[T](), fn)
+}
+
+// RegisterSchemaImplementations records the types of impls as the
+// implementations of the interface I for the default generator:
+//
+//	mcp.RegisterSchemaImplementations[Shape](Circle{}, Square{})
+func RegisterSchemaImplementations[I any](impls ...any) error {
+	types := make([]reflect.Type, len(impls))
+	for i, impl := range impls {
+		types[i] = reflect.TypeOf(impl)
+	}
+	return enhancedSchemaGenerator.RegisterImplementations(reflect.TypeFor[I](), types...)
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	durationType      = reflect.TypeFor[time.Duration]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// schemaBuilder generates the schema of one root type.
+type schemaBuilder struct {
+	esg   *EnhancedSchemaGenerator
+	root  reflect.Type
+	uses  map[reflect.Type]int // references to each struct type
+	defs  map[string]any
+	names map[reflect.Type]string // $defs names of struct types
+	taken map[string]bool
+}
+
+func (b *schemaBuilder) build(t reflect.Type) (map[string]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	b.root = t
+	b.walk(t)
+	var (
+		schema map[string]any
+		err    error
+	)
+	if t.Kind() == reflect.Struct && !b.special(t) {
+		schema, err = b.structSchema(t)
+	} else {
+		schema, err = b.typeSchema(t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(b.defs) > 0 {
+		schema["$defs"] = b.defs
+	}
+	return schema, nil
+}
+
+// special reports whether t has a schema that does not follow from its
+// kind: an override, a time type, or a custom JSON or text encoding.
+func (b *schemaBuilder) special(t reflect.Type) bool {
+	if _, ok := b.esg.overrides[t]; ok {
+		return true
+	}
+	if t.Kind() == reflect.Pointer {
+		return false
+	}
+	return t == timeType || t == durationType || implementsEither(t, jsonMarshalerType) || implementsEither(t, textMarshalerType)
+}
+
+// implementsEither reports whether t or *t implements iface.
+func implementsEither(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+// walk counts the references to each struct type reachable from t.
+func (b *schemaBuilder) walk(t reflect.Type) {
+	if b.special(t) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		b.walk(t.Elem())
+	case reflect.Struct:
+		b.uses[t]++
+		if b.uses[t] > 1 {
+			return
+		}
+		for _, f := range structFields(t) {
+			b.walk(f.typ)
+		}
+	case reflect.Interface:
+		for _, impl := range b.esg.implementations[t] {
+			b.walk(impl)
+		}
+	}
+}
+
+// typeSchema returns the schema for a value of type t.
+func (b *schemaBuilder) typeSchema(t reflect.Type) (map[string]any, error) {
+	if fn, ok := b.esg.overrides[t]; ok {
+		return maps.Clone(fn()), nil
+	}
+	if t.Kind() == reflect.Pointer {
+		elem, err := b.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return nullable(elem), nil
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case t == durationType:
+		return map[string]any{"type": "integer", "description": "Duration in nanoseconds."}, nil
+	case implementsEither(t, jsonMarshalerType):
+		return map[string]any{}, nil
+	case implementsEither(t, textMarshalerType):
+		return map[string]any{"type": "string"}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes []byte as base64.
+			return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := b.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Array:
+		items, err := b.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items, "minItems": t.Len(), "maxItems": t.Len()}, nil
+	case reflect.Map:
+		return b.mapSchema(t)
+	case reflect.Struct:
+		if t == b.root {
+			return map[string]any{"$ref": "#"}, nil
+		}
+		if t.Name() != "" && b.uses[t] > 1 {
+			return b.ref(t)
+		}
+		return b.structSchema(t)
+	case reflect.Interface:
+		impls := b.esg.implementations[t]
+		if len(impls) == 0 {
+			return map[string]any{}, nil
+		}
+		var oneOf []any
+		for _, impl := range impls {
+			s, err := b.typeSchema(impl)
+			if err != nil {
+				return nil, err
+			}
+			oneOf = append(oneOf, s)
+		}
+		return map[string]any{"oneOf": oneOf}, nil
+	}
+	return nil, fmt.Errorf("mcp: cannot generate a JSON schema for %s", t)
+}
+
+func (b *schemaBuilder) mapSchema(t reflect.Type) (map[string]any, error) {
+	values, err := b.typeSchema(t.Elem())
+	if err != nil {
+		return nil, err
+	}
+	schema := map[string]any{"type": "object", "additionalProperties": values}
+	key := t.Key()
+	switch {
+	case key.Kind() == reflect.String || implementsEither(key, textMarshalerType):
+	case key.Kind() >= reflect.Int && key.Kind() <= reflect.Int64:
+		schema["propertyNames"] = map[string]any{"pattern": "^-?[0-9]+$"}
+	case key.Kind() >= reflect.Uint && key.Kind() <= reflect.Uintptr:
+		schema["propertyNames"] = map[string]any{"pattern": "^[0-9]+$"}
+	default:
+		return nil, fmt.Errorf("mcp: cannot generate a JSON schema for %s: unsupported key type", t)
+	}
+	return schema, nil
+}
+
+// ref declares t under $defs on first use and returns a reference to it.
+func (b *schemaBuilder) ref(t reflect.Type) (map[string]any, error) {
+	name, ok := b.names[t]
+	if !ok {
+		name = defName(t)
+		for i := 2; b.taken[name]; i++ {
+			name = defName(t) + strconv.Itoa(i)
+		}
+		b.names[t], b.taken[name] = name, true
+		s, err := b.structSchema(t)
+		if err != nil {
+			return nil, err
+		}
+		b.defs[name] = s
+	}
+	return map[string]any{"$ref": "#/$defs/" + name}, nil
+}
+
+// defName returns the $defs name of a named type, with the type arguments
+// of generic types reduced to their names: Page[pkg/path.Item] is
+// Page_Item.
+func defName(t reflect.Type) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '_':
+			return r
+		case r == '[' || r == ',':
+			return '_'
+		}
+		return -1
+	}, genericArgNames(t.Name()))
+}
+
+// genericArgNames strips package paths from the type arguments in name.
+func genericArgNames(name string) string {
+	var b strings.Builder
+	start := 0
+	for i, r := range name {
+		switch r {
+		case '[', ',', ']':
+			arg := name[start:i]
+			if k := strings.LastIndexByte(arg, '.'); k >= 0 {
+				arg = arg[k+1:]
+			}
+			b.WriteString(arg)
+			b.WriteRune(r)
+			start = i + 1
+		}
+	}
+	b.WriteString(name[start:])
+	return b.String()
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) (map[string]any, error) {
+	props := make(map[string]any)
+	schema := map[string]any{"type": "object", "properties": props}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Name == "_" {
+			opts, err := parseSchemaTag(f.Tag.Get("jsonschema"))
+			if err == nil {
+				_, err = applySchemaTag(schema, nil, opts)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("mcp: %s: %w", t, err)
+			}
+		}
+	}
+	for _, f := range structFields(t) {
+		s, err := b.typeSchema(f.typ)
+		if err != nil {
+			return nil, err
+		}
+		if f.asString {
+			if k := valueKind(f.typ); k == "integer" || k == "number" || k == "boolean" {
+				s = map[string]any{"type": "string"}
+				if f.typ.Kind() == reflect.Pointer {
+					s = nullable(s)
+				}
+			}
+		}
+		if desc := f.tag.Get("description"); desc != "" {
+			s["description"] = desc
+		}
+		opts, err := parseSchemaTag(f.tag.Get("jsonschema"))
+		if err != nil {
+			return nil, fmt.Errorf("mcp: %s.%s: %w", t, f.goName, err)
+		}
+		isRequired := !f.omitEmpty
+		if _, ok := opts["required"]; ok {
+			isRequired = true
+		}
+		if _, ok := opts["optional"]; ok {
+			isRequired = false
+		}
+		if s, err = applySchemaTag(s, f.typ, opts); err != nil {
+			return nil, fmt.Errorf("mcp: %s.%s: %w", t, f.goName, err)
+		}
+		props[f.name] = s
+		if isRequired {
+			required = append(required, f.name)
+		}
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
+
+// structField is a field as encoding/json sees it.
+type structField struct {
+	name      string // JSON name
+	goName    string
+	typ       reflect.Type
+	tag       reflect.StructTag
+	omitEmpty bool
+	asString  bool
+}
+
+// structFields returns the JSON fields of struct type t in order, with the
+// fields of embedded structs promoted and fields tagged jsonschema:"-" left
+// out. Of fields with the same name, the
+// least nested wins.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	depth := make(map[string]int)
+	var collect func(t reflect.Type, level int, visited map[reflect.Type]bool)
+	collect = func(t reflect.Type, level int, visited map[reflect.Type]bool) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		defer delete(visited, t)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" || f.Tag.Get("jsonschema") == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			ft := f.Type
+			if f.Anonymous && name == "" {
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					collect(ft, level+1, visited)
+					continue
+				}
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if d, ok := depth[name]; ok && d <= level {
+				continue
+			}
+			sf := structField{name: name, goName: f.Name, typ: f.Type, tag: f.Tag}
+			for _, opt := range strings.Split(opts, ",") {
+				switch opt {
+				case "omitempty", "omitzero":
+					sf.omitEmpty = true
+				case "string":
+					sf.asString = true
+				}
+			}
+			if _, ok := depth[name]; ok {
+				for j := range fields {
+					if fields[j].name == name {
+						fields[j] = sf
+					}
+				}
+			} else {
+				fields = append(fields, sf)
+			}
+			depth[name] = level
+		}
+	}
+	collect(t, 0, make(map[reflect.Type]bool))
+	return fields
+}
+
+// nullable returns schema extended to also allow null.
+func nullable(schema map[string]any) map[string]any {
+	switch typ := schema["type"].(type) {
+	case string:
+		schema["type"] = []any{typ, "null"}
+		return schema
+	case []any:
+		for _, v := range typ {
+			if v == "null" {
+				return schema
+			}
+		}
+		schema["type"] = append(typ, "null")
+		return schema
+	}
+	if len(schema) == 0 {
+		return schema // already allows anything
+	}
+	return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+}
+
+// valueKeywords are the jsonschema tag keys that describe the elements of
+// slices and arrays rather than the collection.
+var valueKeywords = map[string]bool{
+	"enum": true, "const": true, "format": true, "pattern": true,
+	"minLength": true, "maxLength": true, "minimum": true, "maximum": true,
+	"exclusiveMinimum": true, "exclusiveMaximum": true, "multipleOf": true,
+	"contentEncoding": true, "contentMediaType": true,
+}
+
+// parseSchemaTag splits a jsonschema tag into its keys and values. Flags
+// have empty values.
+func parseSchemaTag(tag string) (map[string]string, error) {
+	opts := make(map[string]string)
+	if tag == "" {
+		return opts, nil
+	}
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			part.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(tag[i])
+		}
+	}
+	parts = append(parts, part.String())
+	for _, p := range parts {
+		key, value, _ := strings.Cut(p, "=")
+		if key == "" {
+			return nil, fmt.Errorf("empty key in jsonschema tag %q", tag)
+		}
+		opts[key] = value
+	}
+	return opts, nil
+}
+
+// applySchemaTag sets the keywords of a parsed jsonschema tag on the schema
+// of a field of type t, which is nil for the blank field of a struct, and
+// returns the result.
+func applySchemaTag(schema map[string]any, t reflect.Type, opts map[string]string) (map[string]any, error) {
+	if _, ok := opts["nullable"]; ok {
+		// First, so that a wrapping anyOf gets the keywords below.
+		schema = nullable(schema)
+	}
+	elem := t
+	values := schema
+	if t != nil {
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		if k := elem.Kind(); (k == reflect.Slice || k == reflect.Array) && elem.Elem().Kind() != reflect.Uint8 {
+			if items, ok := schema["items"].(map[string]any); ok {
+				values, elem = items, elem.Elem()
+			}
+		}
+	}
+	for key, value := range opts {
+		target, kind := schema, valueKind(t)
+		if valueKeywords[key] {
+			target, kind = values, valueKind(elem)
+		}
+		switch key {
+		case "required", "optional", "nullable":
+		case "title", "description", "format", "pattern", "contentEncoding", "contentMediaType":
+			target[key] = value
+		case "enum":
+			var enum []any
+			for _, s := range strings.Split(value, "|") {
+				v, err := parseSchemaValue(kind, s)
+				if err != nil {
+					return nil, fmt.Errorf("enum: %w", err)
+				}
+				enum = append(enum, v)
+			}
+			target[key] = enum
+		case "const", "default":
+			v, err := parseSchemaValue(kind, value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			target[key] = v
+		case "example":
+			v, err := parseSchemaValue(kind, value)
+			if err != nil {
+				return nil, fmt.Errorf("example: %w", err)
+			}
+			examples, _ := target["examples"].([]any)
+			target["examples"] = append(examples, v)
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", key, value)
+			}
+			target[key] = n
+		case "minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s %q", key, value)
+			}
+			target[key] = n
+		case "uniqueItems", "deprecated", "readOnly", "writeOnly":
+			v := true
+			if value != "" {
+				var err error
+				if v, err = strconv.ParseBool(value); err != nil {
+					return nil, fmt.Errorf("invalid %s %q", key, value)
+				}
+			}
+			target[key] = v
+		case "additionalProperties":
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid additionalProperties %q", value)
+			}
+			// additionalProperties only sees the properties declared next to
+			// it, so a $ref or anyOf needs unevaluatedProperties instead.
+			if _, ok := target["properties"]; ok {
+				target["additionalProperties"] = v
+			} else {
+				target["unevaluatedProperties"] = v
+			}
+		default:
+			return nil, fmt.Errorf("unknown jsonschema keyword %q", key)
+		}
+	}
+	return schema, nil
+}
+
+// valueKind returns the JSON type of values of t, or "" if it has no single
+// type.
+func valueKind(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return ""
+}
+
+// parseSchemaValue parses a tag value as a value of the given JSON type.
+// Values of fields without a single type are read as JSON, falling back to
+// a string.
+func parseSchemaValue(kind, s string) (any, error) {
+	switch kind {
+	case "string":
+		return s, nil
+	case "integer":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+		return n, nil
+	case "number":
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", s)
+		}
+		return n, nil
+	case "boolean":
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", s)
+		}
+		return v, nil
+	}
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return s, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,226 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type schemaTreeNode struct {
+	Value    string            `json:"value" jsonschema:"description=Node value\\, any text"`
+	Children []*schemaTreeNode `json:"children,omitempty"`
+}
+
+type schemaAddress struct {
+	City string `json:"city"`
+}
+
+type schemaBase struct {
+	ID      string `json:"id" jsonschema:"format=uuid"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type schemaShape interface{ area() float64 }
+
+type schemaCircle struct {
+	Kind   string  `json:"kind" jsonschema:"const=circle"`
+	Radius float64 `json:"radius" jsonschema:"exclusiveMinimum=0"`
+}
+
+func (c schemaCircle) area() float64 { return 3 * c.Radius * c.Radius }
+
+type schemaSquare struct {
+	Kind string  `json:"kind" jsonschema:"const=square"`
+	Side float64 `json:"side"`
+}
+
+func (s schemaSquare) area() float64 { return s.Side * s.Side }
+
+type schemaArgs struct {
+	schemaBase
+	_ struct{} `jsonschema:"additionalProperties=false"`
+
+	Name     string            `json:"name" jsonschema:"minLength=1,maxLength=64,pattern=^[a-z]+$"`
+	Sort     string            `json:"sort,omitempty" jsonschema:"enum=asc|desc,default=asc"`
+	Limit    int               `json:"limit,omitempty" jsonschema:"minimum=1,maximum=100"`
+	Count    uint8             `json:"count"`
+	Tags     []string          `json:"tags,omitempty" jsonschema:"enum=a|b,uniqueItems,maxItems=3"`
+	Levels   []int             `json:"levels,omitempty" jsonschema:"minimum=0"`
+	Home     schemaAddress     `json:"home"`
+	Work     *schemaAddress    `json:"work,omitempty" jsonschema:"additionalProperties=false"`
+	Note     *string           `json:"note,omitempty"`
+	Timeout  time.Duration     `json:"timeout,omitempty"`
+	Since    time.Time         `json:"since"`
+	Raw      json.RawMessage   `json:"raw,omitempty"`
+	Data     []byte            `json:"data,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Ports    map[int]bool      `json:"ports,omitempty"`
+	Quoted   int64             `json:"quoted,string"`
+	Tree     schemaTreeNode    `json:"tree"`
+	Shape    schemaShape       `json:"shape"`
+	Extra    any               `json:"extra,omitempty" jsonschema:"required"`
+	Legacy   string            `json:"legacy,omitempty" jsonschema:"deprecated,description=Use name"`
+	Internal func()            `json:"internal" jsonschema:"-"`
+	Grid     [2]float64        `json:"grid"`
+	Opt      string            `json:"opt" jsonschema:"optional,nullable"`
+	Skipped  string            `json:"-"`
+	private  string
+}
+
+func TestGenerateSchema(t *testing.T) {
+	esg := NewEnhancedSchemaGenerator()
+	if err := esg.RegisterImplementations(reflect.TypeFor[schemaShape](), reflect.TypeFor[schemaCircle](), reflect.TypeFor[schemaSquare]()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GenerateSchemaWithGenerator[schemaArgs](esg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"comment": {"type": "string"},
+			"name": {"type": "string", "minLength": 1, "maxLength": 64, "pattern": "^[a-z]+$"},
+			"sort": {"type": "string", "enum": ["asc", "desc"], "default": "asc"},
+			"limit": {"type": "integer", "minimum": 1, "maximum": 100},
+			"count": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"type": "string", "enum": ["a", "b"]}, "uniqueItems": true, "maxItems": 3},
+			"levels": {"type": "array", "items": {"type": "integer", "minimum": 0}},
+			"home": {"$ref": "#/$defs/schemaAddress"},
+			"work": {"anyOf": [{"$ref": "#/$defs/schemaAddress"}, {"type": "null"}], "unevaluatedProperties": false},
+			"note": {"type": ["string", "null"]},
+			"timeout": {"type": "integer", "description": "Duration in nanoseconds."},
+			"since": {"type": "string", "format": "date-time"},
+			"raw": {},
+			"data": {"type": "string", "contentEncoding": "base64"},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"ports": {"type": "object", "additionalProperties": {"type": "boolean"}, "propertyNames": {"pattern": "^-?[0-9]+$"}},
+			"quoted": {"type": "string"},
+			"tree": {"$ref": "#/$defs/schemaTreeNode"},
+			"shape": {"oneOf": [
+				{"type": "object", "properties": {"kind": {"type": "string", "const": "circle"}, "radius": {"type": "number", "exclusiveMinimum": 0}}, "required": ["kind", "radius"]},
+				{"type": "object", "properties": {"kind": {"type": "string", "const": "square"}, "side": {"type": "number"}}, "required": ["kind", "side"]}
+			]},
+			"extra": {},
+			"legacy": {"type": "string", "deprecated": true, "description": "Use name"},
+			"grid": {"type": "array", "items": {"type": "number"}, "minItems": 2, "maxItems": 2},
+			"opt": {"type": ["string", "null"]}
+		},
+		"required": ["id", "name", "count", "home", "since", "quoted", "tree", "shape", "extra", "grid"],
+		"$defs": {
+			"schemaAddress": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]},
+			"schemaTreeNode": {
+				"type": "object",
+				"properties": {
+					"value": {"type": "string", "description": "Node value, any text"},
+					"children": {"type": "array", "items": {"anyOf": [{"$ref": "#/$defs/schemaTreeNode"}, {"type": "null"}]}}
+				},
+				"required": ["value"]
+			}
+		}
+	}`
+	assertSchemaJSON(t, got, want)
+
+	esg = NewEnhancedSchemaGenerator()
+	if err := esg.RegisterImplementations(reflect.TypeFor[schemaShape](), reflect.TypeFor[schemaCircle](), reflect.TypeFor[schemaSquare]()); err != nil {
+		t.Fatal(err)
+	}
+	again, err := GenerateSchemaWithGenerator[schemaArgs](esg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(got) {
+		t.Error("schema output is not stable")
+	}
+}
+
+func TestGenerateSchemaRecursiveRoot(t *testing.T) {
+	got, err := GenerateSchemaWithGenerator[*schemaTreeNode](NewEnhancedSchemaGenerator())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSchemaJSON(t, got, `{
+		"type": "object",
+		"properties": {
+			"value": {"type": "string", "description": "Node value, any text"},
+			"children": {"type": "array", "items": {"anyOf": [{"$ref": "#"}, {"type": "null"}]}}
+		},
+		"required": ["value"]
+	}`)
+}
+
+func TestGenerateSchemaOverride(t *testing.T) {
+	esg := NewEnhancedSchemaGenerator()
+	before, err := GenerateSchemaWithGenerator[schemaTreeNode](esg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esg.OverrideType(reflect.TypeFor[schemaTreeNode](), func() map[string]any {
+		return map[string]any{"type": "string", "description": "a serialized tree"}
+	})
+	after, err := GenerateSchemaWithGenerator[schemaTreeNode](esg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) == string(after) {
+		t.Fatal("override ignored: cached schema returned")
+	}
+	assertSchemaJSON(t, after, `{"type": "string", "description": "a serialized tree"}`)
+}
+
+func TestGenerateSchemaErrors(t *testing.T) {
+	esg := NewEnhancedSchemaGenerator()
+	tests := []struct {
+		name string
+		gen  func() (json.RawMessage, error)
+		want string
+	}{
+		{"bad minimum", func() (json.RawMessage, error) {
+			return GenerateSchemaWithGenerator[struct {
+				N int `jsonschema:"minimum=x"`
+			}](esg)
+		}, `invalid minimum "x"`},
+		{"bad enum", func() (json.RawMessage, error) {
+			return GenerateSchemaWithGenerator[struct {
+				N int `jsonschema:"enum=1|two"`
+			}](esg)
+		}, `invalid integer "two"`},
+		{"unknown keyword", func() (json.RawMessage, error) {
+			return GenerateSchemaWithGenerator[struct {
+				S string `jsonschema:"minimun=1"`
+			}](esg)
+		}, `unknown jsonschema keyword "minimun"`},
+		{"func field", func() (json.RawMessage, error) {
+			return GenerateSchemaWithGenerator[struct{ F func() }](esg)
+		}, "cannot generate a JSON schema for func()"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.gen(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+	if err := esg.RegisterImplementations(reflect.TypeFor[schemaShape](), reflect.TypeFor[schemaAddress]()); err == nil {
+		t.Error("RegisterImplementations accepted a type that does not implement the interface")
+	}
+}
+
+// assertSchemaJSON compares schemas as JSON values.
+func assertSchemaJSON(t *testing.T, got json.RawMessage, want string) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		wantJSON, _ := json.MarshalIndent(w, "", "  ")
+		gotJSON, _ := json.MarshalIndent(g, "", "  ")
+		t.Errorf("schema mismatch\ngot:\n%s\nwant:\n%s", gotJSON, wantJSON)
+	}
+}
@@ -0,0 +1,270 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// SchemaHash returns a digest of the callable surface of a server: tool
+// names with their input and output schemas, prompt names with their
+// arguments, and resource template names with their URI templates. Item
+// descriptions are not included, nor are the annotation keywords
+// description, title, examples and $comment anywhere in a schema, nor the
+// order in which items or schema keys are listed, so editing documentation
+// does not change the hash. Every item given is covered; hash only the
+// items a client calls, as ServerSchemaHashOf does, to let the others
+// change freely. The result has the form "sha256:<hex>".
+func SchemaHash(tools []Tool, prompts []Prompt, templates []ResourceTemplate) string {
+	type hashedArg struct {
+		Name     string `json:"name"`
+		Required bool   `json:"required,omitempty"`
+		Schema   any    `json:"schema,omitempty"`
+	}
+	type hashedItem struct {
+		Name   string      `json:"name"`
+		Input  any         `json:"input,omitempty"`
+		Output any         `json:"output,omitempty"`
+		Args   []hashedArg `json:"args,omitempty"`
+		URI    string      `json:"uri,omitempty"`
+	}
+	var surface struct {
+		Tools     []hashedItem `json:"tools"`
+		Prompts   []hashedItem `json:"prompts"`
+		Templates []hashedItem `json:"templates"`
+	}
+	for _, t := range tools {
+		surface.Tools = append(surface.Tools, hashedItem{
+			Name:   t.Name,
+			Input:  canonicalSchema(t.InputSchema),
+			Output: canonicalSchema(t.OutputSchema),
+		})
+	}
+	for _, p := range prompts {
+		item := hashedItem{Name: p.Name}
+		for _, arg := range p.Arguments {
+			item.Args = append(item.Args, hashedArg{Name: arg.Name, Required: arg.Required, Schema: stripAnnotations(arg.Schema)})
+		}
+		sort.Slice(item.Args, func(i, j int) bool { return item.Args[i].Name < item.Args[j].Name })
+		surface.Prompts = append(surface.Prompts, item)
+	}
+	for _, t := range templates {
+		surface.Templates = append(surface.Templates, hashedItem{Name: t.Name, URI: t.uri()})
+	}
+	for _, items := range [][]hashedItem{surface.Tools, surface.Prompts, surface.Templates} {
+		sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	}
+	// Maps marshal with sorted keys, so this encoding is canonical.
+	data, _ := json.Marshal(surface)
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// canonicalSchema decodes a schema so that it re-encodes with sorted keys,
+// without annotations. Empty and undecodable schemas are kept as their raw
+// text.
+func canonicalSchema(raw json.RawMessage) any {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	return stripAnnotations(v)
+}
+
+// schemaAnnotations are the JSON Schema keywords that document a schema
+// without constraining values.
+var schemaAnnotations = []string{"description", "title", "examples", "$comment"}
+
+// stripAnnotations returns the decoded schema v without annotation keywords,
+// descending only into keywords that hold subschemas so that a property
+// named "description" is kept. v may be a schema of another Go type, such
+// as a prompt argument's, which is decoded from its JSON first.
+func stripAnnotations(v any) any {
+	if v == nil {
+		return nil
+	}
+	schema, ok := v.(map[string]any)
+	if !ok {
+		if _, isBool := v.(bool); isBool {
+			return v
+		}
+		data, err := json.Marshal(v)
+		if err != nil || json.Unmarshal(data, &schema) != nil || schema == nil {
+			return v
+		}
+	}
+	out := make(map[string]any, len(schema))
+	for key, val := range schema {
+		out[key] = val
+	}
+	for _, key := range schemaAnnotations {
+		delete(out, key)
+	}
+	for key, val := range out {
+		switch key {
+		case "properties", "patternProperties", "$defs", "definitions", "dependentSchemas":
+			if m, ok := val.(map[string]any); ok {
+				sub := make(map[string]any, len(m))
+				for name, s := range m {
+					sub[name] = stripAnnotations(s)
+				}
+				out[key] = sub
+			}
+		case "items", "additionalItems", "additionalProperties", "contains", "propertyNames",
+			"not", "if", "then", "else", "unevaluatedItems", "unevaluatedProperties":
+			if list, ok := val.([]any); ok {
+				out[key] = stripAnnotationsList(list)
+			} else {
+				out[key] = stripAnnotations(val)
+			}
+		case "allOf", "anyOf", "oneOf", "prefixItems":
+			if list, ok := val.([]any); ok {
+				out[key] = stripAnnotationsList(list)
+			}
+		}
+	}
+	return out
+}
+
+func stripAnnotationsList(list []any) []any {
+	out := make([]any, len(list))
+	for i, s := range list {
+		out[i] = stripAnnotations(s)
+	}
+	return out
+}
+
+// SchemaNames selects tools, prompts and resource templates by name for
+// ServerSchemaHashOf. Resource templates without a name are selected by
+// their URI template.
+type SchemaNames struct {
+	Tools     []string
+	Prompts   []string
+	Templates []string
+}
+
+// SchemaNamesOf returns the names that select tools, prompts and templates.
+func SchemaNamesOf(tools []Tool, prompts []Prompt, templates []ResourceTemplate) SchemaNames {
+	var names SchemaNames
+	for _, t := range tools {
+		names.Tools = append(names.Tools, t.Name)
+	}
+	for _, p := range prompts {
+		names.Prompts = append(names.Prompts, p.Name)
+	}
+	for _, t := range templates {
+		names.Templates = append(names.Templates, templateName(t))
+	}
+	return names
+}
+
+// ServerSchemaHashOf is like ServerSchemaHash but covers only the items
+// named by names, so that the server may add, remove or change others
+// without changing the hash. A named item the server no longer has is left
+// out, which changes the hash too.
+func (c *Client) ServerSchemaHashOf(ctx context.Context, names SchemaNames) (string, error) {
+	tools, prompts, templates, err := c.listSurface(ctx)
+	if err != nil {
+		return "", err
+	}
+	return SchemaHash(
+		selectNamed(tools, names.Tools, func(t Tool) string { return t.Name }),
+		selectNamed(prompts, names.Prompts, func(p Prompt) string { return p.Name }),
+		selectNamed(templates, names.Templates, templateName),
+	), nil
+}
+
+// templateName is the name of t for SchemaNames: its name, or its URI
+// template if it has none.
+func templateName(t ResourceTemplate) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.uri()
+}
+
+// selectNamed returns the items whose name is in names.
+func selectNamed[T any](items []T, names []string, name func(T) string) []T {
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+	var out []T
+	for _, item := range items {
+		if want[name(item)] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// ServerSchemaHash lists the server's tools, prompts and resource templates
+// and returns their SchemaHash. A server without prompts or resources
+// contributes empty lists for them.
+func (c *Client) ServerSchemaHash(ctx context.Context) (string, error) {
+	tools, prompts, templates, err := c.listSurface(ctx)
+	if err != nil {
+		return "", err
+	}
+	return SchemaHash(tools, prompts, templates), nil
+}
+
+// listSurface lists all of the server's tools, prompts and resource
+// templates, treating prompts and templates as empty on a server that does
+// not implement them.
+func (c *Client) listSurface(ctx context.Context) (tools []Tool, prompts []Prompt, templates []ResourceTemplate, err error) {
+	for cursor := ""; ; {
+		res, err := c.ListTools(ctx, ListToolsRequest{Cursor: cursor})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("list tools: %w", err)
+		}
+		tools = append(tools, res.Tools...)
+		if res.NextCursor == "" || res.NextCursor == cursor {
+			break
+		}
+		cursor = res.NextCursor
+	}
+	for cursor := ""; ; {
+		res, err := c.ListPrompts(ctx, ListPromptsRequest{Cursor: cursor})
+		if err != nil {
+			if isMethodNotFound(err) {
+				break
+			}
+			return nil, nil, nil, fmt.Errorf("list prompts: %w", err)
+		}
+		prompts = append(prompts, res.Prompts...)
+		if res.NextCursor == "" || res.NextCursor == cursor {
+			break
+		}
+		cursor = res.NextCursor
+	}
+	for cursor := ""; ; {
+		res, err := c.ListResourceTemplates(ctx, ListResourceTemplatesRequest{Cursor: cursor})
+		if err != nil {
+			if isMethodNotFound(err) {
+				break
+			}
+			return nil, nil, nil, fmt.Errorf("list resource templates: %w", err)
+		}
+		templates = append(templates, res.Templates...)
+		if res.NextCursor == "" || res.NextCursor == cursor {
+			break
+		}
+		cursor = res.NextCursor
+	}
+	return tools, prompts, templates, nil
+}
+
+// isMethodNotFound reports whether err is a JSON-RPC "method not found"
+// response.
+func isMethodNotFound(err error) bool {
+	re, ok := AsResponseError(err)
+	return ok && re.Code == -32601
+}
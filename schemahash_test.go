@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+)
+
+func TestSchemaHash(t *testing.T) {
+	tools := []Tool{
+		{Name: "b", Description: "first", InputSchema: json.RawMessage(`{"type": "object", "properties": {"x": {"type": "string"}}}`)},
+		{Name: "a", InputSchema: json.RawMessage(`{"type":"object"}`)},
+	}
+	prompts := []Prompt{{Name: "p", Arguments: []PromptArgument{{Name: "y"}, {Name: "x", Required: true}}}}
+	templates := []ResourceTemplate{{Name: "doc", URITemplate: "docs://{id}"}}
+	base := SchemaHash(tools, prompts, templates)
+
+	// Order, formatting and descriptions do not matter.
+	same := []Tool{
+		{Name: "a", InputSchema: json.RawMessage(`{"type": "object"}`)},
+		{Name: "b", Description: "second", InputSchema: json.RawMessage(`{"properties":{"x":{"type":"string"}},"type":"object"}`)},
+	}
+	samePrompts := []Prompt{{Name: "p", Description: "d", Arguments: []PromptArgument{{Name: "x", Required: true}, {Name: "y", Description: "d"}}}}
+	if got := SchemaHash(same, samePrompts, templates); got != base {
+		t.Errorf("equivalent surface hashed to %s, want %s", got, base)
+	}
+
+	// Annotations in nested schemas do not matter, but a property named
+	// "description" does.
+	annotated := []Tool{tools[0], {Name: "a", InputSchema: json.RawMessage(`{"type":"object","title":"A","$defs":{"d":{"description":"unused"}}}`)}}
+	annotated[0].InputSchema = json.RawMessage(`{"type":"object","description":"B","properties":{"x":{"type":"string","description":"the x","title":"X","examples":["x"]}}}`)
+	annotatedPrompts := []Prompt{{Name: "p", Arguments: []PromptArgument{{Name: "y", Schema: map[string]any{"description": "y"}}, {Name: "x", Required: true}}}}
+	if got, want := SchemaHash(annotated, prompts, templates), SchemaHash(append([]Tool{tools[0]}, Tool{Name: "a", InputSchema: json.RawMessage(`{"type":"object","$defs":{"d":{}}}`)}), prompts, templates); got != want {
+		t.Errorf("annotated surface hashed to %s, want %s", got, want)
+	}
+	if SchemaHash(annotated, annotatedPrompts, templates) != SchemaHash(annotated, []Prompt{{Name: "p", Arguments: []PromptArgument{{Name: "y", Schema: map[string]any{}}, {Name: "x", Required: true}}}}, templates) {
+		t.Error("prompt argument schema description changed the hash")
+	}
+
+	changed := []struct {
+		name      string
+		tools     []Tool
+		prompts   []Prompt
+		templates []ResourceTemplate
+	}{
+		{"property type", []Tool{tools[0], {Name: "a", InputSchema: json.RawMessage(`{"type":"object","properties":{"x":{"type":"integer"}}}`)}}, prompts, templates},
+		{"output schema", []Tool{tools[0], {Name: "a", InputSchema: tools[1].InputSchema, OutputSchema: json.RawMessage(`{"type":"object"}`)}}, prompts, templates},
+		{"removed tool", tools[:1], prompts, templates},
+		{"description property", []Tool{tools[0], {Name: "a", InputSchema: json.RawMessage(`{"type":"object","properties":{"description":{"type":"string"}}}`)}}, prompts, templates},
+		{"required argument", tools, []Prompt{{Name: "p", Arguments: []PromptArgument{{Name: "x", Required: true}, {Name: "y", Required: true}}}}, templates},
+		{"template", tools, prompts, []ResourceTemplate{{Name: "doc", URITemplate: "docs://{slug}"}}},
+	}
+	for _, tt := range changed {
+		if SchemaHash(tt.tools, tt.prompts, tt.templates) == base {
+			t.Errorf("%s: hash did not change", tt.name)
+		}
+	}
+}
+
+func TestClientServerSchemaHash(t *testing.T) {
+	server := NewServer("hash", "1.0")
+	tool := Tool{Name: "echo", InputSchema: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}}}`)}
+	if err := server.RegisterTool(tool, func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+		return &CallToolResult{}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go func() { _ = server.Serve(context.Background(), &ReadWriteCloserTransport{serverConn}) }()
+	client, err := NewClient(&ReadWriteCloserTransport{clientConn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := client.Initialize(ctx, InitializeRequest{ProtocolVersion: "2025-06-18", ClientInfo: Implementation{Name: "c", Version: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.ServerSchemaHash(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := SchemaHash([]Tool{tool}, nil, nil); got != want {
+		t.Errorf("ServerSchemaHash = %s, want %s", got, want)
+	}
+
+	// Adding a tool changes the whole surface but not the hash of the
+	// items a client uses.
+	names := SchemaNames{Tools: []string{"echo"}}
+	used, err := client.ServerSchemaHashOf(ctx, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterTool(Tool{Name: "added", InputSchema: json.RawMessage(`{"type":"object"}`)}, func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+		return &CallToolResult{}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := client.ServerSchemaHash(ctx); err != nil || all == got {
+		t.Errorf("ServerSchemaHash after adding a tool = %s, %v; want a new hash", all, err)
+	}
+	if after, err := client.ServerSchemaHashOf(ctx, names); err != nil || after != used {
+		t.Errorf("ServerSchemaHashOf after adding a tool = %s, %v; want %s", after, err, used)
+	}
+	gone := Tool{Name: "gone", InputSchema: json.RawMessage(`{"type":"object"}`)}
+	pinned := SchemaHash([]Tool{tool, gone}, nil, nil)
+	if missing, err := client.ServerSchemaHashOf(ctx, SchemaNames{Tools: []string{"echo", "gone"}}); err != nil || missing == pinned {
+		t.Errorf("ServerSchemaHashOf with a missing tool = %s, %v; want other than %s", missing, err, pinned)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -58,10 +59,21 @@ type Server struct {
 	// caller's context has no earlier deadline. Zero means no added deadline.
 	serverRequestTimeout time.Duration
 
+	// batchConcurrency bounds how many requests of one JSON-RPC batch run
+	// at once. Zero means defaultBatchConcurrency.
+	batchConcurrency int
+
+	// toolErrors decides how typed tool handler errors are reported, and
+	// structuredText the text fallback for structured tool results. Both
+	// are set by options and read-only afterward.
+	toolErrors     ToolErrorPolicy
+	structuredText StructuredTextFunc
+
 	mu            sync.RWMutex // Protects the following fields:
 	tools         map[string]toolDefinition
 	resources     map[string]resourceDefinition
 	resourceTmpls map[string]resourceTemplateDefinition
+	tmplOrder     []string // resourceTmpls keys in registration order
 	prompts       map[string]promptDefinition
 	subscriptions map[string]bool
 	conn          *jsonrpc2.Connection
@@ -71,9 +83,27 @@ type Server struct {
 	activeTools   map[string]context.CancelFunc
 	framer        jsonrpc2.Framer
 
+	// protocolVersion is the version agreed in initialize; it decides
+	// whether JSON-RPC batches are accepted. Guarded by mu.
+	protocolVersion string
+
+	// readFallback, when set, reads resources/read URIs that match neither a
+	// registered resource nor a template. subscribeHook, when set, is told
+	// about resources/subscribe and resources/unsubscribe requests before they
+	// are recorded. Both are set by Gateway.
+	readFallback  ReadResourceHandlerFunc
+	subscribeHook func(ctx context.Context, uri string, subscribe bool) error
+
 	// middleware is the chain applied per request in Serve. It is configured
 	// with Use before Serve and read-only afterward, so it is not guarded by mu.
 	middleware []Middleware
+
+	// drain tracks in-flight requests for Shutdown.
+	drain drainTracker
+
+	// streams holds the resource streams handed out when streaming is
+	// enabled with WithServerResourceStreaming, and is nil otherwise.
+	streams *resourceStreams
 }
 
 type toolDefinition struct {
@@ -89,11 +119,14 @@ type resourceDefinition struct {
 type resourceTemplateDefinition struct {
 	template ResourceTemplate
 	handler  ResourceTemplateHandlerFunc
+	complete CompletionHandlerFunc // completes the template's variables, if set
+	matcher  *uriTemplateMatcher   // nil if the template does not parse
 }
 
 type promptDefinition struct {
-	prompt  Prompt
-	handler GetPromptHandlerFunc
+	prompt   Prompt
+	handler  GetPromptHandlerFunc
+	complete CompletionHandlerFunc // completes the prompt's arguments, if set
 }
 
 // Use the connectionBinder type defined in client.go
@@ -186,6 +219,7 @@ func NewServer(name, version string, opts ...ServerOption) *Server {
 		activeTools:          make(map[string]context.CancelFunc),
 		framer:               defaultFramer(),
 		serverRequestTimeout: 30 * time.Second,
+		structuredText:       StructuredJSONText,
 		mu:                   sync.RWMutex{},
 	}
 
@@ -455,6 +489,7 @@ func (s *Server) registerDefaultHandlers() {
 	s.registerToolHandlers()
 	s.registerPromptHandlers()
 	s.registerResourceHandlers()
+	s.registerStreamHandlers()
 }
 
 // registerInitializeHandler registers the initialize protocol handler for handshake and capability negotiation
@@ -475,12 +510,19 @@ func (s *Server) registerInitializeHandler() {
 			return nil, err
 		}
 
+		// Answer with the requested version when it is one we speak, and
+		// with the latest otherwise, as version negotiation requires.
+		version := LATEST_PROTOCOL_VERSION
+		if slices.Contains(supportedProtocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+
 		s.mu.Lock()
 		s.clientCaps = params.Capabilities
+		s.protocolVersion = version
 		s.mu.Unlock()
-
 		result := InitializeResult{
-			ProtocolVersion: LATEST_PROTOCOL_VERSION,
+			ProtocolVersion: version,
 			ServerInfo: Implementation{
 				Name:    s.name,
 				Version: s.version,
@@ -570,7 +612,10 @@ func (s *Server) registerCompletionHandler() {
 		s.capabilities.Completions = &struct{}{}
 	}
 	s.handlers[string(MethodCompletionComplete)] = func(ctx context.Context, req *jsonrpc2.Request) (interface{}, error) {
-		if s.completion == nil {
+		s.mu.RLock()
+		enabled := s.capabilities.Completions != nil
+		s.mu.RUnlock()
+		if !enabled {
 			return nil, jsonrpc2.ErrMethodNotFound
 		}
 		if len(req.Params) == 0 || strings.TrimSpace(string(req.Params)) == "null" {
@@ -584,10 +629,35 @@ func (s *Server) registerCompletionHandler() {
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return nil, NewParameterErrorFromJSON(string(MethodCompletionComplete), err)
 		}
+		ref, err := params.Reference()
+		if err != nil {
+			return nil, NewParameterError(string(MethodCompletionComplete), "ref", "invalid reference", err)
+		}
+		if complete := s.referenceCompleter(ref); complete != nil {
+			return complete(ctx, params)
+		}
+		if s.completion == nil {
+			data, _ := json.Marshal(params.Ref)
+			return nil, NewNotFoundError("completion reference", string(data))
+		}
 		return s.completion(ctx, params)
 	}
 }
 
+// referenceCompleter returns the completion handler registered with the
+// referenced prompt or resource template, or nil.
+func (s *Server) referenceCompleter(ref Reference) CompletionHandlerFunc {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	switch ref := ref.(type) {
+	case PromptReference:
+		return s.prompts[ref.Name].complete
+	case ResourceReference:
+		return s.resourceTmpls[ref.URI].complete
+	}
+	return nil
+}
+
 // registerToolHandlers registers the tool management handlers (list and call)
 func (s *Server) registerToolHandlers() {
 	// Register tools/list handler
@@ -635,9 +705,16 @@ func (s *Server) registerToolHandlers() {
 		}
 
 		result, err := toolDef.handler(ctx, params)
+		var te *ToolError
+		if errors.As(err, &te) {
+			result, err = te.Result(), nil
+		}
 		if err != nil {
 			return nil, err
 		}
+		if err := s.sendToolContent(ctx, result); err != nil {
+			return nil, err
+		}
 
 		return result, nil
 	}
@@ -746,17 +823,32 @@ func (s *Server) registerResourceHandlers() {
 			var matchedTemplate resourceTemplateDefinition
 			var found bool
 
-			// This is a simple exact match for now
-			// In future, implement proper template matching with wildcards or regex
-			for _, tmplDef := range s.resourceTmpls {
-				if tmplDef.template.Template == params.URI {
-					matchedTemplate = tmplDef
-					found = true
-					break
+			// Prefer a template equal to the URI, then the first registered
+			// that expands to it.
+			matchedTemplate, found = s.resourceTmpls[params.URI]
+			if !found {
+				for _, key := range s.tmplOrder {
+					tmplDef := s.resourceTmpls[key]
+					if tmplDef.matcher != nil && tmplDef.matcher.matches(params.URI) {
+						matchedTemplate = tmplDef
+						found = true
+						break
+					}
 				}
 			}
+			fallback := s.readFallback
 			s.mu.RUnlock()
 
+			if !found && fallback != nil {
+				contents, err := fallback(ctx, params)
+				if err != nil {
+					return nil, err
+				}
+				if contents, err = s.sendContents(ctx, contents); err != nil {
+					return nil, err
+				}
+				return ReadResourceResult{Contents: contents}, nil
+			}
 			if !found {
 				return nil, NewNotFoundError("resource", params.URI)
 			}
@@ -765,6 +857,9 @@ func (s *Server) registerResourceHandlers() {
 			if err != nil {
 				return nil, err
 			}
+			if contents, err = s.sendContents(ctx, contents); err != nil {
+				return nil, err
+			}
 
 			result := ReadResourceResult{
 				Contents: contents,
@@ -777,6 +872,9 @@ func (s *Server) registerResourceHandlers() {
 		if err != nil {
 			return nil, err
 		}
+		if contents, err = s.sendContents(ctx, contents); err != nil {
+			return nil, err
+		}
 
 		result := ReadResourceResult{
 			Contents: contents,
@@ -800,7 +898,7 @@ func (s *Server) registerResourceHandlers() {
 			templates = append(templates, def.template)
 		}
 
-		page, next := paginate(templates, params.Cursor, func(t ResourceTemplate) string { return t.Template })
+		page, next := paginate(templates, params.Cursor, func(t ResourceTemplate) string { return t.URITemplate })
 		return ListResourceTemplatesResult{Templates: page, NextCursor: next}, nil
 	}
 
@@ -812,6 +910,9 @@ func (s *Server) registerResourceHandlers() {
 		if err := s.validator.ValidateResourceSubscription(MethodResourcesSubscribe, params.URI); err != nil {
 			return nil, err
 		}
+		if err := s.runSubscribeHook(ctx, params.URI, true); err != nil {
+			return nil, err
+		}
 
 		s.mu.Lock()
 		s.subscriptions[params.URI] = true
@@ -827,6 +928,9 @@ func (s *Server) registerResourceHandlers() {
 		if err := s.validator.ValidateResourceSubscription(MethodResourcesUnsubscribe, params.URI); err != nil {
 			return nil, err
 		}
+		if err := s.runSubscribeHook(ctx, params.URI, false); err != nil {
+			return nil, err
+		}
 
 		s.mu.Lock()
 		delete(s.subscriptions, params.URI)
@@ -835,6 +939,16 @@ func (s *Server) registerResourceHandlers() {
 	}
 }
 
+func (s *Server) runSubscribeHook(ctx context.Context, uri string, subscribe bool) error {
+	s.mu.RLock()
+	hook := s.subscribeHook
+	s.mu.RUnlock()
+	if hook == nil {
+		return nil
+	}
+	return hook(ctx, uri, subscribe)
+}
+
 func unmarshalOptionalParams(method string, params json.RawMessage, dst any) error {
 	if len(params) == 0 || strings.TrimSpace(string(params)) == "null" {
 		return nil
@@ -892,6 +1006,12 @@ func (s *Server) RegisterTool(tool Tool, handler ToolHandlerFunc) error {
 
 // RegisterPrompt adds a new prompt to the server.
 func (s *Server) RegisterPrompt(prompt Prompt, handler GetPromptHandlerFunc) error {
+	return s.registerPrompt(prompt, handler, nil)
+}
+
+// registerPrompt adds a prompt whose arguments complete is used to
+// complete, if it is not nil.
+func (s *Server) registerPrompt(prompt Prompt, handler GetPromptHandlerFunc, complete CompletionHandlerFunc) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -903,8 +1023,12 @@ func (s *Server) RegisterPrompt(prompt Prompt, handler GetPromptHandlerFunc) err
 	}
 
 	s.prompts[prompt.Name] = promptDefinition{
-		prompt:  prompt,
-		handler: handler,
+		prompt:   prompt,
+		handler:  handler,
+		complete: complete,
+	}
+	if complete != nil {
+		s.capabilities.Completions = &struct{}{}
 	}
 
 	// Initialize and set prompts capability
@@ -961,19 +1085,41 @@ func (s *Server) RegisterResource(resource Resource, handler ReadResourceHandler
 
 // RegisterResourceTemplate adds a new resource template to the server.
 func (s *Server) RegisterResourceTemplate(template ResourceTemplate, handler ResourceTemplateHandlerFunc) error {
+	return s.registerResourceTemplate(template, handler, nil)
+}
+
+// registerResourceTemplate adds a resource template whose variables
+// complete is used to complete, if it is not nil.
+func (s *Server) registerResourceTemplate(template ResourceTemplate, handler ResourceTemplateHandlerFunc, complete CompletionHandlerFunc) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.logger.Debug("Registering resource template", "template", template.Template)
+	// Templates registered under the deprecated field name are stored, and
+	// listed, under the spec's.
+	template.URITemplate, template.Template = template.uri(), ""
+	if template.Name == "" {
+		template.Name = template.URITemplate
+	}
+	s.logger.Debug("Registering resource template", "template", template.URITemplate)
 
-	if _, exists := s.resourceTmpls[template.Template]; exists {
-		s.logger.Warn("Resource template already registered", "template", template.Template)
-		return NewAlreadyExistsError("resource template", template.Template)
+	if _, exists := s.resourceTmpls[template.URITemplate]; exists {
+		s.logger.Warn("Resource template already registered", "template", template.URITemplate)
+		return NewAlreadyExistsError("resource template", template.URITemplate)
 	}
 
-	s.resourceTmpls[template.Template] = resourceTemplateDefinition{
+	matcher, err := newURITemplateMatcher(template.URITemplate)
+	if err != nil {
+		s.logger.Warn("Resource template does not parse; it is read only by its literal URI", "template", template.URITemplate, "error", err)
+	}
+	s.resourceTmpls[template.URITemplate] = resourceTemplateDefinition{
 		template: template,
 		handler:  handler,
+		complete: complete,
+		matcher:  matcher,
+	}
+	s.tmplOrder = append(s.tmplOrder, template.URITemplate)
+	if complete != nil {
+		s.capabilities.Completions = &struct{}{}
 	}
 	if s.capabilities.Resources == nil {
 		s.capabilities.Resources = &struct {
@@ -984,18 +1130,88 @@ func (s *Server) RegisterResourceTemplate(template ResourceTemplate, handler Res
 	s.capabilities.Resources.ListChanged = true
 	s.capabilities.Resources.Subscribe = true
 
-	s.logger.Info("Resource template registered successfully", "template", template.Template)
+	s.logger.Info("Resource template registered successfully", "template", template.URITemplate)
 	s.logger.Debug("Sending resource list changed notification")
 	go s.notifyListChanged(MethodResourceListChanged)
 
 	return nil
 }
 
+// RemoveTool removes a registered tool and notifies the connected client.
+// It reports whether the tool was registered.
+func (s *Server) RemoveTool(name string) bool {
+	return s.notifyRemoved(s.removeTool(name), MethodToolListChanged)
+}
+
+// RemovePrompt removes a registered prompt and notifies the connected client.
+// It reports whether the prompt was registered.
+func (s *Server) RemovePrompt(name string) bool {
+	return s.notifyRemoved(s.removePrompt(name), MethodPromptListChanged)
+}
+
+// RemoveResource removes a registered resource and notifies the connected
+// client. It reports whether the resource was registered.
+func (s *Server) RemoveResource(uri string) bool {
+	return s.notifyRemoved(s.removeResource(uri), MethodResourceListChanged)
+}
+
+// RemoveResourceTemplate removes a registered resource template and notifies
+// the connected client. It reports whether the template was registered.
+func (s *Server) RemoveResourceTemplate(template string) bool {
+	return s.notifyRemoved(s.removeResourceTemplate(template), MethodResourceListChanged)
+}
+
+// notifyRemoved sends method, a list_changed notification, if removed.
+func (s *Server) notifyRemoved(removed bool, method Method) bool {
+	if removed {
+		go s.notifyListChanged(method)
+	}
+	return removed
+}
+
+// removeTool, removePrompt, removeResource and removeResourceTemplate
+// unregister without notifying the client, for callers that remove many
+// entries and notify once.
+
+func (s *Server) removeTool(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.tools[name]
+	delete(s.tools, name)
+	return ok
+}
+
+func (s *Server) removePrompt(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.prompts[name]
+	delete(s.prompts, name)
+	return ok
+}
+
+func (s *Server) removeResource(uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.resources[uri]
+	delete(s.resources, uri)
+	return ok
+}
+
+func (s *Server) removeResourceTemplate(template string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.resourceTmpls[template]
+	delete(s.resourceTmpls, template)
+	s.tmplOrder = slices.DeleteFunc(s.tmplOrder, func(key string) bool { return key == template })
+	return ok
+}
+
 // ResourceUpdated notifies subscribed clients that a resource changed.
 func (s *Server) ResourceUpdated(ctx context.Context, params ResourceUpdatedNotificationParams) error {
 	if err := s.validator.ValidateResourceSubscription(MethodResourceUpdated, params.URI); err != nil {
 		return err
 	}
+	s.observeNotification(MethodResourceUpdated, params)
 
 	s.mu.RLock()
 	subscribed := s.subscriptions[params.URI]
@@ -1187,6 +1403,7 @@ func (s *Server) notify(ctx context.Context, method Method, params any) error {
 // It no-ops when no client is connected (for example, when a tool, prompt, or
 // resource is registered before Serve), so startup-time registration is silent.
 func (s *Server) notifyListChanged(method Method) {
+	s.observeNotification(method, nil)
 	if err := s.notify(context.Background(), method, struct{}{}); err != nil {
 		s.logger.Debug("failed to send list changed notification", "method", string(method), "error", err)
 	}
@@ -1195,14 +1412,27 @@ func (s *Server) notifyListChanged(method Method) {
 // Use appends middleware to the server's chain. Middleware runs per request,
 // in priority order, when Serve handles a connection. Call Use before Serve.
 func (s *Server) Use(m Middleware) {
+	if o, ok := m.(serverObserver); ok {
+		o.attach(s)
+	}
 	s.middleware = append(s.middleware, m)
 }
 
+// observeNotification reports an outgoing notification to middleware that
+// tracks server state, such as response caches.
+func (s *Server) observeNotification(method Method, params any) {
+	for _, m := range s.middleware {
+		if o, ok := m.(serverObserver); ok {
+			o.observeNotification(method, params)
+		}
+	}
+}
+
 // mcpBaseHandler adapts handleRequest into the middleware MCPHandler interface.
 // It is the base of the chain: the chain wraps this, and Serve adapts the
 // chain's MCPResponse back to a jsonrpc2 result. A *ResponseError from
 // handleRequest is preserved so its code survives the round-trip; any other
-// error becomes an internal error.
+// error becomes an internal error unless it wraps a jsonrpc2 error code.
 func (s *Server) mcpBaseHandler() MCPHandler {
 	return MCPHandlerFunc(func(ctx context.Context, req MCPRequest) (MCPResponse, error) {
 		result, err := s.handleRequest(ctx, mcpRequestToJSONRPC(req))
@@ -1211,7 +1441,12 @@ func (s *Server) mcpBaseHandler() MCPHandler {
 			if errors.As(err, &re) {
 				return &errorResponse{err: re}, nil
 			}
-			return &errorResponse{err: &ResponseError{Code: -32603, Message: err.Error()}}, nil
+			// Keep the code of a wrapped jsonrpc2 error, as jsonrpc2 itself would.
+			code := -32603
+			if wrapped, ok := AsResponseError(err); ok && wrapped.Code != 0 {
+				code = wrapped.Code
+			}
+			return &errorResponse{err: &ResponseError{Code: code, Message: err.Error()}}, nil
 		}
 		return &successResponse{result: result}, nil
 	})
@@ -1283,10 +1518,50 @@ func (s *Server) Serve(ctx context.Context, transport Transport) error {
 	// configured, the chain wraps the request handler so it runs on the wire.
 	handler := jsonrpc2.HandlerFunc(s.middlewareHandler())
 	binder := serverBinder{handler: handler, logger: s.logger, framer: s.framer}
-	conn, err := jsonrpc2.Dial(ctx, flushingd, binder)
+
+	// jsonrpc2 reads single messages only; JSON-RPC batches are split off
+	// before it sees them and answered here. Requests on the connection are
+	// tracked so Shutdown can drain them.
+	var session string
+	if st, ok := transport.(*StreamableServerTransport); ok {
+		session = st.id
+	}
+	batchCtx, cancelBatches := context.WithCancel(ctx)
+	defer cancelBatches()
+	var owner *batchConn
+	var connSession string // session of the connection's requests
+	batching := batchDialer{dialer: flushingd, bind: func(bc *batchConn) {
+		owner = bc
+		connSession = session
+		if connSession == "" {
+			connSession = fmt.Sprintf("conn-%p", bc)
+		}
+		tracked := s.drainHandler(sessionHandler(handler, connSession), bc, session)
+		binder.handler = tracked
+		bc.single = func(raw json.RawMessage) bool {
+			s.drain.received(bc, session, raw)
+			return false
+		}
+		bc.wrote = func(p []byte) { s.drain.wrote(bc, p) }
+		bc.batch = func(msgs []json.RawMessage, forward func(json.RawMessage)) {
+			s.serveBatch(batchCtx, tracked, msgs, forward, func(v any) {
+				if err := bc.writeMessage(v); err != nil {
+					s.logger.Error("failed to write batch response", "error", err)
+				}
+			})
+		}
+	}}
+	select {
+	case <-s.drain.closed():
+		return ErrServerClosed
+	default:
+	}
+	conn, err := jsonrpc2.Dial(ctx, batching, &binder)
 	if err != nil {
 		return fmt.Errorf("failed to establish connection: %w", err)
 	}
+	defer s.drain.forget(owner)
+	defer s.streams.closeSession(connSession)
 	s.mu.Lock()
 	s.conn = conn
 	s.mu.Unlock()
@@ -1309,6 +1584,8 @@ func (s *Server) Serve(ctx context.Context, transport Transport) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
+	case <-s.drain.closed():
+		return ErrServerClosed
 	case err := <-done:
 		// Connection finished, return any error
 		if err != nil {
@@ -216,7 +216,8 @@ func TestServerResourceTemplateManagement(t *testing.T) {
 	server := NewServer("test", "1.0", WithTestLogger(t, slog.LevelDebug))
 
 	template := ResourceTemplate{
-		Template:    "test://files/{id}",
+		URITemplate: "test://files/{id}",
+		Name:        "files",
 		Description: "Template for file resources",
 	}
 
@@ -257,6 +258,42 @@ func TestServerResourceTemplateManagement(t *testing.T) {
 	server.mu.RUnlock()
 }
 
+// TestServerResourceTemplateOrder tests that overlapping templates match in
+// registration order, and removed ones no longer match
+func TestServerResourceTemplateOrder(t *testing.T) {
+	server := NewServer("test", "1.0", WithTestLogger(t, slog.LevelDebug))
+	for _, tmpl := range []string{"file:///{name}", "file:///{+path}", "file:///a/{name}"} {
+		err := server.RegisterResourceTemplate(ResourceTemplate{URITemplate: tmpl, Name: tmpl}, func(ctx context.Context, req ReadResourceRequest) ([]ResourceContents, error) {
+			return []ResourceContents{TextResourceContents{URI: req.URI, Text: tmpl}}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := newCacheTestPair(t, server)
+	read := func(uri string) string {
+		t.Helper()
+		result, err := client.ReadResource(context.Background(), ReadResourceRequest{URI: uri})
+		if err != nil {
+			return err.Error()
+		}
+		return result.Contents[0].(TextResourceContents).Text
+	}
+
+	for range 10 {
+		if got := read("file:///readme"); got != "file:///{name}" {
+			t.Fatalf("file:///readme read by %q, want the first registered template", got)
+		}
+		if got := read("file:///a/b"); got != "file:///{+path}" {
+			t.Fatalf("file:///a/b read by %q, want file:///{+path}", got)
+		}
+	}
+	server.RemoveResourceTemplate("file:///{+path}")
+	if got := read("file:///a/b"); got != "file:///a/{name}" {
+		t.Errorf("file:///a/b read by %q after removal, want file:///a/{name}", got)
+	}
+}
+
 // TestServerPromptManagement tests prompt registration and handling
 func TestServerPromptManagement(t *testing.T) {
 	server := NewServer("test", "1.0", WithTestLogger(t, slog.LevelDebug))
@@ -59,11 +59,8 @@ func ExampleServer() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	// Content is returned as a map, not a typed struct
-	if content, ok := res.Content[0].(map[string]interface{}); ok {
-		if text, ok := content["text"].(string); ok {
-			fmt.Println(text)
-		}
+	if content, ok := res.Content[0].(mcp.TextContent); ok {
+		fmt.Println(content.Text)
 	}
 
 	client.Close()
@@ -407,8 +407,9 @@ func TestServerHandleInitialize(t *testing.T) {
 				return
 			}
 
-			if initResult.ProtocolVersion != LATEST_PROTOCOL_VERSION {
-				t.Errorf("Expected protocol version %s, got %s", LATEST_PROTOCOL_VERSION, initResult.ProtocolVersion)
+			// The server speaks the requested version, so it answers with it.
+			if initResult.ProtocolVersion != "2024-11-05" {
+				t.Errorf("Expected protocol version 2024-11-05, got %s", initResult.ProtocolVersion)
 			}
 
 			if initResult.ServerInfo.Name != server.name {
@@ -676,7 +677,7 @@ func TestClientComplete(t *testing.T) {
 	}
 
 	var req CompleteRequest
-	req.Ref = map[string]string{"type": "ref/prompt", "name": "code_review"}
+	req.Ref = PromptReference{Type: "ref/prompt", Name: "code_review"}
 	req.Argument.Name = "language"
 	req.Argument.Value = "go"
 	result, err := client.Complete(context.Background(), req)
@@ -0,0 +1,385 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// ErrServerClosed is returned by Serve once Shutdown has been called.
+var ErrServerClosed = errors.New("mcp: server closed")
+
+// ErrShuttingDown is the error requests receive once Shutdown has begun. It
+// is also the cause of the contexts of handlers cancelled when Shutdown's
+// deadline passes. On the wire it is a -32000 error.
+var ErrShuttingDown = &ResponseError{Code: -32000, Message: "server is shutting down"}
+
+// shutdownCancelGrace is how long Shutdown waits for handlers it cancelled
+// to return, so their clients get an error response rather than silence.
+const shutdownCancelGrace = 500 * time.Millisecond
+
+// AbandonedRequest describes a request that was still running when
+// Shutdown's deadline passed.
+type AbandonedRequest struct {
+	Method string
+	ID     any
+	// Session is the streamable HTTP session the request arrived on, or ""
+	// for other transports.
+	Session string
+	// Returned reports whether the handler returned after it was cancelled.
+	// A handler that did not ignored its context and may still be running.
+	Returned bool
+}
+
+// ShutdownError is returned by Shutdown when requests had to be abandoned.
+type ShutdownError struct {
+	Abandoned []AbandonedRequest
+	Err       error // why draining stopped, usually context.DeadlineExceeded
+}
+
+func (e *ShutdownError) Error() string {
+	names := make([]string, len(e.Abandoned))
+	for i, r := range e.Abandoned {
+		names[i] = fmt.Sprintf("%s (id %v)", r.Method, r.ID)
+	}
+	return fmt.Sprintf("mcp: shutdown abandoned %d request(s): %s: %v", len(e.Abandoned), strings.Join(names, ", "), e.Err)
+}
+
+func (e *ShutdownError) Unwrap() error { return e.Err }
+
+// inflightRequest is a request read from a connection whose response has
+// not been written yet.
+type inflightRequest struct {
+	owner   *batchConn // the connection it arrived on
+	key     string     // canonical JSON of its ID
+	method  string
+	id      jsonrpc2.ID
+	session string
+	// cancel is set once a handler starts on the request; returned once it
+	// has finished.
+	cancel   context.CancelCauseFunc
+	returned bool
+}
+
+// drainTracker follows the requests a Server is handling so Shutdown can
+// wait for them. A request is in flight from the moment it is read, so
+// requests still queued behind others count, until its response has been
+// written.
+type drainTracker struct {
+	mu       sync.Mutex
+	closing  bool
+	requests map[*inflightRequest]struct{}
+	changed  chan struct{} // closed and replaced whenever requests shrinks
+	done     chan struct{} // closed when Shutdown completes
+}
+
+// doneChan returns the channel closed when Shutdown completes. It must be
+// called with t.mu held.
+func (t *drainTracker) doneChan() chan struct{} {
+	if t.done == nil {
+		t.done = make(chan struct{})
+	}
+	return t.done
+}
+
+// closed returns the channel closed when Shutdown completes.
+func (t *drainTracker) closed() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.doneChan()
+}
+
+// add records a request read from owner. It must be called with t.mu held.
+func (t *drainTracker) add(owner *batchConn, session string, req *jsonrpc2.Request) *inflightRequest {
+	r := &inflightRequest{owner: owner, key: canonicalIDKey(req.ID.Raw()), method: req.Method, id: req.ID, session: session}
+	if t.requests == nil {
+		t.requests = make(map[*inflightRequest]struct{})
+	}
+	t.requests[r] = struct{}{}
+	return r
+}
+
+// received is told about every single message read from owner, before
+// jsonrpc2 sees it. Requests jsonrpc2 will answer are recorded.
+func (t *drainTracker) received(owner *batchConn, session string, raw json.RawMessage) {
+	if !integralID(raw) {
+		return
+	}
+	msg, err := jsonrpc2.DecodeMessage(raw)
+	if req, ok := msg.(*jsonrpc2.Request); err != nil || !ok || !req.IsCall() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.add(owner, session, msg.(*jsonrpc2.Request))
+}
+
+// start is called as a handler begins on req and returns the context to
+// run it with. It fails with ErrShuttingDown once Shutdown has begun; the
+// request stays in flight until that error has been written.
+func (t *drainTracker) start(ctx context.Context, owner *batchConn, session string, req *jsonrpc2.Request) (context.Context, *inflightRequest, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := canonicalIDKey(req.ID.Raw())
+	var r *inflightRequest
+	for q := range t.requests {
+		if q.owner == owner && q.key == key && q.cancel == nil {
+			r = q
+			break
+		}
+	}
+	if r == nil {
+		// Requests in a batch are not seen by received.
+		r = t.add(owner, session, req)
+	}
+	if t.closing {
+		r.cancel = func(error) {}
+		r.returned = true
+		return ctx, nil, ErrShuttingDown
+	}
+	ctx, r.cancel = context.WithCancelCause(ctx)
+	return ctx, r, nil
+}
+
+// returned records that r's handler has returned.
+func (t *drainTracker) returned(r *inflightRequest) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r.returned = true
+}
+
+// remove drops r and wakes waiters. It must be called with t.mu held.
+func (t *drainTracker) remove(r *inflightRequest) {
+	delete(t.requests, r)
+	if r.cancel != nil {
+		r.cancel(nil)
+	}
+	if t.changed != nil {
+		close(t.changed)
+		t.changed = nil
+	}
+}
+
+// wrote is told about every message written to owner. Responses complete
+// the requests they answer.
+func (t *drainTracker) wrote(owner *batchConn, p []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.requests) == 0 {
+		return
+	}
+	for _, key := range responseIDKeys(p) {
+		for r := range t.requests {
+			if r.owner == owner && r.key == key {
+				t.remove(r)
+				break
+			}
+		}
+	}
+}
+
+// forget drops every request that arrived on owner, whose connection has
+// closed and can no longer carry their responses.
+func (t *drainTracker) forget(owner *batchConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for r := range t.requests {
+		if r.owner == owner {
+			t.remove(r)
+		}
+	}
+}
+
+// wait blocks until no requests are in flight or ctx is done.
+func (t *drainTracker) wait(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		if len(t.requests) == 0 {
+			t.mu.Unlock()
+			return nil
+		}
+		if t.changed == nil {
+			t.changed = make(chan struct{})
+		}
+		changed := t.changed
+		t.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// cancelAll cancels every request in flight with cause and returns them.
+func (t *drainTracker) cancelAll(cause error) []*inflightRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []*inflightRequest
+	for r := range t.requests {
+		if r.cancel != nil {
+			r.cancel(cause)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// canonicalIDKey returns a form of a JSON-RPC ID that is equal for equal
+// IDs however they were spelled on the wire.
+func canonicalIDKey(id any) string {
+	data, _ := json.Marshal(id)
+	return string(data)
+}
+
+// responseIDKeys returns the canonical IDs of the responses in p, which is a
+// single message or a batch.
+func responseIDKeys(p []byte) []string {
+	p = bytes.TrimSpace(p)
+	if len(p) == 0 || p[0] != '[' {
+		if key, ok := responseIDKey(p); ok {
+			return []string{key}
+		}
+		return nil
+	}
+	var msgs []json.RawMessage
+	if json.Unmarshal(p, &msgs) != nil {
+		return nil
+	}
+	var keys []string
+	for _, raw := range msgs {
+		if key, ok := responseIDKey(raw); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// responseIDKey returns the canonical ID of the response in raw. It stops
+// reading at the result or error, which follows the ID on the wire, so large
+// results are not decoded.
+func responseIDKey(raw []byte) (string, bool) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return "", false
+	}
+	var id any
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", false
+		}
+		switch tok {
+		case "id":
+			if dec.Decode(&id) != nil {
+				return "", false
+			}
+		case "method":
+			return "", false
+		case "result", "error":
+			if id != nil {
+				return canonicalIDKey(id), true
+			}
+			fallthrough
+		default:
+			var skip json.RawMessage
+			if dec.Decode(&skip) != nil {
+				return "", false
+			}
+		}
+	}
+	if id == nil {
+		return "", false
+	}
+	return canonicalIDKey(id), true
+}
+
+// drainHandler tracks the requests handler serves on one connection so
+// Shutdown can wait for them, and refuses new ones once it has begun.
+// Notifications pass through untracked.
+func (s *Server) drainHandler(handler jsonrpc2.HandlerFunc, owner *batchConn, session string) jsonrpc2.HandlerFunc {
+	return func(ctx context.Context, req *jsonrpc2.Request) (interface{}, error) {
+		if !req.IsCall() {
+			return handler(ctx, req)
+		}
+		ctx, r, err := s.drain.start(ctx, owner, session, req)
+		if err != nil {
+			return nil, err
+		}
+		defer s.drain.returned(r)
+		return handler(ctx, req)
+	}
+}
+
+// Shutdown gracefully stops the server. New requests are refused with
+// ErrShuttingDown while requests already running are left to finish and
+// their responses written. If ctx expires first, the remaining handlers are
+// cancelled with ErrShuttingDown as the cause and given a moment to return,
+// and Shutdown returns a *ShutdownError listing them. Either way every
+// connection is then closed and Serve returns ErrServerClosed.
+//
+// Notifications a handler sends are written before its response, so they
+// are delivered too. For streamable HTTP use StreamableHTTPHandler.Shutdown,
+// which also ends open SSE streams cleanly.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.drain.mu.Lock()
+	s.drain.closing = true
+	s.drain.mu.Unlock()
+
+	err := s.drain.wait(ctx)
+	var abandoned []*inflightRequest
+	if err != nil {
+		abandoned = s.drain.cancelAll(ErrShuttingDown)
+		s.logger.Warn("shutdown deadline passed, cancelling requests", "requests", len(abandoned))
+		graceCtx, cancel := context.WithTimeout(context.Background(), shutdownCancelGrace)
+		s.drain.wait(graceCtx)
+		cancel()
+	}
+
+	s.drain.mu.Lock()
+	defer s.drain.mu.Unlock()
+	select {
+	case <-s.drain.doneChan():
+	default:
+		close(s.drain.doneChan())
+	}
+	if len(abandoned) == 0 {
+		return nil
+	}
+	serr := &ShutdownError{Err: err}
+	for _, r := range abandoned {
+		serr.Abandoned = append(serr.Abandoned, AbandonedRequest{
+			Method:   r.method,
+			ID:       r.id.Raw(),
+			Session:  r.session,
+			Returned: r.returned,
+		})
+	}
+	return serr
+}
+
+// ServeUntilDone serves transport like Serve until ctx is done, typically a
+// context from signal.NotifyContext, and then calls Shutdown with a deadline
+// of grace so in-flight calls can finish. It returns Shutdown's result in
+// that case, and Serve's otherwise.
+func (s *Server) ServeUntilDone(ctx context.Context, transport Transport, grace time.Duration) error {
+	shutdown := make(chan error, 1)
+	stop := context.AfterFunc(ctx, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+		shutdown <- s.Shutdown(ctx)
+	})
+	err := s.Serve(context.Background(), transport)
+	if stop() {
+		return err
+	}
+	return <-shutdown
+}
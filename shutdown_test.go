@@ -0,0 +1,206 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newShutdownPair serves server over a pipe and returns an initialized
+// client and the channel Serve's result is sent on.
+func newShutdownPair(t *testing.T, server *Server) (*Client, <-chan error) {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(context.Background(), &ReadWriteCloserTransport{serverConn}) }()
+	client, err := NewClient(&ReadWriteCloserTransport{clientConn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if _, err := client.Initialize(context.Background(), InitializeRequest{ClientInfo: Implementation{Name: "c", Version: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	return client, served
+}
+
+func TestServerShutdownDrains(t *testing.T) {
+	server := NewServer("shutdown", "1.0")
+	started, release := make(chan struct{}), make(chan struct{})
+	server.RegisterTool(Tool{Name: "slow"}, func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+		close(started)
+		<-release
+		return &CallToolResult{Content: []any{TextContent{Type: "text", Text: "done"}}}, nil
+	})
+	conn, r := rawSession(t, server, LATEST_PROTOCOL_VERSION)
+	ctx := context.Background()
+
+	writeLine(t, conn, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"slow"}}`)
+	<-started
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- server.Shutdown(ctx) }()
+	waitFor(t, "shutdown to begin", func() bool {
+		server.drain.mu.Lock()
+		defer server.drain.mu.Unlock()
+		return server.drain.closing
+	})
+	// The pipe is synchronous, so the server has read the ping once the
+	// write returns.
+	writeLine(t, conn, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	close(release)
+
+	if line := readLine(t, r); !strings.Contains(line, `"id":1,"result"`) {
+		t.Errorf("in-flight call answered %s", line)
+	}
+	if line := readLine(t, r); !strings.Contains(line, `"id":2,"error":{"code":-32000`) {
+		t.Errorf("ping during shutdown answered %s, want code %d", line, ErrShuttingDown.Code)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+	if err := server.Serve(ctx, &ReadWriteCloserTransport{nopRWC{}}); !errors.Is(err, ErrServerClosed) {
+		t.Errorf("Serve after Shutdown = %v, want ErrServerClosed", err)
+	}
+}
+
+func TestServerServeUntilDone(t *testing.T) {
+	server := NewServer("shutdown", "1.0")
+	started, release := make(chan struct{}), make(chan struct{})
+	server.RegisterTool(Tool{Name: "slow"}, func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+		close(started)
+		<-release
+		return &CallToolResult{Content: []any{TextContent{Type: "text", Text: "done"}}}, nil
+	})
+	clientConn, serverConn := net.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- server.ServeUntilDone(ctx, &ReadWriteCloserTransport{serverConn}, time.Minute) }()
+	client, err := NewClient(&ReadWriteCloserTransport{clientConn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Initialize(context.Background(), InitializeRequest{ClientInfo: Implementation{Name: "c", Version: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	called := make(chan error, 1)
+	go func() {
+		_, err := client.CallTool(context.Background(), CallToolRequest{Name: "slow"})
+		called <- err
+	}()
+	<-started
+	cancel()
+	waitFor(t, "shutdown to begin", func() bool {
+		server.drain.mu.Lock()
+		defer server.drain.mu.Unlock()
+		return server.drain.closing
+	})
+	close(release)
+	if err := <-called; err != nil {
+		t.Errorf("in-flight call = %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("ServeUntilDone = %v", err)
+	}
+}
+
+type nopRWC struct{}
+
+func (nopRWC) Read([]byte) (int, error)    { select {} }
+func (nopRWC) Write(p []byte) (int, error) { return len(p), nil }
+func (nopRWC) Close() error                { return nil }
+
+func TestServerShutdownDeadline(t *testing.T) {
+	server := NewServer("shutdown", "1.0")
+	started, cause := make(chan struct{}), make(chan error, 1)
+	server.RegisterTool(Tool{Name: "stuck"}, func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+		close(started)
+		<-ctx.Done()
+		cause <- context.Cause(ctx)
+		return nil, ctx.Err()
+	})
+	client, served := newShutdownPair(t, server)
+
+	called := make(chan error, 1)
+	go func() {
+		_, err := client.CallTool(context.Background(), CallToolRequest{Name: "stuck"})
+		called <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	var serr *ShutdownError
+	if !errors.As(err, &serr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want a *ShutdownError for the deadline", err)
+	}
+	if len(serr.Abandoned) != 1 || serr.Abandoned[0].Method != "tools/call" || !serr.Abandoned[0].Returned {
+		t.Errorf("abandoned = %+v, want the returned tools/call", serr.Abandoned)
+	}
+	if got := <-cause; got != ErrShuttingDown {
+		t.Errorf("handler context cause = %v, want ErrShuttingDown", got)
+	}
+	if err := <-called; err == nil {
+		t.Error("cancelled call succeeded")
+	}
+	if err := <-served; !errors.Is(err, ErrServerClosed) {
+		t.Errorf("Serve = %v, want ErrServerClosed", err)
+	}
+}
+
+func TestStreamableHTTPShutdown(t *testing.T) {
+	server := NewServer("shutdown", "1.0")
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPConfig{ShutdownRetry: 2 * time.Second})
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+	url := httpServer.URL + "/mcp"
+
+	sessionID, _ := postStreamable(t, url, "", `{"jsonrpc":"2.0","id":"init","method":"initialize","params":{"protocolVersion":"2025-11-25","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(streamableSessionHeader, sessionID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	stream := bufio.NewReader(resp.Body)
+	if line, _ := stream.ReadString('\n'); line != "event: endpoint\n" {
+		t.Fatalf("stream starts with %q", line)
+	}
+
+	if err := handler.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	var rest strings.Builder
+	for {
+		line, err := stream.ReadString('\n')
+		rest.WriteString(line)
+		if err != nil {
+			break
+		}
+	}
+	if !strings.Contains(rest.String(), "event: shutdown\nretry: 2000\n") {
+		t.Errorf("stream ended with %q, want a shutdown event with retry 2000", rest.String())
+	}
+
+	resp, err = http.Post(url, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "2" {
+		t.Errorf("new session after Shutdown = %d (Retry-After %q), want 503", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+}
@@ -0,0 +1,522 @@
+// Package mcp - Resource Streaming
+// This file implements an opt-in extension for contents too large to send in
+// one message. Resource and tool handlers return a ReaderResourceContents;
+// when both peers negotiate the extension the server sends a stub naming a
+// stream, which the client reads in byte ranges with resources/readRange as
+// its consumer asks for data. Peers that do not negotiate it receive the
+// contents inline, up to a size limit.
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// StreamingExtension is the key under which peers advertise resource
+// streaming in their experimental capabilities.
+const StreamingExtension = "io.github.tmc.mcp/streaming"
+
+// ReaderResourceContents is resource content read from an io.Reader. It may
+// be returned by resource handlers and placed in CallToolResult.Content,
+// where it becomes an embedded resource. The server closes Reader, if it is
+// an io.Closer, once it has been read or abandoned.
+//
+// If the reader is an io.ReaderAt, clients may read its ranges in any order.
+type ReaderResourceContents struct {
+	URI      string
+	MimeType string
+	Size     int64 // Length in bytes if known, or 0
+	Reader   io.Reader
+}
+
+func (ReaderResourceContents) resourceContents() {}
+
+// StreamingConfig configures resource streaming on a server.
+type StreamingConfig struct {
+	ChunkSize     int           `json:"chunkSize" yaml:"chunkSize"`         // Most bytes one resources/readRange returns (default 1 MiB)
+	FallbackLimit int64         `json:"fallbackLimit" yaml:"fallbackLimit"` // Most bytes sent inline to clients without the extension (default 16 MiB)
+	IdleTimeout   time.Duration `json:"idleTimeout" yaml:"idleTimeout"`     // How long an unread stream is kept open (default 5m)
+}
+
+// StreamingCapability is the value of StreamingExtension in a server's
+// experimental capabilities. Clients advertise an empty object.
+type StreamingCapability struct {
+	MaxChunkBytes int `json:"maxChunkBytes,omitempty"`
+}
+
+// StreamInfo is the value of StreamingExtension in the _meta of a
+// BlobResourceContents whose content is sent as a stream. Its Blob is empty.
+type StreamInfo struct {
+	Stream string `json:"stream"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// ReadRangeRequest asks for up to Length bytes of a stream from Offset.
+type ReadRangeRequest struct {
+	Stream string `json:"stream"`
+	Offset int64  `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// ReadRangeResult is the server's response to resources/readRange. A stream
+// is closed on the server once a result with EOF set has been sent.
+type ReadRangeResult struct {
+	Blob string `json:"blob"` // base64 encoded
+	EOF  bool   `json:"eof,omitempty"`
+}
+
+// CloseStreamRequest tells the server a stream will not be read further.
+type CloseStreamRequest struct {
+	Stream string `json:"stream"`
+}
+
+const (
+	defaultStreamChunkSize     = 1 << 20
+	defaultStreamFallbackLimit = 16 << 20
+	defaultStreamIdleTimeout   = 5 * time.Minute
+)
+
+// WithServerResourceStreaming enables the streaming extension. Clients that
+// advertise it receive ReaderResourceContents as streams; others still
+// receive them inline.
+func WithServerResourceStreaming(config StreamingConfig) ServerOption {
+	return func(s *Server) {
+		if config.ChunkSize <= 0 {
+			config.ChunkSize = defaultStreamChunkSize
+		}
+		if config.FallbackLimit <= 0 {
+			config.FallbackLimit = defaultStreamFallbackLimit
+		}
+		if config.IdleTimeout <= 0 {
+			config.IdleTimeout = defaultStreamIdleTimeout
+		}
+		s.streams = &resourceStreams{config: config, open: make(map[string]*openStream)}
+		if s.capabilities.Experimental == nil {
+			s.capabilities.Experimental = make(map[string]any)
+		}
+		s.capabilities.Experimental[StreamingExtension] = StreamingCapability{MaxChunkBytes: config.ChunkSize}
+	}
+}
+
+// resourceStreams holds the streams a server has handed out.
+type resourceStreams struct {
+	config StreamingConfig
+	mu     sync.Mutex
+	open   map[string]*openStream
+}
+
+// openStream is a reader being sent in ranges.
+type openStream struct {
+	mu      sync.Mutex
+	reader  io.Reader
+	session string
+	offset  int64 // position of reader
+	used    time.Time
+	idle    *time.Timer // runs expire
+}
+
+func (st *openStream) close() {
+	if c, ok := st.reader.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// add registers reader for session and returns the stream's ID. The stream
+// is closed once it goes unread for the idle timeout.
+func (t *resourceStreams) add(reader io.Reader, session string) string {
+	var b [16]byte
+	rand.Read(b[:])
+	id := hex.EncodeToString(b[:])
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := &openStream{reader: reader, session: session, used: time.Now()}
+	t.open[id] = st
+	st.idle = time.AfterFunc(t.config.IdleTimeout, func() { t.expire(id, st) })
+	return id
+}
+
+// expire closes stream id if it has gone unread for the idle timeout, and
+// otherwise waits for the rest of the timeout from its last read.
+func (t *resourceStreams) expire(id string, st *openStream) {
+	t.mu.Lock()
+	if t.open[id] != st {
+		t.mu.Unlock()
+		return
+	}
+	// readRange takes st.mu before t.mu, so only try it here. A stream
+	// being read is not idle.
+	if !st.mu.TryLock() {
+		t.mu.Unlock()
+		st.idle.Reset(t.config.IdleTimeout)
+		return
+	}
+	if idle := time.Since(st.used); idle < t.config.IdleTimeout {
+		st.mu.Unlock()
+		t.mu.Unlock()
+		st.idle.Reset(t.config.IdleTimeout - idle)
+		return
+	}
+	delete(t.open, id)
+	t.mu.Unlock()
+	st.close()
+	st.mu.Unlock()
+}
+
+// take removes and returns stream id if it belongs to session.
+func (t *resourceStreams) take(id, session string) *openStream {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.open[id]
+	if st == nil || st.session != session {
+		return nil
+	}
+	delete(t.open, id)
+	st.idle.Stop()
+	return st
+}
+
+// get returns stream id if it belongs to session.
+func (t *resourceStreams) get(id, session string) *openStream {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.open[id]
+	if st == nil || st.session != session {
+		return nil
+	}
+	return st
+}
+
+// closeSession closes the streams of a session whose connection has ended.
+func (t *resourceStreams) closeSession(session string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, st := range t.open {
+		if st.session == session {
+			delete(t.open, id)
+			st.idle.Stop()
+			st.close()
+		}
+	}
+}
+
+// readRange reads a range of stream id.
+func (t *resourceStreams) readRange(ctx context.Context, req ReadRangeRequest) (*ReadRangeResult, error) {
+	session, _ := ctx.Value(sessionKey).(string)
+	st := t.get(req.Stream, session)
+	if st == nil {
+		return nil, NewNotFoundError("stream", req.Stream)
+	}
+	length := req.Length
+	if length <= 0 || length > t.config.ChunkSize {
+		length = t.config.ChunkSize
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	buf := make([]byte, length)
+	var n int
+	var err error
+	if ra, ok := st.reader.(io.ReaderAt); ok {
+		n, err = ra.ReadAt(buf, req.Offset)
+	} else if req.Offset != st.offset {
+		return nil, NewParameterError(string(MethodResourcesReadRange), "offset",
+			fmt.Sprintf("stream is sequential and at offset %d", st.offset), nil)
+	} else {
+		n, err = io.ReadFull(st.reader, buf)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+	}
+	st.offset = req.Offset + int64(n)
+	st.used = time.Now()
+	result := &ReadRangeResult{Blob: base64.StdEncoding.EncodeToString(buf[:n])}
+	switch {
+	case err == io.EOF:
+		result.EOF = true
+		if _, ok := st.reader.(io.ReaderAt); !ok {
+			// A sequential stream cannot be read again.
+			if t.take(req.Stream, session) != nil {
+				st.close()
+			}
+		}
+	case err != nil:
+		if t.take(req.Stream, session) != nil {
+			st.close()
+		}
+		return nil, fmt.Errorf("reading stream: %w", err)
+	}
+	return result, nil
+}
+
+// registerStreamHandlers registers resources/readRange and
+// resources/closeStream when streaming is enabled.
+func (s *Server) registerStreamHandlers() {
+	if s.streams == nil {
+		return
+	}
+	s.handlers[string(MethodResourcesReadRange)] = func(ctx context.Context, req *jsonrpc2.Request) (interface{}, error) {
+		var params ReadRangeRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, NewParameterErrorFromJSON(string(MethodResourcesReadRange), err)
+		}
+		return s.streams.readRange(ctx, params)
+	}
+	s.handlers[string(MethodResourcesCloseStream)] = func(ctx context.Context, req *jsonrpc2.Request) (interface{}, error) {
+		var params CloseStreamRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, NewParameterErrorFromJSON(string(MethodResourcesCloseStream), err)
+		}
+		session, _ := ctx.Value(sessionKey).(string)
+		if st := s.streams.take(params.Stream, session); st != nil {
+			st.mu.Lock()
+			st.close()
+			st.mu.Unlock()
+		}
+		return struct{}{}, nil
+	}
+}
+
+// clientStreams reports whether the client negotiated streaming.
+func (s *Server) clientStreams() bool {
+	if s.streams == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.clientCaps.Experimental[StreamingExtension]
+	return ok
+}
+
+// sendContents replaces any ReaderResourceContents in contents with a stream
+// stub, if the client negotiated streaming, or with the content inline.
+func (s *Server) sendContents(ctx context.Context, contents []ResourceContents) ([]ResourceContents, error) {
+	return sendReaders(ctx, s, contents, func(sent ResourceContents) ResourceContents { return sent })
+}
+
+// sendToolContent does the work of sendContents for the content of a tool
+// result, where ReaderResourceContents become embedded resources.
+func (s *Server) sendToolContent(ctx context.Context, result *CallToolResult) error {
+	if result == nil {
+		return nil
+	}
+	content, err := sendReaders(ctx, s, result.Content, func(sent ResourceContents) any {
+		return EmbeddedResource{Type: "resource", Resource: sent}
+	})
+	if err != nil {
+		return err
+	}
+	result.Content = content
+	return nil
+}
+
+// sendReaders sends each ReaderResourceContents in items with sendReader and
+// replaces it with wrap of the result. The handler's slice is left alone:
+// items is copied before the first replacement. If sending fails, the
+// readers not yet sent are closed.
+func sendReaders[S ~[]E, E any](ctx context.Context, s *Server, items S, wrap func(ResourceContents) E) (S, error) {
+	out, copied := items, false
+	for i, item := range items {
+		rc, ok := readerContents(item)
+		if !ok {
+			continue
+		}
+		if !copied {
+			out, copied = append(S(nil), items...), true
+		}
+		sent, err := s.sendReader(ctx, rc)
+		if err != nil {
+			for _, rest := range items[i+1:] {
+				if rc, ok := readerContents(rest); ok {
+					(&openStream{reader: rc.Reader}).close()
+				}
+			}
+			return nil, err
+		}
+		out[i] = wrap(sent)
+	}
+	return out, nil
+}
+
+func readerContents(v any) (ReaderResourceContents, bool) {
+	switch rc := v.(type) {
+	case ReaderResourceContents:
+		return rc, true
+	case *ReaderResourceContents:
+		if rc != nil {
+			return *rc, true
+		}
+	}
+	return ReaderResourceContents{}, false
+}
+
+// sendReader turns rc into a stream stub or inline contents.
+func (s *Server) sendReader(ctx context.Context, rc ReaderResourceContents) (ResourceContents, error) {
+	if s.clientStreams() {
+		session, _ := ctx.Value(sessionKey).(string)
+		id := s.streams.add(rc.Reader, session)
+		return BlobResourceContents{
+			URI:      rc.URI,
+			MimeType: rc.MimeType,
+			Meta:     map[string]any{StreamingExtension: StreamInfo{Stream: id, Size: rc.Size}},
+		}, nil
+	}
+
+	defer (&openStream{reader: rc.Reader}).close()
+	limit := int64(defaultStreamFallbackLimit)
+	if s.streams != nil {
+		limit = s.streams.config.FallbackLimit
+	}
+	data, err := io.ReadAll(io.LimitReader(rc.Reader, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", rc.URI, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, &ResponseError{
+			Code:    -32603,
+			Message: fmt.Sprintf("resource %s is larger than %d bytes; the client must negotiate %s to read it", rc.URI, limit, StreamingExtension),
+		}
+	}
+	if strings.HasPrefix(rc.MimeType, "text/") && utf8.Valid(data) {
+		return TextResourceContents{URI: rc.URI, MimeType: rc.MimeType, Text: string(data)}, nil
+	}
+	return BlobResourceContents{URI: rc.URI, MimeType: rc.MimeType, Blob: base64.StdEncoding.EncodeToString(data)}, nil
+}
+
+// WithResourceStreaming advertises the streaming extension, so servers that
+// enable it send large contents as streams. Read them with OpenResource or
+// OpenContents.
+func WithResourceStreaming() ClientOption {
+	return func(c *Client) {
+		c.setAdvertise(func(caps *ClientCapabilities) {
+			if caps.Experimental == nil {
+				caps.Experimental = make(map[string]any)
+			}
+			caps.Experimental[StreamingExtension] = StreamingCapability{}
+		})
+	}
+}
+
+// OpenResource reads the first contents of the resource at uri.
+func (c *Client) OpenResource(ctx context.Context, uri string) (io.ReadCloser, error) {
+	result, err := c.ReadResource(ctx, ReadResourceRequest{URI: uri})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Contents) == 0 {
+		return nil, fmt.Errorf("resource %q returned no content", uri)
+	}
+	return c.OpenContents(ctx, result.Contents[0])
+}
+
+// OpenContents returns a reader of resource contents: a TextResourceContents,
+// a BlobResourceContents, or an EmbeddedResource from a tool result.
+// Streamed contents are fetched a range at a time as the reader is read, so
+// a slow consumer holds back the server; ctx bounds those requests. Close a
+// stream that is not read to the end so the server can release it.
+func (c *Client) OpenContents(ctx context.Context, contents any) (io.ReadCloser, error) {
+	switch rc := contents.(type) {
+	case TextResourceContents:
+		return io.NopCloser(strings.NewReader(rc.Text)), nil
+	case BlobResourceContents:
+		if info, ok := streamInfo(rc.Meta); ok {
+			return &streamReader{c: c, ctx: ctx, id: info.Stream, chunk: c.streamChunkSize()}, nil
+		}
+		return io.NopCloser(base64.NewDecoder(base64.StdEncoding, strings.NewReader(rc.Blob))), nil
+	case EmbeddedResource:
+		return c.OpenContents(ctx, rc.Resource)
+	}
+	return nil, fmt.Errorf("cannot read contents of type %T", contents)
+}
+
+// streamInfo extracts the stream of a stub from its _meta.
+func streamInfo(meta map[string]any) (StreamInfo, bool) {
+	v, ok := meta[StreamingExtension]
+	if !ok {
+		return StreamInfo{}, false
+	}
+	var info StreamInfo
+	raw, err := json.Marshal(v)
+	if err != nil || json.Unmarshal(raw, &info) != nil || info.Stream == "" {
+		return StreamInfo{}, false
+	}
+	return info, true
+}
+
+// streamChunkSize returns the range size to request, as advertised by the
+// server.
+func (c *Client) streamChunkSize() int {
+	c.initMu.RLock()
+	v := c.serverCapabilities.Experimental[StreamingExtension]
+	c.initMu.RUnlock()
+	var capability StreamingCapability
+	if raw, err := json.Marshal(v); err == nil {
+		json.Unmarshal(raw, &capability)
+	}
+	if capability.MaxChunkBytes <= 0 {
+		return defaultStreamChunkSize
+	}
+	return capability.MaxChunkBytes
+}
+
+// streamReader reads a server stream one range per request.
+type streamReader struct {
+	c      *Client
+	ctx    context.Context
+	id     string
+	chunk  int
+	offset int64
+	buf    bytes.Reader
+	eof    bool
+	err    error
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.eof {
+			return 0, io.EOF
+		}
+		var result ReadRangeResult
+		err := r.c.call(r.ctx, string(MethodResourcesReadRange), ReadRangeRequest{Stream: r.id, Offset: r.offset, Length: r.chunk}, &result)
+		if err != nil {
+			r.err = err
+			return 0, err
+		}
+		data, err := base64.StdEncoding.DecodeString(result.Blob)
+		if err != nil {
+			r.err = fmt.Errorf("decoding stream range: %w", err)
+			return 0, r.err
+		}
+		r.offset += int64(len(data))
+		r.eof = result.EOF
+		r.buf.Reset(data)
+	}
+	return r.buf.Read(p)
+}
+
+func (r *streamReader) Close() error {
+	if r.eof || r.err != nil {
+		return nil
+	}
+	r.err = errors.New("mcp: read on closed stream")
+	var result any
+	return r.c.call(r.ctx, string(MethodResourcesCloseStream), CloseStreamRequest{Stream: r.id}, &result)
+}
@@ -0,0 +1,219 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingReader serves data and records how much was read and whether it
+// was closed.
+type countingReader struct {
+	r      io.Reader
+	read   atomic.Int64
+	closed atomic.Bool
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// newStreamingServer returns a server whose file:///big resource and "big"
+// tool serve data through readers, which are sent on readers.
+func newStreamingServer(t *testing.T, data []byte, opts ...ServerOption) (*Server, <-chan *countingReader) {
+	t.Helper()
+	readers := make(chan *countingReader, 4)
+	open := func() *countingReader {
+		r := &countingReader{r: bytes.NewReader(data)}
+		readers <- r
+		return r
+	}
+	server := NewServer("streaming", "1.0", opts...)
+	err := server.RegisterResource(Resource{URI: "file:///big"}, func(ctx context.Context, req ReadResourceRequest) ([]ResourceContents, error) {
+		return []ResourceContents{ReaderResourceContents{URI: req.URI, MimeType: "application/octet-stream", Size: int64(len(data)), Reader: open()}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RegisterTool(Tool{Name: "big"}, func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+		return &CallToolResult{Content: []any{ReaderResourceContents{URI: "file:///out", Reader: open()}}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, readers
+}
+
+func TestResourceStreaming(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	server, readers := newStreamingServer(t, data, WithServerResourceStreaming(StreamingConfig{ChunkSize: 1000}))
+	client := newCacheTestPair(t, server, WithResourceStreaming())
+	ctx := context.Background()
+
+	result, err := client.ReadResource(ctx, ReadResourceRequest{URI: "file:///big"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub, ok := result.Contents[0].(BlobResourceContents)
+	if info, isStream := streamInfo(stub.Meta); !ok || stub.Blob != "" || !isStream || info.Size != int64(len(data)) {
+		t.Fatalf("contents = %+v, want a stream stub", result.Contents[0])
+	}
+	unread := <-readers
+	r, err := client.OpenContents(ctx, stub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !unread.closed.Load() {
+		t.Error("server did not close the reader of a stream closed early")
+	}
+
+	r, err = client.OpenResource(ctx, "file:///big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := <-readers
+	head := make([]byte, 1500)
+	if _, err := io.ReadFull(r, head); err != nil {
+		t.Fatal(err)
+	}
+	if n := src.read.Load(); n > 2000 {
+		t.Errorf("server read %d bytes ahead of a client that took 1500", n)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := append(head, rest...); !bytes.Equal(got, data) {
+		t.Errorf("streamed %d bytes, want the %d written", len(got), len(data))
+	}
+	if !src.closed.Load() {
+		t.Error("server did not close a reader read to the end")
+	}
+
+	called, err := client.CallTool(ctx, CallToolRequest{Name: "big"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = client.OpenContents(ctx, called.Content[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("tool output streamed %d bytes, %v", len(got), err)
+	}
+}
+
+func TestResourceStreamingIdleTimeout(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	server, readers := newStreamingServer(t, data, WithServerResourceStreaming(StreamingConfig{ChunkSize: 1000, IdleTimeout: 50 * time.Millisecond}))
+	client := newCacheTestPair(t, server, WithResourceStreaming())
+	ctx := context.Background()
+
+	// A stream that is read keeps its reader; one left unread is closed
+	// without another stream being opened.
+	r, err := client.OpenResource(ctx, "file:///big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := <-readers
+	buf := make([]byte, 1000)
+	for range 4 {
+		time.Sleep(20 * time.Millisecond)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if src.closed.Load() {
+		t.Fatal("server closed a stream that was being read")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !src.closed.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !src.closed.Load() {
+		t.Error("server did not close an idle stream")
+	}
+}
+
+func TestResourceStreamingFallback(t *testing.T) {
+	data := bytes.Repeat([]byte{0xff}, 3000)
+	server, _ := newStreamingServer(t, data, WithServerResourceStreaming(StreamingConfig{FallbackLimit: 3000}))
+	client := newCacheTestPair(t, server)
+	ctx := context.Background()
+
+	result, err := client.ReadResource(ctx, ReadResourceRequest{URI: "file:///big"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, ok := result.Contents[0].(BlobResourceContents)
+	if got, _ := base64.StdEncoding.DecodeString(blob.Blob); !ok || !bytes.Equal(got, data) {
+		t.Fatalf("contents = %T, want the data inline", result.Contents[0])
+	}
+	r, err := client.OpenContents(ctx, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("OpenContents read %d bytes, %v", len(got), err)
+	}
+
+	large, _ := newStreamingServer(t, append(data, 0), WithServerResourceStreaming(StreamingConfig{FallbackLimit: 3000}))
+	client = newCacheTestPair(t, large)
+	if _, err := client.ReadResource(ctx, ReadResourceRequest{URI: "file:///big"}); err == nil {
+		t.Error("reading a resource over the fallback limit succeeded")
+	}
+}
+
+func TestResourceStreamingNotCached(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	cache := NewResponseCacheMiddleware(ResponseCacheConfig{})
+	defer cache.Close()
+	useCache := func(s *Server) { s.Use(cache) }
+	server, _ := newStreamingServer(t, data, WithServerResourceStreaming(StreamingConfig{}), useCache)
+	client := newCacheTestPair(t, server, WithResourceStreaming())
+	ctx := context.Background()
+
+	for i := range 2 {
+		r, err := client.OpenResource(ctx, "file:///big")
+		if err != nil {
+			t.Fatalf("read %d: %v", i, err)
+		}
+		if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("read %d: streamed %d bytes, %v", i, len(got), err)
+		}
+	}
+}
+
+func TestSendToolContentError(t *testing.T) {
+	server := NewServer("streaming", "1.0", WithServerResourceStreaming(StreamingConfig{FallbackLimit: 10}))
+	large := &countingReader{r: bytes.NewReader(make([]byte, 20))}
+	rest := &countingReader{r: bytes.NewReader(nil)}
+	content := ContentList{
+		ReaderResourceContents{URI: "file:///large", Reader: large},
+		ReaderResourceContents{URI: "file:///rest", Reader: rest},
+	}
+	result := &CallToolResult{Content: content}
+	if err := server.sendToolContent(context.Background(), result); err == nil {
+		t.Fatal("sendToolContent succeeded with contents over the fallback limit")
+	}
+	if !rest.closed.Load() {
+		t.Error("reader after the failing one was not closed")
+	}
+	if _, ok := content[0].(ReaderResourceContents); !ok {
+		t.Errorf("handler content changed to %T", content[0])
+	}
+}
@@ -93,16 +93,12 @@ func TestRegisterTypedToolStructuredOutput(t *testing.T) {
 	if len(result.Content) != 1 {
 		t.Fatalf("len(Content) = %d, want 1", len(result.Content))
 	}
-	content, ok := result.Content[0].(map[string]any)
+	content, ok := result.Content[0].(TextContent)
 	if !ok {
-		t.Fatalf("Content[0] has type %T, want map[string]any", result.Content[0])
-	}
-	text, ok := content["text"].(string)
-	if !ok {
-		t.Fatalf("Content[0].text has type %T, want string", content["text"])
+		t.Fatalf("Content[0] has type %T, want TextContent", result.Content[0])
 	}
 	var compat structuredAddResult
-	if err := json.Unmarshal([]byte(text), &compat); err != nil {
+	if err := json.Unmarshal([]byte(content.Text), &compat); err != nil {
 		t.Fatalf("unmarshal compatibility content: %v", err)
 	}
 	if compat != structured {
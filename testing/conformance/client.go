@@ -0,0 +1,310 @@
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmc/mcp"
+	"github.com/tmc/mcp/testing/mcptest"
+)
+
+// ClientFactory runs the client under test over transport. It should
+// initialize the session, send notifications/initialized, and then keep the
+// session open, answering server requests, until ctx is done.
+type ClientFactory func(ctx context.Context, transport mcp.Transport) error
+
+// clientSession is the scripted server side of the session with the client
+// under test. All client checks share one session and run in order.
+type clientSession struct {
+	cfg  *config
+	peer *peer
+	init mcp.InitializeRequest
+}
+
+// RunClientSuite runs the client checks against the client that run
+// starts, each as a subtest of t, and returns the report.
+func RunClientSuite(t *testing.T, run ClientFactory, opts ...Option) *Report {
+	t.Helper()
+	cfg := newConfig(opts)
+	report := &Report{Suite: "client"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	clientTransport, serverTransport := mcptest.NewInMemoryTransports()
+	conn, err := serverTransport.Dial(ctx)
+	if err != nil {
+		cancel()
+		t.Fatalf("conformance: %v", err)
+	}
+	session := &clientSession{cfg: cfg, peer: newPeer(conn, cfg.timeout, nil)}
+	runErr := make(chan error, 1)
+	go func() { runErr <- run(ctx, clientTransport) }()
+
+	var exited error
+	setup := func(*testing.T) (*clientSession, error) {
+		// The checks replay one conversation, so a client that exits early
+		// fails every check after it.
+		if exited == nil {
+			select {
+			case err := <-runErr:
+				exited = fmt.Errorf("client exited: %v", err)
+			default:
+			}
+		}
+		return session, exited
+	}
+	for _, c := range clientChecks(report) {
+		runCheck(t, cfg, report, c, setup)
+	}
+
+	cancel()
+	session.peer.close()
+	if exited == nil {
+		select {
+		case err := <-runErr:
+			if err != nil && !errors.Is(err, context.Canceled) {
+				t.Logf("conformance: client returned %v", err)
+			}
+		case <-time.After(cfg.timeout):
+			t.Errorf("conformance: client did not return within %v of cancellation", cfg.timeout)
+		}
+	}
+	finish(t, cfg, report)
+	return report
+}
+
+func clientChecks(report *Report) []check[*clientSession] {
+	return []check[*clientSession]{
+		{
+			id:          "lifecycle/initialize-first",
+			description: "client sends initialize as its first request",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *clientSession) error {
+				msg, err := s.peer.next("initialize request", func(m *message) bool { return m.isRequest() })
+				if err != nil {
+					return err
+				}
+				if msg.Method != "initialize" {
+					return fmt.Errorf("first request is %s", msg.Method)
+				}
+				if err := json.Unmarshal(msg.Params, &s.init); err != nil {
+					return fmt.Errorf("initialize params %s: %v", msg.Params, err)
+				}
+				report.Implementation = s.init.ClientInfo
+				version := s.init.ProtocolVersion
+				if !revisionPattern.MatchString(version) || version > mcp.LATEST_PROTOCOL_VERSION {
+					version = mcp.LATEST_PROTOCOL_VERSION
+				}
+				report.ProtocolVersion = version
+				var caps mcp.ServerCapabilities
+				caps.Logging = &struct{}{}
+				result := mcp.InitializeResult{
+					ProtocolVersion: version,
+					Capabilities:    caps,
+					ServerInfo:      mcp.Implementation{Name: "mcp-conformance", Version: "0.1.0"},
+				}
+				if err := s.peer.write(map[string]any{"jsonrpc": "2.0", "id": msg.ID, "result": result}); err != nil {
+					return err
+				}
+				if s.init.ClientInfo.Name == "" {
+					return errors.New("clientInfo has no name")
+				}
+				if s.init.ProtocolVersion == "" {
+					return errors.New("initialize has no protocolVersion")
+				}
+				return nil
+			},
+		},
+		{
+			id:          "lifecycle/initialized-notification",
+			description: "client sends notifications/initialized after initialize succeeds",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *clientSession) error {
+				_, err := s.peer.next("notifications/initialized", func(m *message) bool {
+					return m.Method == "notifications/initialized"
+				})
+				return err
+			},
+		},
+		{
+			id:          "ping/response",
+			description: "client answers ping with an empty result",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *clientSession) error {
+				msg, err := s.peer.call("ping", nil)
+				if err != nil {
+					return err
+				}
+				var result map[string]any
+				if err := msg.expectResult(&result); err != nil {
+					return err
+				}
+				if len(result) != 0 {
+					return fmt.Errorf("result is %s, want {}", msg.Result)
+				}
+				return nil
+			},
+		},
+		{
+			id:          "errors/method-not-found",
+			description: "client answers an unknown method with -32601",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *clientSession) error {
+				msg, err := s.peer.call("conformance/no-such-method", map[string]any{})
+				if err != nil {
+					return err
+				}
+				return msg.expectError(-32601)
+			},
+		},
+		{
+			id:          "jsonrpc/notifications-unanswered",
+			description: "client does not respond to notifications",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *clientSession) error {
+				if err := s.peer.notify("notifications/conformance/unknown", map[string]any{}); err != nil {
+					return err
+				}
+				if err := s.peer.alive(); err != nil {
+					return err
+				}
+				for _, msg := range s.peer.all() {
+					if msg.Method == "" && msg.idKey() == "" {
+						return fmt.Errorf("client sent a response without an id: result %s, error %+v", msg.Result, msg.Error)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			id:          "roots/list",
+			description: "client that declares roots answers roots/list",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *clientSession) error {
+				if s.init.Capabilities.Roots == nil {
+					return skipf("client does not declare roots")
+				}
+				msg, err := s.peer.call("roots/list", map[string]any{})
+				if err != nil {
+					return err
+				}
+				var result struct {
+					Roots []struct {
+						URI string `json:"uri"`
+					} `json:"roots"`
+				}
+				if err := msg.expectResult(&result); err != nil {
+					return err
+				}
+				if result.Roots == nil {
+					return fmt.Errorf("result %s has no roots array", msg.Result)
+				}
+				for _, r := range result.Roots {
+					if !strings.HasPrefix(r.URI, "file://") {
+						return fmt.Errorf("root %q is not a file:// URI", r.URI)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			id:          "sampling/create-message",
+			description: "client that declares sampling answers sampling/createMessage",
+			revision:    Revision20241105,
+			level:       Should,
+			run: func(s *clientSession) error {
+				if s.init.Capabilities.Sampling == nil {
+					return skipf("client does not declare sampling")
+				}
+				msg, err := s.peer.call("sampling/createMessage", map[string]any{
+					"messages":  []any{map[string]any{"role": "user", "content": map[string]any{"type": "text", "text": "Say hello."}}},
+					"maxTokens": 16,
+				})
+				if err != nil {
+					return err
+				}
+				var result struct {
+					Role    string          `json:"role"`
+					Content json.RawMessage `json:"content"`
+					Model   string          `json:"model"`
+				}
+				if err := msg.expectResult(&result); err != nil {
+					return err
+				}
+				if result.Role == "" || len(result.Content) == 0 || result.Model == "" {
+					return fmt.Errorf("result %s lacks role, content or model", msg.Result)
+				}
+				return nil
+			},
+		},
+		{
+			id:          "jsonrpc/version",
+			description: `every client message has jsonrpc "2.0"`,
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *clientSession) error {
+				for _, msg := range s.peer.all() {
+					if msg.JSONRPC != "2.0" {
+						return fmt.Errorf(`message %s has jsonrpc %q, want "2.0"`, describe(msg), msg.JSONRPC)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			id:          "jsonrpc/request-ids",
+			description: "client request IDs are non-null and not reused in the session",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *clientSession) error {
+				var seen []string
+				for _, msg := range s.peer.all() {
+					if msg.Method == "" || len(msg.ID) == 0 {
+						continue
+					}
+					if msg.idKey() == "" {
+						return fmt.Errorf("request %s has a null id", msg.Method)
+					}
+					if slices.Contains(seen, msg.idKey()) {
+						return fmt.Errorf("request %s reuses id %s", msg.Method, msg.idKey())
+					}
+					seen = append(seen, msg.idKey())
+				}
+				return nil
+			},
+		},
+		{
+			id:          "jsonrpc/no-batches",
+			description: "client does not send JSON-RPC batches",
+			revision:    Revision20250618,
+			level:       Must,
+			run: func(s *clientSession) error {
+				for _, msg := range s.peer.all() {
+					if msg.batch {
+						return fmt.Errorf("message %s arrived in a batch", describe(msg))
+					}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// describe names msg for error messages.
+func describe(msg *message) string {
+	if msg.Method != "" {
+		return msg.Method
+	}
+	return "response " + msg.idKey()
+}
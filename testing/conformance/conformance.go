@@ -0,0 +1,220 @@
+// Package conformance checks MCP servers and clients against the protocol
+// specification.
+//
+// RunServerSuite speaks raw JSON-RPC to a server over any mcp.Transport and
+// RunClientSuite plays a scripted server to a client under test. Each check
+// is tagged with the specification revision that introduced the rule and the
+// requirement level (MUST or SHOULD) it enforces:
+//
+//	func TestConformance(t *testing.T) {
+//	    conformance.RunServerSuite(t, func(t testing.TB) mcp.Transport {
+//	        return mcp.NewStreamableClientTransport("http://localhost:8080/mcp", nil)
+//	    }, conformance.WithReportFile("conformance.json"))
+//	}
+//
+// MUST failures fail the test; SHOULD failures are logged unless WithStrict
+// is given. Checks whose revision is newer than the negotiated protocol
+// version, or that need a capability the peer did not declare, are skipped.
+package conformance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+// Specification revisions that checks are tagged with.
+const (
+	Revision20241105 = "2024-11-05"
+	Revision20250326 = "2025-03-26"
+	Revision20250618 = "2025-06-18"
+	Revision20251125 = "2025-11-25"
+)
+
+// Level is the requirement level of a check, as in RFC 2119.
+type Level string
+
+const (
+	Must   Level = "MUST"
+	Should Level = "SHOULD"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	Pass Status = "pass"
+	Fail Status = "fail"
+	Skip Status = "skip"
+)
+
+// Result is the outcome of one check.
+type Result struct {
+	ID          string        `json:"id"`
+	Description string        `json:"description"`
+	Revision    string        `json:"revision"`
+	Level       Level         `json:"level"`
+	Status      Status        `json:"status"`
+	Message     string        `json:"message,omitempty"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// Report is the machine-readable outcome of a suite.
+type Report struct {
+	Suite           string             `json:"suite"` // "server" or "client"
+	ProtocolVersion string             `json:"protocolVersion,omitempty"`
+	Implementation  mcp.Implementation `json:"implementation"`
+	Results         []Result           `json:"results"`
+}
+
+// Failures returns the failed checks at level, or at any level if level is
+// empty.
+func (r *Report) Failures(level Level) []Result {
+	var out []Result
+	for _, res := range r.Results {
+		if res.Status == Fail && (level == "" || res.Level == level) {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Option configures a suite.
+type Option func(*config)
+
+type config struct {
+	protocolVersion string
+	strict          bool
+	timeout         time.Duration
+	reportPath      string
+	toolCall        *mcp.CallToolRequest
+}
+
+// WithProtocolVersion sets the version the server suite requests in
+// initialize. The default is mcp.LATEST_PROTOCOL_VERSION.
+func WithProtocolVersion(version string) Option {
+	return func(c *config) { c.protocolVersion = version }
+}
+
+// WithStrict makes SHOULD failures fail the test too.
+func WithStrict() Option {
+	return func(c *config) { c.strict = true }
+}
+
+// WithTimeout bounds how long a check waits for each message. The default
+// is five seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) { c.timeout = d }
+}
+
+// WithReportFile writes the JSON report to path when the suite finishes.
+func WithReportFile(path string) Option {
+	return func(c *config) { c.reportPath = path }
+}
+
+// WithToolCall names a tool call the server suite uses for the progress and
+// cancellation checks. It should report progress when given a progress
+// token and take at least 100ms. Without it those checks are skipped.
+func WithToolCall(name string, arguments any) Option {
+	return func(c *config) {
+		args, _ := json.Marshal(arguments)
+		c.toolCall = &mcp.CallToolRequest{Name: name, Arguments: args}
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{protocolVersion: mcp.LATEST_PROTOCOL_VERSION, timeout: 5 * time.Second}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// check is one conformance rule.
+type check[S any] struct {
+	id          string
+	description string
+	revision    string
+	level       Level
+	run         func(S) error
+}
+
+// errSkip reports that a check does not apply.
+type errSkip struct{ reason string }
+
+func (e errSkip) Error() string { return e.reason }
+
+func skipf(format string, args ...any) error {
+	return errSkip{fmt.Sprintf(format, args...)}
+}
+
+// runCheck runs c as a subtest of t and records its result in report.
+// setup returns the state the check runs against, or an error that fails
+// the check.
+func runCheck[S any](t *testing.T, cfg *config, report *Report, c check[S], setup func(t *testing.T) (S, error)) {
+	t.Run(c.id, func(t *testing.T) {
+		res := Result{ID: c.id, Description: c.description, Revision: c.revision, Level: c.level}
+		start := time.Now()
+		var err error
+		if report.ProtocolVersion != "" && c.revision > report.ProtocolVersion {
+			err = skipf("applies from revision %s; negotiated %s", c.revision, report.ProtocolVersion)
+		} else {
+			var s S
+			if s, err = setup(t); err == nil {
+				err = c.run(s)
+			}
+		}
+		res.Duration = time.Since(start)
+		var skip errSkip
+		switch {
+		case err == nil:
+			res.Status = Pass
+		case errors.As(err, &skip):
+			res.Status, res.Message = Skip, skip.reason
+		default:
+			res.Status, res.Message = Fail, err.Error()
+		}
+		report.Results = append(report.Results, res)
+
+		switch {
+		case res.Status == Skip:
+			t.Skip(res.Message)
+		case res.Status == Fail && (c.level == Must || cfg.strict):
+			t.Errorf("%s %s (%s): %s", c.level, c.description, c.revision, res.Message)
+		case res.Status == Fail:
+			t.Logf("%s not met: %s (%s): %s", c.level, c.description, c.revision, res.Message)
+		}
+	})
+}
+
+// finish writes the report file, if one was requested.
+func finish(t *testing.T, cfg *config, report *Report) {
+	if cfg.reportPath == "" {
+		return
+	}
+	f, err := os.Create(cfg.reportPath)
+	if err != nil {
+		t.Errorf("conformance: %v", err)
+		return
+	}
+	defer f.Close()
+	if err := report.WriteJSON(f); err != nil {
+		t.Errorf("conformance: %v", err)
+	}
+}
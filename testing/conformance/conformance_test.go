@@ -0,0 +1,167 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tmc/mcp"
+	"github.com/tmc/mcp/testing/mcptest"
+)
+
+func newServer(t *testing.T) *mcp.Server {
+	t.Helper()
+	server := mcp.NewServer("conformance", "1.0.0")
+	err := server.RegisterTool(mcp.Tool{Name: "slow", InputSchema: json.RawMessage(`{"type":"object","properties":{"steps":{"type":"integer"}}}`)},
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			for i := 1; i <= 3; i++ {
+				if token := req.ProgressToken(); token != nil {
+					server.NotifyProgress(ctx, token, float64(i), nil)
+				}
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+			return &mcp.CallToolResult{Content: []any{map[string]any{"type": "text", "text": "done"}}}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RegisterResource(mcp.Resource{URI: "docs://readme", Name: "readme"},
+		func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{mcp.TextResourceContents{URI: req.URI, Text: "hello"}}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RegisterPrompt(mcp.Prompt{Name: "greet"},
+		func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return &mcp.GetPromptResult{}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func TestServerSuite(t *testing.T) {
+	server := newServer(t)
+	path := filepath.Join(t.TempDir(), "report.json")
+	report := RunServerSuite(t, func(t testing.TB) mcp.Transport {
+		clientTransport, serverTransport := mcptest.NewInMemoryTransports()
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		go server.Serve(ctx, serverTransport)
+		return clientTransport
+	}, WithToolCall("slow", map[string]any{"steps": 3}), WithReportFile(path), WithTimeout(2*time.Second))
+
+	if report.Implementation.Name != "conformance" || report.ProtocolVersion != mcp.LATEST_PROTOCOL_VERSION {
+		t.Errorf("report identifies %+v at %s", report.Implementation, report.ProtocolVersion)
+	}
+	for _, res := range report.Results {
+		if res.Status == Skip {
+			t.Errorf("%s skipped: %s", res.ID, res.Message)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written Report
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("report file: %v", err)
+	}
+	if len(written.Results) != len(report.Results) || written.Suite != "server" {
+		t.Errorf("report file has %d results for suite %q, want %d for server", len(written.Results), written.Suite, len(report.Results))
+	}
+}
+
+func TestClientSuite(t *testing.T) {
+	report := RunClientSuite(t, func(ctx context.Context, transport mcp.Transport) error {
+		client, err := mcp.NewClient(transport)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		client.OnListRoots(func(context.Context) (*mcp.ListRootsResult, error) {
+			return &mcp.ListRootsResult{Roots: []mcp.Root{{URI: "file:///src", Name: "src"}}}, nil
+		})
+		_, err = client.Initialize(ctx, mcp.InitializeRequest{ClientInfo: mcp.Implementation{Name: "client", Version: "1.0.0"}})
+		if err != nil {
+			return err
+		}
+		if err := client.Notify(ctx, "notifications/initialized", nil); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if report.Implementation.Name != "client" {
+		t.Errorf("report identifies %+v", report.Implementation)
+	}
+	for _, res := range report.Results {
+		if res.Status != Pass && res.ID != "sampling/create-message" {
+			t.Errorf("%s: %s %s", res.ID, res.Status, res.Message)
+		}
+	}
+}
+
+// TestClientChecksFail runs the client checks against a peer that breaks
+// the rules.
+func TestClientChecksFail(t *testing.T) {
+	clientTransport, serverTransport := mcptest.NewInMemoryTransports()
+	ctx := context.Background()
+	client, err := clientTransport.Dial(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := serverTransport.Dial(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &clientSession{cfg: newConfig([]Option{WithTimeout(200 * time.Millisecond)}), peer: newPeer(server, 200*time.Millisecond, nil)}
+	defer s.peer.close()
+	client.Write([]byte(`[{"jsonrpc":"1.0","id":1,"method":"tools/list"},{"jsonrpc":"2.0","id":1,"method":"tools/list"}]` + "\n"))
+
+	report := &Report{}
+	want := map[string]bool{
+		"lifecycle/initialize-first":         true,
+		"lifecycle/initialized-notification": true,
+		"ping/response":                      true,
+		"jsonrpc/version":                    true,
+		"jsonrpc/request-ids":                true,
+		"jsonrpc/no-batches":                 true,
+	}
+	for _, c := range clientChecks(report) {
+		if !want[c.id] {
+			continue
+		}
+		if err := c.run(s); err == nil {
+			t.Errorf("%s passed", c.id)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	report := &Report{Suite: "server", Results: []Result{
+		{ID: "a", Level: Must, Status: Fail},
+		{ID: "b", Level: Should, Status: Fail},
+		{ID: "c", Level: Must, Status: Pass},
+	}}
+	if got := report.Failures(Must); len(got) != 1 || got[0].ID != "a" {
+		t.Errorf("Failures(Must) = %v", got)
+	}
+	if got := report.Failures(""); len(got) != 2 {
+		t.Errorf("Failures(\"\") = %v", got)
+	}
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil || !bytes.Contains(buf.Bytes(), []byte(`"status": "fail"`)) {
+		t.Errorf("WriteJSON = %s, %v", buf.Bytes(), err)
+	}
+}
@@ -0,0 +1,266 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// message is one JSON-RPC message as it appeared on the wire.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+
+	batch bool // arrived inside a JSON array
+}
+
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// idKey returns the message ID as a string, or "" if it has none.
+func (m *message) idKey() string {
+	if len(m.ID) == 0 || string(m.ID) == "null" {
+		return ""
+	}
+	return string(m.ID)
+}
+
+func (m *message) isRequest() bool      { return m.Method != "" && m.idKey() != "" }
+func (m *message) isNotification() bool { return m.Method != "" && m.idKey() == "" }
+
+// expectError checks that m is an error response with code.
+func (m *message) expectError(code int) error {
+	if m.Error == nil {
+		return fmt.Errorf("got result %s, want error %d", m.Result, code)
+	}
+	if m.Error.Code != code {
+		return fmt.Errorf("got error %d (%s), want %d", m.Error.Code, m.Error.Message, code)
+	}
+	return nil
+}
+
+// expectResult checks that m is a successful response and decodes its
+// result into v, if v is not nil.
+func (m *message) expectResult(v any) error {
+	if m.Error != nil {
+		return fmt.Errorf("got error %d: %s", m.Error.Code, m.Error.Message)
+	}
+	if len(m.Result) == 0 {
+		return errors.New("response has neither result nor error")
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(m.Result, v); err != nil {
+		return fmt.Errorf("decode result %s: %v", m.Result, err)
+	}
+	return nil
+}
+
+// peer is a raw JSON-RPC endpoint that drives the implementation under
+// test. Responses to its own calls are routed to the caller; everything else
+// the other side sends is queued for next.
+type peer struct {
+	conn    io.ReadWriteCloser
+	timeout time.Duration
+	// answer, if set, replies to requests from the other side instead of
+	// queuing them.
+	answer func(*message) (result any, err *rpcError)
+
+	wmu sync.Mutex
+
+	mu       sync.Mutex
+	nextID   int
+	pending  map[string]chan *message
+	received []*message
+	incoming chan *message
+	done     chan struct{}
+	readErr  error
+}
+
+func newPeer(conn io.ReadWriteCloser, timeout time.Duration, answer func(*message) (any, *rpcError)) *peer {
+	p := &peer{
+		conn:     conn,
+		timeout:  timeout,
+		answer:   answer,
+		nextID:   1,
+		pending:  make(map[string]chan *message),
+		incoming: make(chan *message, 1024),
+		done:     make(chan struct{}),
+	}
+	go p.read()
+	return p
+}
+
+func (p *peer) read() {
+	defer close(p.done)
+	dec := json.NewDecoder(p.conn)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			p.mu.Lock()
+			p.readErr = err
+			p.mu.Unlock()
+			return
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '[' {
+			var items []json.RawMessage
+			if json.Unmarshal(raw, &items) == nil {
+				for _, item := range items {
+					p.dispatch(item, true)
+				}
+			}
+			continue
+		}
+		p.dispatch(raw, false)
+	}
+}
+
+func (p *peer) dispatch(raw json.RawMessage, batch bool) {
+	msg := &message{batch: batch}
+	if err := json.Unmarshal(raw, msg); err != nil {
+		return
+	}
+	p.mu.Lock()
+	p.received = append(p.received, msg)
+	ch := p.pending[msg.idKey()]
+	if msg.Method == "" && ch != nil {
+		delete(p.pending, msg.idKey())
+	}
+	p.mu.Unlock()
+
+	switch {
+	case msg.Method == "" && ch != nil:
+		ch <- msg
+	case msg.isRequest() && p.answer != nil:
+		result, rerr := p.answer(msg)
+		if rerr != nil {
+			_ = p.write(map[string]any{"jsonrpc": "2.0", "id": msg.ID, "error": rerr})
+		} else {
+			_ = p.write(map[string]any{"jsonrpc": "2.0", "id": msg.ID, "result": result})
+		}
+	default:
+		select {
+		case p.incoming <- msg:
+		default:
+		}
+	}
+}
+
+// all returns every message read so far.
+func (p *peer) all() []*message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*message(nil), p.received...)
+}
+
+func (p *peer) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return p.send(data)
+}
+
+// send writes one raw message.
+func (p *peer) send(raw []byte) error {
+	p.wmu.Lock()
+	defer p.wmu.Unlock()
+	_, err := p.conn.Write(append(append([]byte(nil), raw...), '\n'))
+	return err
+}
+
+// call sends a request and waits for its response.
+func (p *peer) call(method string, params any) (*message, error) {
+	p.mu.Lock()
+	id := p.nextID
+	p.nextID++
+	ch := make(chan *message, 1)
+	p.pending[strconv.Itoa(id)] = ch
+	p.mu.Unlock()
+
+	req := map[string]any{"jsonrpc": "2.0", "id": id, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	if err := p.write(req); err != nil {
+		return nil, fmt.Errorf("send %s: %v", method, err)
+	}
+	return p.wait(method, ch)
+}
+
+func (p *peer) wait(what string, ch chan *message) (*message, error) {
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-p.done:
+		// A response may have raced the close.
+		select {
+		case msg := <-ch:
+			return msg, nil
+		default:
+		}
+		return nil, fmt.Errorf("connection closed waiting for %s: %v", what, p.closedErr())
+	case <-time.After(p.timeout):
+		return nil, fmt.Errorf("no response to %s within %v", what, p.timeout)
+	}
+}
+
+func (p *peer) closedErr() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.readErr
+}
+
+// notify sends a notification.
+func (p *peer) notify(method string, params any) error {
+	n := map[string]any{"jsonrpc": "2.0", "method": method}
+	if params != nil {
+		n["params"] = params
+	}
+	return p.write(n)
+}
+
+// next returns the next queued message for which match returns true,
+// discarding the others.
+func (p *peer) next(what string, match func(*message) bool) (*message, error) {
+	deadline := time.After(p.timeout)
+	for {
+		select {
+		case msg := <-p.incoming:
+			if match(msg) {
+				return msg, nil
+			}
+		case <-p.done:
+			return nil, fmt.Errorf("connection closed waiting for %s: %v", what, p.closedErr())
+		case <-deadline:
+			return nil, fmt.Errorf("no %s within %v", what, p.timeout)
+		}
+	}
+}
+
+// alive checks that the connection still answers ping.
+func (p *peer) alive() error {
+	msg, err := p.call("ping", nil)
+	if err != nil {
+		return err
+	}
+	return msg.expectResult(nil)
+}
+
+func (p *peer) close() error {
+	return p.conn.Close()
+}
@@ -0,0 +1,621 @@
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+// TransportFactory returns a transport to a fresh session with the server
+// under test. It is called once per check.
+type TransportFactory func(t testing.TB) mcp.Transport
+
+// serverSession is one connection to the server under test.
+type serverSession struct {
+	cfg  *config
+	peer *peer
+	init *mcp.InitializeResult
+
+	// redial opens another session to the same server.
+	redial func() (*serverSession, error)
+}
+
+// handshake initializes the session with the configured version.
+func (s *serverSession) handshake() error {
+	if s.init != nil {
+		return nil
+	}
+	init, err := s.initialize(s.cfg.protocolVersion)
+	if err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if err := s.peer.notify("notifications/initialized", nil); err != nil {
+		return err
+	}
+	s.init = init
+	return nil
+}
+
+func (s *serverSession) initialize(version string) (*mcp.InitializeResult, error) {
+	msg, err := s.peer.call("initialize", map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "mcp-conformance", "version": "0.1.0"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var init mcp.InitializeResult
+	if err := msg.expectResult(&init); err != nil {
+		return nil, err
+	}
+	return &init, nil
+}
+
+// serverAnswer replies to the requests a server may send a client that
+// declared no capabilities.
+func serverAnswer(msg *message) (any, *rpcError) {
+	if msg.Method == "ping" {
+		return struct{}{}, nil
+	}
+	return nil, &rpcError{Code: -32601, Message: "method not found"}
+}
+
+var revisionPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// RunServerSuite runs the server checks against the server that
+// newTransport connects to, each as a subtest of t, and returns the report.
+func RunServerSuite(t *testing.T, newTransport TransportFactory, opts ...Option) *Report {
+	t.Helper()
+	cfg := newConfig(opts)
+	report := &Report{Suite: "server"}
+	var setup func(t *testing.T) (*serverSession, error)
+	setup = func(t *testing.T) (*serverSession, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+		defer cancel()
+		conn, err := newTransport(t).Dial(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("dial: %w", err)
+		}
+		p := newPeer(conn, cfg.timeout, serverAnswer)
+		t.Cleanup(func() { p.close() })
+		return &serverSession{cfg: cfg, peer: p, redial: func() (*serverSession, error) { return setup(t) }}, nil
+	}
+	for _, c := range serverChecks(report) {
+		runCheck(t, cfg, report, c, setup)
+	}
+	finish(t, cfg, report)
+	return report
+}
+
+func serverChecks(report *Report) []check[*serverSession] {
+	return []check[*serverSession]{
+		{
+			id:          "lifecycle/initialize",
+			description: "server answers initialize with protocolVersion, capabilities and serverInfo",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *serverSession) error {
+				msg, err := s.peer.call("initialize", map[string]any{
+					"protocolVersion": s.cfg.protocolVersion,
+					"capabilities":    map[string]any{},
+					"clientInfo":      map[string]any{"name": "mcp-conformance", "version": "0.1.0"},
+				})
+				if err != nil {
+					return err
+				}
+				if msg.JSONRPC != "2.0" {
+					return fmt.Errorf(`response has jsonrpc %q, want "2.0"`, msg.JSONRPC)
+				}
+				var result map[string]json.RawMessage
+				if err := msg.expectResult(&result); err != nil {
+					return err
+				}
+				for _, field := range []string{"protocolVersion", "capabilities", "serverInfo"} {
+					if _, ok := result[field]; !ok {
+						return fmt.Errorf("result has no %s", field)
+					}
+				}
+				var init mcp.InitializeResult
+				if err := msg.expectResult(&init); err != nil {
+					return err
+				}
+				if init.ServerInfo.Name == "" {
+					return errors.New("serverInfo has no name")
+				}
+				report.ProtocolVersion = init.ProtocolVersion
+				report.Implementation = init.ServerInfo
+				return nil
+			},
+		},
+		{
+			id:          "lifecycle/version-negotiation",
+			description: "server answers an unsupported version with one it supports",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *serverSession) error {
+				init, err := s.initialize("1999-01-01")
+				if err != nil {
+					return err
+				}
+				if init.ProtocolVersion == "1999-01-01" || !revisionPattern.MatchString(init.ProtocolVersion) {
+					return fmt.Errorf("answered with version %q", init.ProtocolVersion)
+				}
+				return nil
+			},
+		},
+		{
+			id:          "lifecycle/version-echo",
+			description: "server answers a version it supports with the same version",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *serverSession) error {
+				// The version the server offers is one it supports.
+				init, err := s.initialize("1999-01-01")
+				if err != nil {
+					return err
+				}
+				s.peer.close()
+				other, err := s.redial()
+				if err != nil {
+					return err
+				}
+				again, err := other.initialize(init.ProtocolVersion)
+				if err != nil {
+					return err
+				}
+				if again.ProtocolVersion != init.ProtocolVersion {
+					return fmt.Errorf("requested supported version %s, got %s", init.ProtocolVersion, again.ProtocolVersion)
+				}
+				return nil
+			},
+		},
+		{
+			id:          "ping/before-initialize",
+			description: "server answers ping before initialization",
+			revision:    Revision20241105,
+			level:       Should,
+			run: func(s *serverSession) error {
+				return s.peer.alive()
+			},
+		},
+		{
+			id:          "ping/empty-result",
+			description: "server answers ping with an empty result",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				msg, err := s.peer.call("ping", nil)
+				if err != nil {
+					return err
+				}
+				var result map[string]any
+				if err := msg.expectResult(&result); err != nil {
+					return err
+				}
+				if len(result) != 0 {
+					return fmt.Errorf("result is %s, want {}", msg.Result)
+				}
+				return nil
+			},
+		},
+		{
+			id:          "errors/method-not-found",
+			description: "server answers an unknown method with -32601",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				msg, err := s.peer.call("conformance/no-such-method", map[string]any{})
+				if err != nil {
+					return err
+				}
+				return msg.expectError(-32601)
+			},
+		},
+		{
+			id:          "errors/invalid-params",
+			description: "server answers malformed params with -32602",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				method := ""
+				switch {
+				case s.init.Capabilities.Tools != nil:
+					method = "tools/call"
+				case s.init.Capabilities.Prompts != nil:
+					method = "prompts/get"
+				default:
+					return skipf("server has neither tools nor prompts")
+				}
+				msg, err := s.peer.call(method, map[string]any{"name": 42})
+				if err != nil {
+					return err
+				}
+				return msg.expectError(-32602)
+			},
+		},
+		{
+			id:          "errors/unknown-tool",
+			description: "server answers a call to an unknown tool with -32602",
+			revision:    Revision20241105,
+			level:       Should,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				if s.init.Capabilities.Tools == nil {
+					return skipf("server has no tools")
+				}
+				msg, err := s.peer.call("tools/call", map[string]any{"name": "conformance_no_such_tool", "arguments": map[string]any{}})
+				if err != nil {
+					return err
+				}
+				return msg.expectError(-32602)
+			},
+		},
+		{
+			id:          "errors/resource-not-found",
+			description: "server answers a read of an unknown resource with -32002",
+			revision:    Revision20241105,
+			level:       Should,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				if s.init.Capabilities.Resources == nil {
+					return skipf("server has no resources")
+				}
+				msg, err := s.peer.call("resources/read", map[string]any{"uri": "conformance://no-such-resource"})
+				if err != nil {
+					return err
+				}
+				return msg.expectError(-32002)
+			},
+		},
+		{
+			id:          "pagination/cursors",
+			description: "list results can be paged through with nextCursor without repeats",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				lists := []struct {
+					method, field, key string
+					enabled            bool
+				}{
+					{"tools/list", "tools", "name", s.init.Capabilities.Tools != nil},
+					{"prompts/list", "prompts", "name", s.init.Capabilities.Prompts != nil},
+					{"resources/list", "resources", "uri", s.init.Capabilities.Resources != nil},
+					{"resources/templates/list", "resourceTemplates", "uriTemplate", s.init.Capabilities.Resources != nil},
+				}
+				checked := 0
+				for _, l := range lists {
+					if !l.enabled {
+						continue
+					}
+					checked++
+					if err := pageThrough(s.peer, l.method, l.field, l.key); err != nil {
+						return err
+					}
+				}
+				if checked == 0 {
+					return skipf("server has no lists")
+				}
+				return nil
+			},
+		},
+		{
+			id:          "pagination/invalid-cursor",
+			description: "server answers an invalid cursor with -32602",
+			revision:    Revision20241105,
+			level:       Should,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				if s.init.Capabilities.Tools == nil {
+					return skipf("server has no tools")
+				}
+				msg, err := s.peer.call("tools/list", map[string]any{"cursor": "!conformance-invalid-cursor!"})
+				if err != nil {
+					return err
+				}
+				return msg.expectError(-32602)
+			},
+		},
+		{
+			id:          "jsonrpc/notifications-unanswered",
+			description: "server does not respond to notifications",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				if err := s.peer.notify("notifications/conformance/unknown", map[string]any{}); err != nil {
+					return err
+				}
+				if err := s.peer.alive(); err != nil {
+					return err
+				}
+				for _, msg := range s.peer.all() {
+					if msg.Method == "" && msg.idKey() == "" {
+						return fmt.Errorf("server sent a response without an id: result %s, error %+v", msg.Result, msg.Error)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			id:          "cancellation/unknown-request",
+			description: "server ignores cancellation of an unknown request",
+			revision:    Revision20241105,
+			level:       Should,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				if err := s.peer.notify("notifications/cancelled", map[string]any{"requestId": 987654, "reason": "conformance"}); err != nil {
+					return err
+				}
+				return s.peer.alive()
+			},
+		},
+		{
+			id:          "cancellation/in-flight",
+			description: "server does not answer a request after it is cancelled",
+			revision:    Revision20241105,
+			level:       Should,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				if s.cfg.toolCall == nil {
+					return skipf("no tool call configured (WithToolCall)")
+				}
+				const id = 900001
+				if err := s.peer.write(map[string]any{"jsonrpc": "2.0", "id": id, "method": "tools/call", "params": s.cfg.toolCall}); err != nil {
+					return err
+				}
+				if err := s.peer.notify("notifications/cancelled", map[string]any{"requestId": id, "reason": "conformance"}); err != nil {
+					return err
+				}
+				if err := s.peer.alive(); err != nil {
+					return err
+				}
+				time.Sleep(200 * time.Millisecond)
+				for _, msg := range s.peer.all() {
+					if msg.Method == "" && msg.idKey() == fmt.Sprint(id) {
+						return errors.New("server answered the cancelled request")
+					}
+				}
+				return nil
+			},
+		},
+		{
+			id:          "progress/token",
+			description: "progress notifications carry the request's token and increase",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				if s.cfg.toolCall == nil {
+					return skipf("no tool call configured (WithToolCall)")
+				}
+				params := map[string]any{
+					"name":      s.cfg.toolCall.Name,
+					"arguments": s.cfg.toolCall.Arguments,
+					"_meta":     map[string]any{"progressToken": "conformance-progress"},
+				}
+				msg, err := s.peer.call("tools/call", params)
+				if err != nil {
+					return err
+				}
+				if err := msg.expectResult(nil); err != nil {
+					return err
+				}
+				last := -1.0
+				for _, n := range s.peer.all() {
+					if n.Method != "notifications/progress" {
+						continue
+					}
+					var p struct {
+						ProgressToken any     `json:"progressToken"`
+						Progress      float64 `json:"progress"`
+					}
+					if err := json.Unmarshal(n.Params, &p); err != nil {
+						return fmt.Errorf("progress params %s: %v", n.Params, err)
+					}
+					if p.ProgressToken != "conformance-progress" {
+						return fmt.Errorf("progress for token %v, want conformance-progress", p.ProgressToken)
+					}
+					if p.Progress <= last {
+						return fmt.Errorf("progress went from %v to %v", last, p.Progress)
+					}
+					last = p.Progress
+				}
+				return nil
+			},
+		},
+		{
+			id:          "resources/subscribe",
+			description: "server acknowledges resources/subscribe and resources/unsubscribe",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				if s.init.Capabilities.Resources == nil || !s.init.Capabilities.Resources.Subscribe {
+					return skipf("server does not support subscriptions")
+				}
+				msg, err := s.peer.call("resources/list", map[string]any{})
+				if err != nil {
+					return err
+				}
+				var list mcp.ListResourcesResult
+				if err := msg.expectResult(&list); err != nil {
+					return err
+				}
+				if len(list.Resources) == 0 {
+					return skipf("server lists no resources")
+				}
+				uri := list.Resources[0].URI
+				for _, method := range []string{"resources/subscribe", "resources/unsubscribe"} {
+					msg, err := s.peer.call(method, map[string]any{"uri": uri})
+					if err != nil {
+						return err
+					}
+					if err := msg.expectResult(nil); err != nil {
+						return fmt.Errorf("%s: %v", method, err)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			id:          "logging/set-level",
+			description: "server acknowledges logging/setLevel",
+			revision:    Revision20241105,
+			level:       Must,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				if s.init.Capabilities.Logging == nil {
+					return skipf("server does not declare logging")
+				}
+				msg, err := s.peer.call("logging/setLevel", map[string]any{"level": "warning"})
+				if err != nil {
+					return err
+				}
+				return msg.expectResult(nil)
+			},
+		},
+		{
+			id:          "logging/invalid-level",
+			description: "server answers an unknown log level with -32602",
+			revision:    Revision20241105,
+			level:       Should,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				if s.init.Capabilities.Logging == nil {
+					return skipf("server does not declare logging")
+				}
+				msg, err := s.peer.call("logging/setLevel", map[string]any{"level": "conformance-loud"})
+				if err != nil {
+					return err
+				}
+				return msg.expectError(-32602)
+			},
+		},
+		{
+			id:          "jsonrpc/batch-rejected",
+			description: "server rejects a JSON-RPC batch with an error and keeps the connection",
+			revision:    Revision20250618,
+			level:       Should,
+			run: func(s *serverSession) error {
+				if err := s.handshake(); err != nil {
+					return err
+				}
+				batch := `[{"jsonrpc":"2.0","id":"batch-1","method":"ping"},{"jsonrpc":"2.0","id":"batch-2","method":"ping"}]`
+				if err := s.peer.send([]byte(batch)); err != nil {
+					return err
+				}
+				if err := s.peer.alive(); err != nil {
+					return fmt.Errorf("after batch: %v", err)
+				}
+				var rejected bool
+				for _, msg := range s.peer.all() {
+					if msg.Method != "" {
+						continue
+					}
+					if msg.idKey() == `"batch-1"` || msg.idKey() == `"batch-2"` {
+						if msg.Error == nil {
+							return errors.New("server executed a batched request")
+						}
+						rejected = true
+					}
+					if msg.idKey() == "" && msg.Error != nil {
+						rejected = true
+					}
+				}
+				if !rejected {
+					return errors.New("server sent no error for the batch")
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// pageThrough follows nextCursor through method and checks that no item
+// appears twice.
+func pageThrough(p *peer, method, field, key string) error {
+	seen := make(map[string]bool)
+	var cursor string
+	for page := 0; page < 1000; page++ {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		msg, err := p.call(method, params)
+		if err != nil {
+			return err
+		}
+		var result map[string]json.RawMessage
+		if err := msg.expectResult(&result); err != nil {
+			return fmt.Errorf("%s: %v", method, err)
+		}
+		var items []map[string]any
+		if raw, ok := result[field]; ok {
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return fmt.Errorf("%s: %s is not an array", method, field)
+			}
+		} else {
+			return fmt.Errorf("%s: result has no %s", method, field)
+		}
+		for _, item := range items {
+			k := fmt.Sprint(item[key])
+			if seen[k] {
+				return fmt.Errorf("%s: %s %q appears on more than one page", method, key, k)
+			}
+			seen[k] = true
+		}
+		var next string
+		if raw, ok := result["nextCursor"]; ok {
+			if err := json.Unmarshal(raw, &next); err != nil {
+				return fmt.Errorf("%s: nextCursor is not a string", method)
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		if next == cursor {
+			return fmt.Errorf("%s: nextCursor %q repeats", method, next)
+		}
+		cursor = next
+	}
+	return fmt.Errorf("%s: more than 1000 pages", method)
+}
@@ -3,6 +3,10 @@
 // This package serves as the parent for various testing-related subpackages:
 //
 //   - mcptestutil: Comprehensive testing utilities including mocks, assertions, and helpers
+//   - mcptest: In-memory client/server pairs with fault injection, golden transcripts,
+//     .mcp trace record/replay and schema-driven server fuzzing
+//   - conformance: Protocol conformance suites for servers and clients, with
+//     per-check spec revision, MUST/SHOULD level and a JSON report
 //
 // The testing package itself provides common testing infrastructure and coordinates
 // testing functionality across the MCP codebase.
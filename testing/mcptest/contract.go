@@ -0,0 +1,50 @@
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmc/mcp"
+	"github.com/tmc/mcp/internal/mcpspy"
+)
+
+// CheckContract fails t if the tools, resources or prompts of server changed
+// incompatibly since the .mcpspec document at path was saved. Breaking
+// changes are errors; other changes are logged. The document has the format
+// written by mcpspy and `mcp contract snapshot`. With MCPTEST_UPDATE=1 in the
+// environment it writes the document instead.
+func CheckContract(t testing.TB, server *mcp.Server, path string) {
+	t.Helper()
+	client, init := connect(t, server, newConfig(nil))
+	live, err := mcpspy.SpecFromClient(context.Background(), client, init.ServerInfo)
+	if err != nil {
+		t.Fatalf("mcptest: %v", err)
+	}
+	if os.Getenv(UpdateEnv) == "1" {
+		data, err := json.MarshalIndent(live, "", "  ")
+		if err != nil {
+			t.Fatalf("mcptest: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mcptest: %v", err)
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			t.Fatalf("mcptest: %v", err)
+		}
+		return
+	}
+	saved, err := mcpspy.LoadSpecDocument(path)
+	if err != nil {
+		t.Fatalf("mcptest: %v (run with %s=1 to create it)", err, UpdateEnv)
+	}
+	for _, c := range mcpspy.CheckContract(saved, live).Changes {
+		if c.Breaking {
+			t.Errorf("mcptest: %s: %s", path, c)
+		} else {
+			t.Logf("mcptest: %s: %s", path, c)
+		}
+	}
+}
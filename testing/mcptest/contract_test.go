@@ -0,0 +1,22 @@
+package mcptest
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tmc/mcp"
+)
+
+func TestCheckContract(t *testing.T) {
+	CheckContract(t, newEchoServer(t), "testdata/echo.mcpspec")
+	if os.Getenv(UpdateEnv) == "1" {
+		return
+	}
+
+	rec := &errorRecorder{TB: t}
+	CheckContract(rec, mcp.NewServer("echo", "2.0.0"), "testdata/echo.mcpspec")
+	if got := strings.Join(rec.errs, "\n"); got != "mcptest: testdata/echo.mcpspec: BREAKING: tool echo: removed" {
+		t.Errorf("errors = %q", got)
+	}
+}
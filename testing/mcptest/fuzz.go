@@ -0,0 +1,338 @@
+package mcptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+// IDs the fuzz harness uses for its own requests.
+const (
+	fuzzInitID = `"mcptest-fuzz-init"`
+	fuzzPingID = `"mcptest-fuzz-ping"`
+)
+
+// fuzzTimeout bounds how long one fuzz input may take to settle.
+const fuzzTimeout = 5 * time.Second
+
+// FuzzServer fuzzes server with JSON-RPC messages. Call it from a fuzz
+// target:
+//
+//	func FuzzMyServer(f *testing.F) {
+//	    mcptest.FuzzServer(f, newMyServer(f))
+//	}
+//
+// The seed corpus holds a tools/call for every argument set
+// GenerateArguments derives from each listed tool's input schema, calls to
+// the other standard methods, and mutated envelopes (odd IDs, wrong
+// versions, batches, truncated JSON). Each input is written to a freshly
+// initialized session, followed by a cancellation of any request it
+// contains and a ping. The input fails if
+//
+//   - a message from the server is not valid JSON-RPC 2.0,
+//   - a response carries an ID that was never sent, or more responses than requests,
+//   - the server stops answering after a well-formed message,
+//   - Serve does not return once the session is closed, or
+//   - goroutines started for the session outlive it.
+//
+// A panic that escapes the server's recovery crashes the fuzz process and
+// is reported as a failure too. As with any fuzz target, go test -fuzz
+// minimizes failing inputs and saves them under testdata/fuzz, where they
+// run as regression cases on every go test.
+func FuzzServer(f *testing.F, server *mcp.Server) {
+	f.Helper()
+	for _, seed := range fuzzSeeds(f, server) {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input []byte) {
+		fuzzOne(t, server, input)
+	})
+}
+
+// fuzzSeeds lists the server's tools and builds the seed corpus.
+func fuzzSeeds(t testing.TB, server *mcp.Server) [][]byte {
+	client, _ := connect(t, server, newConfig(nil))
+	var tools []mcp.Tool
+	var cursor string
+	for {
+		result, err := client.ListTools(context.Background(), mcp.ListToolsRequest{Cursor: cursor})
+		if err != nil {
+			break // a server without tools still gets the envelope seeds
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" || result.NextCursor == cursor {
+			break
+		}
+		cursor = result.NextCursor
+	}
+
+	var seeds [][]byte
+	request := func(id any, method string, params any) []byte {
+		msg := map[string]any{"jsonrpc": "2.0", "method": method}
+		if id != nil {
+			msg["id"] = id
+		}
+		if params != nil {
+			msg["params"] = params
+		}
+		data, _ := json.Marshal(msg)
+		return data
+	}
+	for i, tool := range tools {
+		for j, args := range GenerateArguments(tool.InputSchema) {
+			seeds = append(seeds, request(i*maxArgumentSets+j+1, "tools/call", map[string]any{"name": tool.Name, "arguments": args}))
+		}
+	}
+	name := "mcptest"
+	if len(tools) > 0 {
+		name = tools[0].Name
+	}
+	call := map[string]any{"name": name, "arguments": map[string]any{}}
+	seeds = append(seeds,
+		request(1, "ping", nil),
+		request(1, "tools/list", map[string]any{"cursor": "mcptest-invalid"}),
+		request(1, "prompts/list", nil),
+		request(1, "prompts/get", map[string]any{"name": name, "arguments": map[string]any{"a": 1}}),
+		request(1, "resources/list", nil),
+		request(1, "resources/templates/list", nil),
+		request(1, "resources/read", map[string]any{"uri": "file:///../../etc/passwd"}),
+		request(1, "resources/subscribe", map[string]any{"uri": ""}),
+		request(1, "completion/complete", map[string]any{"ref": map[string]any{"type": "ref/prompt", "name": name}, "argument": map[string]any{"name": "a", "value": ""}}),
+		request(1, "logging/setLevel", map[string]any{"level": "mcptest"}),
+		request(1, "initialize", map[string]any{"protocolVersion": "1999-01-01"}),
+		request(1, "mcptest/unknown", nil),
+		request(nil, "notifications/cancelled", map[string]any{"requestId": 1}),
+		request(nil, "tools/call", call),
+		request("string-id", "tools/call", call),
+		request(1.5, "tools/call", call),
+		request(int64(1)<<53+1, "tools/call", call),
+		request(map[string]any{"id": 1}, "tools/call", call),
+		request(1, "tools/call", []any{name}),
+		request(1, "tools/call", "params"),
+		request(1, "tools/call", map[string]any{"name": name, "arguments": map[string]any{}, "_meta": map[string]any{"progressToken": map[string]any{}}}),
+		[]byte(`{"jsonrpc":"2.0","id":null,"method":"ping"}`),
+		[]byte(`{"jsonrpc":"1.0","id":1,"method":"ping"}`),
+		[]byte(`{"id":1,"method":"ping"}`),
+		[]byte(`{"jsonrpc":"2.0","id":1}`),
+		[]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`),
+		[]byte(`{"jsonrpc":"2.0","id":1,"method":"ping","method":"tools/list"}`),
+		[]byte(`[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","id":2,"method":"tools/list"}]`),
+		[]byte(`[]`),
+		[]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":`),
+		[]byte(`null`),
+		[]byte("\xff\xfe"),
+	)
+	return seeds
+}
+
+// fuzzMessage is a JSON-RPC message as read from the server.
+type fuzzMessage struct {
+	JSONRPC *string         `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  *string         `json:"method"`
+	Result  json.RawMessage `json:"result"`
+	Error   *struct {
+		Code    *int64  `json:"code"`
+		Message *string `json:"message"`
+	} `json:"error"`
+}
+
+// fuzzOne runs one input against a fresh session with server.
+func fuzzOne(t *testing.T, server *mcp.Server, input []byte) {
+	before := runtime.NumGoroutine()
+
+	clientTransport, serverTransport := NewInMemoryTransports()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, serverTransport) }()
+	conn, err := clientTransport.Dial(ctx)
+	if err != nil {
+		t.Fatalf("mcptest: dial: %v", err)
+	}
+
+	messages := make(chan json.RawMessage, 64)
+	go func() {
+		defer close(messages)
+		dec := json.NewDecoder(conn)
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return
+			}
+			messages <- raw
+		}
+	}()
+
+	// Counts per ID, since a batch may reuse one.
+	answered := make(map[string]int)
+	sent := map[string]int{fuzzInitID: 1, fuzzPingID: 1}
+	// await reads until the response to id arrives, checking every message
+	// on the way. It reports false if the connection closed first.
+	await := func(id string) bool {
+		timeout := time.After(fuzzTimeout)
+		for {
+			select {
+			case raw, ok := <-messages:
+				if !ok {
+					return false
+				}
+				if got := checkFuzzMessage(t, conn, raw, sent, answered); got == id {
+					return true
+				}
+			case <-timeout:
+				t.Fatalf("mcptest: no response to %s within %v after input %q", id, fuzzTimeout, input)
+			}
+		}
+	}
+
+	fmt.Fprintf(conn, `{"jsonrpc":"2.0","id":%s,"method":"initialize","params":{"protocolVersion":%q,"capabilities":{},"clientInfo":{"name":"mcptest-fuzz","version":"0.0.0"}}}`+"\n", fuzzInitID, mcp.LATEST_PROTOCOL_VERSION)
+	if !await(fuzzInitID) {
+		t.Fatalf("mcptest: connection closed during initialize")
+	}
+	fmt.Fprintln(conn, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	ids, wellFormed := fuzzRequestIDs(input)
+	for _, id := range ids {
+		sent[id]++
+	}
+	conn.Write(append(bytes.TrimSpace(input), '\n'))
+	// After malformed JSON the stream cannot be resynchronized, so only
+	// the server's survival and cleanup are checked.
+	if json.Valid(input) {
+		for _, id := range ids {
+			fmt.Fprintf(conn, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":%s}}`+"\n", id)
+		}
+		fmt.Fprintf(conn, `{"jsonrpc":"2.0","id":%s,"method":"ping"}`+"\n", fuzzPingID)
+		if !await(fuzzPingID) && wellFormed {
+			t.Errorf("mcptest: server closed the connection after input %q", input)
+		}
+	}
+
+	conn.Close()
+	cancel()
+	select {
+	case <-served:
+	case <-time.After(fuzzTimeout):
+		t.Fatalf("mcptest: Serve did not return within %v of the session closing", fuzzTimeout)
+	}
+	checkGoroutines(t, before)
+}
+
+// fuzzRequestIDs returns the IDs of the requests in input and whether input
+// is a single well-formed JSON object, which the server must survive.
+func fuzzRequestIDs(input []byte) (ids []string, wellFormed bool) {
+	var msgs []fuzzMessage
+	var one fuzzMessage
+	if err := json.Unmarshal(input, &one); err == nil && bytes.HasPrefix(bytes.TrimSpace(input), []byte("{")) {
+		msgs, wellFormed = []fuzzMessage{one}, true
+	} else if err := json.Unmarshal(input, &msgs); err != nil {
+		return nil, false
+	}
+	for _, m := range msgs {
+		if m.Method != nil && len(m.ID) > 0 && string(m.ID) != "null" {
+			ids = append(ids, canonicalID(m.ID))
+		}
+	}
+	return ids, wellFormed
+}
+
+// canonicalID normalizes an ID so that 1 and 1.0 compare equal.
+func canonicalID(id json.RawMessage) string {
+	if n, err := strconv.ParseInt(string(id), 10, 64); err == nil {
+		return strconv.FormatInt(n, 10)
+	}
+	if f, err := strconv.ParseFloat(string(id), 64); err == nil {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	var v any
+	if json.Unmarshal(id, &v) == nil {
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return string(id)
+}
+
+// checkFuzzMessage validates one message from the server and returns the ID
+// it answers, if it is a response. Requests from the server are refused so
+// that handlers waiting on the client do not stall.
+func checkFuzzMessage(t *testing.T, conn interface{ Write([]byte) (int, error) }, raw json.RawMessage, sent, answered map[string]int) string {
+	t.Helper()
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(raw, &batch); err != nil || len(batch) == 0 {
+			t.Errorf("mcptest: invalid batch from server: %s", raw)
+			return ""
+		}
+		var last string
+		for _, item := range batch {
+			if id := checkFuzzMessage(t, conn, item, sent, answered); id != "" {
+				last = id
+			}
+		}
+		return last
+	}
+	var m fuzzMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		t.Errorf("mcptest: server sent a non-object message: %s", raw)
+		return ""
+	}
+	if m.JSONRPC == nil || *m.JSONRPC != "2.0" {
+		t.Errorf(`mcptest: server message without "jsonrpc":"2.0": %s`, raw)
+	}
+	if m.Method != nil {
+		if len(m.ID) > 0 && string(m.ID) != "null" {
+			fmt.Fprintf(conn, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32601,"message":"not supported by fuzz client"}}`+"\n", m.ID)
+		}
+		return ""
+	}
+	hasResult, hasError := len(m.Result) > 0, m.Error != nil
+	if hasResult == hasError {
+		t.Errorf("mcptest: response must have exactly one of result and error: %s", raw)
+	}
+	if hasError && (m.Error.Code == nil || m.Error.Message == nil) {
+		t.Errorf("mcptest: error response without code and message: %s", raw)
+	}
+	if len(m.ID) == 0 {
+		t.Errorf("mcptest: response without an id: %s", raw)
+		return ""
+	}
+	if string(m.ID) == "null" {
+		if !hasError {
+			t.Errorf("mcptest: null id on a non-error response: %s", raw)
+		}
+		return ""
+	}
+	id := canonicalID(m.ID)
+	answered[id]++
+	switch {
+	case sent[id] == 0:
+		t.Errorf("mcptest: response to id %s, which was never sent: %s", m.ID, raw)
+	case answered[id] > sent[id]:
+		t.Errorf("mcptest: more responses to id %s than requests: %s", m.ID, raw)
+	}
+	return id
+}
+
+// checkGoroutines fails t if more goroutines are running than before once
+// stragglers have had a moment to exit.
+func checkGoroutines(t *testing.T, before int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			var buf bytes.Buffer
+			pprof.Lookup("goroutine").WriteTo(&buf, 1)
+			t.Fatalf("mcptest: %d goroutines outlived the session (was %d):\n%s", runtime.NumGoroutine(), before, buf.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,86 @@
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+func newFuzzServer(t testing.TB) *mcp.Server {
+	t.Helper()
+	server := mcp.NewServer("fuzz", "1.0.0")
+	schema := json.RawMessage(`{"type":"object","properties":{
+		"query":{"type":"string","minLength":1,"maxLength":32},
+		"limit":{"type":"integer","minimum":1,"maximum":100},
+		"order":{"type":"string","enum":["asc","desc"]},
+		"tags":{"type":"array","items":{"type":"string"},"maxItems":3},
+		"filter":{"type":"object","properties":{"since":{"type":"string","format":"date-time"}}}
+	},"required":["query"]}`)
+	err := server.RegisterTool(mcp.Tool{Name: "search", InputSchema: schema},
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			var args struct {
+				Query string `json:"query"`
+				Limit int    `json:"limit"`
+			}
+			if err := json.Unmarshal(req.Arguments, &args); err != nil {
+				return nil, err
+			}
+			if args.Limit > 100 {
+				panic("limit out of range") // recovered by the server
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(args.Limit) * time.Millisecond):
+			}
+			return &mcp.CallToolResult{Content: []any{map[string]any{"type": "text", "text": args.Query}}}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func FuzzSearchServer(f *testing.F) {
+	FuzzServer(f, newFuzzServer(f))
+}
+
+func TestGenerateArguments(t *testing.T) {
+	sets := GenerateArguments(json.RawMessage(`{"type":"object","properties":{"n":{"type":"integer","minimum":1,"maximum":9},"s":{"type":"string"}},"required":["n"]}`))
+	decode := func(raw json.RawMessage) map[string]any {
+		var m map[string]any
+		json.Unmarshal(raw, &m)
+		return m
+	}
+	if got := decode(sets[0]); got["n"] != 1.0 || got["s"] != "sample" {
+		t.Errorf("first set = %s, want every property valid", sets[0])
+	}
+	if got := decode(sets[1]); len(got) != 1 || got["n"] != 1.0 {
+		t.Errorf("second set = %s, want only the required property", sets[1])
+	}
+	var boundary, missing, wrongType bool
+	for _, raw := range sets {
+		m := decode(raw)
+		if m == nil {
+			continue
+		}
+		n, ok := m["n"]
+		switch {
+		case !ok:
+			missing = true
+		case n == 10.0:
+			boundary = true
+		case n == "12":
+			wrongType = true
+		}
+	}
+	if !boundary || !missing || !wrongType {
+		t.Errorf("boundary %v, missing required %v, wrong type %v; want all", boundary, missing, wrongType)
+	}
+	if len(GenerateArguments(json.RawMessage(`not json`))) == 0 {
+		t.Error("no arguments for an unparsable schema")
+	}
+}
@@ -0,0 +1,283 @@
+package mcptest
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+// newLink returns transports for the two ends of an in-memory link.
+func newLink(cfg *config) (client, server mcp.Transport) {
+	l := &link{cfg: cfg, rng: cfg.rand()}
+	toServer := newInbox()
+	toClient := newInbox()
+	c := &endpoint{link: l, dir: ClientToServer, in: toClient, out: newSender(toServer)}
+	s := &endpoint{link: l, dir: ServerToClient, in: toServer, out: newSender(toClient)}
+	return dialer(c), dialer(s)
+}
+
+func dialer(e *endpoint) mcp.Transport {
+	return mcp.TransportFunc(func(context.Context) (io.ReadWriteCloser, error) {
+		return e, nil
+	})
+}
+
+// link holds the fault configuration shared by both directions.
+type link struct {
+	cfg *config
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// plan decides the fate of one message: whether to drop it, and how long to
+// delay it.
+func (l *link) plan(dir Direction, msg []byte) (drop bool, delay time.Duration) {
+	if l.cfg.dropFunc != nil && l.cfg.dropFunc(dir, msg) {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cfg.dropRate > 0 && l.rng.Float64() < l.cfg.dropRate {
+		return true, 0
+	}
+	delay = l.cfg.latency
+	if l.cfg.jitter > 0 {
+		delay += time.Duration(l.rng.Int64N(int64(l.cfg.jitter)))
+	}
+	if l.cfg.reorderRate > 0 && l.rng.Float64() < l.cfg.reorderRate {
+		delay += l.cfg.reorderDelay
+	}
+	return false, delay
+}
+
+// endpoint is one side of the link.
+type endpoint struct {
+	link *link
+	dir  Direction // direction of messages written here
+	in   *inbox
+	out  *sender
+
+	wmu     sync.Mutex
+	partial []byte // written bytes not yet ending in a newline
+
+	rmu    sync.Mutex
+	unread []byte // rest of the message being read
+
+	closeOnce sync.Once
+}
+
+func (e *endpoint) Read(p []byte) (int, error) {
+	e.rmu.Lock()
+	defer e.rmu.Unlock()
+	if len(e.unread) == 0 {
+		msg, ok := e.in.pop()
+		if !ok {
+			return 0, io.EOF
+		}
+		e.unread = msg
+	}
+	n := copy(p, e.unread)
+	e.unread = e.unread[n:]
+	return n, nil
+}
+
+// Write splits p into newline-delimited messages and sends each one.
+func (e *endpoint) Write(p []byte) (int, error) {
+	e.wmu.Lock()
+	defer e.wmu.Unlock()
+	if e.in.isClosed() || e.out.to.isClosed() {
+		return 0, io.ErrClosedPipe
+	}
+	e.partial = append(e.partial, p...)
+	for {
+		i := bytes.IndexByte(e.partial, '\n')
+		if i < 0 {
+			break
+		}
+		msg := bytes.TrimSpace(e.partial[:i])
+		e.partial = e.partial[i+1:]
+		if len(msg) > 0 {
+			e.send(append([]byte(nil), msg...))
+		}
+	}
+	return len(p), nil
+}
+
+func (e *endpoint) send(msg []byte) {
+	if tr := e.link.cfg.transcript; tr != nil {
+		tr.record(e.dir, msg)
+	}
+	drop, delay := e.link.plan(e.dir, msg)
+	if drop {
+		return
+	}
+	e.out.send(append(msg, '\n'), delay)
+}
+
+// Close closes both directions of the link; reads on either end return EOF
+// once delivered messages are consumed.
+func (e *endpoint) Close() error {
+	e.closeOnce.Do(func() {
+		e.in.close()
+		e.out.close()
+	})
+	return nil
+}
+
+// inbox is a queue of delivered messages.
+type inbox struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	msgs   [][]byte
+	closed bool
+}
+
+func newInbox() *inbox {
+	b := &inbox{}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *inbox) push(msg []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.msgs = append(b.msgs, msg)
+		b.cond.Signal()
+	}
+}
+
+func (b *inbox) pop() ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for len(b.msgs) == 0 && !b.closed {
+		b.cond.Wait()
+	}
+	if len(b.msgs) == 0 {
+		return nil, false
+	}
+	msg := b.msgs[0]
+	b.msgs = b.msgs[1:]
+	return msg, true
+}
+
+func (b *inbox) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.cond.Broadcast()
+}
+
+func (b *inbox) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+// sender delivers messages to an inbox after their delay, in order of
+// delivery time.
+type sender struct {
+	to *inbox
+
+	mu      sync.Mutex
+	queue   deliveryQueue
+	seq     uint64
+	wake    chan struct{}
+	done    chan struct{}
+	started bool
+}
+
+func newSender(to *inbox) *sender {
+	return &sender{to: to, wake: make(chan struct{}, 1), done: make(chan struct{})}
+}
+
+func (s *sender) send(msg []byte, delay time.Duration) {
+	s.mu.Lock()
+	if delay <= 0 && len(s.queue) == 0 {
+		// Nothing in flight: deliver synchronously to keep order.
+		s.mu.Unlock()
+		s.to.push(msg)
+		return
+	}
+	s.seq++
+	heap.Push(&s.queue, delivery{at: time.Now().Add(delay), seq: s.seq, msg: msg})
+	if !s.started {
+		s.started = true
+		go s.run()
+	}
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *sender) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		wait := time.Hour
+		for len(s.queue) > 0 {
+			next := s.queue[0]
+			if d := time.Until(next.at); d > 0 {
+				wait = d
+				break
+			}
+			heap.Pop(&s.queue)
+			s.to.push(next.msg)
+		}
+		s.mu.Unlock()
+
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *sender) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	s.to.close()
+}
+
+type delivery struct {
+	at  time.Time
+	seq uint64
+	msg []byte
+}
+
+// deliveryQueue is a min-heap of deliveries by time, then send order.
+type deliveryQueue []delivery
+
+func (q deliveryQueue) Len() int { return len(q) }
+func (q deliveryQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+func (q deliveryQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *deliveryQueue) Push(x any)   { *q = append(*q, x.(delivery)) }
+func (q *deliveryQueue) Pop() any {
+	old := *q
+	d := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return d
+}
@@ -0,0 +1,196 @@
+// Package mcptest connects MCP clients and servers in memory for tests.
+//
+// Connect serves a *mcp.Server over an in-memory link and returns an
+// initialized client that is closed when the test ends:
+//
+//	func TestEcho(t *testing.T) {
+//	    server := mcp.NewServer("echo", "1.0.0")
+//	    // ... register tools
+//	    client := mcptest.Connect(t, server)
+//	    result, err := client.CallTool(ctx, mcp.CallToolRequest{Name: "echo"})
+//	    // ...
+//	}
+//
+// Options inject latency, dropped messages and reordering into the link, and
+// a Transcript records the traffic for comparison against a golden file.
+//
+// ReplayTransport records a session with a real server to an .mcp trace and
+// later replays it to a client offline; ReplayServer checks a live server
+// against the responses in such a trace. CheckContract fails a test when a
+// server's tools, resources or prompts change incompatibly with a saved
+// .mcpspec document. FuzzServer drives a server with JSON-RPC messages built
+// from its tools' input schemas and mutated envelopes, checking protocol
+// invariants after each input.
+package mcptest
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+// Direction identifies which way a message travels.
+type Direction int
+
+const (
+	ClientToServer Direction = iota
+	ServerToClient
+)
+
+func (d Direction) String() string {
+	if d == ClientToServer {
+		return "client->server"
+	}
+	return "server->client"
+}
+
+// Option configures an in-memory link or Connect.
+type Option func(*config)
+
+type config struct {
+	latency      time.Duration
+	jitter       time.Duration
+	dropRate     float64
+	dropFunc     func(Direction, []byte) bool
+	reorderRate  float64
+	reorderDelay time.Duration
+	seed         uint64
+	transcript   *Transcript
+
+	clientOptions     []mcp.ClientOption
+	initialize        *mcp.InitializeRequest
+	initializeTimeout time.Duration
+}
+
+// WithLatency delays every message by d plus a random duration up to
+// jitter.
+func WithLatency(d, jitter time.Duration) Option {
+	return func(c *config) {
+		c.latency = d
+		c.jitter = jitter
+	}
+}
+
+// WithDropRate drops each message with probability p.
+func WithDropRate(p float64) Option {
+	return func(c *config) { c.dropRate = p }
+}
+
+// WithDropFunc drops the messages for which fn returns true. msg is one
+// JSON-RPC message without its trailing newline.
+func WithDropFunc(fn func(dir Direction, msg []byte) bool) Option {
+	return func(c *config) { c.dropFunc = fn }
+}
+
+// WithReordering holds back each message with probability p for an extra
+// delay (default 10ms), letting later messages overtake it.
+func WithReordering(p float64, delay time.Duration) Option {
+	return func(c *config) {
+		c.reorderRate = p
+		c.reorderDelay = delay
+	}
+}
+
+// WithSeed seeds the random source behind drops, jitter and reordering, so
+// that a failing run can be reproduced. The default seed is 1.
+func WithSeed(seed uint64) Option {
+	return func(c *config) { c.seed = seed }
+}
+
+// WithTranscript records every message sent over the link in tr.
+func WithTranscript(tr *Transcript) Option {
+	return func(c *config) { c.transcript = tr }
+}
+
+// WithClientOptions passes opts to mcp.NewClient in Connect.
+func WithClientOptions(opts ...mcp.ClientOption) Option {
+	return func(c *config) { c.clientOptions = append(c.clientOptions, opts...) }
+}
+
+// WithInitializeRequest replaces the request Connect initializes with.
+func WithInitializeRequest(req mcp.InitializeRequest) Option {
+	return func(c *config) { c.initialize = &req }
+}
+
+// WithInitializeTimeout bounds how long Connect waits for the server to
+// answer initialize, so a handshake lost to dropped messages fails the test
+// instead of hanging it. The default is 5 seconds.
+func WithInitializeTimeout(d time.Duration) Option {
+	return func(c *config) { c.initializeTimeout = d }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{seed: 1, initializeTimeout: 5 * time.Second}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.reorderRate > 0 && c.reorderDelay <= 0 {
+		c.reorderDelay = 10 * time.Millisecond
+	}
+	return c
+}
+
+func (c *config) rand() *rand.Rand {
+	return rand.New(rand.NewPCG(c.seed, c.seed))
+}
+
+// Connect serves server over an in-memory link and returns a client that has
+// completed initialization. The client is closed and the server stopped when
+// the test ends.
+func Connect(t testing.TB, server *mcp.Server, opts ...Option) *mcp.Client {
+	t.Helper()
+	client, _ := connect(t, server, newConfig(opts))
+	return client
+}
+
+// connect is Connect, also returning the server's initialize result.
+func connect(t testing.TB, server *mcp.Server, cfg *config) (*mcp.Client, *mcp.InitializeResult) {
+	t.Helper()
+	clientTransport, serverTransport := newLink(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, serverTransport) }()
+
+	client, err := mcp.NewClient(clientTransport, cfg.clientOptions...)
+	if err != nil {
+		cancel()
+		t.Fatalf("mcptest: NewClient: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		cancel()
+		if err := <-served; err != nil && !errors.Is(err, context.Canceled) {
+			t.Logf("mcptest: Serve: %v", err)
+		}
+	})
+
+	req := mcp.InitializeRequest{
+		ClientInfo:      mcp.Implementation{Name: "mcptest", Version: "0.0.0"},
+		ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
+	}
+	if cfg.initialize != nil {
+		req = *cfg.initialize
+	}
+	initCtx, cancelInit := context.WithTimeout(ctx, cfg.initializeTimeout)
+	defer cancelInit()
+	init, err := client.Initialize(initCtx, req)
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("mcptest: Initialize: no response within %v; was the handshake dropped?", cfg.initializeTimeout)
+	}
+	if err != nil {
+		t.Fatalf("mcptest: Initialize: %v", err)
+	}
+	return client, init
+}
+
+// NewInMemoryTransports returns the two ends of an in-memory link. Each
+// transport dials the same connection; pass one to mcp.NewClient and the
+// other to Server.Serve.
+func NewInMemoryTransports(opts ...Option) (client, server mcp.Transport) {
+	return newLink(newConfig(opts))
+}
@@ -0,0 +1,191 @@
+package mcptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+func newEchoServer(t *testing.T) *mcp.Server {
+	t.Helper()
+	server := mcp.NewServer("echo", "1.0.0")
+	err := server.RegisterTool(mcp.Tool{Name: "echo", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return &mcp.CallToolResult{Content: []any{map[string]any{"type": "text", "text": string(req.Arguments)}}}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func TestConnect(t *testing.T) {
+	client := Connect(t, newEchoServer(t))
+	result, err := client.CallTool(context.Background(), mcp.CallToolRequest{Name: "echo", Arguments: json.RawMessage(`{"a":1}`)})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if len(result.Content) != 1 {
+		t.Fatalf("content = %v", result.Content)
+	}
+}
+
+func TestTranscriptGolden(t *testing.T) {
+	// Registering a tool notifies list_changed asynchronously, so use a bare
+	// server to keep the transcript deterministic.
+	tr := NewTranscript()
+	client := Connect(t, mcp.NewServer("bare", "1.0.0"), WithTranscript(tr))
+	ctx := context.Background()
+	if _, err := client.ListTools(ctx, mcp.ListToolsRequest{}); err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	tr.Compare(t, "testdata/bare.golden")
+}
+
+func TestNormalizeMessage(t *testing.T) {
+	ids := make(map[string]int)
+	for _, tt := range []struct{ in, want string }{
+		{`{"jsonrpc":"2.0","id":17,"method":"ping"}`, `{"id":1,"jsonrpc":"2.0","method":"ping"}`},
+		{`{"id":"abc","result":{"at":"2026-01-02T03:04:05.5Z","n":"2026"}}`, `{"id":2,"result":{"at":"<time>","n":"2026"}}`},
+		{`{"id":17,"result":{}}`, `{"id":1,"result":{}}`},
+		{`{"id":"17","result":{}}`, `{"id":3,"result":{}}`},
+		{`{"method":"notifications/initialized"}`, `{"method":"notifications/initialized"}`},
+	} {
+		if got := string(normalizeMessage([]byte(tt.in), ids)); got != tt.want {
+			t.Errorf("normalizeMessage(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLatencyAndReordering(t *testing.T) {
+	clientTransport, serverTransport := NewInMemoryTransports(
+		WithLatency(time.Millisecond, 0),
+		WithReordering(0.5, 20*time.Millisecond),
+		WithSeed(7),
+	)
+	ctx := context.Background()
+	c, err := clientTransport.Dial(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := serverTransport.Dial(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		// Split writes must still be framed as whole messages.
+		msg, _ := json.Marshal(map[string]int{"i": i})
+		c.Write(msg[:2])
+		c.Write(append(msg[2:], '\n'))
+	}
+	var order []int
+	buf := make([]byte, 64)
+	var pending []byte
+	for len(order) < n {
+		k, err := s.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending = append(pending, buf[:k]...)
+		for {
+			i := bytes.IndexByte(pending, '\n')
+			if i < 0 {
+				break
+			}
+			var m map[string]int
+			if err := json.Unmarshal(pending[:i], &m); err != nil {
+				t.Fatalf("bad message %q: %v", pending[:i], err)
+			}
+			order = append(order, m["i"])
+			pending = pending[i+1:]
+		}
+	}
+	if time.Since(start) < time.Millisecond {
+		t.Error("messages arrived without latency")
+	}
+	sorted := true
+	for i := 1; i < n; i++ {
+		sorted = sorted && order[i] > order[i-1]
+	}
+	if sorted {
+		t.Errorf("order = %v, want some reordering", order)
+	}
+
+	c.Close()
+	if _, err := s.Read(buf); err == nil {
+		t.Error("Read after peer Close succeeded")
+	}
+}
+
+func TestDropFunc(t *testing.T) {
+	var dropped atomic.Int32
+	var once sync.Once
+	server := newEchoServer(t)
+	// Drop the first tools/list request; the client's retry succeeds.
+	client := Connect(t, server, WithDropFunc(func(dir Direction, msg []byte) bool {
+		drop := false
+		if dir == ClientToServer && bytes.Contains(msg, []byte(`"tools/list"`)) {
+			once.Do(func() { drop = true; dropped.Add(1) })
+		}
+		return drop
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.ListTools(ctx, mcp.ListToolsRequest{}); err == nil {
+		t.Fatal("ListTools succeeded although its request was dropped")
+	}
+	if _, err := client.ListTools(context.Background(), mcp.ListToolsRequest{}); err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if dropped.Load() != 1 {
+		t.Errorf("dropped %d messages, want 1", dropped.Load())
+	}
+}
+
+// fatalRecorder records the message of a Fatalf, which ends the calling
+// goroutine, and the cleanups registered before it.
+type fatalRecorder struct {
+	errorRecorder
+	cleanups []func()
+}
+
+func (r *fatalRecorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func (r *fatalRecorder) Cleanup(fn func())   { r.cleanups = append(r.cleanups, fn) }
+func (r *fatalRecorder) Logf(string, ...any) {}
+
+func TestConnectInitializeTimeout(t *testing.T) {
+	r := &fatalRecorder{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Connect(r, newEchoServer(t), WithInitializeTimeout(50*time.Millisecond), WithDropFunc(func(dir Direction, msg []byte) bool {
+			return dir == ServerToClient
+		}))
+	}()
+	<-done
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+	if len(r.errs) != 1 || !strings.Contains(r.errs[0], "no response within 50ms") {
+		t.Errorf("Connect with the handshake dropped reported %q, want a timeout", r.errs)
+	}
+}
@@ -0,0 +1,594 @@
+package mcptest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tmc/mcp"
+)
+
+// ReplayTransport is an mcp.Transport that records a session to an .mcp
+// trace or replays one to a live client.
+//
+// In record mode it wraps a real transport and writes every message to the
+// trace: "mcp-recv" lines are sent by the client and "mcp-send" lines by the
+// server, each followed by a " # seconds.millis" timestamp. This is the
+// format written by mcpd and mcpspy and read by mcp-replay and mcpdiff.
+//
+// In replay mode no server is involved. Each request the client sends is
+// matched against the recorded requests by method and params, ignoring
+// request order, JSON-RPC IDs and params._meta; initialize requests match by
+// method alone. The recorded response is returned with the live ID, along
+// with any notifications and server requests recorded while it was
+// outstanding; progress notifications for the recorded progress token carry
+// the live one. Each recorded request is answered once. Requests with no
+// recorded match get a JSON-RPC error; Check reports them, and the recorded
+// requests that were never made, as test failures.
+type ReplayTransport struct {
+	// record mode
+	inner mcp.Transport
+	w     io.Writer
+	wmu   sync.Mutex
+	werr  error
+
+	// replay mode
+	mu           sync.Mutex
+	preamble     [][]byte
+	interactions []*interaction
+	unmatched    []string
+}
+
+// interaction is one recorded request and the server messages it produced.
+type interaction struct {
+	method  string
+	key     string
+	desc    string
+	token   json.RawMessage // params._meta.progressToken, if any
+	replies []reply         // server messages, in trace order
+	used    bool
+}
+
+type reply struct {
+	data     []byte
+	response bool // the response to the interaction's request
+}
+
+// NewReplayTransport returns a transport for the trace at path. When
+// MCPTEST_UPDATE=1 is set it records a session over record into path;
+// otherwise it replays path, and the test fails at cleanup if any requests
+// went unmatched or recorded requests went unused.
+func NewReplayTransport(t testing.TB, path string, record mcp.Transport) *ReplayTransport {
+	t.Helper()
+	if os.Getenv(UpdateEnv) == "1" {
+		if record == nil {
+			t.Fatalf("mcptest: %s=1 but no transport to record %s from", UpdateEnv, path)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mcptest: %v", err)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("mcptest: %v", err)
+		}
+		rt := NewRecorder(record, f)
+		t.Cleanup(func() {
+			rt.Check(t)
+			if err := f.Close(); err != nil {
+				t.Errorf("mcptest: %v", err)
+			}
+		})
+		return rt
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("mcptest: %v (run with %s=1 to record it)", err, UpdateEnv)
+	}
+	defer f.Close()
+	rt, err := NewReplayer(f)
+	if err != nil {
+		t.Fatalf("mcptest: %s: %v", path, err)
+	}
+	t.Cleanup(func() { rt.Check(t) })
+	return rt
+}
+
+// NewRecorder returns a transport that dials inner and writes the messages
+// exchanged over it to w.
+func NewRecorder(inner mcp.Transport, w io.Writer) *ReplayTransport {
+	return &ReplayTransport{inner: inner, w: w}
+}
+
+// NewReplayer returns a transport that answers requests from the trace read
+// from r.
+func NewReplayer(r io.Reader) (*ReplayTransport, error) {
+	lines, err := readTrace(r)
+	if err != nil {
+		return nil, err
+	}
+	rt := &ReplayTransport{}
+	outstanding := make(map[string]*interaction)
+	var current *interaction
+	for _, l := range lines {
+		var msg wireMessage
+		if err := json.Unmarshal(l.data, &msg); err != nil {
+			return nil, fmt.Errorf("line %d: %v", l.num, err)
+		}
+		id := msg.idKey()
+		if !l.fromServer {
+			if msg.Method == "" || id == "" {
+				// Notifications and replies to server requests need no answer.
+				continue
+			}
+			current = &interaction{
+				method: msg.Method,
+				key:    matchKey(msg.Method, msg.Params),
+				desc:   describe(msg.Method, msg.Params),
+				token:  progressToken(msg.Params),
+			}
+			rt.interactions = append(rt.interactions, current)
+			outstanding[id] = current
+			continue
+		}
+		if msg.Method == "" && id != "" {
+			in, ok := outstanding[id]
+			if !ok {
+				return nil, fmt.Errorf("line %d: response to unknown request %s", l.num, id)
+			}
+			delete(outstanding, id)
+			in.replies = append(in.replies, reply{data: l.data, response: true})
+			continue
+		}
+		if current == nil {
+			rt.preamble = append(rt.preamble, l.data)
+			continue
+		}
+		current.replies = append(current.replies, reply{data: l.data})
+	}
+	return rt, nil
+}
+
+// Dial implements mcp.Transport.
+func (rt *ReplayTransport) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	if rt.inner != nil {
+		conn, err := rt.inner.Dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &recordConn{ReadWriteCloser: conn, rt: rt}, nil
+	}
+	c := &replayConn{rt: rt, in: newInbox()}
+	rt.mu.Lock()
+	for _, msg := range rt.preamble {
+		c.in.push(append(append([]byte(nil), msg...), '\n'))
+	}
+	rt.mu.Unlock()
+	return c, nil
+}
+
+// Check reports unmatched requests and unused recorded requests as errors
+// on t. In record mode it reports failures to write the trace.
+func (rt *ReplayTransport) Check(t testing.TB) {
+	t.Helper()
+	if rt.inner != nil {
+		rt.wmu.Lock()
+		defer rt.wmu.Unlock()
+		if rt.werr != nil {
+			t.Errorf("mcptest: writing trace: %v", rt.werr)
+		}
+		return
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	for _, desc := range rt.unmatched {
+		t.Errorf("mcptest: no recorded response for %s", desc)
+	}
+	for _, in := range rt.interactions {
+		if !in.used {
+			t.Errorf("mcptest: recorded %s was never requested", in.desc)
+		}
+	}
+}
+
+// answer returns the messages to send in reply to a request from the client.
+func (rt *ReplayTransport) answer(msg wireMessage) [][]byte {
+	key := matchKey(msg.Method, msg.Params)
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	for _, in := range rt.interactions {
+		if in.used || in.method != msg.Method || in.key != key {
+			continue
+		}
+		in.used = true
+		token := progressToken(msg.Params)
+		out := make([][]byte, 0, len(in.replies))
+		for _, r := range in.replies {
+			data := r.data
+			if r.response {
+				data = withID(data, msg.ID)
+			} else if in.token != nil && token != nil {
+				data = withProgressToken(data, in.token, token)
+			}
+			out = append(out, data)
+		}
+		return out
+	}
+	desc := describe(msg.Method, msg.Params)
+	rt.unmatched = append(rt.unmatched, desc)
+	resp, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      msg.ID,
+		"error":   map[string]any{"code": -32603, "message": "mcptest: no recorded response for " + desc},
+	})
+	return [][]byte{resp}
+}
+
+// replayConn is the client's connection in replay mode.
+type replayConn struct {
+	rt *ReplayTransport
+	in *inbox
+
+	wmu     sync.Mutex
+	partial []byte
+
+	rmu    sync.Mutex
+	unread []byte
+}
+
+func (c *replayConn) Read(p []byte) (int, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	if len(c.unread) == 0 {
+		msg, ok := c.in.pop()
+		if !ok {
+			return 0, io.EOF
+		}
+		c.unread = msg
+	}
+	n := copy(p, c.unread)
+	c.unread = c.unread[n:]
+	return n, nil
+}
+
+func (c *replayConn) Write(p []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.in.isClosed() {
+		return 0, io.ErrClosedPipe
+	}
+	c.partial = append(c.partial, p...)
+	for {
+		i := bytes.IndexByte(c.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimSpace(c.partial[:i])
+		c.partial = c.partial[i+1:]
+		var msg wireMessage
+		if len(line) == 0 || json.Unmarshal(line, &msg) != nil || msg.Method == "" || msg.idKey() == "" {
+			continue
+		}
+		for _, out := range c.rt.answer(msg) {
+			c.in.push(append(append([]byte(nil), out...), '\n'))
+		}
+	}
+	return len(p), nil
+}
+
+func (c *replayConn) Close() error {
+	c.in.close()
+	return nil
+}
+
+// recordConn copies the messages exchanged over a connection to the trace.
+type recordConn struct {
+	io.ReadWriteCloser
+	rt *ReplayTransport
+
+	rpartial []byte // guarded by the reader
+	wpartial []byte // guarded by the writer
+}
+
+func (c *recordConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	c.rpartial = c.rt.record("mcp-send", c.rpartial, p[:n])
+	return n, err
+}
+
+func (c *recordConn) Write(p []byte) (int, error) {
+	c.wpartial = c.rt.record("mcp-recv", c.wpartial, p)
+	return c.ReadWriteCloser.Write(p)
+}
+
+// record writes the complete lines of partial+p to the trace and returns the
+// incomplete remainder.
+func (rt *ReplayTransport) record(prefix string, partial, p []byte) []byte {
+	partial = append(partial, p...)
+	rt.wmu.Lock()
+	defer rt.wmu.Unlock()
+	for {
+		i := bytes.IndexByte(partial, '\n')
+		if i < 0 {
+			return partial
+		}
+		line := bytes.TrimSpace(partial[:i])
+		partial = partial[i+1:]
+		if len(line) == 0 || rt.werr != nil {
+			continue
+		}
+		now := time.Now()
+		_, rt.werr = fmt.Fprintf(rt.w, "%s %s # %d.%03d\n", prefix, line, now.Unix(), now.Nanosecond()/int(time.Millisecond))
+	}
+}
+
+// ReplayServer sends the client messages recorded in the trace at path to
+// server, in trace order, and fails t if any response differs from the
+// recorded one. Responses are compared after the normalization Transcript
+// applies, so timestamps may differ; notifications from the server are
+// ignored.
+func ReplayServer(t testing.TB, server *mcp.Server, path string) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("mcptest: %v", err)
+	}
+	lines, err := readTrace(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("mcptest: %s: %v", path, err)
+	}
+
+	clientTransport, serverTransport := NewInMemoryTransports()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, serverTransport) }()
+	conn, err := clientTransport.Dial(ctx)
+	if err != nil {
+		t.Fatalf("mcptest: %v", err)
+	}
+	defer func() {
+		conn.Close()
+		cancel()
+		<-served
+	}()
+
+	live := newMailboxes()
+	go func() {
+		scanner := bufio.NewScanner(conn)
+		scanner.Buffer(nil, maxTraceLine)
+		for scanner.Scan() {
+			var msg wireMessage
+			if json.Unmarshal(scanner.Bytes(), &msg) != nil || msg.idKey() == "" {
+				continue
+			}
+			kind := "response"
+			if msg.Method != "" {
+				kind = "request"
+			}
+			live.get(kind + " " + msg.idKey()) <- append([]byte(nil), scanner.Bytes()...)
+		}
+	}()
+
+	// Wait for each live message at the point its recorded counterpart
+	// appears in the trace, so that the server sees the same interleaving.
+	wait := func(kind, id string) ([]byte, bool) {
+		select {
+		case msg := <-live.get(kind + " " + id):
+			return msg, true
+		case <-time.After(5 * time.Second):
+			t.Errorf("mcptest: %s: timed out waiting for %s %s", path, kind, id)
+			return nil, false
+		}
+	}
+	for _, l := range lines {
+		var msg wireMessage
+		if err := json.Unmarshal(l.data, &msg); err != nil {
+			t.Fatalf("mcptest: %s:%d: %v", path, l.num, err)
+		}
+		id := msg.idKey()
+		switch {
+		case id == "":
+			if !l.fromServer {
+				send(t, conn, l.data)
+			}
+		case l.fromServer && msg.Method != "":
+			wait("request", id)
+		case l.fromServer:
+			got, ok := wait("response", id)
+			if !ok {
+				return
+			}
+			g := normalizeMessage(got, make(map[string]int))
+			w := normalizeMessage(l.data, make(map[string]int))
+			if !bytes.Equal(g, w) {
+				t.Errorf("mcptest: %s:%d: response differs:\ngot:  %s\nwant: %s", path, l.num, g, w)
+			}
+		default:
+			send(t, conn, l.data)
+		}
+	}
+}
+
+func send(t testing.TB, w io.Writer, msg []byte) {
+	t.Helper()
+	if _, err := w.Write(append(append([]byte(nil), msg...), '\n')); err != nil {
+		t.Fatalf("mcptest: %v", err)
+	}
+}
+
+// mailboxes hands live messages to whoever waits for them by key.
+type mailboxes struct {
+	mu sync.Mutex
+	m  map[string]chan []byte
+}
+
+func newMailboxes() *mailboxes {
+	return &mailboxes{m: make(map[string]chan []byte)}
+}
+
+func (b *mailboxes) get(key string) chan []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch, ok := b.m[key]
+	if !ok {
+		ch = make(chan []byte, 1)
+		b.m[key] = ch
+	}
+	return ch
+}
+
+// maxTraceLine bounds the length of one message in a trace.
+const maxTraceLine = 16 << 20
+
+// traceTimestamp matches the timestamp comment ending a trace line.
+var traceTimestamp = regexp.MustCompile(`\s+#\s*\d+(?:\.\d+)?$`)
+
+type traceLine struct {
+	num        int
+	fromServer bool
+	data       []byte
+}
+
+// readTrace parses an .mcp trace. Blank lines and lines starting with # are
+// skipped.
+func readTrace(r io.Reader) ([]traceLine, error) {
+	var lines []traceLine
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxTraceLine)
+	for num := 1; scanner.Scan(); num++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prefix, rest, _ := strings.Cut(line, " ")
+		var fromServer bool
+		switch prefix {
+		case "mcp-recv":
+		case "mcp-send":
+			fromServer = true
+		default:
+			return nil, fmt.Errorf("line %d: want mcp-recv or mcp-send, got %q", num, prefix)
+		}
+		data := []byte(traceTimestamp.ReplaceAllString(strings.TrimSpace(rest), ""))
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("line %d: invalid JSON", num)
+		}
+		lines = append(lines, traceLine{num: num, fromServer: fromServer, data: data})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// wireMessage holds the fields of a JSON-RPC message used for matching.
+type wireMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// idKey returns the message ID as a string, or "" if it has none.
+func (m wireMessage) idKey() string {
+	if len(m.ID) == 0 || string(m.ID) == "null" {
+		return ""
+	}
+	return string(m.ID)
+}
+
+// matchKey returns params in canonical form for matching: object keys are
+// sorted and params._meta is dropped. Initialize requests match by method
+// alone, so that client versions may change without re-recording.
+func matchKey(method string, params json.RawMessage) string {
+	if method == string(mcp.MethodInitialize) || len(params) == 0 {
+		return ""
+	}
+	var v any
+	if json.Unmarshal(params, &v) != nil {
+		return string(params)
+	}
+	if m, ok := v.(map[string]any); ok {
+		delete(m, "_meta")
+		if len(m) == 0 {
+			return ""
+		}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return string(params)
+	}
+	return string(data)
+}
+
+func describe(method string, params json.RawMessage) string {
+	if key := matchKey(method, params); key != "" {
+		return method + " " + key
+	}
+	return method
+}
+
+// withID returns the JSON-RPC message msg with its ID replaced by id.
+func withID(msg []byte, id json.RawMessage) []byte {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &m); err != nil {
+		return msg
+	}
+	m["id"] = id
+	data, err := json.Marshal(m)
+	if err != nil {
+		return msg
+	}
+	return data
+}
+
+// progressToken returns params._meta.progressToken in compact form, or nil.
+func progressToken(params json.RawMessage) json.RawMessage {
+	var p struct {
+		Meta struct {
+			ProgressToken json.RawMessage `json:"progressToken"`
+		} `json:"_meta"`
+	}
+	if json.Unmarshal(params, &p) != nil || len(p.Meta.ProgressToken) == 0 || string(p.Meta.ProgressToken) == "null" {
+		return nil
+	}
+	var b bytes.Buffer
+	if json.Compact(&b, p.Meta.ProgressToken) != nil {
+		return nil
+	}
+	return b.Bytes()
+}
+
+// withProgressToken returns msg with its progress token replaced by to if
+// msg is a progress notification for the token from.
+func withProgressToken(msg []byte, from, to json.RawMessage) []byte {
+	var m struct {
+		Method string                     `json:"method"`
+		Params map[string]json.RawMessage `json:"params"`
+	}
+	if json.Unmarshal(msg, &m) != nil || m.Method != string(mcp.MethodProgress) {
+		return msg
+	}
+	var b bytes.Buffer
+	if json.Compact(&b, m.Params["progressToken"]) != nil || !bytes.Equal(b.Bytes(), from) {
+		return msg
+	}
+	m.Params["progressToken"] = to
+	data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "method": m.Method, "params": m.Params})
+	if err != nil {
+		return msg
+	}
+	return data
+}
+
+var _ mcp.Transport = (*ReplayTransport)(nil)
@@ -0,0 +1,141 @@
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tmc/mcp"
+)
+
+// errorRecorder collects the errors reported to it instead of failing.
+type errorRecorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *errorRecorder) Helper() {}
+
+func (r *errorRecorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestReplayTransport(t *testing.T) {
+	// The live server is only dialed when recording with MCPTEST_UPDATE=1.
+	clientTransport, serverTransport := NewInMemoryTransports()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = newEchoServer(t).Serve(ctx, serverTransport) }()
+
+	rt := NewReplayTransport(t, "testdata/echo.mcp", clientTransport)
+	client, err := mcp.NewClient(rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	init, err := client.Initialize(ctx, mcp.InitializeRequest{
+		ClientInfo:      mcp.Implementation{Name: "mcptest", Version: "0.0.0"},
+		ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
+	})
+	if err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if init.ServerInfo.Name != "echo" {
+		t.Errorf("ServerInfo = %+v", init.ServerInfo)
+	}
+	result, err := client.CallTool(ctx, mcp.CallToolRequest{Name: "echo", Arguments: json.RawMessage(`{"a":1}`)})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; text != `{"a":1}` {
+		t.Errorf("CallTool text = %v", text)
+	}
+	tools, err := client.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil || len(tools.Tools) != 1 {
+		t.Fatalf("ListTools = %+v, %v", tools, err)
+	}
+}
+
+func TestReplayServer(t *testing.T) {
+	ReplayServer(t, newEchoServer(t), "testdata/echo.mcp")
+}
+
+const matchTrace = `
+# Two calls answered out of order, one with progress.
+mcp-recv {"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"old","version":"0.1"}}} # 1683000000.000
+mcp-send {"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-06-18","serverInfo":{"name":"github","version":"1.0"},"capabilities":{"tools":{}}}} # 1683000000.010
+mcp-recv {"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"search","arguments":{"q":"a","limit":1}}} # 1683000000.100
+mcp-recv {"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search","arguments":{"q":"b"},"_meta":{"progressToken":7}}} # 1683000000.110
+mcp-send {"jsonrpc":"2.0","method":"notifications/progress","params":{"progressToken":7,"progress":1}} # 1683000000.150
+mcp-send {"jsonrpc":"2.0","id":3,"result":{"content":[{"type":"text","text":"b"}]}}
+mcp-send {"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"a"}]}}
+mcp-recv {"jsonrpc":"2.0","id":4,"method":"prompts/list"}
+mcp-send {"jsonrpc":"2.0","id":4,"result":{"prompts":[]}}
+`
+
+func TestReplayMatching(t *testing.T) {
+	rt, err := NewReplayer(strings.NewReader(matchTrace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := make(chan string, 1)
+	client, err := mcp.NewClient(rt, mcp.WithNotificationHandler(func(n mcp.JSONRPCNotification) {
+		if n.Method == string(mcp.MethodProgress) {
+			progress <- string(n.Params)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+	if _, err := client.Initialize(ctx, mcp.InitializeRequest{ClientInfo: mcp.Implementation{Name: "new", Version: "2.0"}}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	call := func(args string, meta map[string]any) (string, error) {
+		res, err := client.CallTool(ctx, mcp.CallToolRequest{Name: "search", Arguments: json.RawMessage(args), Meta: meta})
+		if err != nil {
+			return "", err
+		}
+		return res.Content[0].(mcp.TextContent).Text, nil
+	}
+	// Recorded second, with a different progress token, which the recorded
+	// progress notification is given; key order differs.
+	if text, err := call(`{"q":"b"}`, map[string]any{"progressToken": "live"}); err != nil || text != "b" {
+		t.Errorf("call b = %q, %v", text, err)
+	}
+	if got := <-progress; got != `{"progress":1,"progressToken":"live"}` {
+		t.Errorf("progress = %s", got)
+	}
+	if text, err := call(`{"limit":1,"q":"a"}`, nil); err != nil || text != "a" {
+		t.Errorf("call a = %q, %v", text, err)
+	}
+	if _, err := call(`{"q":"c"}`, nil); err == nil {
+		t.Error("unrecorded call succeeded")
+	}
+
+	rec := &errorRecorder{TB: t}
+	rt.Check(rec)
+	want := []string{
+		`mcptest: no recorded response for tools/call {"arguments":{"q":"c"},"name":"search"}`,
+		`mcptest: recorded prompts/list was never requested`,
+	}
+	if strings.Join(rec.errs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Check reported:\n%s\nwant:\n%s", strings.Join(rec.errs, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadTraceErrors(t *testing.T) {
+	for _, trace := range []string{
+		`mcp-peek {"id":1} # 1`,
+		`mcp-recv {"id": # 1`,
+		`mcp-send {"jsonrpc":"2.0","id":9,"result":{}}`,
+	} {
+		if _, err := NewReplayer(strings.NewReader(trace)); err == nil {
+			t.Errorf("NewReplayer(%q) succeeded", trace)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package mcptest
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// maxArgumentSets bounds how many argument sets are generated per tool.
+const maxArgumentSets = 64
+
+// maxGeneratedLen caps strings and arrays sized from minLength, maxLength,
+// minItems and maxItems.
+const maxGeneratedLen = 64 << 10
+
+// schema is a decoded JSON Schema node.
+type schema map[string]any
+
+func (s schema) types() []string {
+	switch t := s["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var out []string
+		for _, v := range t {
+			if name, ok := v.(string); ok {
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+	if _, ok := s["properties"]; ok {
+		return []string{"object"}
+	}
+	if _, ok := s["items"]; ok {
+		return []string{"array"}
+	}
+	return nil
+}
+
+// primaryType is the first non-null type of s, or "" if it has none.
+func (s schema) primaryType() string {
+	for _, t := range s.types() {
+		if t != "null" {
+			return t
+		}
+	}
+	return ""
+}
+
+func (s schema) child(key string) schema {
+	if m, ok := s[key].(map[string]any); ok {
+		return schema(m)
+	}
+	return schema{}
+}
+
+func (s schema) properties() map[string]schema {
+	props, _ := s["properties"].(map[string]any)
+	out := make(map[string]schema, len(props))
+	for name, p := range props {
+		if m, ok := p.(map[string]any); ok {
+			out[name] = schema(m)
+		} else {
+			out[name] = schema{}
+		}
+	}
+	return out
+}
+
+func (s schema) required() []string {
+	var out []string
+	list, _ := s["required"].([]any)
+	for _, v := range list {
+		if name, ok := v.(string); ok {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func (s schema) number(key string) (float64, bool) {
+	f, ok := s[key].(float64)
+	return f, ok
+}
+
+// GenerateArguments returns tools/call argument objects for a tool with the
+// given input schema: valid values first, then boundary values for each
+// property, then adversarial inputs that violate the schema (wrong types,
+// missing required properties, unknown properties, oversized values).
+func GenerateArguments(inputSchema json.RawMessage) []json.RawMessage {
+	var s schema
+	if err := json.Unmarshal(inputSchema, &s); err != nil || s == nil {
+		s = schema{"type": "object"}
+	}
+	var sets []any
+	add := func(v any) {
+		if len(sets) < maxArgumentSets {
+			sets = append(sets, v)
+		}
+	}
+
+	// Valid: every property, then only the required ones.
+	full := sampleObject(s, 0, true)
+	add(full)
+	add(sampleObject(s, 0, false))
+
+	// Boundary: one property at a time at the edges of its schema.
+	props := s.properties()
+	for _, name := range slices.Sorted(maps.Keys(props)) {
+		for _, v := range boundaryValues(props[name]) {
+			add(with(full, name, v))
+		}
+	}
+
+	// Adversarial.
+	for _, name := range s.required() {
+		add(without(full, name))
+	}
+	for _, name := range slices.Sorted(maps.Keys(props)) {
+		add(with(full, name, wrongType(props[name])))
+		add(with(full, name, nil))
+	}
+	add(with(full, "__proto__", map[string]any{"polluted": true}))
+	add(with(full, "mcptest_unknown", strings.Repeat("x", 1<<10)))
+	add(nested(64))
+	add([]any{full})
+	add("arguments")
+
+	out := make([]json.RawMessage, 0, len(sets))
+	for _, v := range sets {
+		data, err := json.Marshal(v)
+		if err == nil {
+			out = append(out, data)
+		}
+	}
+	return out
+}
+
+// sampleValue returns a value that satisfies s where it can.
+func sampleValue(s schema, depth int) any {
+	if v, ok := s["const"]; ok {
+		return v
+	}
+	if enum, ok := s["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	if v, ok := s["default"]; ok {
+		return v
+	}
+	if examples, ok := s["examples"].([]any); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		if alts, ok := s[key].([]any); ok && len(alts) > 0 {
+			if m, ok := alts[0].(map[string]any); ok {
+				return sampleValue(schema(m), depth)
+			}
+		}
+	}
+	switch s.primaryType() {
+	case "string":
+		return sampleString(s)
+	case "integer":
+		return math.Trunc(sampleNumber(s, 1))
+	case "number":
+		return sampleNumber(s, 1.5)
+	case "boolean":
+		return true
+	case "array":
+		n := 1
+		if min, ok := s.number("minItems"); ok && int(min) > n && min <= maxGeneratedLen {
+			n = int(min)
+		}
+		if max, ok := s.number("maxItems"); ok && int(max) < n {
+			n = int(max)
+		}
+		items := make([]any, n)
+		for i := range items {
+			items[i] = sampleValue(s.child("items"), depth+1)
+		}
+		return items
+	case "object":
+		return sampleObject(s, depth+1, false)
+	case "null":
+		return nil
+	}
+	return "sample"
+}
+
+func sampleString(s schema) string {
+	var v string
+	switch s["format"] {
+	case "date-time":
+		v = "2025-01-02T03:04:05Z"
+	case "date":
+		v = "2025-01-02"
+	case "uri", "url":
+		v = "https://example.com/a"
+	case "email":
+		v = "user@example.com"
+	case "uuid":
+		v = "123e4567-e89b-12d3-a456-426614174000"
+	default:
+		v = "sample"
+	}
+	if min, ok := s.number("minLength"); ok && len(v) < int(min) && min <= maxGeneratedLen {
+		v += strings.Repeat("a", int(min)-len(v))
+	}
+	if max, ok := s.number("maxLength"); ok && len(v) > int(max) {
+		v = v[:int(max)]
+	}
+	return v
+}
+
+func sampleNumber(s schema, fallback float64) float64 {
+	v := fallback
+	if min, ok := s.number("minimum"); ok && v < min {
+		v = min
+	}
+	if min, ok := s.number("exclusiveMinimum"); ok && v <= min {
+		v = min + 1
+	}
+	if max, ok := s.number("maximum"); ok && v > max {
+		v = max
+	}
+	if max, ok := s.number("exclusiveMaximum"); ok && v >= max {
+		v = max - 1
+	}
+	return v
+}
+
+// sampleObject fills the required properties of s, or all of them if all is
+// set. Nesting stops at a fixed depth so recursive schemas terminate.
+func sampleObject(s schema, depth int, all bool) map[string]any {
+	out := make(map[string]any)
+	if depth > 4 {
+		return out
+	}
+	props := s.properties()
+	names := s.required()
+	if all {
+		names = slices.Sorted(maps.Keys(props))
+	}
+	for _, name := range names {
+		out[name] = sampleValue(props[name], depth)
+	}
+	return out
+}
+
+// boundaryValues returns values at and just past the edges of s.
+func boundaryValues(s schema) []any {
+	var out []any
+	if enum, ok := s["enum"].([]any); ok {
+		out = append(out, enum...)
+		return append(out, "mcptest-not-in-enum")
+	}
+	switch s.primaryType() {
+	case "string":
+		out = append(out, "", " ", "\x00", "💥‮", strings.Repeat("a", 64<<10))
+		if min, ok := s.number("minLength"); ok && min > 0 && min <= maxGeneratedLen {
+			out = append(out, strings.Repeat("a", int(min)-1), strings.Repeat("a", int(min)))
+		}
+		if max, ok := s.number("maxLength"); ok && max <= maxGeneratedLen {
+			out = append(out, strings.Repeat("a", int(max)), strings.Repeat("a", int(max)+1))
+		}
+		if format, ok := s["format"].(string); ok {
+			out = append(out, "not-a-"+strings.ReplaceAll(format, "-", ""))
+		}
+	case "integer", "number":
+		out = append(out, 0, -1, math.MaxInt64, math.MinInt64, 1e308, -1e308, 2.5)
+		for _, key := range []string{"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum"} {
+			if v, ok := s.number(key); ok {
+				out = append(out, v-1, v, v+1)
+			}
+		}
+	case "boolean":
+		out = append(out, false)
+	case "array":
+		out = append(out, []any{})
+		item := sampleValue(s.child("items"), 1)
+		if max, ok := s.number("maxItems"); ok && max <= maxGeneratedLen {
+			items := make([]any, int(max)+1)
+			for i := range items {
+				items[i] = item
+			}
+			out = append(out, items)
+		}
+		out = append(out, []any{item, item, nil})
+	case "object":
+		out = append(out, map[string]any{}, sampleObject(s, 1, true))
+	}
+	return out
+}
+
+// wrongType returns a value of a type s does not allow.
+func wrongType(s schema) any {
+	switch s.primaryType() {
+	case "string":
+		return 12345
+	case "integer", "number":
+		return "12"
+	case "boolean":
+		return "true"
+	case "array":
+		return map[string]any{"0": "x"}
+	case "object":
+		return []any{"x"}
+	}
+	return map[string]any{"unexpected": []any{1, "two", nil}}
+}
+
+func with(m map[string]any, key string, v any) map[string]any {
+	out := make(map[string]any, len(m)+1)
+	for k, val := range m {
+		out[k] = val
+	}
+	out[key] = v
+	return out
+}
+
+func without(m map[string]any, key string) map[string]any {
+	out := with(m, key, nil)
+	delete(out, key)
+	return out
+}
+
+// nested returns an object nested depth levels deep.
+func nested(depth int) any {
+	var v any = "deep"
+	for range depth {
+		v = map[string]any{"a": v}
+	}
+	return v
+}
@@ -0,0 +1,153 @@
+package mcptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// UpdateEnv names the environment variable that, when set to 1, makes
+// Transcript.Compare rewrite golden files instead of comparing against them.
+const UpdateEnv = "MCPTEST_UPDATE"
+
+// Message is one message recorded by a Transcript.
+type Message struct {
+	Direction Direction
+	Data      json.RawMessage
+}
+
+// Transcript records the messages sent over an in-memory link, in the order
+// they were sent.
+type Transcript struct {
+	mu   sync.Mutex
+	msgs []Message
+}
+
+// NewTranscript returns an empty Transcript.
+func NewTranscript() *Transcript {
+	return &Transcript{}
+}
+
+func (tr *Transcript) record(dir Direction, msg []byte) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.msgs = append(tr.msgs, Message{Direction: dir, Data: append(json.RawMessage(nil), msg...)})
+}
+
+// Messages returns the recorded messages.
+func (tr *Transcript) Messages() []Message {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append([]Message(nil), tr.msgs...)
+}
+
+// Normalized renders the transcript in the mcptrace line format used by the
+// repo's .mcp files: "mcp-recv" lines are client requests and "mcp-send"
+// lines server replies. Object keys are sorted, JSON-RPC IDs are renumbered
+// in order of first use, and RFC 3339 timestamps are replaced with
+// "<time>", so that the output is stable across runs.
+func (tr *Transcript) Normalized() []byte {
+	ids := make(map[string]int)
+	var buf bytes.Buffer
+	for _, m := range tr.Messages() {
+		prefix := "mcp-recv"
+		if m.Direction == ServerToClient {
+			prefix = "mcp-send"
+		}
+		fmt.Fprintf(&buf, "%s %s\n", prefix, normalizeMessage(m.Data, ids))
+	}
+	return buf.Bytes()
+}
+
+func normalizeMessage(data []byte, ids map[string]int) []byte {
+	var msg map[string]any
+	if json.Unmarshal(data, &msg) != nil {
+		return data
+	}
+	if id, ok := msg["id"]; ok && id != nil {
+		// Key on the ID as JSON so that 17 and "17", which are different
+		// IDs, are not renumbered alike.
+		var raw struct {
+			ID json.RawMessage `json:"id"`
+		}
+		json.Unmarshal(data, &raw)
+		var key bytes.Buffer
+		json.Compact(&key, raw.ID)
+		n, ok := ids[key.String()]
+		if !ok {
+			n = len(ids) + 1
+			ids[key.String()] = n
+		}
+		msg["id"] = n
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(normalizeValue(msg)); err != nil {
+		return data
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+}
+
+func normalizeValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = normalizeValue(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = normalizeValue(child)
+		}
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return "<time>"
+		}
+	}
+	return v
+}
+
+// Compare fails t if the normalized transcript differs from the golden file.
+// With MCPTEST_UPDATE=1 in the environment it writes the golden file
+// instead.
+func (tr *Transcript) Compare(t testing.TB, golden string) {
+	t.Helper()
+	got := tr.Normalized()
+	if os.Getenv(UpdateEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatalf("mcptest: %v", err)
+		}
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatalf("mcptest: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("mcptest: %v (run with %s=1 to create it)", err, UpdateEnv)
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+	gotLines := strings.Split(string(got), "\n")
+	wantLines := strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			t.Errorf("mcptest: transcript differs from %s at line %d:\ngot:  %s\nwant: %s\n(run with %s=1 to update)", golden, i+1, g, w, UpdateEnv)
+			return
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ResultBuilder assembles a CallToolResult from mixed content and
+// structured output:
+//
+//	return mcp.NewResult().
+//		Text("Rendered the chart.").
+//		Image(png, "image/png").
+//		Structured(summary).
+//		Result()
+//
+// Typed tool handlers may return a *ResultBuilder as their result.
+type ResultBuilder struct {
+	result CallToolResult
+	err    error
+}
+
+// NewResult returns an empty ResultBuilder.
+func NewResult() *ResultBuilder {
+	return &ResultBuilder{result: CallToolResult{Content: ContentList{}}}
+}
+
+// Text appends a text content block.
+func (b *ResultBuilder) Text(text string) *ResultBuilder {
+	return b.Content(TextContent{Type: "text", Text: text})
+}
+
+// Image appends an image content block.
+func (b *ResultBuilder) Image(data []byte, mimeType string) *ResultBuilder {
+	return b.Content(ImageContent{Type: "image", Data: data, MimeType: mimeType})
+}
+
+// Audio appends an audio content block.
+func (b *ResultBuilder) Audio(data []byte, mimeType string) *ResultBuilder {
+	return b.Content(AudioContent{Type: "audio", Data: data, MimeType: mimeType})
+}
+
+// ResourceLink appends a link to a resource the client can read. Its Type
+// is set to "resource_link".
+func (b *ResultBuilder) ResourceLink(link ResourceLink) *ResultBuilder {
+	link.Type = "resource_link"
+	return b.Content(link)
+}
+
+// Resource appends an embedded resource.
+func (b *ResultBuilder) Resource(contents ResourceContents) *ResultBuilder {
+	return b.Content(EmbeddedResource{Type: "resource", Resource: contents})
+}
+
+// Content appends content blocks as they are.
+func (b *ResultBuilder) Content(content ...Content) *ResultBuilder {
+	for _, c := range content {
+		b.result.Content = append(b.result.Content, c)
+	}
+	return b
+}
+
+// Structured sets the structured content of the result to the JSON
+// encoding of v. An encoding error is reported by Result.
+func (b *ResultBuilder) Structured(v any) *ResultBuilder {
+	data, err := json.Marshal(v)
+	if err != nil {
+		b.err = fmt.Errorf("failed to marshal structured content: %w", err)
+		return b
+	}
+	b.result.StructuredContent = json.RawMessage(data)
+	return b
+}
+
+// Meta sets a _meta entry of the result.
+func (b *ResultBuilder) Meta(key string, value any) *ResultBuilder {
+	if b.result.Meta == nil {
+		b.result.Meta = make(map[string]any)
+	}
+	b.result.Meta[key] = value
+	return b
+}
+
+// IsError marks the result as a tool error.
+func (b *ResultBuilder) IsError() *ResultBuilder {
+	b.result.IsError = true
+	return b
+}
+
+// Result returns the assembled result. Typed tools add the text fallback
+// for its structured content; see WithStructuredTextFallback.
+func (b *ResultBuilder) Result() (*CallToolResult, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	result := b.result
+	result.Content = append(ContentList{}, b.result.Content...)
+	return &result, nil
+}
+
+// ToolError is an error a tool reports to the model rather than to the
+// protocol layer. Returned from any tool handler, it is sent as a result
+// with IsError set, whatever the server's ToolErrorPolicy: Message becomes
+// the text content, and Message, Retryable and Details the structured
+// content. The result's _meta marks it as a ToolError so clients can tell
+// it from other error results. Err is the underlying cause; it is not sent.
+type ToolError struct {
+	Message   string
+	Retryable bool
+	Details   any
+	Err       error
+}
+
+func (e *ToolError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *ToolError) Unwrap() error { return e.Err }
+
+// toolErrorMeta is the _meta key that marks ToolError results.
+const toolErrorMeta = "io.github.tmc.mcp/toolError"
+
+// toolErrorData is the structured content of a ToolError result.
+type toolErrorData struct {
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable,omitempty"`
+	Details   any    `json:"details,omitempty"`
+}
+
+// Result returns the CallToolResult that reports e.
+func (e *ToolError) Result() *CallToolResult {
+	return &CallToolResult{
+		Content:           ContentList{TextContent{Type: "text", Text: e.Message}},
+		StructuredContent: toolErrorData{Message: e.Message, Retryable: e.Retryable, Details: e.Details},
+		IsError:           true,
+		Meta:              map[string]any{toolErrorMeta: true},
+	}
+}
+
+// toolErrorFromResult recovers the ToolError reported by an error result
+// that ToolError.Result marked as one.
+func toolErrorFromResult(result *CallToolResult) (*ToolError, bool) {
+	if !result.IsError || result.StructuredContent == nil || result.Meta[toolErrorMeta] != true {
+		return nil, false
+	}
+	raw, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		return nil, false
+	}
+	var data struct {
+		Message   string          `json:"message"`
+		Retryable bool            `json:"retryable"`
+		Details   json.RawMessage `json:"details"`
+	}
+	if json.Unmarshal(raw, &data) != nil || data.Message == "" {
+		return nil, false
+	}
+	te := &ToolError{Message: data.Message, Retryable: data.Retryable}
+	if len(data.Details) > 0 {
+		te.Details = data.Details
+	}
+	return te, true
+}
+
+// ToolErrorPolicy decides how the errors of typed tool handlers reach the
+// client. A *ToolError is always sent as an error result.
+type ToolErrorPolicy int
+
+const (
+	// ToolErrorsAsResults sends handler errors and invalid arguments as
+	// results with IsError set, so the model sees them and can correct
+	// its call. It is the default.
+	ToolErrorsAsResults ToolErrorPolicy = iota
+
+	// ToolErrorsAsProtocolErrors sends handler errors as JSON-RPC errors:
+	// invalid arguments as -32602, errors carrying a code with that code,
+	// and others as internal errors.
+	ToolErrorsAsProtocolErrors
+)
+
+// WithToolErrorPolicy sets how the errors of typed tool handlers are
+// reported. The default is ToolErrorsAsResults.
+func WithToolErrorPolicy(policy ToolErrorPolicy) ServerOption {
+	return func(s *Server) {
+		s.toolErrors = policy
+	}
+}
+
+// StructuredTextFunc returns the text sent alongside a tool's structured
+// content for clients that read only the content blocks. An empty string
+// sends no text.
+type StructuredTextFunc func(structured json.RawMessage) string
+
+// StructuredJSONText sends the structured content as its JSON encoding.
+func StructuredJSONText(structured json.RawMessage) string {
+	return string(structured)
+}
+
+// WithStructuredTextFallback sets the text content added to the results of
+// typed tools that carry structured content but no text. Results of tools
+// registered with RegisterTool are sent as their handlers built them. The
+// default is StructuredJSONText; nil adds none.
+func WithStructuredTextFallback(fn StructuredTextFunc) ServerOption {
+	return func(s *Server) {
+		s.structuredText = fn
+	}
+}
+
+// toolErrorResult reports err, returned by the typed tool name, according
+// to the server's policy.
+func (s *Server) toolErrorResult(name string, err error) (*CallToolResult, error) {
+	var te *ToolError
+	if errors.As(err, &te) {
+		return te.Result(), nil
+	}
+	if s.toolErrors == ToolErrorsAsProtocolErrors {
+		return nil, err
+	}
+	var pe *ParameterError
+	if errors.As(err, &pe) {
+		return (&ToolError{Message: fmt.Sprintf("Invalid input for tool %q: %v", name, pe.Cause)}).Result(), nil
+	}
+	return (&ToolError{Message: fmt.Sprintf("Tool %q error: %v", name, err)}).Result(), nil
+}
+
+// addStructuredText adds the text fallback to a successful result with
+// structured content and no text content.
+func (s *Server) addStructuredText(result *CallToolResult) error {
+	if result == nil || result.IsError || result.StructuredContent == nil || s.structuredText == nil {
+		return nil
+	}
+	for _, c := range result.Content {
+		if _, ok := c.(TextContent); ok {
+			return nil
+		}
+	}
+	raw, ok := result.StructuredContent.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(result.StructuredContent); err != nil {
+			return fmt.Errorf("failed to marshal structured content: %w", err)
+		}
+	}
+	if text := s.structuredText(raw); text != "" {
+		result.Content = append(result.Content, TextContent{Type: "text", Text: text})
+	}
+	return nil
+}
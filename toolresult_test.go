@@ -0,0 +1,216 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResultBuilder(t *testing.T) {
+	result, err := NewResult().
+		Text("chart").
+		Image([]byte{1, 2}, "image/png").
+		ResourceLink(ResourceLink{URI: "file:///chart.png", Name: "chart"}).
+		Structured(map[string]int{"points": 3}).
+		Meta("source", "test").
+		Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, c := range result.Content {
+		data, _ := json.Marshal(c)
+		var wire struct{ Type string }
+		json.Unmarshal(data, &wire)
+		types = append(types, wire.Type)
+	}
+	if want := []string{"text", "image", "resource_link"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("content types = %v, want %v", types, want)
+	}
+	if got := string(result.StructuredContent.(json.RawMessage)); got != `{"points":3}` {
+		t.Errorf("structured content = %s", got)
+	}
+	if result.Meta["source"] != "test" || result.IsError {
+		t.Errorf("meta = %v, isError = %v", result.Meta, result.IsError)
+	}
+
+	if _, err := NewResult().Structured(func() {}).Result(); err == nil {
+		t.Error("Result did not report an unencodable structured value")
+	}
+	empty, err := NewResult().Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := json.Marshal(empty); string(data) != `{"content":[]}` {
+		t.Errorf("empty result = %s", data)
+	}
+}
+
+type quotaArgs struct {
+	N int `json:"n"`
+}
+
+type quotaResult struct {
+	Used int `json:"used"`
+}
+
+// registerResultTools registers tools that succeed with structured output,
+// return mixed content, fail with a ToolError, and fail with a plain error.
+func registerResultTools(t *testing.T, s *Server) {
+	t.Helper()
+	errs := []error{
+		RegisterTypedToolWithServer(s, "use", "", func(ctx context.Context, args quotaArgs) (quotaResult, error) {
+			return quotaResult{Used: args.N}, nil
+		}),
+		RegisterTypedToolWithServer(s, "render", "", func(ctx context.Context, args quotaArgs) (*ResultBuilder, error) {
+			return NewResult().Image([]byte("png"), "image/png").Structured(quotaResult{Used: args.N}), nil
+		}),
+		RegisterTypedToolWithServer(s, "quota", "", func(ctx context.Context, args quotaArgs) (quotaResult, error) {
+			return quotaResult{}, &ToolError{Message: "quota exceeded", Retryable: true, Details: map[string]int{"limit": 10}, Err: errors.New("backend")}
+		}),
+		RegisterTypedToolWithServer(s, "broken", "", func(ctx context.Context, args quotaArgs) (quotaResult, error) {
+			return quotaResult{}, errors.New("disk on fire")
+		}),
+	}
+	// Untyped tools are sent as built: no text fallback, and error results
+	// are not ToolErrors unless marked as such.
+	errs = append(errs,
+		s.RegisterTool(Tool{Name: "raw", InputSchema: json.RawMessage(`{"type":"object"}`)}, func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+			return &CallToolResult{Content: ContentList{}, StructuredContent: quotaResult{Used: 1}}, nil
+		}),
+		s.RegisterTool(Tool{Name: "failed", InputSchema: json.RawMessage(`{"type":"object"}`)}, func(ctx context.Context, req CallToolRequest) (*CallToolResult, error) {
+			return &CallToolResult{Content: ContentList{TextContent{Type: "text", Text: "failed"}}, StructuredContent: map[string]string{"message": "failed"}, IsError: true}, nil
+		}),
+	)
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func connectResultTools(t *testing.T, s *Server) (context.Context, *Client) {
+	t.Helper()
+	registerResultTools(t, s)
+	clientConn, serverConn := net.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = s.Serve(ctx, &ReadWriteCloserTransport{serverConn}) }()
+	client, err := NewClient(&ReadWriteCloserTransport{clientConn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if _, err := client.Initialize(ctx, InitializeRequest{ProtocolVersion: LATEST_PROTOCOL_VERSION, ClientInfo: Implementation{Name: "c", Version: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	return ctx, client
+}
+
+func TestTypedToolErrorsAsResults(t *testing.T) {
+	ctx, client := connectResultTools(t, NewServer("results", "1.0.0"))
+
+	tools, err := client.ListTools(ctx, ListToolsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range tools.Tools {
+		if tool.Name == "raw" || tool.Name == "failed" {
+			continue
+		}
+		if hasSchema := tool.OutputSchema != nil; hasSchema == (tool.Name == "render") {
+			t.Errorf("tool %s: output schema declared = %v", tool.Name, hasSchema)
+		}
+	}
+
+	used, err := CallToolTyped[quotaArgs, quotaResult](client, ctx, "use", quotaArgs{N: 2})
+	if err != nil || used.Used != 2 {
+		t.Fatalf("use: %v, %v", used, err)
+	}
+	result, err := client.CallTool(ctx, CallToolRequest{Name: "use", Arguments: json.RawMessage(`{"n":2}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 1 || result.Content[0].(TextContent).Text != `{"used":2}` {
+		t.Errorf("use content = %#v", result.Content)
+	}
+
+	result, err = client.CallTool(ctx, CallToolRequest{Name: "render", Arguments: json.RawMessage(`{"n":3}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 2 {
+		t.Fatalf("render content = %#v, want image and text fallback", result.Content)
+	}
+	if _, ok := result.Content[0].(ImageContent); !ok {
+		t.Errorf("render content[0] = %T, want ImageContent", result.Content[0])
+	}
+
+	_, err = CallToolTyped[quotaArgs, quotaResult](client, ctx, "quota", quotaArgs{})
+	var te *ToolError
+	if !errors.As(err, &te) {
+		t.Fatalf("quota: err = %v, want a *ToolError", err)
+	}
+	if te.Message != "quota exceeded" || !te.Retryable || string(te.Details.(json.RawMessage)) != `{"limit":10}` {
+		t.Errorf("quota: tool error = %+v", te)
+	}
+
+	result, err = client.CallTool(ctx, CallToolRequest{Name: "raw", Arguments: json.RawMessage(`{}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 0 {
+		t.Errorf("raw content = %#v, want none added", result.Content)
+	}
+	_, err = CallToolTyped[quotaArgs, quotaResult](client, ctx, "failed", quotaArgs{})
+	if err == nil || errors.As(err, &te) {
+		t.Errorf("failed: err = %v, want an error that is not a *ToolError", err)
+	}
+
+	result, err = client.CallTool(ctx, CallToolRequest{Name: "broken", Arguments: json.RawMessage(`{}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].(TextContent).Text, "disk on fire") {
+		t.Errorf("broken result = %+v", result)
+	}
+
+	result, err = client.CallTool(ctx, CallToolRequest{Name: "use", Arguments: json.RawMessage(`{"n":"two"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].(TextContent).Text, "Invalid input") {
+		t.Errorf("invalid input result = %+v", result)
+	}
+}
+
+func TestTypedToolErrorsAsProtocolErrors(t *testing.T) {
+	s := NewServer("protocol", "1.0.0", WithToolErrorPolicy(ToolErrorsAsProtocolErrors), WithStructuredTextFallback(nil))
+	ctx, client := connectResultTools(t, s)
+
+	_, err := client.CallTool(ctx, CallToolRequest{Name: "broken", Arguments: json.RawMessage(`{}`)})
+	if err == nil || !strings.Contains(err.Error(), "disk on fire") {
+		t.Errorf("broken: err = %v, want a JSON-RPC error", err)
+	}
+	_, err = client.CallTool(ctx, CallToolRequest{Name: "use", Arguments: json.RawMessage(`{"n":"two"}`)})
+	if re, ok := AsResponseError(err); !ok || re.Code != -32602 {
+		t.Errorf("invalid input: err = %v, want code -32602", err)
+	}
+
+	result, err := client.CallTool(ctx, CallToolRequest{Name: "quota", Arguments: json.RawMessage(`{}`)})
+	if err != nil || !result.IsError {
+		t.Errorf("quota: result = %+v, err = %v; a ToolError is always a result", result, err)
+	}
+
+	result, err = client.CallTool(ctx, CallToolRequest{Name: "use", Arguments: json.RawMessage(`{"n":1}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 0 || result.StructuredContent == nil {
+		t.Errorf("use without text fallback = %+v", result)
+	}
+}
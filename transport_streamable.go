@@ -6,6 +6,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"iter"
@@ -18,6 +19,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"golang.org/x/exp/jsonrpc2"
 )
 
 const streamableSessionHeader = "Mcp-Session-Id"
@@ -65,11 +68,19 @@ type StreamableHTTPConfig struct {
 	// DisableLocalhostProtection disables DNS rebinding protection for local
 	// streamable HTTP servers.
 	DisableLocalhostProtection bool
+
+	// ShutdownRetry is the reconnection delay sent to SSE clients in the
+	// final event written by Shutdown. Zero selects the default (5 seconds).
+	ShutdownRetry time.Duration
 }
 
 // defaultMaxRequestBytes bounds an unconfigured POST body at 4 MiB.
 const defaultMaxRequestBytes = 4 << 20
 
+// defaultShutdownRetry is the reconnection delay suggested to SSE clients
+// when the handler shuts down.
+const defaultShutdownRetry = 5 * time.Second
+
 // StreamableHTTPHandler serves streamable MCP sessions as defined by the MCP spec
 type StreamableHTTPHandler struct {
 	getServer func(*http.Request) *Server
@@ -81,6 +92,9 @@ type StreamableHTTPHandler struct {
 	reaperOnce sync.Once
 	done       chan struct{}
 	closeOnce  sync.Once
+
+	// shuttingDown is set by Shutdown; no new sessions are created after.
+	shuttingDown atomic.Bool
 }
 
 // NewStreamableHTTPHandler creates a new streamable HTTP handler
@@ -100,6 +114,9 @@ func NewStreamableHTTPHandler(getServer func(*http.Request) *Server, opts *Strea
 	if opts.MaxRequestBytes <= 0 {
 		opts.MaxRequestBytes = defaultMaxRequestBytes
 	}
+	if opts.ShutdownRetry <= 0 {
+		opts.ShutdownRetry = defaultShutdownRetry
+	}
 
 	return &StreamableHTTPHandler{
 		getServer: getServer,
@@ -154,6 +171,13 @@ func (h *StreamableHTTPHandler) reapIdleSessions() {
 	}
 }
 
+// SessionCount returns the number of live sessions.
+func (h *StreamableHTTPHandler) SessionCount() int {
+	h.sessionsMu.RLock()
+	defer h.sessionsMu.RUnlock()
+	return len(h.sessions)
+}
+
 // Close shuts the handler down: it stops the reaper and cancels every live
 // session so their Serve goroutines return. It is safe to call more than once.
 func (h *StreamableHTTPHandler) Close() error {
@@ -174,6 +198,45 @@ func (h *StreamableHTTPHandler) Close() error {
 	return nil
 }
 
+// Shutdown gracefully shuts the handler down. New sessions are refused with
+// 503 Service Unavailable, and the servers behind the live sessions are shut
+// down with Server.Shutdown, so requests already running may finish until
+// ctx expires. Each open SSE stream is then sent the messages still queued
+// for it and a final "shutdown" event carrying a retry hint before it is
+// closed. The returned error joins the *ShutdownError of every server that
+// had to abandon requests.
+func (h *StreamableHTTPHandler) Shutdown(ctx context.Context) error {
+	h.shuttingDown.Store(true)
+
+	h.sessionsMu.RLock()
+	servers := make(map[*Server]bool)
+	for _, s := range h.sessions {
+		s.shutdownRetry.Store(int64(h.opts.ShutdownRetry))
+		if s.server != nil {
+			servers[s.server] = true
+		}
+	}
+	h.sessionsMu.RUnlock()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for server := range servers {
+		wg.Go(func() {
+			if err := server.Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	h.Close()
+	return errors.Join(errs...)
+}
+
 // ServeHTTP implements the HTTP handler interface
 func (h *StreamableHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if !h.opts.DisableLocalhostProtection && streamableIsLocalhostRequest(r) && !streamableIsLoopback(r.Host) {
@@ -273,6 +336,11 @@ func (h *StreamableHTTPHandler) handleMessage(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		h.handleBatch(w, r, trimmed)
+		return
+	}
+
 	var msg JSONRPCMessage
 	if err := json.Unmarshal(body, &msg); err != nil {
 		http.Error(w, "Invalid JSON-RPC message", http.StatusBadRequest)
@@ -287,6 +355,11 @@ func (h *StreamableHTTPHandler) handleMessage(w http.ResponseWriter, r *http.Req
 	} else {
 		session, err = h.getSession(sessionID)
 	}
+	if errors.Is(err, ErrShuttingDown) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(h.opts.ShutdownRetry.Seconds())))
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -319,6 +392,7 @@ func (h *StreamableHTTPHandler) handleMessage(w http.ResponseWriter, r *http.Req
 	for {
 		out, idx, err := session.waitStreamMessage(r.Context(), sid, next)
 		if err != nil {
+			session.writeShutdown(w, flusher)
 			return
 		}
 		next = idx
@@ -330,6 +404,102 @@ func (h *StreamableHTTPHandler) handleMessage(w http.ResponseWriter, r *http.Req
 	}
 }
 
+// handleBatch handles a POST whose body is a JSON-RPC batch. Batches are
+// accepted only on an initialized session whose negotiated protocol version
+// permits them; anything else is rejected with a single -32600 error. The
+// batch's messages are delivered one at a time and the responses to its
+// requests are streamed back as events on one SSE stream.
+func (h *StreamableHTTPHandler) handleBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	var msgs []json.RawMessage
+	if err := json.Unmarshal(body, &msgs); err != nil {
+		http.Error(w, "Invalid JSON-RPC message", http.StatusBadRequest)
+		return
+	}
+
+	var session *StreamableServerTransport
+	var version string
+	if sessionID := streamableSessionID(r); sessionID != "" {
+		var err error
+		session, err = h.getSession(sessionID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		version = session.server.negotiatedVersion()
+	}
+	rejected := errBatchRejected(version)
+	if batchingAllowed(version) {
+		rejected = nil
+		if len(msgs) == 0 {
+			rejected = &ResponseError{Code: -32600, Message: "empty batch"}
+		}
+	}
+	if rejected != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(errorReply(jsonrpc2.ID{}, rejected))
+		return
+	}
+
+	session.touch()
+	sid := streamID(session.nextStreamID.Add(1))
+	var replies []json.RawMessage
+	pending := make(map[interface{}]bool)
+	for _, raw := range msgs {
+		if _, reply := checkBatchElement(raw); reply != nil {
+			replies = append(replies, reply)
+			continue
+		}
+		var msg JSONRPCMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			replies = append(replies, errorReply(jsonrpc2.ID{}, &ResponseError{Code: -32600, Message: "invalid request in batch"}))
+			continue
+		}
+		if err := session.receive(r.Context(), msg, sid); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestTimeout)
+			return
+		}
+		if msg.ID != nil && msg.Method != "" {
+			pending[msg.ID] = true
+		}
+	}
+
+	w.Header().Set(streamableSessionHeader, session.id)
+	if len(pending) == 0 && len(replies) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "SSE not supported", http.StatusInternalServerError)
+		return
+	}
+	for _, reply := range replies {
+		fmt.Fprintf(w, "data: %s\n\n", reply)
+	}
+	flusher.Flush()
+
+	next := 0
+	for len(pending) > 0 {
+		out, idx, err := session.waitStreamMessage(r.Context(), sid, next)
+		if err != nil {
+			session.writeShutdown(w, flusher)
+			return
+		}
+		next = idx
+		session.writeSSEMessage(w, out)
+		flusher.Flush()
+		if out.Message.Method == "" {
+			delete(pending, out.Message.ID)
+		}
+	}
+}
+
 // handleSessionDelete handles session termination
 func (h *StreamableHTTPHandler) handleSessionDelete(w http.ResponseWriter, r *http.Request) {
 	sessionID := streamableSessionID(r)
@@ -410,12 +580,16 @@ func (h *StreamableHTTPHandler) getOrCreateSession(r *http.Request, sessionID st
 		return session, nil
 	}
 
+	if h.shuttingDown.Load() {
+		return nil, ErrShuttingDown
+	}
 	server := h.getServer(r)
 	if server == nil {
 		return nil, fmt.Errorf("no server available")
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	session = newStreamableServerTransport(sessionID, h.opts.Logger)
+	session.server = server
 	session.cancel = cancel
 
 	h.sessionsMu.Lock()
@@ -463,12 +637,16 @@ type streamableMsg struct {
 type StreamableServerTransport struct {
 	nextStreamID atomic.Int64
 	id           string
+	server       *Server
 	incoming     chan JSONRPCMessage
 	logger       *slog.Logger
 	cancel       context.CancelFunc
 	// lastActive is the unix-nano time of the most recent activity on this
 	// session, read by the handler's reaper to evict idle sessions.
 	lastActive atomic.Int64
+	// shutdownRetry, when set, is the reconnection delay announced to open
+	// SSE streams as the handler shuts the session down.
+	shutdownRetry atomic.Int64
 
 	mu               sync.RWMutex
 	isDone           bool
@@ -705,6 +883,13 @@ func (t *StreamableServerTransport) streamMessages(ctx context.Context, w http.R
 		case <-ctx.Done():
 			return
 		case <-t.done:
+			t.mu.RLock()
+			messages := t.outgoingMessages[resumeStreamID]
+			for i := resumeIndex; i < len(messages); i++ {
+				t.writeSSEMessage(w, messages[i])
+			}
+			t.mu.RUnlock()
+			t.writeShutdown(w, flusher)
 			return
 		case <-ticker.C:
 			// Send keep-alive
@@ -725,6 +910,18 @@ func (t *StreamableServerTransport) streamMessages(ctx context.Context, w http.R
 	}
 }
 
+// writeShutdown ends an SSE stream of a session closed by the handler's
+// Shutdown with a final event telling the client when to reconnect. It does
+// nothing for sessions closed for any other reason.
+func (t *StreamableServerTransport) writeShutdown(w http.ResponseWriter, flusher http.Flusher) {
+	retry := time.Duration(t.shutdownRetry.Load())
+	if retry <= 0 {
+		return
+	}
+	fmt.Fprintf(w, "event: shutdown\nretry: %d\ndata: {}\n\n", retry.Milliseconds())
+	flusher.Flush()
+}
+
 // writeSSEMessage writes a message as an SSE event
 func (t *StreamableServerTransport) writeSSEMessage(w http.ResponseWriter, msg *streamableMsg) {
 	data, err := json.Marshal(msg.Message)
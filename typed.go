@@ -9,8 +9,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
 )
 
 // Type-Safe Tool Registration
@@ -21,6 +24,12 @@ type TypedToolHandlerFunc[TArg any, TResult any] func(ctx context.Context, args
 
 // RegisterTypedToolWithServer registers a type-safe tool handler with automatic JSON marshaling/unmarshaling
 // and schema generation. It provides compile-time type safety while maintaining runtime compatibility.
+//
+// The handler's result becomes the structured content of the tool result,
+// with the text fallback set by WithStructuredTextFallback. Handlers that
+// return mixed content use a TResult of *ResultBuilder or *CallToolResult,
+// which is sent as built and declares no output schema. Errors are reported
+// according to the server's ToolErrorPolicy.
 func RegisterTypedToolWithServer[TArg any, TResult any](
 	s *Server,
 	name string,
@@ -37,9 +46,14 @@ func RegisterTypedToolWithServer[TArg any, TResult any](
 		return fmt.Errorf("failed to create input schema for tool %q: %w", name, err)
 	}
 
-	outputSchema, err := createJSONSchema[TResult]()
-	if err != nil {
-		return fmt.Errorf("failed to create output schema for tool %q: %w", name, err)
+	var outputSchema json.RawMessage
+	switch reflect.TypeFor[TResult]() {
+	case reflect.TypeFor[*ResultBuilder](), reflect.TypeFor[*CallToolResult]():
+	default:
+		outputSchema, err = createJSONSchema[TResult]()
+		if err != nil {
+			return fmt.Errorf("failed to create output schema for tool %q: %w", name, err)
+		}
 	}
 
 	// Create the untyped handler wrapper
@@ -48,57 +62,38 @@ func RegisterTypedToolWithServer[TArg any, TResult any](
 		var input TArg
 		if len(req.Arguments) > 0 {
 			if err := json.Unmarshal(req.Arguments, &input); err != nil {
-				return &CallToolResult{
-					IsError: true,
-					Content: []any{
-						map[string]string{
-							"type": "text",
-							"text": fmt.Sprintf("Invalid input for tool %q: %v", name, err),
-						},
-					},
-				}, nil
+				return s.toolErrorResult(name, NewParameterError(string(MethodToolsCall), "arguments",
+					fmt.Sprintf("invalid input for tool %q", name), err))
 			}
 		}
 
 		// Call the typed handler
 		output, err := handler(ctx, input)
 		if err != nil {
-			return &CallToolResult{
-				IsError: true,
-				Content: []any{
-					map[string]string{
-						"type": "text",
-						"text": fmt.Sprintf("Tool %q error: %v", name, err),
-					},
-				},
-			}, nil
-		}
-
-		// Marshal the typed output
-		outputJSON, err := json.Marshal(output)
+			return s.toolErrorResult(name, err)
+		}
+
+		var result *CallToolResult
+		switch out := any(output).(type) {
+		case *ResultBuilder:
+			if out != nil {
+				result, err = out.Result()
+			}
+		case *CallToolResult:
+			result = out
+		default:
+			result, err = NewResult().Structured(output).Result()
+		}
+		if err == nil && result == nil {
+			err = fmt.Errorf("tool %q returned no result", name)
+		}
 		if err != nil {
-			return &CallToolResult{
-				IsError: true,
-				Content: []any{
-					map[string]string{
-						"type": "text",
-						"text": fmt.Sprintf("Failed to marshal output for tool %q: %v", name, err),
-					},
-				},
-			}, nil
-		}
-
-		// Return structured result
-		return &CallToolResult{
-			Content: []any{
-				map[string]any{
-					"type":   "text",
-					"format": "json",
-					"text":   string(outputJSON),
-				},
-			},
-			StructuredContent: json.RawMessage(outputJSON),
-		}, nil
+			return s.toolErrorResult(name, err)
+		}
+		if err := s.addStructuredText(result); err != nil {
+			return s.toolErrorResult(name, err)
+		}
+		return result, nil
 	}
 
 	// Register the tool with the server
@@ -112,10 +107,246 @@ func RegisterTypedToolWithServer[TArg any, TResult any](
 	return s.RegisterTool(tool, toolHandler)
 }
 
+// Type-Safe Prompt and Resource Template Registration
+// ===================================================
+
+// TypedPromptHandlerFunc defines the signature for type-safe prompt handlers.
+type TypedPromptHandlerFunc[TArgs any] func(ctx context.Context, args TArgs) (*GetPromptResult, error)
+
+// TypedResourceHandlerFunc defines the signature for type-safe resource
+// template handlers. vars holds the template variables of the URI read.
+type TypedResourceHandlerFunc[TVars any, TOut any] func(ctx context.Context, vars TVars) (TOut, error)
+
+// ArgumentCompleter is implemented by the argument types of typed prompts
+// and the variable types of typed resource templates that complete their
+// own values. The receiver holds the arguments the client has already
+// filled in. Types that do not implement it complete the values of fields
+// with a jsonschema enum.
+type ArgumentCompleter interface {
+	CompleteArgument(ctx context.Context, name, value string) ([]string, error)
+}
+
+// RegisterTypedPrompt registers a prompt whose arguments are the fields of
+// TArgs. Each field becomes a PromptArgument named by its json tag,
+// required unless it is omitempty, and described and constrained by its
+// jsonschema tag; the argument's Schema holds the field's schema. Argument
+// values are strings on the wire, and those of non-string fields are read
+// as JSON, so "3" fills an int field.
+func RegisterTypedPrompt[TArgs any](s *Server, name, description string, handler TypedPromptHandlerFunc[TArgs]) error {
+	if s == nil {
+		return fmt.Errorf("server is nil")
+	}
+	props, required, err := typedObjectSchema[TArgs]()
+	if err != nil {
+		return fmt.Errorf("failed to create argument schema for prompt %q: %w", name, err)
+	}
+	prompt := Prompt{Name: name, Description: description}
+	for _, field := range sortedPropertyNames(props, required) {
+		prop, _ := props[field].(map[string]any)
+		desc, _ := prop["description"].(string)
+		prompt.Arguments = append(prompt.Arguments, PromptArgument{
+			Name:        field,
+			Description: desc,
+			Required:    slices.Contains(required, field),
+			Schema:      prop,
+		})
+	}
+	promptHandler := func(ctx context.Context, req GetPromptRequest) (*GetPromptResult, error) {
+		args, err := decodeTypedArgs[TArgs](string(MethodPromptsGet), props, required, req.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, args)
+	}
+	return s.registerPrompt(prompt, promptHandler, typedCompleter[TArgs](props))
+}
+
+// RegisterTypedResourceTemplate registers a resource template whose
+// variables bind to the fields of TVars by json name, with the same string
+// decoding as RegisterTypedPrompt. The handler's result is encoded with
+// codec, JSONCodec if nil, into a TextResourceContents of the codec's MIME
+// type, which template.MimeType defaults to.
+func RegisterTypedResourceTemplate[TVars any, TOut any](s *Server, template ResourceTemplate, codec ResourceCodec, handler TypedResourceHandlerFunc[TVars, TOut]) error {
+	if s == nil {
+		return fmt.Errorf("server is nil")
+	}
+	if codec == nil {
+		codec = JSONCodec
+	}
+	if template.MimeType == "" {
+		template.MimeType = codec.MIMEType()
+	}
+	uriTemplate := template.uri()
+	props, required, err := typedObjectSchema[TVars]()
+	if err != nil {
+		return fmt.Errorf("failed to create variable schema for resource template %q: %w", uriTemplate, err)
+	}
+	matcher, err := newURITemplateMatcher(uriTemplate)
+	if err != nil {
+		return err
+	}
+	templateHandler := func(ctx context.Context, req ReadResourceRequest) ([]ResourceContents, error) {
+		matched, ok := matcher.match(req.URI)
+		if !ok {
+			return nil, NewNotFoundError("resource", req.URI)
+		}
+		values := make(map[string]any, len(matched))
+		for k, v := range matched {
+			values[k] = v
+		}
+		vars, err := decodeTypedArgs[TVars](string(MethodResourcesRead), props, required, values)
+		if err != nil {
+			return nil, err
+		}
+		out, err := handler(ctx, vars)
+		if err != nil {
+			return nil, err
+		}
+		text, err := codec.Encode(out)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode resource %q: %w", req.URI, err)
+		}
+		return []ResourceContents{TextResourceContents{URI: req.URI, MimeType: codec.MIMEType(), Text: text}}, nil
+	}
+	return s.registerResourceTemplate(template, templateHandler, typedCompleter[TVars](props))
+}
+
+// typedObjectSchema returns the properties and required properties of the
+// schema generated for T, which must be a struct.
+func typedObjectSchema[T any]() (map[string]any, []string, error) {
+	if t := reflect.TypeFor[T](); t.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%s is not a struct type", t)
+	}
+	raw, err := createJSONSchema[T]()
+	if err != nil {
+		return nil, nil, err
+	}
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+		Required   []string       `json:"required"`
+	}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, nil, err
+	}
+	return schema.Properties, schema.Required, nil
+}
+
+// sortedPropertyNames lists the required properties, then the others, each
+// in name order.
+func sortedPropertyNames(props map[string]any, required []string) []string {
+	names := slices.Sorted(maps.Keys(props))
+	slices.SortStableFunc(names, func(a, b string) int {
+		ra, rb := slices.Contains(required, a), slices.Contains(required, b)
+		switch {
+		case ra && !rb:
+			return -1
+		case rb && !ra:
+			return 1
+		}
+		return 0
+	})
+	return names
+}
+
+// decodeTypedArgs decodes prompt arguments or URI template variables into
+// a T. String values of properties that do not accept strings are read as
+// JSON.
+func decodeTypedArgs[T any](method string, props map[string]any, required []string, args map[string]any) (T, error) {
+	var out T
+	for _, name := range required {
+		if _, ok := args[name]; !ok {
+			return out, NewParameterError(method, name, "missing required argument", nil)
+		}
+	}
+	values := make(map[string]any, len(args))
+	for name, v := range args {
+		if s, ok := v.(string); ok && !acceptsString(props[name]) && json.Valid([]byte(s)) {
+			v = json.RawMessage(s)
+		}
+		values[name] = v
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return out, NewParameterError(method, "arguments", err.Error(), err)
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, NewParameterError(method, "arguments", err.Error(), err)
+	}
+	return out, nil
+}
+
+// acceptsString reports whether a property schema allows string values.
+func acceptsString(prop any) bool {
+	p, _ := prop.(map[string]any)
+	switch t := p["type"].(type) {
+	case string:
+		return t == "string"
+	case []any:
+		return slices.Contains(t, any("string"))
+	}
+	return len(p) == 0
+}
+
+// typedCompleter returns the completion handler for the fields of T: its
+// CompleteArgument method, or matching the enum values of the schema. It
+// returns nil if T has neither.
+func typedCompleter[T any](props map[string]any) CompletionHandlerFunc {
+	_, custom := any(new(T)).(ArgumentCompleter)
+	enums := make(map[string][]string)
+	for name, prop := range props {
+		p, _ := prop.(map[string]any)
+		values, _ := p["enum"].([]any)
+		for _, v := range values {
+			if s, ok := v.(string); ok {
+				enums[name] = append(enums[name], s)
+			}
+		}
+	}
+	if !custom && len(enums) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, req CompleteRequest) (*CompleteResult, error) {
+		var values []string
+		if custom {
+			filled := make(map[string]any)
+			if req.Context != nil {
+				for k, v := range req.Context.Arguments {
+					filled[k] = v
+				}
+			}
+			// Arguments still being typed may not decode; complete with
+			// what does.
+			args, _ := decodeTypedArgs[T](string(MethodCompletionComplete), props, nil, filled)
+			var err error
+			values, err = any(&args).(ArgumentCompleter).CompleteArgument(ctx, req.Argument.Name, req.Argument.Value)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			for _, v := range enums[req.Argument.Name] {
+				if strings.HasPrefix(v, req.Argument.Value) {
+					values = append(values, v)
+				}
+			}
+		}
+		result := &CompleteResult{}
+		// The spec allows at most 100 values per response.
+		if len(values) > 100 {
+			total, more := len(values), true
+			result.Completion.Total, result.Completion.HasMore = &total, &more
+			values = values[:100]
+		}
+		result.Completion.Values = append([]string{}, values...)
+		return result, nil
+	}
+}
+
 // Type-Safe Client Methods
 // =======================
 
 // CallToolTyped performs a type-safe tool call with compile-time type checking
+// Error results reported with a ToolError are returned as errors wrapping
+// the *ToolError, with its Retryable flag and Details.
 func CallToolTyped[TArg any, TResult any](
 	c *Client,
 	ctx context.Context,
@@ -144,12 +375,13 @@ func CallToolTyped[TArg any, TResult any](
 	}
 
 	if result.IsError {
+		if te, ok := toolErrorFromResult(result); ok {
+			return nil, fmt.Errorf("tool error: %w", te)
+		}
 		// Extract error message from content
 		if len(result.Content) > 0 {
-			if content, ok := result.Content[0].(map[string]any); ok {
-				if text, ok := content["text"].(string); ok {
-					return nil, fmt.Errorf("tool error: %s", text)
-				}
+			if content, ok := result.Content[0].(TextContent); ok {
+				return nil, fmt.Errorf("tool error: %s", content.Text)
 			}
 		}
 		return nil, fmt.Errorf("tool %q returned error", toolName)
@@ -175,19 +407,14 @@ func CallToolTyped[TArg any, TResult any](
 	}
 
 	// Extract JSON content
-	content, ok := result.Content[0].(map[string]any)
-	if !ok {
-		return nil, fmt.Errorf("tool %q returned invalid content format", toolName)
-	}
-
-	text, ok := content["text"].(string)
+	content, ok := result.Content[0].(TextContent)
 	if !ok {
 		return nil, fmt.Errorf("tool %q returned no text content", toolName)
 	}
 
 	// Unmarshal into typed result
 	var typedResult TResult
-	if err := json.Unmarshal([]byte(text), &typedResult); err != nil {
+	if err := json.Unmarshal([]byte(content.Text), &typedResult); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal result from tool %q: %w", toolName, err)
 	}
 
@@ -487,6 +714,10 @@ func (sv *StructValidator) applyValidationRule(fieldName, rule string, fieldValu
 type EnhancedSchemaGenerator struct {
 	cache     *SchemaCache
 	validator *StructValidator
+
+	mu              sync.RWMutex
+	overrides       map[reflect.Type]SchemaOverrideFunc
+	implementations map[reflect.Type][]reflect.Type // interface -> implementations
 }
 
 // NewEnhancedSchemaGenerator creates a new enhanced schema generator
@@ -499,12 +730,12 @@ func NewEnhancedSchemaGenerator() *EnhancedSchemaGenerator {
 
 // GenerateSchema generates a comprehensive JSON schema for the given type
 func GenerateSchemaWithGenerator[T any](esg *EnhancedSchemaGenerator) (json.RawMessage, error) {
-	return createJSONSchema[T]()
+	return esg.generate(reflect.TypeFor[T]())
 }
 
 // GenerateOpenAPISchema generates an OpenAPI-compatible schema
 func GenerateOpenAPISchemaWithGenerator[T any](esg *EnhancedSchemaGenerator) (map[string]any, error) {
-	schema, err := createJSONSchema[T]()
+	schema, err := esg.generate(reflect.TypeFor[T]())
 	if err != nil {
 		return nil, err
 	}
@@ -515,7 +746,7 @@ func GenerateOpenAPISchemaWithGenerator[T any](esg *EnhancedSchemaGenerator) (ma
 	}
 
 	// Add OpenAPI-specific enhancements
-	schemaMap["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schemaMap["$schema"] = "https://json-schema.org/draft/2020-12/schema"
 
 	return schemaMap, nil
 }
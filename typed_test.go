@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -532,3 +535,115 @@ func typedContainsSubstring(str, substr string) bool {
 	}
 	return false
 }
+
+type reviewPromptArgs struct {
+	Code     string `json:"code" jsonschema:"description=Code to review"`
+	Language string `json:"language,omitempty" jsonschema:"enum=go|python|rust"`
+	MaxNotes int    `json:"max_notes,omitempty"`
+}
+
+type fileVars struct {
+	Owner string `json:"owner"`
+	Path  string `json:"path"`
+	Line  int    `json:"line,omitempty"`
+}
+
+// CompleteArgument completes paths under the owner already chosen.
+func (v fileVars) CompleteArgument(ctx context.Context, name, value string) ([]string, error) {
+	if name != "path" {
+		return nil, nil
+	}
+	return []string{v.Owner + "/" + value + "main.go"}, nil
+}
+
+type fileInfo struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+func TestRegisterTypedPromptAndResourceTemplate(t *testing.T) {
+	server := NewServer("typed", "1.0.0")
+	err := RegisterTypedPrompt(server, "review", "Review code", func(ctx context.Context, args reviewPromptArgs) (*GetPromptResult, error) {
+		text := fmt.Sprintf("review %s code (%d notes): %s", args.Language, args.MaxNotes, args.Code)
+		return &GetPromptResult{Messages: []PromptMessage{{Role: "user", Content: TextContent{Type: "text", Text: text}}}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RegisterTypedResourceTemplate(server, ResourceTemplate{URITemplate: "repo://{owner}/{+path}{?line}", Name: "file"}, YAMLCodec,
+		func(ctx context.Context, vars fileVars) (fileInfo, error) {
+			return fileInfo{Path: vars.Owner + "/" + vars.Path, Line: vars.Line}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientConn, serverConn := net.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = server.Serve(ctx, &ReadWriteCloserTransport{serverConn}) }()
+	client, err := NewClient(&ReadWriteCloserTransport{clientConn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	init, err := client.Initialize(ctx, InitializeRequest{ProtocolVersion: LATEST_PROTOCOL_VERSION, ClientInfo: Implementation{Name: "c", Version: "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if init.Capabilities.Completions == nil {
+		t.Error("completions capability not advertised")
+	}
+
+	prompts, err := client.ListPrompts(ctx, ListPromptsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, arg := range prompts.Prompts[0].Arguments {
+		got = append(got, fmt.Sprintf("%s:%v:%s", arg.Name, arg.Required, arg.Description))
+	}
+	if want := []string{"code:true:Code to review", "language:false:", "max_notes:false:"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("prompt arguments = %v, want %v", got, want)
+	}
+
+	result, err := client.GetPrompt(ctx, GetPromptRequest{Name: "review", Arguments: map[string]any{"code": "x := 1", "language": "go", "max_notes": "3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Messages[0].Content.(TextContent).Text; text != "review go code (3 notes): x := 1" {
+		t.Errorf("prompt text = %q", text)
+	}
+	if _, err := client.GetPrompt(ctx, GetPromptRequest{Name: "review"}); err == nil || !strings.Contains(err.Error(), "code") {
+		t.Errorf("missing required argument: err = %v", err)
+	}
+
+	read, err := client.ReadResource(ctx, ReadResourceRequest{URI: "repo://tmc/cmd/mcp/main.go?line=12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := read.Contents[0].(TextResourceContents)
+	if contents.MimeType != "application/yaml" || contents.Text != "path: tmc/cmd/mcp/main.go\nline: 12\n" {
+		t.Errorf("resource contents = %q (%s)", contents.Text, contents.MimeType)
+	}
+
+	var req CompleteRequest
+	req.Ref = PromptReference{Type: "ref/prompt", Name: "review"}
+	req.Argument.Name, req.Argument.Value = "language", "py"
+	completion, err := client.Complete(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := completion.Completion.Values, []string{"python"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("prompt completion = %v, want %v", got, want)
+	}
+	req.Ref = ResourceReference{Type: "ref/resource", URI: "repo://{owner}/{+path}{?line}"}
+	req.Argument.Name, req.Argument.Value = "path", "cmd/"
+	req.Context = &CompleteContext{Arguments: map[string]string{"owner": "tmc"}}
+	if completion, err = client.Complete(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := completion.Completion.Values, []string{"tmc/cmd/main.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("template completion = %v, want %v", got, want)
+	}
+}
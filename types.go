@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/tmc/mcp/modelcontextprotocol"
 )
 
 // Protocol constants.
@@ -19,6 +21,10 @@ const (
 	JSONRPC_VERSION         = "2.0"
 )
 
+// supportedProtocolVersions lists the protocol revisions the Server
+// accepts in initialize, newest first.
+var supportedProtocolVersions = []string{LATEST_PROTOCOL_VERSION, "2025-06-18", "2025-03-26", "2024-11-05"}
+
 // Common error values
 var (
 	ErrInvalidParams = errors.New("mcp: invalid parameters")
@@ -102,6 +108,8 @@ const (
 	MethodResourcesSubscribe      Method = "resources/subscribe"
 	MethodResourcesUnsubscribe    Method = "resources/unsubscribe"
 	MethodResourcesRead           Method = "resources/read"
+	MethodResourcesReadRange      Method = "resources/readRange"
+	MethodResourcesCloseStream    Method = "resources/closeStream"
 	MethodPromptsList             Method = "prompts/list"
 	MethodPromptsGet              Method = "prompts/get"
 	MethodToolsList               Method = "tools/list"
@@ -280,8 +288,9 @@ type Content interface {
 // This is the most common content type used for tool results,
 // prompt messages, and resource contents.
 type TextContent struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type        string       `json:"type"`
+	Text        string       `json:"text"`
+	Annotations *Annotations `json:"annotations,omitempty"`
 }
 
 func (TextContent) content() {}
@@ -290,9 +299,10 @@ func (TextContent) content() {}
 // The Data field carries the raw image bytes, base64 encoded on the wire,
 // and MimeType identifies the image format. Both are required by the spec.
 type ImageContent struct {
-	Type     string `json:"type"`
-	Data     []byte `json:"data"`
-	MimeType string `json:"mimeType"`
+	Type        string       `json:"type"`
+	Data        []byte       `json:"data"`
+	MimeType    string       `json:"mimeType"`
+	Annotations *Annotations `json:"annotations,omitempty"`
 }
 
 func (ImageContent) content() {}
@@ -301,13 +311,55 @@ func (ImageContent) content() {}
 // The Data field carries the raw audio bytes, base64 encoded on the wire,
 // and MimeType identifies the audio format. Both are required by the spec.
 type AudioContent struct {
-	Type     string `json:"type"`
-	Data     []byte `json:"data"`
-	MimeType string `json:"mimeType"`
+	Type        string       `json:"type"`
+	Data        []byte       `json:"data"`
+	MimeType    string       `json:"mimeType"`
+	Annotations *Annotations `json:"annotations,omitempty"`
 }
 
 func (AudioContent) content() {}
 
+// EmbeddedResource represents the contents of a resource embedded in a
+// message, such as a tool result that returns a file it read.
+type EmbeddedResource struct {
+	Type        string           `json:"type"` // "resource"
+	Resource    ResourceContents `json:"resource"`
+	Annotations *Annotations     `json:"annotations,omitempty"`
+}
+
+func (EmbeddedResource) content() {}
+
+// ResourceLink represents a reference to a resource the client can read
+// with resources/read, without embedding its contents.
+type ResourceLink struct {
+	Type        string       `json:"type"` // "resource_link"
+	URI         string       `json:"uri"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	MimeType    string       `json:"mimeType,omitempty"`
+	Size        *int64       `json:"size,omitempty"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+}
+
+func (ResourceLink) content() {}
+
+// Annotations tell the client how to use or display an object. They are
+// hints: clients may ignore them.
+type Annotations struct {
+	// Audience lists who the object is intended for.
+	Audience []Role `json:"audience,omitempty"`
+	// Priority ranges from 0 (optional) to 1 (required).
+	Priority *float64 `json:"priority,omitempty"`
+	// LastModified is an ISO 8601 timestamp.
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// ContentList is the content of a tool result. It holds Content values;
+// decoding resolves each block to its concrete type. Blocks of unknown
+// types, and blocks with keys their type does not define, are kept as
+// map[string]any so that no key is lost.
+type ContentList []any
+
 // ListToolsRequest is the client's request to list available tools.
 type ListToolsRequest struct {
 	Cursor string `json:"cursor,omitempty"`
@@ -325,10 +377,21 @@ type ListToolsResult struct {
 // operations like file system access, API calls, or data processing.
 // The InputSchema provides JSON Schema validation for tool arguments.
 type Tool struct {
-	Name         string          `json:"name"`
-	Description  string          `json:"description,omitempty"`
-	InputSchema  json.RawMessage `json:"inputSchema,omitempty"`
-	OutputSchema json.RawMessage `json:"outputSchema,omitempty"`
+	Name         string           `json:"name"`
+	Description  string           `json:"description,omitempty"`
+	InputSchema  json.RawMessage  `json:"inputSchema,omitempty"`
+	OutputSchema json.RawMessage  `json:"outputSchema,omitempty"`
+	Annotations  *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations describes how a tool behaves. The hints are advisory and
+// come from the server; clients should not rely on them for security.
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty"`    // The tool does not modify its environment
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"` // The tool may perform destructive updates
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty"`  // Repeated calls with the same arguments have no additional effect
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`   // The tool interacts with external entities
 }
 
 // CallToolRequest is the client's request to call a specific tool.
@@ -348,11 +411,24 @@ func (r CallToolRequest) ProgressToken() any {
 	return r.Meta["progressToken"]
 }
 
+// ArgumentMap decodes the arguments of the request as an object whose
+// values are left undecoded. It returns a nil map if there are none.
+func (r CallToolRequest) ArgumentMap() (map[string]json.RawMessage, error) {
+	if len(r.Arguments) == 0 || string(r.Arguments) == "null" {
+		return nil, nil
+	}
+	var args map[string]json.RawMessage
+	if err := json.Unmarshal(r.Arguments, &args); err != nil {
+		return nil, NewParameterErrorFromJSON(string(MethodToolsCall), err)
+	}
+	return args, nil
+}
+
 // CallToolResult is the server's response to a tools/call request.
 // It contains the tool's output as content blocks, an error flag,
 // and optional metadata. Content can include text, images, or other media.
 type CallToolResult struct {
-	Content           []any          `json:"content"`
+	Content           ContentList    `json:"content"`
 	StructuredContent any            `json:"structuredContent,omitempty"`
 	IsError           bool           `json:"isError,omitempty"`
 	Meta              map[string]any `json:"_meta,omitempty"`
@@ -360,13 +436,41 @@ type CallToolResult struct {
 
 // CompleteRequest describes a completion lookup for a prompt or resource reference.
 type CompleteRequest struct {
+	// Ref is the prompt or resource the completion is for: a
+	// PromptReference, a ResourceReference or a map of the same JSON.
+	// Requests decoded from the wire hold a map[string]any; Reference
+	// resolves either form.
 	Ref      any `json:"ref"`
 	Argument struct {
 		Name  string `json:"name"`
 		Value string `json:"value"`
 	} `json:"argument"`
+	Context *CompleteContext `json:"context,omitempty"`
+}
+
+// CompleteContext carries arguments the user has already filled in, so
+// completions of one argument can depend on the others.
+type CompleteContext struct {
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// Reference returns the reference of r as a PromptReference or
+// ResourceReference.
+func (r CompleteRequest) Reference() (Reference, error) {
+	return ParseReference(r.Ref)
 }
 
+// Reference identifies what a completion request is for: a PromptReference
+// or a ResourceReference. The reference types are those of package
+// modelcontextprotocol.
+type Reference = modelcontextprotocol.Reference
+
+// PromptReference refers to a prompt by name.
+type PromptReference = modelcontextprotocol.PromptReference
+
+// ResourceReference refers to a resource or resource template by URI.
+type ResourceReference = modelcontextprotocol.ResourceReference
+
 // CompleteResult contains server-provided completion candidates.
 type CompleteResult struct {
 	Completion struct {
@@ -547,10 +651,13 @@ type ListResourcesResult struct {
 // Resources are data sources like files, databases, or APIs that clients
 // can access through the server. Each resource has a unique URI identifier.
 type Resource struct {
-	URI         string `json:"uri"`
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	MimeType    string `json:"mimeType,omitempty"`
+	URI         string         `json:"uri"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	MimeType    string         `json:"mimeType,omitempty"`
+	Size        *int64         `json:"size,omitempty"`
+	Annotations *Annotations   `json:"annotations,omitempty"`
+	Meta        map[string]any `json:"_meta,omitempty"`
 }
 
 // ReadResourceRequest is the client's request to read a specific resource.
@@ -590,9 +697,10 @@ type ResourceContents interface {
 // Used for resources that contain textual data like configuration files,
 // source code, documentation, or any UTF-8 encoded content.
 type TextResourceContents struct {
-	URI      string `json:"uri"`
-	MimeType string `json:"mimeType,omitempty"`
-	Text     string `json:"text"`
+	URI      string         `json:"uri"`
+	MimeType string         `json:"mimeType,omitempty"`
+	Text     string         `json:"text"`
+	Meta     map[string]any `json:"_meta,omitempty"`
 }
 
 func (TextResourceContents) resourceContents() {}
@@ -601,9 +709,10 @@ func (TextResourceContents) resourceContents() {}
 // Used for resources containing binary data such as images, executables,
 // or other non-text files. The content is base64 encoded for JSON transport.
 type BlobResourceContents struct {
-	URI      string `json:"uri"`
-	MimeType string `json:"mimeType,omitempty"`
-	Blob     string `json:"blob"` // base64 encoded
+	URI      string         `json:"uri"`
+	MimeType string         `json:"mimeType,omitempty"`
+	Blob     string         `json:"blob"` // base64 encoded
+	Meta     map[string]any `json:"_meta,omitempty"`
 }
 
 func (BlobResourceContents) resourceContents() {}
@@ -615,7 +724,7 @@ type ListResourceTemplatesRequest struct {
 
 // ListResourceTemplatesResult is the server's response to a resources/templates/list request.
 type ListResourceTemplatesResult struct {
-	Templates  []ResourceTemplate `json:"templates"`
+	Templates  []ResourceTemplate `json:"resourceTemplates"`
 	NextCursor string             `json:"nextCursor,omitempty"`
 	Meta       map[string]any     `json:"_meta,omitempty"`
 }
@@ -624,8 +733,27 @@ type ListResourceTemplatesResult struct {
 // Templates allow servers to advertise patterns of resources they can provide,
 // such as file system paths with wildcards or parameterized API endpoints.
 type ResourceTemplate struct {
-	Template    string `json:"template"`
-	Description string `json:"description,omitempty"`
+	// URITemplate is an RFC 6570 URI template.
+	URITemplate string       `json:"uriTemplate"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	MimeType    string       `json:"mimeType,omitempty"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+
+	// Template is the URI template under its old field name. It is used
+	// when URITemplate is empty.
+	//
+	// Deprecated: Use URITemplate.
+	Template string `json:"-"`
+}
+
+// uri returns the URI template of t, falling back to the deprecated Template
+// field.
+func (t ResourceTemplate) uri() string {
+	if t.URITemplate != "" {
+		return t.URITemplate
+	}
+	return t.Template
 }
 
 // TransportInterface is a deprecated alias. Use Transport from transport.go instead.
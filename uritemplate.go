@@ -0,0 +1,316 @@
+package mcp
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ExpandURITemplate expands an RFC 6570 URI template with string values,
+// the inverse of the matching the server does for resource templates. All
+// level 3 operators and the prefix modifier ({var:3}) are supported;
+// explode modifiers are ignored since every value is a single string.
+// Variables missing from vars are left out of the result.
+func ExpandURITemplate(template string, vars map[string]string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(template, '{')
+		if i < 0 {
+			b.WriteString(template)
+			break
+		}
+		j := strings.IndexByte(template[i:], '}')
+		if j < 0 {
+			return "", fmt.Errorf("mcp: unterminated expression in URI template %q", template)
+		}
+		b.WriteString(template[:i])
+		expandExpression(&b, template[i+1:i+j], vars)
+		template = template[i+j+1:]
+	}
+	return b.String(), nil
+}
+
+// expandExpression writes the expansion of one template expression, the
+// text between braces.
+func expandExpression(b *strings.Builder, expr string, vars map[string]string) {
+	var (
+		first, sep   = "", ","
+		named        = false
+		ifEmpty      = ""
+		allowReserve = false
+	)
+	if expr != "" {
+		switch expr[0] {
+		case '+':
+			allowReserve = true
+		case '#':
+			first, allowReserve = "#", true
+		case '.':
+			first, sep = ".", "."
+		case '/':
+			first, sep = "/", "/"
+		case ';':
+			first, sep, named = ";", ";", true
+		case '?':
+			first, sep, named, ifEmpty = "?", "&", true, "="
+		case '&':
+			first, sep, named, ifEmpty = "&", "&", true, "="
+		}
+		if strings.ContainsRune("+#./;?&", rune(expr[0])) {
+			expr = expr[1:]
+		}
+	}
+	wrote := false
+	for _, spec := range strings.Split(expr, ",") {
+		name, prefix := strings.TrimSuffix(spec, "*"), -1
+		if k := strings.IndexByte(name, ':'); k >= 0 {
+			fmt.Sscanf(name[k+1:], "%d", &prefix)
+			name = name[:k]
+		}
+		value, ok := vars[name]
+		if !ok {
+			continue
+		}
+		if prefix >= 0 {
+			if r := []rune(value); prefix < len(r) {
+				value = string(r[:prefix])
+			}
+		}
+		if wrote {
+			b.WriteString(sep)
+		} else {
+			b.WriteString(first)
+			wrote = true
+		}
+		if named {
+			b.WriteString(name)
+			if value == "" {
+				b.WriteString(ifEmpty)
+				continue
+			}
+			b.WriteString("=")
+		}
+		b.WriteString(escapeTemplateValue(value, allowReserve))
+	}
+}
+
+// escapeTemplateValue percent-encodes every byte of s outside the
+// unreserved set, or outside the unreserved and reserved sets when
+// allowReserved is set.
+func escapeTemplateValue(s string, allowReserved bool) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', strings.IndexByte("-._~", c) >= 0:
+			b.WriteByte(c)
+		case allowReserved && strings.IndexByte(":/?#[]@!$&'()*+,;=%", c) >= 0:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
+// uriTemplatePattern compiles an RFC 6570 URI template into a pattern that
+// matches the URIs it expands to. Simple expressions match one path
+// segment; reserved ({+var}) and fragment ({#var}) expressions match
+// anything, and query expressions match an optional query string. Each
+// expression is a capture group.
+func uriTemplatePattern(template string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for {
+		i := strings.IndexByte(template, '{')
+		if i < 0 {
+			b.WriteString(regexp.QuoteMeta(template))
+			break
+		}
+		j := strings.IndexByte(template[i:], '}')
+		if j < 0 {
+			return nil, fmt.Errorf("mcp: unterminated expression in URI template %q", template)
+		}
+		b.WriteString(regexp.QuoteMeta(template[:i]))
+		expr := template[i+1 : i+j]
+		op := byte(0)
+		if expr != "" {
+			op = expr[0]
+		}
+		switch op {
+		case '+', '#':
+			// Lazy, so that a following query expression gets the query.
+			b.WriteString("(.*?)")
+		case '/':
+			b.WriteString("((?:/[^/?#]*)*)")
+		case '.':
+			b.WriteString(`((?:\.[^/?#.]*)*)`)
+		case '?', '&':
+			b.WriteString(`((?:[?&][^#]*)?)`)
+		default:
+			b.WriteString("([^/?#]*)")
+		}
+		template = template[i+j+1:]
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// MatchURITemplate reports whether uri is an expansion of the RFC 6570 URI
+// template and returns the values of the template's variables in it,
+// percent-decoded. Variables the URI leaves out are missing from the map.
+func MatchURITemplate(template, uri string) (map[string]string, bool) {
+	m, err := newURITemplateMatcher(template)
+	if err != nil {
+		return nil, false
+	}
+	return m.match(uri)
+}
+
+// uriTemplateMatcher matches URIs against a URI template compiled once.
+type uriTemplateMatcher struct {
+	re         *regexp.Regexp
+	exprs      []string
+	queryNames map[string]bool
+}
+
+func newURITemplateMatcher(template string) (*uriTemplateMatcher, error) {
+	re, err := uriTemplatePattern(template)
+	if err != nil {
+		return nil, err
+	}
+	m := &uriTemplateMatcher{re: re, exprs: templateExpressions(template), queryNames: make(map[string]bool)}
+	// The first query expression captures the whole query string, so each
+	// one binds the names of all of them.
+	for _, expr := range m.exprs {
+		if op, names := splitExpression(expr); op == '?' || op == '&' {
+			for _, name := range names {
+				m.queryNames[name] = true
+			}
+		}
+	}
+	return m, nil
+}
+
+// matches reports whether uri is an expansion of the template.
+func (m *uriTemplateMatcher) matches(uri string) bool {
+	return m.re.MatchString(uri)
+}
+
+// match is MatchURITemplate for the compiled template.
+func (m *uriTemplateMatcher) match(uri string) (map[string]string, bool) {
+	sub := m.re.FindStringSubmatch(uri)
+	if sub == nil {
+		return nil, false
+	}
+	vars := make(map[string]string)
+	for i, expr := range m.exprs {
+		bindExpression(vars, expr, sub[i+1], m.queryNames)
+	}
+	return vars, true
+}
+
+// templateExpressions returns the expressions of template, the text
+// between each pair of braces.
+func templateExpressions(template string) []string {
+	var exprs []string
+	for {
+		i := strings.IndexByte(template, '{')
+		if i < 0 {
+			return exprs
+		}
+		j := strings.IndexByte(template[i:], '}')
+		if j < 0 {
+			return exprs
+		}
+		exprs = append(exprs, template[i+1:i+j])
+		template = template[i+j+1:]
+	}
+}
+
+// URITemplateVariables returns the variable names of an RFC 6570 URI
+// template in order of first use.
+func URITemplateVariables(template string) []string {
+	var vars []string
+	seen := make(map[string]bool)
+	for _, expr := range templateExpressions(template) {
+		_, names := splitExpression(expr)
+		for _, name := range names {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				vars = append(vars, name)
+			}
+		}
+	}
+	return vars
+}
+
+// splitExpression returns the operator of a template expression, or 0, and
+// its variable names without modifiers.
+func splitExpression(expr string) (op byte, names []string) {
+	if expr != "" && strings.IndexByte("+#./;?&", expr[0]) >= 0 {
+		op, expr = expr[0], expr[1:]
+	}
+	for _, spec := range strings.Split(expr, ",") {
+		name := strings.TrimSuffix(spec, "*")
+		if k := strings.IndexByte(name, ':'); k >= 0 {
+			name = name[:k]
+		}
+		names = append(names, name)
+	}
+	return op, names
+}
+
+// bindExpression sets the variables of one expression from the text it
+// matched.
+func bindExpression(vars map[string]string, expr, text string, queryNames map[string]bool) {
+	op, names := splitExpression(expr)
+	set := func(name, value string) {
+		if v, err := url.PathUnescape(value); err == nil {
+			value = v
+		}
+		vars[name] = value
+	}
+	switch op {
+	case '?', '&', ';':
+		sep, named := "&", queryNames
+		if op == ';' {
+			sep, named = ";", make(map[string]bool)
+			for _, name := range names {
+				named[name] = true
+			}
+		}
+		for _, pair := range strings.Split(strings.TrimLeft(text, "?&;"), sep) {
+			name, value, _ := strings.Cut(pair, "=")
+			if named[name] {
+				set(name, value)
+			}
+		}
+		return
+	case '/', '.':
+		if text == "" {
+			return
+		}
+		for i, part := range strings.Split(text[1:], string(op)) {
+			if i < len(names) {
+				set(names[i], part)
+			}
+		}
+		return
+	case '#':
+		text = strings.TrimPrefix(text, "#")
+	}
+	if len(names) == 1 {
+		set(names[0], text)
+		return
+	}
+	for i, part := range strings.Split(text, ",") {
+		if i < len(names) {
+			set(names[i], part)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandURITemplate(t *testing.T) {
+	vars := map[string]string{"owner": "tmc", "repo": "mcp go", "path": "a/b", "q": "x&y", "empty": ""}
+	tests := []struct {
+		template, want string
+	}{
+		{"github://{owner}/{repo}", "github://tmc/mcp%20go"},
+		{"file:///{+path}", "file:///a/b"},
+		{"file:///{path}", "file:///a%2Fb"},
+		{"docs://x{/owner,path}", "docs://x/tmc/a%2Fb"},
+		{"s://h{?q,empty,missing}", "s://h?q=x%26y&empty="},
+		{"s://h?a=1{&owner}", "s://h?a=1&owner=tmc"},
+		{"s://h{#path}", "s://h#a/b"},
+		{"s://h{;owner,empty}", "s://h;owner=tmc;empty"},
+		{"s://{owner:2}{.repo}", "s://tm.mcp%20go"},
+		{"s://{missing}", "s://"},
+	}
+	for _, tt := range tests {
+		got, err := ExpandURITemplate(tt.template, vars)
+		if err != nil || got != tt.want {
+			t.Errorf("ExpandURITemplate(%q) = %q, %v; want %q", tt.template, got, err, tt.want)
+		}
+	}
+	if _, err := ExpandURITemplate("s://{x", vars); err == nil {
+		t.Error("unterminated expression accepted")
+	}
+}
+
+func TestMatchURITemplate(t *testing.T) {
+	tests := []struct {
+		template, uri string
+		want          map[string]string
+	}{
+		{"github://{owner}/{repo}", "github://tmc/mcp%20go", map[string]string{"owner": "tmc", "repo": "mcp go"}},
+		{"file:///{+path}", "file:///a/b", map[string]string{"path": "a/b"}},
+		{"docs://x{/owner,path}", "docs://x/tmc/a%2Fb", map[string]string{"owner": "tmc", "path": "a/b"}},
+		{"s://h{?q,empty,missing}", "s://h?q=x%26y&empty=", map[string]string{"q": "x&y", "empty": ""}},
+		{"s://h{?a}{&b}", "s://h?a=1&b=2&c=3", map[string]string{"a": "1", "b": "2"}},
+		{"s://h{#path}", "s://h#a/b", map[string]string{"path": "a/b"}},
+		{"s://h{;owner,empty}", "s://h;owner=tmc;empty", map[string]string{"owner": "tmc", "empty": ""}},
+		{"s://{x,y}", "s://1,2", map[string]string{"x": "1", "y": "2"}},
+	}
+	for _, tt := range tests {
+		got, ok := MatchURITemplate(tt.template, tt.uri)
+		if !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MatchURITemplate(%q, %q) = %v, %v; want %v", tt.template, tt.uri, got, ok, tt.want)
+		}
+	}
+	if _, ok := MatchURITemplate("github://{owner}/{repo}", "github://tmc"); ok {
+		t.Error("matched a URI with a missing segment")
+	}
+}
+
+func TestURITemplateVariables(t *testing.T) {
+	got := URITemplateVariables("repo://{owner}/{name}/{+path}{?ref,owner}{&depth:2}{/list*}")
+	want := []string{"owner", "name", "path", "ref", "depth", "list"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("URITemplateVariables = %q, want %q", got, want)
+	}
+}